@@ -0,0 +1,306 @@
+// Package agent 实现远程探针模式（pinggo --agent）：定时从中心服务器拉取分配给探针的监控项，
+// 在本地用与服务器相同的检查函数执行，并把结果批量上报。探针不需要数据库和配置文件
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"ping-go/model"
+	"ping-go/monitor"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// flushInterval 上报检查结果的间隔
+	flushInterval = 10 * time.Second
+	// maxBatch 单次上报的结果数量，与服务端的上限相同
+	maxBatch = 1000
+	// maxPending 与服务器断开时最多缓存的结果数量，超出时丢弃最早的结果
+	maxPending = 20000
+	// minInterval 检查间隔的下限，与服务端保存监控项时的规则相同
+	minInterval = 20
+)
+
+// IsAgentMode 命令行参数中是否包含 --agent
+func IsAgentMode(args []string) bool {
+	for _, arg := range args {
+		if arg == "--agent" || arg == "-agent" || strings.HasPrefix(arg, "--agent=") {
+			return true
+		}
+	}
+	return false
+}
+
+// result 一次检查结果，格式与 POST /api/agent/heartbeats 的 results 相同
+type result struct {
+	MonitorID uint      `json:"monitor_id"`
+	Status    int       `json:"status"`
+	Message   string    `json:"msg"`
+	Duration  int       `json:"duration"`
+	Time      time.Time `json:"time"`
+}
+
+// assignment GET /api/agent/monitors 的响应
+type assignment struct {
+	Agent struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+	} `json:"agent"`
+	PollInterval int             `json:"poll_interval"`
+	Monitors     []model.Monitor `json:"monitors"`
+}
+
+// errUnauthorized 令牌无效或探针已被删除
+var errUnauthorized = errors.New("agent token was rejected by the server")
+
+// running 正在本地调度的监控项
+type running struct {
+	monitor model.Monitor
+	cancel  context.CancelFunc
+}
+
+type agent struct {
+	server string
+	token  string
+	http   *http.Client
+
+	mu      sync.Mutex
+	pending []result
+	checks  map[uint]*running
+}
+
+// Run 以探针模式运行，直到收到 SIGINT / SIGTERM。返回进程退出码
+func Run(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Bool("agent", true, "run as a remote probe agent")
+	server := fs.String("server", os.Getenv("PINGGO_URL"), "central server URL (env PINGGO_URL)")
+	token := fs.String("token", os.Getenv("PINGGO_AGENT_TOKEN"), "agent token pga_... (env PINGGO_AGENT_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *server == "" || *token == "" {
+		fmt.Fprintln(stderr, "usage: pinggo --agent --server=https://ping.example.com --token=pga_...")
+		return 2
+	}
+
+	a := &agent{
+		server: strings.TrimRight(*server, "/"),
+		token:  *token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+		checks: make(map[uint]*running),
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 启动时令牌无效直接退出，运行中的网络错误只记录日志并重试
+	poll, err := a.sync(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	go a.flushLoop(ctx)
+
+	timer := time.NewTimer(poll)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.stopAll()
+			// 退出前把已有的结果上报
+			if err := a.flush(context.Background()); err != nil {
+				log.Printf("Failed to report results before exiting: %v", err)
+			}
+			log.Println("Agent stopped")
+			return 0
+		case <-timer.C:
+			if next, err := a.sync(ctx); err != nil {
+				log.Printf("Failed to fetch assigned monitors: %v", err)
+			} else {
+				poll = next
+			}
+			timer.Reset(poll)
+		}
+	}
+}
+
+// request 发送带令牌的请求，非 2xx 时返回错误
+func (a *agent) request(ctx context.Context, method, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errUnauthorized
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// sync 拉取分配的监控项并调整本地调度：新增的开始检查，配置变化的重新开始，取消分配的停止。返回下一次拉取的间隔
+func (a *agent) sync(ctx context.Context) (time.Duration, error) {
+	data, err := a.request(ctx, http.MethodGet, "/api/agent/monitors", nil)
+	if err != nil {
+		return 0, err
+	}
+	var as assignment
+	if err := json.Unmarshal(data, &as); err != nil {
+		return 0, fmt.Errorf("invalid response from server: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	assigned := make(map[uint]bool, len(as.Monitors))
+	started := 0
+	for _, m := range as.Monitors {
+		if m.Type == model.MonitorTypePush {
+			continue
+		}
+		assigned[m.ID] = true
+		if r, ok := a.checks[m.ID]; ok {
+			if r.monitor.Version == m.Version && r.monitor.Interval == m.Interval {
+				continue
+			}
+			r.cancel()
+		}
+		checkCtx, cancel := context.WithCancel(ctx)
+		a.checks[m.ID] = &running{monitor: m, cancel: cancel}
+		go a.runMonitor(checkCtx, m)
+		started++
+	}
+	stopped := 0
+	for id, r := range a.checks {
+		if !assigned[id] {
+			r.cancel()
+			delete(a.checks, id)
+			stopped++
+		}
+	}
+	if started > 0 || stopped > 0 {
+		log.Printf("Agent %s (%s): %d monitors assigned, %d (re)started, %d stopped",
+			as.Agent.Name, as.Agent.Region, len(a.checks), started, stopped)
+	}
+
+	poll := time.Duration(as.PollInterval) * time.Second
+	if poll <= 0 {
+		poll = model.AgentPollInterval
+	}
+	return poll, nil
+}
+
+// runMonitor 按监控项的间隔执行检查，第一次检查随机错开，避免所有监控项同时开始
+func (a *agent) runMonitor(ctx context.Context, m model.Monitor) {
+	interval := time.Duration(max(m.Interval, minInterval)) * time.Second
+	delay := time.Duration(rand.Int64N(int64(interval)))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			status, msg, duration := monitor.RunCheck(m)
+			a.add(result{MonitorID: m.ID, Status: status, Message: msg, Duration: duration, Time: time.Now()})
+			timer.Reset(interval)
+		}
+	}
+}
+
+// add 缓存一条结果，超过 maxPending 时丢弃最早的结果
+func (a *agent) add(r result) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, r)
+	if over := len(a.pending) - maxPending; over > 0 {
+		a.pending = a.pending[over:]
+	}
+}
+
+// flushLoop 每 flushInterval 上报一次缓存的结果
+func (a *agent) flushLoop(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.flush(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to report results, will retry: %v", err)
+			}
+		}
+	}
+}
+
+// flush 分批上报缓存的结果，失败的批次留在缓存中下次重试
+func (a *agent) flush(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		batch := a.pending[:min(len(a.pending), maxBatch)]
+		batch = append([]result(nil), batch...)
+		a.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := a.request(ctx, http.MethodPost, "/api/agent/heartbeats", map[string]any{"results": batch}); err != nil {
+			return err
+		}
+		a.mu.Lock()
+		// 上报期间可能因超出 maxPending 丢弃了最早的结果，按已上报的最后一条定位
+		n := 0
+		for i, r := range a.pending {
+			if r == batch[len(batch)-1] {
+				n = i + 1
+				break
+			}
+		}
+		a.pending = a.pending[n:]
+		a.mu.Unlock()
+	}
+}
+
+// stopAll 停止所有监控项的检查
+func (a *agent) stopAll() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, r := range a.checks {
+		r.cancel()
+		delete(a.checks, id)
+	}
+}
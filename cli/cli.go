@@ -0,0 +1,509 @@
+// Package cli 实现 pinggo 的命令行客户端子命令（monitor、export、check、status），
+// 通过 REST API v1 管理正在运行的服务，供 CI 流水线等脚本使用
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// 退出码
+const (
+	ExitOK    = 0
+	ExitDown  = 1 // check、status：监控项处于 DOWN 状态
+	ExitUsage = 2 // 参数错误
+	ExitError = 3 // 请求失败（连接失败、认证失败、服务端返回错误等）
+)
+
+const (
+	defaultServerURL = "http://localhost:3001"
+	defaultTimeout   = 30 * time.Second
+)
+
+const usage = `Usage: pinggo <command> [flags]
+
+Without a command pinggo starts the server. The commands below talk to a
+running server over the REST API:
+
+  monitor list                 List monitors
+  monitor add --name N --url U Create a monitor
+  monitor pause <id>           Pause a monitor
+  monitor resume <id>          Resume a paused monitor
+  monitor delete <id>          Delete a monitor and its history
+  export                       Export monitors as JSON (importable)
+  check <id>                   Show a monitor's status, exit 1 if it is DOWN
+  status                       Show all monitors, exit 1 if any active monitor is DOWN
+
+Common flags:
+  --server URL     Server address (env PINGGO_URL, default http://localhost:3001)
+  --api-key KEY    API key (env PINGGO_API_KEY)
+  --json           Print JSON instead of a table
+  --timeout D      Request timeout (default 30s)
+
+Exit codes: 0 ok, 1 monitor down, 2 usage error, 3 request failed.
+`
+
+// commands 子命令及其处理函数
+var commands = map[string]func(*env, []string) int{
+	"monitor": runMonitor,
+	"export":  runExport,
+	"check":   runCheck,
+	"status":  runStatus,
+	"help":    runHelp,
+}
+
+// IsCommand 判断 main 收到的第一个参数是否为客户端子命令，不是时按原方式启动服务
+func IsCommand(arg string) bool {
+	_, ok := commands[arg]
+	return ok || arg == "-h" || arg == "--help"
+}
+
+// env 子命令的运行环境
+type env struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	getenv  func(string) string
+	client  *client
+	jsonOut bool
+}
+
+// Run 执行子命令，返回退出码
+func Run(args []string, stdout, stderr io.Writer) int {
+	return run(args, stdout, stderr, os.Getenv)
+}
+
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	e := &env{stdout: stdout, stderr: stderr, getenv: getenv}
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return runHelp(e, nil)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return ExitUsage
+	}
+	return cmd(e, args[1:])
+}
+
+func runHelp(e *env, _ []string) int {
+	fmt.Fprint(e.stdout, usage)
+	return ExitOK
+}
+
+// flags 创建带公共参数的 FlagSet
+func (e *env) flags(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	cf := &commonFlags{}
+	fs.StringVar(&cf.server, "server", "", "server address (env PINGGO_URL)")
+	fs.StringVar(&cf.apiKey, "api-key", "", "API key (env PINGGO_API_KEY)")
+	fs.BoolVar(&cf.json, "json", false, "print JSON")
+	fs.DurationVar(&cf.timeout, "timeout", defaultTimeout, "request timeout")
+	return fs, cf
+}
+
+type commonFlags struct {
+	server  string
+	apiKey  string
+	json    bool
+	timeout time.Duration
+}
+
+// parse 解析参数，参数和位置参数可以交替出现（monitor pause 3 --json）；
+// 返回位置参数，失败时返回的退出码不为 ExitOK
+func (e *env) parse(fs *flag.FlagSet, cf *commonFlags, args []string) ([]string, int) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, ExitOK
+			}
+			return nil, ExitUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	server := cf.server
+	if server == "" {
+		server = e.getenv("PINGGO_URL")
+	}
+	if server == "" {
+		server = defaultServerURL
+	}
+	key := cf.apiKey
+	if key == "" {
+		key = e.getenv("PINGGO_API_KEY")
+	}
+	if key == "" {
+		fmt.Fprintln(e.stderr, "an API key is required: use --api-key or set PINGGO_API_KEY")
+		return nil, ExitUsage
+	}
+	e.client = newClient(server, key, cf.timeout)
+	e.jsonOut = cf.json
+	return positional, ExitOK
+}
+
+// fail 输出请求错误
+func (e *env) fail(err error) int {
+	fmt.Fprintln(e.stderr, "error:", err)
+	return ExitError
+}
+
+// usageError 输出参数错误
+func (e *env) usageError(format string, args ...any) int {
+	fmt.Fprintf(e.stderr, format+"\n", args...)
+	return ExitUsage
+}
+
+// printJSON 以缩进格式输出
+func (e *env) printJSON(v any) int {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return e.fail(err)
+	}
+	return ExitOK
+}
+
+// monitorView 监控项详情中命令行输出使用的字段
+type monitorView struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	URL       string    `json:"url"`
+	Active    int       `json:"active"`
+	Status    int       `json:"status"`
+	StatusKey string    `json:"status_key"`
+	Msg       string    `json:"msg"`
+	Interval  int       `json:"interval"`
+	Tags      string    `json:"tags"`
+	Group     string    `json:"group"`
+	LastCheck time.Time `json:"last_check"`
+}
+
+// down 是否为启用中且状态为 DOWN 的监控项
+func (m monitorView) down() bool {
+	return m.Active == 1 && m.StatusKey == "down"
+}
+
+func (m monitorView) state() string {
+	if m.Active != 1 {
+		return "paused"
+	}
+	return m.StatusKey
+}
+
+// printMonitors 以表格输出监控项
+func (e *env) printMonitors(monitors []monitorView) {
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tSTATUS\tINTERVAL\tTARGET\tLAST CHECK")
+	for _, m := range monitors {
+		last := "-"
+		if !m.LastCheck.IsZero() {
+			last = m.LastCheck.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%ds\t%s\t%s\n", m.ID, m.Name, m.Type, m.state(), m.Interval, m.URL, last)
+	}
+	w.Flush()
+}
+
+// listMonitors 读取监控项，raw 为服务端返回的原始详情（--json 时原样输出）
+func (e *env) listMonitors() ([]monitorView, []json.RawMessage, error) {
+	var body struct {
+		Monitors []json.RawMessage `json:"monitors"`
+	}
+	if err := e.client.getJSON(http.MethodGet, "/api/v1/monitors", nil, &body); err != nil {
+		return nil, nil, err
+	}
+	views := make([]monitorView, len(body.Monitors))
+	for i, raw := range body.Monitors {
+		if err := json.Unmarshal(raw, &views[i]); err != nil {
+			return nil, nil, fmt.Errorf("invalid response from server: %w", err)
+		}
+	}
+	return views, body.Monitors, nil
+}
+
+// monitorID 解析位置参数中的监控项 ID
+func monitorID(args []string) (uint, bool) {
+	if len(args) != 1 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	return uint(id), err == nil && id > 0
+}
+
+func runMonitor(e *env, args []string) int {
+	if len(args) == 0 {
+		return e.usageError("usage: pinggo monitor <list|add|pause|resume|delete> [flags]")
+	}
+	switch args[0] {
+	case "list", "ls":
+		return runMonitorList(e, args[1:])
+	case "add":
+		return runMonitorAdd(e, args[1:])
+	case "pause":
+		return runMonitorActive(e, args[1:], false)
+	case "resume":
+		return runMonitorActive(e, args[1:], true)
+	case "delete", "rm":
+		return runMonitorDelete(e, args[1:])
+	}
+	return e.usageError("unknown monitor command %q", args[0])
+}
+
+func runMonitorList(e *env, args []string) int {
+	fs, cf := e.flags("monitor list")
+	tag := fs.String("tag", "", "only monitors with this tag")
+	if _, code := e.parse(fs, cf, args); code != ExitOK || e.client == nil {
+		return code
+	}
+	views, raw, err := e.listMonitors()
+	if err != nil {
+		return e.fail(err)
+	}
+	if *tag != "" {
+		want := strings.ToLower(strings.TrimSpace(*tag))
+		var fv []monitorView
+		var fr []json.RawMessage
+		for i, m := range views {
+			for _, t := range strings.Split(m.Tags, ",") {
+				if strings.TrimSpace(t) == want {
+					fv, fr = append(fv, m), append(fr, raw[i])
+					break
+				}
+			}
+		}
+		views, raw = fv, fr
+	}
+	if e.jsonOut {
+		if raw == nil {
+			raw = []json.RawMessage{}
+		}
+		return e.printJSON(raw)
+	}
+	e.printMonitors(views)
+	return ExitOK
+}
+
+func runMonitorAdd(e *env, args []string) int {
+	fs, cf := e.flags("monitor add")
+	name := fs.String("name", "", "monitor name (required)")
+	mType := fs.String("type", "http", "monitor type")
+	url := fs.String("url", "", "URL, host or host:port to check")
+	interval := fs.Int("interval", 60, "check interval in seconds")
+	tags := fs.String("tags", "", "comma separated tags")
+	groupID := fs.Uint("group-id", 0, "group ID")
+	data := fs.String("data", "", "additional fields as a JSON object, e.g. '{\"timeout\": 5}'")
+	if _, code := e.parse(fs, cf, args); code != ExitOK || e.client == nil {
+		return code
+	}
+	if *name == "" {
+		return e.usageError("--name is required")
+	}
+
+	body := map[string]any{}
+	if *data != "" {
+		if err := json.Unmarshal([]byte(*data), &body); err != nil {
+			return e.usageError("--data must be a JSON object: %v", err)
+		}
+	}
+	body["name"] = *name
+	body["type"] = *mType
+	body["url"] = *url
+	body["interval"] = *interval
+	if *tags != "" {
+		body["tags"] = *tags
+	}
+	if *groupID != 0 {
+		body["group_id"] = *groupID
+	}
+
+	raw, err := e.client.do(http.MethodPost, "/api/v1/monitors", body)
+	if err != nil {
+		return e.fail(err)
+	}
+	if e.jsonOut {
+		return e.printJSON(json.RawMessage(raw))
+	}
+	var m monitorView
+	json.Unmarshal(raw, &m)
+	fmt.Fprintf(e.stdout, "Created monitor %d (%s)\n", m.ID, m.Name)
+	return ExitOK
+}
+
+func runMonitorActive(e *env, args []string, active bool) int {
+	verb := "pause"
+	if active {
+		verb = "resume"
+	}
+	fs, cf := e.flags("monitor " + verb)
+	positional, code := e.parse(fs, cf, args)
+	if code != ExitOK || e.client == nil {
+		return code
+	}
+	id, ok := monitorID(positional)
+	if !ok {
+		return e.usageError("usage: pinggo monitor %s <id>", verb)
+	}
+	raw, err := e.client.do(http.MethodPatch, fmt.Sprintf("/api/v1/monitors/%d/active", id), map[string]any{"active": active})
+	if err != nil {
+		return e.fail(err)
+	}
+	if e.jsonOut {
+		return e.printJSON(json.RawMessage(raw))
+	}
+	var m monitorView
+	json.Unmarshal(raw, &m)
+	fmt.Fprintf(e.stdout, "Monitor %d (%s) is %s\n", m.ID, m.Name, m.state())
+	return ExitOK
+}
+
+func runMonitorDelete(e *env, args []string) int {
+	fs, cf := e.flags("monitor delete")
+	positional, code := e.parse(fs, cf, args)
+	if code != ExitOK || e.client == nil {
+		return code
+	}
+	id, ok := monitorID(positional)
+	if !ok {
+		return e.usageError("usage: pinggo monitor delete <id>")
+	}
+	raw, err := e.client.do(http.MethodDelete, fmt.Sprintf("/api/v1/monitors/%d", id), nil)
+	if err != nil {
+		return e.fail(err)
+	}
+	if e.jsonOut {
+		return e.printJSON(json.RawMessage(raw))
+	}
+	fmt.Fprintf(e.stdout, "Deleted monitor %d\n", id)
+	return ExitOK
+}
+
+// runExport 导出监控项，输出总是 JSON，可直接用于导入
+func runExport(e *env, args []string) int {
+	fs, cf := e.flags("export")
+	secrets := fs.Bool("include-secrets", false, "include passwords, tokens and other secrets")
+	history := fs.Bool("include-history", false, "include heartbeat history")
+	output := fs.String("o", "", "write to a file instead of stdout")
+	if _, code := e.parse(fs, cf, args); code != ExitOK || e.client == nil {
+		return code
+	}
+	path := fmt.Sprintf("/api/v1/monitors/export?include_secrets=%t&include_history=%t", *secrets, *history)
+	raw, err := e.client.do(http.MethodGet, path, nil)
+	if err != nil {
+		return e.fail(err)
+	}
+	if *output == "" {
+		return e.printJSON(json.RawMessage(raw))
+	}
+	if err := os.WriteFile(*output, raw, 0o600); err != nil {
+		return e.fail(err)
+	}
+	fmt.Fprintf(e.stderr, "Exported to %s\n", *output)
+	return ExitOK
+}
+
+// runCheck 输出单个监控项的状态、最近一次检查和可用率，监控项为 DOWN 时返回 ExitDown
+func runCheck(e *env, args []string) int {
+	fs, cf := e.flags("check")
+	positional, code := e.parse(fs, cf, args)
+	if code != ExitOK || e.client == nil {
+		return code
+	}
+	id, ok := monitorID(positional)
+	if !ok {
+		return e.usageError("usage: pinggo check <id>")
+	}
+	raw, err := e.client.do(http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d", id), nil)
+	if err != nil {
+		return e.fail(err)
+	}
+	var m monitorView
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return e.fail(fmt.Errorf("invalid response from server: %w", err))
+	}
+	var stats map[string]any
+	if err := e.client.getJSON(http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d/stats", id), nil, &stats); err != nil {
+		return e.fail(err)
+	}
+
+	if e.jsonOut {
+		var out map[string]any
+		json.Unmarshal(raw, &out)
+		out["stats"] = stats
+		if code := e.printJSON(out); code != ExitOK {
+			return code
+		}
+	} else {
+		w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Monitor:\t%d (%s)\n", m.ID, m.Name)
+		fmt.Fprintf(w, "Type:\t%s\n", m.Type)
+		fmt.Fprintf(w, "Target:\t%s\n", m.URL)
+		fmt.Fprintf(w, "Status:\t%s\n", m.state())
+		if !m.LastCheck.IsZero() {
+			fmt.Fprintf(w, "Last check:\t%s\n", m.LastCheck.Local().Format(time.RFC3339))
+		}
+		if m.Msg != "" {
+			fmt.Fprintf(w, "Message:\t%s\n", m.Msg)
+		}
+		for _, k := range []string{"uptime24h", "uptime7d", "uptime30d"} {
+			if v, ok := stats[k].(float64); ok {
+				fmt.Fprintf(w, "Uptime %s:\t%.2f%%\n", strings.TrimPrefix(k, "uptime"), v)
+			}
+		}
+		if v, ok := stats["avgResponse24h"].(float64); ok {
+			fmt.Fprintf(w, "Avg response 24h:\t%.0f ms\n", v)
+		}
+		w.Flush()
+	}
+	if m.down() {
+		return ExitDown
+	}
+	return ExitOK
+}
+
+// runStatus 输出全部监控项，任一启用中的监控项为 DOWN 时返回 ExitDown
+func runStatus(e *env, args []string) int {
+	fs, cf := e.flags("status")
+	if _, code := e.parse(fs, cf, args); code != ExitOK || e.client == nil {
+		return code
+	}
+	views, raw, err := e.listMonitors()
+	if err != nil {
+		return e.fail(err)
+	}
+	var down []monitorView
+	for _, m := range views {
+		if m.down() {
+			down = append(down, m)
+		}
+	}
+
+	if e.jsonOut {
+		if raw == nil {
+			raw = []json.RawMessage{}
+		}
+		e.printJSON(map[string]any{"monitors": raw, "total": len(views), "down": len(down)})
+	} else {
+		e.printMonitors(views)
+		fmt.Fprintf(e.stdout, "\n%d monitor(s), %d down\n", len(views), len(down))
+	}
+	if len(down) > 0 {
+		return ExitDown
+	}
+	return ExitOK
+}
@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKey = "pgk_test"
+
+// fakeAPI 模拟 REST API v1 的最小子集：监控项 1（UP）和 2（DOWN），
+// 99 返回代理的 HTML 错误页，98 返回非 JSON 的 200 响应
+func fakeAPI(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requests []string
+	monitors := map[string]string{
+		"1": `{"id":1,"name":"api","type":"http","url":"https://api.example.com","active":1,"status":1,"status_key":"up","interval":60,"tags":"ops"}`,
+		"2": `{"id":2,"name":"db","type":"port","url":"db:5432","active":1,"status":0,"status_key":"down","msg":"Connection Refused","interval":30}`,
+	}
+	writeJSON := func(w http.ResponseWriter, code int, body string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		io.WriteString(w, body)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/monitors", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"monitors":[`+monitors["1"]+`,`+monitors["2"]+`]}`)
+	})
+	mux.HandleFunc("POST /api/v1/monitors", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["name"] == "" {
+			writeJSON(w, http.StatusBadRequest, `{"code":400,"message":"name is required"}`)
+			return
+		}
+		if body["url"] == "" {
+			writeJSON(w, http.StatusBadRequest, `{"code":400,"message":"url is required","field":"url"}`)
+			return
+		}
+		body["id"] = 3
+		out, _ := json.Marshal(body)
+		writeJSON(w, http.StatusCreated, string(out))
+	})
+	mux.HandleFunc("GET /api/v1/monitors/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch id := r.PathValue("id"); id {
+		case "99":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			io.WriteString(w, "<html><body>502 Bad Gateway</body></html>")
+		case "98":
+			io.WriteString(w, "<html>login</html>")
+		default:
+			if m, ok := monitors[id]; ok {
+				writeJSON(w, http.StatusOK, m)
+				return
+			}
+			writeJSON(w, http.StatusNotFound, `{"code":404,"message":"Monitor not found"}`)
+		}
+	})
+	mux.HandleFunc("GET /api/v1/monitors/{id}/stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"uptime24h":99.5,"uptime7d":99.9,"avgResponse24h":120}`)
+	})
+	mux.HandleFunc("PATCH /api/v1/monitors/{id}/active", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Active bool `json:"active"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		active := "0"
+		if body.Active {
+			active = "1"
+		}
+		writeJSON(w, http.StatusOK, `{"id":1,"name":"api","active":`+active+`,"status_key":"up"}`)
+	})
+	mux.HandleFunc("DELETE /api/v1/monitors/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"id":1,"deleted":true}`)
+	})
+	mux.HandleFunc("GET /api/v1/monitors/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("include_secrets") != "true" {
+			t.Errorf("export query = %s", r.URL.RawQuery)
+		}
+		writeJSON(w, http.StatusOK, `{"version":1,"monitors":[]}`)
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer "+testKey {
+			writeJSON(w, http.StatusUnauthorized, `{"code":401,"message":"Unauthorized"}`)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+// runCLI 以 ts 为服务端执行命令，返回退出码和输出
+func runCLI(t *testing.T, ts *httptest.Server, key string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	getenv := func(name string) string {
+		switch name {
+		case "PINGGO_URL":
+			return ts.URL
+		case "PINGGO_API_KEY":
+			return key
+		}
+		return ""
+	}
+	code := run(args, &stdout, &stderr, getenv)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	ts, requests := fakeAPI(t)
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     []string // stdout 中应包含的内容
+		request  string   // 应发出的请求
+	}{
+		{"monitor list", []string{"monitor", "list"}, ExitOK, []string{"ID", "api", "db", "down"}, "GET /api/v1/monitors"},
+		{"monitor list by tag", []string{"monitor", "ls", "--tag", "OPS"}, ExitOK, []string{"api"}, "GET /api/v1/monitors"},
+		{"monitor add", []string{"monitor", "add", "--name", "web", "--url", "https://web.example.com", "--tags", "ci"}, ExitOK, []string{"Created monitor 3 (web)"}, "POST /api/v1/monitors"},
+		{"monitor pause", []string{"monitor", "pause", "1"}, ExitOK, []string{"Monitor 1 (api) is paused"}, "PATCH /api/v1/monitors/1/active"},
+		{"monitor resume json", []string{"monitor", "resume", "1", "--json"}, ExitOK, []string{`"active": 1`}, "PATCH /api/v1/monitors/1/active"},
+		{"monitor delete", []string{"monitor", "rm", "1"}, ExitOK, []string{"Deleted monitor 1"}, "DELETE /api/v1/monitors/1"},
+		{"export", []string{"export", "--include-secrets"}, ExitOK, []string{`"monitors": []`}, "GET /api/v1/monitors/export"},
+		{"check up", []string{"check", "1"}, ExitOK, []string{"Status:", "up", "Uptime 24h:", "99.50%", "120 ms"}, "GET /api/v1/monitors/1/stats"},
+		{"check down", []string{"check", "2"}, ExitDown, []string{"down", "Connection Refused"}, "GET /api/v1/monitors/2"},
+		{"status", []string{"status"}, ExitDown, []string{"2 monitor(s), 1 down"}, "GET /api/v1/monitors"},
+		{"status json", []string{"status", "--json"}, ExitDown, []string{`"down": 1`, `"total": 2`}, "GET /api/v1/monitors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*requests = nil
+			code, stdout, stderr := runCLI(t, ts, testKey, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("exit code %d, want %d; stderr: %s", code, tt.wantCode, stderr)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(stdout, s) {
+					t.Errorf("stdout missing %q:\n%s", s, stdout)
+				}
+			}
+			if !strings.Contains(strings.Join(*requests, "\n"), tt.request) {
+				t.Errorf("requests %v, want %s", *requests, tt.request)
+			}
+		})
+	}
+	if code, _, _ := runCLI(t, ts, testKey, "monitor", "list", "--tag", "none", "--json"); code != ExitOK {
+		t.Fatalf("empty filtered list: exit %d", code)
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	ts, _ := fakeAPI(t)
+	path := filepath.Join(t.TempDir(), "monitors.json")
+	code, stdout, stderr := runCLI(t, ts, testKey, "export", "--include-secrets", "-o", path)
+	if code != ExitOK || stdout != "" || !strings.Contains(stderr, "Exported to") {
+		t.Fatalf("exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !json.Valid(data) {
+		t.Fatalf("export file = %q, %v", data, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts, requests := fakeAPI(t)
+	tests := []struct {
+		name       string
+		key        string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{"bad API key", "pgk_wrong", []string{"status"}, ExitError, "error: Unauthorized (HTTP 401)"},
+		{"bad API key on write", "pgk_wrong", []string{"monitor", "pause", "1"}, ExitError, "Unauthorized (HTTP 401)"},
+		{"AppError body", testKey, []string{"check", "7"}, ExitError, "error: Monitor not found (HTTP 404)"},
+		{"validation error", testKey, []string{"monitor", "add", "--name", "web"}, ExitError, "url is required (HTTP 400)"},
+		{"non-JSON error body", testKey, []string{"check", "99"}, ExitError, "error: HTTP 502"},
+		{"non-JSON success body", testKey, []string{"check", "98"}, ExitError, "invalid response from server"},
+		{"server unreachable", testKey, []string{"status", "--server", "http://127.0.0.1:1"}, ExitError, "error:"},
+		{"missing API key", "", []string{"status"}, ExitUsage, "an API key is required"},
+		{"missing monitor id", testKey, []string{"check"}, ExitUsage, "usage: pinggo check <id>"},
+		{"invalid monitor id", testKey, []string{"monitor", "delete", "abc"}, ExitUsage, "usage: pinggo monitor delete <id>"},
+		{"missing name", testKey, []string{"monitor", "add", "--url", "x"}, ExitUsage, "--name is required"},
+		{"invalid data", testKey, []string{"monitor", "add", "--name", "x", "--data", "{"}, ExitUsage, "--data must be a JSON object"},
+		{"unknown flag", testKey, []string{"status", "--nope"}, ExitUsage, "flag provided but not defined"},
+		{"unknown command", testKey, []string{"frobnicate"}, ExitUsage, `unknown command "frobnicate"`},
+		{"unknown monitor command", testKey, []string{"monitor", "frob"}, ExitUsage, `unknown monitor command "frob"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*requests = nil
+			code, stdout, stderr := runCLI(t, ts, tt.key, tt.args...)
+			if code != tt.wantCode {
+				t.Fatalf("exit code %d, want %d; stderr: %s", code, tt.wantCode, stderr)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Fatalf("stderr %q, want %q", stderr, tt.wantStderr)
+			}
+			if strings.Contains(stdout, "<html") {
+				t.Fatalf("error page printed to stdout: %s", stdout)
+			}
+			if tt.wantCode == ExitUsage && len(*requests) > 0 {
+				t.Fatalf("usage error sent requests %v", *requests)
+			}
+		})
+	}
+}
+
+func TestHelp(t *testing.T) {
+	for _, args := range [][]string{nil, {"help"}, {"--help"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr, func(string) string { return "" }); code != ExitOK || !strings.Contains(stdout.String(), "Usage: pinggo") {
+			t.Fatalf("%v: exit %d, stdout %q", args, code, stdout.String())
+		}
+	}
+	if !IsCommand("status") || !IsCommand("--help") || IsCommand("--config") {
+		t.Fatal("IsCommand misclassifies arguments")
+	}
+}
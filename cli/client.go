@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// client REST API v1 客户端
+type client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// apiError 服务端返回的 AppError（{code, message}），或无法解析时的 HTTP 状态
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+func newClient(baseURL, apiKey string, timeout time.Duration) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// do 发送请求，body 不为 nil 时编码为 JSON。成功时返回响应体，非 2xx 时返回 *apiError
+func (c *client) do(method, path string, body any) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := &apiError{Status: resp.StatusCode}
+		json.Unmarshal(data, e)
+		return nil, e
+	}
+	return data, nil
+}
+
+// getJSON 发送请求并把响应解码到 out
+func (c *client) getJSON(method, path string, body, out any) error {
+	data, err := c.do(method, path, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response from server: %w", err)
+	}
+	return nil
+}
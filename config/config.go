@@ -1,8 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"ping-go/pkg/i18n"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,6 +20,63 @@ type RetentionConfig struct {
 	RawHours   int `yaml:"raw_hours"`   // 原始心跳数据保留小时数，默认 24
 	HourlyDays int `yaml:"hourly_days"` // 小时聚合数据保留天数，默认 7
 	DailyDays  int `yaml:"daily_days"`  // 日聚合数据保留天数，默认 365
+
+	NotificationLogDays int `yaml:"notification_log_days"` // 通知发送记录保留天数，默认 30
+}
+
+// LoggingConfig 日志相关配置
+type LoggingConfig struct {
+	// CheckEvents 检查结果事件（NDJSON）输出：文件路径或 tcp://host:port，为空则关闭
+	CheckEvents string `yaml:"check_events"`
+}
+
+// IngestConfig 心跳上报接口（push 监控等）的限流与大小限制
+type IngestConfig struct {
+	PushIntervalSeconds int   `yaml:"push_interval_seconds"` // 每个 push token 平均允许的上报间隔秒数，默认 10
+	PushBurst           int   `yaml:"push_burst"`            // 允许的突发上报次数，默认 1
+	MaxPayloadBytes     int64 `yaml:"max_payload_bytes"`     // 单次上报请求体上限（字节），默认 4096
+}
+
+// SessionConfig 登录会话的有效期。会话在使用时自动延长（滑动过期），但不超过登录后的最长期限
+type SessionConfig struct {
+	TTLHours int `yaml:"ttl_hours"` // 最后一次使用后会话保持有效的小时数，默认 24
+	MaxDays  int `yaml:"max_days"`  // 登录后会话的最长有效天数，超过后必须重新登录，默认 30
+}
+
+// DatabasePostgres 数据库驱动名：PostgreSQL
+const DatabasePostgres = "postgres"
+
+// DatabaseConfig 数据库连接，默认使用程序目录下的 SQLite 文件 pinggo.db
+type DatabaseConfig struct {
+	Driver string `yaml:"driver"` // sqlite（默认）或 postgres
+	DSN    string `yaml:"dsn"`    // postgres 的连接串，如 "host=db.internal user=pinggo password=... dbname=pinggo sslmode=require"
+}
+
+// HAConfig 多实例协同模式：多个实例共享同一数据库，通过数据库租约选举出唯一的调度实例
+// 使用 SQLite 时所有实例共享同一个数据库文件，只能防止进程故障；跨主机部署需要共享的 PostgreSQL
+type HAConfig struct {
+	Enabled      bool   `yaml:"enabled"`       // 是否启用多实例模式，默认关闭（单实例）
+	InstanceID   string `yaml:"instance_id"`   // 实例标识，为空时使用 主机名-进程号
+	LeaseSeconds int    `yaml:"lease_seconds"` // 领导者租约时长（秒），默认 15；租约过期后其他实例接管
+}
+
+// EmbedConfig 公开嵌入页面（/embed/...）的配置
+type EmbedConfig struct {
+	// AllowedOrigins 允许通过 iframe 嵌入的来源，如 https://docs.example.com；为空时只允许同源嵌入
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// MetricsConfig Prometheus 指标端点 GET /metrics 的访问控制，都为空时不需要认证
+type MetricsConfig struct {
+	Username string `yaml:"username"` // HTTP Basic 认证的用户名和密码
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"` // 也可以通过 "Authorization: Bearer <token>" 访问
+}
+
+// DemoConfig 只读演示模式，只能通过环境变量开启（DEMO_MODE、DEMO_MODE_UNTIL），配置文件和设置界面都无法修改
+type DemoConfig struct {
+	Enabled bool
+	Until   time.Time // 截止时间，过后自动恢复正常模式；零值表示不限时
 }
 
 type Config struct {
@@ -20,10 +84,78 @@ type Config struct {
 	Notification NotificationConfig `yaml:"notification"`
 	Monitor      MonitorConfig      `yaml:"monitor"`
 	Retention    RetentionConfig    `yaml:"retention"`
+	Ingest       IngestConfig       `yaml:"ingest"`
+	Session      SessionConfig      `yaml:"session"`
+	Logging      LoggingConfig      `yaml:"logging"`
+	Database     DatabaseConfig     `yaml:"database"`
+	HA           HAConfig           `yaml:"ha"`
+	Embed        EmbedConfig        `yaml:"embed"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+	Demo         DemoConfig         `yaml:"-"`
+}
+
+// DemoActive 当前是否处于演示模式
+func (c *Config) DemoActive() bool {
+	return c.Demo.Enabled && (c.Demo.Until.IsZero() || time.Now().Before(c.Demo.Until))
 }
 
 type ServerConfig struct {
 	Port int `yaml:"port"`
+	// ExternalURL 外部访问地址（如 https://status.example.com），用于通知中的控制台链接；为空时不附带链接
+	ExternalURL string `yaml:"external_url"`
+	// DebugDump 开启 GET /api/debug/dump（管理员会话下载诊断信息），默认关闭；Socket 事件 getDiagnosticsDump 不受影响
+	DebugDump bool `yaml:"debug_dump"`
+	// DebugProfiling 在 /debug/pprof/ 挂载完整的 net/http/pprof 处理器（需要管理员会话），默认关闭，仅在排查问题时临时开启
+	DebugProfiling bool `yaml:"debug_profiling"`
+	// PublicAPIDisabled 关闭无需登录的只读 JSON 接口（GET /api/status、/api/status/<id>/heartbeats），默认开启
+	PublicAPIDisabled bool `yaml:"public_api_disabled"`
+}
+
+// DashboardURL 返回控制台地址，未配置 server.external_url 时为空
+func (c *Config) DashboardURL() string {
+	if c.Server.ExternalURL == "" {
+		return ""
+	}
+	return strings.TrimRight(c.Server.ExternalURL, "/") + "/dashboard"
+}
+
+// MonitorURL 返回控制台中监控项详情页的地址，未配置 server.external_url 时为空
+func (c *Config) MonitorURL(id uint) string {
+	dashboard := c.DashboardURL()
+	if dashboard == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s#/monitor/%d", dashboard, id)
+}
+
+// StatusPageURL 返回公开状态页的地址，未配置 server.external_url 时为空
+func (c *Config) StatusPageURL(slug string) string {
+	if c.Server.ExternalURL == "" {
+		return ""
+	}
+	return strings.TrimRight(c.Server.ExternalURL, "/") + "/status/" + slug
+}
+
+// SubscriptionURL 返回邮件订阅的确认或退订链接（action 为 confirm 或 unsubscribe），未配置 server.external_url 时为空
+func (c *Config) SubscriptionURL(action string, id uint, token string) string {
+	if c.Server.ExternalURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/subscriptions/%s?id=%d&token=%s", strings.TrimRight(c.Server.ExternalURL, "/"), action, id, url.QueryEscape(token))
+}
+
+// IncidentURL 返回控制台中事件视图的地址：显示监控项在 at 前后的心跳、触发规则和确认状态；
+// ruleID 为触发通知的规则，0 表示不指定。未配置 server.external_url 时为空
+func (c *Config) IncidentURL(id uint, at time.Time, ruleID uint) string {
+	dashboard := c.DashboardURL()
+	if dashboard == "" {
+		return ""
+	}
+	link := fmt.Sprintf("%s#/incident/%d/%d", dashboard, id, at.Unix())
+	if ruleID != 0 {
+		link += fmt.Sprintf("?rule=%d", ruleID)
+	}
+	return link
 }
 
 type NotificationConfig struct {
@@ -31,13 +163,31 @@ type NotificationConfig struct {
 	Email        string `yaml:"email"`
 	FromEmail    string `yaml:"from_email"`
 	FromName     string `yaml:"from_name"`
+	// Language 通知邮件的默认语言（zh / en），可被设置项 emailLanguage 和规则的 language 覆盖；为空时跟随界面语言
+	Language string `yaml:"language"`
 }
 
 type MonitorConfig struct {
-	DNSServer string `yaml:"dns_server"`
+	DNSServer    string `yaml:"dns_server"`
+	MaxBodyBytes int64  `yaml:"max_body_bytes"` // 正则校验读取的响应体上限（解压后，字节），默认 1048576
+	// DNSFallbackDisabled 关闭自定义 DNS 不可用时回退到系统解析器（默认开启回退）
+	DNSFallbackDisabled bool `yaml:"dns_fallback_disabled"`
+	// DNSFallbackAfter 自定义 DNS 连续多少次查询没有响应后回退，默认 5
+	DNSFallbackAfter int `yaml:"dns_fallback_after"`
+	// StartupConcurrency 启动时并发执行首次检查的监控项数，默认 16
+	StartupConcurrency int `yaml:"startup_concurrency"`
 }
 
-var GlobalConfig Config
+// current 当前生效的配置。每次加载都会发布一份新的快照，已发布的快照不再修改
+var current atomic.Pointer[Config]
+
+// Get 返回当前配置的快照，调用方不得修改返回值；尚未加载配置时返回零值配置
+func Get() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return &Config{}
+}
 
 // defaultConfigContent 默认配置文件内容
 const defaultConfigContent = `
@@ -53,8 +203,10 @@ retention:
   raw_hours: 24      # 原始心跳数据保留 24 小时
   hourly_days: 7     # 小时级聚合数据保留 7 天
   daily_days: 365    # 日级聚合数据保留 1 年
+  notification_log_days: 30  # 通知发送记录保留 30 天
 `
 
+// LoadConfig 读取配置文件（不存在时生成默认配置），应用环境变量覆盖并校验后发布为当前配置
 func LoadConfig(path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -71,24 +223,137 @@ func LoadConfig(path string) error {
 		}
 	}
 
-	if err := yaml.Unmarshal(data, &GlobalConfig); err != nil {
+	cfg, err := parse(data)
+	if err != nil {
 		return err
 	}
+	current.Store(cfg)
+	return nil
+}
+
+// Reload 重新读取配置文件（用于 SIGHUP 热加载），校验通过后原子替换当前配置；
+// 失败时保留旧配置。server、数据库和多实例配置只在启动时生效，重载时沿用旧值；
+// 例外是 server.debug_dump 和 server.debug_profiling，排查问题时可以不重启直接开关
+func Reload(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Get(), err
+	}
+	cfg, err := parse(data)
+	if err != nil {
+		return Get(), err
+	}
+	old := Get()
+	debugDump, debugProfiling := cfg.Server.DebugDump, cfg.Server.DebugProfiling
+	cfg.Server, cfg.Database, cfg.HA = old.Server, old.Database, old.HA
+	cfg.Server.DebugDump, cfg.Server.DebugProfiling = debugDump, debugProfiling
+	current.Store(cfg)
+	return cfg, nil
+}
+
+// parse 解析配置内容并应用环境变量覆盖
+func parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
 
 	// Environment variable overrides
 	if apiKey := os.Getenv("RESEND_API_KEY"); apiKey != "" {
-		GlobalConfig.Notification.ResendAPIKey = apiKey
+		cfg.Notification.ResendAPIKey = apiKey
 	}
 	if email := os.Getenv("NOTIFICATION_EMAIL"); email != "" {
-		GlobalConfig.Notification.Email = email
+		cfg.Notification.Email = email
 	}
 	if port := os.Getenv("PORT"); port != "" {
 		var p int
 		fmt.Sscanf(port, "%d", &p)
 		if p != 0 {
-			GlobalConfig.Server.Port = p
+			cfg.Server.Port = p
+		}
+	}
+
+	// 演示模式只读取环境变量，避免通过 Web 界面或配置文件关闭
+	if v := os.Getenv("DEMO_MODE"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("DEMO_MODE %q is not a boolean", v)
 		}
+		cfg.Demo.Enabled = enabled
 	}
+	if v := os.Getenv("DEMO_MODE_UNTIL"); v != "" {
+		until, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("DEMO_MODE_UNTIL %q must be an RFC3339 timestamp", v)
+		}
+		cfg.Demo.Until = until
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
 
+// validate 校验明显无效的配置值，0 表示使用默认值
+func (c *Config) validate() error {
+	if c.Server.Port < 0 || c.Server.Port > 65535 {
+		return fmt.Errorf("server.port %d is out of range", c.Server.Port)
+	}
+	if c.Server.ExternalURL != "" {
+		u, err := url.Parse(c.Server.ExternalURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("server.external_url %q must be an http(s) URL", c.Server.ExternalURL)
+		}
+	}
+	if c.Retention.RawHours < 0 || c.Retention.HourlyDays < 0 || c.Retention.DailyDays < 0 || c.Retention.NotificationLogDays < 0 {
+		return errors.New("retention values must not be negative")
+	}
+	if c.Notification.Language != "" && !i18n.Supported(c.Notification.Language) {
+		return fmt.Errorf("notification.language %q is not supported (supported: %s)", c.Notification.Language, strings.Join(i18n.Languages(), ", "))
+	}
+	if c.Monitor.MaxBodyBytes < 0 {
+		return errors.New("monitor.max_body_bytes must not be negative")
+	}
+	if c.Monitor.DNSFallbackAfter < 0 {
+		return errors.New("monitor.dns_fallback_after must not be negative")
+	}
+	if c.Ingest.PushIntervalSeconds < 0 || c.Ingest.PushBurst < 0 || c.Ingest.MaxPayloadBytes < 0 {
+		return errors.New("ingest values must not be negative")
+	}
+	if c.Session.TTLHours < 0 || c.Session.MaxDays < 0 {
+		return errors.New("session values must not be negative")
+	}
+	switch c.Database.Driver {
+	case "", "sqlite":
+	case DatabasePostgres:
+		if c.Database.DSN == "" {
+			return errors.New("database.dsn is required for the postgres driver")
+		}
+	default:
+		return fmt.Errorf("database.driver %q is not supported (supported: sqlite, postgres)", c.Database.Driver)
+	}
+	if c.HA.LeaseSeconds < 0 {
+		return errors.New("ha.lease_seconds must not be negative")
+	}
+	if (c.Metrics.Username == "") != (c.Metrics.Password == "") {
+		return errors.New("metrics.username and metrics.password must be set together")
+	}
+	for _, origin := range c.Embed.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return fmt.Errorf("embed.allowed_origins: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateOrigin 校验来源格式为 scheme://host[:port]，不允许路径和会破坏响应头的字符
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil ||
+		strings.ContainsAny(origin, " ;,'\"") {
+		return fmt.Errorf("invalid origin %q, expected scheme://host[:port]", origin)
+	}
 	return nil
 }
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 两份配置交替写入，读取方每次拿到的快照必须完整属于其中一份
+const (
+	configA = "server:\n  port: 1111\nretention:\n  raw_hours: 10\n  hourly_days: 2\n"
+	configB = "server:\n  port: 2222\nretention:\n  raw_hours: 20\n  hourly_days: 4\n"
+)
+
+func TestReloadConcurrentWithGet(t *testing.T) {
+	// 每份配置写到独立的文件，重载时不会读到写了一半的文件
+	dir := t.TempDir()
+	paths := [2]string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")}
+	for i, content := range []string{configA, configB} {
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := LoadConfig(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var stop atomic.Bool
+	var readers sync.WaitGroup
+	errs := make(chan string, 16)
+	for range 8 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for !stop.Load() {
+				cfg := Get()
+				r := cfg.Retention
+				if !(r.RawHours == 10 && r.HourlyDays == 2) && !(r.RawHours == 20 && r.HourlyDays == 4) {
+					errs <- "torn retention snapshot"
+					return
+				}
+				// server 只在启动时生效，重载时沿用旧值
+				if cfg.Server.Port != 1111 {
+					errs <- "server config changed by reload"
+					return
+				}
+			}
+		}()
+	}
+
+	for i := range 200 {
+		if _, err := Reload(paths[i%2]); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+	}
+	stop.Store(true)
+	readers.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+
+	if got := Get().Retention.RawHours; got != 20 {
+		t.Fatalf("after reloads raw_hours = %d, want the last loaded value 20", got)
+	}
+}
+
+func TestReloadKeepsConfigOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(configA), 0o644)
+	if err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, []byte("retention: [not a map"), 0o644)
+	cfg, err := Reload(path)
+	if err == nil {
+		t.Fatal("Reload of invalid YAML succeeded")
+	}
+	if cfg != Get() || Get().Retention.RawHours != 10 {
+		t.Fatal("invalid reload replaced the current config")
+	}
+}
+
+func TestDatabaseConfigValidation(t *testing.T) {
+	for _, tt := range []struct {
+		yaml string
+		ok   bool
+	}{
+		{"", true},
+		{"database:\n  driver: sqlite\n", true},
+		{"database:\n  driver: postgres\n  dsn: \"host=db user=pinggo dbname=pinggo\"\n", true},
+		{"database:\n  driver: postgres\n", false},
+		{"database:\n  driver: mysql\n  dsn: \"root@/pinggo\"\n", false},
+	} {
+		if _, err := parse([]byte(tt.yaml)); (err == nil) != tt.ok {
+			t.Errorf("parse(%q) error = %v, want ok %v", tt.yaml, err, tt.ok)
+		}
+	}
+}
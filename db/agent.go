@@ -0,0 +1,126 @@
+package db
+
+import (
+	"ping-go/config"
+	"ping-go/model"
+	"time"
+)
+
+// RegionStats 一个来源（中心服务器或某个探针）在一段时间内的统计
+type RegionStats struct {
+	AgentID     uint    `json:"agent_id"`
+	Checks      int64   `json:"checks"`
+	Uptime      float64 `json:"uptime"`       // 0-100，无数据时为 100
+	AvgResponse float64 `json:"avg_response"` // 成功响应的平均延迟（毫秒）
+}
+
+// regionCount 按来源分组的计数
+type regionCount struct {
+	AgentID     uint
+	Total       int64
+	Up          int64
+	SumDuration int64
+}
+
+// AgentMonitors 返回分配给探针的已启用监控项
+func AgentMonitors(agentID uint) ([]model.Monitor, error) {
+	var monitors []model.Monitor
+	err := DB.Where("active = ? AND id IN (?)", 1,
+		DB.Model(&model.AgentMonitor{}).Select("monitor_id").Where("agent_id = ?", agentID)).
+		Order("id").Find(&monitors).Error
+	return monitors, err
+}
+
+// GetRegionStats 按来源统计监控项最近 duration 的可用率和平均响应时间，key 为探针 ID，0 为中心服务器；没有数据的来源不出现在结果中。
+// 原始数据保留时间内直接从心跳计算；更长的时间使用小时聚合（中心服务器）、探针小时聚合和当前小时的原始心跳。
+// 探针只有小时聚合，超过小时数据保留时间的部分不计入
+func GetRegionStats(monitorID uint, duration time.Duration) map[uint]RegionStats {
+	counts := regionCounts(monitorID, duration)
+	result := make(map[uint]RegionStats, len(counts))
+	for id, c := range counts {
+		result[id] = c.stats()
+	}
+	return result
+}
+
+// GetCombinedStats 合并所有来源（中心服务器和各探针）最近 duration 的统计，AgentID 为 0；没有数据时可用率为 100。
+// GetUptimeStats 等只统计中心服务器的检查，合并的统计只在分地区状态中返回
+func GetCombinedStats(monitorID uint, duration time.Duration) RegionStats {
+	var total regionCount
+	for _, c := range regionCounts(monitorID, duration) {
+		total.Total += c.Total
+		total.Up += c.Up
+		total.SumDuration += c.SumDuration
+	}
+	return total.stats()
+}
+
+// stats 计数转换为可用率和平均响应时间
+func (c *regionCount) stats() RegionStats {
+	stats := RegionStats{AgentID: c.AgentID, Checks: c.Total, Uptime: 100}
+	if c.Total > 0 {
+		stats.Uptime = float64(c.Up) / float64(c.Total) * 100
+	}
+	if c.Up > 0 {
+		stats.AvgResponse = float64(c.SumDuration) / float64(c.Up)
+	}
+	return stats
+}
+
+// regionCounts 按来源统计监控项最近 duration 的检查次数、UP 次数和成功响应的延迟总和
+func regionCounts(monitorID uint, duration time.Duration) map[uint]*regionCount {
+	now := time.Now()
+	since := now.Add(-duration)
+	rawHours := config.Get().Retention.RawHours
+	if rawHours <= 0 {
+		rawHours = defaultRawHours
+	}
+
+	counts := make(map[uint]*regionCount)
+	add := func(rows ...regionCount) {
+		for _, r := range rows {
+			c := counts[r.AgentID]
+			if c == nil {
+				c = &regionCount{AgentID: r.AgentID}
+				counts[r.AgentID] = c
+			}
+			c.Total += r.Total
+			c.Up += r.Up
+			c.SumDuration += r.SumDuration
+		}
+	}
+
+	rawSince := since
+	if int(duration.Hours()) > rawHours {
+		currentHour := now.Truncate(time.Hour)
+		var agents []regionCount
+		DB.Model(&model.HeartbeatAgentHourly{}).
+			Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, since, currentHour).
+			Select("agent_id, COALESCE(SUM(total_count), 0) AS total, COALESCE(SUM(up_count), 0) AS up, COALESCE(SUM(sum_duration), 0) AS sum_duration").
+			Group("agent_id").
+			Scan(&agents)
+		var central regionCount
+		DB.Model(&model.HeartbeatHourly{}).
+			Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, since, currentHour).
+			Select("COALESCE(SUM(total_count), 0) AS total, COALESCE(SUM(up_count), 0) AS up, COALESCE(SUM(sum_duration), 0) AS sum_duration").
+			Scan(&central)
+		add(agents...)
+		if central.Total > 0 {
+			add(central)
+		}
+		rawSince = currentHour
+	}
+
+	// 维护中的心跳不计入分母，与 GetUptimeStats 相同
+	var raw []regionCount
+	DB.Model(&model.Heartbeat{}).
+		Where("monitor_id = ? AND time >= ? AND status <> ?", monitorID, rawSince, model.StatusMaintenance).
+		Select("agent_id, COALESCE(SUM(represented_count), 0) AS total, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN represented_count ELSE 0 END), 0) AS up, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN duration * represented_count ELSE 0 END), 0) AS sum_duration",
+			model.StatusUp, model.StatusUp).
+		Group("agent_id").
+		Scan(&raw)
+	add(raw...)
+	return counts
+}
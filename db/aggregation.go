@@ -2,9 +2,12 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"ping-go/config"
 	"ping-go/model"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -33,6 +36,10 @@ func StartAggregationJob(ctx context.Context) {
 
 // runAggregation 执行完整的聚合流程
 func runAggregation() {
+	if !IsJobLeader() {
+		log.Println("Skipping heartbeat aggregation: not the leader instance")
+		return
+	}
 	log.Println("Running heartbeat aggregation...")
 
 	// 1. 聚合过去1小时的原始数据到 HeartbeatHourly
@@ -47,230 +54,385 @@ func runAggregation() {
 	log.Println("Heartbeat aggregation completed")
 }
 
-// aggregateHourly 将原始心跳数据聚合为小时级
+// aggregateHourly 将上一个完整小时的原始心跳数据聚合为小时级
 func aggregateHourly() {
-	// 获取上一个完整小时的时间范围
-	now := time.Now()
-	hourEnd := now.Truncate(time.Hour)
+	hourEnd := time.Now().Truncate(time.Hour)
 	hourStart := hourEnd.Add(-1 * time.Hour)
 
-	// 获取所有 monitor 的 ID
 	var monitorIDs []uint
 	DB.Model(&model.Monitor{}).Pluck("id", &monitorIDs)
 
+	if n := aggregateHourlyWindow(monitorIDs, hourStart, hourEnd, false); n > 0 {
+		log.Printf("Created %d hourly aggregations for hour %s", n, hourStart.Format("2006-01-02 15:04"))
+	}
+}
+
+// aggStats 一个聚合桶的统计
+// 注意：平均延迟只计算成功响应(status=1)的数据，去除失败响应的影响
+type aggStats struct {
+	UpCount     int
+	DownCount   int
+	TotalCount  int
+	SumDuration int64 // 成功响应的延迟总和，用于加权平均计算
+	MinDuration int
+	MaxDuration int
+	hasUp       bool
+}
+
+// uptime 可用率 (使用10000倍存储，0-10000 表示 0.00%-100.00%)
+func (a *aggStats) uptime() int {
+	if a.TotalCount == 0 {
+		return 0
+	}
+	return a.UpCount * 10000 / a.TotalCount
+}
+
+// add 计入一条心跳（represented 次检查），维护期间的心跳不计入可用率
+func (a *aggStats) add(status, duration, represented int) {
+	represented = max(represented, 1)
+	if status == model.StatusMaintenance {
+		return
+	}
+	a.TotalCount += represented
+	switch status {
+	case model.StatusUp:
+		a.UpCount += represented
+		a.SumDuration += int64(duration * represented)
+		if !a.hasUp || duration < a.MinDuration {
+			a.MinDuration = duration
+		}
+		a.MaxDuration = max(a.MaxDuration, duration)
+		a.hasUp = true
+	case model.StatusDown:
+		a.DownCount += represented
+	}
+}
+
+// avgDuration 平均延迟（只基于成功响应）
+func (a *aggStats) avgDuration() int {
+	if a.UpCount == 0 {
+		return 0
+	}
+	return int(a.SumDuration) / a.UpCount
+}
+
+// aggregateHourlyWindow 将 [from, to) 内的原始心跳按小时聚合，只处理完整的小时。
+// replace 为 false 时跳过已聚合的小时（定时任务），为 true 时先删除窗口内已有的小时数据再重建（导入历史数据后）。
+// 返回写入的小时聚合数
+func aggregateHourlyWindow(monitorIDs []uint, from, to time.Time, replace bool) int {
+	from, to = from.Truncate(time.Hour), earlier(to.Truncate(time.Hour), time.Now().Truncate(time.Hour))
+	if !from.Before(to) {
+		return 0
+	}
+
 	aggregatedCount := 0
 	for _, monitorID := range monitorIDs {
-		// 检查是否已聚合（避免重复聚合）
-		var count int64
-		DB.Model(&model.HeartbeatHourly{}).
-			Where("monitor_id = ? AND hour = ?", monitorID, hourStart).
-			Count(&count)
-		if count > 0 {
-			continue // 已聚合，跳过
+		done := make(map[int64]bool)
+		agentDone := make(map[agentHour]bool)
+		if replace {
+			DB.Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, from, to).Delete(&model.HeartbeatHourly{})
+			DB.Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, from, to).Delete(&model.HeartbeatAgentHourly{})
+		} else {
+			// 检查是否已聚合（避免重复聚合）
+			var hours []time.Time
+			DB.Model(&model.HeartbeatHourly{}).
+				Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, from, to).
+				Pluck("hour", &hours)
+			for _, h := range hours {
+				done[h.Unix()] = true
+			}
+			var agentHours []model.HeartbeatAgentHourly
+			DB.Select("agent_id", "hour").
+				Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, from, to).
+				Find(&agentHours)
+			for _, h := range agentHours {
+				agentDone[agentHour{h.AgentID, h.Hour.Unix()}] = true
+			}
 		}
 
-		// 使用 SQL 聚合查询获取统计数据
-		// 注意：平均延迟只计算成功响应(status=1)的数据，去除失败响应的影响
-		type AggResult struct {
-			UpCount     int
-			DownCount   int
-			TotalCount  int
-			SumDuration int64 // 成功响应的延迟总和
-			MinDuration int
-			MaxDuration int
+		rows, err := DB.Model(&model.Heartbeat{}).
+			Select("status, duration, time, represented_count, agent_id").
+			Where("monitor_id = ? AND time >= ? AND time < ?", monitorID, from, to).
+			Order("time").Rows()
+		if err != nil {
+			log.Printf("Failed to read heartbeats for monitor %d: %v", monitorID, err)
+			continue
 		}
-		var result AggResult
-
-		DB.Model(&model.Heartbeat{}).
-			Select(`
-				SUM(CASE WHEN status = 1 THEN 1 ELSE 0 END) as up_count,
-				SUM(CASE WHEN status = 0 THEN 1 ELSE 0 END) as down_count,
-				COUNT(*) as total_count,
-				COALESCE(SUM(CASE WHEN status = 1 THEN duration ELSE 0 END), 0) as sum_duration,
-				COALESCE(MIN(CASE WHEN status = 1 THEN duration ELSE NULL END), 0) as min_duration,
-				COALESCE(MAX(CASE WHEN status = 1 THEN duration ELSE NULL END), 0) as max_duration
-			`).
-			Where("monitor_id = ? AND time >= ? AND time < ?",
-				monitorID, hourStart, hourEnd).
-			Scan(&result)
-
-		if result.TotalCount == 0 {
-			continue // 没有数据，跳过
+		buckets := make(map[int64]*aggStats)
+		// 小时聚合只统计中心服务器的检查，探针上报的心跳按探针单独统计
+		agentBuckets := make(map[agentHour]*aggStats)
+		for rows.Next() {
+			var status, duration, represented int
+			var agentID uint
+			var t time.Time
+			if rows.Scan(&status, &duration, &t, &represented, &agentID) != nil {
+				continue
+			}
+			key := t.Truncate(time.Hour).Unix()
+			if agentID != 0 {
+				k := agentHour{agentID, key}
+				if agentDone[k] {
+					continue
+				}
+				ab := agentBuckets[k]
+				if ab == nil {
+					ab = &aggStats{}
+					agentBuckets[k] = ab
+				}
+				ab.add(status, duration, represented)
+				continue
+			}
+			if done[key] {
+				continue
+			}
+			b := buckets[key]
+			if b == nil {
+				b = &aggStats{}
+				buckets[key] = b
+			}
+			b.add(status, duration, represented)
 		}
-
-		// 计算可用率 (使用10000倍存储，0-10000 表示 0.00%-100.00%)
-		uptime := 0
-		if result.TotalCount > 0 {
-			uptime = result.UpCount * 10000 / result.TotalCount
+		rows.Close()
+
+		hourly := make([]model.HeartbeatHourly, 0, len(buckets))
+		for key, b := range buckets {
+			hourly = append(hourly, model.HeartbeatHourly{
+				MonitorID:   monitorID,
+				Hour:        time.Unix(key, 0),
+				UpCount:     b.UpCount,
+				DownCount:   b.DownCount,
+				TotalCount:  b.TotalCount,
+				SumDuration: int(b.SumDuration), // 存储总和用于日聚合加权平均
+				AvgDuration: b.avgDuration(),
+				MinDuration: b.MinDuration,
+				MaxDuration: b.MaxDuration,
+				Uptime:      b.uptime(),
+			})
 		}
-
-		// 计算平均延迟（只基于成功响应）
-		avgDuration := 0
-		if result.UpCount > 0 {
-			avgDuration = int(result.SumDuration) / result.UpCount
+		if len(hourly) > 0 {
+			if err := DB.CreateInBatches(&hourly, 500).Error; err != nil {
+				log.Printf("Failed to create hourly aggregation for monitor %d: %v", monitorID, err)
+				RaiseAlert(model.AlertSeverityWarning, "Heartbeat aggregation failed",
+					fmt.Sprintf("hourly aggregation for monitor %d: %v", monitorID, err))
+				continue
+			}
+			aggregatedCount += len(hourly)
 		}
 
-		// 保存聚合结果
-		hourly := model.HeartbeatHourly{
-			MonitorID:   monitorID,
-			Hour:        hourStart,
-			UpCount:     result.UpCount,
-			DownCount:   result.DownCount,
-			TotalCount:  result.TotalCount,
-			SumDuration: int(result.SumDuration), // 存储总和用于日聚合加权平均
-			AvgDuration: avgDuration,
-			MinDuration: result.MinDuration,
-			MaxDuration: result.MaxDuration,
-			Uptime:      uptime,
+		if len(agentBuckets) == 0 {
+			continue
 		}
-		if err := DB.Create(&hourly).Error; err != nil {
-			log.Printf("Failed to create hourly aggregation for monitor %d: %v", monitorID, err)
-		} else {
-			aggregatedCount++
+		agentHourly := make([]model.HeartbeatAgentHourly, 0, len(agentBuckets))
+		for key, b := range agentBuckets {
+			agentHourly = append(agentHourly, model.HeartbeatAgentHourly{
+				MonitorID:   monitorID,
+				AgentID:     key.agentID,
+				Hour:        time.Unix(key.hour, 0),
+				UpCount:     b.UpCount,
+				DownCount:   b.DownCount,
+				TotalCount:  b.TotalCount,
+				SumDuration: int(b.SumDuration),
+			})
+		}
+		if err := DB.CreateInBatches(&agentHourly, 500).Error; err != nil {
+			log.Printf("Failed to create agent hourly aggregation for monitor %d: %v", monitorID, err)
 		}
 	}
+	return aggregatedCount
+}
+
+// agentHour 探针小时聚合桶的 key
+type agentHour struct {
+	agentID uint
+	hour    int64
+}
 
-	if aggregatedCount > 0 {
-		log.Printf("Created %d hourly aggregations for hour %s", aggregatedCount, hourStart.Format("2006-01-02 15:04"))
+// earlier 返回两个时间中较早的一个
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
 	}
+	return a
 }
 
-// aggregateDaily 将小时级数据聚合为日级
+// aggregateDaily 将昨天的小时数据聚合为日级
 func aggregateDaily() {
-	// 获取昨天的日期
-	now := time.Now()
-	today := now.Truncate(24 * time.Hour)
+	today := time.Now().Truncate(24 * time.Hour)
 	yesterday := today.Add(-24 * time.Hour)
 
 	var monitorIDs []uint
 	DB.Model(&model.Monitor{}).Pluck("id", &monitorIDs)
 
+	if n := aggregateDailyWindow(monitorIDs, yesterday, today, false); n > 0 {
+		log.Printf("Created %d daily aggregations for date %s", n, yesterday.Format("2006-01-02"))
+	}
+}
+
+// aggregateDailyWindow 将 [from, to) 内的小时数据按天聚合，只处理完整的天，replace 与 aggregateHourlyWindow 相同。
+// 使用 sum_duration 进行加权平均计算，确保平均延迟准确。返回写入的日聚合数
+func aggregateDailyWindow(monitorIDs []uint, from, to time.Time, replace bool) int {
+	from, to = from.Truncate(24*time.Hour), earlier(to.Truncate(24*time.Hour), time.Now().Truncate(24*time.Hour))
+	if !from.Before(to) {
+		return 0
+	}
+
 	aggregatedCount := 0
 	for _, monitorID := range monitorIDs {
-		// 检查是否已聚合
-		var count int64
-		DB.Model(&model.HeartbeatDaily{}).
-			Where("monitor_id = ? AND date = ?", monitorID, yesterday).
-			Count(&count)
-		if count > 0 {
-			continue
-		}
-
-		// 从小时数据聚合
-		// 使用 sum_duration 进行加权平均计算，确保平均延迟准确
-		type AggResult struct {
-			UpCount     int
-			DownCount   int
-			TotalCount  int
-			SumDuration int64 // 成功响应的延迟总和
-			MinDuration int
-			MaxDuration int
-		}
-		var result AggResult
-
-		DB.Model(&model.HeartbeatHourly{}).
-			Select(`
-				COALESCE(SUM(up_count), 0) as up_count,
-				COALESCE(SUM(down_count), 0) as down_count,
-				COALESCE(SUM(total_count), 0) as total_count,
-				COALESCE(SUM(sum_duration), 0) as sum_duration,
-				COALESCE(MIN(min_duration), 0) as min_duration,
-				COALESCE(MAX(max_duration), 0) as max_duration
-			`).
-			Where("monitor_id = ? AND hour >= ? AND hour < ?",
-				monitorID, yesterday, today).
-			Scan(&result)
-
-		if result.TotalCount == 0 {
-			continue
+		done := make(map[int64]bool)
+		if replace {
+			DB.Where("monitor_id = ? AND date >= ? AND date < ?", monitorID, from, to).Delete(&model.HeartbeatDaily{})
+		} else {
+			var dates []time.Time
+			DB.Model(&model.HeartbeatDaily{}).
+				Where("monitor_id = ? AND date >= ? AND date < ?", monitorID, from, to).
+				Pluck("date", &dates)
+			for _, d := range dates {
+				done[d.Unix()] = true
+			}
 		}
 
-		// 计算可用率 (使用10000倍存储)
-		uptime := 0
-		if result.TotalCount > 0 {
-			uptime = result.UpCount * 10000 / result.TotalCount
+		var hours []model.HeartbeatHourly
+		DB.Where("monitor_id = ? AND hour >= ? AND hour < ?", monitorID, from, to).Order("hour").Find(&hours)
+		buckets := make(map[int64]*aggStats)
+		for _, h := range hours {
+			key := h.Hour.Truncate(24 * time.Hour).Unix()
+			if done[key] {
+				continue
+			}
+			b := buckets[key]
+			if b == nil {
+				b = &aggStats{MinDuration: h.MinDuration}
+				buckets[key] = b
+			}
+			b.UpCount += h.UpCount
+			b.DownCount += h.DownCount
+			b.TotalCount += h.TotalCount
+			b.SumDuration += int64(h.SumDuration)
+			b.MinDuration = min(b.MinDuration, h.MinDuration)
+			b.MaxDuration = max(b.MaxDuration, h.MaxDuration)
 		}
 
-		// 计算加权平均延迟（只基于成功响应）
-		avgDuration := 0
-		if result.UpCount > 0 {
-			avgDuration = int(result.SumDuration) / result.UpCount
+		daily := make([]model.HeartbeatDaily, 0, len(buckets))
+		for key, b := range buckets {
+			if b.TotalCount == 0 {
+				continue
+			}
+			daily = append(daily, model.HeartbeatDaily{
+				MonitorID:   monitorID,
+				Date:        time.Unix(key, 0),
+				UpCount:     b.UpCount,
+				DownCount:   b.DownCount,
+				TotalCount:  b.TotalCount,
+				SumDuration: int(b.SumDuration), // 存储总和
+				AvgDuration: b.avgDuration(),
+				MinDuration: b.MinDuration,
+				MaxDuration: b.MaxDuration,
+				Uptime:      b.uptime(),
+			})
 		}
-
-		daily := model.HeartbeatDaily{
-			MonitorID:   monitorID,
-			Date:        yesterday,
-			UpCount:     result.UpCount,
-			DownCount:   result.DownCount,
-			TotalCount:  result.TotalCount,
-			SumDuration: int(result.SumDuration), // 存储总和
-			AvgDuration: avgDuration,
-			MinDuration: result.MinDuration,
-			MaxDuration: result.MaxDuration,
-			Uptime:      uptime,
+		if len(daily) == 0 {
+			continue
 		}
-		if err := DB.Create(&daily).Error; err != nil {
+		if err := DB.CreateInBatches(&daily, 500).Error; err != nil {
 			log.Printf("Failed to create daily aggregation for monitor %d: %v", monitorID, err)
+			RaiseAlert(model.AlertSeverityWarning, "Heartbeat aggregation failed",
+				fmt.Sprintf("daily aggregation for monitor %d: %v", monitorID, err))
 		} else {
-			aggregatedCount++
+			aggregatedCount += len(daily)
 		}
 	}
+	return aggregatedCount
+}
 
-	if aggregatedCount > 0 {
-		log.Printf("Created %d daily aggregations for date %s", aggregatedCount, yesterday.Format("2006-01-02"))
+// cleanupAggregatedData 清理超期的各级数据，返回各级删除的行数
+func cleanupAggregatedData() []RetentionReport {
+	tiers := retentionTiers(config.Get().Retention, time.Now())
+	reports := make([]RetentionReport, 0, len(tiers))
+	for _, t := range tiers {
+		reports = append(reports, deleteExpired(t))
 	}
-}
 
-// cleanupAggregatedData 清理超期的各级数据
-func cleanupAggregatedData() {
-	now := time.Now()
-	retention := config.GlobalConfig.Retention
+	// 清理已删除监控项遗留的心跳数据
+	PurgeOrphanedHeartbeats()
 
-	// 获取保留时间配置（使用默认值如果未配置）
-	rawHours := retention.RawHours
-	if rawHours <= 0 {
-		rawHours = 24 // 默认保留24小时原始数据
-	}
+	// 清理确认超过 30 天的系统告警
+	cleanupAlerts()
+	return reports
+}
 
-	hourlyDays := retention.HourlyDays
-	if hourlyDays <= 0 {
-		hourlyDays = 7 // 默认保留7天小时数据
-	}
+// ForceAggregation 手动触发聚合（可用于 API 调用或迁移）
+func ForceAggregation() {
+	runAggregation()
+}
 
-	dailyDays := retention.DailyDays
-	if dailyDays <= 0 {
-		dailyDays = 365 // 默认保留1年日数据
-	}
+// AggregationReport 按监控项和时间窗口重建聚合的结果
+type AggregationReport struct {
+	MonitorIDs    []uint    `json:"monitor_ids"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	HourlyBuckets int       `json:"hourly_buckets"`
+	DailyBuckets  int       `json:"daily_buckets"`
+}
 
-	// 清理原始心跳数据
-	rawCutoff := now.Add(-time.Duration(rawHours) * time.Hour)
-	result := DB.Where("time < ?", rawCutoff).Delete(&model.Heartbeat{})
-	if result.Error != nil {
-		log.Printf("Failed to cleanup raw heartbeats: %v", result.Error)
-	} else if result.RowsAffected > 0 {
-		log.Printf("Cleaned up %d raw heartbeats (older than %d hours)", result.RowsAffected, rawHours)
-	}
+// ForceAggregationFor 重建指定监控项在 [from, to) 内的小时和日聚合，已有的聚合会被替换。
+// 用于导入历史心跳后立即生成统计，不必等待下一次定时聚合；不要求持有调度租约，也不执行清理
+func ForceAggregationFor(monitorIDs []uint, from, to time.Time) AggregationReport {
+	report := AggregationReport{MonitorIDs: monitorIDs, From: from, To: to}
+	report.HourlyBuckets = aggregateHourlyWindow(monitorIDs, from, to, true)
+	// 日聚合从小时数据计算，窗口扩展到完整的天，边界当天的其余小时也一并计入
+	report.DailyBuckets = aggregateDailyWindow(monitorIDs, from, to.Add(24*time.Hour), true)
+	InvalidateStorageCache()
+	log.Printf("Rebuilt %d hourly and %d daily aggregations for %d monitors (%s - %s)",
+		report.HourlyBuckets, report.DailyBuckets, len(monitorIDs), from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return report
+}
 
-	// 清理小时级数据
-	hourlyCutoff := now.AddDate(0, 0, -hourlyDays)
-	result = DB.Where("hour < ?", hourlyCutoff).Delete(&model.HeartbeatHourly{})
-	if result.Error != nil {
-		log.Printf("Failed to cleanup hourly heartbeats: %v", result.Error)
-	} else if result.RowsAffected > 0 {
-		log.Printf("Cleaned up %d hourly heartbeats (older than %d days)", result.RowsAffected, hourlyDays)
+// aggregationQueue 等待重建聚合的监控项及各自需要重建的最早时间
+var aggregationQueue = struct {
+	sync.Mutex
+	pending map[uint]time.Time
+	running bool
+}{pending: make(map[uint]time.Time)}
+
+// QueueAggregation 排队重建监控项从 from 到现在的聚合（同 ForceAggregationFor），立即返回。
+// 同一时间最多只有一个重建在执行，执行期间排队的监控项合并为下一次重建
+func QueueAggregation(monitorIDs []uint, from time.Time) {
+	q := &aggregationQueue
+	q.Lock()
+	defer q.Unlock()
+	for _, id := range monitorIDs {
+		if t, ok := q.pending[id]; !ok || from.Before(t) {
+			q.pending[id] = from
+		}
 	}
-
-	// 清理日级数据
-	dailyCutoff := now.AddDate(0, 0, -dailyDays)
-	result = DB.Where("date < ?", dailyCutoff).Delete(&model.HeartbeatDaily{})
-	if result.Error != nil {
-		log.Printf("Failed to cleanup daily heartbeats: %v", result.Error)
-	} else if result.RowsAffected > 0 {
-		log.Printf("Cleaned up %d daily heartbeats (older than %d days)", result.RowsAffected, dailyDays)
+	if !q.running && len(q.pending) > 0 {
+		q.running = true
+		go drainAggregationQueue()
 	}
 }
 
-// ForceAggregation 手动触发聚合（可用于 API 调用或迁移）
-func ForceAggregation() {
-	runAggregation()
+// drainAggregationQueue 依次重建排队的监控项，队列为空时退出
+func drainAggregationQueue() {
+	q := &aggregationQueue
+	for {
+		q.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.Unlock()
+			return
+		}
+		monitorIDs := make([]uint, 0, len(q.pending))
+		var from time.Time
+		for id, t := range q.pending {
+			monitorIDs = append(monitorIDs, id)
+			if from.IsZero() || t.Before(from) {
+				from = t
+			}
+		}
+		q.pending = make(map[uint]time.Time)
+		q.Unlock()
+
+		slices.Sort(monitorIDs)
+		ForceAggregationFor(monitorIDs, from, time.Now())
+	}
 }
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"log"
+	"ping-go/model"
+	"time"
+)
+
+// alertRetention 已确认告警的保留时间
+const alertRetention = 30 * 24 * time.Hour
+
+// OnServerAlert 新增或更新告警后的回调（用于推送到管理员 Socket 房间）
+var OnServerAlert func(a *model.ServerAlert)
+
+// RaiseAlert 记录一条系统告警。已存在相同级别和标题的未确认告警时，更新内容并增加计数
+func RaiseAlert(severity, title, body string) {
+	if DB == nil {
+		return
+	}
+
+	// 使用 Find 而不是 First，避免没有记录时打印 record not found 日志
+	var existing []model.ServerAlert
+	err := DB.Where("severity = ? AND title = ? AND acknowledged = ?", severity, title, false).
+		Order("id desc").Limit(1).Find(&existing).Error
+	if err != nil {
+		log.Printf("Failed to query server alerts: %v", err)
+		return
+	}
+
+	var alert model.ServerAlert
+	if len(existing) > 0 {
+		alert = existing[0]
+		alert.Count++
+		alert.Body = body
+		err = DB.Model(&alert).Select("Count", "Body", "UpdatedAt").Updates(&alert).Error
+	} else {
+		alert = model.ServerAlert{Severity: severity, Title: title, Body: body, Count: 1}
+		err = DB.Create(&alert).Error
+	}
+	if err != nil {
+		log.Printf("Failed to save server alert %q: %v", title, err)
+		return
+	}
+
+	if OnServerAlert != nil {
+		OnServerAlert(&alert)
+	}
+}
+
+// GetServerAlerts 返回告警列表，最近出现的在前
+func GetServerAlerts(includeAcknowledged bool, limit int) ([]model.ServerAlert, error) {
+	var alerts []model.ServerAlert
+	q := DB.Order("updated_at desc").Limit(limit)
+	if !includeAcknowledged {
+		q = q.Where("acknowledged = ?", false)
+	}
+	err := q.Find(&alerts).Error
+	return alerts, err
+}
+
+// AcknowledgeAlert 将告警标记为已确认
+func AcknowledgeAlert(id uint) error {
+	now := time.Now()
+	return DB.Model(&model.ServerAlert{}).Where("id = ?", id).
+		Updates(map[string]any{"acknowledged": true, "acknowledged_at": &now}).Error
+}
+
+// cleanupAlerts 删除确认超过 30 天的告警
+func cleanupAlerts() {
+	result := DB.Where("acknowledged = ? AND acknowledged_at < ?", true, time.Now().Add(-alertRetention)).Delete(&model.ServerAlert{})
+	if result.Error != nil {
+		log.Printf("Failed to cleanup server alerts: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Cleaned up %d acknowledged server alerts", result.RowsAffected)
+	}
+}
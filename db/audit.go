@@ -0,0 +1,21 @@
+package db
+
+import (
+	"log"
+	"ping-go/model"
+)
+
+// RecordAudit 记录一条管理操作审计日志，写入失败只记录日志不影响操作本身
+func RecordAudit(actor, action, target, detail string) {
+	entry := model.AuditLog{Actor: actor, Action: action, Target: target, Detail: detail}
+	if err := DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record audit log (%s %s %s): %v", actor, action, target, err)
+	}
+}
+
+// RecentAuditLogs 返回最近的审计日志，按时间倒序
+func RecentAuditLogs(limit int) ([]model.AuditLog, error) {
+	var logs []model.AuditLog
+	err := DB.Order("id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"ping-go/config"
 	"ping-go/model"
+	"sync/atomic"
 	"time"
 
 	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -28,30 +31,33 @@ const (
 var (
 	heartbeatBuffer *HeartbeatBuffer
 	cleanupCancel   context.CancelFunc
+	// droppedHeartbeats 自上次上报以来因缓冲区满而丢弃的心跳数
+	droppedHeartbeats atomic.Uint64
+	// droppedHeartbeatsTotal 进程启动以来因缓冲区满而丢弃的心跳数
+	droppedHeartbeatsTotal atomic.Uint64
 )
 
-func Init(dbPath string) error {
-	var err error
-	// Enable WAL mode
-	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", dbPath)
-	DB, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to connect database: %w", err)
+// HeartbeatBufferDepth 返回心跳写入缓冲区中等待写入的心跳数和缓冲区容量
+func HeartbeatBufferDepth() (depth, capacity int) {
+	if b := heartbeatBuffer; b != nil {
+		return len(b.buffer), cap(b.buffer)
 	}
+	return 0, HeartbeatBufferSize
+}
 
-	// Optimize connection pool for SQLite
-	sqlDB, err := DB.DB()
+// HeartbeatsDropped 返回进程启动以来因缓冲区满而丢弃的心跳数
+func HeartbeatsDropped() uint64 {
+	return droppedHeartbeatsTotal.Load()
+}
+
+// Init 打开数据库并迁移表结构，启动心跳写入缓冲和聚合任务。
+// 配置中 database.driver 为 postgres 时连接 database.dsn，否则打开 SQLite 数据库文件 dbPath
+func Init(dbPath string) error {
+	var err error
+	DB, err = open(config.Get().Database, dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to get sqlDB: %w", err)
+		return err
 	}
-	// SQLite supports one writer at a time, but WAL allows concurrent readers.
-	// However, GORM/database/sql manages connection pooling.
-	// MaxOpenConns(1) is safest for simple SQLite usage to avoid "database is locked"
-	// if we strictly want serialization, but with WAL we can increase it slightly.
-	// Since we use a single writer routine (heartbeat buffer), we can set this higher for reads.
-	sqlDB.SetMaxOpenConns(25) // Allow more for concurrent reads
-	sqlDB.SetMaxIdleConns(5)
-	sqlDB.SetConnMaxLifetime(1 * time.Hour)
 
 	// Auto Migrate - 包含聚合表
 	err = DB.AutoMigrate(
@@ -63,17 +69,37 @@ func Init(dbPath string) error {
 		&model.Heartbeat{},
 		&model.HeartbeatHourly{},
 		&model.HeartbeatDaily{},
+		&model.ServerAlert{},
+		&model.Instance{},
+		&model.Lease{},
+		&model.APIKey{},
+		&model.MaintenanceWindow{},
+		&model.AuditLog{},
+		&model.NotificationLog{},
+		&model.NotificationState{},
+		&model.StatusPage{},
+		&model.StatusPageMonitor{},
+		&model.MonitorGroup{},
+		&model.Incident{},
+		&model.StatusEvent{},
+		&model.Subscription{},
+		&model.Agent{},
+		&model.AgentMonitor{},
+		&model.HeartbeatAgentHourly{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
+	if err := purgeSoftDeletedMonitors(); err != nil {
+		return fmt.Errorf("failed to purge deleted monitors: %w", err)
+	}
 
 	// Init Buffer
 	heartbeatBuffer = &HeartbeatBuffer{
 		buffer: make(chan *model.Heartbeat, HeartbeatBufferSize),
 		done:   make(chan struct{}),
 	}
-	go runHeartbeatBuffer(HeartbeatBatchSize, HeartbeatFlushInterval)
+	go runHeartbeatBuffer(heartbeatBuffer, HeartbeatBatchSize, HeartbeatFlushInterval)
 
 	// Start Aggregation Job (包含聚合和清理)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -83,14 +109,81 @@ func Init(dbPath string) error {
 	return nil
 }
 
-func runHeartbeatBuffer(batchSize int, flushInterval time.Duration) {
+// open 按配置连接数据库并设置连接池
+func open(cfg config.DatabaseConfig, sqlitePath string) (*gorm.DB, error) {
+	if cfg.Driver == config.DatabasePostgres {
+		conn, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect database: %w", err)
+		}
+		sqlDB, err := conn.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sqlDB: %w", err)
+		}
+		// 多个实例共享同一个 PostgreSQL，每个实例的连接数不宜过多
+		sqlDB.SetMaxOpenConns(10)
+		sqlDB.SetMaxIdleConns(5)
+		sqlDB.SetConnMaxLifetime(30 * time.Minute)
+		return conn, nil
+	}
+
+	// Enable WAL mode
+	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", sqlitePath)
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	// Optimize connection pool for SQLite
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sqlDB: %w", err)
+	}
+	// SQLite supports one writer at a time, but WAL allows concurrent readers.
+	// However, GORM/database/sql manages connection pooling.
+	// MaxOpenConns(1) is safest for simple SQLite usage to avoid "database is locked"
+	// if we strictly want serialization, but with WAL we can increase it slightly.
+	// Since we use a single writer routine (heartbeat buffer), we can set this higher for reads.
+	sqlDB.SetMaxOpenConns(25) // Allow more for concurrent reads
+	sqlDB.SetMaxIdleConns(5)
+	sqlDB.SetConnMaxLifetime(1 * time.Hour)
+	return conn, nil
+}
+
+// isPostgres 当前连接的是否为 PostgreSQL，用于少数依赖方言的查询
+func isPostgres() bool {
+	return DB.Dialector.Name() == "postgres"
+}
+
+// ResetIDSequences 按显式 ID 写入记录后，把 PostgreSQL 的自增序列推进到表中最大 ID 之后，
+// 否则之后新建的记录会和已有 ID 冲突。SQLite 的自增 ID 总是取当前最大值之后，不需要处理
+func ResetIDSequences(tx *gorm.DB, models ...any) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %q", table, table)
+		if err := tx.Exec(query).Error; err != nil {
+			return fmt.Errorf("reset %s id sequence: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// runHeartbeatBuffer 持有 b 本身而不是读取全局变量，FlushHeartbeatBuffer 把全局变量置空后仍能正常退出
+func runHeartbeatBuffer(b *HeartbeatBuffer, batchSize int, flushInterval time.Duration) {
 	batch := make([]*model.Heartbeat, 0, batchSize)
 	ticker := time.NewTicker(flushInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case h := <-heartbeatBuffer.buffer:
+		case h := <-b.buffer:
 			batch = append(batch, h)
 			if len(batch) >= batchSize {
 				flushHeartbeats(batch)
@@ -101,7 +194,11 @@ func runHeartbeatBuffer(batchSize int, flushInterval time.Duration) {
 				flushHeartbeats(batch)
 				batch = batch[:0]
 			}
-		case <-heartbeatBuffer.done:
+			if n := droppedHeartbeats.Swap(0); n > 0 {
+				RaiseAlert(model.AlertSeverityWarning, "Heartbeat buffer full",
+					fmt.Sprintf("%d heartbeats were dropped because the write buffer was full; the database may be too slow", n))
+			}
+		case <-b.done:
 			if len(batch) > 0 {
 				flushHeartbeats(batch)
 			}
@@ -113,6 +210,8 @@ func runHeartbeatBuffer(batchSize int, flushInterval time.Duration) {
 func flushHeartbeats(batch []*model.Heartbeat) {
 	if err := DB.CreateInBatches(batch, HeartbeatBatchSize).Error; err != nil {
 		log.Printf("Failed to flush heartbeats: %v", err)
+		RaiseAlert(model.AlertSeverityCritical, "Failed to write heartbeats",
+			fmt.Sprintf("%d heartbeats lost: %v", len(batch), err))
 	}
 }
 
@@ -125,6 +224,8 @@ func AddHeartbeat(h *model.Heartbeat) {
 	case heartbeatBuffer.buffer <- h:
 	default:
 		log.Println("Heartbeat buffer full, dropping")
+		droppedHeartbeats.Add(1)
+		droppedHeartbeatsTotal.Add(1)
 	}
 }
 
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"ping-go/model"
+	"strings"
+	"time"
+)
+
+// timezoneSettingKey 设置项：按本地时间统计时使用的时区（IANA 名称，如 Asia/Shanghai），为空使用服务器时区
+const timezoneSettingKey = "timezone"
+
+// 失败热力图统计的天数
+const (
+	DefaultHeatmapDays = 90
+	MaxHeatmapDays     = 365
+)
+
+// Location 返回设置的时区，未设置或无法识别时使用服务器本地时区
+func Location() *time.Location {
+	var setting model.Setting
+	if DB == nil || DB.Where("key = ?", timezoneSettingKey).Limit(1).Find(&setting).Error != nil {
+		return time.Local
+	}
+	name := strings.TrimSpace(setting.Value)
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// FailureHeatmap 按星期和小时（本地时间）统计的 DOWN 次数
+// Down/Checks 的下标为 [weekday][hour]，weekday 0 为周日（与 time.Weekday 一致）
+type FailureHeatmap struct {
+	MonitorID uint         `json:"monitor_id"` // 0 表示全部监控项
+	Days      int          `json:"days"`
+	Timezone  string       `json:"timezone"`
+	Since     time.Time    `json:"since"`
+	DataFrom  *time.Time   `json:"data_from,omitempty"` // 实际有数据的最早小时，受 retention.hourly_days 限制可能晚于 Since
+	Down      [7][24]int64 `json:"down"`
+	Checks    [7][24]int64 `json:"checks"` // 各时段的检查总数，用于计算失败率
+	TotalDown int64        `json:"total_down"`
+}
+
+// GetFailureHeatmap 从小时聚合数据统计最近 days 天每个 星期×小时 时段的 DOWN 次数，按时区 loc 的本地时间分桶。
+// monitorID 为 0 时合并统计全部监控项。当前小时尚未聚合，不计入
+func GetFailureHeatmap(monitorID uint, days int, loc *time.Location) FailureHeatmap {
+	since := time.Now().Truncate(time.Hour).AddDate(0, 0, -days)
+	heatmap := FailureHeatmap{MonitorID: monitorID, Days: days, Timezone: loc.String(), Since: since}
+
+	var rows []struct {
+		Hour  time.Time
+		Down  int64
+		Total int64
+	}
+	q := DB.Model(&model.HeartbeatHourly{}).
+		Select("hour, SUM(down_count) AS down, SUM(total_count) AS total").
+		Where("hour >= ?", since)
+	if monitorID != 0 {
+		q = q.Where("monitor_id = ?", monitorID)
+	}
+	q.Group("hour").Order("hour").Scan(&rows)
+
+	for _, r := range rows {
+		local := r.Hour.In(loc)
+		wd, h := local.Weekday(), local.Hour()
+		heatmap.Down[wd][h] += r.Down
+		heatmap.Checks[wd][h] += r.Total
+		heatmap.TotalDown += r.Down
+	}
+	if len(rows) > 0 {
+		from := rows[0].Hour
+		heatmap.DataFrom = &from
+	}
+	return heatmap
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"ping-go/config"
+	"ping-go/model"
+	"time"
+)
+
+// AuditActionIncidentAck 确认事件的审计动作，detail 为确认时填写的备注
+const AuditActionIncidentAck = "incident.ack"
+
+// MonitorAuditTarget 监控项在审计日志中的 target
+func MonitorAuditTarget(monitorID uint) string {
+	return fmt.Sprintf("monitor:%d", monitorID)
+}
+
+// GetHeartbeatsAround 返回 at 前后 window 内的心跳，按时间升序，以及使用的数据层级（raw 或 hourly）。
+// 窗口早于原始数据保留期或原始数据已被清理时改用小时聚合数据
+func GetHeartbeatsAround(monitorID uint, at time.Time, window time.Duration) ([]map[string]any, string) {
+	rawHours := config.Get().Retention.RawHours
+	if rawHours <= 0 {
+		rawHours = defaultRawHours
+	}
+	from, to := at.Add(-window), at.Add(window)
+
+	if !from.Before(time.Now().Add(-time.Duration(rawHours) * time.Hour)) {
+		var heartbeats []model.Heartbeat
+		DB.Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND time <= ?", monitorID, from, to).
+			Order("time ASC").
+			Find(&heartbeats)
+		if len(heartbeats) > 0 {
+			return rawHeartbeatRows(heartbeats), "raw"
+		}
+	}
+
+	var hourly []model.HeartbeatHourly
+	DB.Where("monitor_id = ? AND hour >= ? AND hour <= ?", monitorID, from.Truncate(time.Hour), to).
+		Order("hour ASC").
+		Find(&hourly)
+	return hourlyHeartbeatRows(hourly), "hourly"
+}
+
+// IncidentStart 返回 at 所在宕机的开始时间：at 之前最后一次 UP 之后的第一次 DOWN。
+// 没有原始心跳可供判断时返回 at
+func IncidentStart(monitorID uint, at time.Time) time.Time {
+	query := DB.Model(&model.Heartbeat{}).Where("monitor_id = ? AND agent_id = 0 AND status = ? AND time <= ?", monitorID, model.StatusDown, at)
+	var lastUp model.Heartbeat
+	DB.Where("monitor_id = ? AND agent_id = 0 AND status = ? AND time <= ?", monitorID, model.StatusUp, at).
+		Order("time DESC").Limit(1).Find(&lastUp)
+	if lastUp.ID != 0 {
+		query = query.Where("time > ?", lastUp.Time)
+	}
+	var first model.Heartbeat
+	if query.Order("time ASC").Limit(1).Find(&first).Error != nil || first.ID == 0 {
+		return at
+	}
+	return first.Time
+}
+
+// LongestOutage 返回 since 之后最长的一次宕机的开始时间和时长：从一次 DOWN 到之后的第一次 UP，
+// 仍未恢复时算到现在；PENDING 等状态不结束宕机。只使用原始心跳，没有宕机时时长为 0
+func LongestOutage(monitorID uint, since time.Time) (time.Time, time.Duration) {
+	var heartbeats []model.Heartbeat
+	DB.Select("status", "time").
+		Where("monitor_id = ? AND agent_id = 0 AND time >= ?", monitorID, since).
+		Order("time ASC").
+		Find(&heartbeats)
+
+	var start, longestStart time.Time
+	var longest time.Duration
+	end := func(at time.Time) {
+		if !start.IsZero() && at.Sub(start) > longest {
+			longestStart, longest = start, at.Sub(start)
+		}
+		start = time.Time{}
+	}
+	for _, h := range heartbeats {
+		switch h.Status {
+		case model.StatusDown:
+			if start.IsZero() {
+				start = h.Time
+			}
+		case model.StatusUp:
+			end(h.Time)
+		}
+	}
+	end(time.Now())
+	return longestStart, longest
+}
+
+// GetIncidentAck 返回 since 之后对监控项的最近一次事件确认，没有时返回 nil
+func GetIncidentAck(monitorID uint, since time.Time) *model.AuditLog {
+	var entry model.AuditLog
+	err := DB.Where("action = ? AND target = ? AND created_at >= ?", AuditActionIncidentAck, MonitorAuditTarget(monitorID), since).
+		Order("id DESC").Limit(1).Find(&entry).Error
+	if err != nil || entry.ID == 0 {
+		return nil
+	}
+	return &entry
+}
+
+// MonitorAuditLogs 返回监控项 before 之前的最近审计记录（维护窗口、事件确认等），按时间倒序
+func MonitorAuditLogs(monitorID uint, before time.Time, limit int) ([]model.AuditLog, error) {
+	var logs []model.AuditLog
+	err := DB.Where("target = ? AND created_at <= ?", MonitorAuditTarget(monitorID), before).
+		Order("id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"ping-go/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ActiveIncidents 返回进行中的事件，最新的在前
+func ActiveIncidents() ([]model.Incident, error) {
+	var incidents []model.Incident
+	err := DB.Where("status <> ?", model.IncidentResolved).Order("created_at DESC, id DESC").Find(&incidents).Error
+	return incidents, err
+}
+
+// Incidents 返回最近的 limit 个事件（包括已解决的），最新的在前
+func Incidents(limit int) ([]model.Incident, error) {
+	var incidents []model.Incident
+	err := DB.Order("created_at DESC, id DESC").Limit(limit).Find(&incidents).Error
+	return incidents, err
+}
+
+// ResolvedIncidentsSince 返回 since 之后解决的事件，最近解决的在前
+func ResolvedIncidentsSince(since time.Time) ([]model.Incident, error) {
+	var incidents []model.Incident
+	err := DB.Where("status = ? AND resolved_at >= ?", model.IncidentResolved, since).
+		Order("resolved_at DESC, id DESC").Find(&incidents).Error
+	return incidents, err
+}
+
+// SaveIncident 创建（ID 为 0）或更新事件。不存在的监控项 ID 和重复的 ID 会被忽略；
+// 状态改为 resolved 时记录解决时间，已解决的事件重新打开时清除解决时间
+func SaveIncident(incident *model.Incident) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if len(incident.MonitorIDs) > 0 {
+			if err := tx.Model(&model.Monitor{}).Where("id IN ?", incident.MonitorIDs).Pluck("id", &existing).Error; err != nil {
+				return err
+			}
+		}
+		valid := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			valid[id] = true
+		}
+		ids := make([]uint, 0, len(existing))
+		for _, id := range incident.MonitorIDs {
+			if valid[id] {
+				ids = append(ids, id)
+				delete(valid, id)
+			}
+		}
+		incident.MonitorIDs = ids
+
+		if incident.ID == 0 {
+			if incident.Status == model.IncidentResolved {
+				now := time.Now()
+				incident.ResolvedAt = &now
+			}
+			return tx.Create(incident).Error
+		}
+
+		var current model.Incident
+		if err := tx.First(&current, incident.ID).Error; err != nil {
+			return err
+		}
+		incident.CreatedBy, incident.CreatedAt = current.CreatedBy, current.CreatedAt
+		switch {
+		case incident.Status != model.IncidentResolved:
+			incident.ResolvedAt = nil
+		case current.ResolvedAt != nil:
+			incident.ResolvedAt = current.ResolvedAt
+		default:
+			now := time.Now()
+			incident.ResolvedAt = &now
+		}
+		return tx.Model(incident).Select("Title", "Body", "Severity", "Status", "MonitorIDs", "ResolvedAt").Updates(incident).Error
+	})
+}
+
+// ResolveIncident 把事件标记为已解决并记录解决时间，已解决的事件保持原解决时间
+func ResolveIncident(id uint) (*model.Incident, error) {
+	var incident model.Incident
+	if err := DB.First(&incident, id).Error; err != nil {
+		return nil, err
+	}
+	if incident.Status == model.IncidentResolved {
+		return &incident, nil
+	}
+	now := time.Now()
+	incident.Status, incident.ResolvedAt = model.IncidentResolved, &now
+	err := DB.Model(&incident).Select("Status", "ResolvedAt").Updates(&incident).Error
+	return &incident, err
+}
+
+// DeleteIncident 删除事件
+func DeleteIncident(id uint) error {
+	result := DB.Delete(&model.Incident{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"ping-go/config"
+	"ping-go/model"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// leaderCheck 多实例模式下判断当前实例是否为领导者，为 nil 时视为单实例
+var leaderCheck atomic.Pointer[func() bool]
+
+// SetLeaderCheck 设置领导者判断函数，聚合与清理任务只在领导者上执行
+func SetLeaderCheck(fn func() bool) {
+	leaderCheck.Store(&fn)
+}
+
+// IsJobLeader 返回当前实例是否应执行后台任务。
+// 启用多实例模式但尚未设置判断函数时（启动阶段）返回 false，避免所有实例同时执行
+func IsJobLeader() bool {
+	if fn := leaderCheck.Load(); fn != nil {
+		return (*fn)()
+	}
+	return !config.Get().HA.Enabled
+}
+
+// AcquireLease 尝试获取或续期租约：租约不存在、已过期或本来就由 holder 持有时成功
+func AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expires := now.Add(ttl)
+
+	result := DB.Model(&model.Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]any{"holder": holder, "expires_at": expires})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// 租约记录不存在时插入；并发插入只有一个会成功
+	result = DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.Lease{Name: name, Holder: holder, ExpiresAt: expires})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// HoldsLease 返回 holder 当前是否持有未过期的租约
+func HoldsLease(name, holder string) (bool, error) {
+	var count int64
+	err := DB.Model(&model.Lease{}).
+		Where("name = ? AND holder = ? AND expires_at >= ?", name, holder, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ReleaseLease 主动释放租约（正常退出时调用），其他实例无需等待过期即可接管
+func ReleaseLease(name, holder string) error {
+	return DB.Model(&model.Lease{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Update("expires_at", time.Unix(0, 0)).Error
+}
+
+// GetLeaseHolder 返回租约当前的持有者，租约不存在或已过期时返回空字符串
+func GetLeaseHolder(name string) (string, error) {
+	var leases []model.Lease
+	err := DB.Where("name = ? AND expires_at >= ?", name, time.Now()).Limit(1).Find(&leases).Error
+	if err != nil || len(leases) == 0 {
+		return "", err
+	}
+	return leases[0].Holder, nil
+}
+
+// TouchInstance 登记或刷新实例信息
+func TouchInstance(inst *model.Instance) error {
+	inst.LastSeen = time.Now()
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hostname", "role", "last_seen"}),
+	}).Create(inst).Error
+}
+
+// GetInstances 返回最近 maxAge 内有心跳的实例
+func GetInstances(maxAge time.Duration) ([]model.Instance, error) {
+	var instances []model.Instance
+	err := DB.Where("last_seen >= ?", time.Now().Add(-maxAge)).Order("id").Find(&instances).Error
+	return instances, err
+}
+
+// RemoveStaleInstances 删除超过 maxAge 没有心跳的实例登记
+func RemoveStaleInstances(maxAge time.Duration) error {
+	return DB.Where("last_seen < ?", time.Now().Add(-maxAge)).Delete(&model.Instance{}).Error
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"ping-go/config"
+	"ping-go/model"
+	"testing"
+	"time"
+)
+
+// 租约同一时间只由一个实例持有：持有者可以续期，过期或主动释放后其他实例才能接管
+func TestLeaseFailover(t *testing.T) {
+	openTestDB(t, filepath.Join(t.TempDir(), "pinggo.db"))
+	testLeaseFailover(t, "scheduler")
+}
+
+// 跨主机部署时多个实例共享 PostgreSQL，租约语义必须相同。
+// 需要一个可写的测试库：PINGGO_TEST_POSTGRES_DSN="host=localhost user=postgres dbname=pinggo_test sslmode=disable"
+func TestLeaseFailoverPostgres(t *testing.T) {
+	dsn := os.Getenv("PINGGO_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("PINGGO_TEST_POSTGRES_DSN not set")
+	}
+	conn, err := open(config.DatabaseConfig{Driver: config.DatabasePostgres, DSN: dsn}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := DB
+	DB = conn
+	t.Cleanup(func() {
+		DB.Where("name = ?", "test-scheduler").Delete(&model.Lease{})
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+		DB = old
+	})
+	if err := DB.AutoMigrate(&model.Lease{}); err != nil {
+		t.Fatal(err)
+	}
+	if !isPostgres() {
+		t.Fatalf("dialect = %s, want postgres", DB.Dialector.Name())
+	}
+	DB.Where("name = ?", "test-scheduler").Delete(&model.Lease{})
+	testLeaseFailover(t, "test-scheduler")
+}
+
+func testLeaseFailover(t *testing.T, name string) {
+	t.Helper()
+	const ttl = 200 * time.Millisecond
+
+	acquire := func(holder string, want bool) {
+		t.Helper()
+		ok, err := AcquireLease(name, holder, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Fatalf("AcquireLease(%s) = %v, want %v", holder, ok, want)
+		}
+	}
+	assertHolder := func(want string) {
+		t.Helper()
+		holder, err := GetLeaseHolder(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if holder != want {
+			t.Fatalf("lease holder = %q, want %q", holder, want)
+		}
+		for _, h := range []string{"node-a", "node-b"} {
+			if held, _ := HoldsLease(name, h); held != (h == want) {
+				t.Fatalf("HoldsLease(%s) = %v with holder %q", h, held, want)
+			}
+		}
+	}
+
+	acquire("node-a", true)
+	acquire("node-b", false)
+	acquire("node-a", true) // 续期
+	assertHolder("node-a")
+
+	// node-a 失联：租约过期前 node-b 不能接管，过期后接管，node-a 恢复后不能再发送通知
+	time.Sleep(ttl + 50*time.Millisecond)
+	assertHolder("")
+	acquire("node-b", true)
+	acquire("node-a", false)
+	assertHolder("node-b")
+
+	// 主动释放后无需等待过期
+	if err := ReleaseLease(name, "node-b"); err != nil {
+		t.Fatal(err)
+	}
+	assertHolder("")
+	acquire("node-a", true)
+	assertHolder("node-a")
+}
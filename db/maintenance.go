@@ -0,0 +1,183 @@
+package db
+
+import (
+	"ping-go/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateMaintenance 为每个监控项创建 [start, end) 的一次性维护窗口。
+// 与同一监控项未关闭且时间重叠（或首尾相接）的一次性窗口合并为一个：取最早开始、最晚结束，原因用 "; " 拼接。
+// 返回每个监控项合并后的窗口
+func CreateMaintenance(monitorIDs []uint, start, end time.Time, reason, createdBy string) ([]model.MaintenanceWindow, error) {
+	var windows []model.MaintenanceWindow
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, id := range monitorIDs {
+			var overlapping []model.MaintenanceWindow
+			if err := tx.Where("monitor_id = ? AND COALESCE(cron, '') = '' AND ended_at IS NULL AND ends_at >= ? AND starts_at <= ?", id, start, end).
+				Order("starts_at").Find(&overlapping).Error; err != nil {
+				return err
+			}
+
+			if len(overlapping) == 0 {
+				w := model.MaintenanceWindow{MonitorID: id, StartsAt: start, EndsAt: end, Reason: reason, CreatedBy: createdBy}
+				if err := tx.Create(&w).Error; err != nil {
+					return err
+				}
+				windows = append(windows, w)
+				continue
+			}
+
+			merged := overlapping[0]
+			reasons := []string{}
+			var extra []uint
+			for i, w := range overlapping {
+				if w.StartsAt.Before(merged.StartsAt) {
+					merged.StartsAt = w.StartsAt
+				}
+				if w.EndsAt.After(merged.EndsAt) {
+					merged.EndsAt = w.EndsAt
+				}
+				reasons = appendReason(reasons, w.Reason)
+				if i > 0 {
+					extra = append(extra, w.ID)
+				}
+			}
+			if start.Before(merged.StartsAt) {
+				merged.StartsAt = start
+			}
+			if end.After(merged.EndsAt) {
+				merged.EndsAt = end
+			}
+			merged.Reason = strings.Join(appendReason(reasons, reason), "; ")
+
+			if err := tx.Model(&merged).Select("StartsAt", "EndsAt", "Reason").Updates(&merged).Error; err != nil {
+				return err
+			}
+			if len(extra) > 0 {
+				if err := tx.Delete(&model.MaintenanceWindow{}, extra).Error; err != nil {
+					return err
+				}
+			}
+			windows = append(windows, merged)
+		}
+		return nil
+	})
+	return windows, err
+}
+
+// appendReason 追加非空且未出现过的原因
+func appendReason(reasons []string, reason string) []string {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return reasons
+	}
+	for _, r := range reasons {
+		if r == reason {
+			return reasons
+		}
+	}
+	return append(reasons, reason)
+}
+
+// EndMaintenance 提前关闭维护窗口：结束时间改为当前时间。窗口已结束时原样返回
+func EndMaintenance(id uint) (*model.MaintenanceWindow, error) {
+	var w model.MaintenanceWindow
+	if err := DB.First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if w.EndedAt != nil || !w.EndsAt.After(now) {
+		return &w, nil
+	}
+	if now.Before(w.StartsAt) {
+		w.StartsAt = now
+	}
+	w.EndsAt, w.EndedAt = now, &now
+	if err := DB.Model(&w).Select("StartsAt", "EndsAt", "EndedAt").Updates(&w).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// ActiveMaintenance 返回 t 时刻作用于监控项的维护窗口及本次维护的结束时间，没有时返回 nil。
+// 同时有多个窗口生效时取结束最晚的一个。过期的窗口自然失效，无需清理
+func ActiveMaintenance(m model.Monitor, t time.Time) (*model.MaintenanceWindow, time.Time) {
+	q := DB.Where("starts_at <= ? AND ends_at > ?", t, t)
+	if tags := model.SplitTags(m.Tags); len(tags) > 0 {
+		q = q.Where("monitor_id = ? OR (monitor_id = 0 AND tag IN ?)", m.ID, tags)
+	} else {
+		q = q.Where("monitor_id = ?", m.ID)
+	}
+	var candidates []model.MaintenanceWindow
+	if err := q.Find(&candidates).Error; err != nil {
+		return nil, time.Time{}
+	}
+	var active *model.MaintenanceWindow
+	var until time.Time
+	for i := range candidates {
+		if end, ok := candidates[i].ActiveUntil(t); ok && end.After(until) {
+			active, until = &candidates[i], end
+		}
+	}
+	return active, until
+}
+
+// PendingMaintenance 返回尚未结束的维护窗口（包括未开始的），服务启动时用于重新安排到期检查
+func PendingMaintenance(t time.Time) []model.MaintenanceWindow {
+	var windows []model.MaintenanceWindow
+	DB.Where("ends_at > ?", t).Find(&windows)
+	return windows
+}
+
+// MaintenanceWindows 返回结束时间晚于 since 的维护窗口（未结束的和最近结束的），最新创建的在前
+func MaintenanceWindows(since time.Time) ([]model.MaintenanceWindow, error) {
+	var windows []model.MaintenanceWindow
+	err := DB.Where("ends_at > ?", since).Order("id DESC").Find(&windows).Error
+	return windows, err
+}
+
+// SaveMaintenanceWindow 创建（ID 为 0）或更新维护窗口，更新时保留创建者
+func SaveMaintenanceWindow(w *model.MaintenanceWindow) error {
+	if w.ID == 0 {
+		return DB.Create(w).Error
+	}
+	var old model.MaintenanceWindow
+	if err := DB.First(&old, w.ID).Error; err != nil {
+		return err
+	}
+	w.CreatedBy, w.CreatedAt, w.EndedAt = old.CreatedBy, old.CreatedAt, nil
+	return DB.Model(w).Select("MonitorID", "Tag", "StartsAt", "EndsAt", "Cron", "DurationMinutes", "Timezone", "Reason", "EndedAt").
+		Updates(w).Error
+}
+
+// DeleteMaintenanceWindow 删除维护窗口并返回被删除的窗口，用于恢复受影响监控项的状态
+func DeleteMaintenanceWindow(id uint) (*model.MaintenanceWindow, error) {
+	var w model.MaintenanceWindow
+	if err := DB.First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	if err := DB.Delete(&w).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// MaintenanceMonitors 返回维护窗口作用的监控项：指定监控项时为该监控项，按标签时为当前带有该标签的全部监控项
+func MaintenanceMonitors(w model.MaintenanceWindow) []model.Monitor {
+	var monitors []model.Monitor
+	if w.MonitorID != 0 {
+		DB.Where("id = ?", w.MonitorID).Find(&monitors)
+		return monitors
+	}
+	var all []model.Monitor
+	DB.Where("tags LIKE ?", "%"+w.Tag+"%").Order("id").Find(&all)
+	for _, m := range all {
+		if w.Applies(m) {
+			monitors = append(monitors, m)
+		}
+	}
+	return monitors
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"log"
+	"ping-go/model"
+
+	"gorm.io/gorm"
+)
+
+// heartbeatPurgeBatchSize 分批删除心跳数据时每批的行数
+// SQLite 同一时间只允许一个写者，分批删除可以避免长时间持有写锁阻塞心跳写入
+const heartbeatPurgeBatchSize = 5000
+
+// DeleteMonitor 硬删除监控项及其全部心跳数据（原始、小时聚合、日聚合），并从状态页中移除
+// 监控项采用硬删除语义：删除后同名监控项可以立即重新创建，不会残留无法恢复的记录
+func DeleteMonitor(monitorID uint) error {
+	if err := DeleteMonitorRecords(DB, monitorID); err != nil {
+		return err
+	}
+	return PurgeMonitorHeartbeats(monitorID)
+}
+
+// DeleteMonitorRecords 在 tx 中删除监控项、状态页中的引用、探针分配和状态变化记录，不包含心跳数据。
+// 心跳数据量大，由调用方在事务提交后通过 PurgeMonitorHeartbeats 分批清理。
+// 使用 Unscoped 明确硬删除：即使监控项表带有 deleted_at 列，也不会留下同名的残留行和清理不掉的孤儿心跳
+func DeleteMonitorRecords(tx *gorm.DB, monitorID uint) error {
+	if err := tx.Unscoped().Delete(&model.Monitor{}, monitorID).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("monitor_id = ?", monitorID).Delete(&model.StatusPageMonitor{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("monitor_id = ?", monitorID).Delete(&model.AgentMonitor{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("monitor_id = ?", monitorID).Delete(&model.StatusEvent{}).Error
+}
+
+// PurgeMonitorHeartbeats 分批清理指定监控项的所有心跳及聚合数据
+func PurgeMonitorHeartbeats(monitorID uint) error {
+	tables := []any{&model.Heartbeat{}, &model.HeartbeatHourly{}, &model.HeartbeatDaily{}, &model.HeartbeatAgentHourly{}}
+	for _, table := range tables {
+		if err := purgeInBatches(table, "monitor_id = ?", monitorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeSoftDeletedMonitors 启动迁移：监控项表带有 deleted_at 列时（软删除留下的旧库），
+// 清理 deleted_at 非空的监控项及其通知状态和心跳
+func purgeSoftDeletedMonitors() error {
+	if !DB.Migrator().HasColumn(&model.Monitor{}, "deleted_at") {
+		return nil
+	}
+	var ids []uint
+	if err := DB.Unscoped().Model(&model.Monitor{}).Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := DeleteMonitor(id); err != nil {
+			return err
+		}
+		if err := DeleteNotificationStates(0, id); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("Purged %d soft-deleted monitors", len(ids))
+	}
+	return nil
+}
+
+// PurgeOrphanedHeartbeats 清理引用已删除监控项的心跳数据
+// 删除监控项时缓冲区中可能仍有未落盘的心跳，它们会在删除之后写入，由该函数兜底清理
+func PurgeOrphanedHeartbeats() {
+	tables := []any{&model.Heartbeat{}, &model.HeartbeatHourly{}, &model.HeartbeatDaily{}, &model.HeartbeatAgentHourly{}}
+	for _, table := range tables {
+		if err := purgeInBatches(table, "monitor_id NOT IN (SELECT id FROM monitors)"); err != nil {
+			log.Printf("Failed to purge orphaned heartbeats: %v", err)
+		}
+	}
+}
+
+// purgeInBatches 按批次删除满足条件的行，直到没有剩余数据
+func purgeInBatches(table any, query string, args ...any) error {
+	for {
+		var ids []uint
+		if err := DB.Model(table).Where(query, args...).Limit(heartbeatPurgeBatchSize).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := DB.Where("id IN ?", ids).Delete(table).Error; err != nil {
+			return err
+		}
+		if len(ids) < heartbeatPurgeBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"errors"
+	"ping-go/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrGroupNameTaken 分组名称已被其他分组使用
+var ErrGroupNameTaken = errors.New("group name is already used by another group")
+
+// MonitorGroups 返回全部分组，按 Weight 降序、名称升序排列
+func MonitorGroups() ([]model.MonitorGroup, error) {
+	var groups []model.MonitorGroup
+	err := DB.Order("weight DESC, name").Find(&groups).Error
+	return groups, err
+}
+
+// SaveMonitorGroup 创建（ID 为 0）或更新分组的名称和权重
+func SaveMonitorGroup(group *model.MonitorGroup) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.MonitorGroup{}).Where("name = ? AND id <> ?", group.Name, group.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrGroupNameTaken
+		}
+		if group.ID == 0 {
+			return tx.Create(group).Error
+		}
+		result := tx.Model(group).Select("Name", "Weight").Updates(group)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(group, group.ID).Error
+	})
+}
+
+// DeleteMonitorGroup 删除分组，其中的监控项变为未分组（不会被删除），返回受影响的监控项数
+func DeleteMonitorGroup(id uint) (int64, error) {
+	var moved int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.MonitorGroup{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		result = tx.Model(&model.Monitor{}).Where("group_id = ?", id).
+			Updates(map[string]any{"group_id": 0, "version": gorm.Expr("version + 1")})
+		moved = result.RowsAffected
+		return result.Error
+	})
+	return moved, err
+}
+
+// AssignMonitorGroup 把监控项移入分组，groupID 为 0 时移出分组；返回实际更新的监控项数
+func AssignMonitorGroup(groupID uint, monitorIDs []uint) (int64, error) {
+	if len(monitorIDs) == 0 {
+		return 0, nil
+	}
+	var affected int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if groupID != 0 {
+			var count int64
+			if err := tx.Model(&model.MonitorGroup{}).Where("id = ?", groupID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		result := tx.Model(&model.Monitor{}).Where("id IN ? AND group_id <> ?", monitorIDs, groupID).
+			Updates(map[string]any{"group_id": groupID, "version": gorm.Expr("version + 1")})
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
+
+// MonitorGroupExists 分组是否存在，groupID 为 0（未分组）时总是返回 true
+func MonitorGroupExists(groupID uint) bool {
+	if groupID == 0 {
+		return true
+	}
+	var count int64
+	DB.Model(&model.MonitorGroup{}).Where("id = ?", groupID).Count(&count)
+	return count > 0
+}
+
+// EnsureMonitorGroup 在事务中按名称查找分组，不存在时创建，用于按分组名称导入监控项
+func EnsureMonitorGroup(tx *gorm.DB, name string) (uint, error) {
+	var group model.MonitorGroup
+	if err := tx.Where("name = ?", name).Limit(1).Find(&group).Error; err != nil {
+		return 0, err
+	}
+	if group.ID != 0 {
+		return group.ID, nil
+	}
+	group = model.MonitorGroup{Name: name, Weight: 2000}
+	err := tx.Create(&group).Error
+	return group.ID, err
+}
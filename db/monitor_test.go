@@ -0,0 +1,113 @@
+package db
+
+import (
+	"path/filepath"
+	"ping-go/model"
+	"testing"
+	"time"
+)
+
+// openTestDB 在 t.TempDir() 中初始化数据库，测试结束时关闭
+func openTestDB(t *testing.T, path string) {
+	t.Helper()
+	if err := Init(path); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(Close)
+}
+
+func countRows(t *testing.T, table any, query string, args ...any) int64 {
+	t.Helper()
+	var n int64
+	if err := DB.Unscoped().Model(table).Where(query, args...).Count(&n).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+func TestDeleteMonitorIsHardDelete(t *testing.T) {
+	openTestDB(t, filepath.Join(t.TempDir(), "pinggo.db"))
+
+	m := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: "http://example.com", Interval: 60}
+	if err := DB.Create(&m).Error; err != nil {
+		t.Fatal(err)
+	}
+	DB.Create(&model.Heartbeat{MonitorID: m.ID, Status: model.StatusUp, Time: time.Now()})
+	DB.Create(&model.StatusPageMonitor{StatusPageID: 1, MonitorID: m.ID})
+
+	if err := DeleteMonitor(m.ID); err != nil {
+		t.Fatalf("DeleteMonitor: %v", err)
+	}
+	if n := countRows(t, &model.Monitor{}, "id = ?", m.ID); n != 0 {
+		t.Fatalf("monitor row still present after delete (%d rows, soft delete?)", n)
+	}
+	if n := countRows(t, &model.Heartbeat{}, "monitor_id = ?", m.ID); n != 0 {
+		t.Fatalf("%d heartbeats left after delete", n)
+	}
+	if n := countRows(t, &model.StatusPageMonitor{}, "monitor_id = ?", m.ID); n != 0 {
+		t.Fatalf("%d status page references left after delete", n)
+	}
+
+	again := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: "http://example.com", Interval: 60}
+	if err := DB.Create(&again).Error; err != nil {
+		t.Fatalf("recreate with the same name: %v", err)
+	}
+	if n := countRows(t, &model.Monitor{}, "name = ?", "api"); n != 1 {
+		t.Fatalf("got %d monitors named api after recreate, want 1", n)
+	}
+}
+
+func TestPurgeOrphanedHeartbeatsAfterDelete(t *testing.T) {
+	openTestDB(t, filepath.Join(t.TempDir(), "pinggo.db"))
+
+	m := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: "http://example.com", Interval: 60}
+	DB.Create(&m)
+	if err := DeleteMonitor(m.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 删除后才从缓冲区落盘的心跳
+	DB.Create(&model.Heartbeat{MonitorID: m.ID, Status: model.StatusUp, Time: time.Now()})
+	DB.Create(&model.HeartbeatHourly{MonitorID: m.ID, Hour: time.Now().Truncate(time.Hour)})
+
+	PurgeOrphanedHeartbeats()
+	if n := countRows(t, &model.Heartbeat{}, "monitor_id = ?", m.ID); n != 0 {
+		t.Fatalf("%d orphaned heartbeats left", n)
+	}
+	if n := countRows(t, &model.HeartbeatHourly{}, "monitor_id = ?", m.ID); n != 0 {
+		t.Fatalf("%d orphaned hourly rows left", n)
+	}
+}
+
+func TestInitPurgesSoftDeletedMonitors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pinggo.db")
+	if err := Init(path); err != nil {
+		t.Fatal(err)
+	}
+	kept := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: "http://example.com", Interval: 60}
+	DB.Create(&kept)
+	// 软删除留下的同名行：旧库的监控项表带有 deleted_at 列
+	stale := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: "http://example.com", Interval: 60}
+	DB.Create(&stale)
+	if err := DB.Exec("ALTER TABLE monitors ADD COLUMN deleted_at datetime").Error; err != nil {
+		t.Fatal(err)
+	}
+	DB.Exec("UPDATE monitors SET deleted_at = ? WHERE id = ?", time.Now(), stale.ID)
+	DB.Create(&model.Heartbeat{MonitorID: stale.ID, Status: model.StatusDown, Time: time.Now()})
+	DB.Create(&model.HeartbeatHourly{MonitorID: stale.ID, Hour: time.Now().Truncate(time.Hour)})
+	DB.Create(&model.StatusEvent{MonitorID: stale.ID, Status: model.StatusDown, Time: time.Now()})
+	DB.Create(&model.StatusPageMonitor{StatusPageID: 1, MonitorID: stale.ID})
+	Close()
+
+	openTestDB(t, path)
+	if n := countRows(t, &model.Monitor{}, "id = ?", stale.ID); n != 0 {
+		t.Fatal("soft-deleted monitor survived the startup purge")
+	}
+	for _, table := range []any{&model.Heartbeat{}, &model.HeartbeatHourly{}, &model.StatusEvent{}, &model.StatusPageMonitor{}} {
+		if n := countRows(t, table, "monitor_id = ?", stale.ID); n != 0 {
+			t.Fatalf("%T: %d rows of the soft-deleted monitor left", table, n)
+		}
+	}
+	if n := countRows(t, &model.Monitor{}, "id = ?", kept.ID); n != 1 {
+		t.Fatal("live monitor was purged")
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"log"
+	"ping-go/model"
+	"time"
+)
+
+// NotificationLogPageSize 发送记录每页的默认条数和上限
+const (
+	NotificationLogPageSize    = 50
+	MaxNotificationLogPageSize = 200
+)
+
+// NotificationLogFilter 发送记录的查询条件，零值表示不过滤
+type NotificationLogFilter struct {
+	RuleID    uint
+	MonitorID uint
+	Channel   string
+	Kind      string
+	Success   *bool
+	Since     time.Time
+	Until     time.Time
+	Page      int // 从 1 开始
+	PageSize  int
+}
+
+// RecordNotificationLog 记录一次通知发送，写入失败只记录日志
+func RecordNotificationLog(entry *model.NotificationLog) {
+	if DB == nil {
+		return
+	}
+	if err := DB.Create(entry).Error; err != nil {
+		log.Printf("Failed to record notification log (%s %s): %v", entry.Channel, entry.Subject, err)
+	}
+}
+
+// NotificationLogs 按条件分页查询发送记录，最新的在前，同时返回满足条件的总数
+func NotificationLogs(f NotificationLogFilter) ([]model.NotificationLog, int64, error) {
+	q := DB.Model(&model.NotificationLog{})
+	if f.RuleID != 0 {
+		q = q.Where("rule_id = ?", f.RuleID)
+	}
+	if f.MonitorID != 0 {
+		q = q.Where("monitor_id = ?", f.MonitorID)
+	}
+	if f.Channel != "" {
+		q = q.Where("channel = ?", f.Channel)
+	}
+	if f.Kind != "" {
+		q = q.Where("kind = ?", f.Kind)
+	}
+	if f.Success != nil {
+		q = q.Where("success = ?", *f.Success)
+	}
+	if !f.Since.IsZero() {
+		q = q.Where("created_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		q = q.Where("created_at < ?", f.Until)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if f.PageSize <= 0 {
+		f.PageSize = NotificationLogPageSize
+	}
+	if f.PageSize > MaxNotificationLogPageSize {
+		f.PageSize = MaxNotificationLogPageSize
+	}
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	var logs []model.NotificationLog
+	err := q.Order("id DESC").Limit(f.PageSize).Offset((f.Page - 1) * f.PageSize).Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"ping-go/model"
+
+	"gorm.io/gorm/clause"
+)
+
+// LoadNotificationStates 返回保存的全部通知状态
+func LoadNotificationStates() ([]model.NotificationState, error) {
+	var states []model.NotificationState
+	err := DB.Find(&states).Error
+	return states, err
+}
+
+// SaveNotificationStates 写入（覆盖）通知状态
+func SaveNotificationStates(states []model.NotificationState) error {
+	if len(states) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&states).Error
+}
+
+// DeleteNotificationStates 删除规则或监控项的通知状态，ruleID 或 monitorID 为 0 表示不限
+func DeleteNotificationStates(ruleID, monitorID uint) error {
+	q := DB.Where("1 = 1")
+	if ruleID != 0 {
+		q = q.Where("rule_id = ?", ruleID)
+	}
+	if monitorID != 0 {
+		q = q.Where("monitor_id = ?", monitorID)
+	}
+	return q.Delete(&model.NotificationState{}).Error
+}
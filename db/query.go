@@ -1,18 +1,49 @@
 package db
 
 import (
+	"errors"
+	"fmt"
 	"ping-go/config"
 	"ping-go/model"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
-// GetHeartbeatsWithTimeRange 根据时间范围智能选择合适的数据源
-// 这是分层查询的核心函数，自动根据查询时间范围选取最优数据源：
+// GetHeartbeatsWithTimeRange 根据时间范围智能选择合适的数据源，返回范围内的全部数据
+// 需要分页或按状态过滤时使用 GetHeartbeatsPage
+func GetHeartbeatsWithTimeRange(monitorID uint, hours int) ([]map[string]any, string) {
+	page, _ := GetHeartbeatsPage(monitorID, hours, HeartbeatQuery{})
+	return page.Data, page.DataType
+}
+
+// HeartbeatQuery 心跳查询的分页和过滤条件，零值表示返回全部
+type HeartbeatQuery struct {
+	Limit  int    // 每页条数，0 表示不限制
+	Offset int    // 跳过的条数，设置了 Cursor 时忽略
+	Cursor string // 上一页的 NextCursor，从该条之后继续；翻页期间有新心跳写入也不会重复或遗漏
+	Status *int   // 只返回该状态，聚合数据按等效状态（可用率是否低于 50%）过滤
+}
+
+// HeartbeatPage 心跳分页查询的结果
+type HeartbeatPage struct {
+	Data       []map[string]any
+	DataType   string // raw / hourly / daily
+	Total      int64  // 时间范围和状态过滤后的总条数，不受分页影响
+	HasMore    bool
+	NextCursor string // 下一页的游标，没有更多数据时为空
+}
+
+// GetHeartbeatsPage 分层查询的核心函数，自动根据查询时间范围选取最优数据源：
 // - 24小时内: 查询原始心跳数据 (最高精度)
 // - 7天内: 查询小时级聚合数据
 // - 7天以上: 查询日级聚合数据
-func GetHeartbeatsWithTimeRange(monitorID uint, hours int) ([]map[string]any, string) {
-	retention := config.GlobalConfig.Retention
+// hours <= 0 表示不限时间范围，只查原始数据。结果按时间倒序，时间相同时按 ID 倒序，保证分页稳定；
+// 游标无效时返回 ErrInvalidCursor
+func GetHeartbeatsPage(monitorID uint, hours int, q HeartbeatQuery) (HeartbeatPage, error) {
+	retention := config.Get().Retention
 
 	rawHours := retention.RawHours
 	if rawHours <= 0 {
@@ -24,48 +55,156 @@ func GetHeartbeatsWithTimeRange(monitorID uint, hours int) ([]map[string]any, st
 		hourlyDays = 7
 	}
 
+	var page HeartbeatPage
+	var tx *gorm.DB
+	var timeCol string
 	if hours <= rawHours {
 		// 原始数据
-		return getRawHeartbeats(monitorID, hours), "raw"
-	} else if hours <= hourlyDays*24 {
-		// 小时聚合数据
-		return getHourlyHeartbeats(monitorID, hours), "hourly"
+		page.DataType, timeCol = "raw", "time"
+		tx = DB.Model(&model.Heartbeat{}).Where("monitor_id = ? AND agent_id = 0", monitorID)
+		if hours > 0 {
+			tx = tx.Where("time > ?", time.Now().Add(-time.Duration(hours)*time.Hour))
+		}
+		if q.Status != nil {
+			tx = tx.Where("status = ?", *q.Status)
+		}
 	} else {
-		// 日聚合数据
-		return getDailyHeartbeats(monitorID, hours), "daily"
+		if hours <= hourlyDays*24 {
+			// 小时聚合数据
+			page.DataType, timeCol = "hourly", "hour"
+			tx = DB.Model(&model.HeartbeatHourly{}).
+				Where("monitor_id = ? AND hour > ?", monitorID, time.Now().Add(-time.Duration(hours)*time.Hour))
+		} else {
+			// 日聚合数据
+			days := hours / 24
+			page.DataType, timeCol = "daily", "date"
+			tx = DB.Model(&model.HeartbeatDaily{}).
+				Where("monitor_id = ? AND date > ?", monitorID, time.Now().AddDate(0, 0, -days))
+		}
+		if q.Status != nil {
+			switch *q.Status {
+			case model.StatusDown:
+				tx = tx.Where("uptime < ?", 5000)
+			case model.StatusUp:
+				tx = tx.Where("uptime >= ?", 5000)
+			default:
+				// 聚合数据只有 UP / DOWN 两种等效状态
+				tx = tx.Where("1 = 0")
+			}
+		}
+	}
+	tx = tx.Session(&gorm.Session{})
+
+	if err := tx.Count(&page.Total).Error; err != nil {
+		return page, err
 	}
+
+	query := tx.Order(timeCol + " DESC").Order("id DESC")
+	if q.Cursor != "" {
+		at, id, err := parseHeartbeatCursor(q.Cursor)
+		if err != nil {
+			return page, err
+		}
+		query = query.Where(timeCol+" < ? OR ("+timeCol+" = ? AND id < ?)", at, at, id)
+	} else if q.Offset > 0 {
+		query = query.Offset(q.Offset)
+	}
+	if q.Limit > 0 {
+		// 多取一条用于判断是否还有下一页
+		query = query.Limit(q.Limit + 1)
+	}
+
+	var keys []heartbeatKey
+	switch page.DataType {
+	case "raw":
+		var heartbeats []model.Heartbeat
+		if err := query.Find(&heartbeats).Error; err != nil {
+			return page, err
+		}
+		if q.Limit > 0 && len(heartbeats) > q.Limit {
+			heartbeats, page.HasMore = heartbeats[:q.Limit], true
+		}
+		page.Data = rawHeartbeatRows(heartbeats)
+		for _, h := range heartbeats {
+			keys = append(keys, heartbeatKey{h.Time, h.ID})
+		}
+	case "hourly":
+		var heartbeats []model.HeartbeatHourly
+		if err := query.Find(&heartbeats).Error; err != nil {
+			return page, err
+		}
+		if q.Limit > 0 && len(heartbeats) > q.Limit {
+			heartbeats, page.HasMore = heartbeats[:q.Limit], true
+		}
+		page.Data = hourlyHeartbeatRows(heartbeats)
+		for _, h := range heartbeats {
+			keys = append(keys, heartbeatKey{h.Hour, h.ID})
+		}
+	default:
+		var heartbeats []model.HeartbeatDaily
+		if err := query.Find(&heartbeats).Error; err != nil {
+			return page, err
+		}
+		if q.Limit > 0 && len(heartbeats) > q.Limit {
+			heartbeats, page.HasMore = heartbeats[:q.Limit], true
+		}
+		page.Data = dailyHeartbeatRows(heartbeats)
+		for _, h := range heartbeats {
+			keys = append(keys, heartbeatKey{h.Date, h.ID})
+		}
+	}
+	if page.HasMore {
+		page.NextCursor = keys[len(keys)-1].cursor()
+	}
+	return page, nil
 }
 
-// getRawHeartbeats 获取原始心跳数据
-func getRawHeartbeats(monitorID uint, hours int) []map[string]any {
-	var heartbeats []model.Heartbeat
-	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
-	DB.Where("monitor_id = ? AND time > ?", monitorID, cutoff).
-		Order("time DESC").
-		Find(&heartbeats)
+// ErrInvalidCursor 心跳分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// heartbeatKey 分页排序键（时间 + ID）
+type heartbeatKey struct {
+	at time.Time
+	id uint
+}
 
+// cursor 编码为 "<Unix 纳秒>_<ID>"
+func (k heartbeatKey) cursor() string {
+	return fmt.Sprintf("%d_%d", k.at.UnixNano(), k.id)
+}
+
+// parseHeartbeatCursor 解析 heartbeatKey.cursor 生成的游标
+func parseHeartbeatCursor(cursor string) (time.Time, uint, error) {
+	ns, id, ok := strings.Cut(cursor, "_")
+	n, err1 := strconv.ParseInt(ns, 10, 64)
+	i, err2 := strconv.ParseUint(id, 10, 64)
+	if !ok || err1 != nil || err2 != nil {
+		return time.Time{}, 0, fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+	}
+	return time.Unix(0, n), uint(i), nil
+}
+
+// rawHeartbeatRows 原始心跳转换为接口返回的格式，agent_id 为上报的探针（0 为中心服务器）
+func rawHeartbeatRows(heartbeats []model.Heartbeat) []map[string]any {
 	results := make([]map[string]any, len(heartbeats))
 	for i, h := range heartbeats {
 		results[i] = map[string]any{
-			"monitorID": h.MonitorID,
-			"status":    h.Status,
-			"msg":       h.Message,
-			"time":      h.Time.Format(time.RFC3339),
-			"duration":  h.Duration,
-			"type":      "raw",
+			"id":         h.ID,
+			"monitorID":  h.MonitorID,
+			"status":     h.Status,
+			"status_key": model.StatusKey(h.Status),
+			"msg":        h.Message,
+			"time":       h.Time.Format(time.RFC3339),
+			"duration":   h.Duration,
+			"type":       "raw",
+			"agent_id":   h.AgentID,
 		}
 	}
 	return results
 }
 
-// getHourlyHeartbeats 获取小时级聚合数据
-func getHourlyHeartbeats(monitorID uint, hours int) []map[string]any {
-	var heartbeats []model.HeartbeatHourly
-	cutoff := time.Now().Add(-time.Duration(hours) * time.Hour)
-	DB.Where("monitor_id = ? AND hour > ?", monitorID, cutoff).
-		Order("hour DESC").
-		Find(&heartbeats)
-
+// hourlyHeartbeatRows 小时聚合数据转换为接口返回的格式
+func hourlyHeartbeatRows(heartbeats []model.HeartbeatHourly) []map[string]any {
 	results := make([]map[string]any, len(heartbeats))
 	for i, h := range heartbeats {
 		// 根据可用率计算等效状态 (5000 = 50%)
@@ -77,6 +216,7 @@ func getHourlyHeartbeats(monitorID uint, hours int) []map[string]any {
 		results[i] = map[string]any{
 			"monitorID":   h.MonitorID,
 			"status":      status,
+			"status_key":  model.StatusKey(status),
 			"time":        h.Hour.Format(time.RFC3339),
 			"duration":    h.AvgDuration,
 			"uptime":      float64(h.Uptime) / 100.0, // 转换为百分比显示
@@ -91,19 +231,8 @@ func getHourlyHeartbeats(monitorID uint, hours int) []map[string]any {
 	return results
 }
 
-// getDailyHeartbeats 获取日级聚合数据
-func getDailyHeartbeats(monitorID uint, hours int) []map[string]any {
-	days := hours / 24
-	if days < 1 {
-		days = 1
-	}
-
-	var heartbeats []model.HeartbeatDaily
-	cutoff := time.Now().AddDate(0, 0, -days)
-	DB.Where("monitor_id = ? AND date > ?", monitorID, cutoff).
-		Order("date DESC").
-		Find(&heartbeats)
-
+// dailyHeartbeatRows 日聚合数据转换为接口返回的格式
+func dailyHeartbeatRows(heartbeats []model.HeartbeatDaily) []map[string]any {
 	results := make([]map[string]any, len(heartbeats))
 	for i, h := range heartbeats {
 		status := model.StatusUp
@@ -114,6 +243,7 @@ func getDailyHeartbeats(monitorID uint, hours int) []map[string]any {
 		results[i] = map[string]any{
 			"monitorID":   h.MonitorID,
 			"status":      status,
+			"status_key":  model.StatusKey(status),
 			"time":        h.Date.Format(time.RFC3339),
 			"duration":    h.AvgDuration,
 			"uptime":      float64(h.Uptime) / 100.0, // 转换为百分比显示
@@ -136,7 +266,7 @@ func GetUptimeStats(monitorID uint, duration time.Duration) float64 {
 	since := now.Add(-duration)
 	currentHour := now.Truncate(time.Hour)
 
-	retention := config.GlobalConfig.Retention
+	retention := config.Get().Retention
 	rawHours := retention.RawHours
 	if rawHours <= 0 {
 		rawHours = 24
@@ -148,18 +278,21 @@ func GetUptimeStats(monitorID uint, duration time.Duration) float64 {
 
 	if hours <= rawHours {
 		// 原始数据范围内：直接从 Heartbeat 表精确计算
+		// 按 represented_count 计数，采样模式下被抑制的检查也计入；维护中的心跳不计入分母
 		var totalCount, upCount int64
 		DB.Model(&model.Heartbeat{}).
-			Where("monitor_id = ? AND time >= ?", monitorID, since).
-			Count(&totalCount)
+			Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status <> ?", monitorID, since, model.StatusMaintenance).
+			Select("COALESCE(SUM(represented_count), 0)").
+			Row().Scan(&totalCount)
 
 		if totalCount == 0 {
 			return 100.0 // 无数据时默认100%
 		}
 
 		DB.Model(&model.Heartbeat{}).
-			Where("monitor_id = ? AND time >= ? AND status = ?", monitorID, since, model.StatusUp).
-			Count(&upCount)
+			Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status = ?", monitorID, since, model.StatusUp).
+			Select("COALESCE(SUM(represented_count), 0)").
+			Row().Scan(&upCount)
 
 		return float64(upCount) / float64(totalCount) * 100.0
 	}
@@ -175,11 +308,13 @@ func GetUptimeStats(monitorID uint, duration time.Duration) float64 {
 	// 2. 从原始表获取当前小时（未聚合）的数据
 	var currentUpCount, currentTotalCount int64
 	DB.Model(&model.Heartbeat{}).
-		Where("monitor_id = ? AND time >= ?", monitorID, currentHour).
-		Count(&currentTotalCount)
+		Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status <> ?", monitorID, currentHour, model.StatusMaintenance).
+		Select("COALESCE(SUM(represented_count), 0)").
+		Row().Scan(&currentTotalCount)
 	DB.Model(&model.Heartbeat{}).
-		Where("monitor_id = ? AND time >= ? AND status = ?", monitorID, currentHour, model.StatusUp).
-		Count(&currentUpCount)
+		Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status = ?", monitorID, currentHour, model.StatusUp).
+		Select("COALESCE(SUM(represented_count), 0)").
+		Row().Scan(&currentUpCount)
 
 	// 3. 合并计算
 	totalUp := hourlyUpCount + currentUpCount
@@ -192,13 +327,52 @@ func GetUptimeStats(monitorID uint, duration time.Duration) float64 {
 	return float64(totalUp) / float64(totalCount) * 100.0
 }
 
+// GetUptimeStatsBatch 返回多个监控项最近 duration 的可用率，无数据时为 100。
+// 在原始数据保留时间内用一次分组查询计算，否则逐个调用 GetUptimeStats
+func GetUptimeStatsBatch(monitorIDs []uint, duration time.Duration) map[uint]float64 {
+	result := make(map[uint]float64, len(monitorIDs))
+	rawHours := config.Get().Retention.RawHours
+	if rawHours <= 0 {
+		rawHours = 24
+	}
+	if int(duration.Hours()) > rawHours {
+		for _, id := range monitorIDs {
+			result[id] = GetUptimeStats(id, duration)
+		}
+		return result
+	}
+
+	for _, id := range monitorIDs {
+		result[id] = 100.0
+	}
+	if len(monitorIDs) == 0 {
+		return result
+	}
+	var rows []struct {
+		MonitorID uint
+		Total     int64
+		Up        int64
+	}
+	DB.Model(&model.Heartbeat{}).
+		Where("monitor_id IN ? AND agent_id = 0 AND time >= ? AND status <> ?", monitorIDs, time.Now().Add(-duration), model.StatusMaintenance).
+		Select("monitor_id, COALESCE(SUM(represented_count), 0) AS total, COALESCE(SUM(CASE WHEN status = ? THEN represented_count ELSE 0 END), 0) AS up", model.StatusUp).
+		Group("monitor_id").
+		Scan(&rows)
+	for _, r := range rows {
+		if r.Total > 0 {
+			result[r.MonitorID] = float64(r.Up) / float64(r.Total) * 100.0
+		}
+	}
+	return result
+}
+
 // GetAvgResponseTime 获取指定时间范围的平均响应时间
 // 只统计成功响应(status=1)的延迟数据
 func GetAvgResponseTime(monitorID uint, duration time.Duration) float64 {
 	since := time.Now().Add(-duration)
 	hours := int(duration.Hours())
 
-	retention := config.GlobalConfig.Retention
+	retention := config.Get().Retention
 	rawHours := retention.RawHours
 	if rawHours <= 0 {
 		rawHours = 24
@@ -212,8 +386,8 @@ func GetAvgResponseTime(monitorID uint, duration time.Duration) float64 {
 		// 原始数据：只统计成功响应(status=1)的延迟
 		var avg float64
 		DB.Model(&model.Heartbeat{}).
-			Where("monitor_id = ? AND time >= ? AND status = ? AND duration > 0", monitorID, since, model.StatusUp).
-			Select("COALESCE(AVG(duration), 0)").
+			Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status = ? AND duration > 0", monitorID, since, model.StatusUp).
+			Select("COALESCE(SUM(duration * represented_count) * 1.0 / SUM(represented_count), 0)").
 			Row().Scan(&avg)
 		return avg
 	} else if hours <= hourlyDays*24 {
@@ -246,11 +420,12 @@ func GetAvgResponseTime(monitorID uint, duration time.Duration) float64 {
 // ChartDataPoint 图表数据点结构
 // 用于返回给前端的图表数据
 type ChartDataPoint struct {
-	Time     string  `json:"time"`     // 时间标签
-	Duration int     `json:"duration"` // 响应时间（毫秒）
-	Status   int     `json:"status"`   // 状态 1=正常 0=异常
-	Uptime   float64 `json:"uptime"`   // 可用率（0-100），仅聚合数据有
-	IsLive   bool    `json:"isLive"`   // 是否是实时数据（最近未聚合的点）
+	Time      string  `json:"time"`       // 时间标签
+	Duration  int     `json:"duration"`   // 响应时间（毫秒）
+	Status    int     `json:"status"`     // 状态 1=正常 0=异常
+	StatusKey string  `json:"status_key"` // 状态的规范 key（up/down/...），见 model.StatusKey
+	Uptime    float64 `json:"uptime"`     // 可用率（0-100），仅聚合数据有
+	IsLive    bool    `json:"isLive"`     // 是否是实时数据（最近未聚合的点）
 }
 
 // GetChartData 获取图表数据
@@ -262,16 +437,18 @@ func GetChartData(monitorID uint, view string) []ChartDataPoint {
 	now := time.Now()
 	currentHour := now.Truncate(time.Hour)
 
-	if view == "24h" {
-		// 24小时视图：24个小时采样点
-		return getChartData24h(monitorID, now, currentHour)
-	} else if view == "7d" {
+	var points []ChartDataPoint
+	if view == "7d" {
 		// 7天视图：28个6小时采样点
-		return getChartData7d(monitorID, now, currentHour)
+		points = getChartData7d(monitorID, now, currentHour)
+	} else {
+		// 24小时视图（默认）：24个小时采样点
+		points = getChartData24h(monitorID, now, currentHour)
 	}
-
-	// 默认返回24小时视图
-	return getChartData24h(monitorID, now, currentHour)
+	for i := range points {
+		points[i].StatusKey = model.StatusKey(points[i].Status)
+	}
+	return points
 }
 
 // getChartData24h 获取24小时图表数据
@@ -320,7 +497,7 @@ func getChartData24h(monitorID uint, now time.Time, currentHour time.Time) []Cha
 			points[i] = ChartDataPoint{
 				Time:     hourTime.Format(time.RFC3339),
 				Duration: 0,
-				Status:   -1, // 表示无数据
+				Status:   model.StatusNoData,
 				Uptime:   100,
 				IsLive:   false,
 			}
@@ -399,7 +576,7 @@ func getChartData7d(monitorID uint, now time.Time, currentHour time.Time) []Char
 			points[i] = ChartDataPoint{
 				Time:     slotStartTime.Format(time.RFC3339),
 				Duration: 0,
-				Status:   -1,
+				Status:   model.StatusNoData,
 				Uptime:   100,
 				IsLive:   false,
 			}
@@ -417,7 +594,7 @@ func getChartData7d(monitorID uint, now time.Time, currentHour time.Time) []Char
 func getCurrentHourPoint(monitorID uint, currentHour time.Time, now time.Time) ChartDataPoint {
 	// 查询当前小时内的原始数据
 	var heartbeats []model.Heartbeat
-	DB.Where("monitor_id = ? AND time >= ?", monitorID, currentHour).
+	DB.Where("monitor_id = ? AND agent_id = 0 AND time >= ?", monitorID, currentHour).
 		Order("time DESC").
 		Find(&heartbeats)
 
@@ -426,7 +603,7 @@ func getCurrentHourPoint(monitorID uint, currentHour time.Time, now time.Time) C
 		return ChartDataPoint{
 			Time:     now.Format(time.RFC3339),
 			Duration: 0,
-			Status:   -1,
+			Status:   model.StatusNoData,
 			Uptime:   100,
 			IsLive:   true,
 		}
@@ -438,10 +615,10 @@ func getCurrentHourPoint(monitorID uint, currentHour time.Time, now time.Time) C
 	for _, h := range heartbeats {
 		// 只统计成功响应的延迟
 		if h.Status == model.StatusUp {
-			totalDuration += h.Duration
-			upCount++
+			totalDuration += h.Duration * h.RepresentedCount
+			upCount += h.RepresentedCount
 		} else if h.Status == model.StatusDown {
-			downCount++
+			downCount += h.RepresentedCount
 		}
 	}
 
@@ -501,7 +678,7 @@ func getCurrentSlotPoint(monitorID uint, currentHour time.Time, now time.Time, s
 
 	// 2. 获取当前小时的原始数据
 	var heartbeats []model.Heartbeat
-	DB.Where("monitor_id = ? AND time >= ?", monitorID, currentHour).
+	DB.Where("monitor_id = ? AND agent_id = 0 AND time >= ?", monitorID, currentHour).
 		Find(&heartbeats)
 
 	var currentHourDuration int
@@ -509,10 +686,10 @@ func getCurrentSlotPoint(monitorID uint, currentHour time.Time, now time.Time, s
 	for _, h := range heartbeats {
 		// 只统计成功响应的延迟
 		if h.Status == model.StatusUp {
-			currentHourDuration += h.Duration
-			currentUpCount++
+			currentHourDuration += h.Duration * h.RepresentedCount
+			currentUpCount += h.RepresentedCount
 		} else if h.Status == model.StatusDown {
-			currentDownCount++
+			currentDownCount += h.RepresentedCount
 		}
 	}
 
@@ -531,7 +708,7 @@ func getCurrentSlotPoint(monitorID uint, currentHour time.Time, now time.Time, s
 		return ChartDataPoint{
 			Time:     now.Format(time.RFC3339),
 			Duration: 0,
-			Status:   -1,
+			Status:   model.StatusNoData,
 			Uptime:   100,
 			IsLive:   true,
 		}
@@ -561,3 +738,60 @@ func getCurrentSlotPoint(monitorID uint, currentHour time.Time, now time.Time, s
 		IsLive:   true,
 	}
 }
+
+// UptimeBar 一天的可用率统计，Total 为 0 表示当天没有数据
+type UptimeBar struct {
+	Date   time.Time `json:"date"`
+	Uptime float64   `json:"uptime"` // 可用率（0-100）
+	Total  int64     `json:"total"`
+	Down   int64     `json:"down"` // 非 UP 的检查次数
+}
+
+// GetDailyUptimeBars 返回最近 days 天（含今天）每天的可用率，按日期升序
+// 已完成日聚合的日期读取日聚合表；今天和尚未聚合的昨天由小时聚合数据和当前小时的原始数据补齐
+func GetDailyUptimeBars(monitorID uint, days int) []UptimeBar {
+	now := time.Now()
+	today := now.Truncate(24 * time.Hour)
+	currentHour := now.Truncate(time.Hour)
+	start := today.AddDate(0, 0, -(days - 1))
+
+	var daily []model.HeartbeatDaily
+	DB.Where("monitor_id = ? AND date >= ?", monitorID, start).Find(&daily)
+	byDate := make(map[int64]model.HeartbeatDaily, len(daily))
+	for _, d := range daily {
+		byDate[d.Date.Unix()] = d
+	}
+
+	bars := make([]UptimeBar, 0, days)
+	for i := 0; i < days; i++ {
+		date := start.AddDate(0, 0, i)
+		bar := UptimeBar{Date: date}
+		if d, ok := byDate[date.Unix()]; ok {
+			bar.Total, bar.Down = int64(d.TotalCount), int64(d.TotalCount-d.UpCount)
+		} else if !date.Before(today.AddDate(0, 0, -1)) {
+			var up, total int64
+			DB.Model(&model.HeartbeatHourly{}).
+				Where("monitor_id = ? AND hour >= ? AND hour < ? AND hour < ?", monitorID, date, date.AddDate(0, 0, 1), currentHour).
+				Select("COALESCE(SUM(up_count), 0), COALESCE(SUM(total_count), 0)").
+				Row().Scan(&up, &total)
+			if date.Equal(today) {
+				var rawUp, rawTotal int64
+				DB.Model(&model.Heartbeat{}).
+					Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status <> ?", monitorID, currentHour, model.StatusMaintenance).
+					Select("COALESCE(SUM(represented_count), 0)").
+					Row().Scan(&rawTotal)
+				DB.Model(&model.Heartbeat{}).
+					Where("monitor_id = ? AND agent_id = 0 AND time >= ? AND status = ?", monitorID, currentHour, model.StatusUp).
+					Select("COALESCE(SUM(represented_count), 0)").
+					Row().Scan(&rawUp)
+				up, total = up+rawUp, total+rawTotal
+			}
+			bar.Total, bar.Down = total, total-up
+		}
+		if bar.Total > 0 {
+			bar.Uptime = float64(bar.Total-bar.Down) / float64(bar.Total) * 100.0
+		}
+		bars = append(bars, bar)
+	}
+	return bars
+}
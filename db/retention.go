@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"ping-go/config"
+	"ping-go/model"
+	"time"
+)
+
+// 各级数据的默认保留时间（配置为 0 或负数时使用）
+const (
+	defaultRawHours   = 24
+	defaultHourlyDays = 7
+	defaultDailyDays  = 365
+
+	defaultNotificationLogDays = 30
+)
+
+// retentionTier 一级数据的清理规则：Column 早于 Cutoff 的行会被删除
+type retentionTier struct {
+	Name   string
+	Keep   string
+	Model  any
+	Column string
+	Cutoff time.Time
+}
+
+// retentionTiers 根据保留配置计算各级数据的清理截止时间。
+// 纯函数，不访问数据库，当前时间由调用方传入
+func retentionTiers(retention config.RetentionConfig, now time.Time) []retentionTier {
+	rawHours := retention.RawHours
+	if rawHours <= 0 {
+		rawHours = defaultRawHours
+	}
+	hourlyDays := retention.HourlyDays
+	if hourlyDays <= 0 {
+		hourlyDays = defaultHourlyDays
+	}
+	dailyDays := retention.DailyDays
+	if dailyDays <= 0 {
+		dailyDays = defaultDailyDays
+	}
+	notificationLogDays := retention.NotificationLogDays
+	if notificationLogDays <= 0 {
+		notificationLogDays = defaultNotificationLogDays
+	}
+
+	return []retentionTier{
+		{Name: "raw", Keep: fmt.Sprintf("%d hours", rawHours), Model: &model.Heartbeat{}, Column: "time",
+			Cutoff: now.Add(-time.Duration(rawHours) * time.Hour)},
+		{Name: "hourly", Keep: fmt.Sprintf("%d days", hourlyDays), Model: &model.HeartbeatHourly{}, Column: "hour",
+			Cutoff: now.AddDate(0, 0, -hourlyDays)},
+		{Name: "agent_hourly", Keep: fmt.Sprintf("%d days", hourlyDays), Model: &model.HeartbeatAgentHourly{}, Column: "hour",
+			Cutoff: now.AddDate(0, 0, -hourlyDays)},
+		{Name: "daily", Keep: fmt.Sprintf("%d days", dailyDays), Model: &model.HeartbeatDaily{}, Column: "date",
+			Cutoff: now.AddDate(0, 0, -dailyDays)},
+		{Name: "status_event", Keep: fmt.Sprintf("%d days", dailyDays), Model: &model.StatusEvent{}, Column: "time",
+			Cutoff: now.AddDate(0, 0, -dailyDays)},
+		{Name: "notification_log", Keep: fmt.Sprintf("%d days", notificationLogDays), Model: &model.NotificationLog{}, Column: "created_at",
+			Cutoff: now.AddDate(0, 0, -notificationLogDays)},
+	}
+}
+
+// RetentionReport 一级数据的清理统计：预览时为将被删除的行，执行时为实际删除的行
+type RetentionReport struct {
+	Tier   string     `json:"tier"`
+	Keep   string     `json:"keep"`
+	Cutoff time.Time  `json:"cutoff"`
+	Rows   int64      `json:"rows"`
+	Oldest *time.Time `json:"oldest,omitempty"` // 仅预览：将被删除的最早一行
+	Newest *time.Time `json:"newest,omitempty"` // 仅预览：将被删除的最晚一行
+	Error  string     `json:"error,omitempty"`
+}
+
+// PreviewRetention 统计按给定保留配置清理时每一级会删除多少行及其时间范围，不删除任何数据
+func PreviewRetention(retention config.RetentionConfig) []RetentionReport {
+	tiers := retentionTiers(retention, time.Now())
+	reports := make([]RetentionReport, 0, len(tiers))
+	for _, t := range tiers {
+		r := RetentionReport{Tier: t.Name, Keep: t.Keep, Cutoff: t.Cutoff}
+		q := DB.Model(t.Model).Where(t.Column+" < ?", t.Cutoff)
+		if err := q.Count(&r.Rows).Error; err != nil {
+			r.Error = err.Error()
+		} else if r.Rows > 0 {
+			r.Oldest = boundaryTime(t, "ASC")
+			r.Newest = boundaryTime(t, "DESC")
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+// boundaryTime 返回截止时间之前最早（ASC）或最晚（DESC）一行的时间
+func boundaryTime(t retentionTier, order string) *time.Time {
+	var times []time.Time
+	err := DB.Model(t.Model).Where(t.Column+" < ?", t.Cutoff).
+		Order(t.Column+" "+order).Limit(1).Pluck(t.Column, &times).Error
+	if err != nil || len(times) == 0 {
+		return nil
+	}
+	return &times[0]
+}
+
+// ApplyRetentionNow 按当前配置立即执行一次清理，返回各级实际删除的行数
+func ApplyRetentionNow() []RetentionReport {
+	return cleanupAggregatedData()
+}
+
+// deleteExpired 删除一级数据中早于截止时间的行
+func deleteExpired(t retentionTier) RetentionReport {
+	r := RetentionReport{Tier: t.Name, Keep: t.Keep, Cutoff: t.Cutoff}
+	result := DB.Where(t.Column+" < ?", t.Cutoff).Delete(t.Model)
+	if result.Error != nil {
+		r.Error = result.Error.Error()
+		log.Printf("Failed to cleanup %s data: %v", t.Name, result.Error)
+		RaiseAlert(model.AlertSeverityWarning, "Data cleanup failed", fmt.Sprintf("cleanup %s data: %v", t.Name, result.Error))
+		return r
+	}
+	r.Rows = result.RowsAffected
+	if r.Rows > 0 {
+		log.Printf("Cleaned up %d %s rows (older than %s)", r.Rows, t.Name, t.Keep)
+	}
+	return r
+}
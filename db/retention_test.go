@@ -0,0 +1,123 @@
+package db
+
+import (
+	"path/filepath"
+	"ping-go/config"
+	"ping-go/model"
+	"testing"
+	"time"
+)
+
+func TestRetentionTiers(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 30, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		retention config.RetentionConfig
+		want      map[string]time.Time
+		keep      map[string]string
+	}{
+		{
+			name:      "zero config uses defaults",
+			retention: config.RetentionConfig{},
+			want: map[string]time.Time{
+				"raw":              now.Add(-24 * time.Hour),
+				"hourly":           day(2024, 2, 23), // 2024 年 2 月有 29 天
+				"agent_hourly":     day(2024, 2, 23),
+				"daily":            day(2023, 3, 2), // 闰年，365 天前是 3 月 2 日
+				"status_event":     day(2023, 3, 2),
+				"notification_log": day(2024, 1, 31),
+			},
+			keep: map[string]string{"raw": "24 hours", "hourly": "7 days", "daily": "365 days", "notification_log": "30 days"},
+		},
+		{
+			name:      "negative values fall back to defaults",
+			retention: config.RetentionConfig{RawHours: -1, HourlyDays: -7, DailyDays: -1, NotificationLogDays: -30},
+			want: map[string]time.Time{
+				"raw":              now.Add(-24 * time.Hour),
+				"hourly":           day(2024, 2, 23),
+				"daily":            day(2023, 3, 2),
+				"notification_log": day(2024, 1, 31),
+			},
+		},
+		{
+			name:      "custom values",
+			retention: config.RetentionConfig{RawHours: 1, HourlyDays: 1, DailyDays: 30, NotificationLogDays: 1},
+			want: map[string]time.Time{
+				"raw":              now.Add(-time.Hour),
+				"hourly":           day(2024, 2, 29),
+				"agent_hourly":     day(2024, 2, 29),
+				"daily":            day(2024, 1, 31),
+				"status_event":     day(2024, 1, 31),
+				"notification_log": day(2024, 2, 29),
+			},
+			keep: map[string]string{"raw": "1 hours", "hourly": "1 days", "agent_hourly": "1 days", "daily": "30 days"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers := retentionTiers(tt.retention, now)
+			byName := make(map[string]retentionTier, len(tiers))
+			for _, tier := range tiers {
+				byName[tier.Name] = tier
+				if !tier.Cutoff.Before(now) {
+					t.Errorf("%s: cutoff %v is not before now", tier.Name, tier.Cutoff)
+				}
+			}
+			if len(byName) != 6 {
+				t.Fatalf("got %d tiers, want 6", len(byName))
+			}
+			for name, want := range tt.want {
+				if got := byName[name].Cutoff; !got.Equal(want) {
+					t.Errorf("%s cutoff = %v, want %v", name, got, want)
+				}
+			}
+			for name, want := range tt.keep {
+				if got := byName[name].Keep; got != want {
+					t.Errorf("%s keep = %q, want %q", name, got, want)
+				}
+			}
+			// 粒度越粗保留越久：原始数据 <= 小时聚合 <= 日聚合
+			if byName["raw"].Cutoff.Before(byName["hourly"].Cutoff) || byName["hourly"].Cutoff.Before(byName["daily"].Cutoff) {
+				t.Errorf("tiers out of order: raw %v, hourly %v, daily %v", byName["raw"].Cutoff, byName["hourly"].Cutoff, byName["daily"].Cutoff)
+			}
+		})
+	}
+}
+
+func TestBoundaryTimeOrdering(t *testing.T) {
+	openTestDB(t, filepath.Join(t.TempDir(), "pinggo.db"))
+
+	cutoff := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tier := retentionTier{Name: "raw", Model: &model.Heartbeat{}, Column: "time", Cutoff: cutoff}
+	if got := boundaryTime(tier, "ASC"); got != nil {
+		t.Fatalf("empty table: oldest = %v, want nil", got)
+	}
+
+	for _, ts := range []time.Time{
+		cutoff.Add(-2 * time.Hour),
+		cutoff.Add(-48 * time.Hour), // 最早
+		cutoff.Add(-time.Minute),    // 截止前最晚
+		cutoff,                      // 恰好在截止时间，不删除
+		cutoff.Add(time.Hour),
+	} {
+		if err := DB.Create(&model.Heartbeat{MonitorID: 1, Status: model.StatusUp, Time: ts}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldest, newest := boundaryTime(tier, "ASC"), boundaryTime(tier, "DESC")
+	if oldest == nil || newest == nil {
+		t.Fatalf("oldest = %v, newest = %v, want both set", oldest, newest)
+	}
+	if !oldest.Equal(cutoff.Add(-48 * time.Hour)) {
+		t.Errorf("oldest = %v, want %v", oldest, cutoff.Add(-48*time.Hour))
+	}
+	if !newest.Equal(cutoff.Add(-time.Minute)) {
+		t.Errorf("newest = %v, want %v (rows at or after the cutoff are kept)", newest, cutoff.Add(-time.Minute))
+	}
+	if oldest.After(*newest) {
+		t.Errorf("oldest %v after newest %v", oldest, newest)
+	}
+}
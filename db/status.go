@@ -0,0 +1,156 @@
+package db
+
+import (
+	"ping-go/config"
+	"ping-go/model"
+	"ping-go/pkg/i18n"
+	"strings"
+	"sync"
+)
+
+// 设置项：界面语言、邮件语言和状态颜色（statusColorUp、statusColorDown 等，值为 CSS 颜色）
+const (
+	languageSettingKey       = "language"
+	emailLanguageSettingKey  = "emailLanguage"
+	statusColorSettingPrefix = "statusColor"
+)
+
+// 站点品牌设置项
+const (
+	SiteNameSettingKey         = "siteName"
+	SiteLogoURLSettingKey      = "siteLogoURL"
+	SitePrimaryColorSettingKey = "sitePrimaryColor"
+	SiteFooterTextSettingKey   = "siteFooterText"
+)
+
+// DefaultSiteName 未设置站点名称时使用的名称
+const DefaultSiteName = "PingGo"
+
+// SiteSettings 站点品牌设置：注入到前端页面（window.__CONFIG__），站点名称同时用于邮件主题和日报标题。
+// 未设置的字段为空（名称除外），由前端使用默认样式
+type SiteSettings struct {
+	Name         string `json:"siteName"`
+	LogoURL      string `json:"logoURL"`
+	PrimaryColor string `json:"primaryColor"`
+	FooterText   string `json:"footerText"`
+}
+
+var statusSettings struct {
+	sync.Mutex
+	loaded    bool
+	lang      string
+	emailLang string // 未设置时为空
+	colors    map[string]string
+	site      SiteSettings
+}
+
+// loadStatusSettings 读取语言和颜色设置，结果缓存到设置被修改为止
+func loadStatusSettings() (string, map[string]string) {
+	statusSettings.Lock()
+	defer statusSettings.Unlock()
+	if statusSettings.loaded {
+		return statusSettings.lang, statusSettings.colors
+	}
+
+	lang, colors := i18n.DefaultLanguage, make(map[string]string)
+	site := SiteSettings{Name: DefaultSiteName}
+	var settings []model.Setting
+	if DB != nil {
+		keys := []string{languageSettingKey, emailLanguageSettingKey, SiteNameSettingKey, SiteLogoURLSettingKey, SitePrimaryColorSettingKey, SiteFooterTextSettingKey}
+		DB.Where("key IN ? OR key LIKE ?", keys, statusColorSettingPrefix+"%").Find(&settings)
+	}
+	emailLang := ""
+	for _, s := range settings {
+		value := strings.TrimSpace(s.Value)
+		switch s.Key {
+		case SiteNameSettingKey:
+			if value != "" {
+				site.Name = value
+			}
+			continue
+		case SiteLogoURLSettingKey:
+			site.LogoURL = value
+			continue
+		case SitePrimaryColorSettingKey:
+			site.PrimaryColor = value
+			continue
+		case SiteFooterTextSettingKey:
+			site.FooterText = value
+			continue
+		case languageSettingKey:
+			if i18n.Supported(value) {
+				lang = value
+			}
+			continue
+		case emailLanguageSettingKey:
+			if i18n.Supported(value) {
+				emailLang = value
+			}
+			continue
+		}
+		if key := strings.ToLower(strings.TrimPrefix(s.Key, statusColorSettingPrefix)); key != "" && value != "" {
+			colors[key] = value
+		}
+	}
+	statusSettings.lang, statusSettings.emailLang, statusSettings.colors, statusSettings.loaded = lang, emailLang, colors, true
+	statusSettings.site = site
+	return lang, colors
+}
+
+// InvalidateStatusSettings 设置被修改后调用，下次读取时重新加载
+func InvalidateStatusSettings() {
+	statusSettings.Lock()
+	statusSettings.loaded = false
+	statusSettings.Unlock()
+}
+
+// Site 返回站点品牌设置，与语言设置一起缓存，修改后立即生效
+func Site() SiteSettings {
+	loadStatusSettings()
+	statusSettings.Lock()
+	defer statusSettings.Unlock()
+	return statusSettings.site
+}
+
+// SiteName 返回站点名称，未设置时为 DefaultSiteName
+func SiteName() string {
+	return Site().Name
+}
+
+// Language 返回设置的界面语言
+func Language() string {
+	lang, _ := loadStatusSettings()
+	return lang
+}
+
+// EmailLanguage 返回通知邮件的默认语言：设置项 emailLanguage，其次为配置 notification.language，都未设置时跟随界面语言
+func EmailLanguage() string {
+	lang, _ := loadStatusSettings()
+	statusSettings.Lock()
+	emailLang := statusSettings.emailLang
+	statusSettings.Unlock()
+	if emailLang != "" {
+		return emailLang
+	}
+	if l := config.Get().Notification.Language; i18n.Supported(l) {
+		return l
+	}
+	return lang
+}
+
+// StatusMeta 返回状态码的元数据，名称使用设置的界面语言，颜色可被设置覆盖
+func StatusMeta(code int) model.StatusMeta {
+	lang, colors := loadStatusSettings()
+	return model.DescribeStatus(code, lang, colors)
+}
+
+// StatusMetaList 返回全部已定义状态的元数据，供客户端按 key 建立映射；
+// 最后一项为 unknown，用于展示未定义的状态码（Code 仅作占位）
+func StatusMetaList() []model.StatusMeta {
+	lang, colors := loadStatusSettings()
+	list := make([]model.StatusMeta, 0, len(model.StatusCodes)+1)
+	for _, code := range model.StatusCodes {
+		list = append(list, model.DescribeStatus(code, lang, colors))
+	}
+	return append(list, model.DescribeStatus(-2, lang, colors))
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"log"
+	"ping-go/model"
+)
+
+// RecordStatusEvent 记录一次状态变化，写入失败只记录日志
+func RecordStatusEvent(e *model.StatusEvent) {
+	if DB == nil {
+		return
+	}
+	if err := DB.Create(e).Error; err != nil {
+		log.Printf("Failed to record status event for monitor %d: %v", e.MonitorID, err)
+	}
+}
+
+// RecentStatusEvents 返回指定监控项最近的状态变化，最新的在前
+func RecentStatusEvents(monitorIDs []uint, limit int) ([]model.StatusEvent, error) {
+	events := []model.StatusEvent{}
+	if len(monitorIDs) == 0 {
+		return events, nil
+	}
+	err := DB.Where("monitor_id IN ?", monitorIDs).Order("time DESC, id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"errors"
+	"ping-go/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrSlugTaken 状态页的 slug 已被其他状态页使用
+var ErrSlugTaken = errors.New("slug is already used by another status page")
+
+// StatusPageInfo 状态页及其按展示顺序排列的监控项 ID，供管理界面使用
+type StatusPageInfo struct {
+	model.StatusPage
+	MonitorIDs []uint `json:"monitor_ids"`
+}
+
+// StatusPages 返回全部状态页，按 ID 升序
+func StatusPages() ([]StatusPageInfo, error) {
+	var pages []model.StatusPage
+	if err := DB.Order("id").Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	var links []model.StatusPageMonitor
+	if err := DB.Order("status_page_id, sort_order, id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	byPage := make(map[uint][]uint)
+	for _, l := range links {
+		byPage[l.StatusPageID] = append(byPage[l.StatusPageID], l.MonitorID)
+	}
+	infos := make([]StatusPageInfo, len(pages))
+	for i, p := range pages {
+		infos[i] = StatusPageInfo{StatusPage: p, MonitorIDs: byPage[p.ID]}
+		if infos[i].MonitorIDs == nil {
+			infos[i].MonitorIDs = []uint{}
+		}
+	}
+	return infos, nil
+}
+
+// SaveStatusPage 创建（ID 为 0）或更新状态页，并按 monitorIDs 的顺序替换其中的监控项；
+// 不存在的监控项 ID 和重复的 ID 会被忽略
+func SaveStatusPage(page *model.StatusPage, monitorIDs []uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.StatusPage{}).Where("slug = ? AND id <> ?", page.Slug, page.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSlugTaken
+		}
+
+		if page.ID == 0 {
+			if err := tx.Create(page).Error; err != nil {
+				return err
+			}
+		} else {
+			result := tx.Model(page).Select("Slug", "Title", "Description", "Published").Updates(page)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			if err := tx.Where("status_page_id = ?", page.ID).Delete(&model.StatusPageMonitor{}).Error; err != nil {
+				return err
+			}
+		}
+
+		var existing []uint
+		if len(monitorIDs) > 0 {
+			if err := tx.Model(&model.Monitor{}).Where("id IN ?", monitorIDs).Pluck("id", &existing).Error; err != nil {
+				return err
+			}
+		}
+		valid := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			valid[id] = true
+		}
+		order := 0
+		for _, id := range monitorIDs {
+			if !valid[id] {
+				continue
+			}
+			delete(valid, id)
+			if err := tx.Create(&model.StatusPageMonitor{StatusPageID: page.ID, MonitorID: id, SortOrder: order}).Error; err != nil {
+				return err
+			}
+			order++
+		}
+		return nil
+	})
+}
+
+// DeleteStatusPage 删除状态页及其监控项列表和邮件订阅
+func DeleteStatusPage(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.StatusPage{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("status_page_id = ?", id).Delete(&model.StatusPageMonitor{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("status_page_id = ?", id).Delete(&model.Subscription{}).Error
+	})
+}
+
+// PublishedStatusPage 按 slug 查找已发布的状态页，返回按展示顺序排列的监控项。
+// 监控项只读取公开展示需要的字段，不包含地址和其他配置
+func PublishedStatusPage(slug string) (*model.StatusPage, []model.Monitor, error) {
+	var page model.StatusPage
+	if err := DB.Where("slug = ? AND published = ?", slug, true).First(&page).Error; err != nil {
+		return nil, nil, err
+	}
+	var links []model.StatusPageMonitor
+	if err := DB.Where("status_page_id = ?", page.ID).Order("sort_order, id").Find(&links).Error; err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uint, len(links))
+	for i, l := range links {
+		ids[i] = l.MonitorID
+	}
+	var found []model.Monitor
+	if len(ids) > 0 {
+		if err := DB.Select("id", "name", "type", "status", "active", "color", "icon", "group_id").Where("id IN ?", ids).Find(&found).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	byID := make(map[uint]model.Monitor, len(found))
+	for _, m := range found {
+		byID[m.ID] = m
+	}
+	monitors := make([]model.Monitor, 0, len(ids))
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			monitors = append(monitors, m)
+		}
+	}
+	return &page, monitors, nil
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+	"fmt"
+	"ping-go/model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// storageCacheTTL 存储统计的缓存时间，分组 COUNT 在大库上较慢
+const storageCacheTTL = 5 * time.Minute
+
+// 估算平均行大小时抽样的行数，以及每条记录（表和每个索引各一条）额外的 B-tree 开销
+const (
+	rowSizeSample   = 1000
+	rowOverheadSize = 8
+)
+
+// 保留覆盖建议：检查间隔不超过 shortIntervalSeconds 且占用排在前列的监控项
+const shortIntervalSeconds = 30
+
+// MonitorStorage 单个监控项的数据占用
+type MonitorStorage struct {
+	MonitorID      uint   `json:"monitor_id"`
+	Name           string `json:"name"`
+	Interval       int    `json:"interval"`
+	Deleted        bool   `json:"deleted,omitempty"` // 监控项已删除但仍有残留数据
+	RawRows        int64  `json:"raw_rows"`
+	HourlyRows     int64  `json:"hourly_rows"`
+	DailyRows      int64  `json:"daily_rows"`
+	EstimatedBytes int64  `json:"estimated_bytes"`
+}
+
+// RetentionSuggestion 建议单独设置更短保留时间的监控项
+type RetentionSuggestion struct {
+	MonitorID      uint   `json:"monitor_id"`
+	Name           string `json:"name"`
+	Interval       int    `json:"interval"`
+	RawRows        int64  `json:"raw_rows"`
+	EstimatedBytes int64  `json:"estimated_bytes"`
+	Reason         string `json:"reason"`
+}
+
+// StorageBreakdown 按监控项统计的心跳数据占用。EstimatedBytes 为 行数 × 各表平均行大小，
+// 不含 SQLite 空闲页和 WAL，因此会小于数据库文件大小
+type StorageBreakdown struct {
+	GeneratedAt    time.Time             `json:"generated_at"`
+	AvgRowBytes    map[string]float64    `json:"avg_row_bytes"` // raw/hourly/daily 的平均行大小（含索引）
+	Monitors       []MonitorStorage      `json:"monitors"`      // 按 EstimatedBytes 从大到小
+	Top            []MonitorStorage      `json:"top"`           // 占用最多的 10 个
+	TotalRawRows   int64                 `json:"total_raw_rows"`
+	TotalHourly    int64                 `json:"total_hourly_rows"`
+	TotalDaily     int64                 `json:"total_daily_rows"`
+	TotalBytes     int64                 `json:"total_estimated_bytes"`
+	DatabaseBytes  int64                 `json:"database_bytes"` // page_count × page_size
+	Suggestions    []RetentionSuggestion `json:"suggestions"`
+	CacheExpiresAt time.Time             `json:"cache_expires_at"`
+}
+
+var (
+	storageMu     sync.Mutex
+	storageCache  *StorageBreakdown
+	rowSizeMu     sync.Mutex
+	avgRowSizes   = make(map[string]float64) // 每张表只测量一次
+	storageTables = []struct {
+		Tier  string
+		Model any
+	}{
+		{"raw", &model.Heartbeat{}},
+		{"hourly", &model.HeartbeatHourly{}},
+		{"daily", &model.HeartbeatDaily{}},
+	}
+)
+
+// GetStorageBreakdown 返回按监控项统计的存储占用，结果缓存 storageCacheTTL
+func GetStorageBreakdown() (*StorageBreakdown, error) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	if storageCache != nil && time.Now().Before(storageCache.CacheExpiresAt) {
+		return storageCache, nil
+	}
+	b, err := computeStorageBreakdown()
+	if err != nil {
+		return nil, err
+	}
+	storageCache = b
+	return b, nil
+}
+
+// InvalidateStorageCache 清除存储统计缓存，批量写入或删除心跳后调用
+func InvalidateStorageCache() {
+	storageMu.Lock()
+	storageCache = nil
+	storageMu.Unlock()
+}
+
+func computeStorageBreakdown() (*StorageBreakdown, error) {
+	now := time.Now()
+	b := &StorageBreakdown{
+		GeneratedAt:    now,
+		AvgRowBytes:    make(map[string]float64),
+		CacheExpiresAt: now.Add(storageCacheTTL),
+	}
+
+	var monitors []model.Monitor
+	if err := DB.Select("id", "name", "interval").Find(&monitors).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*MonitorStorage, len(monitors))
+	for _, m := range monitors {
+		byID[m.ID] = &MonitorStorage{MonitorID: m.ID, Name: m.Name, Interval: m.Interval}
+	}
+
+	for _, t := range storageTables {
+		var rows []struct {
+			MonitorID uint
+			Count     int64
+		}
+		if err := DB.Model(t.Model).Select("monitor_id, COUNT(*) AS count").Group("monitor_id").Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("count %s heartbeats: %w", t.Tier, err)
+		}
+		size := avgRowSize(t.Model)
+		b.AvgRowBytes[t.Tier] = size
+		for _, r := range rows {
+			ms, ok := byID[r.MonitorID]
+			if !ok {
+				ms = &MonitorStorage{MonitorID: r.MonitorID, Deleted: true}
+				byID[r.MonitorID] = ms
+			}
+			switch t.Tier {
+			case "raw":
+				ms.RawRows = r.Count
+				b.TotalRawRows += r.Count
+			case "hourly":
+				ms.HourlyRows = r.Count
+				b.TotalHourly += r.Count
+			case "daily":
+				ms.DailyRows = r.Count
+				b.TotalDaily += r.Count
+			}
+			ms.EstimatedBytes += int64(float64(r.Count) * size)
+		}
+	}
+
+	b.Monitors = make([]MonitorStorage, 0, len(byID))
+	for _, ms := range byID {
+		b.Monitors = append(b.Monitors, *ms)
+		b.TotalBytes += ms.EstimatedBytes
+	}
+	sort.Slice(b.Monitors, func(i, j int) bool {
+		if b.Monitors[i].EstimatedBytes != b.Monitors[j].EstimatedBytes {
+			return b.Monitors[i].EstimatedBytes > b.Monitors[j].EstimatedBytes
+		}
+		return b.Monitors[i].MonitorID < b.Monitors[j].MonitorID
+	})
+	b.Top = b.Monitors[:min(10, len(b.Monitors))]
+	b.Suggestions = retentionSuggestions(b.Top, b.TotalBytes)
+
+	if isPostgres() {
+		DB.Raw("SELECT pg_database_size(current_database())").Scan(&b.DatabaseBytes)
+	} else {
+		var pageCount, pageSize int64
+		DB.Raw("PRAGMA page_count").Scan(&pageCount)
+		DB.Raw("PRAGMA page_size").Scan(&pageSize)
+		b.DatabaseBytes = pageCount * pageSize
+	}
+	return b, nil
+}
+
+// retentionSuggestions 从占用最多的监控项中挑出检查间隔很短的：原始心跳按间隔线性增长，
+// 这类监控项适合单独设置更短的原始数据保留时间；已删除监控项的残留数据建议直接清理
+func retentionSuggestions(top []MonitorStorage, total int64) []RetentionSuggestion {
+	suggestions := []RetentionSuggestion{}
+	for _, ms := range top {
+		if ms.EstimatedBytes == 0 {
+			continue
+		}
+		s := RetentionSuggestion{
+			MonitorID: ms.MonitorID, Name: ms.Name, Interval: ms.Interval,
+			RawRows: ms.RawRows, EstimatedBytes: ms.EstimatedBytes,
+		}
+		share := float64(ms.EstimatedBytes) / float64(max(total, 1)) * 100
+		switch {
+		case ms.Deleted:
+			s.Reason = fmt.Sprintf("monitor deleted but still holds %.1f%% of heartbeat storage", share)
+		case ms.Interval > 0 && ms.Interval <= shortIntervalSeconds:
+			s.Reason = fmt.Sprintf("checks every %ds and holds %.1f%% of heartbeat storage; consider a shorter raw retention for it", ms.Interval, share)
+		default:
+			continue
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions
+}
+
+// avgRowSize 估算一张表每行占用的字节数：抽样最近的行计算各列（含索引列）的长度之和，
+// 再加上表和每个索引的记录开销。表为空时返回 0 且不缓存，下次再测
+func avgRowSize(m any) float64 {
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(m); err != nil {
+		return 0
+	}
+	table := stmt.Schema.Table
+
+	rowSizeMu.Lock()
+	defer rowSizeMu.Unlock()
+	if size, ok := avgRowSizes[table]; ok {
+		return size
+	}
+
+	colLen := func(col string) string {
+		return fmt.Sprintf("IFNULL(LENGTH(CAST(%q AS BLOB)), 0)", col)
+	}
+	if isPostgres() {
+		colLen = func(col string) string {
+			return fmt.Sprintf("COALESCE(pg_column_size(%q), 0)", col)
+		}
+	}
+	parts := make([]string, 0, len(stmt.Schema.DBNames))
+	for _, col := range stmt.Schema.DBNames {
+		parts = append(parts, colLen(col))
+	}
+	records := 1
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		records++
+		for _, f := range idx.Fields {
+			parts = append(parts, colLen(f.DBName))
+		}
+		// 索引记录中还保存了 rowid
+		parts = append(parts, colLen("id"))
+	}
+
+	var size struct {
+		Avg   float64
+		Count int64
+	}
+	query := fmt.Sprintf("SELECT AVG(%s) AS avg, COUNT(*) AS count FROM (SELECT * FROM %q ORDER BY id DESC LIMIT %d) AS sample",
+		strings.Join(parts, " + "), table, rowSizeSample)
+	if err := DB.Raw(query).Scan(&size).Error; err != nil || size.Count == 0 {
+		return 0
+	}
+	avg := size.Avg + float64(records*rowOverheadSize)
+	avgRowSizes[table] = avg
+	return avg
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"ping-go/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SubscriptionInfo 订阅及其状态页，供管理界面使用
+type SubscriptionInfo struct {
+	model.Subscription
+	StatusPageSlug  string `json:"status_page_slug"`
+	StatusPageTitle string `json:"status_page_title"`
+}
+
+// SubscriberPage 已发布的状态页及其已确认的订阅者
+type SubscriberPage struct {
+	Page        model.StatusPage
+	Subscribers []model.Subscription
+}
+
+// RequestSubscription 为状态页登记订阅，已存在时沿用原记录。
+// 返回的 bool 表示是否需要发送确认邮件：已确认或 cooldown 内已发送过确认邮件时为 false
+func RequestSubscription(pageID uint, email string, cooldown time.Duration) (*model.Subscription, bool, error) {
+	var sub model.Subscription
+	send := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status_page_id = ? AND email = ?", pageID, email).Limit(1).Find(&sub).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if sub.ID == 0 {
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return err
+			}
+			sub = model.Subscription{StatusPageID: pageID, Email: email, Secret: hex.EncodeToString(secret), ConfirmSentAt: now}
+			send = true
+			return tx.Create(&sub).Error
+		}
+		if sub.Confirmed || now.Sub(sub.ConfirmSentAt) < cooldown {
+			return nil
+		}
+		send = true
+		sub.ConfirmSentAt = now
+		return tx.Model(&sub).Update("confirm_sent_at", now).Error
+	})
+	return &sub, send, err
+}
+
+// GetSubscription 按 ID 查找订阅
+func GetSubscription(id uint) (*model.Subscription, error) {
+	var sub model.Subscription
+	if err := DB.First(&sub, id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ConfirmSubscription 把订阅标记为已确认，已确认的保持原确认时间
+func ConfirmSubscription(sub *model.Subscription) error {
+	if sub.Confirmed {
+		return nil
+	}
+	now := time.Now()
+	sub.Confirmed, sub.ConfirmedAt = true, &now
+	return DB.Model(sub).Select("Confirmed", "ConfirmedAt").Updates(sub).Error
+}
+
+// DeleteSubscription 删除订阅
+func DeleteSubscription(id uint) error {
+	result := DB.Delete(&model.Subscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Subscriptions 返回全部订阅，最新的在前
+func Subscriptions() ([]SubscriptionInfo, error) {
+	var subs []model.Subscription
+	if err := DB.Order("id DESC").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	var pages []model.StatusPage
+	if err := DB.Select("id", "slug", "title").Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.StatusPage, len(pages))
+	for _, p := range pages {
+		byID[p.ID] = p
+	}
+	infos := make([]SubscriptionInfo, len(subs))
+	for i, sub := range subs {
+		page := byID[sub.StatusPageID]
+		infos[i] = SubscriptionInfo{Subscription: sub, StatusPageSlug: page.Slug, StatusPageTitle: page.Title}
+	}
+	return infos, nil
+}
+
+// SubscriberPages 返回包含 monitorIDs 中任一监控项的已发布状态页及其已确认的订阅者；
+// monitorIDs 为空时返回所有已发布的状态页。没有订阅者的状态页不返回
+func SubscriberPages(monitorIDs []uint) ([]SubscriberPage, error) {
+	query := DB.Where("published = ?", true)
+	if len(monitorIDs) > 0 {
+		query = query.Where("id IN (?)", DB.Model(&model.StatusPageMonitor{}).Select("status_page_id").Where("monitor_id IN ?", monitorIDs))
+	}
+	var pages []model.StatusPage
+	if err := query.Order("id").Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(pages))
+	for i, p := range pages {
+		ids[i] = p.ID
+	}
+	var subs []model.Subscription
+	if err := DB.Where("status_page_id IN ? AND confirmed = ?", ids, true).Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	byPage := make(map[uint][]model.Subscription)
+	for _, sub := range subs {
+		byPage[sub.StatusPageID] = append(byPage[sub.StatusPageID], sub)
+	}
+	var result []SubscriberPage
+	for _, p := range pages {
+		if len(byPage[p.ID]) > 0 {
+			result = append(result, SubscriberPage{Page: p, Subscribers: byPage[p.ID]})
+		}
+	}
+	return result, nil
+}
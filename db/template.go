@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"ping-go/model"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 自定义邮件模板保存在设置表中：全局模板的 key 为 "emailTemplate.<类型>"，
+// 某条规则的模板为 "emailTemplate.<类型>.<规则ID>"，规则模板优先于全局模板
+const templateSettingPrefix = "emailTemplate."
+
+// AuditActionTemplateUpdate 修改自定义模板的审计动作
+const AuditActionTemplateUpdate = "template.update"
+
+// CustomTemplate 一条自定义模板，RuleID 为 0 表示全局模板
+type CustomTemplate struct {
+	Kind    string `json:"kind"`
+	RuleID  uint   `json:"rule_id"`
+	Source  string `json:"source"`
+	Version int    `json:"version"`
+}
+
+// TemplateSettingKey 返回模板在设置表中的 key
+func TemplateSettingKey(kind string, ruleID uint) string {
+	if ruleID == 0 {
+		return templateSettingPrefix + kind
+	}
+	return fmt.Sprintf("%s%s.%d", templateSettingPrefix, kind, ruleID)
+}
+
+// IsTemplateSettingKey 是否是自定义模板的 key（只能通过 setNotificationTemplate 修改）
+func IsTemplateSettingKey(key string) bool {
+	return strings.HasPrefix(key, templateSettingPrefix)
+}
+
+// NotificationTemplate 返回规则使用的模板源码：先找规则模板，再找全局模板，都没有时返回空字符串（使用内置模板）
+func NotificationTemplate(kind string, ruleID uint) string {
+	keys := []string{TemplateSettingKey(kind, 0)}
+	if ruleID != 0 {
+		keys = append(keys, TemplateSettingKey(kind, ruleID))
+	}
+	var settings []model.Setting
+	DB.Where("key IN ?", keys).Find(&settings)
+	src := ""
+	for _, s := range settings {
+		if s.Value == "" {
+			continue
+		}
+		if s.Key != keys[0] || src == "" {
+			src = s.Value
+		}
+	}
+	return src
+}
+
+// CustomTemplates 返回全部自定义模板
+func CustomTemplates() ([]CustomTemplate, error) {
+	var settings []model.Setting
+	if err := DB.Where("key LIKE ?", templateSettingPrefix+"%").Order("key ASC").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	list := make([]CustomTemplate, 0, len(settings))
+	for _, s := range settings {
+		kind, rule, _ := strings.Cut(strings.TrimPrefix(s.Key, templateSettingPrefix), ".")
+		ruleID, _ := strconv.ParseUint(rule, 10, 64)
+		list = append(list, CustomTemplate{Kind: kind, RuleID: uint(ruleID), Source: s.Value, Version: s.Version})
+	}
+	return list, nil
+}
+
+// SetNotificationTemplate 保存自定义模板，src 为空时删除（恢复使用内置或全局模板）。
+// base 为读取到的版本号（新建为 0），与当前版本不一致时返回 ErrVersionConflict
+func SetNotificationTemplate(kind string, ruleID uint, src string, base int) error {
+	key := TemplateSettingKey(kind, ruleID)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var setting model.Setting
+		if err := tx.Where("key = ?", key).Limit(1).Find(&setting).Error; err != nil {
+			return err
+		}
+		if setting.Version != base {
+			return ErrVersionConflict
+		}
+		switch {
+		case setting.ID == 0 && src == "":
+			return nil
+		case setting.ID == 0:
+			return tx.Create(&model.Setting{Key: key, Value: src, Type: "template"}).Error
+		case src == "":
+			return tx.Delete(&setting).Error
+		}
+		result := tx.Model(&setting).Where("version = ?", base).Updates(map[string]any{"value": src, "version": base + 1})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// DeleteRuleTemplates 删除规则的自定义模板，删除规则时调用
+func DeleteRuleTemplates(ruleID uint) {
+	DB.Where("key LIKE ?", fmt.Sprintf("%s%%.%d", templateSettingPrefix, ruleID)).Delete(&model.Setting{})
+}
@@ -0,0 +1,19 @@
+package db
+
+import "errors"
+
+// ErrVersionConflict 乐观锁冲突：记录在读取之后已被其他人修改
+var ErrVersionConflict = errors.New("version conflict")
+
+// SaveVersioned 以乐观锁保存整条记录：只有数据库中的 version 仍为 base 时才写入。
+// value 为带 Version 字段的模型指针，调用方需先把 Version 设为 base+1；记录已被修改时返回 ErrVersionConflict
+func SaveVersioned(value any, base int) error {
+	result := DB.Model(value).Where("version = ?", base).Select("*").Updates(value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -41,6 +41,13 @@ function formatDate(dateStr) {
     return `${y}-${m}-${d} ${h}:${min}:${s}`;
 }
 
+// toLocalInput 将时间转换为 datetime-local 输入框使用的本地时间字符串
+function toLocalInput(dateStr) {
+    if (!dateStr) return '';
+    const d = new Date(dateStr);
+    return new Date(d.getTime() - d.getTimezoneOffset() * 60000).toISOString().slice(0, 16);
+}
+
 function formatTime(dateStr, includeDate = false, includeSeconds = false) {
     if (!dateStr) return '';
     const date = new Date(dateStr);
@@ -70,14 +77,21 @@ function app() {
 
         socket: null,
         page: 'loading',
+        // 站点名称、Logo、主题色和页脚，由服务端注入 window.__CONFIG__
+        site: window.__CONFIG__ || {},
         monitors: [],
         currentMonitor: null,
-        dashboardView: 'overview', // 'overview', 'details' or 'form'
+        dashboardView: 'overview', // 'overview', 'details', 'incident' or 'form'
+        // 事件视图（通知中的 #/incident/<id>/<timestamp> 链接）
+        incident: null,
+        incidentNote: '',
         heartbeats: [],
         showAdvanced: false,
         isEditing: false,
         isTesting: false,
         isEditingNotif: false,
+        importJobId: null, // 正在进行的后台导入任务
+        startupProgress: null, // 服务启动时分批调度监控项的进度，完成后为 null
         searchText: '',
         setupForm: { username: '', password: '', confirmPassword: '' },
         // ❌ 移除：不在 Alpine 数据中存储 chart，避免 Proxy 包装
@@ -94,6 +108,10 @@ function app() {
         },
 
         loginForm: { username: '', password: '' },
+        // 开启两步验证的账号在密码验证通过后需要输入验证码（login2FA）
+        loginCode: null,
+        // 修改当前账号的用户名和密码，不为空时显示账号设置弹窗
+        accountForm: null,
         monitorForm: {
             name: '',
             url: '',
@@ -108,6 +126,12 @@ function app() {
             body: '',
             bodyType: 'application/json',
             response_regex: '',
+            assert_on_empty_body: false,
+            color: '',
+            icon: '',
+            group_id: 0,
+            badge: false,
+            public_visible: true,
             formFields: [], // {key: '', value: '', type: 'text'}
             headerFields: [], // {key: '', value: ''}
             queryFields: [] // {key: '', value: ''}
@@ -123,6 +147,23 @@ function app() {
             );
         },
 
+        // monitorSections 侧边栏按分组划分的区块：分组按服务端顺序（权重、名称），未分组的监控项放在最后；
+        // 没有任何分组时只有一个不显示标题的区块
+        get monitorSections() {
+            const list = this.filteredMonitors;
+            if (this.monitorGroups.length === 0) return [{ id: 0, name: '', monitors: list }];
+            const sections = this.monitorGroups.map(g => Object.assign({}, g, { monitors: list.filter(m => m.group_id === g.id) }));
+            sections.push({ id: 0, name: '未分组', status: null, monitors: list.filter(m => !m.group_id) });
+            // 搜索时隐藏没有匹配项的区块
+            return sections.filter(s => s.monitors.length > 0 || (!this.searchText && s.id !== 0));
+        },
+
+        // toggleGroup 折叠/展开分组，折叠状态保存在浏览器中
+        toggleGroup(id) {
+            this.collapsedGroups[id] = !this.collapsedGroups[id];
+            localStorage.setItem('collapsedGroups', JSON.stringify(this.collapsedGroups));
+        },
+
         get globalStats() {
             return {
                 total: this.monitors.length,
@@ -144,9 +185,46 @@ function app() {
             days: [],
             timezone: '',
             max_retries: 0,
-            max_retries_recovery: 0
+            max_retries_recovery: 0,
+            resend_interval: 0,
+            resend_unit: 'checks',
+            down_mode: 'checks',
+            down_for_seconds: 120,
+            redact_details: false,
+            quiet_enabled: false,
+            quiet_start: '22:00',
+            quiet_end: '07:00',
+            quiet_days: [],
+            quiet_digest: true,
+            rate_limit_per_hour: 0,
+            flap_transitions: 0,
+            flap_window_minutes: 10,
+            flap_stable_minutes: 10,
+            escalation_after_minutes: 0,
+            escalation_channel: '',
+            escalation_email: '',
+            language: ''
         },
         showNotifModal: false,
+        // 通知发送记录（getNotificationHistory），failedOnly 时只显示失败的记录
+        notifHistory: { logs: [], total: 0, page: 1, pageSize: 20, failedOnly: false, loading: false },
+        // 公开状态页（getStatusPages），statusPageForm 不为空时显示编辑表单
+        statusPages: [],
+        statusPageForm: null,
+        // 监控分组（monitorGroupList），status 为成员中最严重的状态；groupForm 不为空时显示编辑表单
+        monitorGroups: [],
+        collapsedGroups: JSON.parse(localStorage.getItem('collapsedGroups') || '{}'),
+        groupForm: null,
+        // 事件公告（getIncidents），包括已解决的；incidentForm 不为空时显示编辑表单
+        incidents: [],
+        incidentForm: null,
+        incidentSeverities: { info: '公告', minor: '轻微', major: '严重', critical: '紧急' },
+        incidentStatuses: { investigating: '调查中', identified: '已定位', monitoring: '观察中', resolved: '已解决' },
+        // 维护窗口（getMaintenanceWindows），包括最近 7 天内结束的；maintenanceForm 不为空时显示编辑表单
+        maintenanceWindows: [],
+        maintenanceForm: null,
+        // 状态页邮件订阅者（getSubscriptions），包括未确认的
+        subscriptions: [],
 
         // Modal State
         msgBox: {
@@ -195,6 +273,7 @@ function app() {
         },
 
         connectionStatus: 'connected', // connected, disconnected, reconnecting
+        demoMode: false, // 服务器以 DEMO_MODE 运行时所有管理功能只读
 
         // 3.4 错误处理优化: 统一错误处理
         handleError(error) {
@@ -247,6 +326,7 @@ function app() {
         },
 
         init() {
+            if (this.site.siteName) document.title = this.site.siteName + ' 管理面板';
             this.socket = io({
                 transports: ['websocket', 'polling'],
                 reconnection: true,
@@ -276,6 +356,10 @@ function app() {
                                     }
                                     // 刷新数据
                                     this.socket.emit('getMonitorList');
+                                    this.loadStatusPages();
+                                    this.loadIncidents();
+                                    this.loadMaintenanceWindows();
+                                    this.loadSubscriptions();
                                     if (this.dashboardView === 'details' && this.currentMonitor) {
                                         this.selectMonitor(this.currentMonitor);
                                     }
@@ -301,13 +385,21 @@ function app() {
                 this.connectionStatus = 'reconnecting';
             });
 
+            this.socket.on('monitorGroupList', (groups) => {
+                this.monitorGroups = groups || [];
+            });
+
             this.socket.on('adminMonitorList', (list) => {
                 if (!Array.isArray(list)) {
                     list = Object.values(list);
                 }
                 this.monitors = list.sort((a, b) => (b.active - a.active) || (a.id - b.id));
+                this.openMonitorFromHash();
             });
 
+            // 日报等通知中的链接：/dashboard#/monitor/<id>、/dashboard#/incident/<id>/<timestamp>
+            window.addEventListener('hashchange', () => this.openMonitorFromHash());
+
             this.socket.on('monitor', (m) => {
                 let index = this.monitors.findIndex(x => x.id === m.id);
                 if (index !== -1) {
@@ -421,6 +513,53 @@ function app() {
                 this.socket.emit('getMonitorList');
             });
 
+            this.socket.on('importProgress', (job) => {
+                if (job.job_id !== this.importJobId) return;
+                if (!job.done) {
+                    this.showAlert('正在导入', `已处理 ${job.processed} / ${job.total}，成功 ${job.imported}，跳过 ${job.skipped}，失败 ${job.failed}`, 'info');
+                    return;
+                }
+                this.importJobId = null;
+                let msg = `<div class="text-left">成功导入 <span class="text-emerald-600 font-bold">${job.imported}</span> 个监控项。`;
+                if (job.heartbeats > 0) {
+                    msg += `<div class="mt-2 text-[11px] text-gray-500">导入历史心跳 ${job.heartbeats} 条${job.heartbeats_skipped > 0 ? `（丢弃无效 ${job.heartbeats_skipped} 条）` : ''}`;
+                    if (job.rebuilt) {
+                        msg += `，已重建 ${job.rebuilt.hourly_buckets} 个小时聚合和 ${job.rebuilt.daily_buckets} 个日聚合`;
+                    }
+                    msg += `</div>`;
+                }
+                if (job.notifications_imported > 0 || job.notifications_skipped > 0 || job.notifications_failed > 0) {
+                    msg += `<div class="mt-2 text-[11px] text-gray-500">通知规则：导入 ${job.notifications_imported} 条，跳过同名或无效 ${job.notifications_skipped} 条${job.notifications_failed > 0 ? `，写入失败 ${job.notifications_failed} 条` : ''}${job.assignments_created > 0 ? `，为对应的监控项复制 ${job.assignments_created} 条` : ''}</div>`;
+                }
+                if (job.skipped > 0) {
+                    msg += `<div class="mt-4 pt-3 border-t border-gray-100">
+                              <div class="text-amber-600 font-bold text-[11px] uppercase tracking-wider mb-2">跳过 ${job.skipped} 个重名或无效项</div>
+                              <div class="flex flex-wrap gap-1.5 max-h-32 overflow-y-auto pr-1">
+                                ${job.skippedNames.map(name => `<span class="px-2 py-0.5 bg-amber-50 text-amber-700 rounded-md text-[10px] border border-amber-100 font-medium">${this.escapeHtml(name)}</span>`).join('')}
+                              </div>
+                            </div>`;
+                }
+                if (job.failed > 0) {
+                    msg += `<div class="mt-4 pt-3 border-t border-gray-100">
+                              <div class="text-red-600 font-bold text-[11px] uppercase tracking-wider mb-2">写入失败 ${job.failed} 项（所在批次已回滚）</div>
+                              <div class="max-h-32 overflow-y-auto pr-1 text-[10px] text-red-700">
+                                ${job.failures.map(f => `<div>${this.escapeHtml(f.name)}: ${this.escapeHtml(f.error)}</div>`).join('')}
+                              </div>
+                            </div>`;
+                }
+                msg += `</div>`;
+                this.showAlert('导入完成', msg, job.failed > 0 ? 'error' : (job.skipped > 0 ? 'warning' : 'success'));
+                this.socket.emit('getMonitorList');
+            });
+
+            this.socket.on('startupProgress', (p) => {
+                this.startupProgress = p && p.starting ? p : null;
+            });
+
+            this.socket.on('info', (info) => {
+                this.demoMode = !!(info && info.demo);
+            });
+
             this.socket.on('notification', (data) => {
                 this.showAlert(data.type === 'error' ? '错误' : '通知', data.message, data.type || 'info');
             });
@@ -429,9 +568,9 @@ function app() {
                 this.notifications = list || [];
             });
 
-            this.socket.on('monitorConfigExport', (monitors) => {
+            this.socket.on('monitorConfigExport', (data) => {
                 // Clean data for export
-                const exportData = monitors.map(m => ({
+                const exportData = (data.monitors || []).map(m => ({
                     name: m.name,
                     url: m.url,
                     type: m.type,
@@ -443,11 +582,16 @@ function app() {
                     timeout: m.timeout,
                     expected_status: m.expected_status,
                     response_regex: m.response_regex,
+                    assert_on_empty_body: m.assert_on_empty_body,
+                    color: m.color,
+                    icon: m.icon,
+                    group: m.group || undefined,
                     follow_redirects: m.follow_redirects,
                     active: m.active
                 }));
 
-                const dataStr = "data:text/json;charset=utf-8," + encodeURIComponent(JSON.stringify(exportData, null, 2));
+                const exportFile = { monitors: exportData, notifications: data.notifications || [], assignments: data.assignments || [] };
+                const dataStr = "data:text/json;charset=utf-8," + encodeURIComponent(JSON.stringify(exportFile, null, 2));
                 const downloadAnchorNode = document.createElement('a');
                 downloadAnchorNode.setAttribute("href", dataStr);
                 downloadAnchorNode.setAttribute("download", "pinggo_monitors_" + new Date().toISOString().slice(0, 10) + ".json");
@@ -515,6 +659,34 @@ function app() {
         openNotifications() {
             this.dashboardView = 'notifications';
             this.socket.emit('getNotificationList'); // Ensure we have latest
+            this.loadNotificationHistory(1);
+        },
+
+        // loadNotificationHistory 加载一页发送记录
+        loadNotificationHistory(page) {
+            const h = this.notifHistory;
+            const query = { page: page || 1, page_size: h.pageSize };
+            if (h.failedOnly) query.success = false;
+            h.loading = true;
+            this.socket.emit('getNotificationHistory', query, (res) => {
+                h.loading = false;
+                if (!res || !res.ok) return;
+                h.logs = res.logs || [];
+                h.total = res.total || 0;
+                h.page = query.page;
+            });
+        },
+
+        // resendNotification 重新发送一条失败的记录，完成后刷新当前页
+        resendNotification(log) {
+            this.socket.emit('resendNotification', log.id, (res) => {
+                if (!res || !res.ok) {
+                    this.showAlert('重新发送失败', res ? res.msg : '未知错误', 'error');
+                } else {
+                    this.showAlert('已重新发送', log.subject, 'success');
+                }
+                this.loadNotificationHistory(this.notifHistory.page);
+            });
         },
 
         openAddTrigger() {
@@ -528,6 +700,53 @@ function app() {
                 email: '',
                 max_retries: 3,
                 max_retries_recovery: 3,
+                resend_interval: 0,
+                resend_unit: 'checks',
+                down_mode: 'checks',
+                down_for_seconds: 120,
+                redact_details: false,
+                quiet_enabled: false,
+                quiet_start: '22:00',
+                quiet_end: '07:00',
+                quiet_days: [],
+                quiet_digest: true,
+                rate_limit_per_hour: 0,
+                flap_transitions: 0,
+                flap_window_minutes: 10,
+                flap_stable_minutes: 10,
+                escalation_after_minutes: 0,
+                escalation_channel: '',
+                escalation_email: '',
+                language: '',
+                channel: 'email',
+                ntfy_server: '',
+                ntfy_topic: '',
+                ntfy_token: '',
+                ntfy_username: '',
+                ntfy_password: '',
+                ntfy_priority: 0,
+                gotify_server: '',
+                gotify_token: '',
+                pushover_user: '',
+                pushover_token: '',
+                pushover_emergency: false,
+                pushover_retry: 60,
+                pushover_expire: 3600,
+                bark_server: '',
+                bark_device_key: '',
+                bark_icon_down: '',
+                bark_icon_recovery: '',
+                dingtalk_webhook: '',
+                dingtalk_secret: '',
+                dingtalk_at_all: false,
+                dingtalk_at_mobiles: '',
+                wecom_key: '',
+                pagerduty_routing_key: '',
+                opsgenie_api_key: '',
+                opsgenie_region: 'us',
+                opsgenie_priority: 'P3',
+                teams_webhook: '',
+                teams_format: 'adaptive',
                 time: '',
                 days: []
             };
@@ -545,6 +764,11 @@ function app() {
                 email: '',
                 max_retries: 3,
                 max_retries_recovery: 3,
+                resend_interval: 0,
+                resend_unit: 'checks',
+                redact_details: false,
+                channel: 'email',
+                wecom_key: '',
                 time: '09:00',
                 days: []
             };
@@ -557,6 +781,9 @@ function app() {
             try { cfg = JSON.parse(n.config); } catch (e) { }
             this.notifForm = {
                 id: n.id,
+                version: n.version,
+                // 读取时的内容（与 editNotification 提交格式一致），保存冲突时服务端据此判断哪些字段被他人修改
+                _original: Object.assign({}, cfg, { id: n.id, name: n.name, type: n.type, active: n.active, version: n.version }),
                 type: n.type,
                 name: n.name,
                 monitorName: cfg.monitor_name || '*',
@@ -564,6 +791,66 @@ function app() {
                 email: cfg.email || '',
                 max_retries: cfg.max_retries || 0,
                 max_retries_recovery: cfg.max_retries_recovery || 0,
+                resend_interval: cfg.resend_interval || 0,
+                resend_unit: cfg.resend_unit || 'checks',
+                down_mode: cfg.down_for_seconds > 0 ? 'time' : 'checks',
+                down_for_seconds: cfg.down_for_seconds || 120,
+                redact_details: !!cfg.redact_details,
+                quiet_enabled: !!cfg.quiet_hours,
+                quiet_start: cfg.quiet_hours ? cfg.quiet_hours.start : '22:00',
+                quiet_end: cfg.quiet_hours ? cfg.quiet_hours.end : '07:00',
+                quiet_timezone: cfg.quiet_hours ? cfg.quiet_hours.timezone : '',
+                quiet_days: cfg.quiet_hours && cfg.quiet_hours.days ? cfg.quiet_hours.days.map(String) : [],
+                quiet_digest: cfg.quiet_hours ? !!cfg.quiet_hours.digest : true,
+                rate_limit_per_hour: cfg.rate_limit_per_hour || 0,
+                flap_transitions: cfg.flap_transitions || 0,
+                flap_window_minutes: cfg.flap_window_minutes || 10,
+                flap_stable_minutes: cfg.flap_stable_minutes || 10,
+                escalation_after_minutes: cfg.escalation_after_minutes || 0,
+                escalation_channel: cfg.escalation_channel || '',
+                escalation_email: cfg.escalation_email || '',
+                language: cfg.language || '',
+                channel: cfg.channel || 'email',
+                ntfy_server: cfg.ntfy_server || '',
+                ntfy_topic: cfg.ntfy_topic || '',
+                ntfy_token: '',
+                ntfy_token_set: !!cfg.ntfy_token_set,
+                ntfy_username: cfg.ntfy_username || '',
+                ntfy_password: '',
+                ntfy_password_set: !!cfg.ntfy_password_set,
+                ntfy_priority: cfg.ntfy_priority || 0,
+                gotify_server: cfg.gotify_server || '',
+                gotify_token: '',
+                gotify_token_set: !!cfg.gotify_token_set,
+                pushover_user: '',
+                pushover_user_set: !!cfg.pushover_user_set,
+                pushover_token: '',
+                pushover_token_set: !!cfg.pushover_token_set,
+                pushover_emergency: !!cfg.pushover_emergency,
+                pushover_retry: cfg.pushover_retry || 60,
+                pushover_expire: cfg.pushover_expire || 3600,
+                bark_server: cfg.bark_server || '',
+                bark_device_key: '',
+                bark_device_key_set: !!cfg.bark_device_key_set,
+                bark_icon_down: cfg.bark_icon_down || '',
+                bark_icon_recovery: cfg.bark_icon_recovery || '',
+                dingtalk_webhook: '',
+                dingtalk_webhook_set: !!cfg.dingtalk_webhook_set,
+                dingtalk_secret: '',
+                dingtalk_secret_set: !!cfg.dingtalk_secret_set,
+                dingtalk_at_all: !!cfg.dingtalk_at_all,
+                dingtalk_at_mobiles: cfg.dingtalk_at_mobiles || '',
+                wecom_key: '',
+                wecom_key_set: !!cfg.wecom_key_set,
+                pagerduty_routing_key: '',
+                pagerduty_routing_key_set: !!cfg.pagerduty_routing_key_set,
+                opsgenie_api_key: '',
+                opsgenie_api_key_set: !!cfg.opsgenie_api_key_set,
+                opsgenie_region: cfg.opsgenie_region || 'us',
+                opsgenie_priority: cfg.opsgenie_priority || 'P3',
+                teams_webhook: '',
+                teams_webhook_set: !!cfg.teams_webhook_set,
+                teams_format: cfg.teams_format || 'adaptive',
                 time: cfg.time || '09:00',
                 days: cfg.days || [],
                 timezone: cfg.timezone || ''
@@ -573,10 +860,64 @@ function app() {
 
         saveNotification() {
             // Basic validation
-            if (!this.notifForm.email) {
+            const isNtfy = this.notifForm.type === 'trigger' && this.usesChannel('ntfy');
+            const isGotify = this.notifForm.type === 'trigger' && this.usesChannel('gotify');
+            if (isNtfy && !this.notifForm.ntfy_topic) {
+                this.showAlert('表单错误', '请输入 ntfy 主题', 'warning');
+                return;
+            }
+            if (isGotify && (!this.notifForm.gotify_server || (!this.notifForm.gotify_token && !this.notifForm.gotify_token_set))) {
+                this.showAlert('表单错误', '请输入 Gotify 服务器地址和应用令牌', 'warning');
+                return;
+            }
+            const isPushover = this.notifForm.type === 'trigger' && this.usesChannel('pushover');
+            if (isPushover && ((!this.notifForm.pushover_user && !this.notifForm.pushover_user_set) || (!this.notifForm.pushover_token && !this.notifForm.pushover_token_set))) {
+                this.showAlert('表单错误', '请输入 Pushover 用户 Key 和应用 Token', 'warning');
+                return;
+            }
+            const isBark = this.notifForm.type === 'trigger' && this.usesChannel('bark');
+            if (isBark && !this.notifForm.bark_device_key && !this.notifForm.bark_device_key_set) {
+                this.showAlert('表单错误', '请输入 Bark 设备 Key', 'warning');
+                return;
+            }
+            const isDingTalk = this.notifForm.type === 'trigger' && this.usesChannel('dingtalk');
+            if (isDingTalk && !this.notifForm.dingtalk_webhook && !this.notifForm.dingtalk_webhook_set) {
+                this.showAlert('表单错误', '请输入钉钉机器人 Webhook 地址', 'warning');
+                return;
+            }
+            const isWeCom = this.usesChannel('wecom');
+            if (isWeCom && !this.notifForm.wecom_key && !this.notifForm.wecom_key_set) {
+                this.showAlert('表单错误', '请输入企业微信机器人 Key', 'warning');
+                return;
+            }
+            const isPagerDuty = this.notifForm.type === 'trigger' && this.usesChannel('pagerduty');
+            if (isPagerDuty && !this.notifForm.pagerduty_routing_key && !this.notifForm.pagerduty_routing_key_set) {
+                this.showAlert('表单错误', '请输入 PagerDuty Integration Key', 'warning');
+                return;
+            }
+            const isOpsgenie = this.notifForm.type === 'trigger' && this.usesChannel('opsgenie');
+            if (isOpsgenie && !this.notifForm.opsgenie_api_key && !this.notifForm.opsgenie_api_key_set) {
+                this.showAlert('表单错误', '请输入 Opsgenie API Key', 'warning');
+                return;
+            }
+            const isTeams = this.notifForm.type === 'trigger' && this.usesChannel('teams');
+            if (isTeams && !this.notifForm.teams_webhook && !this.notifForm.teams_webhook_set) {
+                this.showAlert('表单错误', '请输入 Teams Webhook 地址', 'warning');
+                return;
+            }
+            if (this.notifForm.channel === 'email' && !this.notifForm.email) {
                 this.showAlert('表单错误', '请输入接收邮箱', 'warning');
                 return;
             }
+            const escalates = this.notifForm.type === 'trigger' && this.notifForm.escalation_after_minutes > 0;
+            if (escalates && !this.notifForm.escalation_channel) {
+                this.showAlert('表单错误', '请选择升级渠道', 'warning');
+                return;
+            }
+            if (escalates && this.notifForm.escalation_channel === 'email' && !this.notifForm.escalation_email && !this.notifForm.email) {
+                this.showAlert('表单错误', '请输入升级通知的接收邮箱', 'warning');
+                return;
+            }
             // Default name if empty
             if (!this.notifForm.name) {
                 this.notifForm.name = this.notifForm.type === 'trigger' ? '监控告警' : '每日日报';
@@ -587,6 +928,8 @@ function app() {
             // Explicitly construct payload to avoid spread syntax issues and ensure correct keys for Go backend
             const payload = {
                 id: this.notifForm.id,
+                version: this.notifForm.version,
+                _original: this.notifForm._original,
                 name: this.notifForm.name,
                 type: this.notifForm.type,
                 email: this.notifForm.email,
@@ -594,21 +937,316 @@ function app() {
                 on_status: isTrigger ? (this.notifForm.onStatus || 'down') : '',
                 max_retries: isTrigger ? (parseInt(this.notifForm.max_retries) || 0) : 0,
                 max_retries_recovery: isTrigger ? (parseInt(this.notifForm.max_retries_recovery) || 0) : 0,
+                resend_interval: isTrigger ? (parseInt(this.notifForm.resend_interval) || 0) : 0,
+                resend_unit: isTrigger ? (this.notifForm.resend_unit || 'checks') : '',
+                // 按持续时间判定宕机时提交秒数，0 表示按连续失败次数
+                down_for_seconds: isTrigger && this.notifForm.down_mode === 'time' ? (parseInt(this.notifForm.down_for_seconds) || 0) : 0,
+                redact_details: isTrigger ? !!this.notifForm.redact_details : false,
+                // 静默时段：时段内不发送通知，已发出宕机通知的恢复通知除外
+                quiet_hours: isTrigger && this.notifForm.quiet_enabled ? {
+                    start: this.notifForm.quiet_start,
+                    end: this.notifForm.quiet_end,
+                    timezone: this.notifForm.quiet_timezone || Intl.DateTimeFormat().resolvedOptions().timeZone,
+                    days: (this.notifForm.quiet_days || []).map(Number),
+                    digest: !!this.notifForm.quiet_digest
+                } : null,
+                // 限流与抖动检测，0 表示关闭
+                rate_limit_per_hour: isTrigger ? (parseInt(this.notifForm.rate_limit_per_hour) || 0) : 0,
+                flap_transitions: isTrigger ? (parseInt(this.notifForm.flap_transitions) || 0) : 0,
+                flap_window_minutes: isTrigger ? (parseInt(this.notifForm.flap_window_minutes) || 0) : 0,
+                flap_stable_minutes: isTrigger ? (parseInt(this.notifForm.flap_stable_minutes) || 0) : 0,
+                // 升级：宕机通知发出后持续 DOWN 超过设定分钟数时再通过升级渠道发送，0 表示关闭
+                escalation_after_minutes: escalates ? (parseInt(this.notifForm.escalation_after_minutes) || 0) : 0,
+                escalation_channel: escalates ? this.notifForm.escalation_channel : '',
+                escalation_email: escalates && this.notifForm.escalation_channel === 'email' ? (this.notifForm.escalation_email || '') : '',
+                // 通知语言，空字符串表示使用邮件语言设置
+                language: this.notifForm.language || '',
                 time: isTrigger ? '' : (this.notifForm.time || '09:00'),
                 timezone: Intl.DateTimeFormat().resolvedOptions().timeZone
             };
+            if (isNtfy) {
+                Object.assign(payload, this.ntfyPayload());
+            }
+            if (isGotify) {
+                Object.assign(payload, this.gotifyPayload());
+            }
+            if (isPushover) {
+                Object.assign(payload, this.pushoverPayload());
+            }
+            if (isBark) {
+                Object.assign(payload, this.barkPayload());
+            }
+            if (isDingTalk) {
+                Object.assign(payload, this.dingTalkPayload());
+            }
+            if (isWeCom) {
+                Object.assign(payload, this.weComPayload());
+            }
+            if (isPagerDuty) {
+                Object.assign(payload, this.pagerDutyPayload());
+            }
+            if (isOpsgenie) {
+                Object.assign(payload, this.opsgeniePayload());
+            }
+            if (isTeams) {
+                Object.assign(payload, this.teamsPayload());
+            }
+            // 各渠道的字段会覆盖 channel（升级渠道的字段同样提交），最后恢复为规则的发送渠道；不使用邮件时清空收件邮箱
+            payload.channel = this.notifForm.channel;
+            if (payload.channel !== 'email' && payload.escalation_channel !== 'email') {
+                payload.email = '';
+            }
 
             const event = this.isEditingNotif ? 'editNotification' : 'addNotification';
             this.socket.emit(event, payload, (res) => {
                 if (res && res.ok) {
                     this.showNotifModal = false;
                     this.socket.emit('getNotificationList'); // Refresh list
+                } else if (res && res.conflict) {
+                    const fields = (res.changed_fields || []).join(', ');
+                    this.showConfirm('保存冲突', res.msg + (fields ? `（不一致的字段：${fields}）` : ''), () => {
+                        this.showNotifModal = false;
+                        this.socket.emit('getNotificationList');
+                    }, false, '重新加载');
                 } else {
                     this.showAlert('保存失败', res ? res.msg : '未知错误', 'error');
                 }
             });
         },
 
+        // usesChannel 规则的发送渠道或开启升级时的升级渠道是否为 ch，决定显示和提交哪些渠道字段
+        usesChannel(ch) {
+            const f = this.notifForm;
+            return f.channel === ch || (f.type === 'trigger' && f.escalation_after_minutes > 0 && f.escalation_channel === ch);
+        },
+
+        // testEmail 触发规则发送一次模拟的宕机和恢复邮件，定时报告发送一份示例日报，均使用已保存的自定义模板
+        testEmail() {
+            const payload = {
+                type: this.notifForm.type,
+                id: this.notifForm.id,
+                channel: 'email',
+                email: this.notifForm.email,
+                language: this.notifForm.language || '',
+                redact_details: this.notifForm.type === 'trigger' && !!this.notifForm.redact_details
+            };
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试邮件已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        ntfyPayload() {
+            const p = {
+                channel: 'ntfy',
+                ntfy_server: this.notifForm.ntfy_server || '',
+                ntfy_topic: this.notifForm.ntfy_topic,
+                ntfy_username: this.notifForm.ntfy_username || '',
+                ntfy_priority: parseInt(this.notifForm.ntfy_priority) || 0
+            };
+            if (this.notifForm.ntfy_token) p.ntfy_token = this.notifForm.ntfy_token;
+            if (this.notifForm.ntfy_password) p.ntfy_password = this.notifForm.ntfy_password;
+            return p;
+        },
+
+        testNtfy() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.ntfyPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试推送已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // gotifyPayload 令牌留空时不提交，服务端沿用已保存的值
+        gotifyPayload() {
+            const p = {
+                channel: 'gotify',
+                gotify_server: this.notifForm.gotify_server || ''
+            };
+            if (this.notifForm.gotify_token) p.gotify_token = this.notifForm.gotify_token;
+            return p;
+        },
+
+        testGotify() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.gotifyPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试消息已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // pushoverPayload 用户 Key 和 Token 留空时不提交，服务端沿用已保存的值
+        pushoverPayload() {
+            const p = {
+                channel: 'pushover',
+                pushover_emergency: !!this.notifForm.pushover_emergency,
+                pushover_retry: parseInt(this.notifForm.pushover_retry) || 0,
+                pushover_expire: parseInt(this.notifForm.pushover_expire) || 0
+            };
+            if (this.notifForm.pushover_user) p.pushover_user = this.notifForm.pushover_user;
+            if (this.notifForm.pushover_token) p.pushover_token = this.notifForm.pushover_token;
+            return p;
+        },
+
+        testPushover() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.pushoverPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试消息已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // barkPayload 设备 Key 留空时不提交，服务端沿用已保存的值
+        barkPayload() {
+            const p = {
+                channel: 'bark',
+                bark_server: this.notifForm.bark_server || '',
+                bark_icon_down: this.notifForm.bark_icon_down || '',
+                bark_icon_recovery: this.notifForm.bark_icon_recovery || ''
+            };
+            if (this.notifForm.bark_device_key) p.bark_device_key = this.notifForm.bark_device_key;
+            return p;
+        },
+
+        testBark() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.barkPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试推送已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // dingTalkPayload Webhook 地址和加签密钥留空时不提交，服务端沿用已保存的值
+        dingTalkPayload() {
+            const p = {
+                channel: 'dingtalk',
+                dingtalk_at_all: !!this.notifForm.dingtalk_at_all,
+                dingtalk_at_mobiles: this.notifForm.dingtalk_at_mobiles || ''
+            };
+            if (this.notifForm.dingtalk_webhook) p.dingtalk_webhook = this.notifForm.dingtalk_webhook;
+            if (this.notifForm.dingtalk_secret) p.dingtalk_secret = this.notifForm.dingtalk_secret;
+            return p;
+        },
+
+        testDingTalk() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.dingTalkPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试消息已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // weComPayload 机器人 Key 留空时不提交，服务端沿用已保存的值
+        weComPayload() {
+            const p = { channel: 'wecom' };
+            if (this.notifForm.wecom_key) p.wecom_key = this.notifForm.wecom_key;
+            return p;
+        },
+
+        testWeCom() {
+            const payload = Object.assign({ type: this.notifForm.type, id: this.notifForm.id }, this.weComPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试消息已发送', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // pagerDutyPayload Integration Key 留空时不提交，服务端沿用已保存的值
+        pagerDutyPayload() {
+            const p = { channel: 'pagerduty' };
+            if (this.notifForm.pagerduty_routing_key) p.pagerduty_routing_key = this.notifForm.pagerduty_routing_key;
+            return p;
+        },
+
+        testPagerDuty() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.pagerDutyPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '已创建并解决一个测试事件', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // opsgeniePayload API Key 留空时不提交，服务端沿用已保存的值
+        opsgeniePayload() {
+            const p = {
+                channel: 'opsgenie',
+                opsgenie_region: this.notifForm.opsgenie_region || 'us',
+                opsgenie_priority: this.notifForm.opsgenie_priority || 'P3',
+            };
+            if (this.notifForm.opsgenie_api_key) p.opsgenie_api_key = this.notifForm.opsgenie_api_key;
+            return p;
+        },
+
+        testOpsgenie() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.opsgeniePayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '已创建并关闭一条测试告警', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // teamsPayload Webhook 地址留空时不提交，服务端沿用已保存的值
+        teamsPayload() {
+            const p = {
+                channel: 'teams',
+                teams_format: this.notifForm.teams_format || 'adaptive',
+            };
+            if (this.notifForm.teams_webhook) p.teams_webhook = this.notifForm.teams_webhook;
+            return p;
+        },
+
+        testTeams() {
+            const payload = Object.assign({ type: 'trigger', id: this.notifForm.id }, this.teamsPayload());
+            this.socket.emit('testNotification', payload, (res) => {
+                if (res && res.ok) {
+                    this.showAlert('发送成功', '测试卡片已发送到 Teams', 'success');
+                } else {
+                    this.showAlert('发送失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        // notifTarget 通知列表中显示的发送目标
+        notifTarget(cfg) {
+            if (cfg.channel === 'ntfy') return 'ntfy: ' + cfg.ntfy_topic;
+            if (cfg.channel === 'gotify') return 'Gotify: ' + cfg.gotify_server;
+            if (cfg.channel === 'pushover') return 'Pushover';
+            if (cfg.channel === 'bark') return 'Bark: ' + (cfg.bark_server || 'api.day.app');
+            if (cfg.channel === 'dingtalk') return '钉钉机器人';
+            if (cfg.channel === 'wecom') return '企业微信机器人';
+            if (cfg.channel === 'pagerduty') return 'PagerDuty';
+            if (cfg.channel === 'opsgenie') return 'Opsgenie (' + (cfg.opsgenie_region || 'us').toUpperCase() + ')';
+            if (cfg.channel === 'teams') return 'Microsoft Teams';
+            return cfg.email;
+        },
+
         async toggleNotification(n) {
             this.socket.emit('toggleNotification', n.id);
         },
@@ -623,6 +1261,24 @@ function app() {
             this.socket.emit('exportMonitorConfig');
         },
 
+        // 下载诊断信息（goroutine 栈、内存、调度和数据库连接池状态），用于排查调度卡住或内存增长
+        downloadDiagnostics() {
+            this.socket.emit('getDiagnosticsDump', (res) => {
+                if (!res || !res.ok) {
+                    this.showAlert('下载失败', (res && res.msg) || '无法获取诊断信息', 'error');
+                    return;
+                }
+                const url = URL.createObjectURL(new Blob([res.content], { type: 'text/plain' }));
+                const a = document.createElement('a');
+                a.href = url;
+                a.download = res.filename;
+                document.body.appendChild(a);
+                a.click();
+                a.remove();
+                URL.revokeObjectURL(url);
+            });
+        },
+
         importConfig() {
             const input = document.createElement('input');
             input.type = 'file';
@@ -634,24 +1290,18 @@ function app() {
                 reader.onload = event => {
                     try {
                         const json = JSON.parse(event.target.result);
-                        if (!Array.isArray(json)) {
-                            this.showAlert('导入失败', '文件格式错误：必须是 JSON 数组', 'error');
+                        // 导出的对象 {monitors, notifications, assignments}，或旧版本导出的监控项数组
+                        if (!Array.isArray(json) && !(json && Array.isArray(json.monitors))) {
+                            this.showAlert('导入失败', '文件格式错误：必须是导出的 JSON 文件或监控项数组', 'error');
                             return;
                         }
                         this.socket.emit('importMonitorConfig', json, (res) => {
                             if (res.ok) {
-                                let msg = `<div class="text-left">成功导入 <span class="text-emerald-600 font-bold">${res.imported}</span> 个监控项。`;
-                                if (res.skipped > 0) {
-                                    msg += `<div class="mt-4 pt-3 border-t border-gray-100">
-                                              <div class="text-amber-600 font-bold text-[11px] uppercase tracking-wider mb-2">跳过 ${res.skipped} 个重名项</div>
-                                              <div class="flex flex-wrap gap-1.5 max-h-32 overflow-y-auto pr-1">
-                                                ${res.skippedNames.map(name => `<span class="px-2 py-0.5 bg-amber-50 text-amber-700 rounded-md text-[10px] border border-amber-100 font-medium">${this.escapeHtml(name)}</span>`).join('')}
-                                              </div>
-                                            </div>`;
-                                }
-                                msg += `</div>`;
-                                this.showAlert('导入完成', msg, res.skipped > 0 ? 'warning' : 'success');
-                                this.socket.emit('getMonitorList');
+                                // 导入在后台执行，进度和结果通过 importProgress 事件返回
+                                this.importJobId = res.job_id;
+                                const history = res.heartbeats > 0 ? `（含 ${res.heartbeats} 条历史心跳）` : '';
+                                const rules = res.notifications > 0 ? `和 ${res.notifications} 条通知规则` : '';
+                                this.showAlert('正在导入', `正在后台导入 ${res.total} 个监控项${history}${rules}…`, 'info');
                             } else {
                                 this.showAlert('导入失败', res.msg || '服务器返回错误', 'error');
                             }
@@ -681,17 +1331,119 @@ function app() {
         },
 
         doLogin() {
+            if (this.loginCode !== null) {
+                this.socket.emit('login2FA', { code: this.loginCode }, (res) => {
+                    if (res.ok) {
+                        this.loginCode = null;
+                        this.onLoggedIn(res.token);
+                    } else {
+                        this.loginCode = '';
+                        // 登录已过期或尝试次数过多时需要重新输入密码
+                        if (!/Invalid code/.test(res.msg || '')) this.loginCode = null;
+                        this.showAlert('验证失败', res.msg || '验证码无效', 'error');
+                    }
+                });
+                return;
+            }
             this.socket.emit('login', this.loginForm, (res) => {
                 if (res.ok) {
-                    localStorage.setItem('pinggo_token', res.token);
-                    this.page = 'dashboard';
-                    this.socket.emit('getMonitorList');
+                    this.onLoggedIn(res.token);
+                } else if (res.tokenRequired) {
+                    this.loginCode = '';
                 } else {
                     this.showAlert('登录失败', res.msg || '凭据无效', 'error');
                 }
             });
         },
 
+        onLoggedIn(token) {
+            localStorage.setItem('pinggo_token', token);
+            this.page = 'dashboard';
+            this.socket.emit('getMonitorList');
+            this.loadStatusPages();
+            this.loadIncidents();
+            this.loadMaintenanceWindows();
+            this.loadSubscriptions();
+        },
+
+        openAccount() {
+            this.accountForm = {
+                username: '', currentPassword: '', newPassword: '', confirmPassword: '',
+                // 两步验证：setup 为 setup2FA 返回的密钥和地址，recoveryCodes 只在启用时显示一次
+                twoFactor: null, setup: null, code: '', recoveryCodes: null
+            };
+            this.socket.emit('get2FAStatus', (res) => {
+                if (res && res.ok && this.accountForm) this.accountForm.twoFactor = res;
+            });
+        },
+
+        setup2FA() {
+            this.socket.emit('setup2FA', (res) => {
+                if (res && res.ok) {
+                    this.accountForm.setup = res;
+                    this.accountForm.code = '';
+                } else {
+                    this.showAlert('操作失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        confirm2FA() {
+            this.socket.emit('confirm2FA', { code: this.accountForm.code }, (res) => {
+                if (res && res.ok) {
+                    this.accountForm.setup = null;
+                    this.accountForm.code = '';
+                    this.accountForm.recoveryCodes = res.recovery_codes;
+                    this.accountForm.twoFactor = { enabled: true, recovery_codes_left: res.recovery_codes.length };
+                } else {
+                    this.showAlert('验证失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        disable2FA() {
+            this.socket.emit('disable2FA', { code: this.accountForm.code }, (res) => {
+                if (res && res.ok) {
+                    this.accountForm.code = '';
+                    this.accountForm.twoFactor = { enabled: false, recovery_codes_left: 0 };
+                    this.showAlert('已关闭', '两步验证已关闭', 'success');
+                } else {
+                    this.showAlert('验证失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        changeUsername() {
+            const username = this.accountForm.username.trim();
+            if (!username) return;
+            this.socket.emit('changeUsername', { username }, (res) => {
+                if (res && res.ok) {
+                    this.accountForm.username = '';
+                    this.showAlert('已修改', `用户名已改为 ${res.username}，下次登录时使用新用户名`, 'success');
+                } else {
+                    this.showAlert('修改失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        changePassword() {
+            const f = this.accountForm;
+            if (f.newPassword !== f.confirmPassword) {
+                this.showAlert('密码错误', '两次输入的新密码不一致！', 'warning');
+                return;
+            }
+            this.socket.emit('changePassword', { currentPassword: f.currentPassword, newPassword: f.newPassword }, (res) => {
+                if (res && res.ok) {
+                    // 旧会话已全部失效，当前页面改用新 token
+                    localStorage.setItem('pinggo_token', res.token);
+                    this.accountForm = null;
+                    this.showAlert('已修改', '密码已修改，其他设备上的登录已失效', 'success');
+                } else {
+                    this.showAlert('修改失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
         logout() {
             this.socket.emit('logout', () => {
                 localStorage.removeItem('pinggo_token');
@@ -722,6 +1474,12 @@ function app() {
                 body: '',
                 bodyType: 'application/json',
                 response_regex: '',
+                assert_on_empty_body: false,
+                color: '',
+                icon: '',
+                group_id: 0,
+                badge: false,
+                public_visible: true,
                 formFields: [],
                 headerFields: [],
                 queryFields: []
@@ -745,6 +1503,8 @@ function app() {
 
                     this.monitorForm = {
                         id: data.id,
+                        version: data.version,
+                        _original: data, // 读取时的内容，保存冲突时服务端据此判断哪些字段被他人修改
                         name: data.name,
                         url: data.url, // Now available from secure fetch
                         type: data.type,
@@ -758,6 +1518,12 @@ function app() {
                         body: data.body || '',
                         bodyType: bodyType,
                         response_regex: data.response_regex || '',
+                        assert_on_empty_body: !!data.assert_on_empty_body,
+                        color: data.color || '',
+                        icon: data.icon || '',
+                        group_id: data.group_id || 0,
+                        badge: !!data.badge,
+                        public_visible: data.public_visible !== false,
                         formFields: formFields,
                         headerFields: this.parseHeaderFields(data.headers),
                         queryFields: []
@@ -795,6 +1561,12 @@ function app() {
                         body: data.body || '',
                         bodyType: bodyType,
                         response_regex: data.response_regex || '',
+                        assert_on_empty_body: !!data.assert_on_empty_body,
+                        color: data.color || '',
+                        icon: data.icon || '',
+                        group_id: data.group_id || 0,
+                        badge: !!data.badge,
+                        public_visible: data.public_visible !== false,
                         formFields: formFields,
                         headerFields: this.parseHeaderFields(data.headers),
                         queryFields: []
@@ -851,10 +1623,311 @@ function app() {
             this.socket.emit('getMonitorStats', m.id);
         },
 
+        // openMonitorFromHash 地址为 #/monitor/<id> 时打开对应监控项的详情，打开后清除 hash
+        openMonitorFromHash() {
+            const incident = window.location.hash.match(/^#\/incident\/(\d+)\/(\d+)(?:\?rule=(\d+))?$/);
+            if (incident && this.page === 'dashboard') {
+                history.replaceState(null, '', window.location.pathname + window.location.search);
+                this.openIncident(Number(incident[1]), Number(incident[2]), Number(incident[3] || 0));
+                return;
+            }
+            const match = window.location.hash.match(/^#\/monitor\/(\d+)$/);
+            if (!match || this.page !== 'dashboard') return;
+            const m = this.monitors.find(x => x.id === Number(match[1]));
+            if (!m) return;
+            history.replaceState(null, '', window.location.pathname + window.location.search);
+            this.selectMonitor(m);
+        },
+
+        // openIncident 打开事件视图：触发时间前后 30 分钟的心跳、触发规则、确认状态和最近备注
+        openIncident(monitorId, timestamp, ruleId = 0) {
+            this.destroyChart();
+            this.currentMonitor = null;
+            this.incident = { loading: true, monitorId, timestamp, ruleId };
+            this.incidentNote = '';
+            this.dashboardView = 'incident';
+            this.socket.emit('getIncidentContext', monitorId, timestamp, ruleId, (res) => {
+                if (!res || !res.ok) {
+                    this.incident = { error: (res && res.msg) || '无法加载事件', monitorId, timestamp, ruleId };
+                    return;
+                }
+                this.incident = { ...res, monitorId, timestamp, ruleId };
+            });
+        },
+
+        // acknowledgeIncident 确认事件，备注会出现在事件视图的最近备注中
+        acknowledgeIncident() {
+            const inc = this.incident;
+            if (!inc || inc.ack) return;
+            this.socket.emit('acknowledgeIncident', inc.monitorId, inc.timestamp, this.incidentNote, (res) => {
+                if (!res || !res.ok) {
+                    this.showAlert('确认失败', res ? res.msg : '未知错误', 'error');
+                    return;
+                }
+                this.openIncident(inc.monitorId, inc.timestamp, inc.ruleId);
+            });
+        },
+
         showOverview() {
             this.destroyChart();
             this.currentMonitor = null;
             this.dashboardView = 'overview';
+            this.loadStatusPages();
+            this.loadIncidents();
+            this.loadMaintenanceWindows();
+            this.loadSubscriptions();
+        },
+
+        loadStatusPages() {
+            this.socket.emit('getStatusPages', (res) => {
+                if (res && res.ok) this.statusPages = res.pages || [];
+            });
+        },
+
+        // editStatusPage 打开状态页编辑表单，page 为空时新建
+        editStatusPage(page) {
+            this.statusPageForm = page ? {
+                id: page.id,
+                slug: page.slug,
+                title: page.title,
+                description: page.description,
+                published: page.published,
+                monitor_ids: (page.monitor_ids || []).map(String)
+            } : { id: 0, slug: '', title: '', description: '', published: false, monitor_ids: [] };
+        },
+
+        // saveStatusPage 勾选顺序即状态页中的展示顺序
+        saveStatusPage() {
+            const f = this.statusPageForm;
+            const payload = Object.assign({}, f, { monitor_ids: f.monitor_ids.map(Number) });
+            this.socket.emit('saveStatusPage', payload, (res) => {
+                if (res && res.ok) {
+                    this.statusPageForm = null;
+                    this.loadStatusPages();
+                } else {
+                    this.showAlert('保存失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        deleteStatusPage(page) {
+            this.showConfirm('删除状态页', `确定删除状态页 /status/${page.slug} 吗？此操作无法撤销。`, () => {
+                this.socket.emit('deleteStatusPage', page.id, (res) => {
+                    if (res && res.ok) {
+                        this.loadStatusPages();
+                    } else {
+                        this.showAlert('删除失败', res ? res.msg : '未知错误', 'error');
+                    }
+                });
+            });
+        },
+
+        loadIncidents() {
+            this.socket.emit('getIncidents', (res) => {
+                if (res && res.ok) this.incidents = res.incidents || [];
+            });
+        },
+
+        // incidentDuration 已解决的事件为解决前的持续时间，进行中的为到现在为止的时间
+        incidentDuration(inc) {
+            const end = inc.resolved_at ? new Date(inc.resolved_at) : new Date();
+            const seconds = Math.max(0, Math.round((end - new Date(inc.created_at)) / 1000));
+            if (seconds < 3600) return formatDuration(seconds);
+            const hours = Math.floor(seconds / 3600);
+            const minutes = Math.floor(seconds % 3600 / 60);
+            return hours >= 24 ? `${Math.floor(hours / 24)}天${hours % 24}小时` : `${hours}小时${minutes}分钟`;
+        },
+
+        // editIncident 打开事件编辑表单，inc 为空时新建；不勾选监控项即为全局公告
+        editIncident(inc) {
+            this.incidentForm = inc ? {
+                id: inc.id,
+                title: inc.title,
+                body: inc.body,
+                severity: inc.severity,
+                status: inc.status,
+                monitor_ids: (inc.monitor_ids || []).map(String)
+            } : { id: 0, title: '', body: '', severity: 'minor', status: 'investigating', monitor_ids: [] };
+        },
+
+        saveIncident() {
+            const f = this.incidentForm;
+            const payload = Object.assign({}, f, { monitor_ids: f.monitor_ids.map(Number) });
+            this.socket.emit(f.id ? 'editIncident' : 'addIncident', payload, (res) => {
+                if (res && res.ok) {
+                    this.incidentForm = null;
+                    this.loadIncidents();
+                } else {
+                    this.showAlert('保存失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        resolveIncident(inc) {
+            this.socket.emit('resolveIncident', inc.id, (res) => {
+                if (res && res.ok) {
+                    this.loadIncidents();
+                } else {
+                    this.showAlert('操作失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        deleteIncident(inc) {
+            this.showConfirm('删除事件', `确定删除事件「${inc.title}」吗？此操作无法撤销。`, () => {
+                this.socket.emit('deleteIncident', inc.id, (res) => {
+                    if (res && res.ok) {
+                        this.loadIncidents();
+                    } else {
+                        this.showAlert('删除失败', res ? res.msg : '未知错误', 'error');
+                    }
+                });
+            });
+        },
+
+        loadMaintenanceWindows() {
+            this.socket.emit('getMaintenanceWindows', (res) => {
+                if (res && res.ok) this.maintenanceWindows = res.windows || [];
+            });
+        },
+
+        // maintenanceTarget 维护窗口作用的监控项或标签
+        maintenanceTarget(w) {
+            if (!w.monitor_id) return '标签 ' + w.tag;
+            const m = this.monitors.find(m => m.id === w.monitor_id);
+            return m ? m.name : '#' + w.monitor_id;
+        },
+
+        // maintenanceSchedule 维护窗口的时间说明：一次性窗口为起止时间，周期性窗口为 cron 和每次持续时间
+        maintenanceSchedule(w) {
+            if (!w.cron) return formatDate(w.starts_at) + ' – ' + formatDate(w.ends_at);
+            let text = `${w.cron} · ${w.duration_minutes} 分钟`;
+            if (w.timezone) text += ` · ${w.timezone}`;
+            if (new Date(w.ends_at).getFullYear() < 9999) text += ` · 至 ${formatDate(w.ends_at)}`;
+            return text;
+        },
+
+        // editMaintenanceWindow 打开维护窗口表单，w 为空时新建；新建时勾选多个监控项会为每个监控项各创建一个窗口
+        editMaintenanceWindow(w) {
+            const now = new Date();
+            this.maintenanceForm = w ? {
+                id: w.id,
+                target: w.monitor_id ? 'monitors' : 'tag',
+                monitor_ids: w.monitor_id ? [String(w.monitor_id)] : [],
+                tag: w.tag,
+                recurring: !!w.cron,
+                starts_at: toLocalInput(w.starts_at),
+                ends_at: new Date(w.ends_at).getFullYear() < 9999 ? toLocalInput(w.ends_at) : '',
+                cron: w.cron,
+                duration_minutes: w.duration_minutes || 60,
+                timezone: w.timezone,
+                reason: w.reason
+            } : {
+                id: 0, target: 'monitors', monitor_ids: [], tag: '', recurring: false,
+                starts_at: toLocalInput(now), ends_at: toLocalInput(new Date(now.getTime() + 3600000)),
+                cron: '0 3 * * 0', duration_minutes: 60,
+                timezone: Intl.DateTimeFormat().resolvedOptions().timeZone, reason: ''
+            };
+        },
+
+        saveMaintenanceWindow() {
+            const f = this.maintenanceForm;
+            const payload = {
+                id: f.id,
+                monitor_ids: f.target === 'monitors' ? f.monitor_ids.map(Number) : [],
+                tag: f.target === 'tag' ? f.tag : '',
+                starts_at: f.starts_at ? new Date(f.starts_at).toISOString() : '',
+                ends_at: f.ends_at ? new Date(f.ends_at).toISOString() : '',
+                cron: f.recurring ? f.cron : '',
+                duration_minutes: f.recurring ? Number(f.duration_minutes) : 0,
+                timezone: f.recurring ? f.timezone : '',
+                reason: f.reason
+            };
+            this.socket.emit('saveMaintenanceWindow', payload, (res) => {
+                if (res && res.ok) {
+                    this.maintenanceForm = null;
+                    this.loadMaintenanceWindows();
+                } else {
+                    this.showAlert('保存失败', res ? res.msg : '未知错误', 'error');
+                }
+            });
+        },
+
+        loadSubscriptions() {
+            this.socket.emit('getSubscriptions', (res) => {
+                if (res && res.ok) this.subscriptions = res.subscriptions || [];
+            });
+        },
+
+        deleteSubscription(sub) {
+            this.showConfirm('删除订阅者', `确定删除 ${sub.email} 对「${sub.status_page_title || sub.status_page_slug}」的订阅吗？`, () => {
+                this.socket.emit('deleteSubscription', sub.id, (res) => {
+                    if (res && res.ok) {
+                        this.loadSubscriptions();
+                    } else {
+                        this.showAlert('删除失败', res ? res.msg : '未知错误', 'error');
+                    }
+                });
+            });
+        },
+
+        deleteMaintenanceWindow(w) {
+            this.showConfirm('删除维护窗口', `确定删除「${this.maintenanceTarget(w)}」的维护窗口吗？受影响的监控项会立即恢复检查。`, () => {
+                this.socket.emit('deleteMaintenanceWindow', w.id, (res) => {
+                    if (res && res.ok) {
+                        this.loadMaintenanceWindows();
+                    } else {
+                        this.showAlert('删除失败', res ? res.msg : '未知错误', 'error');
+                    }
+                });
+            });
+        },
+
+        // editMonitorGroup 打开分组编辑表单，group 为空时新建；勾选的监控项保存后移入该分组
+        editMonitorGroup(group) {
+            this.groupForm = group ? {
+                id: group.id,
+                name: group.name,
+                weight: group.weight,
+                monitor_ids: this.monitors.filter(m => m.group_id === group.id).map(m => String(m.id))
+            } : { id: 0, name: '', weight: 2000, monitor_ids: [] };
+        },
+
+        // saveMonitorGroup 先保存名称和权重，再移入勾选的监控项、移出取消勾选的监控项
+        saveMonitorGroup() {
+            const f = this.groupForm;
+            const event = f.id ? 'editMonitorGroup' : 'addMonitorGroup';
+            this.socket.emit(event, { id: f.id, name: f.name, weight: Number(f.weight) }, (res) => {
+                if (!res || !res.ok) {
+                    this.showAlert('保存失败', res ? res.msg : '未知错误', 'error');
+                    return;
+                }
+                const groupId = res.group.id;
+                const selected = f.monitor_ids.map(Number);
+                const removed = this.monitors.filter(m => m.group_id === groupId && !selected.includes(m.id)).map(m => m.id);
+                const done = (r) => {
+                    if (r && !r.ok) this.showAlert('分组成员保存失败', r.msg, 'error');
+                };
+                if (selected.length > 0) this.socket.emit('assignMonitorGroup', { group_id: groupId, monitor_ids: selected }, done);
+                if (removed.length > 0) this.socket.emit('assignMonitorGroup', { group_id: 0, monitor_ids: removed }, done);
+                this.groupForm = null;
+            });
+        },
+
+        deleteMonitorGroup(group) {
+            this.showConfirm('删除分组', `确定删除分组「${group.name}」吗？其中的监控项不会被删除，只会变为未分组。`, () => {
+                this.socket.emit('deleteMonitorGroup', group.id, (res) => {
+                    if (!res || !res.ok) {
+                        this.showAlert('删除失败', res ? res.msg : '未知错误', 'error');
+                    }
+                });
+            });
+        },
+
+        // badgeMarkdown 监控项徽章的 Markdown 片段，可直接粘贴到 README
+        badgeMarkdown(id) {
+            const base = window.location.origin + '/badge/' + id;
+            return `![status](${base}/status.svg) ![uptime](${base}/uptime/30d.svg)`;
         },
 
         saveMonitor() {
@@ -903,14 +1976,20 @@ function app() {
                     } else if (this.isEditing && this.currentMonitor && this.currentMonitor.id === this.monitorForm.id) {
                         this.socket.emit('getMonitor', this.monitorForm.id);
                     }
+                } else if (res.conflict) {
+                    // 已被他人修改：提示对方改动的字段，确认后重新加载表单
+                    const fields = (res.changed_fields || []).join(', ');
+                    this.showConfirm('保存冲突', res.msg + (fields ? `（不一致的字段：${fields}）` : ''), () => {
+                        this.openEditMonitor({ id: this.monitorForm.id });
+                    }, false, '重新加载');
                 } else {
                     this.showAlert('保存失败', res.msg, 'error');
                 }
             });
         },
 
-        testMonitor() {
-            this.isTesting = true;
+        // testMonitorPayload 把编辑表单转换为后端测试使用的监控项数据
+        testMonitorPayload() {
             // Convert formFields to JSON string for backend
             const monitorData = JSON.parse(JSON.stringify(this.monitorForm));
 
@@ -944,7 +2023,12 @@ function app() {
             delete monitorData.headerFields;
             delete monitorData.queryFields;
             delete monitorData.bodyType;
+            return monitorData;
+        },
 
+        testMonitor() {
+            this.isTesting = true;
+            const monitorData = this.testMonitorPayload();
             this.socket.emit('testMonitor', monitorData, (res) => {
                 this.isTesting = false;
                 if (res && res.ok) {
@@ -959,6 +2043,49 @@ function app() {
             });
         },
 
+        // testAssertions 用最近一次测试的响应（服务端缓存 5 分钟）校验响应正则和状态码，不再请求目标地址；
+        // refresh 为 true 或没有缓存时重新请求
+        testAssertions(refresh = false) {
+            this.isTesting = true;
+            const monitorData = this.testMonitorPayload();
+            const assertions = {
+                expected_status: monitorData.expected_status || 0,
+                response_regex: monitorData.response_regex || '',
+                refresh,
+                monitor: monitorData,
+            };
+            this.socket.emit('testMonitorAssertions', monitorData.id || 0, assertions, (res) => {
+                this.isTesting = false;
+                if (!res || !res.ok) {
+                    this.showAlert('校验失败', res ? res.msg : '未知错误', 'error');
+                    return;
+                }
+                const r = res.results || {};
+                const lines = [
+                    `${res.passed ? '✅ 全部通过' : '❌ 未通过'}（${res.cached ? `使用 ${res.age_seconds} 秒前的缓存响应` : '已重新请求'}）`,
+                ];
+                if (r.status) {
+                    lines.push(`状态码: ${r.status.actual}（期望 ${r.status.expected}）${r.status.ok ? '✅' : '❌'}`);
+                }
+                if (r.regex) {
+                    if (r.regex.error) {
+                        lines.push(`正则错误: ${r.regex.error}`);
+                    } else {
+                        lines.push(`正则: ${r.regex.count} 处匹配 ${r.regex.ok ? '✅' : '❌'}`);
+                        (r.regex.matches || []).slice(0, 10).forEach(m => {
+                            lines.push(`  [${m.start}, ${m.end}) ${m.text}`);
+                        });
+                    }
+                }
+                if (res.last_check) {
+                    const status = ['DOWN', 'UP', 'PENDING'][res.last_check.status] || res.last_check.status;
+                    lines.push('', `上次正式检查: ${status}，${res.last_check.msg}`);
+                }
+                lines.push('', `状态码: ${res.status}`, '', `响应信息:\n${res.body}`);
+                this.openMsgDetail(lines.join('\n'));
+            });
+        },
+
         deleteMonitor(id) {
             this.showConfirm('删除监控项', '确定要删除这个监控项吗？相关的历史数据也将被清除。', () => {
                 this.socket.emit('deleteMonitor', id, (res) => {
@@ -989,6 +2116,18 @@ function app() {
             }, true, '清除');
         },
 
+        // monitorIcons 与后端 model.MonitorIcons 对应的图标名称
+        monitorIcons: {
+            server: '🖥️', database: '🗄️', globe: '🌐', cloud: '☁️', api: '🔌', mail: '✉️', shield: '🛡️', lock: '🔒',
+            cart: '🛒', code: '💻', bell: '🔔', phone: '📱', storage: '💾', network: '📡', home: '🏠', star: '⭐'
+        },
+
+        // monitorIconGlyph 图标名称显示为对应的符号，emoji 原样显示
+        monitorIconGlyph(icon) {
+            if (!icon) return '';
+            return this.monitorIcons[icon] || icon;
+        },
+
         statusClass(status, active = 1) {
             if (active === 0) return 'bg-gray-400';
             switch (status) {
@@ -2,14 +2,23 @@ function statusApp() {
     return {
         socket: null,
         monitors: [],
+        // 监控分组及其汇总状态（成员中最严重的状态），折叠状态保存在浏览器中
+        monitorGroups: [],
+        collapsedGroups: JSON.parse(localStorage.getItem('collapsedGroups') || '{}'),
+        // 进行中的事件和公告（incidentList），最新的在前
+        incidents: [],
         overallStatus: 2, // 0: Down, 1: Up, 2: Loading
         lastUpdated: '-',
         searchText: '',
         filterBy: 'None',
         sortBy: 'Name',
         now: Date.now(),
+        // 站点名称、Logo、主题色和页脚，由服务端注入 window.__CONFIG__，连接后通过 getPublicSettings 刷新
+        site: window.__CONFIG__ || {},
 
         init() {
+            if (this.site.siteName) document.title = this.site.siteName;
+
             this.socket = io({
                 transports: ['websocket', 'polling']
             });
@@ -22,6 +31,17 @@ function statusApp() {
             this.socket.on('connect', () => {
                 console.log('Connected to server (status page)');
                 this.socket.emit('getMonitorList');
+                this.socket.emit('getPublicSettings', (site) => {
+                    if (site) this.site = site;
+                });
+            });
+
+            this.socket.on('monitorGroupList', (groups) => {
+                this.monitorGroups = groups || [];
+            });
+
+            this.socket.on('incidentList', (list) => {
+                this.incidents = list || [];
             });
 
             this.socket.on('monitorList', (list) => {
@@ -37,7 +57,7 @@ function statusApp() {
 
                 // Fetch history for each monitor
                 this.monitors.forEach(m => {
-                    this.socket.emit('getHeartbeatList', m.id);
+                    this.socket.emit('getPublicHeartbeatList', m.id);
                 });
 
                 this.updateOverallStatus();
@@ -138,6 +158,20 @@ function statusApp() {
             return result;
         },
 
+        // monitorSections 按分组划分的区块，未分组的监控项放在最后；没有任何分组时只有一个不显示标题的区块
+        get monitorSections() {
+            const list = this.filteredMonitors;
+            if (this.monitorGroups.length === 0) return [{ id: 0, name: '', monitors: list }];
+            const sections = this.monitorGroups.map(g => Object.assign({}, g, { monitors: list.filter(m => m.group_id === g.id) }));
+            sections.push({ id: 0, name: '未分组', status: null, monitors: list.filter(m => !m.group_id) });
+            return sections.filter(s => s.monitors.length > 0);
+        },
+
+        toggleGroup(id) {
+            this.collapsedGroups[id] = !this.collapsedGroups[id];
+            localStorage.setItem('collapsedGroups', JSON.stringify(this.collapsedGroups));
+        },
+
         get monitorTypes() {
             const types = new Set(this.monitors.map(m => m.type.toUpperCase()));
             return ['None', ...Array.from(types)];
@@ -155,11 +189,36 @@ function statusApp() {
                 : '一个或多个服务目前正经历访问问题。';
         },
 
+        // monitorIconGlyph 图标名称显示为对应的符号（与后台一致），emoji 原样显示
+        monitorIconGlyph(icon) {
+            const glyphs = {
+                server: '🖥️', database: '🗄️', globe: '🌐', cloud: '☁️', api: '🔌', mail: '✉️', shield: '🛡️', lock: '🔒',
+                cart: '🛒', code: '💻', bell: '🔔', phone: '📱', storage: '💾', network: '📡', home: '🏠', star: '⭐'
+            };
+            if (!icon) return '';
+            return glyphs[icon] || icon;
+        },
+
+        incidentSeverityLabel(severity) {
+            return { info: '公告', minor: '轻微', major: '严重', critical: '紧急' }[severity] || severity;
+        },
+
+        incidentStatusLabel(status) {
+            return { investigating: '调查中', identified: '已定位', monitoring: '观察中', resolved: '已解决' }[status] || status;
+        },
+
+        // incidentMonitorNames 事件关联的监控项名称，全局公告为空
+        incidentMonitorNames(inc) {
+            return (inc.monitor_ids || []).map(id => (this.monitors.find(m => m.id === id) || {}).name).filter(Boolean).join('、');
+        },
+
         statusLabel(status) {
             switch (status) {
                 case 1: return '健康';
                 case 0: return '异常';
                 case 2: return '检查中';
+                case 3: return '维护中';
+                case -1: return '暂无数据';
                 default: return '未知';
             }
         },
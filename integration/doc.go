@@ -0,0 +1,7 @@
+// Package integration 端到端测试：在临时 SQLite 数据库上启动完整的服务器，通过 Socket.IO 客户端
+// （internal/testutil）和 REST API 走完初始化、登录、监控项增删改和心跳推送等流程，监控项检查的是进程内的 httptest 目标。
+//
+// 测试使用 integration 构建标签，不随 go test ./... 运行：
+//
+//	go test -tags integration ./integration
+package integration
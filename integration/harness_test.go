@@ -0,0 +1,214 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"ping-go/db"
+	"ping-go/internal/testutil"
+	"ping-go/monitor"
+	"ping-go/pkg/logger"
+	"ping-go/server"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	adminUser     = "admin"
+	adminPassword = "integration-admin-pw"
+)
+
+// startServer 在 t.TempDir() 中的临时数据库上启动完整的服务器，监听随机端口。
+// 测试结束时依次关闭服务器、停止监控调度并关闭数据库
+func startServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	logger.Logger = zap.NewNop()
+	if err := db.Init(filepath.Join(t.TempDir(), "pinggo.db")); err != nil {
+		t.Fatalf("db.Init: %v", err)
+	}
+	t.Cleanup(db.Close)
+	svc := monitor.NewService()
+	t.Cleanup(svc.StopAll)
+	ts := httptest.NewServer(server.NewServer(svc, nil).Router())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// startTarget 监控项检查的目标：/ 返回 200，/down 返回 500
+func startTarget(t *testing.T) *httptest.Server {
+	t.Helper()
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			http.Error(w, "maintenance", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(target.Close)
+	return target
+}
+
+// dial 建立一个未登录的 Socket.IO 连接，测试结束时（在关闭服务器之前）断开
+func dial(t *testing.T, ts *httptest.Server) *testutil.Client {
+	t.Helper()
+	c, err := testutil.Dial(ts.URL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// ack 发送带 ack 的事件，返回 ack 的第一个参数
+func ack(t *testing.T, c *testutil.Client, event string, args ...any) map[string]any {
+	t.Helper()
+	reply, err := c.EmitWithAck(event, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", event, err)
+	}
+	if len(reply) == 0 {
+		t.Fatalf("%s: empty ack", event)
+	}
+	m, ok := reply[0].(map[string]any)
+	if !ok {
+		t.Fatalf("%s: ack %v is not an object", event, reply)
+	}
+	return m
+}
+
+// ackOK 同 ack，要求 ok 为 true
+func ackOK(t *testing.T, c *testutil.Client, event string, args ...any) map[string]any {
+	t.Helper()
+	reply := ack(t, c, event, args...)
+	if reply["ok"] != true {
+		t.Fatalf("%s = %v, want ok", event, reply)
+	}
+	return reply
+}
+
+// next 等待服务端发送 event
+func next(t *testing.T, c *testutil.Client, event string) testutil.Event {
+	t.Helper()
+	ev, err := c.Next(event, testutil.Timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+// expectNone 确认短时间内没有收到 event
+func expectNone(t *testing.T, c *testutil.Client, event string) {
+	t.Helper()
+	if ev, err := c.Next(event, 200*time.Millisecond); err == nil {
+		t.Fatalf("unexpected %s: %s", event, ev.Raw)
+	}
+}
+
+// expectSocketError 等待 error 事件并检查 code（未登录为 401）
+func expectSocketError(t *testing.T, c *testutil.Client, code int) {
+	t.Helper()
+	ev := next(t, c, "error")
+	if e, _ := ev.Args[0].(map[string]any); e["code"] != float64(code) {
+		t.Fatalf("error = %s, want code %d", ev.Raw, code)
+	}
+}
+
+// waitHeartbeat 等待指定监控项状态为 status 的心跳，跳过其他心跳
+func waitHeartbeat(t *testing.T, c *testutil.Client, monitorID float64, status int) map[string]any {
+	t.Helper()
+	deadline := time.Now().Add(2 * testutil.Timeout)
+	for {
+		ev, err := c.Next("heartbeat", time.Until(deadline))
+		if err != nil {
+			t.Fatalf("no heartbeat with status %d for monitor %v: %v", status, monitorID, err)
+		}
+		if hb, _ := ev.Args[0].(map[string]any); hb["monitorID"] == monitorID && hb["status"] == float64(status) {
+			return hb
+		}
+	}
+}
+
+// waitStoredHeartbeats 心跳先推送、再批量写入数据库，轮询 getHeartbeatList 直到至少有 n 条
+func waitStoredHeartbeats(t *testing.T, c *testutil.Client, monitorID float64, n int) []any {
+	t.Helper()
+	deadline := time.Now().Add(db.HeartbeatFlushInterval + testutil.Timeout)
+	for {
+		list := ackOK(t, c, "getHeartbeatList", monitorID, map[string]any{})
+		if rows, _ := list["data"].([]any); len(rows) >= n {
+			return rows
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("getHeartbeatList = %v, want at least %d heartbeats", list, n)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// setupAdmin 完成初始化并以管理员登录，返回已登录的连接
+func setupAdmin(t *testing.T, ts *httptest.Server) *testutil.Client {
+	t.Helper()
+	c := dial(t, ts)
+	ackOK(t, c, "setup", map[string]any{"username": adminUser, "password": adminPassword, "confirmPassword": adminPassword})
+	ackOK(t, c, "login", map[string]any{"username": adminUser, "password": adminPassword})
+	return c
+}
+
+// login 以账号密码登录一个新连接
+func login(t *testing.T, ts *httptest.Server, username, password string) *testutil.Client {
+	t.Helper()
+	c := dial(t, ts)
+	ackOK(t, c, "login", map[string]any{"username": username, "password": password})
+	return c
+}
+
+// addMonitor 通过 add 事件添加检查 url 的 HTTP 监控项，返回监控项 ID
+func addMonitor(t *testing.T, c *testutil.Client, name, url string) float64 {
+	t.Helper()
+	reply := ackOK(t, c, "add", map[string]any{"name": name, "type": "http", "url": url, "interval": 60})
+	id, _ := reply["monitorID"].(float64)
+	if id == 0 {
+		t.Fatalf("add = %v, want monitorID", reply)
+	}
+	return id
+}
+
+// doAPI 以 Bearer token 调用 REST API，返回状态码和解析后的 JSON 响应
+func doAPI(t *testing.T, ts *httptest.Server, method, path, token, body string) (int, map[string]any) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("%s %s: invalid JSON: %v", method, path, err)
+	}
+	return resp.StatusCode, out
+}
+
+// monitorPath REST API 中监控项的路径
+func monitorPath(id float64, suffix string) string {
+	return fmt.Sprintf("/api/v1/monitors/%d%s", int(id), suffix)
+}
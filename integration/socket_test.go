@@ -0,0 +1,237 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	"testing"
+)
+
+// 初始化 → 登录 → 添加监控项 → 收到心跳 → 编辑 → 统计 → 删除
+func TestCoreFlow(t *testing.T) {
+	ts := startServer(t)
+	target := startTarget(t)
+	c := dial(t, ts)
+
+	if reply := ack(t, c, "checkSetup"); reply["needSetup"] != true {
+		t.Fatalf("checkSetup = %v, want needSetup", reply)
+	}
+	ackOK(t, c, "setup", map[string]any{"username": adminUser, "password": adminPassword, "confirmPassword": adminPassword})
+	if reply := ack(t, c, "setup", map[string]any{"username": "other", "password": adminPassword}); reply["ok"] != false {
+		t.Fatalf("second setup = %v, want rejected", reply)
+	}
+	if reply := ack(t, c, "checkSetup"); reply["needSetup"] != false {
+		t.Fatalf("checkSetup after setup = %v", reply)
+	}
+	if token, _ := ackOK(t, c, "login", map[string]any{"username": adminUser, "password": adminPassword})["token"].(string); token == "" {
+		t.Fatal("login returned no token")
+	}
+
+	// 添加后立即检查一次，结果通过 heartbeat 推送
+	id := addMonitor(t, c, "target", target.URL)
+	waitHeartbeat(t, c, id, model.StatusUp)
+
+	c.Emit("getMonitor", id)
+	m, _ := next(t, c, "monitor").Args[0].(map[string]any)
+	if m["name"] != "target" || m["version"] == nil {
+		t.Fatalf("monitor = %v", m)
+	}
+	// 不带 version 的编辑被拒绝，带上读取到的 version 后保存并重新检查
+	edit := map[string]any{"id": id, "name": "target-down", "type": "http", "url": target.URL + "/down", "interval": 60}
+	if reply := ack(t, c, "edit", edit); reply["ok"] != false || reply["code"] != float64(428) {
+		t.Fatalf("edit without version = %v, want 428", reply)
+	}
+	edit["version"] = m["version"]
+	ackOK(t, c, "edit", edit)
+	hb := waitHeartbeat(t, c, id, model.StatusDown)
+	if hb["msg"] == "" {
+		t.Fatalf("DOWN heartbeat without message: %v", hb)
+	}
+
+	c.Emit("getMonitorStats", id)
+	stats := next(t, c, "monitorStats")
+	if stats.Args[0] != id {
+		t.Fatalf("monitorStats = %s", stats.Raw)
+	}
+	rows := waitStoredHeartbeats(t, c, id, 2)
+	if latest, _ := rows[0].(map[string]any); latest["status"] != float64(model.StatusDown) {
+		t.Fatalf("latest stored heartbeat = %v, want DOWN", latest)
+	}
+
+	ackOK(t, c, "deleteMonitor", id)
+	var n int64
+	db.DB.Model(&model.Monitor{}).Where("id = ?", id).Count(&n)
+	if n != 0 {
+		t.Fatal("monitor still exists after deleteMonitor")
+	}
+	c.Emit("getMonitor", id)
+	expectNone(t, c, "monitor")
+}
+
+// 登录失败的连接仍是未登录状态，修改类事件返回 401 且不生效
+func TestBadLogin(t *testing.T) {
+	ts := startServer(t)
+	setupAdmin(t, ts)
+	c := dial(t, ts)
+
+	for _, creds := range []map[string]any{
+		{"username": adminUser, "password": "wrong-password"},
+		{"username": "nobody", "password": adminPassword},
+	} {
+		reply := ack(t, c, "login", creds)
+		if reply["ok"] != false || reply["token"] != nil || reply["msg"] != "Invalid username or password" {
+			t.Fatalf("login %v = %v, want rejected", creds, reply)
+		}
+	}
+	if reply := ack(t, c, "auth", map[string]any{"token": "not-a-session"}); reply["ok"] == true {
+		t.Fatalf("auth with an unknown token = %v", reply)
+	}
+
+	for _, ev := range []struct {
+		name string
+		args []any
+	}{
+		{"add", []any{map[string]any{"name": "sneaky", "type": "http", "url": "http://example.invalid", "interval": 60}}},
+		{"deleteMonitor", []any{1}},
+		{"clearEvents", []any{1}},
+		{"createApiKey", []any{map[string]any{"name": "sneaky"}}},
+		{"getSettings", nil},
+	} {
+		t.Run(ev.name, func(t *testing.T) {
+			if err := c.Emit(ev.name, ev.args...); err != nil {
+				t.Fatal(err)
+			}
+			expectSocketError(t, c, 401)
+		})
+	}
+	var n int64
+	db.DB.Model(&model.Monitor{}).Count(&n)
+	if n != 0 {
+		t.Fatalf("%d monitors created by an unauthenticated socket", n)
+	}
+}
+
+// viewer 可以读取，修改类事件返回 403
+func TestViewerForbidden(t *testing.T) {
+	ts := startServer(t)
+	target := startTarget(t)
+	admin := setupAdmin(t, ts)
+	id := addMonitor(t, admin, "target", target.URL)
+	ackOK(t, admin, "createUser", map[string]any{"username": "viewer", "password": "integration-viewer-pw", "role": model.RoleViewer})
+
+	viewer := login(t, ts, "viewer", "integration-viewer-pw")
+	ackOK(t, viewer, "getHeartbeatList", id, map[string]any{})
+
+	for _, ev := range []struct {
+		name string
+		args []any
+	}{
+		{"add", []any{map[string]any{"name": "viewer-added", "type": "http", "url": target.URL, "interval": 60}}},
+		{"deleteMonitor", []any{id}},
+		{"clearEvents", []any{id}},
+		{"createApiKey", []any{map[string]any{"name": "viewer-key"}}},
+	} {
+		t.Run(ev.name, func(t *testing.T) {
+			reply := ack(t, viewer, ev.name, ev.args...)
+			if reply["ok"] != false || reply["code"] != float64(403) {
+				t.Fatalf("viewer %s = %v, want 403", ev.name, reply)
+			}
+		})
+	}
+	var names []string
+	db.DB.Model(&model.Monitor{}).Pluck("name", &names)
+	if len(names) != 1 || names[0] != "target" {
+		t.Fatalf("monitors after viewer events = %v", names)
+	}
+	var keys int64
+	db.DB.Model(&model.APIKey{}).Count(&keys)
+	if keys != 0 {
+		t.Fatalf("viewer created %d API keys", keys)
+	}
+}
+
+// read 密钥可以读取，修改返回 403；未知密钥返回 401
+func TestAPIKeyScope(t *testing.T) {
+	ts := startServer(t)
+	target := startTarget(t)
+	admin := setupAdmin(t, ts)
+	id := addMonitor(t, admin, "target", target.URL)
+	readKey, _ := ackOK(t, admin, "createApiKey", map[string]any{"name": "dashboard", "scope": model.APIKeyScopeRead})["key"].(string)
+	writeKey, _ := ackOK(t, admin, "createApiKey", map[string]any{"name": "ci", "scope": model.APIKeyScopeWrite})["key"].(string)
+
+	if code, body := doAPI(t, ts, http.MethodGet, monitorPath(id, ""), readKey, ""); code != http.StatusOK || body["name"] != "target" {
+		t.Fatalf("GET with read key = %d %v", code, body)
+	}
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPatch, monitorPath(id, "/active"), `{"active":false}`},
+		{http.MethodDelete, monitorPath(id, "/heartbeats"), ""},
+		{http.MethodDelete, monitorPath(id, ""), ""},
+	} {
+		code, body := doAPI(t, ts, req.method, req.path, readKey, req.body)
+		if code != http.StatusForbidden || body["code"] != float64(http.StatusForbidden) || body["message"] == "" {
+			t.Fatalf("%s %s with read key = %d %v, want a 403 AppError", req.method, req.path, code, body)
+		}
+	}
+	// write 密钥不能访问需要 admin 的接口
+	if code, body := doAPI(t, ts, http.MethodGet, "/api/v1/settings", writeKey, ""); code != http.StatusForbidden {
+		t.Fatalf("GET /api/v1/settings with write key = %d %v, want 403", code, body)
+	}
+	if code, body := doAPI(t, ts, http.MethodGet, monitorPath(id, ""), "pgk_unknown", ""); code != http.StatusUnauthorized {
+		t.Fatalf("unknown key = %d %v, want 401", code, body)
+	}
+
+	var m model.Monitor
+	db.DB.First(&m, uint(id))
+	if m.Active != 1 {
+		t.Fatal("read key paused the monitor")
+	}
+	code, body := doAPI(t, ts, http.MethodPatch, monitorPath(id, "/active"), writeKey, `{"active":false}`)
+	if code != http.StatusOK || body["active"] != float64(0) {
+		t.Fatalf("PATCH with write key = %d %v", code, body)
+	}
+}
+
+// 参数格式错误的事件被忽略，不影响连接和数据
+func TestMalformedArguments(t *testing.T) {
+	ts := startServer(t)
+	target := startTarget(t)
+	c := setupAdmin(t, ts)
+	id := addMonitor(t, c, "target", target.URL)
+
+	for _, ev := range []struct {
+		name string
+		args []any
+	}{
+		{"add", nil},
+		{"add", []any{"not an object"}},
+		{"edit", []any{[]any{1, 2}}},
+		{"edit", []any{map[string]any{"id": "abc"}}},
+		{"deleteMonitor", []any{"abc"}},
+		{"deleteMonitor", []any{map[string]any{"id": id}}},
+		{"getMonitorStats", []any{nil}},
+		{"getHeartbeatList", []any{"abc", "not options"}},
+		{"clearEvents", []any{-1}},
+		{"login", []any{42}},
+		{"setup", []any{"admin"}},
+	} {
+		if err := c.Emit(ev.name, ev.args...); err != nil {
+			t.Fatalf("%s: %v", ev.name, err)
+		}
+	}
+
+	// 连接仍然可用，仍是已登录状态，监控项没有被修改
+	if reply := ack(t, c, "checkSetup"); reply["needSetup"] != false {
+		t.Fatalf("checkSetup = %v", reply)
+	}
+	c.Emit("getMonitor", id)
+	if m, _ := next(t, c, "monitor").Args[0].(map[string]any); m["name"] != "target" {
+		t.Fatalf("monitor = %v", m)
+	}
+	var users int64
+	db.DB.Model(&model.User{}).Count(&users)
+	if users != 1 {
+		t.Fatalf("%d users after malformed setup", users)
+	}
+}
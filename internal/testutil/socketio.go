@@ -0,0 +1,291 @@
+// Package testutil 测试辅助代码。Client 是测试用的 Socket.IO 客户端，使用 Engine.IO v4 的 HTTP 长轮询传输连接默认命名空间，
+// 只支持 JSON 事件和 ack（与前端使用的功能相同），用于在 httptest.Server 上测试 Socket 事件
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeout 等待 ack 和事件的默认时间
+const Timeout = 5 * time.Second
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("testutil: connection closed")
+
+// Event 服务端发送的一个事件
+type Event struct {
+	Name string
+	Args []any
+	Raw  string // 事件的原始 JSON 数组，用于检查内容中不应出现的字符串
+}
+
+// Client 一个 Socket.IO 连接。后台持续轮询，收到的事件按顺序缓存，由 Next 取出
+type Client struct {
+	endpoint string // 带 sid 的轮询地址
+	http     *http.Client
+	ctx      context.Context
+	cancel   context.CancelFunc
+	postMu   sync.Mutex // Engine.IO 不允许同一连接并发 POST
+	done     chan struct{}
+
+	mu      sync.Mutex
+	events  []Event
+	acks    map[int]chan []any
+	nextAck int
+	notify  chan struct{} // 收到新事件时关闭并替换
+	err     error
+}
+
+// Dial 连接 serverURL（如 httptest.Server.URL）上的 /socket.io/，返回时已连接默认命名空间
+func Dial(serverURL string) (*Client, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		http:   &http.Client{},
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		acks:   make(map[int]chan []any),
+		notify: make(chan struct{}),
+	}
+	endpoint := strings.TrimRight(serverURL, "/") + "/socket.io/?EIO=4&transport=polling"
+	packets, err := c.get(endpoint)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	var handshake struct {
+		Sid string `json:"sid"`
+	}
+	if len(packets) == 0 || !strings.HasPrefix(packets[0], "0") || json.Unmarshal([]byte(packets[0][1:]), &handshake) != nil || handshake.Sid == "" {
+		cancel()
+		return nil, fmt.Errorf("testutil: unexpected handshake %q", packets)
+	}
+	c.endpoint = endpoint + "&sid=" + url.QueryEscape(handshake.Sid)
+
+	if err := c.post("40"); err != nil {
+		cancel()
+		return nil, err
+	}
+	for connected := false; !connected; {
+		packets, err := c.get(c.endpoint)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		for _, p := range packets {
+			switch {
+			case strings.HasPrefix(p, "40"):
+				connected = true
+			case strings.HasPrefix(p, "44"):
+				cancel()
+				return nil, fmt.Errorf("testutil: connection rejected: %s", p[2:])
+			default:
+				c.handle(p)
+			}
+		}
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Emit 发送事件，不等待 ack
+func (c *Client) Emit(event string, args ...any) error {
+	payload, err := json.Marshal(append([]any{event}, args...))
+	if err != nil {
+		return err
+	}
+	return c.post("42" + string(payload))
+}
+
+// EmitWithAck 发送带 ack 的事件并等待服务端的回调参数，Timeout 内没有回调时返回错误
+func (c *Client) EmitWithAck(event string, args ...any) ([]any, error) {
+	payload, err := json.Marshal(append([]any{event}, args...))
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan []any, 1)
+	c.mu.Lock()
+	id := c.nextAck
+	c.nextAck++
+	c.acks[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.acks, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.post("42" + strconv.Itoa(id) + string(payload)); err != nil {
+		return nil, err
+	}
+	select {
+	case args := <-ch:
+		return args, nil
+	case <-time.After(Timeout):
+		return nil, fmt.Errorf("testutil: no ack for %s within %v", event, Timeout)
+	case <-c.done:
+		return nil, c.closedErr()
+	}
+}
+
+// Next 取出下一个名为 event 的事件（包括连接后、调用前收到的），timeout 内没有时返回错误
+func (c *Client) Next(event string, timeout time.Duration) (Event, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		c.mu.Lock()
+		for i, ev := range c.events {
+			if ev.Name == event {
+				c.events = append(c.events[:i], c.events[i+1:]...)
+				c.mu.Unlock()
+				return ev, nil
+			}
+		}
+		notify := c.notify
+		c.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-deadline.C:
+			return Event{}, fmt.Errorf("testutil: no %s event within %v", event, timeout)
+		case <-c.done:
+			return Event{}, c.closedErr()
+		}
+	}
+}
+
+// Close 通知服务端关闭连接并停止轮询
+func (c *Client) Close() {
+	c.post("1")
+	c.cancel()
+	<-c.done
+}
+
+func (c *Client) closedErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return ErrClosed
+}
+
+// readLoop 持续长轮询，直到连接关闭或出错
+func (c *Client) readLoop() {
+	defer close(c.done)
+	for {
+		packets, err := c.get(c.endpoint)
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			return
+		}
+		for _, p := range packets {
+			if p == "1" {
+				return
+			}
+			c.handle(p)
+		}
+	}
+}
+
+// handle 处理一个 Engine.IO 包：回复 ping，缓存事件，分发 ack
+func (c *Client) handle(p string) {
+	switch {
+	case p == "2":
+		c.post("3")
+	case strings.HasPrefix(p, "42"), strings.HasPrefix(p, "43"):
+		id, data := splitAckID(p[2:])
+		var args []any
+		if err := json.Unmarshal([]byte(data), &args); err != nil {
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if p[1] == '3' {
+			if ch, ok := c.acks[id]; ok {
+				ch <- args
+			}
+			return
+		}
+		if len(args) == 0 {
+			return
+		}
+		name, _ := args[0].(string)
+		c.events = append(c.events, Event{Name: name, Args: args[1:], Raw: data})
+		close(c.notify)
+		c.notify = make(chan struct{})
+	}
+}
+
+// splitAckID 拆分 Socket.IO 包中命名空间之后的 ack id 和 JSON 数据，没有 id 时返回 -1
+func splitAckID(s string) (int, string) {
+	if strings.HasPrefix(s, "/") {
+		if i := strings.IndexByte(s, ','); i >= 0 {
+			s = s[i+1:]
+		}
+	}
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return -1, s
+	}
+	id, _ := strconv.Atoi(s[:i])
+	return id, s[i:]
+}
+
+// get 发送一次轮询请求，返回其中的包（多个包以 0x1e 分隔）
+func (c *Client) get(u string) ([]string, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("testutil: poll returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return strings.Split(string(body), "\x1e"), nil
+}
+
+// post 发送一个包
+func (c *Client) post(packet string) error {
+	c.postMu.Lock()
+	defer c.postMu.Unlock()
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.endpoint, strings.NewReader(packet))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("testutil: send returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
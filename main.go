@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"ping-go/agent"
+	"ping-go/cli"
 	"ping-go/config"
 	"ping-go/db"
 	"ping-go/monitor"
@@ -22,6 +24,16 @@ import (
 var distFS embed.FS
 
 func main() {
+	// 远程探针模式：执行中心服务器分配的监控项并上报结果，不启动服务
+	if agent.IsAgentMode(os.Args[1:]) {
+		os.Exit(agent.Run(os.Args[1:], os.Stderr))
+	}
+
+	// 子命令（monitor、export、check、status）作为 REST API 客户端运行，不启动服务
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	log.Println("Starting ping-go...")
 
 	// Load Config
@@ -36,6 +48,22 @@ func main() {
 
 	// Initialize Monitor Service
 	monitorService := monitor.NewService()
+	monitorService.ConfigureCheckEvents(config.Get().Logging.CheckEvents)
+
+	// SIGHUP 重新加载配置文件（端口和多实例配置除外），并重新打开检查事件输出
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			cfg, err := config.Reload("config.yaml")
+			if err != nil {
+				log.Printf("Failed to reload config.yaml, keeping the current configuration: %v", err)
+				continue
+			}
+			monitorService.ConfigureCheckEvents(cfg.Logging.CheckEvents)
+			log.Println("Reloaded configuration")
+		}
+	}()
 
 	// Static Files (Embedded)
 	distRoot, _ := fs.Sub(distFS, "dist")
@@ -45,18 +73,20 @@ func main() {
 	srv := server.NewServer(monitorService, staticFS)
 	srv.SetStatic(staticFS)
 
-	// Start Monitoring AFTER server initialization to ensure OnStatusChange is set
-	monitorService.Start()
+	// 多实例模式：只有领导者执行调度
+	if ha := config.Get().HA; ha.Enabled {
+		monitorService.EnableHA(ha.InstanceID, ha.LeaseSeconds)
+	}
 
 	// Check for RESEND_API_KEY
-	if config.GlobalConfig.Notification.ResendAPIKey == "" {
+	if config.Get().Notification.ResendAPIKey == "" {
 		log.Println("Warning: RESEND_API_KEY is not set in config.yaml. Email notifications will fail.")
 	}
 
 	// Run Server
 	port := ":3001"
-	if config.GlobalConfig.Server.Port != 0 {
-		port = ":" + strconv.Itoa(config.GlobalConfig.Server.Port)
+	if p := config.Get().Server.Port; p != 0 {
+		port = ":" + strconv.Itoa(p)
 	}
 
 	httpSrv := &http.Server{
@@ -73,6 +103,10 @@ func main() {
 		}
 	}()
 
+	// Start Monitoring AFTER server initialization to ensure OnStatusChange is set.
+	// 监听已经开始，调度在后台分批进行，/health?mode=readiness 在全部调度完成前返回 503
+	monitorService.Start()
+
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
 	quit := make(chan os.Signal, 1)
@@ -95,6 +129,7 @@ func main() {
 	// Stop Monitor Service
 	log.Println("Stopping monitor service...")
 	monitorService.StopAll()
+	monitorService.ConfigureCheckEvents("")
 
 	// Close Database (includes flushing buffer)
 	db.Close()
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+const (
+	// AgentPollInterval 探针拉取分配的监控项和上报结果的间隔
+	AgentPollInterval = 30 * time.Second
+	// AgentOfflineAfter 探针超过该时间没有请求视为离线
+	AgentOfflineAfter = 3 * time.Minute
+)
+
+// Agent 远程探针：在其他地区运行 pinggo --agent，执行分配给它的监控项并上报心跳。
+// 令牌只保存 SHA-256 摘要；Offline 由定时检查设置，探针再次上报时清除
+type Agent struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `json:"name"`
+	Region      string     `json:"region"`
+	TokenHash   string     `gorm:"uniqueIndex" json:"-"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeen    *time.Time `json:"last_seen"`
+	Offline     bool       `json:"offline"`
+}
+
+// Online 探针在 now 时是否在线：最近 AgentOfflineAfter 内有过请求
+func (a Agent) Online(now time.Time) bool {
+	return a.LastSeen != nil && now.Sub(*a.LastSeen) < AgentOfflineAfter
+}
+
+// AgentMonitor 分配给探针的监控项，同时保存该探针最近一次的检查结果（各地区的状态）
+type AgentMonitor struct {
+	AgentID   uint       `gorm:"primaryKey" json:"agent_id"`
+	MonitorID uint       `gorm:"primaryKey;index" json:"monitor_id"`
+	Status    int        `json:"status"`
+	Message   string     `json:"msg"`
+	LastCheck *time.Time `json:"last_check"`
+}
+
+// HeartbeatAgentHourly 探针心跳的小时级聚合，按监控项和探针分别统计。
+// HeartbeatHourly 是所有来源（中心服务器和各探针）合并后的统计，中心服务器自己的部分为两者之差
+type HeartbeatAgentHourly struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	MonitorID   uint      `gorm:"index:idx_agent_hourly_monitor_time" json:"monitorID"`
+	AgentID     uint      `gorm:"index" json:"agent_id"`
+	Hour        time.Time `gorm:"index:idx_agent_hourly_monitor_time" json:"hour"`
+	UpCount     int       `json:"upCount"`
+	DownCount   int       `json:"downCount"`
+	TotalCount  int       `json:"totalCount"`
+	SumDuration int       `json:"sumDuration"` // 成功响应的延迟总和
+}
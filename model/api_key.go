@@ -0,0 +1,81 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// API 密钥的权限，按从低到高排列：read 只能读取监控项、心跳和统计；
+// write 还可以创建、修改和删除监控项；admin 还可以访问通知规则、设置、导入导出和备份
+const (
+	APIKeyScopeRead  = "read"
+	APIKeyScopeWrite = "write"
+	APIKeyScopeAdmin = "admin"
+)
+
+// APIKeyScopes 所有权限，按从低到高排列
+var APIKeyScopes = []string{APIKeyScopeRead, APIKeyScopeWrite, APIKeyScopeAdmin}
+
+// APIKey REST API 密钥，只保存 SHA-256 摘要。Scope 为密钥的权限（read / write / admin），
+// TagScope 非空时只能访问带有其中任一标签的监控项。吊销的密钥保留在列表中以便查看，但不能再用于认证
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `json:"name"`
+	KeyHash    string     `gorm:"uniqueIndex" json:"-"`
+	Prefix     string     `json:"prefix"` // 明文前几位，用于在列表中辨认
+	Scope      string     `gorm:"default:admin" json:"scope"`
+	TagScope   string     `json:"tag_scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"` // nil 表示永不过期
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Usable 密钥在 now 时是否可以用于认证：未吊销且未过期
+func (k APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Allows 密钥的权限是否不低于 scope。添加权限之前创建的密钥（Scope 为空）视为 admin
+func (k APIKey) Allows(scope string) bool {
+	own := k.Scope
+	if own == "" {
+		own = APIKeyScopeAdmin
+	}
+	return slices.Index(APIKeyScopes, own) >= slices.Index(APIKeyScopes, scope)
+}
+
+// NormalizeTags 规范化逗号分隔的标签：去除空白、转为小写、去重，保持原有顺序
+func NormalizeTags(s string) string {
+	return strings.Join(SplitTags(s), ",")
+}
+
+// SplitTags 将逗号分隔的标签拆分为列表
+func SplitTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// InScope 监控项是否在标签范围内：范围为空表示不限制，否则监控项需带有其中任一标签
+func (m Monitor) InScope(scope []string) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	for _, t := range SplitTags(m.Tags) {
+		for _, s := range scope {
+			if t == s {
+				return true
+			}
+		}
+	}
+	return false
+}
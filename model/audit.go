@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// AuditLog 管理操作的审计记录
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Actor     string    `json:"actor"`  // 操作者，如 api_key:deploy-bot、user:admin
+	Action    string    `json:"action"` // 如 maintenance.create、maintenance.end
+	Target    string    `json:"target"`
+	Detail    string    `json:"detail"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// 事件严重程度，info 用于不影响服务的公告（如计划变更通知）
+const (
+	IncidentSeverityInfo     = "info"
+	IncidentSeverityMinor    = "minor"
+	IncidentSeverityMajor    = "major"
+	IncidentSeverityCritical = "critical"
+)
+
+// 事件处理进度，resolved 为已解决，其余为进行中
+const (
+	IncidentInvestigating = "investigating"
+	IncidentIdentified    = "identified"
+	IncidentMonitoring    = "monitoring"
+	IncidentResolved      = "resolved"
+)
+
+// IncidentSeverities 和 IncidentStatuses 所有可用的严重程度和处理进度，按展示顺序排列
+var (
+	IncidentSeverities = []string{IncidentSeverityInfo, IncidentSeverityMinor, IncidentSeverityMajor, IncidentSeverityCritical}
+	IncidentStatuses   = []string{IncidentInvestigating, IncidentIdentified, IncidentMonitoring, IncidentResolved}
+)
+
+// Incident 管理员发布的事件或公告，展示在公开仪表盘和状态页中。
+// MonitorIDs 为受影响的监控项，为空表示全局公告；解决时记录 ResolvedAt，用于显示事件持续时间
+type Incident struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Severity   string     `json:"severity"`
+	Status     string     `gorm:"index" json:"status"`
+	MonitorIDs []uint     `gorm:"serializer:json" json:"monitor_ids"`
+	CreatedBy  string     `json:"created_by"` // 如 user:admin
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Active 事件是否仍在进行中
+func (i Incident) Active() bool {
+	return i.Status != IncidentResolved
+}
+
+// Duration 事件持续时间：已解决的为创建到解决的时间，进行中的为创建到 now 的时间
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.ResolvedAt != nil {
+		return i.ResolvedAt.Sub(i.CreatedAt)
+	}
+	return now.Sub(i.CreatedAt)
+}
+
+// Global 是否为不关联监控项的全局公告
+func (i Incident) Global() bool {
+	return len(i.MonitorIDs) == 0
+}
+
+// Affects 事件是否关联了该监控项
+func (i Incident) Affects(monitorID uint) bool {
+	return slices.Contains(i.MonitorIDs, monitorID)
+}
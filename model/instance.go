@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 实例角色
+const (
+	RoleStandalone = "standalone" // 未启用多实例模式
+	RoleLeader     = "leader"     // 持有调度租约，负责监控调度、数据聚合和通知发送
+	RoleFollower   = "follower"   // 只提供 Web/Socket 服务，等待接管
+)
+
+// Instance 多实例模式下的实例登记，LastSeen 由各实例定期刷新
+type Instance struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Hostname  string    `json:"hostname"`
+	Role      string    `json:"role"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `gorm:"index" json:"last_seen"`
+}
+
+// Lease 数据库租约：同一时刻只有一个 Holder，过期后可被其他实例抢占
+type Lease struct {
+	Name      string    `gorm:"primaryKey" json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
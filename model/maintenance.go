@@ -0,0 +1,86 @@
+package model
+
+import (
+	"ping-go/pkg/cron"
+	"time"
+)
+
+// MaintenanceForever 周期性窗口未设置失效时间时使用的结束时间
+var MaintenanceForever = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// MaxMaintenanceOccurrence 周期性窗口单次持续的最长时间
+const MaxMaintenanceOccurrence = 24 * time.Hour
+
+// MaintenanceWindow 维护窗口：窗口内不执行检查、不发送通知，状态显示为维护中。
+// MonitorID 为 0 时作用于带有 Tag 标签的全部监控项（包括之后添加该标签的监控项）。
+// Cron 为空时是 [StartsAt, EndsAt) 的一次性窗口，同一监控项重叠的一次性窗口会被合并为一个；
+// Cron 非空时在 Timezone 时区按表达式每次触发后持续 DurationMinutes 分钟，StartsAt/EndsAt 为规则的有效期。
+// 提前关闭时 EndsAt 改为关闭时间并记录 EndedAt
+type MaintenanceWindow struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	MonitorID       uint       `gorm:"index:idx_maintenance_monitor_end" json:"monitor_id"`
+	Tag             string     `gorm:"index" json:"tag"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          time.Time  `gorm:"index:idx_maintenance_monitor_end" json:"ends_at"`
+	Cron            string     `json:"cron"`             // 5 段 cron 表达式，如 "0 3 * * 0"
+	DurationMinutes int        `json:"duration_minutes"` // 周期性窗口每次持续的分钟数
+	Timezone        string     `json:"timezone"`         // IANA 时区名，空表示服务器时区
+	Reason          string     `json:"reason"`
+	CreatedBy       string     `json:"created_by"` // 如 api_key:deploy-bot、user:admin
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"` // 被提前关闭的时间
+}
+
+// ActiveAt 窗口在 t 时刻是否生效
+func (w MaintenanceWindow) ActiveAt(t time.Time) bool {
+	_, ok := w.ActiveUntil(t)
+	return ok
+}
+
+// ActiveUntil 窗口在 t 时刻生效时返回本次维护的结束时间
+func (w MaintenanceWindow) ActiveUntil(t time.Time) (time.Time, bool) {
+	if t.Before(w.StartsAt) || !t.Before(w.EndsAt) {
+		return time.Time{}, false
+	}
+	if w.Cron == "" {
+		return w.EndsAt, true
+	}
+	sched, err := cron.Parse(w.Cron)
+	if err != nil || w.DurationMinutes <= 0 {
+		return time.Time{}, false
+	}
+	duration := min(time.Duration(w.DurationMinutes)*time.Minute, MaxMaintenanceOccurrence)
+	start, ok := sched.Prev(t.In(w.Location()), duration)
+	if !ok {
+		return time.Time{}, false
+	}
+	end := start.Add(duration)
+	if end.After(w.EndsAt) {
+		end = w.EndsAt
+	}
+	return end, true
+}
+
+// Location 窗口的时区，未设置或无效时使用服务器时区
+func (w MaintenanceWindow) Location() *time.Location {
+	if w.Timezone != "" {
+		if loc, err := time.LoadLocation(w.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// Recurring 是否为周期性窗口
+func (w MaintenanceWindow) Recurring() bool {
+	return w.Cron != ""
+}
+
+// Applies 窗口是否作用于监控项 m
+func (w MaintenanceWindow) Applies(m Monitor) bool {
+	if w.MonitorID != 0 {
+		return w.MonitorID == m.ID
+	}
+	return w.Tag != "" && m.InScope([]string{w.Tag})
+}
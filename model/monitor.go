@@ -13,18 +13,50 @@ const (
 	MonitorTypePing MonitorType = "ping"
 	MonitorTypeTCP  MonitorType = "tcp"
 	MonitorTypeDNS  MonitorType = "dns"
+	MonitorTypeNTP  MonitorType = "ntp"
+	MonitorTypeSSH  MonitorType = "ssh"
+	MonitorTypePush MonitorType = "push"
+	MonitorTypeUDP  MonitorType = "udp"
+
+	MonitorTypeMultiStep MonitorType = "multistep"
 )
 
+// IP 版本：auto（默认，空值等同）由解析结果决定，ipv4/ipv6 强制使用对应地址族
+const (
+	IPVersionAuto = "auto"
+	IPVersion4    = "ipv4"
+	IPVersion6    = "ipv6"
+)
+
+// 监控项 webhook 的发送条件：all 每次心跳，failures 仅 DOWN，transitions 仅状态变化
+const (
+	WebhookFilterAll         = "all"
+	WebhookFilterFailures    = "failures"
+	WebhookFilterTransitions = "transitions"
+)
+
+// MonitorIcons 监控项可选的图标名称，前端显示为对应的图标
+var MonitorIcons = []string{
+	"server", "database", "globe", "cloud", "api", "mail", "shield", "lock",
+	"cart", "code", "bell", "phone", "storage", "network", "home", "star",
+}
+
 const (
 	StatusDown    = 0
 	StatusUp      = 1
 	StatusPending = 2
+	// StatusMaintenance 维护中（维护窗口内）
+	StatusMaintenance = 3
+	// StatusNoData 图表和最近结果中没有数据的位置
+	StatusNoData = -1
 )
 
 type Monitor struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// Version 配置的乐观锁版本号，每次编辑加一；编辑时需要回传读取到的版本，不一致表示已被他人修改
+	Version int `json:"version" gorm:"default:1"`
 
 	Name     string      `json:"name"`
 	URL      string      `json:"url"` // For HTTP/TCP
@@ -37,25 +69,122 @@ type Monitor struct {
 	Timeout         int    `json:"timeout" gorm:"default:10"`
 	ExpectedStatus  int    `json:"expected_status" gorm:"default:0"` // 0 means 2xx
 	ResponseRegex   string `json:"response_regex"`
+	RegexScanBytes  int64  `json:"regex_scan_bytes"` // 响应正则最多扫描的响应体字节数，0 使用默认值（256KB）
 	FollowRedirects bool   `json:"follow_redirects" gorm:"default:true"`
+	IPVersion       string `json:"ip_version"` // auto/ipv4/ipv6, applies to HTTP/TCP/Ping/DNS
+	BasicAuthUser   string `json:"basic_auth_user"`
+	BasicAuthPass   string `json:"basic_auth_pass"` // never sent to clients, only exported with include_secrets
+
+	// AssertOnEmptyBody 响应体为空（204、Content-Length: 0 或空的分块响应）时仍执行响应正则和引用 body/text 的表达式：
+	// 为 false（默认）时跳过这些断言，为 true 时直接判定为 DOWN 并说明响应体为空
+	AssertOnEmptyBody bool `json:"assert_on_empty_body"`
+
+	// Redirect assertions: with FollowRedirects the first hop status and final URL are checked,
+	// otherwise the 3xx status and Location header. ExpectedFinalURL is an exact URL or a regex.
+	ExpectedRedirectStatus int    `json:"expected_redirect_status"`
+	ExpectedFinalURL       string `json:"expected_final_url"`
+
+	// Proxy: http://, https:// or socks5:// (user:pass@ allowed, masked in output); TCP checks only support socks5
+	ProxyURL string `json:"proxy_url"`
+
+	// mTLS: PEM client certificate and private key presented to HTTPS targets
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"` // never sent to clients, only exported with include_secrets
+
+	// OAuth2 client credentials: HTTP checks fetch a bearer token from TokenURL (cached until expiry)
+	OAuthTokenURL     string `json:"token_url" gorm:"column:oauth_token_url"`
+	OAuthClientID     string `json:"client_id" gorm:"column:oauth_client_id"`
+	OAuthClientSecret string `json:"client_secret" gorm:"column:oauth_client_secret"` // never sent to clients, only exported with include_secrets
+	OAuthScopes       string `json:"scopes" gorm:"column:oauth_scopes"`               // space or comma separated
+
+	// Pre/post hooks: JSON HookSpec {url, method, headers, body, extract}; values extracted by the
+	// pre-hook replace {{name}} placeholders in the main request and are never persisted
+	PreHook  string `json:"pre_hook"`
+	PostHook string `json:"post_hook"`
+
+	// Expression: optional assertion evaluated against status, headers, JSON body and duration (see pkg/expr)
+	Expression string `json:"expression"`
+
+	// Steps: multistep monitors only, JSON array of StepSpec executed in order; values extracted by a
+	// step replace {{name}} placeholders in the following steps
+	Steps string `json:"steps"`
 
-	Interval int `json:"interval"` // In seconds
+	MaxOffsetMs int    `json:"max_offset_ms" gorm:"default:0"` // NTP: max absolute clock offset, 0 disables the threshold
+	SSHHostKey  string `json:"ssh_host_key"`                   // SSH: expected host key fingerprint (SHA256:... or MD5), empty skips verification
+
+	PingCount     int `json:"ping_count"`      // Ping: packets per check, 0 uses the default (3)
+	PingSize      int `json:"ping_size"`       // Ping: ICMP payload size in bytes, 0 uses the default
+	MaxPacketLoss int `json:"max_packet_loss"` // Ping: max packet loss percentage, 0 means any reply is UP
+
+	UDPPayload       string `json:"udp_payload"`        // UDP: payload to send
+	UDPPayloadFormat string `json:"udp_payload_format"` // UDP: "text" (default) or "hex"
+
+	PushToken  string    `json:"push_token" gorm:"index"` // Push: token used in /api/push/:token
+	PushSecret string    `json:"push_secret"`             // Push: optional HMAC secret, non-empty enforces signed requests
+	LastPush   time.Time `json:"last_push"`               // Push: time of the last accepted report
+
+	// Webhook: every matching heartbeat is POSTed as JSON to WebhookURL, signed with WebhookSecret when set
+	WebhookURL     string `json:"webhook_url"`
+	WebhookSecret  string `json:"webhook_secret"`
+	WebhookFilter  string `json:"webhook_filter"`  // "all" (default), "failures" (DOWN only) or "transitions" (status changes)
+	WebhookEnabled bool   `json:"webhook_enabled"` // toggle delivery without deleting the config
+
+	RunDiagnostics bool      `json:"run_diagnostics"` // Ping/TCP: run a traceroute when the monitor goes from UP to DOWN
+	Diagnostics    string    `json:"-"`               // Output of the last diagnostics run (admin only, via getMonitor)
+	DiagnosticsAt  time.Time `json:"-"`
+
+	Interval    int `json:"interval"`     // In seconds
+	SampleEvery int `json:"sample_every"` // Adaptive sampling: persist every Nth identical UP heartbeat, 0/1 persists all
 
 	Active int `json:"active" gorm:"default:1"`
 	Weight int `json:"weight" gorm:"default:2000"`
 
+	Tags   string `json:"tags"`   // comma separated, lowercase; used by tag-scoped API keys and viewer accounts
+	Public bool   `json:"public"` // uptime widget available without login at /embed/monitor/:id
+	Badge  bool   `json:"badge"`  // SVG status/uptime badges available without login at /badge/:id/...
+
+	// PublicVisible 是否向未登录的客户端（状态页）显示该监控项，默认显示
+	PublicVisible bool `json:"public_visible" gorm:"default:true"`
+
+	// Color 列表和状态页中的标识颜色（小写 #rrggbb），为空时不显示；Icon 为 MonitorIcons 中的名称或单个 emoji
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+
+	GroupID uint `json:"group_id" gorm:"index"` // 所属 MonitorGroup，0 表示未分组
+
 	Status    int       `json:"status"` // 0: DOWN, 1: UP, 2: PENDING
 	LastCheck time.Time `json:"last_check"`
 	Message   string    `json:"msg"` // Frontend expects "msg" not "message" usually? checking.. Uptime Kuma uses "msg" in heartbeat, but "message" in monitor? Let's check heartbeat.
 }
 
+// 账号角色：admin 可以管理监控项、通知、设置和账号；viewer 只能查看（监控项详情、心跳、事件），修改操作返回 403。
+// 角色为空的账号（添加角色之前创建的）视为 admin
+const (
+	RoleAdmin  = "admin"
+	RoleViewer = "viewer"
+)
+
 type User struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	Username  string         `gorm:"uniqueIndex" json:"username"`
 	Password  string         `json:"-"`
+	TagScope  string         `json:"tag_scope"` // viewer account: only monitors with one of these tags, empty means full access
+	Role      string         `gorm:"default:admin" json:"role"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 两步验证：TOTPSecret 为加密后的密钥（setup2FA 生成，确认后 TOTPEnabled 才为 true），
+	// TOTPLastStep 为最近一次通过验证的时间步，防止同一验证码重复使用；RecoveryCodes 为未使用恢复码的 SHA-256 摘要，逗号分隔
+	TOTPSecret    string `gorm:"column:totp_secret" json:"-"`
+	TOTPEnabled   bool   `gorm:"column:totp_enabled" json:"totp_enabled"`
+	TOTPLastStep  int64  `gorm:"column:totp_last_step" json:"-"`
+	RecoveryCodes string `json:"-"`
+}
+
+// IsViewer 账号是否为只读的 viewer 角色
+func (u User) IsViewer() bool {
+	return u.Role == RoleViewer
 }
 
 type Setting struct {
@@ -63,6 +192,8 @@ type Setting struct {
 	Key   string `gorm:"uniqueIndex" json:"key"`
 	Value string `json:"value"`
 	Type  string `json:"type"`
+	// Version 乐观锁版本号，getSettings 的 settingVersions 中按 key 返回
+	Version int `json:"version" gorm:"default:1"`
 }
 
 type Notification struct {
@@ -72,6 +203,8 @@ type Notification struct {
 	Config string `json:"config"` // JSON string of config
 	Active bool   `json:"active" gorm:"default:true"`
 	UserID uint   `json:"userId"`
+	// Version 乐观锁版本号，与 Monitor.Version 相同
+	Version int `json:"version" gorm:"default:1"`
 }
 
 type Heartbeat struct {
@@ -81,4 +214,8 @@ type Heartbeat struct {
 	Message   string    `json:"msg"`
 	Time      time.Time `gorm:"index:idx_monitor_time" json:"time"`
 	Duration  int       `json:"duration"` // response time in ms
+	// RepresentedCount 该心跳代表的检查次数（自适应采样时包含被抑制的相同结果），旧数据默认为 1
+	RepresentedCount int `json:"represented_count" gorm:"default:1"`
+	// AgentID 上报该心跳的远程探针，0 表示中心服务器自己的检查
+	AgentID uint `json:"agent_id,omitempty" gorm:"default:0"`
 }
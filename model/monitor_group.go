@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// MonitorGroup 监控项分组，仪表盘和状态页按分组折叠显示；按 Weight 降序、名称升序排列
+type MonitorGroup struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"uniqueIndex" json:"name"`
+	Weight    int       `json:"weight" gorm:"default:2000"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// statusSeverity 分组汇总状态时各状态的严重程度，数值越大越严重
+var statusSeverity = map[int]int{
+	StatusNoData:      0,
+	StatusUp:          1,
+	StatusMaintenance: 2,
+	StatusPending:     3,
+	StatusDown:        4,
+}
+
+// WorstStatus 返回最严重的状态：DOWN > PENDING > 维护中 > UP；statuses 为空时返回 StatusNoData
+func WorstStatus(statuses []int) int {
+	worst := StatusNoData
+	for _, s := range statuses {
+		if statusSeverity[s] > statusSeverity[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// NotificationLog 一次通知发送的记录（状态通知、日报、测试消息），用于排查“没有收到告警”
+type NotificationLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	RuleID    uint      `gorm:"index" json:"rule_id"`    // 通知规则 ID，测试未保存的规则时为 0
+	MonitorID uint      `gorm:"index" json:"monitor_id"` // 状态通知的监控项 ID，日报和汇总为 0
+	Channel   string    `gorm:"index" json:"channel"`
+	Kind      string    `json:"kind"`      // status / report / test
+	Recipient string    `json:"recipient"` // 收件邮箱或推送服务地址（只保留主机名），不含密钥
+	Subject   string    `json:"subject"`
+	Summary   string    `json:"summary"`
+	Success   bool      `gorm:"index" json:"success"`
+	Error     string    `json:"error"`
+	ResendOf  uint      `json:"resend_of"` // 重新发送时为原记录的 ID
+	// Payload 通知内容（notification.Event 的 JSON，不含规则配置），用于重新发送
+	Payload string `json:"-"`
+}
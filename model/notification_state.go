@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// NotificationState 触发规则对某个监控项的通知状态，重启后由通知 worker 恢复，
+// 使重启前已开始的宕机在重启后仍能按原有计数发出通知
+type NotificationState struct {
+	RuleID    uint `gorm:"primaryKey;autoIncrement:false"`
+	MonitorID uint `gorm:"primaryKey;autoIncrement:false;index"`
+
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	LastSentStatus       int
+	FirstFailureAt       time.Time
+	DownSince            time.Time
+	LastSentAt           time.Time
+	ChecksSinceSent      int
+	DownNotified         bool
+	DownNotifiedAt       time.Time
+	Escalated            bool
+	LastTransitionAt     time.Time
+	Flapping             bool
+	Suppressed           int
+	UpdatedAt            time.Time
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// 服务端告警级别
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+// ServerAlert 持久化的系统告警（心跳缓冲区丢弃、后台任务失败、监控项停滞、通知发送失败等），
+// 管理员登录后可在通知中心查看。相同的未确认告警只增加 Count，不重复插入
+type ServerAlert struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Severity       string     `gorm:"index" json:"severity"`
+	Title          string     `gorm:"index" json:"title"`
+	Body           string     `json:"body"`
+	Count          int        `gorm:"default:1" json:"count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"` // 最近一次出现的时间
+	Acknowledged   bool       `gorm:"index" json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+}
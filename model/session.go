@@ -2,9 +2,11 @@ package model
 
 import "time"
 
+// Session 登录会话，Role 为登录时账号的角色
 type Session struct {
 	Token     string    `gorm:"primaryKey" json:"token"`
 	UserID    uint      `gorm:"index" json:"userId"`
+	Role      string    `json:"role"`
 	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
 	CreatedAt time.Time `json:"createdAt"`
 }
@@ -0,0 +1,62 @@
+package model
+
+import "ping-go/pkg/i18n"
+
+// StatusMeta 状态码的元数据：客户端应使用 Key 判断状态，Label 和 Color 只用于展示
+type StatusMeta struct {
+	Code  int    `json:"code"`
+	Key   string `json:"key"`   // up / down / pending / maintenance / no_data / unknown
+	Label string `json:"label"` // 按界面语言本地化的名称
+	Color string `json:"color"`
+}
+
+// StatusCodes 所有已定义的状态码，按展示顺序排列
+var StatusCodes = []int{StatusUp, StatusDown, StatusPending, StatusMaintenance, StatusNoData}
+
+// DefaultStatusColors 各状态的默认颜色，可通过设置覆盖
+var DefaultStatusColors = map[string]string{
+	"up":          "#2ecc71",
+	"down":        "#e74c3c",
+	"pending":     "#f1c40f",
+	"maintenance": "#3498db",
+	"no_data":     "#dfe4ea",
+	"unknown":     "#95a5a6",
+}
+
+// StatusKey 返回状态码对应的规范 key，未知状态码返回 "unknown"
+func StatusKey(code int) string {
+	switch code {
+	case StatusUp:
+		return "up"
+	case StatusDown:
+		return "down"
+	case StatusPending:
+		return "pending"
+	case StatusMaintenance:
+		return "maintenance"
+	case StatusNoData:
+		return "no_data"
+	default:
+		return "unknown"
+	}
+}
+
+// StatusCode 返回 key 对应的状态码，与 StatusKey 相反；未知 key 返回 false
+func StatusCode(key string) (int, bool) {
+	for _, code := range StatusCodes {
+		if StatusKey(code) == key {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// DescribeStatus 返回状态码的完整元数据；colors 为按 key 覆盖的颜色，可以为 nil
+func DescribeStatus(code int, lang string, colors map[string]string) StatusMeta {
+	key := StatusKey(code)
+	color := DefaultStatusColors[key]
+	if c := colors[key]; c != "" {
+		color = c
+	}
+	return StatusMeta{Code: code, Key: key, Label: i18n.T(lang, "status."+key), Color: color}
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// StatusEvent 监控项的一次状态变化（如 UP → DOWN），用于 RSS/Atom 订阅等需要变化历史的场景。
+// 新监控项第一次检查结果（PENDING → 其他）不记录
+type StatusEvent struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	MonitorID      uint      `gorm:"index" json:"monitor_id"`
+	Status         int       `json:"status"`
+	PreviousStatus int       `json:"previous_status"`
+	Message        string    `json:"message"`
+	Time           time.Time `gorm:"index" json:"time"`
+}
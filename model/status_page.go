@@ -0,0 +1,33 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// StatusPage 公开状态页，发布后可以在 /status/:slug 无需登录访问，只展示 StatusPageMonitor 中选择的监控项
+type StatusPage struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Slug        string    `gorm:"uniqueIndex" json:"slug"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Published   bool      `json:"published"` // 未发布的状态页对外返回 404
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StatusPageMonitor 状态页中的监控项，按 SortOrder 升序展示
+type StatusPageMonitor struct {
+	ID           uint `gorm:"primaryKey" json:"id"`
+	StatusPageID uint `gorm:"uniqueIndex:idx_status_page_monitor" json:"status_page_id"`
+	MonitorID    uint `gorm:"uniqueIndex:idx_status_page_monitor;index" json:"monitor_id"`
+	SortOrder    int  `json:"sort_order"`
+}
+
+// statusPageSlugRe slug 只允许小写字母、数字和连字符，不能以连字符开头或结尾
+var statusPageSlugRe = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,62}[a-z0-9])?$`)
+
+// ValidStatusPageSlug slug 是否可以用在 /status/:slug 中
+func ValidStatusPageSlug(slug string) bool {
+	return statusPageSlugRe.MatchString(slug)
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// 订阅链接的用途，签名中包含用途，确认链接不能用于退订，反之亦然
+const (
+	SubscriptionConfirm     = "confirm"
+	SubscriptionUnsubscribe = "unsubscribe"
+)
+
+// Subscription 状态页的邮件订阅。访客提交邮箱后先发送确认邮件，确认后才会收到事件公告和状态变化通知；
+// Secret 只用于为确认和退订链接签名，不对外返回
+type Subscription struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Email         string     `gorm:"uniqueIndex:idx_subscription_page_email" json:"email"`
+	StatusPageID  uint       `gorm:"uniqueIndex:idx_subscription_page_email" json:"status_page_id"`
+	Confirmed     bool       `gorm:"index" json:"confirmed"`
+	Secret        string     `json:"-"`
+	ConfirmSentAt time.Time  `json:"confirm_sent_at"` // 最近一次发送确认邮件的时间，用于限制重复发送
+	ConfirmedAt   *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Token 返回确认或退订链接中的签名：hex(HMAC-SHA256(secret, "<action>:<id>"))
+func (s Subscription) Token(action string) string {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	fmt.Fprintf(mac, "%s:%d", action, s.ID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyToken 校验链接中的签名
+func (s Subscription) VerifyToken(action, token string) bool {
+	return s.Secret != "" && hmac.Equal([]byte(s.Token(action)), []byte(token))
+}
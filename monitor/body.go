@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"ping-go/config"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	// defaultMaxBodyBytes 正则校验时读取的响应体上限（解压后），可通过 monitor.max_body_bytes 配置
+	defaultMaxBodyBytes = 1024 * 1024
+	// drainLimit 关闭响应前最多丢弃的剩余字节数，超过时直接关闭连接而不是放回连接池
+	drainLimit = 64 * 1024
+)
+
+// maxBodyBytes 返回响应体读取上限
+func maxBodyBytes() int64 {
+	if n := config.Get().Monitor.MaxBodyBytes; n > 0 {
+		return n
+	}
+	return defaultMaxBodyBytes
+}
+
+// peekEmptyBody 判断响应体是否为空：204、Content-Length: 0 直接判定，长度未知（分块传输）时预读 1 字节，
+// 返回拼接回预读内容的响应体
+func peekEmptyBody(resp *http.Response, body io.Reader) (bool, io.Reader) {
+	if resp.StatusCode == http.StatusNoContent || resp.ContentLength == 0 {
+		return true, body
+	}
+	var b [1]byte
+	n, err := io.ReadFull(body, b[:])
+	if n == 0 && errors.Is(err, io.EOF) {
+		return true, body
+	}
+	return false, io.MultiReader(bytes.NewReader(b[:n]), body)
+}
+
+// decodeError 响应体按 Content-Encoding 解压失败
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return fmt.Sprintf("Decode error (%v)", e.err)
+}
+
+// decodedBody 返回按 Content-Encoding 解压后的响应体。
+// Transport 只在自己添加 Accept-Encoding 时才会自动解压 gzip，用户在 Headers 中手动设置 Accept-Encoding 时由这里处理。
+// 解压器在第一次读取时才创建，不读取响应体的检查不受影响
+func decodedBody(resp *http.Response) io.Reader {
+	enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if resp.Uncompressed || enc == "" || enc == "identity" {
+		return resp.Body
+	}
+	return &decodingReader{src: &sourceReader{r: resp.Body}, encoding: enc}
+}
+
+// sourceReader 记录底层响应体的读取错误，用于区分网络错误和解压错误
+type sourceReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *sourceReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+	return n, err
+}
+
+type decodingReader struct {
+	src      *sourceReader
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = newBodyDecoder(d.encoding, d.src)
+		if d.err != nil {
+			d.err = d.wrap(d.err)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	n, err := d.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = d.wrap(err)
+	}
+	return n, err
+}
+
+// wrap 网络错误原样返回，其余（数据损坏、流被截断）视为解压错误
+func (d *decodingReader) wrap(err error) error {
+	if d.src.err != nil && errors.Is(err, d.src.err) {
+		return err
+	}
+	return &decodeError{err: err}
+}
+
+func newBodyDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// HTTP 的 deflate 应为 zlib 格式，但也有服务端直接返回裸 deflate 数据
+		br := bufio.NewReader(r)
+		if head, err := br.Peek(2); err == nil && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return brotli.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+}
+
+// readBody 读取最多 limit 字节（解压后）的响应体
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(r, limit))
+}
+
+// bodyReadError 将读取响应体的错误转换为检查消息
+func bodyReadError(err error) string {
+	var de *decodeError
+	if errors.As(err, &de) {
+		return de.Error()
+	}
+	return fmt.Sprintf("Read body failed: %v", err)
+}
+
+// drainAndClose 读完少量剩余数据后关闭响应体，使连接可以被共享的 Transport 复用
+func drainAndClose(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, drainLimit))
+	body.Close()
+}
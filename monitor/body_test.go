@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"ping-go/model"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// emptyBodyServer 返回三种空响应体：/no-content 为 204，/empty 为 Content-Length: 0 的 200，
+// /chunked 为没有内容的分块响应
+func emptyBodyServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/no-content", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush() // 先发送响应头，长度未知，使用分块传输
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestCheckHTTPEmptyBody(t *testing.T) {
+	ts := emptyBodyServer(t)
+
+	modes := []struct {
+		name string
+		set  func(m *model.Monitor)
+	}{
+		{"keyword", func(m *model.Monitor) { m.ResponseRegex = regexp.QuoteMeta("healthy") }},
+		{"regex", func(m *model.Monitor) { m.ResponseRegex = `"status"\s*:\s*"ok"` }},
+		{"json path", func(m *model.Monitor) { m.Expression = `body.status == "ok"` }},
+		{"text expression", func(m *model.Monitor) { m.Expression = `text contains "ok"` }},
+	}
+	for _, path := range []string{"/no-content", "/empty", "/chunked"} {
+		for _, mode := range modes {
+			for _, assert := range []bool{false, true} {
+				name := strings.TrimPrefix(path, "/") + "/" + mode.name
+				if assert {
+					name += "/assert_on_empty_body"
+				}
+				t.Run(name, func(t *testing.T) {
+					m := model.Monitor{Type: model.MonitorTypeHTTP, URL: ts.URL + path, Timeout: 5, AssertOnEmptyBody: assert}
+					mode.set(&m)
+					status, msg := CheckHTTP(m)
+					if strings.Contains(msg, "Body:") {
+						t.Errorf("message has an empty Body suffix: %q", msg)
+					}
+					if assert {
+						if status != model.StatusDown || !strings.Contains(msg, "响应体为空") {
+							t.Fatalf("got %d %q, want DOWN reporting the empty body", status, msg)
+						}
+						return
+					}
+					if status != model.StatusUp || !strings.Contains(msg, "已跳过响应内容校验") {
+						t.Fatalf("got %d %q, want UP with the body assertions skipped", status, msg)
+					}
+				})
+			}
+		}
+	}
+}
+
+// 不引用响应体的表达式在空响应上照常执行
+func TestCheckHTTPEmptyBodyStatusExpression(t *testing.T) {
+	ts := emptyBodyServer(t)
+	m := model.Monitor{Type: model.MonitorTypeHTTP, URL: ts.URL + "/no-content", Timeout: 5, Expression: `status == 200`}
+	if status, msg := CheckHTTP(m); status != model.StatusDown {
+		t.Fatalf("status expression on a 204: got %d %q, want DOWN", status, msg)
+	}
+	m.Expression = `status == 204`
+	if status, msg := CheckHTTP(m); status != model.StatusUp || strings.Contains(msg, "已跳过") {
+		t.Fatalf("status expression on a 204: got %d %q, want UP without skipping", status, msg)
+	}
+}
+
+// 非空响应体不匹配时仍然附带响应内容
+func TestCheckHTTPNonEmptyMismatchKeepsBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("degraded")) }))
+	defer ts.Close()
+	m := model.Monitor{Type: model.MonitorTypeHTTP, URL: ts.URL, Timeout: 5, ResponseRegex: "healthy"}
+	status, msg := CheckHTTP(m)
+	if status != model.StatusDown || !strings.Contains(msg, "Body: degraded") {
+		t.Fatalf("got %d %q, want DOWN with the body", status, msg)
+	}
+}
@@ -0,0 +1,340 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CurlRequest 从 curl 命令解析出的请求，字段与添加监控表单一致
+type CurlRequest struct {
+	Method          string
+	URL             string
+	Headers         map[string]string
+	Body            string
+	BasicAuthUser   string
+	BasicAuthPass   string
+	FollowRedirects bool
+	Timeout         int
+}
+
+// curlIgnoredFlags 只影响 curl 输出、与监控无关的参数，静默忽略；值为 true 表示带一个参数
+var curlIgnoredFlags = map[string]bool{
+	"-s": false, "--silent": false, "-S": false, "--show-error": false,
+	"-v": false, "--verbose": false, "-i": false, "--include": false,
+	"-f": false, "--fail": false, "--fail-with-body": false, "-#": false, "--progress-bar": false,
+	"-N": false, "--no-buffer": false, "--no-progress-meter": false,
+	"-o": true, "--output": true, "-w": true, "--write-out": true,
+}
+
+// curlArgFlags 会被解析的带参数的选项（用于展开 -XPOST 这类紧跟参数的写法）
+var curlArgFlags = map[string]bool{
+	"-X": true, "-H": true, "-d": true, "-u": true, "-m": true, "-A": true, "-e": true, "-b": true,
+	"-o": true, "-w": true,
+}
+
+// ParseCurl 解析 curl 命令，返回请求和被忽略参数的提示。
+// 支持单/双引号、$'...'、反斜杠续行和多个 -H；无法映射到监控项的参数会被忽略并写入 warnings
+func ParseCurl(command string) (*CurlRequest, []string, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) == 0 || (args[0] != "curl" && !strings.HasSuffix(args[0], "/curl") && args[0] != "curl.exe") {
+		return nil, nil, errors.New("not a curl command")
+	}
+	args = expandShortFlags(args[1:])
+
+	req := &CurlRequest{Headers: make(map[string]string)}
+	var warnings []string
+	var data []string
+	useGet, head := false, false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			if k, v, ok := strings.Cut(arg, "="); ok {
+				name, value, hasValue = k, v, true
+			}
+		}
+		// next 读取选项的参数
+		next := func() (string, bool) {
+			if hasValue {
+				return value, true
+			}
+			if i+1 < len(args) {
+				i++
+				return args[i], true
+			}
+			warnings = append(warnings, fmt.Sprintf("%s: missing value", name))
+			return "", false
+		}
+
+		switch name {
+		case "-X", "--request":
+			if v, ok := next(); ok {
+				req.Method = strings.ToUpper(v)
+			}
+		case "-H", "--header":
+			if v, ok := next(); ok {
+				k, hv, found := strings.Cut(v, ":")
+				if !found || strings.TrimSpace(k) == "" {
+					warnings = append(warnings, fmt.Sprintf("invalid header %q ignored", v))
+					continue
+				}
+				req.Headers[strings.TrimSpace(k)] = strings.TrimSpace(hv)
+			}
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii", "--data-urlencode":
+			if v, ok := next(); ok {
+				if strings.HasPrefix(v, "@") && name != "--data-raw" {
+					warnings = append(warnings, fmt.Sprintf("%s %s: reading the body from a file is not supported", name, v))
+					continue
+				}
+				if name == "--data-urlencode" {
+					v = encodeCurlData(v)
+				}
+				data = append(data, v)
+			}
+		case "--json":
+			if v, ok := next(); ok {
+				data = append(data, v)
+				setDefaultHeader(req.Headers, "Content-Type", "application/json")
+				setDefaultHeader(req.Headers, "Accept", "application/json")
+			}
+		case "-u", "--user":
+			if v, ok := next(); ok {
+				user, pass, _ := strings.Cut(v, ":")
+				req.BasicAuthUser, req.BasicAuthPass = user, pass
+			}
+		case "-A", "--user-agent":
+			if v, ok := next(); ok {
+				req.Headers["User-Agent"] = v
+			}
+		case "-e", "--referer":
+			if v, ok := next(); ok {
+				req.Headers["Referer"] = v
+			}
+		case "-b", "--cookie":
+			if v, ok := next(); ok {
+				if !strings.Contains(v, "=") {
+					warnings = append(warnings, fmt.Sprintf("%s %s: cookie files are not supported", name, v))
+					continue
+				}
+				req.Headers["Cookie"] = v
+			}
+		case "-m", "--max-time", "--connect-timeout":
+			if v, ok := next(); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+					if t := int(f + 0.999); name != "--connect-timeout" || req.Timeout == 0 {
+						req.Timeout = t
+					}
+				}
+			}
+		case "--url":
+			if v, ok := next(); ok {
+				req.URL = v
+			}
+		case "-L", "--location":
+			req.FollowRedirects = true
+		case "-G", "--get":
+			useGet = true
+		case "-I", "--head":
+			head = true
+		case "--compressed":
+			setDefaultHeader(req.Headers, "Accept-Encoding", "gzip, deflate, br")
+		case "-k", "--insecure":
+			warnings = append(warnings, name+": monitors always verify TLS certificates, flag ignored")
+		default:
+			if takesArg, ok := curlIgnoredFlags[name]; ok {
+				if takesArg && !hasValue && i+1 < len(args) {
+					i++
+				}
+				continue
+			}
+			if strings.HasPrefix(arg, "-") && arg != "-" {
+				warnings = append(warnings, fmt.Sprintf("unsupported option %s ignored", name))
+				continue
+			}
+			if req.URL == "" {
+				req.URL = arg
+			} else {
+				warnings = append(warnings, fmt.Sprintf("extra argument %q ignored", arg))
+			}
+		}
+	}
+
+	if req.URL == "" {
+		return nil, warnings, errors.New("no URL found in the curl command")
+	}
+
+	body := strings.Join(data, "&")
+	switch {
+	case useGet && body != "":
+		sep := "?"
+		if strings.Contains(req.URL, "?") {
+			sep = "&"
+		}
+		req.URL += sep + body
+		if req.Method == "" {
+			req.Method = "GET"
+		}
+	case body != "":
+		req.Body = body
+		if req.Method == "" {
+			req.Method = "POST"
+		}
+		setDefaultHeader(req.Headers, "Content-Type", "application/x-www-form-urlencoded")
+	}
+	if head && req.Method == "" {
+		req.Method = "HEAD"
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	return req, warnings, nil
+}
+
+// setDefaultHeader 头部不存在（不区分大小写）时才设置
+func setDefaultHeader(headers map[string]string, key, value string) {
+	for k := range headers {
+		if strings.EqualFold(k, key) {
+			return
+		}
+	}
+	headers[key] = value
+}
+
+// encodeCurlData 按 curl --data-urlencode 的规则编码：name=content 只编码 content，否则编码整体
+func encodeCurlData(v string) string {
+	if name, content, ok := strings.Cut(v, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content)
+		}
+		return name + "=" + url.QueryEscape(content)
+	}
+	return url.QueryEscape(v)
+}
+
+// expandShortFlags 展开组合的短选项（-sSL）和紧跟参数的短选项（-XPOST、-H'Accept: */*'）
+func expandShortFlags(args []string) []string {
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		if len(arg) <= 2 || arg[0] != '-' || arg[1] == '-' {
+			out = append(out, arg)
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			flag := "-" + string(arg[j])
+			out = append(out, flag)
+			if curlArgFlags[flag] && j+1 < len(arg) {
+				out = append(out, arg[j+1:])
+				break
+			}
+		}
+	}
+	return out
+}
+
+// splitShellWords 按 POSIX shell 规则拆分命令行：支持单引号、双引号、$'...' 和反斜杠转义/续行。
+// 也接受 Windows cmd 复制出的 ^ 续行
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	r := []rune(s)
+
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case c == '\\' && i+1 < len(r) && (r[i+1] == '\n' || (r[i+1] == '\r' && i+2 < len(r) && r[i+2] == '\n')):
+			// 续行
+			if r[i+1] == '\r' {
+				i++
+			}
+			i++
+		case c == '^' && i+1 < len(r) && (r[i+1] == '\n' || r[i+1] == '\r'):
+			for i+1 < len(r) && (r[i+1] == '\n' || r[i+1] == '\r') {
+				i++
+			}
+		case c == '\\' && i+1 < len(r):
+			i++
+			cur.WriteRune(r[i])
+			inWord = true
+		case c == '\'':
+			end := indexRune(r, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			cur.WriteString(string(r[i+1 : end]))
+			i, inWord = end, true
+		case c == '$' && i+1 < len(r) && r[i+1] == '\'':
+			j := i + 2
+			for ; j < len(r) && r[j] != '\''; j++ {
+				if r[j] == '\\' && j+1 < len(r) {
+					j++
+					cur.WriteString(ansiEscape(r[j]))
+					continue
+				}
+				cur.WriteRune(r[j])
+			}
+			if j >= len(r) {
+				return nil, errors.New("unterminated $'...' quote")
+			}
+			i, inWord = j, true
+		case c == '"':
+			j := i + 1
+			for ; j < len(r) && r[j] != '"'; j++ {
+				if r[j] == '\\' && j+1 < len(r) && strings.ContainsRune("\"\\$`\n", r[j+1]) {
+					j++
+					if r[j] == '\n' {
+						continue
+					}
+				}
+				cur.WriteRune(r[j])
+			}
+			if j >= len(r) {
+				return nil, errors.New("unterminated double quote")
+			}
+			i, inWord = j, true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+func indexRune(r []rune, from int, target rune) int {
+	for i := from; i < len(r); i++ {
+		if r[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiEscape 处理 $'...' 中常见的转义字符
+func ansiEscape(c rune) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	default:
+		return string(c)
+	}
+}
@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxDebugDuration 单次调试会话的最长持续时间
+	MaxDebugDuration = 10 * time.Minute
+	// debugBodyLimit 调试模式下捕获的响应体最大字节数
+	debugBodyLimit = 2048
+)
+
+var ErrDebugSessionActive = errors.New("a debug session is already active for this monitor")
+
+// DebugInfo holds the extra detail captured for a single check while debug mode is on.
+// It is only streamed to the requesting socket and never persisted.
+type DebugInfo struct {
+	MonitorID       uint                `json:"monitorID"`
+	Time            string              `json:"time"`
+	Type            string              `json:"type"`
+	URL             string              `json:"url"`
+	Method          string              `json:"method,omitempty"`
+	RequestHeaders  map[string][]string `json:"requestHeaders,omitempty"`
+	StatusCode      int                 `json:"statusCode,omitempty"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+	BodyPreview     string              `json:"bodyPreview,omitempty"`
+	Timing          DebugTiming         `json:"timing"`
+	Status          int                 `json:"status"`
+	StatusKey       string              `json:"status_key"`
+	Message         string              `json:"msg"`
+	Duration        int                 `json:"duration"`
+}
+
+// DebugTiming is the timing breakdown of an HTTP check in milliseconds
+type DebugTiming struct {
+	DNS     int64 `json:"dns"`
+	Connect int64 `json:"connect"`
+	TLS     int64 `json:"tls"`
+	TTFB    int64 `json:"ttfb"`
+	Total   int64 `json:"total"`
+}
+
+type debugSession struct {
+	owner     string
+	expiresAt time.Time
+	emit      func(*DebugInfo)
+	timer     *time.Timer
+}
+
+// StartDebug enables detail capture for a monitor's checks for at most MaxDebugDuration.
+// Only one session per monitor is allowed; onStop is called when the session expires or is stopped.
+func (s *Service) StartDebug(id uint, owner string, d time.Duration, emit func(*DebugInfo), onStop func()) error {
+	if d <= 0 || d > MaxDebugDuration {
+		d = MaxDebugDuration
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.debugSessions[id]; ok && time.Now().Before(sess.expiresAt) {
+		return ErrDebugSessionActive
+	}
+
+	sess := &debugSession{
+		owner:     owner,
+		expiresAt: time.Now().Add(d),
+		emit:      emit,
+	}
+	sess.timer = time.AfterFunc(d, func() {
+		s.mu.Lock()
+		current, ok := s.debugSessions[id]
+		if ok && current == sess {
+			delete(s.debugSessions, id)
+		}
+		s.mu.Unlock()
+		if ok && current == sess && onStop != nil {
+			onStop()
+		}
+	})
+	s.debugSessions[id] = sess
+	return nil
+}
+
+// StopDebug ends the debug session of a monitor. A non-empty owner only stops a session it started.
+func (s *Service) StopDebug(id uint, owner string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.debugSessions[id]
+	if !ok || (owner != "" && sess.owner != owner) {
+		return false
+	}
+	sess.timer.Stop()
+	delete(s.debugSessions, id)
+	return true
+}
+
+// StopDebugByOwner ends all debug sessions started by the given owner (e.g. on socket disconnect).
+func (s *Service) StopDebugByOwner(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sess := range s.debugSessions {
+		if sess.owner == owner {
+			sess.timer.Stop()
+			delete(s.debugSessions, id)
+		}
+	}
+}
+
+// debugSessionFor returns the active debug session of a monitor, if any
+func (s *Service) debugSessionFor(id uint) *debugSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.debugSessions[id]
+	if !ok || time.Now().After(sess.expiresAt) {
+		return nil
+	}
+	return sess
+}
+
+// withDebugTrace attaches an httptrace to the request that records the timing breakdown into dbg.
+func withDebugTrace(req *http.Request, dbg *DebugInfo) *http.Request {
+	start := time.Now()
+	var dnsStart, connectStart, tlsStart time.Time
+	// 双栈拨号时 Connect 回调可能并发触发
+	var mu sync.Mutex
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			dbg.Timing.DNS = time.Since(dnsStart).Milliseconds()
+		},
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			connectStart = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			mu.Lock()
+			dbg.Timing.Connect = time.Since(connectStart).Milliseconds()
+			mu.Unlock()
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			dbg.Timing.TLS = time.Since(tlsStart).Milliseconds()
+		},
+		GotFirstResponseByte: func() {
+			dbg.Timing.TTFB = time.Since(start).Milliseconds()
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
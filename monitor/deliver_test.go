@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider 记录发送的事件，err 不为空时发送失败
+type fakeProvider struct {
+	mu   sync.Mutex
+	err  error
+	sent chan notification.Event
+}
+
+func (p *fakeProvider) Name() string                         { return "fake" }
+func (p *fakeProvider) ValidateConfig(json.RawMessage) error { return nil }
+func (p *fakeProvider) Recipient(raw json.RawMessage) string { return "fake-recipient" }
+func (p *fakeProvider) setErr(err error)                     { p.mu.Lock(); p.err = err; p.mu.Unlock() }
+func (p *fakeProvider) Send(ctx context.Context, ev notification.Event) error {
+	p.mu.Lock()
+	err := p.err
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	p.sent <- ev
+	return nil
+}
+
+var fake = &fakeProvider{sent: make(chan notification.Event, 16)}
+
+func init() { notification.Register(fake) }
+
+// useFakeProvider 在临时数据库上重置 fake 渠道
+func useFakeProvider(t *testing.T) {
+	t.Helper()
+	if err := db.Init(filepath.Join(t.TempDir(), "pinggo.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	fake.setErr(nil)
+	for len(fake.sent) > 0 {
+		<-fake.sent
+	}
+}
+
+func statusEvent(name string) notification.Event {
+	return notification.Event{
+		Kind:    notification.EventStatusChange,
+		Subject: name + " is DOWN",
+		Status:  notification.StatusChangeData{MonitorID: 7, Name: name, OldStatus: "UP", NewStatus: "DOWN"},
+		Config:  json.RawMessage(`{"channel":"fake"}`),
+	}
+}
+
+// deliver 异步发送，失败时产生服务器告警
+func TestDeliver(t *testing.T) {
+	useFakeProvider(t)
+	s := newTestService(t)
+
+	s.deliver("fake", 1, statusEvent("api"), "api")
+	select {
+	case ev := <-fake.sent:
+		if ev.Subject != "api is DOWN" {
+			t.Fatalf("delivered %q", ev.Subject)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not dispatched")
+	}
+
+	fake.setErr(errors.New("upstream unavailable"))
+	s.deliver("fake", 1, statusEvent("api"), "api")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var alerts []model.ServerAlert
+		db.DB.Where("title = ?", "Notification delivery failed").Find(&alerts)
+		if len(alerts) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("failed delivery did not raise a server alert")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,212 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// traceMaxHops traceroute 最大跳数
+	traceMaxHops = 20
+	// traceHopTimeout 每一跳等待回复的时间
+	traceHopTimeout = time.Second
+	// maxDiagnosticsBytes 保存的诊断输出最大长度
+	maxDiagnosticsBytes = 2048
+	// ipv6HeaderLen ICMPv6 错误报文中引用的原始 IPv6 固定头长度
+	ipv6HeaderLen = 40
+)
+
+// TraceHop traceroute 的一跳，Addr 为空表示该跳超时未回复
+type TraceHop struct {
+	TTL  int
+	Addr string
+	RTT  time.Duration
+}
+
+// Traceroute 发送 TTL 递增的 ICMP Echo，记录每一跳返回 Time Exceeded 的路由器地址，直到到达目标或达到最大跳数。
+// 需要 raw socket 权限（root 或 CAP_NET_RAW）。
+func Traceroute(host, ipVersion string, maxHops int, hopTimeout time.Duration) ([]TraceHop, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ips, err := getCustomResolver().LookupIP(ctx, ipNetwork("ip", ipVersion), host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+	dst := ips[0]
+	v4 := dst.To4() != nil
+
+	var conn *icmp.PacketConn
+	var echoType, replyType icmp.Type
+	var proto int
+	if v4 {
+		conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+		echoType, replyType, proto = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply, 1
+	} else {
+		conn, err = icmp.ListenPacket("ip6:ipv6-icmp", "::")
+		echoType, replyType, proto = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, 58
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	id := int(rand.Uint32() & 0xffff)
+	buf := make([]byte, 1500)
+	var hops []TraceHop
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if v4 {
+			err = conn.IPv4PacketConn().SetTTL(ttl)
+		} else {
+			err = conn.IPv6PacketConn().SetHopLimit(ttl)
+		}
+		if err != nil {
+			return hops, err
+		}
+
+		msg := icmp.Message{Type: echoType, Body: &icmp.Echo{ID: id, Seq: ttl, Data: []byte("pinggo-trace")}}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return hops, err
+		}
+		start := time.Now()
+		if _, err := conn.WriteTo(b, &net.IPAddr{IP: dst}); err != nil {
+			return hops, err
+		}
+
+		hop := TraceHop{TTL: ttl}
+		reached := false
+		conn.SetReadDeadline(start.Add(hopTimeout))
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				break // 超时，该跳无回复
+			}
+			reply, err := icmp.ParseMessage(proto, buf[:n])
+			if err != nil {
+				continue
+			}
+			matched := false
+			switch body := reply.Body.(type) {
+			case *icmp.Echo:
+				if reply.Type == replyType && body.ID == id && body.Seq == ttl {
+					matched, reached = true, true
+				}
+			case *icmp.TimeExceeded:
+				matched = quotedEchoMatches(body.Data, v4, id, ttl)
+			case *icmp.DstUnreach:
+				// 目标不可达同样结束追踪
+				if quotedEchoMatches(body.Data, v4, id, ttl) {
+					matched, reached = true, true
+				}
+			}
+			if matched {
+				hop.Addr = peer.String()
+				hop.RTT = time.Since(start)
+				break
+			}
+		}
+		hops = append(hops, hop)
+		if reached {
+			break
+		}
+	}
+	return hops, nil
+}
+
+// quotedEchoMatches 检查 ICMP 错误报文中引用的原始 Echo 请求是否是本次追踪发出的
+func quotedEchoMatches(data []byte, v4 bool, id, seq int) bool {
+	headerLen := ipv6HeaderLen
+	if v4 {
+		if len(data) < 1 {
+			return false
+		}
+		headerLen = int(data[0]&0x0f) * 4
+	}
+	if len(data) < headerLen+8 {
+		return false
+	}
+	echo := data[headerLen:]
+	return int(binary.BigEndian.Uint16(echo[4:6])) == id && int(binary.BigEndian.Uint16(echo[6:8])) == seq
+}
+
+// formatTraceHops 将跳列表格式化为类似 traceroute 的文本
+func formatTraceHops(hops []TraceHop) string {
+	var sb strings.Builder
+	for _, h := range hops {
+		if h.Addr == "" {
+			fmt.Fprintf(&sb, "%2d  *\n", h.TTL)
+			continue
+		}
+		fmt.Fprintf(&sb, "%2d  %s  %.2f ms\n", h.TTL, h.Addr, float64(h.RTT.Microseconds())/1000.0)
+	}
+	return sb.String()
+}
+
+// diagnosticsHost 返回 ping/TCP 监控项的目标主机
+func diagnosticsHost(m model.Monitor) string {
+	if m.Type == model.MonitorTypeTCP {
+		if host, _, err := net.SplitHostPort(m.URL); err == nil {
+			return host
+		}
+	}
+	return m.URL
+}
+
+// maybeRunDiagnostics 在启用诊断的 ping/TCP 监控项由 UP 变为 DOWN 时，于后台执行一次 traceroute。
+// 不阻塞心跳写入和通知分发；同一监控项同时只会运行一次。
+func (s *Service) maybeRunDiagnostics(m model.Monitor, prevStatus, status int) {
+	if !m.RunDiagnostics || prevStatus != model.StatusUp || status != model.StatusDown {
+		return
+	}
+	if m.Type != model.MonitorTypePing && m.Type != model.MonitorTypeTCP {
+		return
+	}
+	if _, running := s.diagRunning.LoadOrStore(m.ID, true); running {
+		return
+	}
+
+	go func() {
+		defer s.diagRunning.Delete(m.ID)
+
+		startedAt := time.Now()
+		hops, err := Traceroute(diagnosticsHost(m), m.IPVersion, traceMaxHops, traceHopTimeout)
+		out := fmt.Sprintf("traceroute to %s at %s\n", diagnosticsHost(m), startedAt.Format(time.RFC3339))
+		out += formatTraceHops(hops)
+		if err != nil {
+			if strings.Contains(err.Error(), "operation not permitted") || strings.Contains(err.Error(), "permission denied") {
+				out += "traceroute unavailable: requires root or CAP_NET_RAW\n"
+			} else {
+				out += fmt.Sprintf("error: %v\n", err)
+			}
+		}
+		if len(out) > maxDiagnosticsBytes {
+			out = strings.ToValidUTF8(out[:maxDiagnosticsBytes], "") + "\n...(truncated)"
+		}
+
+		if err := db.DB.Model(&model.Monitor{}).Where("id = ?", m.ID).Updates(map[string]any{
+			"diagnostics":    out,
+			"diagnostics_at": startedAt,
+		}).Error; err != nil {
+			logger.Error("Failed to save diagnostics", zap.String("name", m.Name), zap.Error(err))
+			return
+		}
+		logger.Info("Diagnostics finished", zap.String("name", m.Name), zap.Int("hops", len(hops)))
+	}()
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"math"
+	"ping-go/pkg/logger"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// driftWindow 每个监控项保留的最近间隔样本数
+	driftWindow = 60
+	// driftWarnRatio 漂移超过监控间隔的该比例视为异常
+	driftWarnRatio = 0.1
+	// driftWarnConsecutive 连续异常达到该次数时输出告警日志
+	driftWarnConsecutive = 10
+)
+
+// DriftStats 调度漂移统计：实际检查间隔与配置间隔之差
+type DriftStats struct {
+	AvgMs   float64 `json:"avg_ms"`
+	MaxMs   float64 `json:"max_ms"`
+	Samples int     `json:"samples"`
+}
+
+// driftTracker 单个监控项的漂移样本环形缓冲
+type driftTracker struct {
+	interval time.Duration
+	last     time.Time
+	samples  [driftWindow]time.Duration
+	count    int
+	next     int
+	streak   int
+}
+
+// recordCheckStart 在每次调度执行检查前调用，记录与上一次检查的实际间隔
+func (s *Service) recordCheckStart(id uint, name string, interval time.Duration) {
+	now := time.Now()
+
+	s.driftMu.Lock()
+	defer s.driftMu.Unlock()
+
+	t, ok := s.drift[id]
+	if !ok || t.interval != interval {
+		s.drift[id] = &driftTracker{interval: interval, last: now}
+		return
+	}
+
+	drift := now.Sub(t.last) - interval
+	t.last = now
+	t.samples[t.next] = drift
+	t.next = (t.next + 1) % driftWindow
+	if t.count < driftWindow {
+		t.count++
+	}
+
+	if float64(drift) > float64(interval)*driftWarnRatio {
+		t.streak++
+		if t.streak == driftWarnConsecutive {
+			logger.Warn("Monitor scheduling drift exceeds 10% of interval",
+				zap.String("name", name),
+				zap.Duration("interval", interval),
+				zap.Duration("drift", drift),
+				zap.Int("consecutive", t.streak),
+			)
+		}
+	} else {
+		t.streak = 0
+	}
+}
+
+// resetDrift 清除监控项的漂移统计（停止或重新调度时）
+func (s *Service) resetDrift(id uint) {
+	s.driftMu.Lock()
+	defer s.driftMu.Unlock()
+	delete(s.drift, id)
+}
+
+// DriftStats 返回监控项最近的平均与最大调度漂移
+func (s *Service) DriftStats(id uint) DriftStats {
+	s.driftMu.Lock()
+	defer s.driftMu.Unlock()
+
+	t, ok := s.drift[id]
+	if !ok || t.count == 0 {
+		return DriftStats{}
+	}
+	var sum time.Duration
+	maxDrift := time.Duration(math.MinInt64)
+	for i := 0; i < t.count; i++ {
+		sum += t.samples[i]
+		if t.samples[i] > maxDrift {
+			maxDrift = t.samples[i]
+		}
+	}
+	return DriftStats{
+		AvgMs:   durationMs(sum / time.Duration(t.count)),
+		MaxMs:   durationMs(maxDrift),
+		Samples: t.count,
+	}
+}
+
+// fleetDriftP95 返回所有监控项最近样本的 p95 漂移（毫秒）
+func (s *Service) fleetDriftP95() float64 {
+	s.driftMu.Lock()
+	var all []time.Duration
+	for _, t := range s.drift {
+		all = append(all, t.samples[:t.count]...)
+	}
+	s.driftMu.Unlock()
+
+	if len(all) == 0 {
+		return 0
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	idx := int(math.Ceil(float64(len(all))*0.95)) - 1
+	return durationMs(all[idx])
+}
+
+func durationMs(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())/10.0) / 100.0
+}
@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+	"ping-go/pkg/i18n"
+	"time"
+)
+
+// escalationDue 判断是否应该发送升级通知：本轮宕机通知已发出 escalation_after_minutes 分钟后仍为 DOWN 且尚未升级，抖动期间不升级
+func (st *NotificationState) escalationDue(cfg triggerConfig, now time.Time) bool {
+	if cfg.EscalationAfterMinutes <= 0 || cfg.EscalationChannel == "" {
+		return false
+	}
+	if st.LastSentStatus != model.StatusDown || !st.DownNotified || st.Escalated || st.Flapping {
+		return false
+	}
+	return now.Sub(st.DownNotifiedAt) >= time.Duration(cfg.EscalationAfterMinutes)*time.Minute
+}
+
+// escalationRule 升级通知使用的规则：渠道换为 escalation_channel，其余配置不变；
+// 升级到邮件且设置了 escalation_email 时改发到该邮箱
+func (c triggerConfig) escalationRule() triggerConfig {
+	esc := c
+	esc.Channel = c.EscalationChannel
+	if c.EscalationChannel == notification.DefaultProvider && c.EscalationEmail != "" {
+		var m map[string]any
+		if err := json.Unmarshal(c.Config, &m); err == nil {
+			m["email"] = c.EscalationEmail
+			if raw, err := json.Marshal(m); err == nil {
+				esc.Config = raw
+			}
+		}
+	}
+	return esc
+}
+
+// sendEscalationNotification 通过升级渠道发送宕机升级通知，outage 为本次宕机的总时长
+func (s *Service) sendEscalationNotification(rule triggerConfig, result *CheckResult, outage time.Duration) {
+	lang := ruleLanguage(rule.Language)
+	subject := NotifySubject(lang, "notify.subject.escalation", result.Name, FormatDowntime(outage))
+	data := notification.StatusChangeData{
+		Name:       result.Name,
+		URL:        result.URL,
+		OldStatus:  statusToString(model.StatusDown),
+		NewStatus:  statusToString(model.StatusDown),
+		Message:    result.Message,
+		Color:      db.StatusMeta(model.StatusDown).Color,
+		StatusText: fmt.Sprintf(i18n.T(lang, "notify.title.escalation"), FormatDowntime(outage)),
+		DateTime:   time.Now().Format("2006-01-02 15:04:05"),
+		Duration:   shortDuration(outage),
+		DownSince:  formatDownSince(time.Now().Add(-outage)),
+		Condition: fmt.Sprintf("still down %s after the first notification, outage %s",
+			shortDuration(time.Duration(rule.EscalationAfterMinutes)*time.Minute), shortDuration(outage)),
+		MonitorID:   result.MonitorID,
+		MonitorType: result.Type,
+		IncidentURL: config.Get().IncidentURL(result.MonitorID, time.Now(), rule.RuleID),
+		Lang:        lang,
+		SiteName:    db.SiteName(),
+	}
+	s.deliverStatus(rule.escalationRule(), subject, data)
+}
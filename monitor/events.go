@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"ping-go/model"
+	"ping-go/pkg/eventlog"
+	"ping-go/pkg/logger"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CheckEvent 单次检查结果的结构化事件，以 NDJSON 写入 logging.check_events，供 SIEM 采集
+type CheckEvent struct {
+	Timestamp  string `json:"timestamp"`
+	MonitorID  uint   `json:"monitor_id"`
+	Name       string `json:"monitor_name"`
+	Type       string `json:"monitor_type"`
+	Status     string `json:"status"`
+	StatusKey  string `json:"status_key"`
+	ErrorClass string `json:"error_class,omitempty"`
+	Message    string `json:"msg"`
+	DurationMs int    `json:"duration_ms"`
+	ResolvedIP string `json:"resolved_ip,omitempty"` // 目前仅 HTTP 检查会记录
+}
+
+// ConfigureCheckEvents 设置（或替换）检查事件输出，target 为空表示关闭。
+// 旧输出会在写完队列中剩余事件后关闭，可在 SIGHUP 时重复调用。
+func (s *Service) ConfigureCheckEvents(target string) {
+	var sink *eventlog.Sink
+	if target != "" {
+		sink = eventlog.Open(target)
+	}
+	if old := s.checkEvents.Swap(sink); old != nil {
+		old.Close()
+		if old.Dropped() > 0 {
+			logger.Warn("Check event sink dropped events", zap.String("target", old.Target()), zap.Uint64("dropped", old.Dropped()))
+		}
+	}
+	if sink != nil {
+		logger.Info("Check events enabled", zap.String("target", target))
+	}
+}
+
+// checkEventsEnabled 是否配置了检查事件输出
+func (s *Service) checkEventsEnabled() bool {
+	return s.checkEvents.Load() != nil
+}
+
+// emitCheckEvent 写入一条检查事件，不会阻塞检查流程
+func (s *Service) emitCheckEvent(m model.Monitor, status int, msg string, duration int, resolvedIP string) {
+	sink := s.checkEvents.Load()
+	if sink == nil {
+		return
+	}
+	sink.Write(CheckEvent{
+		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
+		MonitorID:  m.ID,
+		Name:       m.Name,
+		Type:       string(m.Type),
+		Status:     statusToString(status),
+		StatusKey:  model.StatusKey(status),
+		ErrorClass: errorClass(status, msg),
+		Message:    msg,
+		DurationMs: duration,
+		ResolvedIP: resolvedIP,
+	})
+}
+
+// checkEventsDropped 返回当前输出丢弃的事件数
+func (s *Service) checkEventsDropped() uint64 {
+	if sink := s.checkEvents.Load(); sink != nil {
+		return sink.Dropped()
+	}
+	return 0
+}
+
+// errorClass 将失败消息归类，便于在 SIEM 中聚合
+func errorClass(status int, msg string) string {
+	if status != model.StatusDown {
+		return ""
+	}
+	switch {
+	case strings.HasPrefix(msg, "Host key mismatch"): // 指纹中可能出现其他类别的关键字，先于其他类别判断
+		return "host_key"
+	case strings.Contains(msg, "Timeout"):
+		return "timeout"
+	case strings.Contains(msg, "Connection Refused"), strings.Contains(msg, "Port Closed"):
+		return "connection_refused"
+	case strings.Contains(msg, "DNS Resolution Failed"), strings.Contains(msg, "Host Not Found"):
+		return "dns"
+	case strings.Contains(msg, "TLS"):
+		return "tls"
+	case strings.HasPrefix(msg, "HTTP "), strings.HasPrefix(msg, "Status "):
+		return "http_status"
+	case strings.Contains(msg, "mismatch"), strings.Contains(msg, "响应不匹配"):
+		return "content_mismatch"
+	default:
+		return "other"
+	}
+}
+
+// httpStatusRe 匹配 HTTP 状态失败消息开头的状态码（"HTTP 500 Internal Server Error Body: ..."）
+var httpStatusRe = regexp.MustCompile(`^(?:HTTP|Status) (\d{3})\b`)
+
+// publicErrorMessages errorClass 对应的公开消息
+var publicErrorMessages = map[string]string{
+	"timeout":            "Timeout",
+	"connection_refused": "Connection Refused",
+	"dns":                "DNS Error",
+	"tls":                "TLS Error",
+	"content_mismatch":   "Content Mismatch",
+	"host_key":           "Host Key Mismatch",
+}
+
+// PublicMessage 返回检查消息的公开版本，发送给未登录的客户端。检查消息可能包含响应体片段（Body: ...）、
+// 内部地址和错误详情，公开版本只保留类别：UP 为 OK，HTTP 状态失败为 "HTTP 500"，其他失败为 Timeout、DNS Error 等，无法归类时为 Down
+func PublicMessage(status int, msg string) string {
+	switch status {
+	case model.StatusUp:
+		return "OK"
+	case model.StatusPending:
+		return "Pending"
+	case model.StatusMaintenance:
+		return "Maintenance"
+	case model.StatusDown:
+		if m := httpStatusRe.FindStringSubmatch(msg); m != nil {
+			return "HTTP " + m[1]
+		}
+		if public, ok := publicErrorMessages[errorClass(status, msg)]; ok {
+			return public
+		}
+		return "Down"
+	}
+	return ""
+}
+
+// withRemoteIPTrace 记录 HTTP 请求实际连接的对端 IP
+func withRemoteIPTrace(req *http.Request, remoteIP *string) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				*remoteIP = host
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"path/filepath"
+	"ping-go/db"
+	"ping-go/model"
+	"strings"
+	"testing"
+)
+
+// internalDetails 检查消息中不能公开的内容
+var internalDetails = []string{"10.20.30.40", "db.internal.corp", "Body:", "SHA256:"}
+
+func TestPublicMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		msg       string
+		wantClass string
+		want      string
+	}{
+		{"up", model.StatusUp, "200 OK (12.34 ms) from 10.20.30.40", "", "OK"},
+		{"pending", model.StatusPending, "retry 1/3: dial tcp 10.20.30.40:5432", "", "Pending"},
+		{"maintenance", model.StatusMaintenance, "db.internal.corp in maintenance window", "", "Maintenance"},
+		{"http status", model.StatusDown, `HTTP 503 Service Unavailable Body: {"upstream":"db.internal.corp"}`, "http_status", "HTTP 503"},
+		{"timeout", model.StatusDown, "Timeout: dial tcp 10.20.30.40:443: i/o timeout", "timeout", "Timeout"},
+		{"connection refused", model.StatusDown, "Connection Refused: dial tcp 10.20.30.40:5432", "connection_refused", "Connection Refused"},
+		{"dns", model.StatusDown, "DNS Resolution Failed: lookup db.internal.corp: no such host", "dns", "DNS Error"},
+		{"tls", model.StatusDown, "TLS handshake with db.internal.corp failed", "tls", "TLS Error"},
+		{"content mismatch", model.StatusDown, "Keyword mismatch Body: token=10.20.30.40", "content_mismatch", "Content Mismatch"},
+		{"ssh host key", model.StatusDown, "Host key mismatch (got ssh-ed25519 SHA256:TLSTimeoutHTTP+abc)", "host_key", "Host Key Mismatch"},
+		{"unclassified", model.StatusDown, "exit status 2 on db.internal.corp", "other", "Down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorClass(tt.status, tt.msg); got != tt.wantClass {
+				t.Errorf("errorClass = %q, want %q", got, tt.wantClass)
+			}
+			got := PublicMessage(tt.status, tt.msg)
+			if got != tt.want {
+				t.Errorf("PublicMessage = %q, want %q", got, tt.want)
+			}
+			for _, s := range internalDetails {
+				if strings.Contains(got, s) {
+					t.Errorf("PublicMessage %q leaks %q", got, s)
+				}
+			}
+		})
+	}
+}
+
+// 状态页订阅者的通知邮件只包含检查消息的类别
+func TestNotifyStatusSubscribersUsesPublicMessage(t *testing.T) {
+	if err := db.Init(filepath.Join(t.TempDir(), "pinggo.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	s := &Service{subscriberQueue: make(chan SubscriberUpdate, 2)}
+	m := model.Monitor{ID: 1, Name: "api"}
+
+	s.notifyStatusSubscribers(m, model.StatusUp, &model.Heartbeat{Status: model.StatusDown, Message: "Timeout: dial tcp 10.20.30.40:443 (db.internal.corp)"})
+	s.notifyStatusSubscribers(m, model.StatusDown, &model.Heartbeat{Status: model.StatusUp, Message: "200 OK from 10.20.30.40"})
+	for _, want := range []string{"Timeout", "OK"} {
+		u := <-s.subscriberQueue
+		if u.Body != want {
+			t.Errorf("subscriber body = %q, want %q", u.Body, want)
+		}
+		for _, s := range internalDetails {
+			if strings.Contains(u.Subject+u.Body, s) {
+				t.Errorf("subscriber update %+v leaks %q", u, s)
+			}
+		}
+	}
+}
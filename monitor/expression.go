@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"ping-go/model"
+	"ping-go/pkg/expr"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expressionTimeout 单次表达式求值的时间上限
+const expressionTimeout = 50 * time.Millisecond
+
+// ExpressionVariable 表达式中可以使用的变量（通过 validateMonitor 的预检结果返回给前端）
+type ExpressionVariable struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// ExpressionVariables HTTP 检查表达式可用的变量
+var ExpressionVariables = []ExpressionVariable{
+	{Name: "status", Type: "number", Description: "HTTP status code of the final response"},
+	{Name: "headers", Type: "object", Description: `response headers with lowercase names, first value only, e.g. headers["x-queue-depth"]`},
+	{Name: "body", Type: "any", Description: "response body parsed as JSON, nil when the body is not JSON"},
+	{Name: "text", Type: "string", Description: "raw response body (decompressed, up to monitor.max_body_bytes)"},
+	{Name: "duration", Type: "number", Description: "response time in milliseconds"},
+	{Name: "url", Type: "string", Description: "final URL after redirects"},
+}
+
+// ExpressionFunctions 表达式可用的内置函数
+var ExpressionFunctions = []string{
+	"len(x)", "num(x)", "str(x)", "lower(s)", "upper(s)", "pass(msg)", "fail(msg)",
+	"a contains b", "a startsWith b", "a endsWith b", "a matches regex",
+}
+
+var expressionVarNames = func() []string {
+	names := make([]string, len(ExpressionVariables))
+	for i, v := range ExpressionVariables {
+		names[i] = v.Name
+	}
+	return names
+}()
+
+// CompileExpression 编译监控项的断言表达式（保存时校验）
+func CompileExpression(source string) (*expr.Program, error) {
+	return expr.Compile(source, expressionVarNames)
+}
+
+var (
+	expressionMu    sync.Mutex
+	expressionCache = make(map[uint]*expr.Program)
+)
+
+// expressionFor 返回监控项编译后的表达式，按监控项 ID 缓存，表达式内容变化时重新编译
+func expressionFor(m model.Monitor) (*expr.Program, error) {
+	source := strings.TrimSpace(m.Expression)
+	if m.ID != 0 {
+		expressionMu.Lock()
+		prog, ok := expressionCache[m.ID]
+		expressionMu.Unlock()
+		if ok && prog.Source() == source {
+			return prog, nil
+		}
+	}
+	prog, err := CompileExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	if m.ID != 0 {
+		expressionMu.Lock()
+		expressionCache[m.ID] = prog
+		expressionMu.Unlock()
+	}
+	return prog, nil
+}
+
+// invalidateExpression 丢弃监控项缓存的表达式（停止监控时调用）
+func invalidateExpression(id uint) {
+	expressionMu.Lock()
+	delete(expressionCache, id)
+	expressionMu.Unlock()
+}
+
+// expressionUsesBody 表达式是否引用了响应体（body 或 text）；无法编译时返回 true，由执行时报告错误
+func expressionUsesBody(m model.Monitor) bool {
+	prog, err := expressionFor(m)
+	if err != nil {
+		return true
+	}
+	return prog.Uses("body") || prog.Uses("text")
+}
+
+// evalExpression 在响应上执行监控项的断言表达式，返回是否通过以及消息
+func evalExpression(m model.Monitor, resp *http.Response, body []byte, duration time.Duration) (bool, string) {
+	prog, err := expressionFor(m)
+	if err != nil {
+		return false, "Expression error: " + err.Error()
+	}
+
+	headers := make(map[string]any, len(resp.Header))
+	for k, v := range resp.Header {
+		if len(v) > 0 {
+			headers[strings.ToLower(k)] = v[0]
+		}
+	}
+	var parsed any
+	if json.Unmarshal(body, &parsed) != nil {
+		parsed = nil
+	}
+	env := map[string]any{
+		"status":   float64(resp.StatusCode),
+		"headers":  headers,
+		"body":     parsed,
+		"text":     string(body),
+		"duration": float64(duration.Microseconds()) / 1000.0,
+		"url":      resp.Request.URL.String(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), expressionTimeout)
+	defer cancel()
+	res, err := prog.Run(ctx, env)
+	if err != nil {
+		return false, "Expression error: " + err.Error()
+	}
+	if !res.Pass {
+		if res.Message != "" {
+			return false, "表达式校验失败: " + res.Message
+		}
+		return false, "表达式校验失败"
+	}
+	return true, res.Message
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultFlapWindow 开启抖动检测但未设置 flap_window_minutes 时统计状态变化的时间窗口
+	defaultFlapWindow = 10 * time.Minute
+	// rateLimitWindow rate_limit_per_hour 的统计窗口
+	rateLimitWindow = time.Hour
+)
+
+// flapWindow 统计状态变化次数的时间窗口
+func (c triggerConfig) flapWindow() time.Duration {
+	if c.FlapWindowMinutes > 0 {
+		return time.Duration(c.FlapWindowMinutes) * time.Minute
+	}
+	return defaultFlapWindow
+}
+
+// flapStable 抖动期间状态需要保持不变多久才恢复正常通知，默认与统计窗口相同
+func (c triggerConfig) flapStable() time.Duration {
+	if c.FlapStableMinutes > 0 {
+		return time.Duration(c.FlapStableMinutes) * time.Minute
+	}
+	return c.flapWindow()
+}
+
+// noteTransition 记录一次状态变化（已判定的 UP/DOWN 切换），返回是否由此进入抖动状态
+func (st *NotificationState) noteTransition(cfg triggerConfig, now time.Time) bool {
+	st.LastTransitionAt = now
+	if cfg.FlapTransitions <= 0 {
+		st.Transitions = nil
+		return false
+	}
+	st.Transitions = append(pruneBefore(st.Transitions, now.Add(-cfg.flapWindow())), now)
+	if st.Flapping || len(st.Transitions) < cfg.FlapTransitions {
+		return false
+	}
+	st.Flapping = true
+	st.Transitions = nil
+	return true
+}
+
+// flapEnded 抖动状态下状态已保持 flap_stable_minutes 不变时结束抖动并返回 true；规则关闭抖动检测后立即结束
+func (st *NotificationState) flapEnded(cfg triggerConfig, now time.Time) bool {
+	if !st.Flapping {
+		return false
+	}
+	if cfg.FlapTransitions > 0 && now.Sub(st.LastTransitionAt) < cfg.flapStable() {
+		return false
+	}
+	st.Flapping = false
+	return true
+}
+
+// allowSend 按 rate_limit_per_hour 判断这个监控项在最近一小时内是否还能发送通知，0 表示不限制
+func (st *NotificationState) allowSend(limit int, now time.Time) bool {
+	st.SentTimes = pruneBefore(st.SentTimes, now.Add(-rateLimitWindow))
+	return limit <= 0 || len(st.SentTimes) < limit
+}
+
+// markSent 记录一次实际发送，返回上次发送后被抑制的通知数并清零
+func (st *NotificationState) markSent(now time.Time) int {
+	st.SentTimes = append(pruneBefore(st.SentTimes, now.Add(-rateLimitWindow)), now)
+	n := st.Suppressed
+	st.Suppressed = 0
+	return n
+}
+
+// pruneBefore 移除早于 cutoff 的时间戳（按时间顺序追加，只需找到第一个不早于 cutoff 的位置）
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// withSuppressed 在触发条件后注明被抑制的通知数，各渠道都会显示 Condition
+func withSuppressed(condition string, suppressed int) string {
+	if suppressed <= 0 {
+		return condition
+	}
+	note := fmt.Sprintf("%d notifications suppressed since the last one", suppressed)
+	if condition == "" {
+		return note
+	}
+	return condition + "; " + note
+}
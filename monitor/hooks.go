@@ -0,0 +1,289 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"ping-go/model"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// hookBodyLimit 钩子响应读取上限（用于提取变量）
+	hookBodyLimit = 1024 * 1024
+	// hookRedactMinLen 提取值达到该长度才会从消息中脱敏
+	hookRedactMinLen = 4
+)
+
+var (
+	hookVarPattern  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	hookNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// HookSpec 主检查前/后执行的 HTTP 请求，以 JSON 存储在 Monitor.PreHook / PostHook 中。
+// URL、Headers、Body 中可以使用 {{name}} 引用前置钩子提取的变量
+type HookSpec struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	Extract []HookExtract     `json:"extract"` // 仅前置钩子有效
+}
+
+// HookExtract 从前置钩子响应中提取一个变量：JSON 为点路径（如 data.token、items.0.id，可带 "$." 前缀），
+// Regex 取第一个捕获组（没有捕获组时取整个匹配），二者选其一
+type HookExtract struct {
+	Name  string `json:"name"`
+	JSON  string `json:"json"`
+	Regex string `json:"regex"`
+}
+
+// hookVars 运行时提取的变量，只存在于单次检查中，不写入数据库和心跳
+type hookVars map[string]string
+
+// ParseHook 解析并校验钩子配置，raw 为空时返回 nil
+func ParseHook(raw string) (*HookSpec, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var h HookSpec
+	if err := json.Unmarshal([]byte(raw), &h); err != nil {
+		return nil, fmt.Errorf("invalid hook JSON: %w", err)
+	}
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// validate 校验请求地址和变量提取规则
+func (h *HookSpec) validate() error {
+	if h.URL == "" {
+		return errors.New("url is required")
+	}
+	if !strings.HasPrefix(h.URL, "http://") && !strings.HasPrefix(h.URL, "https://") && !strings.HasPrefix(h.URL, "{{") {
+		return errors.New("url must start with http:// or https://")
+	}
+	names := make(map[string]bool, len(h.Extract))
+	for _, e := range h.Extract {
+		if !hookNamePattern.MatchString(e.Name) {
+			return fmt.Errorf("invalid variable name %q", e.Name)
+		}
+		if names[e.Name] {
+			return fmt.Errorf("duplicate variable %q", e.Name)
+		}
+		names[e.Name] = true
+		if (e.JSON == "") == (e.Regex == "") {
+			return fmt.Errorf("variable %q needs exactly one of json or regex", e.Name)
+		}
+		if e.Regex != "" {
+			if _, err := regexp.Compile(e.Regex); err != nil {
+				return fmt.Errorf("variable %q: invalid regex: %v", e.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expand 替换 {{name}} 占位符，未知变量保持原样
+func (v hookVars) expand(s string) string {
+	if len(v) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	return hookVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := hookVarPattern.FindStringSubmatch(match)[1]
+		if val, ok := v[name]; ok {
+			return val
+		}
+		return match
+	})
+}
+
+// redact 从消息中移除提取到的变量值，避免写入心跳。
+// 过短的值（如数字 ID、布尔值）不替换，以免误伤消息中的状态码等内容
+func (v hookVars) redact(msg string) string {
+	for _, val := range v {
+		if len(val) >= hookRedactMinLen {
+			msg = redactSecret(msg, val)
+		}
+	}
+	return msg
+}
+
+// runHook 执行一个钩子请求，要求返回 expectedStatus（0 表示 2xx）；按 Extract 提取变量并合并到 vars
+func runHook(ctx context.Context, client *http.Client, h *HookSpec, expectedStatus int, vars hookVars) error {
+	method := h.Method
+	if method == "" {
+		method = "GET"
+	}
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(vars.expand(h.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), vars.expand(h.URL), body)
+	if err != nil {
+		return fmt.Errorf("create request: %v", err)
+	}
+	for k, val := range h.Headers {
+		req.Header.Set(k, vars.expand(val))
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "PingGo-Monitor/1.0")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if msg, ok := proxyErrorMessage(err); ok {
+			return errors.New(msg)
+		}
+		errStr := err.Error()
+		switch {
+		case strings.Contains(errStr, "deadline exceeded") || strings.Contains(errStr, "Client.Timeout"):
+			return errors.New("Timeout")
+		case strings.Contains(errStr, "connection refused"):
+			return errors.New("Connection Refused")
+		case isResolutionError(errStr):
+			return errors.New("DNS Resolution Failed: " + req.URL.Hostname())
+		}
+		if len(errStr) > 40 {
+			errStr = errStr[:37] + "..."
+		}
+		return errors.New(errStr)
+	}
+	defer drainAndClose(resp.Body)
+
+	if expectedStatus > 0 && resp.StatusCode != expectedStatus {
+		return fmt.Errorf("Status %d (Expected %d)", resp.StatusCode, expectedStatus)
+	}
+	if expectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return fmt.Errorf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if len(h.Extract) == 0 {
+		return nil
+	}
+
+	data, err := readBody(decodedBody(resp), hookBodyLimit)
+	if err != nil {
+		return errors.New(bodyReadError(err))
+	}
+	var parsed any
+	parsedOK := false
+	for _, e := range h.Extract {
+		var val string
+		if e.JSON != "" {
+			if !parsedOK {
+				if err := json.Unmarshal(data, &parsed); err != nil {
+					return fmt.Errorf("extract %s: response is not JSON", e.Name)
+				}
+				parsedOK = true
+			}
+			v, ok := lookupJSONPath(parsed, e.JSON)
+			if !ok {
+				return fmt.Errorf("extract %s: path %s not found", e.Name, e.JSON)
+			}
+			val = v
+		} else {
+			sub := regexp.MustCompile(e.Regex).FindSubmatch(data)
+			if sub == nil {
+				return fmt.Errorf("extract %s: regex did not match", e.Name)
+			}
+			val = string(sub[0])
+			if len(sub) > 1 {
+				val = string(sub[1])
+			}
+		}
+		vars[e.Name] = val
+	}
+	return nil
+}
+
+// LookupJSONPath 按点路径从解析后的 JSON 中取值（供编辑器测试断言使用），规则与钩子的 json 提取相同
+func LookupJSONPath(doc any, path string) (string, bool) {
+	return lookupJSONPath(doc, path)
+}
+
+// lookupJSONPath 按点路径取值，支持 a.b、a.0.b 和 a[0].b 写法；对象和数组以 JSON 文本返回
+func lookupJSONPath(doc any, path string) (string, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	cur := doc
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			switch node := cur.(type) {
+			case map[string]any:
+				v, ok := node[key]
+				if !ok {
+					return "", false
+				}
+				cur = v
+			case []any:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(node) {
+					return "", false
+				}
+				cur = node[i]
+			default:
+				return "", false
+			}
+		}
+	}
+
+	switch v := cur.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+}
+
+// runPreHook 执行前置钩子并返回提取到的变量，失败时返回 "Pre-hook failed: ..." 消息
+func runPreHook(ctx context.Context, m model.Monitor) (hookVars, string) {
+	vars := hookVars{}
+	h, err := ParseHook(m.PreHook)
+	if err != nil {
+		return vars, "Pre-hook failed: " + err.Error()
+	}
+	if h == nil {
+		return vars, ""
+	}
+	client, err := httpClientFor(m)
+	if err != nil {
+		return vars, "Pre-hook failed: " + httpClientError(err)
+	}
+	if err := runHook(ctx, client, h, 0, vars); err != nil {
+		return vars, "Pre-hook failed: " + vars.redact(err.Error())
+	}
+	return vars, ""
+}
+
+// runPostHook 执行后置钩子（使用剩余的超时预算），失败时返回 "Post-hook failed: ..." 消息
+func runPostHook(ctx context.Context, m model.Monitor, vars hookVars) string {
+	h, err := ParseHook(m.PostHook)
+	if err != nil {
+		return "Post-hook failed: " + err.Error()
+	}
+	if h == nil {
+		return ""
+	}
+	client, err := httpClientFor(m)
+	if err != nil {
+		return "Post-hook failed: " + httpClientError(err)
+	}
+	if err := runHook(ctx, client, h, 0, vars); err != nil {
+		return "Post-hook failed: " + vars.redact(err.Error())
+	}
+	return ""
+}
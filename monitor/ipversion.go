@@ -0,0 +1,36 @@
+package monitor
+
+import (
+	"ping-go/model"
+	"strings"
+)
+
+// ipNetwork 根据监控项的 IP 版本返回受限的网络类型，例如 ("tcp", "ipv4") -> "tcp4"、("ip", "ipv6") -> "ip6"
+func ipNetwork(base, ipVersion string) string {
+	switch ipVersion {
+	case model.IPVersion4:
+		return base + "4"
+	case model.IPVersion6:
+		return base + "6"
+	default:
+		return base
+	}
+}
+
+// ipVersionSuffix 强制地址族时附加到心跳消息末尾的标记
+func ipVersionSuffix(ipVersion string) string {
+	switch ipVersion {
+	case model.IPVersion4:
+		return " [IPv4]"
+	case model.IPVersion6:
+		return " [IPv6]"
+	default:
+		return ""
+	}
+}
+
+// isResolutionError 判断错误是否属于域名解析失败
+// 强制地址族时若缺少对应记录（如没有 AAAA），Go 返回 "no suitable address found"，同样视为解析失败而不是回退到另一地址族
+func isResolutionError(errStr string) bool {
+	return strings.Contains(errStr, "no such host") || strings.Contains(errStr, "no suitable address")
+}
@@ -0,0 +1,20 @@
+package monitor
+
+import (
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/i18n"
+)
+
+// ruleLanguage 规则发送通知使用的语言：规则设置了受支持的 language 时使用它，否则使用邮件语言设置
+func ruleLanguage(lang string) string {
+	if i18n.Supported(lang) {
+		return lang
+	}
+	return db.EmailLanguage()
+}
+
+// statusWord 通知主题中的状态文案，如 "DOWN" / "宕机"
+func statusWord(lang string, status int) string {
+	return i18n.T(lang, "notify.status."+model.StatusKey(status))
+}
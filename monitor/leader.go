@@ -0,0 +1,259 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// schedulerLease 调度租约名称
+	schedulerLease = "scheduler"
+	// defaultLeaseDuration 默认租约时长，续期间隔为其 1/3
+	defaultLeaseDuration = 15 * time.Second
+	// instanceExpiry 超过该时长没有心跳的实例登记会被清理
+	instanceExpiry = 24 * time.Hour
+)
+
+// haState 多实例模式状态
+type haState struct {
+	instanceID string
+	lease      time.Duration
+	inst       model.Instance
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// EnableHA 启用多实例模式：多个实例共享同一数据库，通过租约选举出领导者。
+// 只有领导者执行监控调度、数据聚合、定时报告和通知发送，其余实例只提供 Web/Socket 服务。
+// 必须在 Start 之前调用。
+func (s *Service) EnableHA(instanceID string, leaseSeconds int) {
+	hostname, _ := os.Hostname()
+	if instanceID == "" {
+		instanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	lease := time.Duration(leaseSeconds) * time.Second
+	if lease <= 0 {
+		lease = defaultLeaseDuration
+	}
+
+	s.ha = &haState{
+		instanceID: instanceID,
+		lease:      lease,
+		inst:       model.Instance{ID: instanceID, Hostname: hostname, Role: model.RoleFollower, StartedAt: time.Now()},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	db.SetLeaderCheck(s.IsLeader)
+
+	// 先尝试一次选举，使随后的 Start 能直接按角色决定是否调度
+	acquired, err := db.AcquireLease(schedulerLease, instanceID, lease)
+	if err != nil {
+		logger.Error("Failed to acquire scheduler lease", zap.Error(err))
+	}
+	s.leader.Store(acquired)
+	if acquired {
+		go db.ForceAggregation()
+	}
+	s.ha.inst.Role = s.Role()
+	if err := db.TouchInstance(&s.ha.inst); err != nil {
+		logger.Error("Failed to update instance heartbeat", zap.Error(err))
+	}
+
+	go s.runElection()
+	logger.Info("HA mode enabled", zap.String("instance", instanceID), zap.String("role", s.Role()), zap.Duration("lease", lease))
+}
+
+// IsLeader 返回当前实例是否负责调度。未启用多实例模式时总是 true
+func (s *Service) IsLeader() bool {
+	return s.ha == nil || s.leader.Load()
+}
+
+// Role 返回当前实例角色
+func (s *Service) Role() string {
+	switch {
+	case s.ha == nil:
+		return model.RoleStandalone
+	case s.leader.Load():
+		return model.RoleLeader
+	default:
+		return model.RoleFollower
+	}
+}
+
+// InstanceID 返回多实例模式下的实例标识，未启用时为空
+func (s *Service) InstanceID() string {
+	if s.ha == nil {
+		return ""
+	}
+	return s.ha.instanceID
+}
+
+// leaderInstance 返回当前持有调度租约的实例标识（用于健康检查），未启用多实例模式时为空
+func (s *Service) leaderInstance() string {
+	if s.ha == nil {
+		return ""
+	}
+	holder, err := db.GetLeaseHolder(schedulerLease)
+	if err != nil {
+		logger.Error("Failed to query scheduler lease", zap.Error(err))
+	}
+	return holder
+}
+
+// holdsLeaseNow 发送通知前实时确认租约仍由本实例持有，防止网络分区时两个实例重复发送
+func (s *Service) holdsLeaseNow() bool {
+	if s.ha == nil {
+		return true
+	}
+	if !s.leader.Load() {
+		return false
+	}
+	ok, err := db.HoldsLease(schedulerLease, s.ha.instanceID)
+	if err != nil {
+		logger.Error("Failed to verify scheduler lease", zap.Error(err))
+		return false
+	}
+	return ok
+}
+
+// runElection 每 1/3 租约时长续期或尝试抢占租约
+func (s *Service) runElection() {
+	defer close(s.ha.done)
+	ticker := time.NewTicker(s.ha.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.electionTick()
+		case <-s.ha.stop:
+			return
+		}
+	}
+}
+
+func (s *Service) electionTick() {
+	acquired, err := db.AcquireLease(schedulerLease, s.ha.instanceID, s.ha.lease)
+	if err != nil {
+		logger.Error("Failed to acquire scheduler lease", zap.Error(err))
+		acquired = false
+	}
+
+	wasLeader := s.leader.Load()
+	switch {
+	case acquired && !wasLeader:
+		s.leader.Store(true)
+		logger.Info("Became leader, taking over monitor scheduling", zap.String("instance", s.ha.instanceID))
+		s.loadNotificationStates()
+		s.Start()
+		// 补跑可能因切换而错过的聚合
+		go db.ForceAggregation()
+	case acquired:
+		s.syncSchedules()
+	case wasLeader:
+		s.leader.Store(false)
+		logger.Warn("Lost scheduler lease, stepping down", zap.String("instance", s.ha.instanceID))
+		s.suspendSchedules()
+	}
+
+	s.ha.inst.Role = s.Role()
+	if err := db.TouchInstance(&s.ha.inst); err != nil {
+		logger.Error("Failed to update instance heartbeat", zap.Error(err))
+	}
+	if acquired {
+		if err := db.RemoveStaleInstances(instanceExpiry); err != nil {
+			logger.Error("Failed to remove stale instances", zap.Error(err))
+		}
+	}
+}
+
+// syncSchedules 领导者定期与数据库对齐调度：其他实例上新增、修改、暂停或删除的监控项
+// 只写入了数据库，需要在这里启动、重新调度或停止
+func (s *Service) syncSchedules() {
+	// 启动（包括重新当选后的启动）尚未完成时由启动流程负责调度
+	if s.GetStartupProgress().Starting {
+		return
+	}
+	var monitors []model.Monitor
+	if err := db.DB.Find(&monitors).Error; err != nil {
+		logger.Error("Failed to load monitors", zap.Error(err))
+		return
+	}
+
+	active := make(map[uint]bool, len(monitors))
+	var toStart []model.Monitor
+	s.mu.Lock()
+	for _, m := range monitors {
+		if m.Active != 1 {
+			continue
+		}
+		active[m.ID] = true
+		interval := max(m.Interval, MinMonitorInterval)
+		cur, ok := s.monitors[m.ID]
+		if _, running := s.tickers[m.ID]; !running || !ok || cur.Interval != interval {
+			toStart = append(toStart, m)
+		}
+	}
+	var toStop []uint
+	for id := range s.tickers {
+		if !active[id] {
+			toStop = append(toStop, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, m := range toStart {
+		monitor := m
+		s.StartMonitor(&monitor)
+	}
+	for _, id := range toStop {
+		s.StopMonitor(id)
+	}
+}
+
+// suspendSchedules 失去租约后停止所有调度，但保留监控项登记，重新当选时再恢复
+func (s *Service) suspendSchedules() {
+	s.cancelStartup()
+	// 交给新的领导者从数据库恢复
+	s.persistNotificationStates()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, stopChan := range s.stopChans {
+		delete(s.stopChans, id)
+		close(stopChan)
+	}
+	for id, t := range s.tickers {
+		t.Stop()
+		delete(s.tickers, id)
+		s.resetDrift(id)
+	}
+	s.notificationStates = make(map[string]*NotificationState)
+	s.dirtyStates = make(map[string]bool)
+	s.flushAllSamples()
+}
+
+// stopHA 停止选举并释放租约，使其他实例立即接管
+func (s *Service) stopHA() {
+	if s.ha == nil {
+		return
+	}
+	select {
+	case <-s.ha.stop:
+		return
+	default:
+	}
+	close(s.ha.stop)
+	<-s.ha.done
+	if s.leader.Swap(false) {
+		if err := db.ReleaseLease(schedulerLease, s.ha.instanceID); err != nil {
+			logger.Error("Failed to release scheduler lease", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maintenanceMessage 维护期间显示的状态消息，until 为本次维护的结束时间
+func maintenanceMessage(w *model.MaintenanceWindow, until time.Time) string {
+	msg := "Maintenance until " + until.Format(time.RFC3339)
+	if w.Reason != "" {
+		msg += fmt.Sprintf(" (%s)", w.Reason)
+	}
+	return msg
+}
+
+// checkMaintenance 监控项处于维护窗口时将状态置为维护中并返回 true。
+// 维护期间不执行检查、不交给通知 worker，只写入维护心跳；窗口结束或删除后的第一次检查按正常流程处理
+func (s *Service) checkMaintenance(m model.Monitor) bool {
+	w, until := db.ActiveMaintenance(m, time.Now())
+	if w == nil {
+		return false
+	}
+	s.enterMaintenance(m, w, until)
+	return true
+}
+
+// enterMaintenance 更新监控状态并写入一条维护心跳。维护心跳不计入可用率
+func (s *Service) enterMaintenance(m model.Monitor, w *model.MaintenanceWindow, until time.Time) {
+	prevStatus := m.Status
+	m.Status = model.StatusMaintenance
+	m.Message = maintenanceMessage(w, until)
+	m.LastCheck = time.Now()
+	db.DB.Model(&m).Select("Status", "Message", "LastCheck").Updates(&m)
+
+	heartbeat := model.Heartbeat{
+		MonitorID: m.ID,
+		Status:    model.StatusMaintenance,
+		Message:   m.Message,
+		Time:      m.LastCheck,
+	}
+	s.persistHeartbeat(m, &heartbeat)
+	s.recordStatusChange(m, prevStatus, &heartbeat)
+	if s.OnHeartbeat != nil {
+		s.OnHeartbeat(&heartbeat)
+	}
+}
+
+// ApplyMaintenance 在维护窗口创建、修改、关闭或删除后立即生效：
+// 生效中的窗口直接将作用的监控项置为维护中，不再处于维护的监控项由领导者立即检查一次以恢复真实状态；
+// 领导者同时在一次性窗口结束时安排一次检查，使过期窗口无需等待下一个检查周期
+func (s *Service) ApplyMaintenance(windows []model.MaintenanceWindow) {
+	now := time.Now()
+	for _, w := range windows {
+		for _, m := range db.MaintenanceMonitors(w) {
+			if m.Active != 1 {
+				continue
+			}
+			if active, until := db.ActiveMaintenance(m, now); active != nil {
+				s.enterMaintenance(m, active, until)
+			} else if m.Status == model.StatusMaintenance && s.IsLeader() {
+				go s.Check(m.ID)
+				continue
+			}
+			if !w.Recurring() && w.EndsAt.After(now) {
+				s.scheduleMaintenanceEnd(w, m.ID)
+			}
+		}
+	}
+}
+
+// scheduleMaintenanceEnd 领导者在一次性窗口结束时立即检查一次（窗口被延长或提前关闭时，多余的检查按最新窗口处理）。
+// 周期性窗口每次结束后由下一次检查恢复
+func (s *Service) scheduleMaintenanceEnd(w model.MaintenanceWindow, monitorID uint) {
+	if !s.IsLeader() {
+		return
+	}
+	time.AfterFunc(time.Until(w.EndsAt), func() {
+		if !s.IsLeader() {
+			return
+		}
+		logger.Info("Maintenance window ended", zap.Uint("monitorID", monitorID), zap.Uint("windowID", w.ID))
+		s.Check(monitorID)
+	})
+}
+
+// scheduleAllMaintenanceEnds 启动时为尚未结束的一次性维护窗口安排到期检查
+func (s *Service) scheduleAllMaintenanceEnds() {
+	for _, w := range db.PendingMaintenance(time.Now()) {
+		if w.Recurring() {
+			continue
+		}
+		for _, m := range db.MaintenanceMonitors(w) {
+			s.scheduleMaintenanceEnd(w, m.ID)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"ping-go/model"
+	"sync/atomic"
+	"time"
+)
+
+// 进程级计数器，由 /metrics 导出，进程重启后从 0 开始
+var (
+	checksTotal         atomic.Uint64
+	checksFailed        atomic.Uint64
+	notificationsSent   atomic.Uint64
+	notificationsFailed atomic.Uint64
+)
+
+// Counters 进程启动以来的检查次数和通知发送次数
+type Counters struct {
+	Checks              uint64
+	ChecksFailed        uint64 // 结果为 DOWN 的检查
+	NotificationsSent   uint64 // 发送成功的通知（含测试消息和重新发送）
+	NotificationsFailed uint64
+}
+
+// ReadCounters 返回当前的计数
+func ReadCounters() Counters {
+	return Counters{
+		Checks:              checksTotal.Load(),
+		ChecksFailed:        checksFailed.Load(),
+		NotificationsSent:   notificationsSent.Load(),
+		NotificationsFailed: notificationsFailed.Load(),
+	}
+}
+
+// LastCheck 本实例最近一次检查的结果
+type LastCheck struct {
+	Status   int
+	Duration int // 响应时间（毫秒）
+	Time     time.Time
+}
+
+// recordCheckMetrics 更新检查计数和监控项最近一次检查的结果
+func (s *Service) recordCheckMetrics(h *model.Heartbeat) {
+	checksTotal.Add(1)
+	if h.Status == model.StatusDown {
+		checksFailed.Add(1)
+	}
+	s.lastChecks.Store(h.MonitorID, LastCheck{Status: h.Status, Duration: h.Duration, Time: h.Time})
+}
+
+// recordNotificationMetrics 更新通知发送计数
+func recordNotificationMetrics(err error) {
+	if err != nil {
+		notificationsFailed.Add(1)
+	} else {
+		notificationsSent.Add(1)
+	}
+}
+
+// LastCheckOf 返回本实例最近一次检查监控项的结果；本实例没有检查过（如非领导者实例、刚启动）时返回 false
+func (s *Service) LastCheckOf(id uint) (LastCheck, bool) {
+	v, ok := s.lastChecks.Load(id)
+	if !ok {
+		return LastCheck{}, false
+	}
+	return v.(LastCheck), true
+}
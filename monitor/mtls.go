@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"ping-go/model"
+	"sync"
+	"time"
+)
+
+// clientCertClients 配置了客户端证书或代理的监控项各自使用独立的 Transport，按监控项 ID 缓存
+type clientCertEntry struct {
+	fingerprint string
+	transport   *http.Transport
+	follow      *http.Client
+	noredirect  *http.Client
+}
+
+var (
+	clientCertMu      sync.Mutex
+	clientCertClients = make(map[uint]*clientCertEntry)
+)
+
+// hasClientCert 监控项是否配置了 mTLS 客户端证书
+func hasClientCert(m model.Monitor) bool {
+	return m.ClientCert != "" || m.ClientKey != ""
+}
+
+// ParseClientCertificate 解析 PEM 格式的客户端证书与私钥，并校验二者是否匹配
+func ParseClientCertificate(certPEM, keyPEM string) (tls.Certificate, error) {
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return cert, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// InvalidateHTTPClient 丢弃监控项缓存的独立客户端（编辑或停止监控时调用）
+func InvalidateHTTPClient(id uint) {
+	clientCertMu.Lock()
+	entry, ok := clientCertClients[id]
+	delete(clientCertClients, id)
+	clientCertMu.Unlock()
+
+	if ok {
+		entry.transport.CloseIdleConnections()
+	}
+}
+
+// httpClientFor 返回监控项使用的 HTTP 客户端：未配置客户端证书和代理时使用共享客户端，
+// 否则使用带证书或代理的独立客户端。ID 为 0（测试未保存的监控项）时不缓存
+func httpClientFor(m model.Monitor) (*http.Client, error) {
+	if !hasClientCert(m) && m.ProxyURL == "" {
+		return getHTTPClient(m.FollowRedirects, m.IPVersion), nil
+	}
+
+	sum := sha256.Sum256([]byte(m.ClientCert + "\x00" + m.ClientKey + "\x00" + m.IPVersion + "\x00" + m.ProxyURL))
+	fp := hex.EncodeToString(sum[:8])
+	if m.ID != 0 {
+		clientCertMu.Lock()
+		entry, ok := clientCertClients[m.ID]
+		clientCertMu.Unlock()
+		if ok && entry.fingerprint == fp {
+			return entry.pick(m.FollowRedirects), nil
+		}
+	}
+
+	transport := newTransport(m.IPVersion)
+	if hasClientCert(m) {
+		cert, err := ParseClientCertificate(m.ClientCert, m.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	if m.ProxyURL != "" {
+		u, err := ParseProxyURL(m.ProxyURL)
+		if err != nil {
+			return nil, proxyConfigError{err}
+		}
+		if err := applyProxy(transport, u); err != nil {
+			return nil, proxyConfigError{err}
+		}
+	}
+	entry := &clientCertEntry{
+		fingerprint: fp,
+		transport:   transport,
+		follow:      &http.Client{Transport: transport, Timeout: 600 * time.Second},
+		noredirect: &http.Client{
+			Transport: transport,
+			Timeout:   600 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+
+	if m.ID != 0 {
+		clientCertMu.Lock()
+		if old, ok := clientCertClients[m.ID]; ok {
+			old.transport.CloseIdleConnections()
+		}
+		clientCertClients[m.ID] = entry
+		clientCertMu.Unlock()
+	}
+	return entry.pick(m.FollowRedirects), nil
+}
+
+// proxyConfigError 代理配置无效，与客户端证书错误区分开
+type proxyConfigError struct{ err error }
+
+func (e proxyConfigError) Error() string { return e.err.Error() }
+
+// httpClientError 将 httpClientFor 的错误转换为心跳消息
+func httpClientError(err error) string {
+	var perr proxyConfigError
+	if errors.As(err, &perr) {
+		return "Proxy Error (" + perr.Error() + ")"
+	}
+	return "TLS Error (" + err.Error() + ")"
+}
+
+func (e *clientCertEntry) pick(followRedirects bool) *http.Client {
+	if followRedirects {
+		return e.follow
+	}
+	return e.noredirect
+}
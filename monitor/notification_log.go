@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+)
+
+// SendRecorded 通过渠道同步发送一条通知并写入发送记录（NotificationLog），返回该记录和发送错误。
+// resendOf 为重新发送的原记录 ID，首次发送为 0
+func SendRecorded(ctx context.Context, channel string, ruleID uint, ev notification.Event, resendOf uint) (*model.NotificationLog, error) {
+	entry := &model.NotificationLog{
+		RuleID:    ruleID,
+		MonitorID: ev.Status.MonitorID,
+		Channel:   channel,
+		Kind:      string(ev.Kind),
+		Recipient: notification.Recipient(channel, ev.Config),
+		Subject:   ev.Subject,
+		Summary:   ev.Summary(),
+		ResendOf:  resendOf,
+	}
+	if entry.Channel == "" {
+		entry.Channel = notification.DefaultProvider
+	}
+	if ev.Test {
+		entry.Kind = string(notification.EventTest)
+	}
+	if payload, err := json.Marshal(ev); err == nil {
+		entry.Payload = string(payload)
+	}
+
+	p, err := notification.Lookup(channel)
+	if err == nil {
+		err = p.Send(ctx, ev)
+	}
+	entry.Success = err == nil
+	recordNotificationMetrics(err)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	db.RecordNotificationLog(entry)
+	return entry, err
+}
+
+// ResendNotification 重新发送一条失败的发送记录：使用原内容和规则当前的渠道配置、模板，结果写入新的记录。
+// 测试消息、规则已删除或规则已改用其他渠道时不能重新发送
+func ResendNotification(ctx context.Context, id uint) (*model.NotificationLog, error) {
+	var entry model.NotificationLog
+	if err := db.DB.First(&entry, id).Error; err != nil {
+		return nil, fmt.Errorf("发送记录 %d 不存在", id)
+	}
+	if entry.Success {
+		return nil, errors.New("只能重新发送失败的记录")
+	}
+	if entry.Kind == string(notification.EventTest) || entry.RuleID == 0 {
+		return nil, errors.New("测试消息不能重新发送")
+	}
+
+	var rule model.Notification
+	if err := db.DB.First(&rule, entry.RuleID).Error; err != nil {
+		return nil, fmt.Errorf("通知规则 %d 已删除", entry.RuleID)
+	}
+	var cfg struct {
+		Channel string `json:"channel"`
+	}
+	json.Unmarshal([]byte(rule.Config), &cfg)
+	if cfg.Channel == "" {
+		cfg.Channel = notification.DefaultProvider
+	}
+	if cfg.Channel != entry.Channel {
+		return nil, fmt.Errorf("通知规则已改用 %s 渠道，不能按 %s 重新发送", cfg.Channel, entry.Channel)
+	}
+
+	var ev notification.Event
+	if err := json.Unmarshal([]byte(entry.Payload), &ev); err != nil {
+		return nil, fmt.Errorf("发送记录 %d 的内容无法解析: %w", id, err)
+	}
+	ev.Config = json.RawMessage(rule.Config)
+	// 早期的发送记录中没有站点名称
+	if ev.Status.SiteName == "" {
+		ev.Status.SiteName = db.SiteName()
+	}
+	if ev.Report.SiteName == "" {
+		ev.Report.SiteName = db.SiteName()
+	}
+	switch ev.Kind {
+	case notification.EventStatusChange:
+		ev.Template = db.NotificationTemplate(notification.TemplateStatusChange, rule.ID)
+	case notification.EventDailyReport:
+		ev.Template = db.NotificationTemplate(notification.TemplateDailyReport, rule.ID)
+	}
+	return SendRecorded(ctx, entry.Channel, rule.ID, ev, entry.ID)
+}
@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"ping-go/db"
+	"ping-go/model"
+	"testing"
+)
+
+func TestSendRecorded(t *testing.T) {
+	useFakeProvider(t)
+	before := ReadCounters()
+
+	entry, err := SendRecorded(context.Background(), "fake", 3, statusEvent("api"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-fake.sent:
+		if ev.Status.Name != "api" {
+			t.Fatalf("provider received %+v", ev.Status)
+		}
+	default:
+		t.Fatal("provider was not called")
+	}
+	if !entry.Success || entry.Channel != "fake" || entry.Recipient != "fake-recipient" || entry.RuleID != 3 || entry.MonitorID != 7 {
+		t.Fatalf("log entry = %+v", entry)
+	}
+
+	fake.setErr(errors.New("upstream unavailable"))
+	entry, err = SendRecorded(context.Background(), "fake", 3, statusEvent("api"), 0)
+	if err == nil || entry.Success || entry.Error != "upstream unavailable" {
+		t.Fatalf("failed send: err = %v, entry = %+v", err, entry)
+	}
+
+	if _, err := SendRecorded(context.Background(), "no-such-channel", 3, statusEvent("api"), 0); err == nil {
+		t.Fatal("unknown channel succeeded")
+	}
+
+	var logs []model.NotificationLog
+	db.DB.Order("id").Find(&logs)
+	if len(logs) != 3 || !logs[0].Success || logs[1].Success || logs[2].Success {
+		t.Fatalf("recorded %+v, want one success and two failures", logs)
+	}
+	after := ReadCounters()
+	if after.NotificationsSent-before.NotificationsSent != 1 || after.NotificationsFailed-before.NotificationsFailed != 2 {
+		t.Fatalf("counters %+v -> %+v, want +1 sent and +2 failed", before, after)
+	}
+}
+
+func TestResendNotification(t *testing.T) {
+	useFakeProvider(t)
+	rule := model.Notification{Name: "ops", Config: `{"channel":"fake"}`, Active: true}
+	db.DB.Create(&rule)
+
+	fake.setErr(errors.New("upstream unavailable"))
+	failed, _ := SendRecorded(context.Background(), "fake", rule.ID, statusEvent("api"), 0)
+
+	fake.setErr(nil)
+	entry, err := ResendNotification(context.Background(), failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Success || entry.ResendOf != failed.ID {
+		t.Fatalf("resend entry = %+v", entry)
+	}
+	if ev := <-fake.sent; ev.Status.Name != "api" || string(ev.Config) != rule.Config {
+		t.Fatalf("resent event = %+v (config %s)", ev.Status, ev.Config)
+	}
+	if _, err := ResendNotification(context.Background(), entry.ID); err == nil {
+		t.Fatal("resending a successful entry succeeded")
+	}
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// stateFlushInterval 通知状态定期写入数据库的间隔；状态变化、提醒和抖动结束时立即写入
+const stateFlushInterval = 30 * time.Second
+
+// notificationStateKey 通知状态的 key：规则 ID + 监控项 ID
+func notificationStateKey(ruleID, monitorID uint) string {
+	return fmt.Sprintf("%d_%d", ruleID, monitorID)
+}
+
+// loadNotificationStates 从数据库恢复通知状态（启动时和重新当选领导者时），覆盖内存中的状态。
+// 限流和抖动检测的时间窗口（SentTimes、Transitions）不保存，重启后重新统计
+func (s *Service) loadNotificationStates() {
+	if db.DB == nil {
+		return
+	}
+	rows, err := db.LoadNotificationStates()
+	if err != nil {
+		logger.Error("Failed to load notification states", zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range rows {
+		s.notificationStates[notificationStateKey(r.RuleID, r.MonitorID)] = &NotificationState{
+			ConsecutiveFailures:  r.ConsecutiveFailures,
+			ConsecutiveSuccesses: r.ConsecutiveSuccesses,
+			LastSentStatus:       r.LastSentStatus,
+			FirstFailureAt:       r.FirstFailureAt,
+			DownSince:            r.DownSince,
+			LastSentAt:           r.LastSentAt,
+			ChecksSinceSent:      r.ChecksSinceSent,
+			DownNotified:         r.DownNotified,
+			DownNotifiedAt:       r.DownNotifiedAt,
+			Escalated:            r.Escalated,
+			LastTransitionAt:     r.LastTransitionAt,
+			Flapping:             r.Flapping,
+			Suppressed:           r.Suppressed,
+		}
+	}
+	if len(rows) > 0 {
+		logger.Info("Restored notification states", zap.Int("count", len(rows)))
+	}
+}
+
+// persistNotificationStates 将有变化的通知状态写入数据库，写入失败时保留标记等待下一次
+func (s *Service) persistNotificationStates() {
+	if db.DB == nil {
+		return
+	}
+	s.mu.Lock()
+	rows := make([]model.NotificationState, 0, len(s.dirtyStates))
+	for key := range s.dirtyStates {
+		st, ok := s.notificationStates[key]
+		if !ok {
+			delete(s.dirtyStates, key)
+			continue
+		}
+		var ruleID, monitorID uint
+		if _, err := fmt.Sscanf(key, "%d_%d", &ruleID, &monitorID); err != nil {
+			delete(s.dirtyStates, key)
+			continue
+		}
+		rows = append(rows, model.NotificationState{
+			RuleID:               ruleID,
+			MonitorID:            monitorID,
+			ConsecutiveFailures:  st.ConsecutiveFailures,
+			ConsecutiveSuccesses: st.ConsecutiveSuccesses,
+			LastSentStatus:       st.LastSentStatus,
+			FirstFailureAt:       st.FirstFailureAt,
+			DownSince:            st.DownSince,
+			LastSentAt:           st.LastSentAt,
+			ChecksSinceSent:      st.ChecksSinceSent,
+			DownNotified:         st.DownNotified,
+			DownNotifiedAt:       st.DownNotifiedAt,
+			Escalated:            st.Escalated,
+			LastTransitionAt:     st.LastTransitionAt,
+			Flapping:             st.Flapping,
+			Suppressed:           st.Suppressed,
+		})
+	}
+	s.dirtyStates = make(map[string]bool)
+	s.mu.Unlock()
+
+	if err := db.SaveNotificationStates(rows); err != nil {
+		logger.Error("Failed to persist notification states", zap.Int("count", len(rows)), zap.Error(err))
+		s.mu.Lock()
+		for _, r := range rows {
+			s.dirtyStates[notificationStateKey(r.RuleID, r.MonitorID)] = true
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"ping-go/model"
+	"strings"
+	"time"
+)
+
+const (
+	ntpPacketSize = 48
+	// ntpEpochOffset NTP 时间从 1900-01-01 开始计算，与 Unix 时间相差的秒数
+	ntpEpochOffset = 2208988800
+)
+
+// NTPResult holds the measurements of a single NTP query
+type NTPResult struct {
+	Stratum int
+	Offset  time.Duration // local clock offset relative to the server
+	RTT     time.Duration // round-trip delay excluding server processing time
+}
+
+// OffsetMs returns the clock offset in milliseconds
+func (r *NTPResult) OffsetMs() float64 {
+	return float64(r.Offset.Microseconds()) / 1000.0
+}
+
+// QueryNTP sends a single SNTP (v4, client mode) request and returns the measured stratum, offset and RTT.
+func QueryNTP(addr string, timeoutSec int) (*NTPResult, error) {
+	timeout := time.Duration(timeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "123")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dialer := net.Dialer{Resolver: getCustomResolver()}
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = 0x23 // LI = 0, VN = 4, Mode = 3 (client)
+	t1 := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTPTime(t1))
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	resp := make([]byte, ntpPacketSize)
+	n, err := conn.Read(resp)
+	t4 := time.Now()
+	if err != nil {
+		return nil, err
+	}
+	if n < ntpPacketSize {
+		return nil, fmt.Errorf("short NTP response (%d bytes)", n)
+	}
+
+	// 校验响应：必须是服务器模式，且 originate 时间戳与请求的 transmit 时间戳一致
+	if mode := resp[0] & 0x07; mode != 4 {
+		return nil, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	if binary.BigEndian.Uint64(resp[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		return nil, errors.New("NTP response does not match request")
+	}
+
+	stratum := int(resp[1])
+	if stratum == 0 {
+		return nil, fmt.Errorf("kiss-o'-death %q", strings.TrimRight(string(resp[12:16]), "\x00"))
+	}
+	if li := resp[0] >> 6; li == 3 {
+		return nil, errors.New("server clock not synchronized")
+	}
+
+	t2 := fromNTPTime(binary.BigEndian.Uint64(resp[32:]))
+	t3 := fromNTPTime(binary.BigEndian.Uint64(resp[40:]))
+
+	rtt := t4.Sub(t1) - t3.Sub(t2)
+	if rtt < 0 {
+		rtt = 0
+	}
+
+	return &NTPResult{
+		Stratum: stratum,
+		Offset:  (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		RTT:     rtt,
+	}, nil
+}
+
+// CheckNTP queries the NTP server and marks it DOWN when it does not answer
+// or when the absolute clock offset exceeds maxOffsetMs (0 disables the threshold).
+func CheckNTP(addr string, timeoutSec int, maxOffsetMs int) (int, string, time.Duration) {
+	result, err := QueryNTP(addr, timeoutSec)
+	status, msg := EvaluateNTP(result, err, maxOffsetMs)
+	if result == nil {
+		return status, msg, 0
+	}
+	return status, msg, result.RTT
+}
+
+// EvaluateNTP converts the outcome of QueryNTP into a monitor status and heartbeat message.
+func EvaluateNTP(result *NTPResult, err error, maxOffsetMs int) (int, string) {
+	if err != nil {
+		errStr := err.Error()
+		if strings.Contains(errStr, "i/o timeout") || strings.Contains(errStr, "deadline exceeded") {
+			return model.StatusDown, "Timeout"
+		}
+		if strings.Contains(errStr, "no such host") {
+			return model.StatusDown, "DNS Resolution Failed"
+		}
+		if strings.Contains(errStr, "connection refused") {
+			return model.StatusDown, "Port Closed"
+		}
+		if len(errStr) > 40 {
+			return model.StatusDown, errStr[:37] + "..."
+		}
+		return model.StatusDown, errStr
+	}
+
+	msg := fmt.Sprintf("offset %.1fms, stratum %d", result.OffsetMs(), result.Stratum)
+	if maxOffsetMs > 0 && math.Abs(result.OffsetMs()) > float64(maxOffsetMs) {
+		return model.StatusDown, fmt.Sprintf("%s (exceeds %dms)", msg, maxOffsetMs)
+	}
+	return model.StatusUp, msg
+}
+
+func toNTPTime(t time.Time) uint64 {
+	sec := uint64(t.Unix()) + ntpEpochOffset
+	frac := uint64(t.Nanosecond()) << 32 / 1e9
+	return sec<<32 | frac
+}
+
+func fromNTPTime(v uint64) time.Time {
+	sec := int64(v>>32) - ntpEpochOffset
+	nsec := int64((v & 0xffffffff) * 1e9 >> 32)
+	return time.Unix(sec, nsec)
+}
@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"ping-go/model"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// oauthRefreshMargin 在 token 过期前提前刷新的时间
+	oauthRefreshMargin = 30 * time.Second
+	// oauthDefaultLifetime 令牌端点未返回 expires_in 时的缓存时间
+	oauthDefaultLifetime = 5 * time.Minute
+)
+
+// oauthToken 缓存的访问令牌，fingerprint 用于在配置变化时丢弃旧令牌
+type oauthToken struct {
+	accessToken string
+	expiresAt   time.Time
+	fingerprint string
+}
+
+var (
+	oauthMu     sync.Mutex
+	oauthTokens = make(map[uint]*oauthToken)
+)
+
+// hasOAuth 监控项是否配置了 OAuth2 client credentials
+func hasOAuth(m model.Monitor) bool {
+	return m.OAuthTokenURL != ""
+}
+
+// InvalidateOAuthToken 丢弃监控项缓存的访问令牌（编辑或停止监控时调用）
+func InvalidateOAuthToken(id uint) {
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	delete(oauthTokens, id)
+}
+
+// getOAuthToken 返回监控项的访问令牌，缓存有效时直接使用，否则向令牌端点重新申请。
+// ID 为 0（测试未保存的监控项）时不缓存
+func getOAuthToken(ctx context.Context, m model.Monitor) (string, error) {
+	fp := oauthFingerprint(m)
+	if m.ID != 0 {
+		oauthMu.Lock()
+		t, ok := oauthTokens[m.ID]
+		oauthMu.Unlock()
+		if ok && t.fingerprint == fp && time.Now().Before(t.expiresAt) {
+			return t.accessToken, nil
+		}
+	}
+
+	token, lifetime, err := fetchOAuthToken(ctx, m)
+	if err != nil {
+		return "", err
+	}
+	if m.ID != 0 {
+		expiresAt := time.Now().Add(lifetime)
+		if lifetime > 2*oauthRefreshMargin {
+			expiresAt = expiresAt.Add(-oauthRefreshMargin)
+		}
+		oauthMu.Lock()
+		oauthTokens[m.ID] = &oauthToken{accessToken: token, expiresAt: expiresAt, fingerprint: fp}
+		oauthMu.Unlock()
+	}
+	return token, nil
+}
+
+// fetchOAuthToken 使用 client_credentials 授权向令牌端点申请访问令牌。
+// 先以 HTTP Basic 方式提交客户端凭据，被拒绝（401）时改为在表单中提交
+func fetchOAuthToken(ctx context.Context, m model.Monitor) (string, time.Duration, error) {
+	token, lifetime, status, err := requestOAuthToken(ctx, m, true)
+	if status == http.StatusUnauthorized {
+		token, lifetime, _, err = requestOAuthToken(ctx, m, false)
+	}
+	return token, lifetime, err
+}
+
+func requestOAuthToken(ctx context.Context, m model.Monitor, basicAuth bool) (string, time.Duration, int, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if scopes := strings.Join(strings.FieldsFunc(m.OAuthScopes, func(r rune) bool { return r == ',' || r == ' ' }), " "); scopes != "" {
+		form.Set("scope", scopes)
+	}
+	if !basicAuth {
+		form.Set("client_id", m.OAuthClientID)
+		form.Set("client_secret", m.OAuthClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.OAuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "PingGo-Monitor/1.0")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(m.OAuthClientID), url.QueryEscape(m.OAuthClientSecret))
+	}
+
+	resp, err := getHTTPClient(true, m.IPVersion).Do(req)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer drainAndClose(resp.Body)
+	body, _ := readBody(decodedBody(resp), 64*1024)
+
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &result)
+
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != "" {
+			return "", 0, resp.StatusCode, fmt.Errorf("HTTP %d %s", resp.StatusCode, result.Error)
+		}
+		return "", 0, resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if result.AccessToken == "" {
+		return "", 0, resp.StatusCode, errors.New("no access_token in response")
+	}
+
+	lifetime := oauthDefaultLifetime
+	if result.ExpiresIn > 0 {
+		lifetime = time.Duration(result.ExpiresIn) * time.Second
+	}
+	return result.AccessToken, lifetime, resp.StatusCode, nil
+}
+
+// oauthFingerprint 对令牌相关配置取摘要，配置变化后旧令牌不再使用
+func oauthFingerprint(m model.Monitor) string {
+	sum := sha256.Sum256([]byte(m.OAuthTokenURL + "\x00" + m.OAuthClientID + "\x00" + m.OAuthClientSecret + "\x00" + m.OAuthScopes))
+	return hex.EncodeToString(sum[:8])
+}
+
+// applyOAuth 申请（或复用缓存的）访问令牌并设置 Authorization: Bearer，优先于 Basic Auth
+func applyOAuth(ctx context.Context, req *http.Request, m model.Monitor) error {
+	if !hasOAuth(m) {
+		return nil
+	}
+	token, err := getOAuthToken(ctx, m)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
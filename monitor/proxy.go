@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// proxyMask 替换代理 URL 中密码的占位符
+const proxyMask = "******"
+
+var errTCPProxyScheme = errors.New("TCP checks require a socks5:// proxy")
+
+// ParseProxyURL 解析并校验监控项的代理地址，支持 http://、https:// 和 socks5://，必须包含端口
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, errors.New("invalid proxy URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, errors.New("proxy URL must start with http://, https:// or socks5://")
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, errors.New("proxy URL must include host and port")
+	}
+	return u, nil
+}
+
+// MaskProxyURL 将代理 URL 中的密码替换为 ******，用于展示和日志
+func MaskProxyURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return proxyMask
+	}
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	// url.UserPassword 会把 * 转义为 %2A，这里手动拼接
+	user := url.User(u.User.Username()).String()
+	u.User = nil
+	return strings.Replace(u.String(), "://", "://"+user+":"+proxyMask+"@", 1)
+}
+
+// StripProxyPassword 去掉代理 URL 中的密码（导出配置时使用）
+func StripProxyPassword(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.User(u.User.Username())
+	}
+	return u.String()
+}
+
+// RestoreProxyPassword 提交的代理 URL 密码仍为 ****** 占位符时（编辑时前端拿到的是脱敏地址），
+// 使用已保存地址中的密码
+func RestoreProxyPassword(raw, saved string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if pass, ok := u.User.Password(); !ok || pass != proxyMask {
+		return raw
+	}
+	s, err := url.Parse(saved)
+	if err != nil || s.User == nil {
+		return raw
+	}
+	savedPass, _ := s.User.Password()
+	u.User = url.UserPassword(u.User.Username(), savedPass)
+	return u.String()
+}
+
+// proxySecret 返回代理 URL 中的密码，用于从错误消息中脱敏
+func proxySecret(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	pass, _ := u.User.Password()
+	return pass
+}
+
+// socksDialContext 返回经由 SOCKS5 代理建立连接的 DialContext，目标域名由代理端解析
+func socksDialContext(u *url.URL, forward *net.Dialer) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	d, err := proxy.FromURL(u, forward)
+	if err != nil {
+		return nil, err
+	}
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("proxy dialer does not support context")
+	}
+	return cd.DialContext, nil
+}
+
+// applyProxy 为监控项独立的 Transport 设置代理
+func applyProxy(t *http.Transport, u *url.URL) error {
+	if u.Scheme == "socks5" {
+		dial, err := socksDialContext(u, &net.Dialer{KeepAlive: 30 * time.Second, Resolver: getCustomResolver()})
+		if err != nil {
+			return err
+		}
+		t.DialContext = dial
+		return nil
+	}
+	t.Proxy = http.ProxyURL(u)
+	return nil
+}
+
+// socksTargetReplies SOCKS5 代理回复的目标连接失败原因，属于目标本身的错误而不是代理错误
+var socksTargetReplies = []string{"connection refused", "host unreachable", "network unreachable", "TTL expired"}
+
+// proxyErrorMessage 将连接代理阶段的错误归类为 "Proxy Error"，避免显示成目标的 DNS 或超时错误
+func proxyErrorMessage(err error) (string, bool) {
+	// HTTP 代理对 CONNECT 返回非 200 时，Transport 只返回状态文本（如 "Bad Gateway"）
+	var uerr *url.Error
+	if errors.As(err, &uerr) && isHTTPStatusText(uerr.Err.Error()) {
+		return "Proxy Error (CONNECT " + uerr.Err.Error() + ")", true
+	}
+
+	errStr := err.Error()
+	if !strings.Contains(errStr, "proxyconnect") && !strings.Contains(errStr, "socks connect") {
+		return "", false
+	}
+	if strings.Contains(errStr, "socks connect") {
+		for _, reply := range socksTargetReplies {
+			if strings.HasSuffix(errStr, "unknown error "+reply) {
+				return "", false
+			}
+		}
+	}
+	var detail string
+	switch {
+	case strings.Contains(errStr, "connection refused"):
+		detail = "connection refused"
+	case strings.Contains(errStr, "deadline exceeded") || strings.Contains(errStr, "i/o timeout"):
+		detail = "timeout"
+	case isResolutionError(errStr):
+		detail = "proxy host not found"
+	default:
+		detail = errStr[strings.LastIndex(errStr, ": ")+1:]
+		detail = strings.TrimSpace(detail)
+		if len(detail) > 40 {
+			detail = detail[:37] + "..."
+		}
+	}
+	return "Proxy Error (" + detail + ")", true
+}
+
+func isHTTPStatusText(s string) bool {
+	for code := 400; code < 600; code++ {
+		if text := http.StatusText(code); text != "" && text == s {
+			return true
+		}
+	}
+	return false
+}
+
+// dialTCPViaProxy 通过 SOCKS5 代理连接 TCP 目标
+func dialTCPViaProxy(proxyURL, addr string, timeout time.Duration) (net.Conn, error) {
+	u, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" {
+		return nil, errTCPProxyScheme
+	}
+	dial, err := socksDialContext(u, &net.Dialer{Resolver: getCustomResolver()})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return dial(ctx, "tcp", addr)
+}
@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"time"
+)
+
+// pushGracePeriod 判断 push 超时时在监控间隔之外额外允许的延迟，避免客户端定时误差导致误报
+const pushGracePeriod = 10 * time.Second
+
+// evaluatePush 判断 push 监控在一个监控间隔内是否收到过上报。
+// ok 为 false 表示仍在等待窗口内，本轮无需记录心跳（心跳由上报接口写入）。
+func evaluatePush(m model.Monitor) (status int, msg string, ok bool) {
+	last := m.LastPush
+	if last.IsZero() {
+		last = m.CreatedAt
+	}
+	window := time.Duration(m.Interval)*time.Second + pushGracePeriod
+	if time.Since(last) <= window {
+		return 0, "", false
+	}
+	if m.LastPush.IsZero() {
+		return model.StatusDown, "No push received yet", true
+	}
+	return model.StatusDown, fmt.Sprintf("No push received for %s", time.Since(last).Round(time.Second)), true
+}
+
+// RecordPush 记录一次来自 /api/push/:token 的上报，走与主动检查相同的心跳与通知流程
+// 维护期间只更新最后上报时间
+func (s *Service) RecordPush(m model.Monitor, status int, msg string, duration int) {
+	m.LastPush = time.Now()
+	db.DB.Model(&m).Update("last_push", m.LastPush)
+	if s.checkMaintenance(m) {
+		return
+	}
+	s.emitCheckEvent(m, status, msg, duration, "")
+	s.recordResult(m, status, msg, duration)
+}
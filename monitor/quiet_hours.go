@@ -0,0 +1,234 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+	"ping-go/pkg/i18n"
+	"ping-go/pkg/logger"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxDigestEvents 静默时段汇总中最多列出的事件数，超出的只计数
+const maxDigestEvents = 50
+
+// QuietHours 触发规则的静默时段（规则配置中的 quiet_hours）：时段内不发送通知，但通知状态照常更新。
+// Start 晚于 End 时跨越午夜（如 22:00-07:00），Days 指时段开始的那一天
+type QuietHours struct {
+	Start    string `json:"start"`    // "HH:MM"
+	End      string `json:"end"`      // "HH:MM"
+	Timezone string `json:"timezone"` // IANA 时区，为空时使用显示时区
+	Days     []int  `json:"days"`     // 0=周日 … 6=周六，为空表示每天
+	Digest   bool   `json:"digest"`   // 时段结束后发送一条被抑制通知的汇总
+}
+
+// parseClock 解析 "HH:MM"，返回距午夜的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("时间 %q 必须是 HH:MM 格式", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate 校验静默时段配置，保存规则时调用
+func (q *QuietHours) Validate() error {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("静默时段的开始和结束时间不能相同")
+	}
+	if q.Timezone != "" {
+		if _, err := time.LoadLocation(q.Timezone); err != nil {
+			return fmt.Errorf("无效的时区 %q", q.Timezone)
+		}
+	}
+	for _, d := range q.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("days 必须是 0（周日）到 6（周六）之间的整数")
+		}
+	}
+	return nil
+}
+
+// location 静默时段使用的时区
+func (q *QuietHours) location() *time.Location {
+	if q.Timezone != "" {
+		if loc, err := time.LoadLocation(q.Timezone); err == nil {
+			return loc
+		}
+	}
+	return db.Location()
+}
+
+// onDay 时段是否在该星期几开始
+func (q *QuietHours) onDay(d time.Weekday) bool {
+	if len(q.Days) == 0 {
+		return true
+	}
+	for _, day := range q.Days {
+		if time.Weekday(day) == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Active 判断 now 是否处于静默时段。nil 或配置无效时返回 false（宁可多发也不漏发）
+func (q *QuietHours) Active(now time.Time) bool {
+	if q == nil {
+		return false
+	}
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(q.End)
+	if err != nil || start == end {
+		return false
+	}
+	now = now.In(q.location())
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end && q.onDay(now.Weekday())
+	}
+	// 跨越午夜：午夜前属于今天开始的时段，午夜后属于前一天开始的时段
+	if minute >= start {
+		return q.onDay(now.Weekday())
+	}
+	return minute < end && q.onDay(now.AddDate(0, 0, -1).Weekday())
+}
+
+// suppressedEvent 静默时段内被抑制的一条状态通知
+type suppressedEvent struct {
+	Name     string
+	Status   int
+	At       time.Time
+	Duration time.Duration
+}
+
+// quietDigest 一条规则在静默时段内累积的被抑制通知，时段结束后由 flushQuietDigests 发送
+type quietDigest struct {
+	events  []suppressedEvent
+	dropped int
+}
+
+// recordSuppressed 记录被静默时段抑制的状态通知；规则未开启 digest 时不记录
+func (s *Service) recordSuppressed(rule triggerConfig, result *CheckResult, status int, duration time.Duration) {
+	logger.Info("Notification suppressed by quiet hours",
+		zap.Uint("rule", rule.RuleID), zap.String("monitor", result.Name), zap.String("status", statusToString(status)))
+	if rule.QuietHours == nil || !rule.QuietHours.Digest {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.quietDigests[rule.RuleID]
+	if !ok {
+		d = &quietDigest{}
+		s.quietDigests[rule.RuleID] = d
+	}
+	if len(d.events) >= maxDigestEvents {
+		d.dropped++
+		return
+	}
+	d.events = append(d.events, suppressedEvent{Name: result.Name, Status: status, At: time.Now(), Duration: duration})
+}
+
+// flushQuietDigests 静默时段结束后发送累积的汇总，由定时 worker 每分钟调用。
+// 规则已删除、停用或取消 digest 时丢弃汇总
+func (s *Service) flushQuietDigests(now time.Time) {
+	s.mu.Lock()
+	ids := make([]uint, 0, len(s.quietDigests))
+	for id := range s.quietDigests {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		var rule model.Notification
+		var cfg triggerConfig
+		if err := db.DB.First(&rule, id).Error; err != nil || !rule.Active || rule.Type != "trigger" ||
+			json.Unmarshal([]byte(rule.Config), &cfg) != nil || cfg.QuietHours == nil || !cfg.QuietHours.Digest {
+			s.mu.Lock()
+			delete(s.quietDigests, id)
+			s.mu.Unlock()
+			continue
+		}
+		if cfg.QuietHours.Active(now) {
+			continue
+		}
+
+		s.mu.Lock()
+		d := s.quietDigests[id]
+		delete(s.quietDigests, id)
+		s.mu.Unlock()
+		// PagerDuty、Opsgenie 按监控项打开和关闭事件，汇总无法对应到事件，不发送
+		if d == nil || len(d.events) == 0 || notification.ResolvesOnRecovery(cfg.Channel) {
+			continue
+		}
+		cfg.RuleID = id
+		cfg.Config = json.RawMessage(rule.Config)
+		s.sendQuietDigest(cfg, d)
+	}
+}
+
+// sendQuietDigest 发送静默时段汇总：每个事件一行，状态为各监控项最后一次被抑制的状态。
+// 汇总只包含名称、状态和时间，不含检查消息和地址
+func (s *Service) sendQuietDigest(rule triggerConfig, d *quietDigest) {
+	loc := rule.QuietHours.location()
+	lang := ruleLanguage(rule.Language)
+	latest := make(map[string]int)
+	var lines []string
+	for _, ev := range d.events {
+		latest[ev.Name] = ev.Status
+		line := fmt.Sprintf("%s %s %s", ev.At.In(loc).Format("01-02 15:04"), ev.Name, statusWord(lang, ev.Status))
+		if ev.Duration > 0 {
+			line += fmt.Sprintf(" (%s)", shortDuration(ev.Duration))
+		}
+		lines = append(lines, line)
+	}
+	if d.dropped > 0 {
+		lines = append(lines, fmt.Sprintf(i18n.T(lang, "notify.digest.more"), d.dropped))
+	}
+
+	status := model.StatusUp
+	for _, st := range latest {
+		if st == model.StatusDown {
+			status = model.StatusDown
+			break
+		}
+	}
+	total := len(d.events) + d.dropped
+	now := time.Now()
+	data := notification.StatusChangeData{
+		Name:       fmt.Sprintf(i18n.T(lang, "notify.digest.name"), total),
+		NewStatus:  statusToString(status),
+		Message:    strings.Join(lines, "\n"),
+		Color:      db.StatusMeta(status).Color,
+		StatusText: i18n.T(lang, "notify.title.digest"),
+		DateTime:   now.Format("2006-01-02 15:04:05"),
+		Lang:       lang,
+		SiteName:   db.SiteName(),
+	}
+	s.deliver(rule.Channel, rule.RuleID, notification.Event{
+		Kind:     notification.EventStatusChange,
+		Subject:  NotifySubject(lang, "notify.subject.digest", total),
+		Status:   data,
+		Link:     config.Get().DashboardURL(),
+		Time:     now.In(db.Location()),
+		Config:   rule.Config,
+		Template: db.NotificationTemplate(notification.TemplateStatusChange, rule.RuleID),
+	}, "quiet hours digest")
+}
@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"ping-go/model"
+	"regexp"
+)
+
+// hasRedirectAssertion 监控项是否配置了重定向断言
+func hasRedirectAssertion(m model.Monitor) bool {
+	return m.ExpectedRedirectStatus > 0 || m.ExpectedFinalURL != ""
+}
+
+// checkRedirect 校验重定向断言。
+// 未跟随重定向时：响应必须是 3xx（或 ExpectedRedirectStatus 指定的状态码），Location 需匹配 ExpectedFinalURL；
+// 跟随重定向时：重定向链第一跳的状态码需等于 ExpectedRedirectStatus，最终请求的 URL 需匹配 ExpectedFinalURL。
+func checkRedirect(m model.Monitor, resp *http.Response) (bool, string) {
+	if !hasRedirectAssertion(m) {
+		return true, ""
+	}
+
+	if !m.FollowRedirects {
+		location := resp.Header.Get("Location")
+		if loc, err := resp.Location(); err == nil {
+			location = loc.String() // 相对地址按请求 URL 解析为绝对地址
+		}
+		if m.ExpectedRedirectStatus > 0 && resp.StatusCode != m.ExpectedRedirectStatus {
+			return false, fmt.Sprintf("Status %d (Expected redirect %d), Location: %s", resp.StatusCode, m.ExpectedRedirectStatus, orNone(location))
+		}
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+			return false, fmt.Sprintf("Status %d (Expected redirect)", resp.StatusCode)
+		}
+		if m.ExpectedFinalURL != "" && !matchURL(m.ExpectedFinalURL, location) {
+			return false, fmt.Sprintf("Location %s (Expected %s)", orNone(location), m.ExpectedFinalURL)
+		}
+		return true, ""
+	}
+
+	finalURL := resp.Request.URL.String()
+	if m.ExpectedRedirectStatus > 0 {
+		// resp.Request.Response 是触发本次请求的重定向响应，沿链回溯到第一跳
+		var first *http.Response
+		for r := resp.Request; r != nil && r.Response != nil; r = r.Response.Request {
+			first = r.Response
+		}
+		if first == nil {
+			return false, fmt.Sprintf("No redirect (Expected %d), final URL: %s", m.ExpectedRedirectStatus, finalURL)
+		}
+		if first.StatusCode != m.ExpectedRedirectStatus {
+			return false, fmt.Sprintf("Redirect %d (Expected %d), final URL: %s", first.StatusCode, m.ExpectedRedirectStatus, finalURL)
+		}
+	}
+	if m.ExpectedFinalURL != "" && !matchURL(m.ExpectedFinalURL, finalURL) {
+		return false, fmt.Sprintf("Final URL %s (Expected %s)", finalURL, m.ExpectedFinalURL)
+	}
+	return true, ""
+}
+
+// matchURL 期望值与实际 URL 完全相同，或作为正则表达式匹配
+func matchURL(expected, actual string) bool {
+	if expected == actual {
+		return true
+	}
+	re, err := regexp.Compile(expected)
+	return err == nil && re.MatchString(actual)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
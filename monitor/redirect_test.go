@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"ping-go/model"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// redirectChain 返回一个多跳重定向的测试服务器：/a 301 到 /b，/b 302 到 /c，/c 返回 200；
+// /loop/n 无限重定向到 /loop/n+1
+func redirectChain(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/b", http.StatusMovedPermanently) })
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/c", http.StatusFound) })
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "landed") })
+	mux.HandleFunc("/loop/", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/loop/"))
+		http.Redirect(w, r, "/loop/"+strconv.Itoa(n+1), http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestCheckHTTPRedirectChain(t *testing.T) {
+	ts := redirectChain(t)
+
+	tests := []struct {
+		name    string
+		m       model.Monitor
+		want    int
+		wantMsg string
+	}{
+		{
+			name: "follow chain to final URL",
+			m:    model.Monitor{URL: ts.URL + "/a", FollowRedirects: true, ExpectedRedirectStatus: 301, ExpectedFinalURL: ts.URL + "/c"},
+			want: model.StatusUp,
+		},
+		{
+			name: "final URL as regex",
+			m:    model.Monitor{URL: ts.URL + "/a", FollowRedirects: true, ExpectedFinalURL: `/c$`},
+			want: model.StatusUp,
+		},
+		{
+			name:    "final URL mismatch shows the observed URL",
+			m:       model.Monitor{URL: ts.URL + "/a", FollowRedirects: true, ExpectedFinalURL: ts.URL + "/b"},
+			want:    model.StatusDown,
+			wantMsg: "Final URL " + ts.URL + "/c",
+		},
+		{
+			name:    "first hop status is asserted, not the last",
+			m:       model.Monitor{URL: ts.URL + "/a", FollowRedirects: true, ExpectedRedirectStatus: 302},
+			want:    model.StatusDown,
+			wantMsg: "Redirect 301 (Expected 302)",
+		},
+		{
+			name:    "expected redirect but none happened",
+			m:       model.Monitor{URL: ts.URL + "/c", FollowRedirects: true, ExpectedRedirectStatus: 301},
+			want:    model.StatusDown,
+			wantMsg: "No redirect (Expected 301)",
+		},
+		{
+			name: "not following asserts the first hop and its Location",
+			m:    model.Monitor{URL: ts.URL + "/a", ExpectedRedirectStatus: 301, ExpectedFinalURL: ts.URL + "/b"},
+			want: model.StatusUp,
+		},
+		{
+			name:    "not following with wrong Location",
+			m:       model.Monitor{URL: ts.URL + "/a", ExpectedFinalURL: ts.URL + "/c"},
+			want:    model.StatusDown,
+			wantMsg: "Location " + ts.URL + "/b (Expected " + ts.URL + "/c)",
+		},
+		{
+			name:    "max redirects",
+			m:       model.Monitor{URL: ts.URL + "/loop/0", FollowRedirects: true, ExpectedFinalURL: `/loop/`},
+			want:    model.StatusDown,
+			wantMsg: "stopped after 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.m.Type = model.MonitorTypeHTTP
+			tt.m.Timeout = 5
+			status, msg := CheckHTTP(tt.m)
+			if status != tt.want {
+				t.Fatalf("status = %d (%s), want %d", status, msg, tt.want)
+			}
+			if tt.wantMsg != "" && !strings.Contains(msg, tt.wantMsg) {
+				t.Fatalf("msg = %q, want it to contain %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestCheckHTTPCrossHostRedirect(t *testing.T) {
+	landing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") }))
+	defer landing.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, landing.URL+"/home", http.StatusTemporaryRedirect)
+	}))
+	defer origin.Close()
+
+	m := model.Monitor{Type: model.MonitorTypeHTTP, Timeout: 5, URL: origin.URL + "/start", FollowRedirects: true,
+		ExpectedRedirectStatus: http.StatusTemporaryRedirect, ExpectedFinalURL: landing.URL + "/home"}
+	if status, msg := CheckHTTP(m); status != model.StatusUp {
+		t.Fatalf("follow across hosts: status %d (%s)", status, msg)
+	}
+
+	m.FollowRedirects = false
+	if status, msg := CheckHTTP(m); status != model.StatusUp {
+		t.Fatalf("cross-host Location: status %d (%s)", status, msg)
+	}
+
+	m.ExpectedFinalURL = origin.URL + "/home"
+	status, msg := CheckHTTP(m)
+	if status != model.StatusDown || !strings.Contains(msg, landing.URL+"/home") {
+		t.Fatalf("Location on the wrong host: status %d (%s), want DOWN showing the observed Location", status, msg)
+	}
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"regexp/syntax"
+	"sync"
+)
+
+const (
+	// DefaultRegexScanBytes 响应正则默认最多扫描的响应体字节数（解压后），可按监控项设置 regex_scan_bytes
+	DefaultRegexScanBytes = 256 * 1024
+	// MinRegexScanBytes 单个监控项可设置的最小扫描上限
+	MinRegexScanBytes = 1024
+	// regexHeadBytes 未匹配时保留用于检查消息的响应体开头
+	regexHeadBytes = 10240
+	// regexCacheSize 编译结果缓存的正则数量，超过时整体清空
+	regexCacheSize = 512
+)
+
+var (
+	regexMu    sync.Mutex
+	regexCache = make(map[string]*regexp.Regexp)
+)
+
+// compiledRegex 返回编译后的正则，同一表达式只编译一次
+func compiledRegex(pattern string) (*regexp.Regexp, error) {
+	regexMu.Lock()
+	defer regexMu.Unlock()
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(regexCache) >= regexCacheSize {
+		clear(regexCache)
+	}
+	regexCache[pattern] = re
+	return re, nil
+}
+
+// MaxBodyBytes 返回响应体读取上限（monitor.max_body_bytes），也是 regex_scan_bytes 允许的最大值
+func MaxBodyBytes() int64 {
+	return maxBodyBytes()
+}
+
+// regexScanLimit 返回监控项的正则扫描上限，不超过 monitor.max_body_bytes
+func regexScanLimit(scanBytes int64) int64 {
+	limit := int64(DefaultRegexScanBytes)
+	if scanBytes > 0 {
+		limit = scanBytes
+	}
+	return min(limit, maxBodyBytes())
+}
+
+// regexScan 一次正则扫描的结果
+type regexScan struct {
+	Matched   bool
+	Scanned   int64  // 读取并参与匹配的字节数
+	Truncated bool   // 响应体超过扫描上限，上限之后的内容没有参与匹配
+	Head      []byte // 响应体开头（最多 regexHeadBytes），用于未匹配时的检查消息
+}
+
+// regexBufPool 扫描缓冲区复用，避免每次检查都分配
+var regexBufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// 分段读取时第一段的大小，之后每段翻倍，匹配的总开销不超过最终读取长度的两倍
+const regexFirstChunk = 4 * 1024
+
+// scanRegex 分段读取响应体并匹配：第一段 4KB，之后每段翻倍，每读入一段就对已读取的内容匹配一次，
+// 匹配成功立即停止读取，最多读取 limit 字节。匹配对象始终从响应体开头算起，^ 等开头锚点与整体匹配一致；
+// 含 $、\b 等依赖后续内容的断言时，中途的匹配可能是假阳性，只在读完（或达到上限）后匹配一次
+func scanRegex(re *regexp.Regexp, body io.Reader, limit int64) (regexScan, error) {
+	buf := regexBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		// 不缓存超大的缓冲区
+		if buf.Cap() <= DefaultRegexScanBytes*2 {
+			regexBufPool.Put(buf)
+		}
+	}()
+
+	incremental := !dependsOnTextEnd(re)
+	src := io.LimitReader(body, limit)
+	var res regexScan
+	chunk := int64(regexFirstChunk)
+	for {
+		n, err := buf.ReadFrom(io.LimitReader(src, chunk))
+		eof := n < chunk || int64(buf.Len()) >= limit
+		if err != nil {
+			res.Scanned = int64(buf.Len())
+			return res, err
+		}
+		if incremental || eof {
+			if res.Matched = re.Match(buf.Bytes()); res.Matched || eof {
+				break
+			}
+		}
+		chunk *= 2
+	}
+
+	res.Scanned = int64(buf.Len())
+	if !res.Matched {
+		res.Head = bytes.Clone(buf.Bytes()[:min(buf.Len(), regexHeadBytes)])
+		if res.Scanned >= limit {
+			// 上限处恰好结束的响应体不算截断
+			var one [1]byte
+			n, _ := io.ReadFull(body, one[:])
+			res.Truncated = n > 0
+		}
+	}
+	return res, nil
+}
+
+// dependsOnTextEnd 正则是否含有结果取决于后续内容的断言（$、\z、\b、\B），
+// 这类正则在只读取了一部分的响应体上匹配成功不代表在完整响应体上也成功
+func dependsOnTextEnd(re *regexp.Regexp) bool {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return true
+	}
+	var walk func(*syntax.Regexp) bool
+	walk = func(r *syntax.Regexp) bool {
+		switch r.Op {
+		case syntax.OpEndText, syntax.OpEndLine, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+			return true
+		}
+		for _, sub := range r.Sub {
+			if walk(sub) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(parsed)
+}
+
+// matchBytes 对已读取的响应体（表达式校验同时需要时）做同样的匹配，遵守相同的扫描上限
+func matchBytes(re *regexp.Regexp, body []byte, limit int64) regexScan {
+	scanned := body
+	if int64(len(scanned)) > limit {
+		scanned = scanned[:limit]
+	}
+	res := regexScan{Matched: re.Match(scanned), Scanned: int64(len(scanned))}
+	res.Truncated = !res.Matched && int64(len(body)) > limit
+	res.Head = scanned[:min(len(scanned), regexHeadBytes)]
+	return res
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// largeBody 1MB 的响应体，"ready" 出现在 offset 处
+func largeBody(offset int) []byte {
+	body := bytes.Repeat([]byte("x"), 1024*1024)
+	copy(body[offset:], "ready")
+	return body
+}
+
+func TestScanRegex(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		body          string
+		limit         int64
+		wantMatch     bool
+		wantTruncated bool
+		maxScanned    int64
+	}{
+		{"early match stops reading", `ready`, "ready" + strings.Repeat("x", 100_000), 256 * 1024, true, false, regexFirstChunk},
+		{"match past the first chunk", `ready`, strings.Repeat("x", 10_000) + "ready", 256 * 1024, true, false, 16 * 1024},
+		{"no match within the limit", `ready`, strings.Repeat("x", 4096) + "ready", 2048, false, true, 2048},
+		{"body exactly at the limit is not truncated", `ready`, strings.Repeat("x", 2048), 2048, false, false, 2048},
+		{"end anchor waits for the whole body", `x$`, "x" + strings.Repeat("y", 8192) + "x", 256 * 1024, true, false, 8194},
+		{"end anchor does not match a prefix", `^x+$`, strings.Repeat("x", 5000) + "y", 256 * 1024, false, false, 5001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := regexp.MustCompile(tt.pattern)
+			res, err := scanRegex(re, strings.NewReader(tt.body), tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Matched != tt.wantMatch || res.Truncated != tt.wantTruncated {
+				t.Fatalf("matched=%v truncated=%v, want %v/%v", res.Matched, res.Truncated, tt.wantMatch, tt.wantTruncated)
+			}
+			if res.Scanned > tt.maxScanned {
+				t.Fatalf("scanned %d bytes, want at most %d", res.Scanned, tt.maxScanned)
+			}
+		})
+	}
+}
+
+// benchmarkReadAllMatch 优化前的做法：读入完整的响应体（最多 1MB）后再匹配
+func benchmarkReadAllMatch(b *testing.B, body []byte) {
+	re := regexp.MustCompile(`ready`)
+	b.ReportAllocs()
+	for b.Loop() {
+		data, err := readBody(bytes.NewReader(body), defaultMaxBodyBytes)
+		if err != nil {
+			b.Fatal(err)
+		}
+		re.Match(data)
+	}
+}
+
+func benchmarkScanRegex(b *testing.B, body []byte, limit int64) {
+	re := regexp.MustCompile(`ready`)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := scanRegex(re, bytes.NewReader(body), limit); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRegexReadAll1MBEarlyMatch(b *testing.B) { benchmarkReadAllMatch(b, largeBody(100)) }
+func BenchmarkRegexScan1MBEarlyMatch(b *testing.B) {
+	benchmarkScanRegex(b, largeBody(100), DefaultRegexScanBytes)
+}
+
+func BenchmarkRegexReadAll1MBLateMatch(b *testing.B) { benchmarkReadAllMatch(b, largeBody(200*1024)) }
+func BenchmarkRegexScan1MBLateMatch(b *testing.B) {
+	benchmarkScanRegex(b, largeBody(200*1024), DefaultRegexScanBytes)
+}
+
+func BenchmarkRegexReadAll1MBNoMatch(b *testing.B) { benchmarkReadAllMatch(b, largeBody(1024*1024-5)) }
+func BenchmarkRegexScan1MBNoMatch(b *testing.B) {
+	benchmarkScanRegex(b, largeBody(1024*1024-5), DefaultRegexScanBytes)
+}
+
+// 编译缓存：同一表达式重复检查时不再编译
+func BenchmarkCompiledRegexCached(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := compiledRegex(`"status"\s*:\s*"ok"`); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
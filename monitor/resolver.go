@@ -0,0 +1,248 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultDNSFallbackAfter 自定义 DNS 连续失败多少次查询后回退到系统解析器（monitor.dns_fallback_after）
+	defaultDNSFallbackAfter = 5
+	// resolverProbeInterval 回退期间探测自定义 DNS 是否恢复的间隔
+	resolverProbeInterval = 30 * time.Second
+	// resolverProbeTimeout 单次探测的超时时间
+	resolverProbeTimeout = 3 * time.Second
+	// resolverFallbackSuffix 回退期间检查消息的后缀
+	resolverFallbackSuffix = " (system resolver fallback)"
+)
+
+// systemResolver 回退时使用的系统解析器（/etc/resolv.conf 或平台解析器）
+var systemResolver = &net.Resolver{}
+
+// ResolverHealth 自定义 DNS 的健康状态，出现在 /health 中
+type ResolverHealth struct {
+	Server              string    `json:"server"`               // 当前配置的 DNS（未配置 monitor.dns_server 时为内置的 1.1.1.1 / 223.5.5.5）
+	Fallback            bool      `json:"fallback"`             // 是否正在使用系统解析器
+	FallbackEnabled     bool      `json:"fallback_enabled"`     // monitor.dns_fallback_disabled 未开启
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 自定义 DNS 连续失败的查询数
+	LastError           string    `json:"last_error,omitempty"`
+	FallbackSince       time.Time `json:"fallback_since,omitempty"`
+	LastProbeAt         time.Time `json:"last_probe_at,omitempty"`
+}
+
+// OnResolverChange 进入或退出系统解析器回退时的回调（用于向管理员推送 systemWarning）
+var OnResolverChange func(h ResolverHealth)
+
+// resolverState 自定义 DNS 的健康跟踪：查询连续失败达到阈值时回退到系统解析器，
+// 回退期间定期探测自定义 DNS，可以连通后切换回去
+type resolverState struct {
+	mu            sync.Mutex
+	failures      int
+	lastError     string
+	fallback      bool
+	fallbackSince time.Time
+	lastProbe     time.Time
+	probing       bool
+}
+
+var resolverHealth resolverState
+
+// configuredDNS 返回配置的 DNS 服务器地址（含端口），未配置时为空
+func configuredDNS() string {
+	addr := config.Get().Monitor.DNSServer
+	if addr != "" && !strings.Contains(addr, ":") {
+		addr += ":53"
+	}
+	return addr
+}
+
+// dnsFallbackAfter 回退阈值，未配置时为 defaultDNSFallbackAfter
+func dnsFallbackAfter() int {
+	if n := config.Get().Monitor.DNSFallbackAfter; n > 0 {
+		return n
+	}
+	return defaultDNSFallbackAfter
+}
+
+// ResolverFallbackActive 当前是否正在使用系统解析器
+func ResolverFallbackActive() bool {
+	resolverHealth.mu.Lock()
+	defer resolverHealth.mu.Unlock()
+	return resolverHealth.fallback
+}
+
+// GetResolverHealth 返回自定义 DNS 的健康状态
+func GetResolverHealth() ResolverHealth {
+	server := configuredDNS()
+	if server == "" {
+		server = "1.1.1.1:53, 223.5.5.5:53"
+	}
+	resolverHealth.mu.Lock()
+	defer resolverHealth.mu.Unlock()
+	return ResolverHealth{
+		Server:              server,
+		Fallback:            resolverHealth.fallback,
+		FallbackEnabled:     !config.Get().Monitor.DNSFallbackDisabled,
+		ConsecutiveFailures: resolverHealth.failures,
+		LastError:           resolverHealth.lastError,
+		FallbackSince:       resolverHealth.fallbackSince,
+		LastProbeAt:         resolverHealth.lastProbe,
+	}
+}
+
+// recordSuccess 自定义 DNS 返回了响应（包括 NXDOMAIN 等错误响应），清零失败计数
+func (r *resolverState) recordSuccess() {
+	r.mu.Lock()
+	r.failures = 0
+	r.mu.Unlock()
+}
+
+// recordFailure 自定义 DNS 查询没有响应（超时、端口不可达等），连续失败达到阈值时进入回退
+func (r *resolverState) recordFailure(err error) {
+	r.mu.Lock()
+	r.failures++
+	r.lastError = err.Error()
+	enter := !r.fallback && r.failures >= dnsFallbackAfter() && !config.Get().Monitor.DNSFallbackDisabled
+	if enter {
+		r.fallback = true
+		r.fallbackSince = time.Now()
+	}
+	startProbe := enter && !r.probing
+	if startProbe {
+		r.probing = true
+	}
+	r.mu.Unlock()
+
+	if enter {
+		h := GetResolverHealth()
+		detail := fmt.Sprintf("custom DNS %s failed %d consecutive queries (last error: %s), falling back to the system resolver",
+			h.Server, h.ConsecutiveFailures, h.LastError)
+		logger.Warn("DNS resolver fallback activated", zap.String("server", h.Server), zap.String("error", h.LastError))
+		db.RaiseAlert(model.AlertSeverityWarning, "DNS resolver unreachable", detail)
+		db.RecordAudit("system", "dns.fallback", h.Server, detail)
+		if OnResolverChange != nil {
+			OnResolverChange(h)
+		}
+	}
+	if startProbe {
+		go r.probeLoop()
+	}
+}
+
+// probeLoop 回退期间定期探测自定义 DNS，恢复响应后切换回去；关闭回退配置时也立即切换回去
+func (r *resolverState) probeLoop() {
+	ticker := time.NewTicker(resolverProbeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		err := probeResolver()
+		r.mu.Lock()
+		r.lastProbe = time.Now()
+		recovered := err == nil || config.Get().Monitor.DNSFallbackDisabled
+		if err != nil {
+			r.lastError = err.Error()
+		}
+		if recovered {
+			r.fallback, r.probing, r.failures = false, false, 0
+			r.fallbackSince = time.Time{}
+		}
+		r.mu.Unlock()
+		if !recovered {
+			continue
+		}
+
+		h := GetResolverHealth()
+		logger.Info("DNS resolver recovered, leaving system resolver fallback", zap.String("server", h.Server))
+		db.RecordAudit("system", "dns.recovered", h.Server, fmt.Sprintf("custom DNS %s is reachable again", h.Server))
+		if OnResolverChange != nil {
+			OnResolverChange(h)
+		}
+		return
+	}
+}
+
+// probeResolver 通过自定义 DNS 查询一次，收到任何响应（包括 NXDOMAIN）都视为可用
+func probeResolver() error {
+	var answered atomic.Bool
+	res := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := dialConfiguredDNS(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &probeConn{UDPConn: conn, answered: &answered}, nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), resolverProbeTimeout)
+	defer cancel()
+	_, err := res.LookupHost(ctx, "example.com")
+	if answered.Load() {
+		return nil
+	}
+	if err == nil {
+		err = errors.New("no response")
+	}
+	return err
+}
+
+// dialConfiguredDNS 连接配置的 DNS；未配置时依次尝试 1.1.1.1 和 223.5.5.5
+func dialConfiguredDNS(ctx context.Context) (*net.UDPConn, error) {
+	d := net.Dialer{Timeout: 2 * time.Second}
+	addr := configuredDNS()
+	if addr == "" {
+		addr = "1.1.1.1:53"
+	}
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil && configuredDNS() == "" {
+		conn, err = d.DialContext(ctx, "udp", "223.5.5.5:53")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
+
+// trackedConn 记录每次查询是否收到了自定义 DNS 的响应：读到数据为成功，读取出错（超时、端口不可达）为失败。
+// 调用方取消查询（如另一地址族的查询已失败）导致的读取错误不计入。
+// 嵌入 *net.UDPConn 保留 net.PacketConn 接口，否则 Go 解析器会按 TCP 报文格式收发
+type trackedConn struct {
+	*net.UDPConn
+	ctx context.Context
+}
+
+func (c *trackedConn) Read(b []byte) (int, error) {
+	n, err := c.UDPConn.Read(b)
+	switch {
+	case n > 0:
+		resolverHealth.recordSuccess()
+	case err != nil && !errors.Is(c.ctx.Err(), context.Canceled):
+		resolverHealth.recordFailure(err)
+	}
+	return n, err
+}
+
+// probeConn 探测使用的连接，只记录是否收到响应，不影响失败计数
+type probeConn struct {
+	*net.UDPConn
+	answered *atomic.Bool
+}
+
+func (c *probeConn) Read(b []byte) (int, error) {
+	n, err := c.UDPConn.Read(b)
+	if n > 0 {
+		c.answered.Store(true)
+	}
+	return n, err
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"ping-go/db"
+	"ping-go/model"
+	"time"
+)
+
+// sampleState 自适应采样模式下单个监控项的状态
+type sampleState struct {
+	lastStatus int
+	lastHour   time.Time
+	// pending 最近一次被抑制的心跳，RepresentedCount 为自上次写入以来被抑制的检查次数
+	pending *model.Heartbeat
+}
+
+// persistHeartbeat 决定心跳是否写入数据库。
+// 未开启采样（SampleEvery <= 1）时每条都写入；开启后连续相同的 UP 结果只写入每第 N 条，
+// 状态变化、DOWN、每小时的第一条和最后一条总是写入。被抑制的检查数累加到下一条写入的心跳的 RepresentedCount 上。
+func (s *Service) persistHeartbeat(m model.Monitor, h *model.Heartbeat) {
+	h.RepresentedCount = 1
+	if m.SampleEvery <= 1 {
+		s.flushSample(m.ID)
+		db.AddHeartbeat(h)
+		return
+	}
+
+	hour := h.Time.Truncate(time.Hour)
+
+	s.sampleMu.Lock()
+	st, ok := s.samples[m.ID]
+	if !ok {
+		st = &sampleState{lastStatus: -1}
+		s.samples[m.ID] = st
+	}
+	var write []*model.Heartbeat
+	switch {
+	case h.Status != model.StatusUp || st.lastStatus != model.StatusUp || !hour.Equal(st.lastHour):
+		// 变化、DOWN 或新的一小时：先写出挂起的心跳（即上一段/上一小时的最后一条），再写入当前心跳
+		if st.pending != nil {
+			write = append(write, st.pending)
+			st.pending = nil
+		}
+		write = append(write, h)
+	default:
+		count := 1
+		if st.pending != nil {
+			count += st.pending.RepresentedCount
+		}
+		pending := *h
+		pending.RepresentedCount = count
+		st.pending = &pending
+		if count >= m.SampleEvery {
+			write = append(write, st.pending)
+			st.pending = nil
+		}
+	}
+	st.lastStatus = h.Status
+	st.lastHour = hour
+	s.sampleMu.Unlock()
+
+	for _, hb := range write {
+		db.AddHeartbeat(hb)
+	}
+}
+
+// flushSample 写出监控项挂起的心跳并清除采样状态（停止监控、关闭采样或退出时调用）
+func (s *Service) flushSample(id uint) {
+	s.sampleMu.Lock()
+	st, ok := s.samples[id]
+	delete(s.samples, id)
+	s.sampleMu.Unlock()
+
+	if ok && st.pending != nil {
+		db.AddHeartbeat(st.pending)
+	}
+}
+
+// flushAllSamples 写出所有挂起的心跳
+func (s *Service) flushAllSamples() {
+	s.sampleMu.Lock()
+	var pending []*model.Heartbeat
+	for id, st := range s.samples {
+		if st.pending != nil {
+			pending = append(pending, st.pending)
+		}
+		delete(s.samples, id)
+	}
+	s.sampleMu.Unlock()
+
+	for _, h := range pending {
+		db.AddHeartbeat(h)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"mime/multipart"
 	"net"
 	"net/http"
@@ -15,10 +16,13 @@ import (
 	"ping-go/db"
 	"ping-go/model"
 	"ping-go/notification"
+	"ping-go/pkg/eventlog"
+	"ping-go/pkg/i18n"
 	"ping-go/pkg/logger"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	probing "github.com/prometheus-community/pro-bing"
@@ -28,11 +32,26 @@ import (
 const (
 	MinMonitorInterval = 20
 	DefaultPingTimeout = 5 * time.Second
+	DefaultPingCount   = 3
+	MaxPingCount       = 20
+	MaxPingSize        = 65500
+	// pingPacketInterval 相邻两个 ICMP 包的发送间隔
+	pingPacketInterval = 100 * time.Millisecond
 )
 
+// PingOptions 控制 ping 检查的发包数量、包大小与丢包阈值，零值表示使用默认行为
+type PingOptions struct {
+	Count          int           // 发包数量，默认 3
+	Size           int           // ICMP 负载字节数，0 使用库默认值
+	MaxLossPercent int           // 丢包率超过该值判定为 DOWN，0 表示只要有回包即为 UP
+	MaxDuration    time.Duration // 整个检查允许的最长耗时（通常为监控间隔），0 表示不限制
+	IPVersion      string        // auto/ipv4/ipv6
+}
+
 type CheckResult struct {
 	MonitorID uint
 	Name      string
+	Type      string
 	URL       string
 	Status    int
 	Message   string
@@ -42,6 +61,39 @@ type NotificationState struct {
 	ConsecutiveFailures  int
 	ConsecutiveSuccesses int
 	LastSentStatus       int
+
+	// 持续宕机提醒：FirstFailureAt 为本轮连续失败的第一次检查时间，DownSince 为进入 DOWN 的起始时间，
+	// LastSentAt / ChecksSinceSent 用于计算下一次提醒
+	FirstFailureAt  time.Time
+	DownSince       time.Time
+	LastSentAt      time.Time
+	ChecksSinceSent int
+
+	// DownNotified 本轮宕机通知已实际发出（未被静默时段或限流抑制），此时恢复通知不受静默时段和限流限制，避免事件一直处于未恢复状态
+	DownNotified bool
+
+	// 升级通知：DownNotifiedAt 为本轮宕机通知实际发出的时间，Escalated 表示本轮已发送升级通知，恢复时需要同时通知升级渠道
+	DownNotifiedAt time.Time
+	Escalated      bool
+
+	// 限流和抖动检测：SentTimes 为最近一小时实际发送的时间，Transitions 为统计窗口内的状态变化时间，
+	// Suppressed 为上次发送后被限流或抖动抑制的通知数，附在下一条发出的通知中
+	SentTimes        []time.Time
+	Transitions      []time.Time
+	LastTransitionAt time.Time
+	Flapping         bool
+	Suppressed       int
+}
+
+// reminderDue 判断持续宕机时是否应该再次提醒：unit 为 "minutes" 时按距离上次发送的时间，否则按检查次数
+func (st *NotificationState) reminderDue(interval int, unit string, now time.Time) bool {
+	if interval <= 0 || st.LastSentStatus != model.StatusDown || st.DownSince.IsZero() {
+		return false
+	}
+	if unit == "minutes" {
+		return now.Sub(st.LastSentAt) >= time.Duration(interval)*time.Minute
+	}
+	return st.ChecksSinceSent >= interval
 }
 
 type Service struct {
@@ -51,15 +103,37 @@ type Service struct {
 	mu                 sync.Mutex
 	OnHeartbeat        func(h *model.Heartbeat)
 	checkResultChannel chan *CheckResult
+	webhookQueue       chan *webhookDelivery
+	subscriberQueue    chan SubscriberUpdate
 	stopWorker         chan struct{}
 	workerStopped      bool
 	stoppedMonitors    map[uint]bool
 	notificationStates map[string]*NotificationState
+	dirtyStates        map[string]bool       // 尚未写入数据库的通知状态
+	quietDigests       map[uint]*quietDigest // ruleID -> 静默时段内被抑制的通知
+	debugSessions      map[uint]*debugSession
+	driftMu            sync.Mutex
+	drift              map[uint]*driftTracker
+	checkEvents        atomic.Pointer[eventlog.Sink]
+	sampleMu           sync.Mutex
+	samples            map[uint]*sampleState
+	diagRunning        sync.Map // monitorID -> bool，正在执行故障诊断的监控项
+	lastChecks         sync.Map // monitorID -> LastCheck，供 /metrics 导出
+	// runningLoops 正在运行的监控调度 goroutine 数，诊断信息中与应运行数对比
+	runningLoops atomic.Int64
+	// startup 启动时分批调度监控项的进度
+	startup startupState
+
+	// 多实例模式（未启用时 ha 为 nil）
+	ha     *haState
+	leader atomic.Bool
 }
 
 func NewService() *Service {
 	// Init logger if not already
-	logger.Init("info")
+	if logger.Logger == nil {
+		logger.Init("info")
+	}
 
 	// Reset trigger notifications to inactive on startup as requested
 	if err := db.DB.Model(&model.Notification{}).Where("type = ?", "trigger").Update("active", false).Error; err != nil {
@@ -71,13 +145,26 @@ func NewService() *Service {
 		tickers:            make(map[uint]*time.Ticker),
 		stopChans:          make(map[uint]chan struct{}),
 		checkResultChannel: make(chan *CheckResult, 1000),
+		webhookQueue:       make(chan *webhookDelivery, webhookQueueSize),
+		subscriberQueue:    make(chan SubscriberUpdate, subscriberQueueSize),
 		stopWorker:         make(chan struct{}),
 		stoppedMonitors:    make(map[uint]bool),
 		notificationStates: make(map[string]*NotificationState),
+		dirtyStates:        make(map[string]bool),
+		quietDigests:       make(map[uint]*quietDigest),
+		debugSessions:      make(map[uint]*debugSession),
+		drift:              make(map[uint]*driftTracker),
+		samples:            make(map[uint]*sampleState),
 	}
 
+	// 恢复重启前的通知状态，重启时正在进行的宕机仍会按原有计数发出通知
+	s.loadNotificationStates()
 	go s.runNotificationWorker()
 	go s.runScheduledWorker()
+	for range webhookWorkers {
+		go s.runWebhookWorker()
+	}
+	go s.runSubscriberWorker()
 	return s
 }
 
@@ -106,36 +193,73 @@ func (s *Service) Shutdown(ctx context.Context) error {
 }
 
 func (s *Service) HealthCheck() map[string]any {
+	leader := s.leaderInstance()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return map[string]any{
+	health := map[string]any{
 		"total_monitors":  len(s.monitors),
 		"active_monitors": len(s.tickers),
+		"drift_p95_ms":    s.fleetDriftP95(),
+		"events_dropped":  s.checkEventsDropped(),
+		"role":            s.Role(),
 		"status":          "healthy",
+		"dns_resolver":    GetResolverHealth(),
+	}
+	if startup := s.GetStartupProgress(); startup.Starting {
+		health["status"] = "starting"
+		health["startup"] = startup
+	}
+	if s.ha != nil {
+		health["instance_id"] = s.ha.instanceID
+		health["leader"] = leader
+	}
+	return health
+}
+
+// SchedulerStats 返回正在运行的监控调度 goroutine 数和应运行数（已启用的监控项，非领导者实例为 0）。
+// 两者不一致说明有调度 goroutine 泄漏或意外退出
+func (s *Service) SchedulerStats() (running, expected int) {
+	leader := s.IsLeader()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if leader {
+		for _, m := range s.monitors {
+			if m.Active == 1 {
+				expected++
+			}
+		}
 	}
+	return int(s.runningLoops.Load()), expected
 }
 
 func (s *Service) runNotificationWorker() {
 	logger.Info("Notification worker started")
+	flush := time.NewTicker(stateFlushInterval)
+	defer flush.Stop()
 	for {
 		select {
+		case <-flush.C:
+			s.persistNotificationStates()
+
 		case result := <-s.checkResultChannel:
+			// 维护中的结果既不算失败也不算恢复，不影响通知状态
+			if result.Status == model.StatusMaintenance {
+				continue
+			}
+
 			// 1. Check DB Trigger Rules
 			var rules []model.Notification
 			if err := db.DB.Where("type = ? AND active = ?", "trigger", true).Find(&rules).Error; err == nil && len(rules) > 0 {
 				for _, rule := range rules {
-					var cfg struct {
-						MonitorName        string `json:"monitor_name"`
-						OnStatus           string `json:"on_status"` // "down", "up", "change"
-						Email              string `json:"email"`
-						MaxRetries         int    `json:"max_retries"`
-						MaxRetriesRecovery int    `json:"max_retries_recovery"`
-					}
+					var cfg triggerConfig
 					if err := json.Unmarshal([]byte(rule.Config), &cfg); err != nil {
 						logger.Error("Failed to unmarshal trigger config", zap.Error(err))
 						continue
 					}
+					cfg.RuleID = rule.ID
+					cfg.Config = json.RawMessage(rule.Config)
 
 					// Check Monitor Name Match ("*" means all)
 					if cfg.MonitorName != "*" && cfg.MonitorName != result.Name {
@@ -143,7 +267,7 @@ func (s *Service) runNotificationWorker() {
 					}
 
 					// State Management Key
-					stateKey := fmt.Sprintf("%d_%d", rule.ID, result.MonitorID)
+					stateKey := notificationStateKey(rule.ID, result.MonitorID)
 
 					s.mu.Lock()
 					state, exists := s.notificationStates[stateKey]
@@ -152,6 +276,7 @@ func (s *Service) runNotificationWorker() {
 							LastSentStatus: result.Status, // Initialize with current status to arm immediately
 						}
 						s.notificationStates[stateKey] = state
+						s.dirtyStates[stateKey] = true
 						s.mu.Unlock()
 						// First time sync, no notification needed yet
 						continue
@@ -160,13 +285,18 @@ func (s *Service) runNotificationWorker() {
 					// Update Counters
 					// Only count Success/Failure for definitive statuses.
 					// Pending (and others) should not reset/increment counters.
+					now := time.Now()
 					if result.Status == model.StatusDown {
+						if state.ConsecutiveFailures == 0 {
+							state.FirstFailureAt = now
+						}
 						state.ConsecutiveFailures++
 						state.ConsecutiveSuccesses = 0
 					} else if result.Status == model.StatusUp {
 						state.ConsecutiveSuccesses++
 						state.ConsecutiveFailures = 0
 					}
+					s.dirtyStates[stateKey] = true
 
 					// Determine Effective Status (Hard Status)
 					shouldNotify := false
@@ -182,8 +312,14 @@ func (s *Service) runNotificationWorker() {
 						thresholdUp = 1
 					}
 
+					// down_for_seconds 大于 0 时按持续时间判断：重试、暂停和手动检查都会让检查次数对应的时间不确定
+					thresholdDownFor := time.Duration(cfg.DownForSeconds) * time.Second
 					if result.Status == model.StatusDown {
-						if state.ConsecutiveFailures >= thresholdDown {
+						if thresholdDownFor > 0 {
+							if now.Sub(state.FirstFailureAt) >= thresholdDownFor {
+								newStatusToSend = model.StatusDown
+							}
+						} else if state.ConsecutiveFailures >= thresholdDown {
 							newStatusToSend = model.StatusDown
 						}
 					} else if result.Status == model.StatusUp {
@@ -205,20 +341,153 @@ func (s *Service) runNotificationWorker() {
 							shouldNotify = true
 						} else if cfg.OnStatus == "up" && newStatusToSend == model.StatusUp {
 							shouldNotify = true
+						} else if notification.ResolvesOnRecovery(cfg.Channel) && newStatusToSend == model.StatusUp {
+							// PagerDuty / Opsgenie 等渠道的恢复通知用于关闭宕机时打开的事件，on_status 为 down 时也发送
+							shouldNotify = true
 						}
 
+						// 通知中说明实际满足的条件；duration 为本轮失败已持续的时间或恢复时的宕机总时长
+						condition := ""
+						var duration time.Duration
+						downSince := state.DownSince
+						if !downSince.IsZero() {
+							duration = now.Sub(downSince)
+						}
+						if newStatusToSend == model.StatusDown {
+							downFor := now.Sub(state.FirstFailureAt)
+							duration = downFor
+							if thresholdDownFor > 0 {
+								condition = fmt.Sprintf("down for %s, threshold %s", shortDuration(downFor), shortDuration(thresholdDownFor))
+							} else {
+								condition = fmt.Sprintf("%d consecutive failed checks over %s, threshold %d",
+									state.ConsecutiveFailures, shortDuration(downFor), thresholdDown)
+							}
+						}
+
+						// 静默时段内只抑制发送，状态照常更新；之前已发出宕机通知的恢复通知不受静默时段和限流影响。
+						// 进入抖动状态时只发送一条抖动通知，之后的状态变化在状态稳定前都被抑制并计数
+						startFlap := state.noteTransition(cfg, now)
+						resolving := newStatusToSend == model.StatusUp && state.DownNotified
+						// 已发送升级通知的宕机恢复时，原渠道和升级渠道都发送恢复通知（不受 on_status 限制）
+						resolveEscalation := newStatusToSend == model.StatusUp && state.Escalated
+						if resolveEscalation {
+							shouldNotify = true
+						}
+						suppress, sendFlap, limited := false, false, false
+						switch {
+						case cfg.QuietHours.Active(now) && !resolving:
+							suppress = shouldNotify
+						case startFlap:
+							sendFlap = true
+						case !shouldNotify:
+						case state.Flapping:
+							limited = true
+						case resolving:
+						case !state.allowSend(cfg.RateLimitPerHour, now):
+							limited = true
+						}
+						notified := sendFlap || (shouldNotify && !suppress && !limited)
+						suppressed := 0
+						if limited {
+							state.Suppressed++
+						} else if notified {
+							suppressed = state.markSent(now)
+						}
+						state.DownNotified = notified && newStatusToSend == model.StatusDown
+						if state.DownNotified {
+							state.DownNotifiedAt = now
+						}
+						// 状态变化时取消未发送的升级
+						state.Escalated = false
+
 						// Update State
 						state.LastSentStatus = newStatusToSend
+						state.LastSentAt, state.ChecksSinceSent = now, 0
+						if newStatusToSend == model.StatusDown {
+							state.DownSince = state.FirstFailureAt
+						} else {
+							// 恢复后立即停止提醒
+							state.DownSince = time.Time{}
+						}
 
 						// Release lock before sending notification (although send is async, let's minimize lock time)
 						s.mu.Unlock()
-
-						if shouldNotify {
+						// 状态变化立即写入数据库，重启后不会重复或遗漏这次通知
+						s.persistNotificationStates()
+
+						switch {
+						case suppress:
+							s.recordSuppressed(cfg, result, newStatusToSend, duration)
+						case sendFlap:
+							s.sendFlapNotification(cfg, result, newStatusToSend, true,
+								withSuppressed(fmt.Sprintf("%d status changes within %s", cfg.FlapTransitions, shortDuration(cfg.flapWindow())), suppressed))
+						case limited:
+							logger.Info("Notification rate limited", zap.Uint("rule", rule.ID), zap.String("monitor", result.Name))
+						case shouldNotify:
 							// Send Notification
-							s.sendTriggerNotification(cfg.Email, result.Name, result.URL, state.LastSentStatus, newStatusToSend, result.Message)
+							s.sendTriggerNotification(cfg, result, state.LastSentStatus, newStatusToSend, condition, duration, downSince, suppressed)
+						}
+						if resolveEscalation {
+							s.sendTriggerNotification(cfg.escalationRule(), result, model.StatusDown, model.StatusUp, "", duration, downSince, 0)
 						}
 					} else {
+						// 抖动结束（状态已稳定 flap_stable_minutes）：发送一条当前状态的通知，附带期间被抑制的通知数
+						quiet := cfg.QuietHours.Active(now)
+						flapEnded, suppressed := state.flapEnded(cfg, now), 0
+						currentStatus := state.LastSentStatus
+						if flapEnded && !quiet {
+							suppressed = state.markSent(now)
+							state.DownNotified = currentStatus == model.StatusDown
+							state.DownNotifiedAt = now
+						}
+
+						// 仍处于 DOWN：按 resend_interval 发送持续宕机提醒（只对会发送宕机通知的规则生效），抖动期间不提醒
+						remind := false
+						var downFor time.Duration
+						if !flapEnded && !state.Flapping && currentStatus == model.StatusDown && (cfg.OnStatus == "down" || cfg.OnStatus == "change") {
+							state.ChecksSinceSent++
+							if state.reminderDue(cfg.ResendInterval, cfg.ResendUnit, now) {
+								// 静默时段内跳过提醒（不计入汇总），时段结束后按间隔继续提醒；超过 rate_limit_per_hour 的提醒计入被抑制数
+								remind = !quiet
+								downFor = now.Sub(state.DownSince)
+								state.LastSentAt, state.ChecksSinceSent = now, 0
+								if remind && !state.allowSend(cfg.RateLimitPerHour, now) {
+									remind = false
+									state.Suppressed++
+								} else if remind {
+									suppressed = state.markSent(now)
+								}
+							}
+						}
+
+						// 宕机通知发出 escalation_after_minutes 分钟后仍为 DOWN：通过升级渠道发送一次，静默时段内推迟到时段结束，不计入限流
+						escalate := !quiet && state.escalationDue(cfg, now)
+						var outage time.Duration
+						if escalate {
+							state.Escalated = true
+							outage = now.Sub(state.DownSince)
+						}
 						s.mu.Unlock()
+						if flapEnded || remind || escalate {
+							s.persistNotificationStates()
+						}
+
+						if flapEnded {
+							if quiet {
+								s.recordSuppressed(cfg, result, currentStatus, 0)
+							} else {
+								s.sendFlapNotification(cfg, result, currentStatus, false,
+									withSuppressed(fmt.Sprintf("status stable for %s", shortDuration(cfg.flapStable())), suppressed))
+							}
+						}
+
+						if remind {
+							s.sendReminderNotification(cfg, result, downFor, suppressed)
+						}
+
+						if escalate {
+							s.sendEscalationNotification(cfg, result, outage)
+						}
 					}
 				}
 			} else if err != nil {
@@ -226,51 +495,184 @@ func (s *Service) runNotificationWorker() {
 			}
 
 		case <-s.stopWorker:
+			s.persistNotificationStates()
 			logger.Info("Notification worker stopped")
 			return
 		}
 	}
 }
 
-func (s *Service) sendTriggerNotification(email, name, url string, oldStatus, newStatus int, msg string) {
-	if email == "" {
-		return
-	}
-	to := []string{email}
-	subject := fmt.Sprintf("PingGo Notification: %s is %s", name, statusToString(newStatus))
+// triggerConfig 触发规则的配置（Notification.Config）
+type triggerConfig struct {
+	MonitorName            string      `json:"monitor_name"`
+	OnStatus               string      `json:"on_status"` // "down", "up", "change"
+	Channel                string      `json:"channel"`   // 通知渠道，见 notification.Providers()，默认 "email"
+	MaxRetries             int         `json:"max_retries"`
+	MaxRetriesRecovery     int         `json:"max_retries_recovery"`
+	ResendInterval         int         `json:"resend_interval"`          // 持续 DOWN 时每隔多少次检查（或分钟）再次提醒，0 不提醒
+	ResendUnit             string      `json:"resend_unit"`              // "checks"（默认）或 "minutes"
+	DownForSeconds         int         `json:"down_for_seconds"`         // 大于 0 时改为按持续时间触发：本轮第一次失败起持续不少于该秒数，忽略 max_retries
+	RedactDetails          bool        `json:"redact_details"`           // 只发送名称、状态和时间，不包含检查消息和地址
+	QuietHours             *QuietHours `json:"quiet_hours"`              // 静默时段，为空表示不静默
+	RateLimitPerHour       int         `json:"rate_limit_per_hour"`      // 每个监控项每小时最多发送的通知数，0 不限制；被抑制的数量附在下一条通知中
+	FlapTransitions        int         `json:"flap_transitions"`         // 大于 0 时开启抖动检测：flap_window_minutes 内状态变化达到该次数即视为抖动
+	FlapWindowMinutes      int         `json:"flap_window_minutes"`      // 抖动检测的统计窗口，默认 10 分钟
+	FlapStableMinutes      int         `json:"flap_stable_minutes"`      // 抖动后状态保持不变多久恢复正常通知，默认与统计窗口相同
+	EscalationAfterMinutes int         `json:"escalation_after_minutes"` // 大于 0 时开启升级：宕机通知发出后持续 DOWN 超过该分钟数，再通过 escalation_channel 发送一次
+	EscalationChannel      string      `json:"escalation_channel"`       // 升级渠道，渠道字段同样从规则配置中读取
+	EscalationEmail        string      `json:"escalation_email"`         // 升级渠道为 email 时的收件邮箱，为空时使用 email
+	Language               string      `json:"language"`                 // 通知语言（zh / en），为空时使用邮件语言设置
+	RuleID                 uint        `json:"-"`                        // 规则 ID，用于事件视图链接
+
+	// Config 完整的规则配置，渠道从中读取自己的字段
+	Config json.RawMessage `json:"-"`
+}
+
+// sendTriggerNotification 发送状态变化通知；恢复通知的 duration 为宕机总时长，downSince 为宕机开始时间（宕机通知时为零值）
+func (s *Service) sendTriggerNotification(rule triggerConfig, result *CheckResult, oldStatus, newStatus int, condition string, duration time.Duration, downSince time.Time, suppressed int) {
+	lang := ruleLanguage(rule.Language)
+	subject := NotifySubject(lang, "notify.subject.status", result.Name, statusWord(lang, newStatus))
 	// Determine style
-	color := "#e74c3c" // Red for error
-	statusText := "服务宕机通知"
+	color := db.StatusMeta(model.StatusDown).Color
+	statusText := i18n.T(lang, "notify.title.down")
 	if newStatus == model.StatusUp {
-		color = "#2ecc71" // Green for recovery
-		statusText = "服务恢复通知"
+		color = db.StatusMeta(model.StatusUp).Color
+		statusText = i18n.T(lang, "notify.title.up")
 	}
 
 	data := notification.StatusChangeData{
-		Name:       name,
-		URL:        url,
-		OldStatus:  statusToString(oldStatus),
-		NewStatus:  statusToString(newStatus),
-		Message:    msg,
-		Color:      color,
-		StatusText: statusText,
-		DateTime:   time.Now().Format("2006-01-02 15:04:05"),
+		Name:        result.Name,
+		URL:         result.URL,
+		OldStatus:   statusToString(oldStatus),
+		NewStatus:   statusToString(newStatus),
+		Message:     result.Message,
+		Color:       color,
+		StatusText:  statusText,
+		DateTime:    time.Now().Format("2006-01-02 15:04:05"),
+		Condition:   withSuppressed(condition, suppressed),
+		Suppressed:  suppressed,
+		MonitorID:   result.MonitorID,
+		MonitorType: result.Type,
+		IncidentURL: config.Get().IncidentURL(result.MonitorID, time.Now(), rule.RuleID),
+		Lang:        lang,
+		SiteName:    db.SiteName(),
+	}
+	if duration > 0 {
+		data.Duration = shortDuration(duration)
 	}
+	if newStatus == model.StatusUp {
+		data.DownSince = formatDownSince(downSince)
+	}
+
+	s.deliverStatus(rule, subject, data)
+}
+
+// sendReminderNotification 发送持续宕机提醒，主题中包含累计宕机时长
+func (s *Service) sendReminderNotification(rule triggerConfig, result *CheckResult, downFor time.Duration, suppressed int) {
+	lang := ruleLanguage(rule.Language)
+	subject := NotifySubject(lang, "notify.subject.reminder", result.Name, FormatDowntime(downFor))
+	data := notification.StatusChangeData{
+		Name:        result.Name,
+		URL:         result.URL,
+		OldStatus:   statusToString(model.StatusDown),
+		NewStatus:   statusToString(model.StatusDown),
+		Message:     result.Message,
+		Color:       db.StatusMeta(model.StatusDown).Color,
+		StatusText:  fmt.Sprintf(i18n.T(lang, "notify.title.reminder"), FormatDowntime(downFor)),
+		DateTime:    time.Now().Format("2006-01-02 15:04:05"),
+		Duration:    shortDuration(downFor),
+		DownSince:   formatDownSince(time.Now().Add(-downFor)),
+		Condition:   withSuppressed("", suppressed),
+		Suppressed:  suppressed,
+		MonitorID:   result.MonitorID,
+		MonitorType: result.Type,
+		IncidentURL: config.Get().IncidentURL(result.MonitorID, time.Now(), rule.RuleID),
+		Lang:        lang,
+		SiteName:    db.SiteName(),
+	}
+	s.deliverStatus(rule, subject, data)
+}
+
+// sendFlapNotification 发送抖动通知：started 为 true 时通知进入抖动状态，否则通知状态已稳定，status 为当前状态
+func (s *Service) sendFlapNotification(rule triggerConfig, result *CheckResult, status int, started bool, condition string) {
+	lang := ruleLanguage(rule.Language)
+	subject := NotifySubject(lang, "notify.subject.flapping", result.Name)
+	statusText := i18n.T(lang, "notify.title.flapping")
+	oldStatus := statusToString(model.StatusUp)
+	if status == model.StatusUp {
+		oldStatus = statusToString(model.StatusDown)
+	}
+	if !started {
+		subject = NotifySubject(lang, "notify.subject.flap_stable", result.Name, statusWord(lang, status))
+		statusText = i18n.T(lang, "notify.title.flap_stable")
+		oldStatus = "FLAPPING"
+	}
+	data := notification.StatusChangeData{
+		Name:        result.Name,
+		URL:         result.URL,
+		OldStatus:   oldStatus,
+		NewStatus:   statusToString(status),
+		Message:     result.Message,
+		Color:       db.StatusMeta(status).Color,
+		StatusText:  statusText,
+		DateTime:    time.Now().Format("2006-01-02 15:04:05"),
+		Condition:   condition,
+		MonitorID:   result.MonitorID,
+		MonitorType: result.Type,
+		IncidentURL: config.Get().IncidentURL(result.MonitorID, time.Now(), rule.RuleID),
+		Lang:        lang,
+		SiteName:    db.SiteName(),
+	}
+	s.deliverStatus(rule, subject, data)
+}
 
-	content, err := notification.RenderStatusChangeEmail(data)
+// deliverStatus 按规则的渠道发送状态通知，redact_details 在这里统一处理
+func (s *Service) deliverStatus(rule triggerConfig, subject string, data notification.StatusChangeData) {
+	if rule.RedactDetails {
+		data = data.Redact()
+	}
+	s.deliver(rule.Channel, rule.RuleID, notification.Event{
+		Kind:     notification.EventStatusChange,
+		Subject:  subject,
+		Status:   data,
+		Link:     statusLink(data),
+		Time:     time.Now().In(db.Location()),
+		Config:   rule.Config,
+		Template: db.NotificationTemplate(notification.TemplateStatusChange, rule.RuleID),
+	}, data.Name)
+}
+
+// statusLink 推送通知中的控制台链接：优先指向事件视图，未配置 server.external_url 时为空
+func statusLink(data notification.StatusChangeData) string {
+	if data.IncidentURL != "" {
+		return data.IncidentURL
+	}
+	return config.Get().DashboardURL()
+}
+
+// deliver 通过渠道异步发送通知，只有持有调度租约的实例会发送；每次发送都写入发送记录，失败时记录系统告警。
+// 企业微信等渠道的 Send 会等待发送队列，不阻塞结果处理
+func (s *Service) deliver(channel string, ruleID uint, ev notification.Event, name string) {
+	p, err := notification.Lookup(channel)
 	if err != nil {
-		logger.Error("Failed to render status change email", zap.Error(err))
+		logger.Error("Failed to deliver notification", zap.String("name", name), zap.Error(err))
 		return
 	}
 
-	logger.Info("Sending trigger email", zap.Strings("to", to), zap.String("subject", subject))
-	go func(recipients []string, subj, body string) {
-		if err := notification.SendEmail(recipients, subj, body); err != nil {
-			logger.Error("Failed to send trigger email", zap.Strings("recipients", recipients), zap.Error(err))
-		} else {
-			logger.Info("Trigger email sent successfully", zap.Strings("recipients", recipients))
+	if !s.holdsLeaseNow() {
+		logger.Warn("Skipping notification: this instance does not hold the scheduler lease",
+			zap.String("channel", p.Name()), zap.String("name", name))
+		return
+	}
+
+	logger.Info("Sending notification", zap.String("channel", p.Name()), zap.String("kind", string(ev.Kind)), zap.String("name", name))
+	go func() {
+		if _, err := SendRecorded(context.Background(), p.Name(), ruleID, ev, 0); err != nil {
+			logger.Error("Failed to send notification", zap.String("channel", p.Name()), zap.String("name", name), zap.Error(err))
+			db.RaiseAlert(model.AlertSeverityWarning, "Notification delivery failed",
+				fmt.Sprintf("%s %s: %v", p.Name(), ev.Kind, err))
 		}
-	}(to, subject, content)
+	}()
 }
 
 func (s *Service) runScheduledWorker() {
@@ -281,13 +683,21 @@ func (s *Service) runScheduledWorker() {
 	for {
 		select {
 		case <-ticker.C:
+			if !s.IsLeader() {
+				continue
+			}
+			s.checkStaleMonitors()
+			s.checkStaleAgents()
+			s.flushQuietDigests(time.Now())
+
 			var rules []model.Notification
 			if err := db.DB.Where("type = ? AND active = ?", "schedule", true).Find(&rules).Error; err == nil {
 				for _, rule := range rules {
 					var cfg struct {
 						Time     string `json:"time"`
-						Email    string `json:"email"`
 						Timezone string `json:"timezone"`
+						Channel  string `json:"channel"`  // 支持日报的渠道："email"（默认）或 "wecom"
+						Language string `json:"language"` // 日报语言，为空时使用邮件语言设置
 					}
 					if err := json.Unmarshal([]byte(rule.Config), &cfg); err != nil {
 						continue
@@ -306,10 +716,9 @@ func (s *Service) runScheduledWorker() {
 					nowStr := now.Format("15:04")
 
 					if cfg.Time == nowStr {
-						logger.Info("Triggering scheduled report", zap.String("email", cfg.Email), zap.String("time", nowStr), zap.String("timezone", cfg.Timezone))
-						// Send Report
-						if cfg.Email != "" {
-							go s.sendReport(cfg.Email)
+						logger.Info("Triggering scheduled report", zap.Uint("rule", rule.ID), zap.String("time", nowStr), zap.String("timezone", cfg.Timezone))
+						if notification.SupportsReports(cfg.Channel) {
+							go s.sendReport(cfg.Channel, rule.ID, ruleLanguage(cfg.Language), json.RawMessage(rule.Config))
 						}
 					}
 				}
@@ -321,7 +730,22 @@ func (s *Service) runScheduledWorker() {
 	}
 }
 
-func (s *Service) sendReport(email string) {
+// sendReport 汇总日报并按 lang 语言通过规则的渠道发送
+func (s *Service) sendReport(channel string, ruleID uint, lang string, cfg json.RawMessage) {
+	data := s.dailyReportData(lang)
+	s.deliver(channel, ruleID, notification.Event{
+		Kind:     notification.EventDailyReport,
+		Subject:  NotifySubject(lang, "notify.subject.report", data.Date),
+		Report:   data,
+		Link:     config.Get().DashboardURL(),
+		Time:     time.Now().In(db.Location()),
+		Config:   cfg,
+		Template: db.NotificationTemplate(notification.TemplateDailyReport, ruleID),
+	}, "daily report")
+}
+
+// dailyReportData 汇总日报数据，邮件和企业微信共用；状态名称等文案使用 lang 语言
+func (s *Service) dailyReportData(lang string) notification.DailyReportData {
 	// Gather stats
 	s.mu.Lock()
 	total := len(s.monitors)
@@ -330,12 +754,16 @@ func (s *Service) sendReport(email string) {
 	paused := 0
 
 	type MonitorInfo struct {
+		ID             uint
 		Name           string
+		HourlyUptime   []float64
 		Status         string
+		StatusKey      string
 		Color          string
 		Type           string
 		Uptime24h      float64
 		AvgResponse24h int64
+		LongestOutage  string
 	}
 	var monitorList []MonitorInfo
 
@@ -345,34 +773,46 @@ func (s *Service) sendReport(email string) {
 			continue
 		}
 
-		statusStr := "UNKNOWN"
-		color := "#95a5a6" // grey for unknown
-
 		switch m.Status {
 		case model.StatusUp:
 			up++
-			statusStr = "正常"
-			color = "#2ecc71" // green
 		case model.StatusDown:
 			down++
-			statusStr = "异常"
-			color = "#e74c3c" // red
-		case model.StatusPending:
-			statusStr = "检测中"
-			color = "#f1c40f" // yellow
+		}
+		meta := db.StatusMeta(m.Status)
+		// 设置了监控项颜色时日报的状态标签使用该颜色
+		pillColor := meta.Color
+		if m.Color != "" {
+			pillColor = m.Color
 		}
 
 		// Calculate 24h stats
 		uptime24h := db.GetUptimeStats(m.ID, 24*time.Hour)
 		avgResp24h := db.GetAvgResponseTime(m.ID, 24*time.Hour)
+		longestOutage := ""
+		if start, d := db.LongestOutage(m.ID, time.Now().Add(-24*time.Hour)); d > 0 {
+			longestOutage = fmt.Sprintf(i18n.T(lang, "notify.longest_outage"), shortDuration(d), start.In(db.Location()).Format("15:04"))
+		}
+		var hourly []float64
+		for _, p := range db.GetChartData(m.ID, "24h") {
+			if p.Status == model.StatusNoData {
+				hourly = append(hourly, -1)
+			} else {
+				hourly = append(hourly, p.Uptime)
+			}
+		}
 
 		monitorList = append(monitorList, MonitorInfo{
+			ID:             m.ID,
 			Name:           m.Name,
-			Status:         statusStr,
-			Color:          color,
+			HourlyUptime:   hourly,
+			Status:         i18n.T(lang, "status."+meta.Key),
+			StatusKey:      meta.Key,
+			Color:          pillColor,
 			Type:           string(m.Type),
 			Uptime24h:      uptime24h,
 			AvgResponse24h: int64(avgResp24h),
+			LongestOutage:  longestOutage,
 		})
 	}
 	s.mu.Unlock()
@@ -385,15 +825,19 @@ func (s *Service) sendReport(email string) {
 	}
 
 	dateStr := time.Now().Format("2006-01-02")
-	subject := fmt.Sprintf("PingGo 日报 - %s", dateStr)
 
+	upColor := db.StatusMeta(model.StatusUp).Color
+	warnColor := db.StatusMeta(model.StatusPending).Color
+	failColor := db.StatusMeta(model.StatusDown).Color
 	downColor := "#94a3b8"
 	if down > 0 {
-		downColor = "#e74c3c"
+		downColor = failColor
 	}
 
 	// Prepare monitor list for template
 	var reportMonitors []notification.MonitorInfo
+	cfg := config.Get()
+	sparklineBytes := 0
 	for index, m := range monitorList {
 		rowBg := "#ffffff"
 		if index%2 == 1 {
@@ -401,12 +845,19 @@ func (s *Service) sendReport(email string) {
 		}
 
 		// Color logic for uptime
-		uptimeColor := "#2ecc71"
+		uptimeColor := upColor
 		if m.Uptime24h < 90 {
-			uptimeColor = "#e74c3c"
+			uptimeColor = failColor
 		} else if m.Uptime24h < 99 {
-			uptimeColor = "#f1c40f"
+			uptimeColor = warnColor
+		}
+
+		// 迷你图按顺序占用预算，超出后其余行不显示
+		sparkline := notification.UptimeSparkline(m.HourlyUptime)
+		if sparklineBytes+len(sparkline) > notification.ReportSparklineBudget {
+			sparkline = ""
 		}
+		sparklineBytes += len(sparkline)
 
 		reportMonitors = append(reportMonitors, notification.MonitorInfo{
 			Name:           m.Name,
@@ -414,50 +865,48 @@ func (s *Service) sendReport(email string) {
 			Uptime24h:      m.Uptime24h,
 			AvgResponse24h: m.AvgResponse24h,
 			Status:         m.Status,
+			StatusKey:      m.StatusKey,
 			Color:          m.Color,
 			UptimeColor:    uptimeColor,
 			RowBg:          rowBg,
+			DetailURL:      cfg.MonitorURL(m.ID),
+			Sparkline:      sparkline,
+			LongestOutage:  m.LongestOutage,
 		})
 	}
 
-	data := notification.DailyReportData{
+	return notification.DailyReportData{
 		Date:          dateStr,
 		TotalCount:    activeCount,
 		UptimePercent: uptimePercent,
 		DownCount:     down,
 		DownColor:     downColor,
 		Monitors:      reportMonitors,
-	}
-
-	html, err := notification.RenderDailyReportEmail(data)
-	if err != nil {
-		logger.Error("Failed to render daily report email", zap.Error(err))
-		return
-	}
-
-	if err := notification.SendEmail([]string{email}, subject, html); err != nil {
-		logger.Error("Failed to send report", zap.String("email", email), zap.Error(err))
+		Lang:          lang,
+		SiteName:      db.SiteName(),
 	}
 }
 
-func (s *Service) Start() {
-	var monitors []model.Monitor
-	result := db.DB.Find(&monitors)
-	if result.Error != nil {
-		logger.Error("Failed to load monitors", zap.Error(result.Error))
-		return
-	}
+func (s *Service) StartMonitor(m *model.Monitor) {
+	s.startMonitor(m, 0, nil)
+}
 
-	for _, m := range monitors {
-		if m.Active == 1 {
-			// Copy variable to avoid loop scope issues
-			monitor := m
-			s.StartMonitor(&monitor)
+// StartMonitorsStaggered 启动一批监控项，首次检查在 window 内均匀错开（不超过各自的检查间隔），
+// 避免批量导入后所有监控项在同一时刻发起请求
+func (s *Service) StartMonitorsStaggered(monitors []*model.Monitor, window time.Duration) {
+	for i, m := range monitors {
+		delay := window * time.Duration(i) / time.Duration(len(monitors))
+		if limit := time.Duration(max(m.Interval, MinMonitorInterval)) * time.Second; delay >= limit {
+			delay = time.Duration(rand.Int64N(int64(limit)))
 		}
+		s.startMonitor(m, delay, nil)
 	}
 }
 
-func (s *Service) StartMonitor(m *model.Monitor) {
+// startMonitor 启动监控项调度，delay 为首次检查前的等待时间，后续检查相对首次检查按间隔进行。
+// first 不为 nil 时首次检查由启动工作池执行，调度 goroutine 等到 first 关闭后才开始按间隔检查。
+// 返回调度的停止通道，监控项未启用或本实例不是领导者时返回 nil
+func (s *Service) startMonitor(m *model.Monitor, delay time.Duration, first <-chan struct{}) chan struct{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -478,31 +927,74 @@ func (s *Service) StartMonitor(m *model.Monitor) {
 
 	if m.Active != 1 {
 		logger.Info("Monitor is inactive, skipping", zap.String("name", m.Name))
-		return
+		return nil
 	}
 
 	if m.Interval < MinMonitorInterval {
 		m.Interval = MinMonitorInterval
 	}
 
-	ticker := time.NewTicker(time.Duration(m.Interval) * time.Second)
+	// 多实例模式下非领导者只登记监控项，由领导者负责调度
+	if !s.IsLeader() {
+		return nil
+	}
+
+	interval := time.Duration(m.Interval) * time.Second
+	ticker := time.NewTicker(interval)
 	stopChan := make(chan struct{})
 	s.tickers[m.ID] = ticker
 	s.stopChans[m.ID] = stopChan
+	s.resetDrift(m.ID)
 
+	id, name := m.ID, m.Name
 	go func() {
-		// Run immediately once
-		s.Check(m.ID)
+		s.runningLoops.Add(1)
+		defer s.runningLoops.Add(-1)
+		if first != nil {
+			select {
+			case <-first:
+				ticker.Reset(interval)
+			case <-stopChan:
+				return
+			}
+		} else {
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+					ticker.Reset(interval)
+				case <-stopChan:
+					timer.Stop()
+					return
+				}
+			}
+			// Run immediately once
+			s.recordCheckStart(id, name, interval)
+			s.Check(id)
+		}
 		for {
 			select {
 			case <-ticker.C:
-				s.Check(m.ID)
+				s.recordCheckStart(id, name, interval)
+				s.Check(id)
 			case <-stopChan:
 				return // Exit goroutine gracefully
 			}
 		}
 	}()
-	logger.Info("Started monitoring", zap.String("name", m.Name), zap.String("url", m.URL))
+	logger.Info("Started monitoring", zap.String("name", m.Name), zap.String("url", m.URL), zap.String("proxy", MaskProxyURL(m.ProxyURL)))
+	return stopChan
+}
+
+// UpdateColor 批量设置颜色后更新已加载监控项的颜色（日报使用），不重新调度
+func (s *Service) UpdateColor(ids []uint, color string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		if m, ok := s.monitors[id]; ok {
+			m.Color = color
+		}
+	}
 }
 
 func (s *Service) StopMonitor(id uint) {
@@ -526,6 +1018,12 @@ func (s *Service) StopMonitor(id uint) {
 		delete(s.tickers, id)
 	}
 	delete(s.monitors, id)
+	s.lastChecks.Delete(id)
+	s.resetDrift(id)
+	s.flushSample(id)
+	InvalidateOAuthToken(id)
+	InvalidateHTTPClient(id)
+	invalidateExpression(id)
 
 	// Clean up states for this monitor?
 	// The problem is keys are string "RuleID_MonitorID"
@@ -541,31 +1039,44 @@ func (s *Service) StopMonitor(id uint) {
 
 func (s *Service) ResetNotificationState(ruleID uint) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	prefix := fmt.Sprintf("%d_", ruleID)
 	for key := range s.notificationStates {
 		if strings.HasPrefix(key, prefix) {
 			delete(s.notificationStates, key)
+			delete(s.dirtyStates, key)
 		}
 	}
+	s.mu.Unlock()
+
+	if err := db.DeleteNotificationStates(ruleID, 0); err != nil {
+		logger.Error("Failed to delete notification states", zap.Uint("ruleID", ruleID), zap.Error(err))
+	}
 	logger.Info("Reset notification memory state for rule", zap.Uint("ruleID", ruleID))
 }
 
 func (s *Service) ResetNotificationStateByMonitor(monitorID uint) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	suffix := fmt.Sprintf("_%d", monitorID)
 	for key := range s.notificationStates {
 		if strings.HasSuffix(key, suffix) {
 			delete(s.notificationStates, key)
+			delete(s.dirtyStates, key)
 		}
 	}
+	s.mu.Unlock()
+
+	if err := db.DeleteNotificationStates(0, monitorID); err != nil {
+		logger.Error("Failed to delete notification states", zap.Uint("monitorID", monitorID), zap.Error(err))
+	}
 	logger.Info("Reset notification memory state for monitor", zap.Uint("monitorID", monitorID))
 }
 
 func (s *Service) StopAll() {
+	// 先停止选举（不能持有 s.mu，选举可能正在启动调度）
+	s.stopHA()
+	s.cancelStartup()
+	s.persistNotificationStates()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -586,6 +1097,8 @@ func (s *Service) StopAll() {
 
 	// Reset all states
 	s.notificationStates = make(map[string]*NotificationState)
+	s.dirtyStates = make(map[string]bool)
+	s.flushAllSamples()
 }
 
 func (s *Service) Check(id uint) {
@@ -600,44 +1113,136 @@ func (s *Service) Check(id uint) {
 		return
 	}
 
+	if s.checkMaintenance(m) {
+		return
+	}
+
 	var status int
 	var msg string
 	var duration int
 	startTime := time.Now()
 
+	// 调试模式：捕获额外的请求/响应细节，仅推送给发起调试的客户端，不写入数据库
+	var dbg *DebugInfo
+	debugSess := s.debugSessionFor(m.ID)
+	if debugSess != nil {
+		dbg = &DebugInfo{MonitorID: m.ID, Type: string(m.Type), URL: m.URL}
+	}
+
+	// 仅在启用检查事件输出时记录 HTTP 对端 IP
+	var resolvedIP string
+	var remoteIP *string
+	if s.checkEventsEnabled() {
+		remoteIP = &resolvedIP
+	}
+
+	if m.Type == model.MonitorTypePush {
+		// push 监控由客户端主动上报，定时检查只负责发现超时未上报
+		var ok bool
+		status, msg, ok = evaluatePush(m)
+		if !ok {
+			return
+		}
+	} else {
+		status, msg, duration = runCheck(m, dbg, remoteIP)
+	}
+
+	// 系统解析器回退期间的结果加上说明，便于区分目标故障和 DNS 故障
+	if m.Type != model.MonitorTypePush && m.Type != model.MonitorTypePing && ResolverFallbackActive() {
+		msg += resolverFallbackSuffix
+	}
+
+	if dbg != nil {
+		dbg.Status = status
+		dbg.StatusKey = model.StatusKey(status)
+		dbg.Message = msg
+		dbg.Duration = duration
+		dbg.Timing.Total = time.Since(startTime).Milliseconds()
+		dbg.Time = time.Now().Format(time.RFC3339)
+		debugSess.emit(dbg)
+	}
+
+	s.emitCheckEvent(m, status, msg, duration, resolvedIP)
+	s.recordResult(m, status, msg, duration)
+}
+
+// RunCheck 执行一次检查并返回状态、消息和耗时（毫秒），不读写数据库。
+// 远程探针用它在本地执行分配的监控项；push 监控项由上报驱动，不能这样检查
+func RunCheck(m model.Monitor) (int, string, int) {
+	status, msg, duration := runCheck(m, nil, nil)
+	if m.Type != model.MonitorTypePing && ResolverFallbackActive() {
+		msg += resolverFallbackSuffix
+	}
+	return status, msg, duration
+}
+
+// runCheck 按监控类型执行检查（push 除外）
+func runCheck(m model.Monitor, dbg *DebugInfo, remoteIP *string) (status int, msg string, duration int) {
+	startTime := time.Now()
 	switch m.Type {
 	case model.MonitorTypeHTTP:
-		status, msg = CheckHTTP(m)
+		status, msg = checkHTTP(m, dbg, remoteIP)
 		duration = int(time.Since(startTime).Milliseconds())
 		// 如果是超时或网络连接类的硬故障，将时长设为 0，以便前端图表显示为虚线
-		if status == model.StatusDown && (msg == "Timeout" || msg == "Connection Refused" || msg == "DNS Resolution Failed" || msg == "TLS Error") {
+		if status == model.StatusDown && (strings.HasPrefix(msg, "Timeout") || strings.HasPrefix(msg, "Connection Refused") || strings.HasPrefix(msg, "DNS Resolution Failed") || strings.HasPrefix(msg, "TLS Error")) {
 			duration = 0
 		}
 	case model.MonitorTypePing:
 		var rtt time.Duration
-		status, msg, rtt = CheckPing(m.URL, m.Timeout)
+		status, msg, rtt = CheckPing(m.URL, m.Timeout, PingOptions{
+			Count:          m.PingCount,
+			Size:           m.PingSize,
+			MaxLossPercent: m.MaxPacketLoss,
+			MaxDuration:    time.Duration(m.Interval) * time.Second,
+			IPVersion:      m.IPVersion,
+		})
 		duration = int(rtt.Milliseconds())
 	case model.MonitorTypeTCP:
 		var tcpDuration time.Duration
-		status, msg, tcpDuration = CheckTCP(m.URL, m.Timeout)
+		status, msg, tcpDuration = CheckTCP(m.URL, m.Timeout, m.IPVersion, m.ProxyURL)
 		duration = int(tcpDuration.Milliseconds())
 	case model.MonitorTypeDNS:
-		status, msg = CheckDNS(m.URL, m.Timeout)
+		status, msg = CheckDNS(m.URL, m.Timeout, m.IPVersion)
 		duration = int(time.Since(startTime).Milliseconds())
 		// DNS 失败通常视为硬故障
 		if status == model.StatusDown {
 			duration = 0
 		}
+	case model.MonitorTypeNTP:
+		var rtt time.Duration
+		status, msg, rtt = CheckNTP(m.URL, m.Timeout, m.MaxOffsetMs)
+		duration = int(rtt.Milliseconds())
+	case model.MonitorTypeSSH:
+		// SSH 复用 ResponseRegex 作为协议标识（banner）的校验正则
+		var sshDuration time.Duration
+		status, msg, sshDuration = CheckSSH(m.URL, m.Timeout, m.ResponseRegex, m.SSHHostKey)
+		duration = int(sshDuration.Milliseconds())
+	case model.MonitorTypeUDP:
+		// UDP 复用 ResponseRegex 校验响应内容（hex 模式下匹配十六进制编码后的响应）
+		var rtt time.Duration
+		status, msg, rtt = CheckUDP(m.URL, m.Timeout, m.UDPPayload, m.UDPPayloadFormat, m.ResponseRegex)
+		duration = int(rtt.Milliseconds())
+	case model.MonitorTypeMultiStep:
+		var chainDuration time.Duration
+		status, msg, chainDuration = CheckSteps(m)
+		// 时长为整条链的总耗时（包括失败前已执行的步骤）
+		duration = int(chainDuration.Milliseconds())
 	default:
 		// Default to HTTP if unknown or fallback
 		if m.Type == "" {
-			status, msg = CheckHTTP(m)
+			status, msg = checkHTTP(m, dbg, remoteIP)
 			duration = int(time.Since(startTime).Milliseconds())
 		} else {
 			status, msg = model.StatusDown, fmt.Sprintf("Unsupported type: %s", m.Type)
 			duration = 0
 		}
 	}
+	return status, msg, duration
+}
+
+// recordResult 保存检查结果：更新监控状态、写入心跳、推送前端并交给通知 worker
+func (s *Service) recordResult(m model.Monitor, status int, msg string, duration int) {
+	prevStatus := m.Status
 
 	// Always update DB with raw status
 	m.Status = status
@@ -655,18 +1260,22 @@ func (s *Service) Check(id uint) {
 		Time:      m.LastCheck,
 		Duration:  duration,
 	}
-	db.AddHeartbeat(&heartbeat)
+	s.persistHeartbeat(m, &heartbeat)
+	s.recordStatusChange(m, prevStatus, &heartbeat)
+	s.recordCheckMetrics(&heartbeat)
 
-	// Notify via callback (Socket.IO)
+	// Notify via callback (Socket.IO), every check even when sampled
 	if s.OnHeartbeat != nil {
 		s.OnHeartbeat(&heartbeat)
 	}
+	s.dispatchWebhook(m, prevStatus, &heartbeat)
 
 	// Send to Notification Worker
 	select {
 	case s.checkResultChannel <- &CheckResult{
 		MonitorID: m.ID,
 		Name:      m.Name,
+		Type:      string(m.Type),
 		URL:       m.URL,
 		Status:    status,
 		Message:   msg,
@@ -675,6 +1284,8 @@ func (s *Service) Check(id uint) {
 		logger.Warn("Check result channel full, dropping result")
 	}
 
+	s.maybeRunDiagnostics(m, prevStatus, status)
+
 	logger.Info("Check finished",
 		zap.String("name", m.Name),
 		zap.Int("status", status),
@@ -682,96 +1293,113 @@ func (s *Service) Check(id uint) {
 	)
 }
 
-func statusToString(status int) string {
-	switch status {
-	case model.StatusUp:
-		return "UP"
-	case model.StatusDown:
-		return "DOWN"
-	case model.StatusPending:
-		return "PENDING"
-	default:
-		return "UNKNOWN"
+// recordStatusChange 状态与上一次不同时记录一条状态变化并通知状态页订阅者；新监控项的第一次结果（PENDING → 其他）不记录
+func (s *Service) recordStatusChange(m model.Monitor, prevStatus int, h *model.Heartbeat) {
+	if h.Status == prevStatus || prevStatus == model.StatusPending {
+		return
 	}
+	db.RecordStatusEvent(&model.StatusEvent{
+		MonitorID: m.ID, Status: h.Status, PreviousStatus: prevStatus, Message: h.Message, Time: h.Time,
+	})
+	s.notifyStatusSubscribers(m, prevStatus, h)
 }
 
-var defaultTransport = &http.Transport{
-	MaxIdleConns:        100,
-	MaxIdleConnsPerHost: 10,
-	IdleConnTimeout:     90 * time.Second,
-	DisableKeepAlives:   false,
-	DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-		dialer := &net.Dialer{
-			Timeout:   0,                // Rely on context timeout
-			KeepAlive: 30 * time.Second, // Keep-alive is fine to stay at 30s as it doesn't affect detection timeout
-			Resolver:  getCustomResolver(),
-		}
-		return dialer.DialContext(ctx, network, addr)
-	},
+// NotifySubject 按 lang 格式化通知主题，主题文案的第一个占位符为站点名称（设置项 siteName，修改后立即生效）
+func NotifySubject(lang, key string, args ...any) string {
+	return fmt.Sprintf(i18n.T(lang, key), append([]any{db.SiteName()}, args...)...)
 }
 
-func getCustomResolver() *net.Resolver {
-	dnsServer := config.GlobalConfig.Monitor.DNSServer
+// statusToString 返回通知和检查事件中使用的状态名（规范 key 的大写形式，如 UP、DOWN）
+func statusToString(status int) string {
+	return strings.ToUpper(model.StatusKey(status))
+}
+
+var defaultTransport = newTransport(model.IPVersionAuto)
+
+// newTransport 创建 HTTP Transport；ipVersion 为 ipv4/ipv6 时只拨号对应地址族。
+// 每种地址族使用独立的 Transport，避免连接池复用另一地址族的连接。
+func newTransport(ipVersion string) *http.Transport {
+	return &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		DisableKeepAlives:   false,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialer := &net.Dialer{
+				Timeout:   0,                // Rely on context timeout
+				KeepAlive: 30 * time.Second, // Keep-alive is fine to stay at 30s as it doesn't affect detection timeout
+				Resolver:  getCustomResolver(),
+			}
+			return dialer.DialContext(ctx, ipNetwork(network, ipVersion), addr)
+		},
+	}
+}
 
+// getCustomResolver 返回检查使用的解析器：配置了 monitor.dns_server 时使用该服务器，否则依次尝试 1.1.1.1 和 223.5.5.5；
+// 连续查询失败进入回退后返回系统解析器，直到探测到自定义 DNS 恢复
+func getCustomResolver() *net.Resolver {
+	if ResolverFallbackActive() {
+		return systemResolver
+	}
 	return &net.Resolver{
 		PreferGo: true,
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: 2 * time.Second,
-			}
-
-			// If user configured a specific DNS, use it
-			if dnsServer != "" {
-				addr := dnsServer
-				if !strings.Contains(addr, ":") {
-					addr += ":53"
-				}
-				return d.DialContext(ctx, "udp", addr)
-			}
-
-			// Default logic: Try Google DNS first, then Alidns
-			conn, err := d.DialContext(ctx, "udp", "1.1.1.1:53")
-			if err == nil {
-				return conn, nil
+			conn, err := dialConfiguredDNS(ctx)
+			if err != nil {
+				return nil, err
 			}
-
-			// Fallback to Alidns
-			return d.DialContext(ctx, "udp", "223.5.5.5:53")
+			return &trackedConn{UDPConn: conn, ctx: ctx}, nil
 		},
 	}
 }
 
 var (
-	httpClient           *http.Client
-	httpClientNoRedirect *http.Client
-	httpClientOnce       sync.Once
+	httpClients    map[string]*http.Client
+	httpClientOnce sync.Once
 )
 
 func initHTTPClients() {
 	httpClientOnce.Do(func() {
-		httpClient = &http.Client{
-			Transport: defaultTransport,
-			Timeout:   600 * time.Second, // 10 minutes max as safety net (actual timeout via context)
-		}
-		httpClientNoRedirect = &http.Client{
-			Transport: defaultTransport,
-			Timeout:   600 * time.Second, // 10 minutes max as safety net
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
+		httpClients = make(map[string]*http.Client)
+		for _, ipVersion := range []string{model.IPVersionAuto, model.IPVersion4, model.IPVersion6} {
+			transport := defaultTransport
+			if ipVersion != model.IPVersionAuto {
+				transport = newTransport(ipVersion)
+			}
+			httpClients[ipVersion+"/follow"] = &http.Client{
+				Transport: transport,
+				Timeout:   600 * time.Second, // 10 minutes max as safety net (actual timeout via context)
+			}
+			httpClients[ipVersion+"/noredirect"] = &http.Client{
+				Transport: transport,
+				Timeout:   600 * time.Second, // 10 minutes max as safety net
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
 		}
 	})
 }
 
-func getHTTPClient(followRedirects bool) *http.Client {
+func getHTTPClient(followRedirects bool, ipVersion string) *http.Client {
 	initHTTPClients()
+	if ipVersion != model.IPVersion4 && ipVersion != model.IPVersion6 {
+		ipVersion = model.IPVersionAuto
+	}
 	if followRedirects {
-		return httpClient
+		return httpClients[ipVersion+"/follow"]
 	}
-	return httpClientNoRedirect
+	return httpClients[ipVersion+"/noredirect"]
 }
 
 func CheckHTTP(m model.Monitor) (int, string) {
+	return checkHTTP(m, nil, nil)
+}
+
+// checkHTTP performs the HTTP check; when dbg is non-nil the request/response detail is captured into it,
+// and when remoteIP is non-nil it receives the IP address actually connected to.
+// Pre/post hooks run around the main request and share its timeout budget.
+func checkHTTP(m model.Monitor, dbg *DebugInfo, remoteIP *string) (int, string) {
 	timeout := m.Timeout
 	if timeout <= 0 {
 		timeout = 10
@@ -780,6 +1408,33 @@ func CheckHTTP(m model.Monitor) (int, string) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
 
+	vars, hookMsg := runPreHook(ctx, m)
+	if hookMsg != "" {
+		return model.StatusDown, hookMsg
+	}
+
+	status, msg := checkHTTPRequest(ctx, m, vars, dbg, remoteIP)
+
+	// 后置钩子（清理）无论主检查结果如何都会执行，但只在主检查成功时影响结果
+	if hookMsg := runPostHook(ctx, m, vars); hookMsg != "" && status == model.StatusUp {
+		status, msg = model.StatusDown, hookMsg
+	}
+
+	// 运行时提取的变量（如 CSRF token、会话 ID）不能出现在心跳和调试信息中
+	if dbg != nil && len(vars) > 0 {
+		for k, values := range dbg.RequestHeaders {
+			for i, v := range values {
+				values[i] = vars.redact(v)
+			}
+			dbg.RequestHeaders[k] = values
+		}
+		dbg.BodyPreview = vars.redact(dbg.BodyPreview)
+	}
+	return status, vars.redact(msg)
+}
+
+// checkHTTPRequest 执行主请求，Headers、Body 和表单字段中的 {{name}} 会替换为前置钩子提取的变量
+func checkHTTPRequest(ctx context.Context, m model.Monitor, vars hookVars, dbg *DebugInfo, remoteIP *string) (int, string) {
 	method := m.Method
 	if method == "" {
 		method = "GET"
@@ -824,7 +1479,7 @@ func CheckHTTP(m model.Monitor) (int, string) {
 						return model.StatusDown, fmt.Sprintf("Copy file content failed: %v", err)
 					}
 				} else {
-					_ = writer.WriteField(field.Key, field.Value)
+					_ = writer.WriteField(field.Key, vars.expand(field.Value))
 				}
 			}
 			writer.Close()
@@ -834,15 +1489,31 @@ func CheckHTTP(m model.Monitor) (int, string) {
 	}
 
 	if body == nil && m.Body != "" {
-		body = strings.NewReader(m.Body)
+		body = strings.NewReader(vars.expand(m.Body))
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, m.URL, body)
+	// 旧数据可能保存了未规范化的地址，请求前同样规范化，错误消息中展示实际请求的地址
+	target := m.URL
+	if n, err := NormalizeURL(model.MonitorTypeHTTP, m.URL); err == nil {
+		target = n.URL
+		if n.HasUserinfo && !hasBasicAuth(m) {
+			m.BasicAuthUser, m.BasicAuthPass = n.User, n.Password
+		}
+	}
+	requested := ""
+	if target != m.URL {
+		requested = " (" + target + ")"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
 	if err != nil {
 		return model.StatusDown, fmt.Sprintf("Create request failed: %v", err)
 	}
 
-	client := getHTTPClient(m.FollowRedirects)
+	client, err := httpClientFor(m)
+	if err != nil {
+		return model.StatusDown, httpClientError(err)
+	}
 
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
@@ -858,7 +1529,7 @@ func CheckHTTP(m model.Monitor) (int, string) {
 				if contentType != "" && strings.EqualFold(k, "Content-Type") {
 					continue
 				}
-				req.Header.Set(k, v)
+				req.Header.Set(k, vars.expand(v))
 			}
 		} else {
 			// Legacy K=V format: KEY=VALUE,KEY=VALUE
@@ -869,7 +1540,7 @@ func CheckHTTP(m model.Monitor) (int, string) {
 					key := strings.TrimSpace(kv[0])
 					value := strings.TrimSpace(kv[1])
 					if key != "" {
-						req.Header.Set(key, value)
+						req.Header.Set(key, vars.expand(value))
 					}
 				}
 			}
@@ -880,41 +1551,86 @@ func CheckHTTP(m model.Monitor) (int, string) {
 	if req.Header.Get("User-Agent") == "" {
 		req.Header.Set("User-Agent", "PingGo-Monitor/1.0")
 	}
+	applyBasicAuth(req, m)
+	if err := applyOAuth(ctx, req, m); err != nil {
+		return model.StatusDown, "OAuth token error: " + redactSecret(err.Error(), m.OAuthClientSecret)
+	}
+
+	if dbg != nil {
+		dbg.Method = method
+		dbg.RequestHeaders = req.Header.Clone()
+		if hasOAuth(m) {
+			dbg.RequestHeaders["Authorization"] = []string{"Bearer ******"}
+		} else if hasBasicAuth(m) {
+			dbg.RequestHeaders["Authorization"] = []string{"Basic ******"}
+		}
+		req = withDebugTrace(req, dbg)
+	}
+	if remoteIP != nil {
+		req = withRemoteIPTrace(req, remoteIP)
+	}
 
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
 		// Simplify common errors
-		errStr := err.Error()
+		if msg, ok := proxyErrorMessage(err); ok {
+			return model.StatusDown, msg + requested
+		}
+		errStr := redactSecret(redactSecret(err.Error(), m.BasicAuthPass), proxySecret(m.ProxyURL))
 		if strings.Contains(errStr, "deadline exceeded") || strings.Contains(errStr, "Client.Timeout") {
-			return model.StatusDown, "Timeout"
+			return model.StatusDown, "Timeout" + requested
 		}
 		if strings.Contains(errStr, "connection refused") {
-			return model.StatusDown, "Connection Refused"
+			return model.StatusDown, "Connection Refused" + requested
 		}
-		if strings.Contains(errStr, "no such host") {
-			return model.StatusDown, "DNS Resolution Failed"
+		if isResolutionError(errStr) {
+			return model.StatusDown, "DNS Resolution Failed: " + req.URL.Hostname()
 		}
 		if strings.Contains(errStr, "remote error: tls") {
-			return model.StatusDown, "TLS Error"
+			if hasClientCert(m) {
+				return model.StatusDown, "TLS Error (client cert rejected)" + requested
+			}
+			return model.StatusDown, "TLS Error" + requested
 		}
 		// Truncate long error messages
 		if len(errStr) > 40 {
-			return model.StatusDown, errStr[:37] + "..."
+			return model.StatusDown, errStr[:37] + "..." + requested
 		}
-		return model.StatusDown, errStr
+		return model.StatusDown, errStr + requested
+	}
+	defer drainAndClose(resp.Body)
+	respBody := decodedBody(resp)
+
+	// 目标返回 401 时令牌可能已被吊销，丢弃缓存，下次检查重新申请
+	if resp.StatusCode == http.StatusUnauthorized && hasOAuth(m) {
+		InvalidateOAuthToken(m.ID)
+	}
+
+	if dbg != nil {
+		dbg.StatusCode = resp.StatusCode
+		dbg.ResponseHeaders = resp.Header.Clone()
+		// 预读前 2KB（解压后）用于调试展示，再拼接回响应体以免影响后续的正则校验
+		head, _ := readBody(respBody, debugBodyLimit)
+		dbg.BodyPreview = redactSecret(string(head), m.BasicAuthPass)
+		respBody = io.MultiReader(bytes.NewReader(head), respBody)
 	}
-	defer resp.Body.Close()
 
-	// Check Status
 	// Check Status
 	statusOk := true
 	var errorMsg string
 
+	if resp.StatusCode == http.StatusProxyAuthRequired && m.ProxyURL != "" {
+		return model.StatusDown, "Proxy Error (HTTP 407 Proxy Authentication Required)"
+	}
+
 	if m.ExpectedStatus > 0 {
 		if resp.StatusCode != m.ExpectedStatus {
 			statusOk = false
 			errorMsg = fmt.Sprintf("Status %d (Expected %d)", resp.StatusCode, m.ExpectedStatus)
 		}
+	} else if !m.FollowRedirects && hasRedirectAssertion(m) {
+		// 不跟随重定向时由 checkRedirect 校验 3xx 状态码
 	} else {
 		// Default 2xx check
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
@@ -925,6 +1641,7 @@ func CheckHTTP(m model.Monitor) (int, string) {
 
 	// Helper function for body truncation
 	truncateBody := func(b string) string {
+		b = redactSecret(b, m.BasicAuthPass)
 		maxLen := 10000
 		if len(b) > maxLen {
 			return b[:maxLen] + "...(truncated)"
@@ -936,7 +1653,7 @@ func CheckHTTP(m model.Monitor) (int, string) {
 		// Helper: If POST request fails, append body for debugging
 		if m.Method == "POST" {
 			// Read up to 10KB (enough for most error JSONs)
-			bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 10240))
+			bodyBytes, _ := readBody(respBody, 10240)
 			if len(bodyBytes) > 0 {
 				bodyStr := strings.TrimSpace(string(bodyBytes))
 				if bodyStr != "" {
@@ -947,39 +1664,104 @@ func CheckHTTP(m model.Monitor) (int, string) {
 		return model.StatusDown, errorMsg
 	}
 
-	// Check Regex
+	if ok, redirectMsg := checkRedirect(m, resp); !ok {
+		return model.StatusDown, redirectMsg
+	}
+
 	// 响应正则验证：数据库中存储的始终是正则表达式（JSON 输入已在服务端转换）
+	var re *regexp.Regexp
 	if m.ResponseRegex != "" {
-		// Read body (limit to 1MB)
-		bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
-		if err != nil {
-			return model.StatusDown, fmt.Sprintf("Read body failed: %v", err)
+		if re, err = compiledRegex(m.ResponseRegex); err != nil {
+			return model.StatusDown, fmt.Sprintf("Regex error: %v", err)
+		}
+	}
+
+	// 响应体为空（204、Content-Length: 0 或空的分块响应）时没有可校验的内容：默认跳过响应正则和引用 body/text 的表达式，
+	// 设置了 assert_on_empty_body 时直接判定为 DOWN，避免出现没有内容的“响应不匹配”
+	bodyExpr := m.Expression != "" && expressionUsesBody(m)
+	emptySkipped := false
+	if re != nil || bodyExpr {
+		var empty bool
+		empty, respBody = peekEmptyBody(resp, respBody)
+		if empty {
+			if m.AssertOnEmptyBody {
+				return model.StatusDown, fmt.Sprintf("响应体为空（HTTP %d %s），无法校验响应内容", resp.StatusCode, http.StatusText(resp.StatusCode))
+			}
+			re, emptySkipped = nil, true
 		}
-		bodyStr := string(bodyBytes)
+	}
 
-		matched, err := regexp.MatchString(m.ResponseRegex, bodyStr)
+	// 表达式需要完整的响应体（limit to monitor.max_body_bytes, default 1MB），此时正则在读取的内容上匹配；
+	// 只有正则时流式扫描，匹配成功即停止读取，最多读取 regex_scan_bytes（默认 256KB）
+	var bodyBytes []byte
+	var scan regexScan
+	switch {
+	case m.Expression != "":
+		bodyBytes, err = readBody(respBody, maxBodyBytes())
 		if err != nil {
-			return model.StatusDown, fmt.Sprintf("Regex error: %v", err)
+			return model.StatusDown, bodyReadError(err)
 		}
-		if !matched {
-			msg := "响应不匹配！"
-			if len(bodyStr) > 0 {
-				msg += fmt.Sprintf(" Body: %s", truncateBody(strings.TrimSpace(bodyStr)))
-			}
-			return model.StatusDown, msg
+		if re != nil {
+			scan = matchBytes(re, bodyBytes, regexScanLimit(m.RegexScanBytes))
+		}
+	case re != nil:
+		scan, err = scanRegex(re, respBody, regexScanLimit(m.RegexScanBytes))
+		if err != nil {
+			return model.StatusDown, bodyReadError(err)
 		}
 	}
+	duration := time.Since(start)
+
+	if re != nil && !scan.Matched {
+		msg := "响应不匹配！"
+		if scan.Truncated {
+			msg = fmt.Sprintf("响应不匹配！响应体超过扫描上限，前 %d 字节内未匹配", regexScanLimit(m.RegexScanBytes))
+		}
+		if bodyStr := strings.TrimSpace(string(scan.Head)); bodyStr != "" {
+			msg += fmt.Sprintf(" Body: %s", truncateBody(bodyStr))
+		}
+		return model.StatusDown, msg
+	}
+
+	// Check Expression
+	var exprMsg string
+	runExpr := m.Expression != "" && !(emptySkipped && bodyExpr)
+	if runExpr {
+		ok, emsg := evalExpression(m, resp, bodyBytes, duration)
+		if !ok {
+			return model.StatusDown, redactSecret(emsg, m.BasicAuthPass)
+		}
+		exprMsg = emsg
+	}
 
 	msg := fmt.Sprintf("HTTP %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
-	if m.ResponseRegex != "" {
+	if m.ResponseRegex != "" && !emptySkipped {
 		msg += "，正则匹配成功！"
 	}
-	return model.StatusUp, msg
+	if runExpr {
+		msg += "，表达式校验通过"
+		if exprMsg != "" {
+			msg += ": " + redactSecret(exprMsg, m.BasicAuthPass)
+		}
+	}
+	if hasRedirectAssertion(m) {
+		msg += "，重定向匹配成功！"
+	}
+	if emptySkipped {
+		msg += "，响应体为空，已跳过响应内容校验"
+	}
+	return model.StatusUp, msg + ipVersionSuffix(m.IPVersion)
 }
 
-func CheckPing(addr string, timeoutSec int) (int, string, time.Duration) {
-	pinger, err := probing.NewPinger(addr)
-	if err != nil {
+func CheckPing(addr string, timeoutSec int, opts PingOptions) (int, string, time.Duration) {
+	pinger := probing.New(addr)
+	if opts.IPVersion == model.IPVersion4 || opts.IPVersion == model.IPVersion6 {
+		pinger.SetNetwork(ipNetwork("ip", opts.IPVersion))
+	}
+	if err := pinger.Resolve(); err != nil {
+		if isResolutionError(err.Error()) {
+			return model.StatusDown, "DNS Resolution Failed", 0
+		}
 		return model.StatusDown, fmt.Sprintf("Init ping failed: %v", err), 0
 	}
 
@@ -990,16 +1772,31 @@ func CheckPing(addr string, timeoutSec int) (int, string, time.Duration) {
 		pinger.SetPrivileged(true)
 	}
 
-	pinger.Count = 3
-	pinger.Interval = 100 * time.Millisecond // Reduce wait between packets
+	count := opts.Count
+	if count <= 0 {
+		count = DefaultPingCount
+	}
+	if count > MaxPingCount {
+		count = MaxPingCount
+	}
+	pinger.Count = count
+	pinger.Interval = pingPacketInterval // Reduce wait between packets
+	if opts.Size > 0 {
+		pinger.Size = opts.Size
+	}
 
 	timeout := time.Duration(timeoutSec) * time.Second
 	if timeout <= 0 {
 		timeout = DefaultPingTimeout
 	}
+	// 超时按发包数量放大：最后一个包在 (count-1)*interval 之后才发出，仍需完整的等待时间
+	timeout += time.Duration(count-1) * pingPacketInterval
+	if opts.MaxDuration > 0 && timeout > opts.MaxDuration {
+		timeout = opts.MaxDuration
+	}
 	pinger.Timeout = timeout
 
-	err = pinger.Run() // blocks
+	err := pinger.Run() // blocks
 	if err != nil {
 		return model.StatusDown, fmt.Sprintf("Ping failed: %v", err), 0
 	}
@@ -1010,31 +1807,52 @@ func CheckPing(addr string, timeoutSec int) (int, string, time.Duration) {
 	}
 
 	msg := fmt.Sprintf("%.2f ms", float64(stats.AvgRtt.Microseconds())/1000.0)
+	if opts.MaxLossPercent > 0 && stats.PacketLoss > float64(opts.MaxLossPercent) {
+		msg += fmt.Sprintf(" (%.0f%% loss, exceeds %d%%)", stats.PacketLoss, opts.MaxLossPercent)
+		return model.StatusDown, msg, stats.AvgRtt
+	}
 	if stats.PacketLoss > 0 {
 		msg += fmt.Sprintf(" (%.0f%% loss)", stats.PacketLoss)
 	}
 
-	return model.StatusUp, msg, stats.AvgRtt
+	return model.StatusUp, msg + ipVersionSuffix(opts.IPVersion), stats.AvgRtt
 }
 
-func CheckTCP(addr string, timeoutSec int) (int, string, time.Duration) {
+// CheckTCP 检查端口是否可连接；proxyURL 非空时经由 SOCKS5 代理连接（目标由代理端解析，IP 版本设置不生效）
+func CheckTCP(addr string, timeoutSec int, ipVersion, proxyURL string) (int, string, time.Duration) {
 	timeout := time.Duration(timeoutSec) * time.Second
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
-	dialer := net.Dialer{
-		Timeout:  timeout,
-		Resolver: getCustomResolver(),
-	}
+	var conn net.Conn
+	var err error
 	start := time.Now()
-	conn, err := dialer.Dial("tcp", addr)
+	if proxyURL != "" {
+		conn, err = dialTCPViaProxy(proxyURL, addr, timeout)
+	} else {
+		dialer := net.Dialer{
+			Timeout:  timeout,
+			Resolver: getCustomResolver(),
+		}
+		conn, err = dialer.Dial(ipNetwork("tcp", ipVersion), addr)
+	}
 	duration := time.Since(start)
 
 	if err != nil {
-		errStr := err.Error()
+		if msg, ok := proxyErrorMessage(err); ok {
+			return model.StatusDown, msg, 0
+		}
+		errStr := redactSecret(err.Error(), proxySecret(proxyURL))
+		if proxyURL != "" && !strings.Contains(errStr, "socks connect") {
+			// 代理地址无效或不是 socks5://
+			return model.StatusDown, "Proxy Error (" + errStr + ")", 0
+		}
 		if strings.Contains(errStr, "connection refused") {
 			return model.StatusDown, "Connection Refused", 0
 		}
+		if isResolutionError(errStr) {
+			return model.StatusDown, "DNS Resolution Failed", 0
+		}
 		if strings.Contains(errStr, "i/o timeout") {
 			return model.StatusDown, "Timeout", 0
 		}
@@ -1043,10 +1861,13 @@ func CheckTCP(addr string, timeoutSec int) (int, string, time.Duration) {
 	defer conn.Close()
 
 	msg := fmt.Sprintf("Port Open (%.2f ms)", float64(duration.Microseconds())/1000.0)
-	return model.StatusUp, msg, duration
+	if proxyURL != "" {
+		return model.StatusUp, msg + " [via proxy]", duration
+	}
+	return model.StatusUp, msg + ipVersionSuffix(ipVersion), duration
 }
 
-func CheckDNS(domain string, timeoutSec int) (int, string) {
+func CheckDNS(domain string, timeoutSec int, ipVersion string) (int, string) {
 	timeout := time.Duration(timeoutSec) * time.Second
 	if timeout <= 0 {
 		timeout = 10 * time.Second
@@ -1059,9 +1880,15 @@ func CheckDNS(domain string, timeoutSec int) (int, string) {
 		resolver = net.DefaultResolver
 	}
 
-	ips, err := resolver.LookupIP(ctx, "ip", domain)
+	ips, err := resolver.LookupIP(ctx, ipNetwork("ip", ipVersion), domain)
 	if err != nil {
 		errStr := err.Error()
+		if ipVersion == model.IPVersion4 && isResolutionError(errStr) {
+			return model.StatusDown, "DNS Resolution Failed (no A record)"
+		}
+		if ipVersion == model.IPVersion6 && isResolutionError(errStr) {
+			return model.StatusDown, "DNS Resolution Failed (no AAAA record)"
+		}
 		if strings.Contains(errStr, "no such host") {
 			return model.StatusDown, "Host Not Found"
 		}
@@ -1080,10 +1907,30 @@ func CheckDNS(domain string, timeoutSec int) (int, string) {
 	if len(ips) == 0 {
 		return model.StatusDown, "No IP found"
 	}
-	return model.StatusUp, fmt.Sprintf("IP: %v", ips[0])
+	return model.StatusUp, fmt.Sprintf("IP: %v", ips[0]) + ipVersionSuffix(ipVersion)
 }
 
 // TestHTTP performs a request but returns the raw status code and body for testing purposes.
+// hasBasicAuth 监控项是否配置了 HTTP Basic Auth
+func hasBasicAuth(m model.Monitor) bool {
+	return m.BasicAuthUser != "" || m.BasicAuthPass != ""
+}
+
+// applyBasicAuth 设置 Basic Auth 凭据，优先于 Headers 中手动填写的 Authorization
+func applyBasicAuth(req *http.Request, m model.Monitor) {
+	if hasBasicAuth(m) {
+		req.SetBasicAuth(m.BasicAuthUser, m.BasicAuthPass)
+	}
+}
+
+// redactSecret 从要展示或写入心跳的消息中移除密码
+func redactSecret(msg, secret string) string {
+	if secret == "" {
+		return msg
+	}
+	return strings.ReplaceAll(msg, secret, "******")
+}
+
 func TestHTTP(m model.Monitor) (int, string) {
 	timeout := m.Timeout
 	if timeout <= 0 {
@@ -1093,6 +1940,20 @@ func TestHTTP(m model.Monitor) (int, string) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
 
+	vars, hookMsg := runPreHook(ctx, m)
+	if hookMsg != "" {
+		return 0, hookMsg
+	}
+
+	code, body := testHTTPRequest(ctx, m, vars)
+	if hookMsg := runPostHook(ctx, m, vars); hookMsg != "" {
+		body = hookMsg + "\n\n" + body
+	}
+	return code, vars.redact(body)
+}
+
+// testHTTPRequest 执行 TestHTTP 的主请求
+func testHTTPRequest(ctx context.Context, m model.Monitor, vars hookVars) (int, string) {
 	method := m.Method
 	if method == "" {
 		method = "GET"
@@ -1136,7 +1997,7 @@ func TestHTTP(m model.Monitor) (int, string) {
 						return 0, fmt.Sprintf("Copy file content failed: %v", err)
 					}
 				} else {
-					_ = writer.WriteField(field.Key, field.Value)
+					_ = writer.WriteField(field.Key, vars.expand(field.Value))
 				}
 			}
 			writer.Close()
@@ -1146,7 +2007,7 @@ func TestHTTP(m model.Monitor) (int, string) {
 	}
 
 	if body == nil && m.Body != "" {
-		body = strings.NewReader(m.Body)
+		body = strings.NewReader(vars.expand(m.Body))
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, m.URL, body)
@@ -1154,7 +2015,10 @@ func TestHTTP(m model.Monitor) (int, string) {
 		return 0, fmt.Sprintf("Create request failed: %v", err)
 	}
 
-	client := getHTTPClient(m.FollowRedirects)
+	client, err := httpClientFor(m)
+	if err != nil {
+		return 0, httpClientError(err)
+	}
 
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
@@ -1169,7 +2033,7 @@ func TestHTTP(m model.Monitor) (int, string) {
 				if contentType != "" && strings.EqualFold(k, "Content-Type") {
 					continue
 				}
-				req.Header.Set(k, v)
+				req.Header.Set(k, vars.expand(v))
 			}
 		} else {
 			pairs := strings.Split(m.Headers, ",")
@@ -1179,7 +2043,7 @@ func TestHTTP(m model.Monitor) (int, string) {
 					key := strings.TrimSpace(kv[0])
 					value := strings.TrimSpace(kv[1])
 					if key != "" {
-						req.Header.Set(key, value)
+						req.Header.Set(key, vars.expand(value))
 					}
 				}
 			}
@@ -1189,18 +2053,69 @@ func TestHTTP(m model.Monitor) (int, string) {
 	if req.Header.Get("User-Agent") == "" {
 		req.Header.Set("User-Agent", "PingGo-Monitor/1.0")
 	}
+	applyBasicAuth(req, m)
+	if err := applyOAuth(ctx, req, m); err != nil {
+		return 0, "OAuth token error: " + redactSecret(err.Error(), m.OAuthClientSecret)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, err.Error()
+		if msg, ok := proxyErrorMessage(err); ok {
+			return 0, msg
+		}
+		return 0, redactSecret(redactSecret(err.Error(), m.BasicAuthPass), proxySecret(m.ProxyURL))
+	}
+	defer drainAndClose(resp.Body)
+
+	if ok, redirectMsg := checkRedirect(m, resp); !ok {
+		return resp.StatusCode, redirectMsg
 	}
-	defer resp.Body.Close()
 
 	// Read body (limit to 50KB for test preview)
-	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, 51200))
+	bodyBytes, err := readBody(decodedBody(resp), 51200)
 	if err != nil {
-		return resp.StatusCode, fmt.Sprintf("Read body failed: %v", err)
+		return resp.StatusCode, bodyReadError(err)
+	}
+
+	return resp.StatusCode, redactSecret(string(bodyBytes), m.BasicAuthPass)
+}
+
+// shortDuration 精确到秒的时长，省略为 0 的单位，如 3m12s、2m、1h5s
+func shortDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d <= 0 {
+		return "0s"
 	}
+	var b strings.Builder
+	if h := int(d.Hours()); h > 0 {
+		fmt.Fprintf(&b, "%dh", h)
+	}
+	if m := int(d.Minutes()) % 60; m > 0 {
+		fmt.Fprintf(&b, "%dm", m)
+	}
+	if sec := int(d.Seconds()) % 60; sec > 0 {
+		fmt.Fprintf(&b, "%ds", sec)
+	}
+	return b.String()
+}
 
-	return resp.StatusCode, string(bodyBytes)
+// formatDownSince 通知中的宕机开始时间（显示时区，精确到分钟），零值为空
+func formatDownSince(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(db.Location()).Format("2006-01-02 15:04")
+}
+
+// FormatDowntime 将宕机时长格式化为 "2h 15m"、"1d 3h" 或 "45m"，也用于状态页中的事件持续时间
+func FormatDowntime(d time.Duration) string {
+	minutes := int(d.Minutes())
+	switch {
+	case minutes >= 24*60:
+		return fmt.Sprintf("%dd %dh", minutes/(24*60), minutes%(24*60)/60)
+	case minutes >= 60:
+		return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
 }
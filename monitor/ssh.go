@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"ping-go/model"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// errHostKeyCaptured 在密钥交换完成、拿到主机公钥后主动中断握手，确保不会发起任何认证
+var errHostKeyCaptured = errors.New("host key captured")
+
+// SSHResult holds what was learned from an SSH endpoint without authenticating
+type SSHResult struct {
+	Banner      string
+	HostKeyType string
+	Fingerprint string // SHA256 fingerprint, e.g. SHA256:abc...
+	legacyMD5   string
+	Duration    time.Duration
+}
+
+// MatchesHostKey compares the captured key against an expected fingerprint.
+// Both "SHA256:..." and legacy MD5 ("MD5:aa:bb:..." or "aa:bb:...") formats are accepted.
+func (r *SSHResult) MatchesHostKey(expected string) bool {
+	expected = strings.TrimSpace(expected)
+	if strings.HasPrefix(expected, "SHA256:") {
+		return expected == r.Fingerprint
+	}
+	expected = strings.TrimPrefix(strings.ToLower(expected), "md5:")
+	return expected == r.legacyMD5
+}
+
+// QuerySSH connects to an SSH endpoint, reads the protocol banner and, when withHostKey is set,
+// performs the key exchange to capture the server host key. No authentication is attempted.
+func QuerySSH(addr string, timeoutSec int, withHostKey bool) (*SSHResult, error) {
+	timeout := time.Duration(timeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "22")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	dialer := net.Dialer{Resolver: getCustomResolver()}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	// 读取协议标识行（RFC 4253 允许在 "SSH-" 行之前发送其他文本行）
+	reader := bufio.NewReader(conn)
+	var banner string
+	for i := 0; i < 20; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read banner: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			banner = line
+			break
+		}
+	}
+	if banner == "" {
+		return nil, errors.New("no SSH banner received")
+	}
+
+	result := &SSHResult{Banner: banner}
+	if !withHostKey {
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+
+	// ssh 库会自行读取服务端标识，因此把已读取的标识行重新拼接回连接
+	replay := &replayConn{Conn: conn, r: io.MultiReader(strings.NewReader(banner+"\r\n"), reader)}
+	config := &ssh.ClientConfig{
+		User: "pinggo",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			result.HostKeyType = key.Type()
+			result.Fingerprint = ssh.FingerprintSHA256(key)
+			result.legacyMD5 = ssh.FingerprintLegacyMD5(key)
+			return errHostKeyCaptured
+		},
+		Timeout: timeout,
+	}
+	_, _, _, err = ssh.NewClientConn(replay, addr, config)
+	if result.Fingerprint == "" {
+		if err == nil {
+			err = errors.New("no host key received")
+		}
+		return nil, fmt.Errorf("key exchange: %w", err)
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// CheckSSH reads the SSH banner and optionally verifies it against bannerRegex and
+// the host key against expectedHostKey. A changed host key is reported as "Host key mismatch".
+func CheckSSH(addr string, timeoutSec int, bannerRegex, expectedHostKey string) (int, string, time.Duration) {
+	result, err := QuerySSH(addr, timeoutSec, expectedHostKey != "")
+	status, msg := EvaluateSSH(result, err, bannerRegex, expectedHostKey)
+	if result == nil || status != model.StatusUp {
+		return status, msg, 0
+	}
+	return status, msg, result.Duration
+}
+
+// EvaluateSSH converts the outcome of QuerySSH into a monitor status and heartbeat message.
+func EvaluateSSH(result *SSHResult, err error, bannerRegex, expectedHostKey string) (int, string) {
+	if err != nil {
+		errStr := err.Error()
+		if strings.Contains(errStr, "connection refused") {
+			return model.StatusDown, "Connection Refused"
+		}
+		if strings.Contains(errStr, "i/o timeout") || strings.Contains(errStr, "deadline exceeded") {
+			return model.StatusDown, "Timeout"
+		}
+		if strings.Contains(errStr, "no such host") {
+			return model.StatusDown, "DNS Resolution Failed"
+		}
+		if len(errStr) > 40 {
+			return model.StatusDown, errStr[:37] + "..."
+		}
+		return model.StatusDown, errStr
+	}
+
+	if bannerRegex != "" {
+		matched, err := regexp.MatchString(bannerRegex, result.Banner)
+		if err != nil {
+			return model.StatusDown, fmt.Sprintf("Regex error: %v", err)
+		}
+		if !matched {
+			return model.StatusDown, fmt.Sprintf("Banner mismatch: %s", result.Banner)
+		}
+	}
+
+	if expectedHostKey != "" && !result.MatchesHostKey(expectedHostKey) {
+		return model.StatusDown, fmt.Sprintf("Host key mismatch (got %s %s)", result.HostKeyType, result.Fingerprint)
+	}
+
+	return model.StatusUp, fmt.Sprintf("%s (%.2f ms)", result.Banner, float64(result.Duration.Microseconds())/1000.0)
+}
+
+// replayConn 先返回已缓冲的数据，再继续从底层连接读取
+type replayConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
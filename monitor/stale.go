@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"time"
+)
+
+// staleIntervals 超过多少个检查间隔没有新结果视为监控项停滞
+const staleIntervals = 3
+
+// checkStaleMonitors 检查已启用的监控项是否长时间没有新的检查结果（调度卡住或检查一直挂起），
+// 发现时记录系统告警。push 监控项由上报驱动，不在此检查
+func (s *Service) checkStaleMonitors() {
+	s.mu.Lock()
+	ids := make([]uint, 0, len(s.monitors))
+	for id := range s.monitors {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	var monitors []model.Monitor
+	if err := db.DB.Where("id IN ? AND active = ? AND type != ?", ids, 1, model.MonitorTypePush).Find(&monitors).Error; err != nil {
+		return
+	}
+	now := time.Now()
+	for _, m := range monitors {
+		if m.LastCheck.IsZero() || m.Interval <= 0 {
+			continue
+		}
+		limit := time.Duration(staleIntervals*m.Interval)*time.Second + time.Minute
+		if since := now.Sub(m.LastCheck); since > limit {
+			db.RaiseAlert(model.AlertSeverityWarning, "Monitor stopped reporting: "+m.Name,
+				fmt.Sprintf("No check result for %s (interval %ds, last check %s)",
+					since.Round(time.Second), m.Interval, m.LastCheck.Format("2006-01-02 15:04:05")))
+		}
+	}
+}
+
+// checkStaleAgents 把超过 model.AgentOfflineAfter 没有请求的探针标记为离线并记录系统告警，
+// 每个探针离线期间只告警一次，再次上报时由服务端清除标记。从未上报过的探针不检查
+func (s *Service) checkStaleAgents() {
+	var agents []model.Agent
+	cutoff := time.Now().Add(-model.AgentOfflineAfter)
+	if err := db.DB.Where("offline = ? AND last_seen IS NOT NULL AND last_seen < ?", false, cutoff).Find(&agents).Error; err != nil {
+		return
+	}
+	for _, a := range agents {
+		res := db.DB.Model(&model.Agent{}).Where("id = ? AND offline = ?", a.ID, false).Update("offline", true)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		db.RaiseAlert(model.AlertSeverityWarning, "Probe agent stopped reporting: "+a.Name,
+			fmt.Sprintf("No request from agent %s (region %s) since %s", a.Name, a.Region, a.LastSeen.Format("2006-01-02 15:04:05")))
+	}
+}
@@ -0,0 +1,241 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// startupPageSize 启动时每次从数据库加载的监控项数
+	startupPageSize = 200
+	// defaultStartupConcurrency 启动时并发执行首次检查的数量（monitor.startup_concurrency）
+	defaultStartupConcurrency = 16
+	// startupProgressEvery 每完成多少个监控项的首次检查推送一次进度
+	startupProgressEvery = 50
+)
+
+// StartupProgress 启动进度，出现在 /health 中
+type StartupProgress struct {
+	Starting  bool      `json:"starting"`
+	Scheduled int       `json:"scheduled"` // 已完成首次检查、进入正常调度的监控项数
+	Total     int       `json:"total"`     // 启动开始时启用的监控项数
+	StartedAt time.Time `json:"started_at"`
+}
+
+// OnStartupProgress 启动进度回调（用于向管理员推送 startupProgress），每完成一批首次检查以及启动结束时调用
+var OnStartupProgress func(p StartupProgress)
+
+// startupState 当前这一次启动的进度。gen 在每次 Start 时递增，被取消的旧启动不再更新进度
+type startupState struct {
+	mu       sync.Mutex
+	gen      uint64
+	cancel   context.CancelFunc
+	progress StartupProgress
+}
+
+// startupJob 等待工作池执行首次检查的监控项
+type startupJob struct {
+	id       uint
+	name     string
+	interval time.Duration
+	first    chan struct{} // 首次检查完成后关闭，调度 goroutine 随后按间隔检查
+	stop     chan struct{} // 调度的停止通道，排队期间被停止或重新调度时跳过检查
+}
+
+// startupConcurrency 启动工作池大小，未配置时为 defaultStartupConcurrency
+func startupConcurrency() int {
+	if n := config.Get().Monitor.StartupConcurrency; n > 0 {
+		return n
+	}
+	return defaultStartupConcurrency
+}
+
+// Start 在后台分批加载并调度所有启用的监控项，立即返回，不阻塞 HTTP 服务。
+// 停机前为 DOWN 的监控项排在最前；首次检查通过大小有限的工作池执行，避免上千个监控项在启动时同时发起请求。
+// 再次调用（如重新当选领导者）会取消尚未完成的上一次启动；已在调度中的监控项不会重复登记
+func (s *Service) Start() {
+	s.scheduleAllMaintenanceEnds()
+
+	var total int64
+	if err := db.DB.Model(&model.Monitor{}).Where("active = ?", 1).Count(&total).Error; err != nil {
+		logger.Error("Failed to count monitors", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.startup.mu.Lock()
+	if s.startup.cancel != nil {
+		s.startup.cancel()
+	}
+	s.startup.gen++
+	gen := s.startup.gen
+	s.startup.cancel = cancel
+	s.startup.progress = StartupProgress{Starting: true, Total: int(total), StartedAt: time.Now()}
+	progress := s.startup.progress
+	s.startup.mu.Unlock()
+
+	logger.Info("Scheduling monitors", zap.Int64("total", total), zap.Int("concurrency", startupConcurrency()))
+	if OnStartupProgress != nil {
+		OnStartupProgress(progress)
+	}
+	go s.runStartup(ctx, gen)
+}
+
+// GetStartupProgress 返回启动进度
+func (s *Service) GetStartupProgress() StartupProgress {
+	s.startup.mu.Lock()
+	defer s.startup.mu.Unlock()
+	return s.startup.progress
+}
+
+// cancelStartup 取消进行中的启动（停止服务或失去租约时），已登记的监控项由调用方停止
+func (s *Service) cancelStartup() {
+	s.startup.mu.Lock()
+	defer s.startup.mu.Unlock()
+	if s.startup.cancel != nil {
+		s.startup.cancel()
+		s.startup.cancel = nil
+	}
+	s.startup.progress.Starting = false
+}
+
+// runStartup 分页加载监控项并交给工作池执行首次检查
+func (s *Service) runStartup(ctx context.Context, gen uint64) {
+	workers := startupConcurrency()
+	jobs := make(chan startupJob, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				s.runStartupCheck(gen, job)
+			}
+		}()
+	}
+
+	err := s.loadStartupPages(ctx, gen, jobs)
+	close(jobs)
+	wg.Wait()
+	s.finishStartup(gen, err)
+}
+
+// loadStartupPages 先加载停机前为 DOWN 的监控项，再加载其他监控项，均按 ID 分页（keyset，不受期间增删影响）。
+// 每个监控项先登记调度，再排队等待首次检查；队列满时暂停加载
+func (s *Service) loadStartupPages(ctx context.Context, gen uint64, jobs chan<- startupJob) error {
+	seen := make(map[uint]bool)
+	for _, down := range []bool{true, false} {
+		var lastID uint
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			query := db.DB.Where("active = ? AND id > ?", 1, lastID)
+			if down {
+				query = query.Where("status = ?", model.StatusDown)
+			} else {
+				query = query.Where("status <> ?", model.StatusDown)
+			}
+			var page []model.Monitor
+			if err := query.Order("id ASC").Limit(startupPageSize).Find(&page).Error; err != nil {
+				return err
+			}
+
+			for i := range page {
+				m := &page[i]
+				// 首次检查可能已把监控项从 DOWN 改为 UP，第二轮不再重复调度
+				if seen[m.ID] {
+					continue
+				}
+				seen[m.ID] = true
+
+				// 启动期间已被手动启动或编辑的监控项已在调度中，不再重复登记
+				s.mu.Lock()
+				_, running := s.tickers[m.ID]
+				s.mu.Unlock()
+				if running {
+					s.startupScheduled(gen)
+					continue
+				}
+
+				first := make(chan struct{})
+				stop := s.startMonitor(m, 0, first)
+				if stop == nil {
+					s.startupScheduled(gen)
+					continue
+				}
+				// 已登记的监控项一定会入队：启动被取消时工作池仍会完成已入队的首次检查
+				jobs <- startupJob{id: m.ID, name: m.Name, interval: time.Duration(m.Interval) * time.Second, first: first, stop: stop}
+			}
+			if len(page) < startupPageSize {
+				break
+			}
+			lastID = page[len(page)-1].ID
+		}
+	}
+	return nil
+}
+
+// runStartupCheck 执行一个监控项的首次检查，完成后放行其调度 goroutine。
+// 监控项在排队期间被停止或重新调度（包括停止服务、失去租约）时不检查
+func (s *Service) runStartupCheck(gen uint64, job startupJob) {
+	defer close(job.first)
+	select {
+	case <-job.stop:
+	default:
+		s.recordCheckStart(job.id, job.name, job.interval)
+		s.Check(job.id)
+	}
+	s.startupScheduled(gen)
+}
+
+// startupScheduled 记录一个监控项进入正常调度，每 startupProgressEvery 个推送一次进度
+func (s *Service) startupScheduled(gen uint64) {
+	s.startup.mu.Lock()
+	if gen != s.startup.gen || !s.startup.progress.Starting {
+		s.startup.mu.Unlock()
+		return
+	}
+	s.startup.progress.Scheduled++
+	progress := s.startup.progress
+	s.startup.mu.Unlock()
+
+	if progress.Scheduled%startupProgressEvery == 0 && OnStartupProgress != nil {
+		OnStartupProgress(progress)
+	}
+}
+
+// finishStartup 结束启动：记录日志并推送最终进度。启动期间新增的监控项可能使已调度数超过开始时的总数
+func (s *Service) finishStartup(gen uint64, err error) {
+	s.startup.mu.Lock()
+	if gen != s.startup.gen {
+		s.startup.mu.Unlock()
+		return
+	}
+	s.startup.cancel = nil
+	s.startup.progress.Starting = false
+	s.startup.progress.Total = max(s.startup.progress.Total, s.startup.progress.Scheduled)
+	progress := s.startup.progress
+	s.startup.mu.Unlock()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		logger.Info("Monitor scheduling cancelled", zap.Int("scheduled", progress.Scheduled))
+		return
+	case err != nil:
+		logger.Error("Failed to load monitors", zap.Error(err), zap.Int("scheduled", progress.Scheduled))
+	default:
+		logger.Info("All monitors scheduled", zap.Int("scheduled", progress.Scheduled),
+			zap.Duration("elapsed", time.Since(progress.StartedAt).Round(time.Millisecond)))
+	}
+	if OnStartupProgress != nil {
+		OnStartupProgress(progress)
+	}
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestService 在临时数据库上创建监控服务，日志丢弃，测试结束时停止服务并关闭数据库
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	logger.Logger = zap.NewNop()
+	if db.DB == nil {
+		t.Fatal("database not initialized")
+	}
+	s := NewService()
+	t.Cleanup(s.StopAll)
+	return s
+}
+
+func TestStartSkipsSoftDeletedMonitors(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	path := filepath.Join(t.TempDir(), "pinggo.db")
+	if err := db.Init(path); err != nil {
+		t.Fatal(err)
+	}
+	stale := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: target.URL, Interval: 60, Active: 1}
+	db.DB.Create(&stale)
+	// 旧库中软删除留下的同名行
+	db.DB.Exec("ALTER TABLE monitors ADD COLUMN deleted_at datetime")
+	db.DB.Exec("UPDATE monitors SET deleted_at = ? WHERE id = ?", time.Now(), stale.ID)
+	db.Close()
+
+	if err := db.Init(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	live := model.Monitor{Name: "api", Type: model.MonitorTypeHTTP, URL: target.URL, Interval: 60, Active: 1}
+	if err := db.DB.Create(&live).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService(t)
+	s.Start()
+	deadline := time.Now().Add(10 * time.Second)
+	for s.GetStartupProgress().Starting {
+		if time.Now().After(deadline) {
+			t.Fatal("startup did not finish")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if p := s.GetStartupProgress(); p.Total != 1 || p.Scheduled != 1 {
+		t.Fatalf("progress = %+v, want exactly the live monitor scheduled", p)
+	}
+	s.mu.Lock()
+	_, liveRunning := s.tickers[live.ID]
+	_, staleRunning := s.tickers[stale.ID]
+	s.mu.Unlock()
+	if !liveRunning || staleRunning {
+		t.Fatalf("scheduled live=%v stale=%v, want only the live monitor", liveRunning, staleRunning)
+	}
+	var n int64
+	db.DB.Model(&model.Monitor{}).Where("name = ?", "api").Count(&n)
+	if n != 1 {
+		t.Fatalf("%d monitors named api, want 1", n)
+	}
+}
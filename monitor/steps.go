@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ping-go/model"
+	"strings"
+	"time"
+)
+
+// MaxSteps 多步骤监控允许的最大步骤数
+const MaxSteps = 20
+
+// StepSpec 多步骤监控中的一个请求：在 HookSpec 的基础上增加名称和期望状态码（0 表示任意 2xx）。
+// 每一步提取的变量可以在之后的步骤中以 {{name}} 引用
+type StepSpec struct {
+	Name string `json:"name"`
+	HookSpec
+	ExpectedStatus int `json:"expected_status"`
+}
+
+// label 返回用于消息的步骤描述，如 "Step 2 (login)"
+func (st *StepSpec) label(i int) string {
+	if st.Name != "" {
+		return fmt.Sprintf("Step %d (%s)", i+1, st.Name)
+	}
+	return fmt.Sprintf("Step %d", i+1)
+}
+
+// ParseSteps 解析并校验多步骤配置
+func ParseSteps(raw string) ([]StepSpec, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, errors.New("at least one step is required")
+	}
+	var steps []StepSpec
+	if err := json.Unmarshal([]byte(raw), &steps); err != nil {
+		return nil, fmt.Errorf("invalid steps JSON: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("at least one step is required")
+	}
+	if len(steps) > MaxSteps {
+		return nil, fmt.Errorf("too many steps (max %d)", MaxSteps)
+	}
+	for i := range steps {
+		st := &steps[i]
+		if err := st.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", st.label(i), err)
+		}
+		if st.ExpectedStatus != 0 && (st.ExpectedStatus < 100 || st.ExpectedStatus > 599) {
+			return nil, fmt.Errorf("%s: invalid expected_status %d", st.label(i), st.ExpectedStatus)
+		}
+	}
+	return steps, nil
+}
+
+// CheckSteps 依次执行多步骤监控的全部请求，整条链共享监控项的超时时间。
+// 任一步骤失败即判定为 DOWN，消息中包含步骤序号和错误；返回的时长为整条链的耗时
+func CheckSteps(m model.Monitor) (int, string, time.Duration) {
+	start := time.Now()
+	steps, err := ParseSteps(m.Steps)
+	if err != nil {
+		return model.StatusDown, "Invalid steps: " + err.Error(), 0
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 10
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	client, err := httpClientFor(m)
+	if err != nil {
+		return model.StatusDown, httpClientError(err), 0
+	}
+
+	vars := hookVars{}
+	for i := range steps {
+		if err := runHook(ctx, client, &steps[i].HookSpec, steps[i].ExpectedStatus, vars); err != nil {
+			return model.StatusDown, vars.redact(steps[i].label(i) + " failed: " + err.Error()), time.Since(start)
+		}
+	}
+	duration := time.Since(start)
+	return model.StatusUp, fmt.Sprintf("%d steps OK (%.2f ms)", len(steps), float64(duration.Microseconds())/1000.0), duration
+}
@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"fmt"
+	"html"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+	"ping-go/pkg/i18n"
+	"ping-go/pkg/logger"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// subscriberQueueSize 等待发送给状态页订阅者的通知数
+const subscriberQueueSize = 100
+
+// SubscriberUpdate 发给状态页邮件订阅者的一条通知（事件公告或监控项状态变化）
+type SubscriberUpdate struct {
+	MonitorIDs []uint // 相关的监控项，只发给包含其中任一监控项的状态页的订阅者；为空时发给所有已发布状态页的订阅者
+	Subject    string // 邮件主题，发送时加上状态页标题前缀
+	Body       string // 纯文本正文
+}
+
+// NotifySubscribers 将通知放入订阅者发送队列（不阻塞调用方），队列满时丢弃并记录告警
+func (s *Service) NotifySubscribers(u SubscriberUpdate) {
+	select {
+	case s.subscriberQueue <- u:
+	default:
+		logger.Warn("Subscriber queue full, dropping update", zap.String("subject", u.Subject))
+		db.RaiseAlert(model.AlertSeverityWarning, "Notification delivery failed",
+			fmt.Sprintf("subscriber email %q dropped: delivery queue full", u.Subject))
+	}
+}
+
+// runSubscriberWorker 逐条发送订阅者通知，每条通知的所有邮件通过 Resend 批量接口分批发送
+func (s *Service) runSubscriberWorker() {
+	for {
+		select {
+		case u := <-s.subscriberQueue:
+			deliverSubscriberUpdate(u)
+		case <-s.stopWorker:
+			return
+		}
+	}
+}
+
+// notifyStatusSubscribers 监控项变为 DOWN 或从 DOWN 恢复为 UP 时通知所在状态页的订阅者
+func (s *Service) notifyStatusSubscribers(m model.Monitor, prevStatus int, h *model.Heartbeat) {
+	if h.Status != model.StatusDown && (h.Status != model.StatusUp || prevStatus != model.StatusDown) {
+		return
+	}
+	lang := db.Language()
+	s.NotifySubscribers(SubscriberUpdate{
+		MonitorIDs: []uint{m.ID},
+		Subject:    fmt.Sprintf(i18n.T(lang, "feed.status_change"), m.Name, statusToString(h.Status)),
+		Body:       PublicMessage(h.Status, h.Message), // 订阅者是外部用户，不发送检查消息原文
+	})
+}
+
+// deliverSubscriberUpdate 为每个相关状态页的每个订阅者生成带退订链接的邮件并批量发送。
+// 邮件中的链接需要 server.external_url，未配置时不发送
+func deliverSubscriberUpdate(u SubscriberUpdate) {
+	cfg := config.Get()
+	if cfg.Server.ExternalURL == "" {
+		return
+	}
+	pages, err := db.SubscriberPages(u.MonitorIDs)
+	if err != nil {
+		logger.Error("Failed to load subscribers", zap.Error(err))
+		return
+	}
+	lang := db.Language()
+	var messages []notification.EmailMessage
+	for _, p := range pages {
+		pageURL := cfg.StatusPageURL(p.Page.Slug)
+		subject := fmt.Sprintf("[%s] %s", p.Page.Title, u.Subject)
+		for _, sub := range p.Subscribers {
+			unsubscribe := cfg.SubscriptionURL(model.SubscriptionUnsubscribe, sub.ID, sub.Token(model.SubscriptionUnsubscribe))
+			messages = append(messages, notification.EmailMessage{
+				To:      sub.Email,
+				Subject: subject,
+				HTML: SubscriptionEmailHTML(u.Subject, u.Body, i18n.T(lang, "subscription.view_page"), pageURL,
+					fmt.Sprintf(i18n.T(lang, "subscription.footer"), p.Page.Title), i18n.T(lang, "subscription.unsubscribe"), unsubscribe),
+				// RFC 8058 一键退订，邮件客户端直接 POST 到退订链接
+				Headers: map[string]string{"List-Unsubscribe": "<" + unsubscribe + ">", "List-Unsubscribe-Post": "List-Unsubscribe=One-Click"},
+			})
+		}
+	}
+	if len(messages) == 0 {
+		return
+	}
+	failed, err := notification.SendEmailBatch(messages)
+	if err != nil {
+		logger.Error("Failed to email subscribers", zap.String("subject", u.Subject), zap.Int("failed", failed), zap.Error(err))
+		db.RaiseAlert(model.AlertSeverityWarning, "Notification delivery failed",
+			fmt.Sprintf("subscriber email %q: %d of %d failed: %v", u.Subject, failed, len(messages), err))
+	}
+}
+
+// SubscriptionEmailHTML 订阅相关邮件（确认邮件和通知）的 HTML：标题、正文、一个操作链接和页脚；
+// 退订链接为空时页脚不带退订链接
+func SubscriptionEmailHTML(heading, body, actionLabel, actionURL, footer, unsubscribeLabel, unsubscribeURL string) string {
+	var b strings.Builder
+	b.WriteString(`<div style="font-family:-apple-system,BlinkMacSystemFont,'Segoe UI',Roboto,sans-serif;max-width:560px;margin:0 auto;color:#2d3436">`)
+	fmt.Fprintf(&b, `<h2 style="font-size:18px">%s</h2>`, html.EscapeString(heading))
+	if body != "" {
+		fmt.Fprintf(&b, `<p style="white-space:pre-line;line-height:1.6">%s</p>`, html.EscapeString(body))
+	}
+	if actionURL != "" {
+		fmt.Fprintf(&b, `<p><a href="%s" style="display:inline-block;padding:8px 16px;background:#0984e3;color:#fff;border-radius:6px;text-decoration:none">%s</a></p>`,
+			html.EscapeString(actionURL), html.EscapeString(actionLabel))
+	}
+	fmt.Fprintf(&b, `<p style="margin-top:24px;font-size:12px;color:#95a5a6">%s`, html.EscapeString(footer))
+	if unsubscribeURL != "" {
+		fmt.Fprintf(&b, ` <a href="%s" style="color:#95a5a6">%s</a>`, html.EscapeString(unsubscribeURL), html.EscapeString(unsubscribeLabel))
+	}
+	b.WriteString(`</p></div>`)
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"ping-go/model"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// udpMaxReply UDP 响应读取缓冲区大小
+	udpMaxReply = 65535
+	// udpPreviewBytes 心跳消息中展示的响应前缀字节数
+	udpPreviewBytes = 16
+)
+
+// UDPResult holds the reply of a single UDP request/response exchange
+type UDPResult struct {
+	Reply []byte
+	RTT   time.Duration
+}
+
+// ParseUDPPayload decodes the configured payload. format "hex" accepts hex digits with optional
+// whitespace or ":" separators; anything else sends the text as-is (with \n, \r, \t, \0 unescaped).
+func ParseUDPPayload(payload, format string) ([]byte, error) {
+	if format == "hex" {
+		cleaned := strings.NewReplacer(" ", "", ":", "", "\n", "", "\t", "").Replace(payload)
+		cleaned = strings.TrimPrefix(strings.ToLower(cleaned), "0x")
+		b, err := hex.DecodeString(cleaned)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex payload: %w", err)
+		}
+		return b, nil
+	}
+	return []byte(strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\0`, "\x00").Replace(payload)), nil
+}
+
+// QueryUDP sends payload to addr and waits for a single reply datagram.
+func QueryUDP(addr string, timeoutSec int, payload []byte) (*UDPResult, error) {
+	timeout := time.Duration(timeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	dialer := net.Dialer{Resolver: getCustomResolver()}
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	start := time.Now()
+	if _, err := conn.Write(payload); err != nil {
+		return nil, err
+	}
+
+	// 已连接的 UDP socket 收到 ICMP 端口不可达时，Read 会返回 connection refused
+	buf := make([]byte, udpMaxReply)
+	n, err := conn.Read(buf)
+	rtt := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	return &UDPResult{Reply: buf[:n], RTT: rtt}, nil
+}
+
+// CheckUDP sends the configured payload and marks the monitor UP when a reply arrives in time
+// and, if responseRegex is set, the reply matches it.
+func CheckUDP(addr string, timeoutSec int, payload, format, responseRegex string) (int, string, time.Duration) {
+	data, err := ParseUDPPayload(payload, format)
+	if err != nil {
+		return model.StatusDown, err.Error(), 0
+	}
+	result, err := QueryUDP(addr, timeoutSec, data)
+	status, msg := EvaluateUDP(result, err, format, responseRegex)
+	if result == nil || status != model.StatusUp {
+		return status, msg, 0
+	}
+	return status, msg, result.RTT
+}
+
+// EvaluateUDP converts the outcome of QueryUDP into a monitor status and heartbeat message.
+// In hex mode the regex is matched against the hex-encoded reply, so "^abcd" checks a byte prefix.
+func EvaluateUDP(result *UDPResult, err error, format, responseRegex string) (int, string) {
+	if err != nil {
+		errStr := err.Error()
+		if strings.Contains(errStr, "connection refused") {
+			return model.StatusDown, "Port Closed"
+		}
+		var netErr net.Error
+		if (errors.As(err, &netErr) && netErr.Timeout()) || strings.Contains(errStr, "deadline exceeded") {
+			return model.StatusDown, "No Reply (Timeout)"
+		}
+		if strings.Contains(errStr, "no such host") {
+			return model.StatusDown, "DNS Resolution Failed"
+		}
+		if len(errStr) > 40 {
+			return model.StatusDown, errStr[:37] + "..."
+		}
+		return model.StatusDown, errStr
+	}
+
+	if responseRegex != "" {
+		subject := string(result.Reply)
+		if format == "hex" {
+			subject = hex.EncodeToString(result.Reply)
+		}
+		matched, err := regexp.MatchString(responseRegex, subject)
+		if err != nil {
+			return model.StatusDown, fmt.Sprintf("Regex error: %v", err)
+		}
+		if !matched {
+			return model.StatusDown, fmt.Sprintf("Reply mismatch: %s", udpPreview(result.Reply))
+		}
+	}
+
+	return model.StatusUp, fmt.Sprintf("Reply %d bytes: %s (%.2f ms)", len(result.Reply), udpPreview(result.Reply), float64(result.RTT.Microseconds())/1000.0)
+}
+
+// udpPreview 返回响应前若干字节的十六进制表示
+func udpPreview(reply []byte) string {
+	if len(reply) > udpPreviewBytes {
+		return hex.EncodeToString(reply[:udpPreviewBytes]) + "..."
+	}
+	return hex.EncodeToString(reply)
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"ping-go/model"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizedURL 规范化后的监控地址
+type NormalizedURL struct {
+	URL string
+	// User/Password 从 HTTP 地址的 userinfo 中拆出的凭据，HasUserinfo 为 true 时有效
+	User        string
+	Password    string
+	HasUserinfo bool
+	// Notes 对地址做出的修改说明，用于回显给用户
+	Notes []string
+}
+
+// NormalizeURL 规范化监控地址：主机名转小写，国际化域名（IDN）转换为 punycode。
+// HTTP 监控项缺少协议时补全 https://，并拆出 URL 中的 user:pass。其他类型按 host 或 host:port 处理。
+func NormalizeURL(t model.MonitorType, raw string) (NormalizedURL, error) {
+	raw = strings.TrimSpace(raw)
+	switch t {
+	case model.MonitorTypeHTTP, "":
+		return normalizeHTTPURL(raw)
+	case model.MonitorTypePush, model.MonitorTypeMultiStep:
+		return NormalizedURL{URL: raw}, nil
+	}
+
+	if raw == "" {
+		return NormalizedURL{}, nil
+	}
+	var n NormalizedURL
+	host, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		host, port = raw, ""
+	}
+	asciiHost, err := normalizeHost(host)
+	if err != nil {
+		return n, err
+	}
+	if asciiHost != strings.ToLower(host) {
+		n.Notes = append(n.Notes, "国际化域名已转换为 "+asciiHost)
+	}
+	if port != "" {
+		n.URL = net.JoinHostPort(asciiHost, port)
+	} else {
+		n.URL = asciiHost
+	}
+	return n, nil
+}
+
+func normalizeHTTPURL(raw string) (NormalizedURL, error) {
+	var n NormalizedURL
+	if raw == "" {
+		return n, errors.New("URL 不能为空")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+		n.Notes = append(n.Notes, "已自动补全 https://")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return n, fmt.Errorf("URL 格式无效: %v", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return n, fmt.Errorf("不支持的协议 %q，仅支持 http 和 https", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return n, errors.New("URL 缺少主机名")
+	}
+
+	if u.User != nil {
+		n.HasUserinfo = true
+		n.User = u.User.Username()
+		n.Password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return n, err
+	}
+	if host != strings.ToLower(u.Hostname()) {
+		n.Notes = append(n.Notes, "国际化域名已转换为 "+host)
+	}
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	n.URL = u.String()
+	return n, nil
+}
+
+// normalizeHost 主机名转小写，包含非 ASCII 字符时按 label 转换为 punycode（xn--）
+func normalizeHost(host string) (string, error) {
+	host = strings.ToLower(host)
+	if isASCII(host) {
+		return host, nil
+	}
+	ascii, err := idna.Punycode.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("无效的国际化域名 %q: %v", host, err)
+	}
+	return ascii, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// webhook 投递队列与重试参数
+const (
+	webhookQueueSize = 1000
+	webhookWorkers   = 4
+	webhookTimeout   = 10 * time.Second
+)
+
+// webhookRetryDelays 投递失败后的重试间隔，用完后记录到系统告警
+var webhookRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// 与 push 签名相同的请求头：X-PingGo-Signature = hex(HMAC-SHA256(secret, timestamp + body))
+const (
+	webhookSignatureHeader = "X-PingGo-Signature"
+	webhookTimestampHeader = "X-PingGo-Timestamp"
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookDelivery 队列中的一次投递
+type webhookDelivery struct {
+	MonitorID uint
+	Name      string
+	URL       string
+	Secret    string
+	Body      []byte
+	Attempt   int
+}
+
+// webhookPayload 发送给监控项 webhook 的 JSON
+type webhookPayload struct {
+	Event          string         `json:"event"` // heartbeat
+	Monitor        webhookMonitor `json:"monitor"`
+	Status         int            `json:"status"`
+	StatusKey      string         `json:"status_key"`
+	PreviousStatus int            `json:"previous_status"`
+	PreviousKey    string         `json:"previous_status_key"`
+	Transition     bool           `json:"transition"`
+	Message        string         `json:"msg"`
+	Duration       int            `json:"duration"`
+	Time           time.Time      `json:"time"`
+}
+
+type webhookMonitor struct {
+	ID   uint              `json:"id"`
+	Name string            `json:"name"`
+	Type model.MonitorType `json:"type"`
+	URL  string            `json:"url"`
+	Tags []string          `json:"tags"`
+}
+
+// webhookMatches 心跳是否满足监控项 webhook 的发送条件
+func webhookMatches(filter string, prevStatus, status int) bool {
+	switch filter {
+	case model.WebhookFilterFailures:
+		return status == model.StatusDown
+	case model.WebhookFilterTransitions:
+		return status != prevStatus
+	default:
+		return true
+	}
+}
+
+// dispatchWebhook 将心跳放入投递队列（不阻塞检查流程），队列满时丢弃并记录告警
+func (s *Service) dispatchWebhook(m model.Monitor, prevStatus int, h *model.Heartbeat) {
+	if !m.WebhookEnabled || m.WebhookURL == "" || !webhookMatches(m.WebhookFilter, prevStatus, h.Status) {
+		return
+	}
+	body, err := json.Marshal(webhookPayload{
+		Event:          "heartbeat",
+		Monitor:        webhookMonitor{ID: m.ID, Name: m.Name, Type: m.Type, URL: m.URL, Tags: model.SplitTags(m.Tags)},
+		Status:         h.Status,
+		StatusKey:      model.StatusKey(h.Status),
+		PreviousStatus: prevStatus,
+		PreviousKey:    model.StatusKey(prevStatus),
+		Transition:     h.Status != prevStatus,
+		Message:        h.Message,
+		Duration:       h.Duration,
+		Time:           h.Time,
+	})
+	if err != nil {
+		return
+	}
+	s.enqueueWebhook(&webhookDelivery{MonitorID: m.ID, Name: m.Name, URL: m.WebhookURL, Secret: m.WebhookSecret, Body: body})
+}
+
+func (s *Service) enqueueWebhook(d *webhookDelivery) {
+	select {
+	case s.webhookQueue <- d:
+	default:
+		logger.Warn("Webhook queue full, dropping delivery", zap.Uint("monitorID", d.MonitorID))
+		db.RaiseAlert(model.AlertSeverityWarning, "Notification delivery failed",
+			fmt.Sprintf("webhook for %s dropped: delivery queue full", d.Name))
+	}
+}
+
+// runWebhookWorker 从队列取出并投递 webhook，失败按 webhookRetryDelays 延迟后重新入队
+func (s *Service) runWebhookWorker() {
+	for {
+		select {
+		case d := <-s.webhookQueue:
+			err := deliverWebhook(d)
+			if err == nil {
+				continue
+			}
+			if d.Attempt < len(webhookRetryDelays) {
+				delay := webhookRetryDelays[d.Attempt]
+				d.Attempt++
+				logger.Warn("Webhook delivery failed, retrying",
+					zap.Uint("monitorID", d.MonitorID), zap.Int("attempt", d.Attempt), zap.Duration("delay", delay), zap.Error(err))
+				time.AfterFunc(delay, func() { s.enqueueWebhook(d) })
+				continue
+			}
+			logger.Error("Webhook delivery failed", zap.Uint("monitorID", d.MonitorID), zap.String("url", d.URL), zap.Error(err))
+			db.RaiseAlert(model.AlertSeverityWarning, "Notification delivery failed",
+				fmt.Sprintf("webhook for %s to %s after %d attempts: %v", d.Name, d.URL, d.Attempt+1, err))
+		case <-s.stopWorker:
+			return
+		}
+	}
+}
+
+// deliverWebhook 发送一次请求，2xx 视为成功
+func deliverWebhook(d *webhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PingGo-Webhook")
+	if d.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(d.Secret))
+		mac.Write([]byte(timestamp))
+		mac.Write(d.Body)
+		req.Header.Set(webhookTimestampHeader, timestamp)
+		req.Header.Set(webhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
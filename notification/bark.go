@@ -0,0 +1,183 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBarkServer 未配置服务器地址时使用的官方 Bark 服务
+const DefaultBarkServer = "https://api.day.app"
+
+// Bark 通知铃声：DOWN 使用警报声，恢复使用提示音
+const (
+	BarkSoundDown     = "alarm"
+	BarkSoundRecovery = "glass"
+)
+
+// BarkConfig Bark 渠道配置，字段与触发规则配置中的 JSON key 对应
+type BarkConfig struct {
+	Server       string `json:"bark_server"`        // 服务器地址，默认 https://api.day.app，自建服务填写自己的地址
+	DeviceKey    string `json:"bark_device_key"`    // 设备 key（App 中复制的推送地址最后一段）
+	IconDown     string `json:"bark_icon_down"`     // 可选：DOWN 通知的图标地址
+	IconRecovery string `json:"bark_icon_recovery"` // 可选：恢复通知的图标地址
+}
+
+// BarkMessage 一条 Bark 推送
+type BarkMessage struct {
+	Title string
+	Body  string
+	Group string // 通知分组，状态通知使用监控名称
+	Sound string
+	Icon  string
+	Level string // active / timeSensitive
+	URL   string // 点击通知打开的地址
+}
+
+// Validate 校验配置
+func (c BarkConfig) Validate() error {
+	if strings.TrimSpace(c.DeviceKey) == "" {
+		return errors.New("bark_device_key is required")
+	}
+	if strings.ContainsAny(c.DeviceKey, "/?#") {
+		return errors.New("invalid bark_device_key")
+	}
+	if _, err := c.pushURL(); err != nil {
+		return err
+	}
+	for key, icon := range map[string]string{"bark_icon_down": c.IconDown, "bark_icon_recovery": c.IconRecovery} {
+		if icon == "" {
+			continue
+		}
+		if u, err := url.Parse(icon); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid %s %q", key, icon)
+		}
+	}
+	return nil
+}
+
+// pushURL 返回推送地址 <server>/push
+func (c BarkConfig) pushURL() (*url.URL, error) {
+	server := strings.TrimSpace(c.Server)
+	if server == "" {
+		server = DefaultBarkServer
+	}
+	u, err := url.Parse(strings.TrimRight(server, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid bark_server %q", c.Server)
+	}
+	u.Path += "/push"
+	return u, nil
+}
+
+// BarkStatusMessage 用与邮件相同的状态变化数据构造推送：按监控名称分组，DOWN 为警报声和时效性通知，恢复为普通提示音
+func BarkStatusMessage(cfg BarkConfig, d StatusChangeData, click string) BarkMessage {
+	msg := BarkMessage{
+		Title: fmt.Sprintf("%s: %s is %s", d.StatusText, d.Name, d.NewStatus),
+		Group: d.Name,
+		URL:   click,
+	}
+	if d.NewStatus == "UP" {
+		msg.Sound, msg.Icon, msg.Level = BarkSoundRecovery, cfg.IconRecovery, "active"
+	} else {
+		msg.Sound, msg.Icon, msg.Level = BarkSoundDown, cfg.IconDown, "timeSensitive"
+	}
+
+	lines := []string{fmt.Sprintf("%s → %s", d.OldStatus, d.NewStatus)}
+	if d.Condition != "" {
+		lines = append(lines, d.Condition)
+	}
+	if o := d.OutageSummary(); o != "" {
+		lines = append(lines, o)
+	}
+	if d.URL != "" {
+		lines = append(lines, d.URL)
+	}
+	switch {
+	case d.Message != "":
+		lines = append(lines, d.Message)
+	case d.Redacted:
+		lines = append(lines, "Details available in dashboard")
+	}
+	lines = append(lines, d.DateTime)
+	msg.Body = strings.Join(lines, "\n")
+	return msg
+}
+
+// barkClient 推送请求使用的客户端，超时较短，自建服务不可达时尽快进入重试
+var barkClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendBark 发送一条 Bark 推送。内容以 JSON 提交到 /push，中文名称和长错误信息无需放进 URL 路径；
+// 网络错误（如自建服务不可达）、429 和 5xx 按指数退避重试 3 次
+func SendBark(ctx context.Context, cfg BarkConfig, msg BarkMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "Bark push to "+cfg.Server, func() (bool, error) {
+		return postBark(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send Bark push: %w", err)
+	}
+	return nil
+}
+
+// postBark 发送一次请求，返回失败是否值得重试
+func postBark(ctx context.Context, cfg BarkConfig, msg BarkMessage) (bool, error) {
+	u, err := cfg.pushURL()
+	if err != nil {
+		return false, err
+	}
+	payload := map[string]any{
+		"device_key": strings.TrimSpace(cfg.DeviceKey),
+		"title":      msg.Title,
+		"body":       msg.Body,
+	}
+	for key, v := range map[string]string{"group": msg.Group, "sound": msg.Sound, "icon": msg.Icon, "level": msg.Level, "url": msg.URL} {
+		if v != "" {
+			payload[key] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := barkClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	// Bark 在 HTTP 200 之外还通过 code 字段返回结果
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	parsed := json.Unmarshal(raw, &result) == nil && result.Code != 0
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 && (!parsed || result.Code == http.StatusOK) {
+		return false, nil
+	}
+	if parsed && result.Message != "" {
+		err = fmt.Errorf("bark returned HTTP %d: %s", resp.StatusCode, result.Message)
+	} else {
+		err = fmt.Errorf("bark returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, &retryAfterError{err: err, after: retryAfter(resp, 0)}
+	}
+	return resp.StatusCode >= 500, err
+}
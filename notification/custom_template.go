@@ -0,0 +1,200 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTemplateBytes 自定义模板源码的上限
+const MaxTemplateBytes = 64 * 1024
+
+// TemplateVariable 模板中可以使用的变量，通过 getNotificationTemplates 返回给前端
+type TemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// TemplateVariables 每种模板可用的变量
+var TemplateVariables = map[string][]TemplateVariable{
+	TemplateStatusChange: {
+		{"{{.Name}}", "监控项名称"},
+		{"{{.NewStatus}}", "新状态（UP / DOWN）"},
+		{"{{.OldStatus}}", "之前的状态"},
+		{"{{.StatusText}}", "通知标题，如“服务宕机通知”“服务持续宕机提醒”"},
+		{"{{.Message}}", "检查消息；规则开启 redact_details 时为空"},
+		{"{{.DateTime}}", "触发时间"},
+		{"{{.Duration}}", "宕机通知为本轮失败已持续的时间，恢复通知为宕机总时长，持续宕机提醒为已宕机时长"},
+		{"{{.Condition}}", "满足的触发条件，恢复和提醒通知为空；有被抑制的通知时附带其数量"},
+		{"{{.DownSince}}", "本次宕机开始的时间，只有恢复、持续宕机提醒和升级通知有"},
+		{"{{.OutageSummary}}", "宕机时长说明，如 DOWN for 1h23m (since 2024-05-01 03:12)；没有 DownSince 时为空"},
+		{"{{.Suppressed}}", "上一条通知发出后因限流或抖动被抑制的通知数"},
+		{"{{.URL}}", "监控地址；规则开启 redact_details 时为空"},
+		{"{{.MonitorType}}", "监控类型（http、tcp 等）"},
+		{"{{.Color}}", "状态颜色"},
+		{"{{.IncidentURL}}", "事件视图地址，未配置 server.external_url 时为空"},
+		{"{{.Redacted}}", "检查消息和地址是否已隐藏"},
+		{"{{.Lang}}", "邮件语言（zh / en）"},
+	},
+	TemplateDailyReport: {
+		{"{{.Date}}", "日报日期"},
+		{"{{.TotalCount}}", "监控项总数"},
+		{"{{.UptimePercent}}", "启用的监控项中 UP 的比例"},
+		{"{{.DownCount}}", "DOWN 的监控项数"},
+		{"{{range .Monitors}}...{{end}}", "逐个监控项：.Name、.Type、.Uptime24h、.AvgResponse24h、.Status、.StatusKey、.Color、.DetailURL、.Sparkline、.LongestOutage"},
+	},
+}
+
+// TemplateError 自定义模板的错误及其位置（从 1 开始），Column 为 0 表示无法确定列
+type TemplateError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *TemplateError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// templateErrorRe 匹配 text/template 和 html/template 的错误位置：
+// "template: name:3: ..."（解析错误只有行号）、"template: name:3:12: executing ..."、"html/template:name:3:12: ..."
+var templateErrorRe = regexp.MustCompile(`^(?:template: |html/template:)[^:]*:(\d+)(?::(\d+))?: (.*)$`)
+
+// BuiltinTemplate 返回内置模板指定语言的源码，lang 不受支持时使用默认语言
+func BuiltinTemplate(kind, lang string) (string, error) {
+	variants, ok := builtinTemplates[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q", kind)
+	}
+	return variants[EmailLanguage(lang)], nil
+}
+
+// CheckTemplate 保存自定义模板前校验：规则与 ValidateTemplate 相同，
+// 解析和执行错误转换为带行列号的 *TemplateError
+func CheckTemplate(kind, src string) error {
+	if len(src) > MaxTemplateBytes {
+		return fmt.Errorf("template is %d bytes, exceeds the %d byte limit", len(src), MaxTemplateBytes)
+	}
+	if err := ValidateTemplate(kind, src); err != nil {
+		return locateTemplateError(kind, src, err)
+	}
+	return nil
+}
+
+// PreviewTemplate 用示例数据渲染模板并返回 HTML，src 为空时渲染 lang 语言的内置模板
+func PreviewTemplate(kind, src, lang string) (string, error) {
+	if src == "" {
+		builtin, err := BuiltinTemplate(kind, lang)
+		if err != nil {
+			return "", err
+		}
+		src = builtin
+	}
+	var (
+		html string
+		err  error
+	)
+	switch kind {
+	case TemplateStatusChange:
+		html, err = renderStatusChange(src, StatusChangeFixture(lang))
+	case TemplateDailyReport:
+		html, err = renderDailyReport(src, DailyReportFixture(lang))
+	default:
+		return "", fmt.Errorf("unknown template %q", kind)
+	}
+	if err != nil {
+		return "", locateTemplateError(kind, src, err)
+	}
+	return html, nil
+}
+
+// RenderStatusChangeEmailWith 使用自定义模板渲染状态通知邮件；src 为空或渲染失败时使用内置模板，确保告警照常发出
+func RenderStatusChangeEmailWith(src string, data StatusChangeData) (string, error) {
+	if src != "" {
+		html, err := renderStatusChange(src, data)
+		if err == nil {
+			return html, nil
+		}
+		if errors.Is(err, ErrMissingField) {
+			return "", err
+		}
+		log.Printf("WARN: custom %s template failed, using the built-in template: %v", TemplateStatusChange, err)
+	}
+	return RenderStatusChangeEmail(data)
+}
+
+// RenderDailyReportEmailWith 使用自定义模板渲染日报邮件；src 为空或渲染失败时使用内置模板
+func RenderDailyReportEmailWith(src string, data DailyReportData) (string, error) {
+	if src != "" {
+		html, err := renderDailyReport(src, data)
+		if err == nil {
+			return html, nil
+		}
+		if errors.Is(err, ErrMissingField) {
+			return "", err
+		}
+		log.Printf("WARN: custom %s template failed, using the built-in template: %v", TemplateDailyReport, err)
+	}
+	return RenderDailyReportEmail(data)
+}
+
+// locateTemplateError 从模板错误中取出行列号；解析错误只带行号，列号定位到该行出错的 {{ }}。
+// 不带位置的错误（如渲染结果缺少必需内容）原样返回
+func locateTemplateError(kind, src string, err error) error {
+	m := templateErrorRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(m[1])
+	column, _ := strconv.Atoi(m[2])
+	if column == 0 {
+		column = templateErrorColumn(kind, src, line)
+	}
+	return &TemplateError{Line: line, Column: column, Msg: m[3]}
+}
+
+// templateErrorColumn 找出第 line 行第一个导致解析失败的动作：依次解析到该行每个 }} 为止的前缀，
+// 第一次出现该行的错误（不算前缀截断导致的 EOF）时返回这个动作 {{ 的列号；找不到时返回该行第一个 {{ 的列号
+func templateErrorColumn(kind, src string, line int) int {
+	lines := strings.SplitAfter(src, "\n")
+	if line < 1 || line > len(lines) {
+		return 0
+	}
+	lineStart := 0
+	for _, l := range lines[:line-1] {
+		lineStart += len(l)
+	}
+	text := lines[line-1]
+
+	first := 0
+	for off := 0; ; {
+		open := strings.Index(text[off:], "{{")
+		if open < 0 {
+			break
+		}
+		open += off
+		column := utf8.RuneCountInString(text[:open]) + 1
+		if first == 0 {
+			first = column
+		}
+		end := len(text)
+		if closeIdx := strings.Index(text[open:], "}}"); closeIdx >= 0 {
+			end = open + closeIdx + 2
+		}
+		_, err := parseTemplate(kind, src[:lineStart+end])
+		if err != nil && !strings.Contains(err.Error(), "unexpected EOF") {
+			if m := templateErrorRe.FindStringSubmatch(err.Error()); m != nil && m[1] == strconv.Itoa(line) {
+				return column
+			}
+		}
+		off = end
+	}
+	return first
+}
@@ -0,0 +1,213 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 钉钉机器人返回的错误码：安全设置校验失败（签名、关键词、IP）和发送过于频繁（每分钟 20 条，超过后限流 10 分钟）
+const (
+	dingTalkErrSecurity = 310000
+	dingTalkErrTooFast  = 130101
+)
+
+// DingTalkConfig 钉钉自定义机器人渠道配置，字段与触发规则配置中的 JSON key 对应
+type DingTalkConfig struct {
+	Webhook   string `json:"dingtalk_webhook"`    // 机器人 Webhook 地址，含 access_token
+	Secret    string `json:"dingtalk_secret"`     // 可选：安全设置为“加签”时的密钥（SEC 开头）
+	AtAll     bool   `json:"dingtalk_at_all"`     // DOWN 通知 @所有人
+	AtMobiles string `json:"dingtalk_at_mobiles"` // DOWN 通知 @ 的手机号，逗号分隔
+}
+
+// DingTalkMessage 一条钉钉 Markdown 消息
+type DingTalkMessage struct {
+	Title     string // 会话列表中显示的摘要
+	Text      string // Markdown 正文
+	AtAll     bool
+	AtMobiles []string
+}
+
+// Validate 校验配置
+func (c DingTalkConfig) Validate() error {
+	if strings.TrimSpace(c.Webhook) == "" {
+		return errors.New("dingtalk_webhook is required")
+	}
+	u, err := url.Parse(strings.TrimSpace(c.Webhook))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid dingtalk_webhook")
+	}
+	if u.Query().Get("access_token") == "" {
+		return errors.New("dingtalk_webhook must contain access_token")
+	}
+	if secret := strings.TrimSpace(c.Secret); secret != "" && !strings.HasPrefix(secret, "SEC") {
+		return errors.New("dingtalk_secret should start with SEC (copy it from the robot's 加签 setting)")
+	}
+	for _, m := range c.mobiles() {
+		if strings.Trim(m, "+-0123456789") != "" {
+			return fmt.Errorf("invalid mobile %q in dingtalk_at_mobiles", m)
+		}
+	}
+	return nil
+}
+
+// mobiles 解析 AtMobiles，支持逗号、空格和换行分隔
+func (c DingTalkConfig) mobiles() []string {
+	return strings.FieldsFunc(c.AtMobiles, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == ';'
+	})
+}
+
+// signedURL 返回带签名的请求地址：sign = Base64(HMAC-SHA256(secret, timestamp + "\n" + secret))，
+// timestamp 为毫秒，钉钉要求与服务器时间相差不超过 1 小时；未配置密钥时原样返回
+func (c DingTalkConfig) signedURL(now time.Time) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(c.Webhook))
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(c.Secret)
+	if secret == "" {
+		return u.String(), nil
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	q := u.Query()
+	q.Set("timestamp", timestamp)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// DingTalkStatusMessage 用与邮件相同的状态变化数据构造 Markdown 消息；只有 DOWN（含持续宕机提醒）才 @ 配置的成员。
+// 标题包含 PingGo，机器人安全设置为“自定义关键词”时可以用 PingGo 作为关键词
+func DingTalkStatusMessage(cfg DingTalkConfig, d StatusChangeData, dashboard string) DingTalkMessage {
+	msg := DingTalkMessage{Title: fmt.Sprintf("PingGo: %s is %s", d.Name, d.NewStatus)}
+
+	lines := []string{
+		fmt.Sprintf("### PingGo %s", d.StatusText),
+		fmt.Sprintf("**%s**", d.Name),
+		fmt.Sprintf("- 状态：%s → **%s**", d.OldStatus, d.NewStatus),
+	}
+	if d.Condition != "" {
+		lines = append(lines, "- 触发条件："+d.Condition)
+	}
+	if o := d.OutageSummary(); o != "" {
+		lines = append(lines, "- 宕机时长："+o)
+	}
+	if d.URL != "" {
+		lines = append(lines, "- 地址："+d.URL)
+	}
+	switch {
+	case d.Message != "":
+		lines = append(lines, "- 消息："+d.Message)
+	case d.Redacted:
+		lines = append(lines, "- 详情请在控制台查看")
+	}
+	lines = append(lines, "- 时间："+d.DateTime)
+	if dashboard != "" {
+		lines = append(lines, fmt.Sprintf("[打开 PingGo 控制台](%s)", dashboard))
+	}
+
+	if d.NewStatus != "UP" {
+		msg.AtAll = cfg.AtAll
+		msg.AtMobiles = cfg.mobiles()
+		// 钉钉只提醒正文中出现了 @手机号 的成员
+		if len(msg.AtMobiles) > 0 {
+			lines = append(lines, "@"+strings.Join(msg.AtMobiles, " @"))
+		}
+	}
+	msg.Text = strings.Join(lines, "\n\n")
+	return msg
+}
+
+// dingTalkClient 发送请求使用的客户端
+var dingTalkClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendDingTalk 发送一条钉钉机器人消息。每次请求重新计算签名；网络错误和 5xx 按指数退避重试，
+// 签名错误、关键词不匹配等业务错误（HTTP 200 + errcode）不重试，返回的错误包含钉钉的 errmsg 和排查提示
+func SendDingTalk(ctx context.Context, cfg DingTalkConfig, msg DingTalkMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "DingTalk message", func() (bool, error) {
+		return postDingTalk(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send DingTalk message: %w", err)
+	}
+	return nil
+}
+
+// postDingTalk 发送一次请求，返回失败是否值得重试
+func postDingTalk(ctx context.Context, cfg DingTalkConfig, msg DingTalkMessage) (bool, error) {
+	endpoint, err := cfg.signedURL(time.Now())
+	if err != nil {
+		return false, err
+	}
+	payload := map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": msg.Title, "text": msg.Text},
+		"at":       map[string]any{"isAtAll": msg.AtAll, "atMobiles": msg.AtMobiles},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := dingTalkClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("dingtalk returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+		return resp.StatusCode >= 500, err
+	}
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return false, fmt.Errorf("dingtalk returned an unexpected response: %s", strings.TrimSpace(string(raw)))
+	}
+	if result.ErrCode == 0 {
+		return false, nil
+	}
+	return false, dingTalkError(result.ErrCode, result.ErrMsg)
+}
+
+// dingTalkError 把钉钉的错误码转换为便于排查的错误，安全设置相关的错误附带提示
+func dingTalkError(code int, msg string) error {
+	err := fmt.Errorf("dingtalk errcode %d: %s", code, msg)
+	lower := strings.ToLower(msg)
+	switch {
+	case code == dingTalkErrSecurity && strings.Contains(lower, "sign"):
+		return fmt.Errorf("%w (signature rejected: check dingtalk_secret and that the server clock is accurate)", err)
+	case code == dingTalkErrSecurity && strings.Contains(lower, "keyword"):
+		return fmt.Errorf("%w (message does not contain the robot's custom keyword: add \"PingGo\" as a keyword)", err)
+	case code == dingTalkErrSecurity && strings.Contains(lower, "ip"):
+		return fmt.Errorf("%w (this server's IP is not in the robot's IP allowlist)", err)
+	case code == dingTalkErrTooFast:
+		return fmt.Errorf("%w (robot is rate limited: at most 20 messages per minute)", err)
+	}
+	return err
+}
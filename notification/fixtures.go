@@ -0,0 +1,52 @@
+package notification
+
+import "ping-go/pkg/i18n"
+
+// 固定的示例数据：内容确定、覆盖模板中的所有字段（包括可选字段和列表），
+// 用于校验模板和生成预览，渲染结果在模板不变时逐字节相同
+
+// StatusChangeFixture 状态变化邮件的示例数据，标题使用 lang 语言
+func StatusChangeFixture(lang string) StatusChangeData {
+	lang = EmailLanguage(lang)
+	return StatusChangeData{
+		Name:        "Payment API",
+		URL:         "https://api.example.com/health",
+		OldStatus:   "UP",
+		NewStatus:   "DOWN",
+		Message:     "Timeout: context deadline exceeded (Client.Timeout exceeded while awaiting headers)",
+		Color:       "#e74c3c",
+		StatusText:  i18n.T(lang, "notify.title.down"),
+		DateTime:    "2024-01-02 03:04:05",
+		Duration:    "3m12s",
+		DownSince:   "2024-01-02 03:00",
+		Condition:   "down for 3m12s, threshold 2m",
+		IncidentURL: "https://status.example.com/dashboard#/incident/1/1704135845?rule=2",
+		Lang:        lang,
+		SiteName:    "PingGo",
+	}
+}
+
+// DailyReportFixture 日报邮件的示例数据，状态名称使用 lang 语言
+func DailyReportFixture(lang string) DailyReportData {
+	lang = EmailLanguage(lang)
+	return DailyReportData{
+		Date:          "2024-01-02",
+		TotalCount:    3,
+		UptimePercent: 99.5,
+		DownCount:     1,
+		DownColor:     "#e74c3c",
+		Monitors: []MonitorInfo{
+			{Name: "Payment API", Type: "http", Uptime24h: 98.6, AvgResponse24h: 182, Status: i18n.T(lang, "status.down"), StatusKey: "down", Color: "#e74c3c", UptimeColor: "#e67e22", RowBg: "#fff5f5",
+				DetailURL: "https://status.example.com/dashboard#/monitor/1", Sparkline: UptimeSparkline(fixtureHourlyUptime),
+				LongestOutage: "12m30s (since 03:12)"},
+			{Name: "Website", Type: "http", Uptime24h: 100, AvgResponse24h: 95, Status: i18n.T(lang, "status.up"), StatusKey: "up", Color: "#2ecc71", UptimeColor: "#2ecc71", RowBg: "#ffffff",
+				DetailURL: "https://status.example.com/dashboard#/monitor/2"},
+			{Name: "DNS", Type: "dns", Uptime24h: 100, AvgResponse24h: 12, Status: i18n.T(lang, "status.up"), StatusKey: "up", Color: "#2ecc71", UptimeColor: "#2ecc71", RowBg: "#ffffff"},
+		},
+		Lang:     lang,
+		SiteName: "PingGo",
+	}
+}
+
+// fixtureHourlyUptime 示例迷你图数据：包含没有数据的小时和一次短暂宕机
+var fixtureHourlyUptime = []float64{-1, -1, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 96.5, 40, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100}
@@ -0,0 +1,178 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Gotify 消息优先级（0-10）：DOWN 为 8，会在客户端弹出通知；恢复为 4
+const (
+	GotifyPriorityDown     = 8
+	GotifyPriorityRecovery = 4
+)
+
+// GotifyConfig Gotify 渠道配置，字段与触发规则配置中的 JSON key 对应
+type GotifyConfig struct {
+	Server string `json:"gotify_server"` // 服务器地址，如 https://gotify.example.com
+	Token  string `json:"gotify_token"`  // 应用令牌（Apps 中创建，A 开头）
+}
+
+// GotifyMessage 一条 Gotify 消息，Message 按 Markdown 显示
+type GotifyMessage struct {
+	Title    string
+	Message  string
+	Priority int
+	Click    string // 点击通知打开的地址
+}
+
+// Validate 校验配置
+func (c GotifyConfig) Validate() error {
+	if strings.TrimSpace(c.Server) == "" {
+		return errors.New("gotify_server is required")
+	}
+	if _, err := c.messageURL(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.Token) == "" {
+		return errors.New("gotify_token is required")
+	}
+	return nil
+}
+
+// messageURL 返回发送地址 <server>/message
+func (c GotifyConfig) messageURL() (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(strings.TrimSpace(c.Server), "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid gotify_server %q", c.Server)
+	}
+	u.Path += "/message"
+	return u, nil
+}
+
+// GotifyStatusMessage 用与邮件相同的状态变化数据构造 Markdown 消息：DOWN 优先级 8，恢复优先级 4
+func GotifyStatusMessage(d StatusChangeData, click string) GotifyMessage {
+	msg := GotifyMessage{
+		Title:    fmt.Sprintf("%s: %s is %s", d.StatusText, d.Name, d.NewStatus),
+		Priority: GotifyPriorityDown,
+		Click:    click,
+	}
+	if d.NewStatus == "UP" {
+		msg.Priority = GotifyPriorityRecovery
+	}
+
+	lines := []string{
+		fmt.Sprintf("**%s**", escapeMarkdown(d.Name)),
+		"",
+		fmt.Sprintf("- Status: %s → **%s**", d.OldStatus, d.NewStatus),
+	}
+	if d.Condition != "" {
+		lines = append(lines, "- Triggered: "+d.Condition)
+	}
+	if o := d.OutageSummary(); o != "" {
+		lines = append(lines, "- Outage: "+o)
+	}
+	if d.URL != "" {
+		lines = append(lines, "- URL: "+escapeMarkdown(d.URL))
+	}
+	switch {
+	case d.Message != "":
+		lines = append(lines, "- Error: "+escapeMarkdown(d.Message))
+	case d.Redacted:
+		lines = append(lines, "- Details available in dashboard")
+	}
+	lines = append(lines, "- Time: "+d.DateTime)
+	msg.Message = strings.Join(lines, "\n")
+	return msg
+}
+
+// markdownEscaper 转义会被 Markdown 解释的字符，避免监控名称或错误信息打乱格式
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// gotifyClient 发送请求使用的客户端
+var gotifyClient = &http.Client{Timeout: 15 * time.Second}
+
+// SendGotify 发送一条 Gotify 消息，网络错误和 5xx 按指数退避重试 3 次；
+// 令牌无效等 4xx 错误不重试，返回的错误包含 Gotify 的错误说明
+func SendGotify(ctx context.Context, cfg GotifyConfig, msg GotifyMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "Gotify message to "+cfg.Server, func() (bool, error) {
+		return postGotify(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send Gotify message: %w", err)
+	}
+	return nil
+}
+
+// postGotify 发送一次请求，返回失败是否值得重试
+func postGotify(ctx context.Context, cfg GotifyConfig, msg GotifyMessage) (bool, error) {
+	u, err := cfg.messageURL()
+	if err != nil {
+		return false, err
+	}
+
+	extras := map[string]any{
+		"client::display": map[string]any{"contentType": "text/markdown"},
+	}
+	if msg.Click != "" {
+		extras["client::notification"] = map[string]any{"click": map[string]any{"url": msg.Click}}
+	}
+	body, err := json.Marshal(map[string]any{
+		"title":    msg.Title,
+		"message":  msg.Message,
+		"priority": msg.Priority,
+		"extras":   extras,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", strings.TrimSpace(cfg.Token))
+
+	resp, err := gotifyClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, &retryAfterError{err: gotifyError(resp), after: retryAfter(resp, 0)}
+	}
+	return resp.StatusCode >= 500, gotifyError(resp)
+}
+
+// gotifyError 解析 Gotify 的错误响应（{"error","errorCode","errorDescription"}），无法解析时返回原始内容
+func gotifyError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	var e struct {
+		ErrorDescription string `json:"errorDescription"`
+	}
+	if json.Unmarshal(raw, &e) == nil && e.ErrorDescription != "" {
+		return fmt.Errorf("gotify returned HTTP %d: %s", resp.StatusCode, e.ErrorDescription)
+	}
+	return fmt.Errorf("gotify returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
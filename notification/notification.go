@@ -3,8 +3,6 @@ package notification
 import (
 	"fmt"
 	"log"
-	"os"
-	"ping-go/config"
 	"time"
 
 	"github.com/resend/resend-go/v3"
@@ -12,30 +10,19 @@ import (
 
 // SendEmail sends an email using Resend with retry logic
 func SendEmail(to []string, subject, htmlContent string) error {
-	apiKey := config.GlobalConfig.Notification.ResendAPIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("RESEND_API_KEY")
-	}
+	apiKey := resendAPIKey()
 
 	if apiKey == "" || apiKey == "YOUR_RESEND_API_KEY" {
 		log.Printf("ERROR: RESEND_API_KEY is not set or is still default. Current value: %s", apiKey)
 		return fmt.Errorf("RESEND_API_KEY is not set correctly")
 	}
 
-	log.Printf("DEBUG: Preparing to send email via Resend. To: %v, Subject: %s", to, subject)
+	from := EffectiveFrom()
+	log.Printf("DEBUG: Preparing to send email via Resend. From: %s, To: %v, Subject: %s", from, to, subject)
 	client := resend.NewClient(apiKey)
 
-	fromEmail := config.GlobalConfig.Notification.FromEmail
-	if fromEmail == "" {
-		fromEmail = "onboarding@resend.dev"
-	}
-	fromName := config.GlobalConfig.Notification.FromName
-	if fromName == "" {
-		fromName = "PingGo Monitor"
-	}
-
 	params := &resend.SendEmailRequest{
-		From:    fmt.Sprintf("%s <%s>", fromName, fromEmail),
+		From:    from,
 		To:      to,
 		Subject: subject,
 		Html:    htmlContent,
@@ -48,11 +35,11 @@ func SendEmail(to []string, subject, htmlContent string) error {
 		log.Printf("DEBUG: Sending email attempt %d/%d", i+1, maxRetries)
 		resp, err := client.Emails.Send(params)
 		if err == nil {
-			log.Printf("SUCCESS: Email sent successfully to %v. ID: %s", to, resp.Id)
+			log.Printf("SUCCESS: Email sent successfully from %s to %v. ID: %s", from, to, resp.Id)
 			return nil
 		}
 
-		log.Printf("ERROR: Failed to send email (attempt %d/%d): %v", i+1, maxRetries, err)
+		log.Printf("ERROR: Failed to send email from %s (attempt %d/%d): %v", from, i+1, maxRetries, err)
 		if i < maxRetries-1 {
 			time.Sleep(time.Duration(2*(i+1)) * time.Second)
 		}
@@ -60,3 +47,57 @@ func SendEmail(to []string, subject, htmlContent string) error {
 
 	return fmt.Errorf("failed to send email after %d attempts: %w", maxRetries, err)
 }
+
+// Resend 批量发送的限制：每次请求最多 100 封；两次请求之间暂停，避免超过 API 的速率限制
+const (
+	maxBatchEmails = 100
+	batchPause     = time.Second
+)
+
+// EmailMessage 批量发送中的一封邮件
+type EmailMessage struct {
+	To      string
+	Subject string
+	HTML    string
+	Headers map[string]string
+}
+
+// SendEmailBatch 通过 Resend 批量接口发送邮件，每批最多 maxBatchEmails 封，每批失败时与 SendEmail 一样重试 3 次。
+// 某一批最终失败时继续发送后续批次，返回发送失败的邮件数和最后一个错误
+func SendEmailBatch(messages []EmailMessage) (int, error) {
+	apiKey := resendAPIKey()
+	if apiKey == "" || apiKey == "YOUR_RESEND_API_KEY" {
+		return len(messages), fmt.Errorf("RESEND_API_KEY is not set correctly")
+	}
+	from := EffectiveFrom()
+	client := resend.NewClient(apiKey)
+
+	failed := 0
+	var lastErr error
+	for start := 0; start < len(messages); start += maxBatchEmails {
+		if start > 0 {
+			time.Sleep(batchPause)
+		}
+		chunk := messages[start:min(start+maxBatchEmails, len(messages))]
+		params := make([]*resend.SendEmailRequest, len(chunk))
+		for i, m := range chunk {
+			params[i] = &resend.SendEmailRequest{From: from, To: []string{m.To}, Subject: m.Subject, Html: m.HTML, Headers: m.Headers}
+		}
+
+		var err error
+		for i := 0; i < 3; i++ {
+			if i > 0 {
+				time.Sleep(time.Duration(2*i) * time.Second)
+			}
+			if _, err = client.Batch.Send(params); err == nil {
+				break
+			}
+			log.Printf("ERROR: Failed to send email batch of %d (attempt %d/3): %v", len(params), i+1, err)
+		}
+		if err != nil {
+			failed += len(chunk)
+			lastErr = err
+		}
+	}
+	return failed, lastErr
+}
@@ -0,0 +1,189 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultNtfyServer 未配置服务器地址时使用的公共 ntfy 服务
+const DefaultNtfyServer = "https://ntfy.sh"
+
+// ntfy 消息优先级（1-5）
+const (
+	NtfyPriorityDefault = 3
+	NtfyPriorityHigh    = 4
+)
+
+// NtfyConfig ntfy 渠道配置，字段与触发规则配置中的 JSON key 对应
+type NtfyConfig struct {
+	Server   string `json:"ntfy_server"`   // 服务器地址，默认 https://ntfy.sh；自建服务可在地址中带 user:pass@
+	Topic    string `json:"ntfy_topic"`    // 主题
+	Token    string `json:"ntfy_token"`    // 可选的访问令牌（tk_...），优先于用户名密码
+	Username string `json:"ntfy_username"` // 可选的 Basic Auth 用户名（自建服务）
+	Password string `json:"ntfy_password"`
+	Priority int    `json:"ntfy_priority"` // DOWN 通知的优先级，0 表示 high(4)；恢复通知始终为 default(3)
+}
+
+// NtfyMessage 一条 ntfy 推送
+type NtfyMessage struct {
+	Title    string
+	Body     string
+	Priority int
+	Tags     []string
+	Click    string // 点击通知打开的地址
+}
+
+// Validate 校验配置
+func (c NtfyConfig) Validate() error {
+	if strings.TrimSpace(c.Topic) == "" {
+		return errors.New("ntfy_topic is required")
+	}
+	if strings.ContainsAny(c.Topic, "/?#") {
+		return fmt.Errorf("invalid ntfy_topic %q", c.Topic)
+	}
+	if _, err := c.topicURL(); err != nil {
+		return err
+	}
+	if c.Priority < 0 || c.Priority > 5 {
+		return errors.New("ntfy_priority must be between 1 and 5")
+	}
+	return nil
+}
+
+// topicURL 返回发布地址 <server>/<topic>
+func (c NtfyConfig) topicURL() (*url.URL, error) {
+	server := strings.TrimSpace(c.Server)
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+	u, err := url.Parse(strings.TrimRight(server, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid ntfy_server %q", c.Server)
+	}
+	u.Path += "/" + url.PathEscape(strings.TrimSpace(c.Topic))
+	return u, nil
+}
+
+// NtfyStatusMessage 用与邮件相同的状态变化数据构造推送：DOWN 为高优先级并带警告标签，恢复为默认优先级并带勾选标签
+func NtfyStatusMessage(d StatusChangeData, downPriority int, click string) NtfyMessage {
+	msg := NtfyMessage{
+		Title: fmt.Sprintf("%s: %s is %s", d.StatusText, d.Name, d.NewStatus),
+		Click: click,
+	}
+	if d.NewStatus == "UP" {
+		msg.Priority, msg.Tags = NtfyPriorityDefault, []string{"white_check_mark"}
+	} else {
+		msg.Priority, msg.Tags = NtfyPriorityHigh, []string{"warning"}
+		if downPriority > 0 {
+			msg.Priority = downPriority
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%s → %s", d.OldStatus, d.NewStatus)}
+	if d.Condition != "" {
+		lines = append(lines, d.Condition)
+	}
+	if o := d.OutageSummary(); o != "" {
+		lines = append(lines, o)
+	}
+	if d.URL != "" {
+		lines = append(lines, d.URL)
+	}
+	switch {
+	case d.Message != "":
+		lines = append(lines, d.Message)
+	case d.Redacted:
+		lines = append(lines, "Details available in dashboard")
+	}
+	lines = append(lines, d.DateTime)
+	msg.Body = strings.Join(lines, "\n")
+	return msg
+}
+
+// ntfyClient 发布请求使用的客户端
+var ntfyClient = &http.Client{Timeout: 15 * time.Second}
+
+// SendNtfy 发布一条 ntfy 推送，网络错误、429 和 5xx 按指数退避重试 3 次
+func SendNtfy(ctx context.Context, cfg NtfyConfig, msg NtfyMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "ntfy message to "+cfg.Topic, func() (bool, error) {
+		return publishNtfy(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish ntfy message: %w", err)
+	}
+	return nil
+}
+
+// publishNtfy 发送一次请求，返回失败是否值得重试
+func publishNtfy(ctx context.Context, cfg NtfyConfig, msg NtfyMessage) (bool, error) {
+	u, err := cfg.topicURL()
+	if err != nil {
+		return false, err
+	}
+	// 地址中的 user:pass@ 转为 Authorization 头，Go 客户端不会自动处理
+	user, pass := cfg.Username, cfg.Password
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+		u.User = nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(msg.Body))
+	if err != nil {
+		return false, err
+	}
+	// 标题等非 ASCII 头使用 RFC 2047 编码，ntfy 服务端会自动解码
+	req.Header.Set("Title", encodeNtfyHeader(msg.Title))
+	if msg.Priority > 0 {
+		req.Header.Set("Priority", strconv.Itoa(msg.Priority))
+	}
+	if len(msg.Tags) > 0 {
+		req.Header.Set("Tags", strings.Join(msg.Tags, ","))
+	}
+	if msg.Click != "" {
+		req.Header.Set("Click", msg.Click)
+	}
+	switch {
+	case cfg.Token != "":
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	case user != "":
+		req.SetBasicAuth(user, pass)
+	}
+
+	resp, err := ntfyClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("ntfy returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, &retryAfterError{err: err, after: retryAfter(resp, 0)}
+	}
+	return resp.StatusCode >= 500, err
+}
+
+// encodeNtfyHeader 非 ASCII 内容按 RFC 2047 编码
+func encodeNtfyHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.BEncoding.Encode("UTF-8", s)
+		}
+	}
+	return s
+}
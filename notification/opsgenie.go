@@ -0,0 +1,227 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Opsgenie 各区域的 Alert API 地址
+var opsgenieAPI = map[string]string{
+	"us": "https://api.opsgenie.com/v2/alerts",
+	"eu": "https://api.eu.opsgenie.com/v2/alerts",
+}
+
+// Opsgenie 字段长度上限
+const (
+	opsgenieMaxMessage     = 130
+	opsgenieMaxDescription = 15000
+)
+
+// DefaultOpsgeniePriority 未配置优先级时宕机告警使用的优先级
+const DefaultOpsgeniePriority = "P3"
+
+// OpsgenieConfig Opsgenie 渠道配置，字段与触发规则配置中的 JSON key 对应
+type OpsgenieConfig struct {
+	APIKey   string `json:"opsgenie_api_key"`  // API 集成的 API Key
+	Region   string `json:"opsgenie_region"`   // "us"（默认）或 "eu"
+	Priority string `json:"opsgenie_priority"` // 宕机告警的优先级 P1-P5，默认 P3
+}
+
+// OpsgenieAlert 创建或关闭一条告警
+type OpsgenieAlert struct {
+	Close       bool   // true 时按 Alias 关闭告警
+	Alias       string // 同一监控项的告警使用相同的 alias，重复宕机合并，恢复时关闭
+	Message     string
+	Description string
+	Priority    string
+	Tags        []string
+	Details     map[string]string
+	Entity      string
+}
+
+// Validate 校验配置
+func (c OpsgenieConfig) Validate() error {
+	if strings.TrimSpace(c.APIKey) == "" {
+		return errors.New("opsgenie_api_key is required")
+	}
+	if _, ok := opsgenieAPI[c.region()]; !ok {
+		return fmt.Errorf("invalid opsgenie_region %q (use us or eu)", c.Region)
+	}
+	if p := c.Priority; p != "" && (len(p) != 2 || p[0] != 'P' || p[1] < '1' || p[1] > '5') {
+		return fmt.Errorf("invalid opsgenie_priority %q (use P1 to P5)", p)
+	}
+	return nil
+}
+
+func (c OpsgenieConfig) region() string {
+	if c.Region == "" {
+		return "us"
+	}
+	return strings.ToLower(c.Region)
+}
+
+func (c OpsgenieConfig) priority() string {
+	if c.Priority == "" {
+		return DefaultOpsgeniePriority
+	}
+	return c.Priority
+}
+
+// OpsgenieAlias 监控项的告警 alias，由监控项 ID 生成：改名不影响恢复时关闭对应的告警
+func OpsgenieAlias(monitorID uint) string {
+	return fmt.Sprintf("pinggo-monitor-%d", monitorID)
+}
+
+// OpsgenieStatusAlert 用与邮件相同的状态变化数据构造告警：恢复时关闭，其他（宕机和持续宕机提醒）创建，
+// 相同 alias 的告警未关闭时 Opsgenie 只增加计数，不会重复通知
+func OpsgenieStatusAlert(cfg OpsgenieConfig, d StatusChangeData, dashboard string) OpsgenieAlert {
+	alert := OpsgenieAlert{
+		Close:    d.NewStatus == "UP",
+		Alias:    OpsgenieAlias(d.MonitorID),
+		Message:  fmt.Sprintf("%s is %s", d.Name, d.NewStatus),
+		Priority: cfg.priority(),
+		Tags:     []string{"PingGo"},
+		Details:  map[string]string{"monitor": d.Name, "status": d.NewStatus, "previous_status": d.OldStatus, "time": d.DateTime},
+		Entity:   d.Name,
+	}
+	if d.MonitorType != "" {
+		alert.Tags = append(alert.Tags, d.MonitorType)
+	}
+	lines := []string{}
+	switch {
+	case d.Message != "":
+		lines = append(lines, d.Message)
+	case d.Redacted:
+		lines = append(lines, "Details available in dashboard")
+	}
+	if d.URL != "" {
+		lines = append(lines, "URL: "+d.URL)
+		alert.Details["url"] = d.URL
+	}
+	if d.Condition != "" {
+		lines = append(lines, "Condition: "+d.Condition)
+		alert.Details["condition"] = d.Condition
+	}
+	if dashboard != "" {
+		lines = append(lines, "Dashboard: "+dashboard)
+		alert.Details["dashboard"] = dashboard
+	}
+	alert.Description = strings.Join(lines, "\n")
+	return alert
+}
+
+// opsgenieClient 发送请求使用的客户端
+var opsgenieClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendOpsgenie 创建或关闭一条告警，网络错误、429 和 5xx 重试；422 等请求错误不重试，返回可读的字段错误
+func SendOpsgenie(ctx context.Context, cfg OpsgenieConfig, alert OpsgenieAlert) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	action := "create"
+	if alert.Close {
+		action = "close"
+	}
+	err := sendWithRetry(ctx, "Opsgenie alert", func() (bool, error) {
+		return postOpsgenie(ctx, cfg, alert)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to %s Opsgenie alert: %w", action, err)
+	}
+	return nil
+}
+
+// postOpsgenie 发送一次请求，返回失败是否值得重试
+func postOpsgenie(ctx context.Context, cfg OpsgenieConfig, alert OpsgenieAlert) (bool, error) {
+	endpoint := opsgenieAPI[cfg.region()]
+	var payload map[string]any
+	if alert.Close {
+		endpoint += "/" + url.PathEscape(alert.Alias) + "/close?identifierType=alias"
+		payload = map[string]any{"source": "PingGo", "note": "Monitor recovered"}
+	} else {
+		payload = map[string]any{
+			"message":     truncateRunes(alert.Message, opsgenieMaxMessage),
+			"alias":       alert.Alias,
+			"description": truncateRunes(alert.Description, opsgenieMaxDescription),
+			"priority":    alert.Priority,
+			"tags":        alert.Tags,
+			"details":     alert.Details,
+			"entity":      alert.Entity,
+			"source":      "PingGo",
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+strings.TrimSpace(cfg.APIKey))
+
+	resp, err := opsgenieClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = opsgenieError(resp.StatusCode, raw)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, &retryAfterError{err: err, after: retryAfter(resp, 5*time.Second)}
+	case resp.StatusCode >= 500:
+		return true, err
+	}
+	return false, err
+}
+
+// opsgenieError 把 Opsgenie 的错误响应转换为可读的错误：422 时列出每个字段的校验错误，401/403 提示检查 API Key 和区域
+func opsgenieError(status int, raw []byte) error {
+	var result struct {
+		Message string            `json:"message"`
+		Errors  map[string]string `json:"errors"`
+	}
+	detail := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &result) == nil && result.Message != "" {
+		detail = result.Message
+		if len(result.Errors) > 0 {
+			fields := make([]string, 0, len(result.Errors))
+			for field, msg := range result.Errors {
+				fields = append(fields, field+": "+msg)
+			}
+			sort.Strings(fields)
+			detail = strings.Join(fields, "; ")
+		}
+	}
+	switch status {
+	case http.StatusUnprocessableEntity:
+		return fmt.Errorf("opsgenie rejected the alert: %s", detail)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("opsgenie returned HTTP %d: %s (check opsgenie_api_key and that opsgenie_region matches your account)", status, detail)
+	}
+	return fmt.Errorf("opsgenie returned HTTP %d: %s", status, detail)
+}
+
+// truncateRunes 按字符截断到 max 个字符以内
+func truncateRunes(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+	return s
+}
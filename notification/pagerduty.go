@@ -0,0 +1,187 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pagerDutyAPI PagerDuty Events API v2 接口
+var pagerDutyAPI = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty 事件动作
+const (
+	PagerDutyTrigger = "trigger"
+	PagerDutyResolve = "resolve"
+)
+
+// pagerDutyMaxSummary summary 字段的长度上限（PagerDuty 规定为 1024 个字符）
+const pagerDutyMaxSummary = 1024
+
+// PagerDutyConfig PagerDuty 渠道配置，字段与触发规则配置中的 JSON key 对应
+type PagerDutyConfig struct {
+	RoutingKey string `json:"pagerduty_routing_key"` // 服务 Events API v2 集成的 Integration Key
+}
+
+// PagerDutyEvent 一条 Events API v2 事件
+type PagerDutyEvent struct {
+	Action   string // trigger 或 resolve
+	DedupKey string // 同一监控项的宕机和恢复使用相同的 key，恢复时解决的正是宕机时打开的事件
+	Summary  string
+	Severity string // critical/error/warning/info，resolve 时忽略
+	Source   string
+	Details  map[string]string
+	Link     string // 控制台地址
+}
+
+// Validate 校验配置
+func (c PagerDutyConfig) Validate() error {
+	key := strings.TrimSpace(c.RoutingKey)
+	if key == "" {
+		return errors.New("pagerduty_routing_key is required")
+	}
+	if len(key) != 32 || strings.ContainsAny(key, " /?#&=") {
+		return errors.New("invalid pagerduty_routing_key (use the 32-character Integration Key of an Events API v2 integration)")
+	}
+	return nil
+}
+
+// PagerDutyDedupKey 监控项的 dedup_key，由监控项 ID 生成：改名不影响恢复时解决对应的事件
+func PagerDutyDedupKey(monitorID uint) string {
+	return fmt.Sprintf("pinggo-monitor-%d", monitorID)
+}
+
+// pagerDutySeverity 按状态映射事件级别：DOWN 为 critical，PENDING 为 warning，其他为 info
+func pagerDutySeverity(status string) string {
+	switch status {
+	case "DOWN":
+		return "critical"
+	case "PENDING":
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// PagerDutyStatusEvent 用与邮件相同的状态变化数据构造事件：恢复为 resolve，其他（宕机和持续宕机提醒）为 trigger，
+// 持续宕机提醒使用相同的 dedup_key，PagerDuty 会合并到已打开的事件中
+func PagerDutyStatusEvent(d StatusChangeData, dashboard string) PagerDutyEvent {
+	ev := PagerDutyEvent{
+		Action:   PagerDutyTrigger,
+		DedupKey: PagerDutyDedupKey(d.MonitorID),
+		Summary:  fmt.Sprintf("%s is %s", d.Name, d.NewStatus),
+		Severity: pagerDutySeverity(d.NewStatus),
+		Source:   "PingGo",
+		Details:  map[string]string{"monitor": d.Name, "status": d.NewStatus, "previous_status": d.OldStatus, "time": d.DateTime},
+		Link:     dashboard,
+	}
+	if d.NewStatus == "UP" {
+		ev.Action = PagerDutyResolve
+	}
+	if d.URL != "" {
+		ev.Source = d.URL
+		ev.Details["url"] = d.URL
+	}
+	switch {
+	case d.Message != "":
+		ev.Summary += ": " + d.Message
+		ev.Details["message"] = d.Message
+	case d.Redacted:
+		ev.Details["message"] = "Details available in dashboard"
+	}
+	if d.Condition != "" {
+		ev.Details["condition"] = d.Condition
+	}
+	return ev
+}
+
+// pagerDutyClient 发送请求使用的客户端
+var pagerDutyClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendPagerDuty 发送一条事件，网络错误、429 和 5xx 重试；400 等请求错误不重试，返回 PagerDuty 的错误说明
+func SendPagerDuty(ctx context.Context, cfg PagerDutyConfig, ev PagerDutyEvent) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "PagerDuty event", func() (bool, error) {
+		return postPagerDuty(ctx, cfg, ev)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send PagerDuty %s event: %w", ev.Action, err)
+	}
+	return nil
+}
+
+// postPagerDuty 发送一次请求，返回失败是否值得重试
+func postPagerDuty(ctx context.Context, cfg PagerDutyConfig, ev PagerDutyEvent) (bool, error) {
+	payload := map[string]any{
+		"routing_key":  strings.TrimSpace(cfg.RoutingKey),
+		"event_action": ev.Action,
+		"dedup_key":    ev.DedupKey,
+		"client":       "PingGo",
+	}
+	if ev.Action == PagerDutyTrigger {
+		summary := ev.Summary
+		if r := []rune(summary); len(r) > pagerDutyMaxSummary {
+			summary = string(r[:pagerDutyMaxSummary])
+		}
+		payload["payload"] = map[string]any{
+			"summary":        summary,
+			"source":         ev.Source,
+			"severity":       ev.Severity,
+			"timestamp":      time.Now().UTC().Format(time.RFC3339),
+			"component":      ev.Details["monitor"],
+			"custom_details": ev.Details,
+		}
+		if ev.Link != "" {
+			payload["client_url"] = ev.Link
+			payload["links"] = []map[string]string{{"href": ev.Link, "text": "Open PingGo dashboard"}}
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pagerDutyAPI, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := pagerDutyClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	var result struct {
+		Message string   `json:"message"`
+		Errors  []string `json:"errors"`
+	}
+	detail := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &result) == nil && result.Message != "" {
+		detail = result.Message
+		if len(result.Errors) > 0 {
+			detail += ": " + strings.Join(result.Errors, "; ")
+		}
+	}
+	err = fmt.Errorf("pagerduty returned HTTP %d: %s", resp.StatusCode, detail)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, &retryAfterError{err: err, after: retryAfter(resp, 5*time.Second)}
+	case resp.StatusCode >= 500:
+		return true, err
+	}
+	return false, err
+}
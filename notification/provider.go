@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventKind 通知事件的类型
+type EventKind string
+
+const (
+	EventStatusChange EventKind = "status" // 状态变化和持续宕机提醒
+	EventDailyReport  EventKind = "report" // 定时日报
+	EventTest         EventKind = "test"   // 通知设置中的“发送测试”
+)
+
+// DefaultProvider 规则未指定 channel 时使用的渠道
+const DefaultProvider = "email"
+
+// ErrUnsupportedEvent 渠道不支持该类型的事件（如推送渠道不发送日报）
+var ErrUnsupportedEvent = errors.New("event kind not supported by this channel")
+
+// Event 发送给渠道的一条通知。渠道从 Config 中读取自己的配置字段
+type Event struct {
+	Kind    EventKind
+	Subject string           // 标题（邮件主题）
+	Status  StatusChangeData // 状态通知的数据，按规则设置已脱敏；测试消息只有 Color 和 DateTime
+	Report  DailyReportData  // 日报数据
+	Link    string           // 控制台链接：状态通知指向事件视图，其他指向控制台首页；未配置 server.external_url 时为空
+	Time    time.Time        // 事件时间（显示时区）
+	Config  json.RawMessage  `json:"-"` // 规则配置（Notification.Config），发送记录中不保存（含密钥）
+	Test    bool             // “发送测试”模拟的状态通知或日报，发送记录中的类型为 test
+
+	// Template 自定义邮件模板源码（见 RenderStatusChangeEmailWith），为空时使用内置模板；重新发送时按当前模板渲染
+	Template string `json:"-"`
+}
+
+// Provider 通知渠道。新增渠道实现该接口并调用 Register 注册，规则配置中的 channel 为 Name()
+type Provider interface {
+	Name() string
+	// Send 同步发送一条通知，重试由渠道自行处理；不支持的事件类型返回 ErrUnsupportedEvent
+	Send(ctx context.Context, ev Event) error
+	// ValidateConfig 校验规则配置中该渠道的字段，保存规则时调用
+	ValidateConfig(raw json.RawMessage) error
+}
+
+// RecipientProvider 可以说明接收方的渠道额外实现该接口，结果写入发送记录，不能包含密钥
+type RecipientProvider interface {
+	Recipient(raw json.RawMessage) string
+}
+
+// ReportProvider 支持发送定时日报的渠道额外实现该接口
+type ReportProvider interface {
+	SupportsReports() bool
+}
+
+// ResolvingProvider 用恢复通知关闭宕机时打开的事件的渠道（PagerDuty、Opsgenie）额外实现该接口，
+// 规则只订阅宕机通知时也会发送恢复通知
+type ResolvingProvider interface {
+	ResolvesOnRecovery() bool
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// Register 注册通知渠道，名称重复时 panic（与 database/sql 的驱动注册一致，重复注册是编程错误）
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if p == nil {
+		panic("notification: Register provider is nil")
+	}
+	if _, dup := providers[p.Name()]; dup {
+		panic("notification: Register called twice for provider " + p.Name())
+	}
+	providers[p.Name()] = p
+}
+
+// Lookup 返回渠道，name 为空时为 DefaultProvider
+func Lookup(name string) (Provider, error) {
+	if name == "" {
+		name = DefaultProvider
+	}
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的通知渠道 %q", name)
+	}
+	return p, nil
+}
+
+// Providers 返回已注册的渠道名称，按字母排序
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SupportsReports 渠道是否可以发送定时日报
+func SupportsReports(name string) bool {
+	p, err := Lookup(name)
+	if err != nil {
+		return false
+	}
+	r, ok := p.(ReportProvider)
+	return ok && r.SupportsReports()
+}
+
+// ResolvesOnRecovery 渠道是否需要恢复通知来关闭事件
+func ResolvesOnRecovery(name string) bool {
+	p, err := Lookup(name)
+	if err != nil {
+		return false
+	}
+	r, ok := p.(ResolvingProvider)
+	return ok && r.ResolvesOnRecovery()
+}
+
+// Recipient 返回发送记录中显示的接收方，渠道未实现 RecipientProvider 时为空
+func Recipient(name string, raw json.RawMessage) string {
+	p, err := Lookup(name)
+	if err != nil {
+		return ""
+	}
+	if r, ok := p.(RecipientProvider); ok {
+		return r.Recipient(raw)
+	}
+	return ""
+}
+
+// Summary 发送记录中的一行摘要
+func (ev Event) Summary() string {
+	switch ev.Kind {
+	case EventStatusChange:
+		if ev.Status.OldStatus == "" {
+			return fmt.Sprintf("%s: %s", ev.Status.Name, ev.Status.NewStatus)
+		}
+		return fmt.Sprintf("%s: %s → %s", ev.Status.Name, ev.Status.OldStatus, ev.Status.NewStatus)
+	case EventDailyReport:
+		return fmt.Sprintf("%s: %d monitors, %d down", ev.Report.Date, ev.Report.TotalCount, ev.Report.DownCount)
+	}
+	return string(ev.Kind)
+}
+
+// decodeConfig 从规则配置中读取渠道的配置结构
+func decodeConfig[T any](raw json.RawMessage) (T, error) {
+	var cfg T
+	if len(raw) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid notification config: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,213 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeProvider 记录收到的事件；outcomes 依次作为每次尝试的结果，用完后发送成功
+type fakeProvider struct {
+	name     string
+	mu       sync.Mutex
+	events   []Event
+	attempts int
+	outcomes []fakeOutcome
+}
+
+type fakeOutcome struct {
+	retry bool
+	err   error
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[struct {
+		Target string `json:"fake_target"`
+	}](raw)
+	if err != nil {
+		return err
+	}
+	if cfg.Target == "" {
+		return errors.New("fake_target is required")
+	}
+	return nil
+}
+
+func (p *fakeProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[struct {
+		Target string `json:"fake_target"`
+	}](raw)
+	return cfg.Target
+}
+
+func (p *fakeProvider) SupportsReports() bool { return true }
+
+func (p *fakeProvider) Send(ctx context.Context, ev Event) error {
+	return sendWithRetry(ctx, "fake message", func() (bool, error) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.attempts++
+		if len(p.outcomes) > 0 {
+			o := p.outcomes[0]
+			p.outcomes = p.outcomes[1:]
+			if o.err != nil {
+				return o.retry, o.err
+			}
+		}
+		p.events = append(p.events, ev)
+		return false, nil
+	})
+}
+
+// fastRetry 测试期间缩短重试间隔
+func fastRetry(t *testing.T) {
+	t.Helper()
+	old := retryBackoff
+	retryBackoff = time.Millisecond
+	t.Cleanup(func() { retryBackoff = old })
+}
+
+var errTransient = errors.New("HTTP 503")
+
+func TestRegisterAndLookup(t *testing.T) {
+	fake := &fakeProvider{name: "fake-registry"}
+	Register(fake)
+
+	p, err := Lookup("fake-registry")
+	if err != nil || p != fake {
+		t.Fatalf("Lookup = %v, %v, want the registered provider", p, err)
+	}
+	if !slices.Contains(Providers(), "fake-registry") || !slices.IsSorted(Providers()) {
+		t.Fatalf("Providers() = %v", Providers())
+	}
+	if p, err := Lookup(""); err != nil || p.Name() != DefaultProvider {
+		t.Fatalf("Lookup(\"\") = %v, %v, want %s", p, err, DefaultProvider)
+	}
+	if _, err := Lookup("no-such-channel"); err == nil {
+		t.Fatal("Lookup of an unknown channel succeeded")
+	}
+
+	if !SupportsReports("fake-registry") || SupportsReports("ntfy") || SupportsReports("no-such-channel") {
+		t.Error("SupportsReports does not follow ReportProvider")
+	}
+	if ResolvesOnRecovery("fake-registry") || !ResolvesOnRecovery("pagerduty") {
+		t.Error("ResolvesOnRecovery does not follow ResolvingProvider")
+	}
+	if got := Recipient("fake-registry", json.RawMessage(`{"fake_target":"ops"}`)); got != "ops" {
+		t.Errorf("Recipient = %q, want ops", got)
+	}
+	if err := fake.ValidateConfig(json.RawMessage(`{}`)); err == nil {
+		t.Error("empty config passed validation")
+	}
+
+	for name, p := range map[string]Provider{"duplicate": &fakeProvider{name: "fake-registry"}, "nil": nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%s) did not panic", name)
+				}
+			}()
+			Register(p)
+		}()
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	fastRetry(t)
+	tests := []struct {
+		name         string
+		outcomes     []fakeOutcome
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"first attempt succeeds", nil, 1, false},
+		{"transient failure then success", []fakeOutcome{{true, errTransient}}, 2, false},
+		{"gives up after max attempts", []fakeOutcome{{true, errTransient}, {true, errTransient}, {true, errTransient}}, maxSendAttempts, true},
+		{"permanent failure is not retried", []fakeOutcome{{false, errors.New("HTTP 400")}}, 1, true},
+		{"short Retry-After is honoured", []fakeOutcome{{true, &retryAfterError{err: errTransient, after: 5 * time.Millisecond}}}, 2, false},
+		{"Retry-After above the limit stops retrying", []fakeOutcome{{true, &retryAfterError{err: errTransient, after: maxRetryWait + time.Second}}}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeProvider{name: "fake", outcomes: tt.outcomes}
+			err := fake.Send(context.Background(), Event{Kind: EventTest})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fake.attempts != tt.wantAttempts {
+				t.Fatalf("%d attempts, want %d", fake.attempts, tt.wantAttempts)
+			}
+			if wantEvents := map[bool]int{false: 1, true: 0}[tt.wantErr]; len(fake.events) != wantEvents {
+				t.Fatalf("%d events delivered, want %d", len(fake.events), wantEvents)
+			}
+		})
+	}
+}
+
+// 等待重试期间取消时立即返回 ctx 的错误
+func TestSendWithRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fake := &fakeProvider{name: "fake", outcomes: []fakeOutcome{{true, errTransient}, {true, errTransient}}}
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := fake.Send(ctx, Event{Kind: EventTest})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if fake.attempts != 1 || time.Since(start) > retryBackoff {
+		t.Fatalf("%d attempts in %v, want 1 attempt and no full backoff", fake.attempts, time.Since(start))
+	}
+}
+
+// 内置渠道通过同一重试逻辑处理服务端错误：5xx 和 429 重试，4xx 不重试
+func TestNtfyProviderRetry(t *testing.T) {
+	fastRetry(t)
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int32
+		wantErr      bool
+	}{
+		{"success", []int{http.StatusOK}, 1, false},
+		{"server error then success", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, false},
+		{"rate limited then success", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"client error", []int{http.StatusBadRequest}, 1, true},
+		{"server keeps failing", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, maxSendAttempts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(requests.Add(1))
+				if r.Method != http.MethodPost || r.URL.Path != "/alerts" || r.Header.Get("Title") == "" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer ts.Close()
+
+			p, err := Lookup("ntfy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, _ := json.Marshal(NtfyConfig{Server: ts.URL, Topic: "alerts"})
+			err = p.Send(context.Background(), Event{Kind: EventTest, Config: cfg})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Fatalf("%d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 内置渠道
+func init() {
+	Register(emailProvider{})
+	Register(ntfyProvider{})
+	Register(gotifyProvider{})
+	Register(pushoverProvider{})
+	Register(barkProvider{})
+	Register(dingTalkProvider{})
+	Register(weComProvider{})
+	Register(pagerDutyProvider{})
+	Register(opsgenieProvider{})
+	Register(teamsProvider{})
+}
+
+// 测试消息的标题和正文
+const (
+	testTitle = "PingGo test notification"
+	testBody  = "This is a test notification from ping-go."
+)
+
+// EmailConfig 邮件渠道配置（通过 Resend 发送）
+type EmailConfig struct {
+	Email string `json:"email"` // 收件邮箱
+}
+
+// Validate 校验配置
+func (c EmailConfig) Validate() error {
+	if strings.TrimSpace(c.Email) == "" {
+		return errors.New("email is required")
+	}
+	if _, err := mail.ParseAddress(strings.TrimSpace(c.Email)); err != nil {
+		return fmt.Errorf("invalid email %q", c.Email)
+	}
+	return nil
+}
+
+type emailProvider struct{}
+
+func (emailProvider) Name() string          { return "email" }
+func (emailProvider) SupportsReports() bool { return true }
+
+func (emailProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[EmailConfig](raw)
+	return strings.TrimSpace(cfg.Email)
+}
+
+func (emailProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[EmailConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (emailProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[EmailConfig](ev.Config)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		return err
+	}
+	to := []string{strings.TrimSpace(cfg.Email)}
+
+	switch ev.Kind {
+	case EventStatusChange:
+		html, err := RenderStatusChangeEmailWith(ev.Template, ev.Status)
+		if err != nil {
+			return fmt.Errorf("failed to render status change email: %w", err)
+		}
+		return SendEmail(to, ev.Subject, html)
+	case EventDailyReport:
+		html, err := RenderDailyReportEmailWith(ev.Template, ev.Report)
+		if err != nil {
+			return fmt.Errorf("failed to render daily report email: %w", err)
+		}
+		return SendEmail(to, ev.Subject, html)
+	case EventTest:
+		return SendEmail(to, "Test Notification", testBody)
+	}
+	return ErrUnsupportedEvent
+}
+
+type ntfyProvider struct{}
+
+func (ntfyProvider) Name() string { return "ntfy" }
+
+func (ntfyProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[NtfyConfig](raw)
+	server := cfg.Server
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+	return urlHost(server) + "/" + cfg.Topic
+}
+
+func (ntfyProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[NtfyConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (ntfyProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[NtfyConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendNtfy(ctx, cfg, NtfyStatusMessage(ev.Status, cfg.Priority, ev.Link))
+	case EventTest:
+		return SendNtfy(ctx, cfg, NtfyMessage{
+			Title:    testTitle,
+			Body:     testBody,
+			Priority: NtfyPriorityDefault,
+			Tags:     []string{"test_tube"},
+			Click:    ev.Link,
+		})
+	}
+	return ErrUnsupportedEvent
+}
+
+type gotifyProvider struct{}
+
+func (gotifyProvider) Name() string { return "gotify" }
+
+func (gotifyProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[GotifyConfig](raw)
+	return urlHost(cfg.Server)
+}
+
+func (gotifyProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[GotifyConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (gotifyProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[GotifyConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendGotify(ctx, cfg, GotifyStatusMessage(ev.Status, ev.Link))
+	case EventTest:
+		return SendGotify(ctx, cfg, GotifyMessage{
+			Title:    testTitle,
+			Message:  testBody,
+			Priority: GotifyPriorityRecovery,
+			Click:    ev.Link,
+		})
+	}
+	return ErrUnsupportedEvent
+}
+
+type pushoverProvider struct{}
+
+func (pushoverProvider) Name() string { return "pushover" }
+
+func (pushoverProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[PushoverConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (pushoverProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[PushoverConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendPushover(ctx, cfg, PushoverStatusMessage(ev.Status, cfg.Emergency, ev.Link))
+	case EventTest:
+		msg := PushoverMessage{Title: testTitle, Message: testBody, Priority: PushoverPriorityNormal}
+		if ev.Link != "" {
+			msg.URL, msg.URLTitle = ev.Link, "Open PingGo dashboard"
+		}
+		return SendPushover(ctx, cfg, msg)
+	}
+	return ErrUnsupportedEvent
+}
+
+type barkProvider struct{}
+
+func (barkProvider) Name() string { return "bark" }
+
+func (barkProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[BarkConfig](raw)
+	if cfg.Server == "" {
+		return "api.day.app"
+	}
+	return urlHost(cfg.Server)
+}
+
+func (barkProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[BarkConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (barkProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[BarkConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendBark(ctx, cfg, BarkStatusMessage(cfg, ev.Status, ev.Link))
+	case EventTest:
+		return SendBark(ctx, cfg, BarkMessage{
+			Title: testTitle,
+			Body:  testBody,
+			Group: "PingGo",
+			Sound: BarkSoundRecovery,
+			URL:   ev.Link,
+		})
+	}
+	return ErrUnsupportedEvent
+}
+
+type dingTalkProvider struct{}
+
+func (dingTalkProvider) Name() string { return "dingtalk" }
+
+func (dingTalkProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[DingTalkConfig](raw)
+	return urlHost(cfg.Webhook)
+}
+
+func (dingTalkProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[DingTalkConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (dingTalkProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[DingTalkConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendDingTalk(ctx, cfg, DingTalkStatusMessage(cfg, ev.Status, ev.Link))
+	case EventTest:
+		// 测试消息同样包含 PingGo，便于验证“自定义关键词”安全设置
+		return SendDingTalk(ctx, cfg, DingTalkMessage{
+			Title: testTitle,
+			Text:  "### " + testTitle + "\n\n" + testBody,
+		})
+	}
+	return ErrUnsupportedEvent
+}
+
+type weComProvider struct{}
+
+func (weComProvider) Name() string          { return "wecom" }
+func (weComProvider) SupportsReports() bool { return true }
+
+func (weComProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[WeComConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+// Send 状态通知和日报经过机器人的发送队列（每分钟 20 条），等待发送完成；测试消息立即发送
+func (weComProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[WeComConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	var msg WeComMessage
+	switch ev.Kind {
+	case EventStatusChange:
+		msg = WeComStatusMessage(ev.Status, ev.Time)
+	case EventDailyReport:
+		msg = WeComReportMessage(ev.Report)
+	case EventTest:
+		return SendWeComNow(ctx, cfg, WeComMessage{
+			Content: "## <font color=\"info\">" + testTitle + "</font>\n> " + testBody + "\n> 时间：" + ev.Time.Format("2006-01-02 15:04:05 MST"),
+		})
+	default:
+		return ErrUnsupportedEvent
+	}
+
+	done := make(chan error, 1)
+	if err := EnqueueWeCom(cfg, msg, func(err error) { done <- err }); err != nil {
+		return err
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type pagerDutyProvider struct{}
+
+func (pagerDutyProvider) Name() string             { return "pagerduty" }
+func (pagerDutyProvider) ResolvesOnRecovery() bool { return true }
+
+func (pagerDutyProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[PagerDutyConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (pagerDutyProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[PagerDutyConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendPagerDuty(ctx, cfg, PagerDutyStatusEvent(ev.Status, ev.Link))
+	case EventTest:
+		// 发送一个测试事件后立即解决，PagerDuty 中会出现一个已解决的测试事件
+		pd := PagerDutyEvent{
+			Action:   PagerDutyTrigger,
+			DedupKey: fmt.Sprintf("pinggo-test-%d", time.Now().UnixNano()),
+			Summary:  testTitle,
+			Severity: "info",
+			Source:   "PingGo",
+			Details:  map[string]string{"message": testBody},
+			Link:     ev.Link,
+		}
+		if err := SendPagerDuty(ctx, cfg, pd); err != nil {
+			return err
+		}
+		pd.Action = PagerDutyResolve
+		if err := SendPagerDuty(ctx, cfg, pd); err != nil {
+			return fmt.Errorf("test incident %s was triggered but could not be resolved: %w", pd.DedupKey, err)
+		}
+		return nil
+	}
+	return ErrUnsupportedEvent
+}
+
+type opsgenieProvider struct{}
+
+func (opsgenieProvider) Name() string             { return "opsgenie" }
+func (opsgenieProvider) ResolvesOnRecovery() bool { return true }
+
+func (opsgenieProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[OpsgenieConfig](raw)
+	if cfg.Region == "eu" {
+		return "api.eu.opsgenie.com"
+	}
+	return "api.opsgenie.com"
+}
+
+func (opsgenieProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[OpsgenieConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (opsgenieProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[OpsgenieConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendOpsgenie(ctx, cfg, OpsgenieStatusAlert(cfg, ev.Status, ev.Link))
+	case EventTest:
+		// 创建一条测试告警后立即关闭
+		alert := OpsgenieAlert{
+			Alias:       fmt.Sprintf("pinggo-test-%d", time.Now().UnixNano()),
+			Message:     testTitle,
+			Description: testBody,
+			Priority:    "P5",
+			Tags:        []string{"PingGo", "test"},
+		}
+		if err := SendOpsgenie(ctx, cfg, alert); err != nil {
+			return err
+		}
+		alert.Close = true
+		if err := SendOpsgenie(ctx, cfg, alert); err != nil {
+			return fmt.Errorf("test alert %s was created but could not be closed: %w", alert.Alias, err)
+		}
+		return nil
+	}
+	return ErrUnsupportedEvent
+}
+
+type teamsProvider struct{}
+
+func (teamsProvider) Name() string { return "teams" }
+
+func (teamsProvider) Recipient(raw json.RawMessage) string {
+	cfg, _ := decodeConfig[TeamsConfig](raw)
+	return urlHost(cfg.Webhook)
+}
+
+func (teamsProvider) ValidateConfig(raw json.RawMessage) error {
+	cfg, err := decodeConfig[TeamsConfig](raw)
+	if err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+func (teamsProvider) Send(ctx context.Context, ev Event) error {
+	cfg, err := decodeConfig[TeamsConfig](ev.Config)
+	if err != nil {
+		return err
+	}
+	switch ev.Kind {
+	case EventStatusChange:
+		return SendTeams(ctx, cfg, TeamsStatusMessage(ev.Status, ev.Link))
+	case EventTest:
+		return SendTeams(ctx, cfg, TeamsMessage{
+			Title:     "🧪 " + testTitle,
+			Text:      testBody,
+			Facts:     []TeamsFact{{Title: "Time", Value: ev.Time.Format("2006-01-02 15:04:05 MST")}},
+			Color:     strings.TrimPrefix(ev.Status.Color, "#"),
+			LinkURL:   ev.Link,
+			LinkTitle: "Open PingGo dashboard",
+		})
+	}
+	return ErrUnsupportedEvent
+}
+
+// urlHost 只返回地址中的主机名，用于发送记录：Webhook 的路径和查询参数中通常含有 token
+func urlHost(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Hostname()
+}
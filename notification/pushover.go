@@ -0,0 +1,185 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pushoverAPI Pushover 消息接口
+var pushoverAPI = "https://api.pushover.net/1/messages.json"
+
+// Pushover 消息优先级：DOWN 为 high(1)，开启紧急模式时为 emergency(2)，恢复为 normal(0)
+const (
+	PushoverPriorityNormal    = 0
+	PushoverPriorityHigh      = 1
+	PushoverPriorityEmergency = 2
+)
+
+// 紧急通知的重复提醒间隔和过期时间（秒），取值范围由 Pushover 规定
+const (
+	DefaultPushoverRetry  = 60
+	DefaultPushoverExpire = 3600
+	minPushoverRetry      = 30
+	maxPushoverExpire     = 10800
+)
+
+// PushoverConfig Pushover 渠道配置，字段与触发规则配置中的 JSON key 对应
+type PushoverConfig struct {
+	User      string `json:"pushover_user"`      // 用户或分组 key
+	Token     string `json:"pushover_token"`     // 应用 API token
+	Emergency bool   `json:"pushover_emergency"` // DOWN 通知使用紧急优先级(2)，需要在客户端确认
+	Retry     int    `json:"pushover_retry"`     // 紧急通知重复提醒间隔（秒），0 表示 60
+	Expire    int    `json:"pushover_expire"`    // 紧急通知停止重复提醒的时间（秒），0 表示 3600
+}
+
+// PushoverMessage 一条 Pushover 消息
+type PushoverMessage struct {
+	Title    string
+	Message  string
+	Priority int
+	URL      string // 补充链接，指向控制台
+	URLTitle string
+}
+
+// Validate 校验配置
+func (c PushoverConfig) Validate() error {
+	if strings.TrimSpace(c.User) == "" {
+		return errors.New("pushover_user is required")
+	}
+	if strings.TrimSpace(c.Token) == "" {
+		return errors.New("pushover_token is required")
+	}
+	if c.Retry != 0 && c.Retry < minPushoverRetry {
+		return fmt.Errorf("pushover_retry must be at least %d seconds", minPushoverRetry)
+	}
+	if c.Expire < 0 || c.Expire > maxPushoverExpire {
+		return fmt.Errorf("pushover_expire must be between 1 and %d seconds", maxPushoverExpire)
+	}
+	return nil
+}
+
+// PushoverStatusMessage 用与邮件相同的状态变化数据构造消息：DOWN 为 high（或紧急）优先级，恢复为 normal
+func PushoverStatusMessage(d StatusChangeData, emergency bool, dashboard string) PushoverMessage {
+	msg := PushoverMessage{
+		Title:    fmt.Sprintf("%s is %s", d.Name, d.NewStatus),
+		Priority: PushoverPriorityHigh,
+	}
+	switch {
+	case d.NewStatus == "UP":
+		msg.Priority = PushoverPriorityNormal
+	case emergency:
+		msg.Priority = PushoverPriorityEmergency
+	}
+	if dashboard != "" {
+		msg.URL, msg.URLTitle = dashboard, "Open PingGo dashboard"
+	}
+
+	lines := []string{d.StatusText, fmt.Sprintf("%s → %s", d.OldStatus, d.NewStatus)}
+	if d.Condition != "" {
+		lines = append(lines, d.Condition)
+	}
+	if o := d.OutageSummary(); o != "" {
+		lines = append(lines, o)
+	}
+	if d.URL != "" {
+		lines = append(lines, d.URL)
+	}
+	switch {
+	case d.Message != "":
+		lines = append(lines, d.Message)
+	case d.Redacted:
+		lines = append(lines, "Details available in dashboard")
+	}
+	lines = append(lines, d.DateTime)
+	msg.Message = strings.Join(lines, "\n")
+	return msg
+}
+
+// pushoverClient 发送请求使用的客户端
+var pushoverClient = &http.Client{Timeout: 15 * time.Second}
+
+// SendPushover 发送一条 Pushover 消息。网络错误和 5xx 至少等待 5 秒后重试，429（应用额度用尽）按 Retry-After 重试；
+// 4xx 校验错误不重试，返回的错误包含 Pushover 响应中的 errors 内容
+func SendPushover(ctx context.Context, cfg PushoverConfig, msg PushoverMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "Pushover message", func() (bool, error) {
+		return postPushover(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send Pushover message: %w", err)
+	}
+	return nil
+}
+
+// postPushover 发送一次请求，返回失败是否值得重试
+func postPushover(ctx context.Context, cfg PushoverConfig, msg PushoverMessage) (bool, error) {
+	form := url.Values{
+		"token":    {strings.TrimSpace(cfg.Token)},
+		"user":     {strings.TrimSpace(cfg.User)},
+		"title":    {msg.Title},
+		"message":  {msg.Message},
+		"priority": {strconv.Itoa(msg.Priority)},
+	}
+	if msg.URL != "" {
+		form.Set("url", msg.URL)
+		form.Set("url_title", msg.URLTitle)
+	}
+	if msg.Priority == PushoverPriorityEmergency {
+		retry, expire := cfg.Retry, cfg.Expire
+		if retry == 0 {
+			retry = DefaultPushoverRetry
+		}
+		if expire == 0 {
+			expire = DefaultPushoverExpire
+		}
+		form.Set("retry", strconv.Itoa(retry))
+		form.Set("expire", strconv.Itoa(expire))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushoverAPI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := pushoverClient.Do(req)
+	if err != nil {
+		return true, &retryAfterError{err: err, after: 5 * time.Second}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	err = pushoverError(resp)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, &retryAfterError{err: err, after: retryAfter(resp, 5*time.Second)}
+	case resp.StatusCode >= 500:
+		return true, &retryAfterError{err: err, after: 5 * time.Second}
+	default:
+		return false, err
+	}
+}
+
+// pushoverError 解析 Pushover 的错误响应（{"status":0,"errors":[...]}），无法解析时返回原始内容
+func pushoverError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	var e struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(raw, &e) == nil && len(e.Errors) > 0 {
+		return fmt.Errorf("pushover returned HTTP %d: %s", resp.StatusCode, strings.Join(e.Errors, "; "))
+	}
+	return fmt.Errorf("pushover returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+}
@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxSendAttempts 推送渠道每条消息的最大尝试次数
+const maxSendAttempts = 3
+
+// retryBackoff 第一次重试前的等待时间，之后每次增加一倍基数（2s、4s）；测试中缩短
+var retryBackoff = 2 * time.Second
+
+// maxRetryWait Retry-After 等服务端要求的等待时间上限，超过时不再重试
+const maxRetryWait = 2 * time.Minute
+
+// retryAfterError 服务端要求至少等待 after 后再重试（429 的 Retry-After、Pushover 5xx 的最少 5 秒等）
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// retryAfter 读取响应的 Retry-After（秒数或 HTTP 日期），没有时返回 min
+func retryAfter(resp *http.Response, min time.Duration) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return min
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, min)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), min)
+	}
+	return min
+}
+
+// sendWithRetry 各推送渠道共用的重试：attempt 发送一次并返回失败是否值得重试，
+// 重试间隔为 2s、4s，返回 retryAfterError 时至少等待其要求的时间；what 用于日志
+func sendWithRetry(ctx context.Context, what string, attempt func() (bool, error)) error {
+	var err error
+	for i := 0; i < maxSendAttempts; i++ {
+		var retry bool
+		retry, err = attempt()
+		if err == nil {
+			return nil
+		}
+		log.Printf("ERROR: Failed to send %s (attempt %d/%d): %v", what, i+1, maxSendAttempts, err)
+		if !retry || i == maxSendAttempts-1 {
+			break
+		}
+		wait := retryBackoff * time.Duration(i+1)
+		var ra *retryAfterError
+		if errors.As(err, &ra) {
+			if ra.after > maxRetryWait {
+				break
+			}
+			wait = max(wait, ra.after)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
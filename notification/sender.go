@@ -0,0 +1,181 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"ping-go/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/resend/resend-go/v3"
+)
+
+const (
+	// defaultFromEmail 未配置 from_email 时 Resend 使用的测试发件人，很容易被当作垃圾邮件或被拒收
+	defaultFromEmail = "onboarding@resend.dev"
+	defaultFromName  = "PingGo Monitor"
+)
+
+// SenderStatus 发件人配置的检查结果
+type SenderStatus struct {
+	From      string    `json:"from"`     // 实际使用的 From
+	Domain    string    `json:"domain"`   // 发件域名
+	Fallback  bool      `json:"fallback"` // 未配置 from_email，使用 Resend 默认发件人
+	Verified  bool      `json:"verified"`
+	Method    string    `json:"method,omitempty"` // resend_api 或 dns，未检查时为空
+	Detail    string    `json:"detail"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Warning 发件人存在问题时返回提示，正常时返回空字符串
+func (s SenderStatus) Warning() string {
+	if s.Fallback {
+		return "notification.from_email 未配置，邮件将以 " + defaultFromEmail + " 发出，可能进入垃圾箱或被拒收"
+	}
+	if !s.Verified {
+		return fmt.Sprintf("发件域名 %s 未通过验证（%s），邮件可能进入垃圾箱或被拒收", s.Domain, s.Detail)
+	}
+	return ""
+}
+
+var (
+	senderMu     sync.Mutex
+	senderStatus *SenderStatus
+)
+
+// resendAPIKey 返回配置或环境变量中的 Resend API Key
+func resendAPIKey() string {
+	if key := config.Get().Notification.ResendAPIKey; key != "" {
+		return key
+	}
+	return os.Getenv("RESEND_API_KEY")
+}
+
+// EffectiveFrom 返回发送邮件时实际使用的 From
+func EffectiveFrom() string {
+	cfg := config.Get().Notification
+	fromEmail := cfg.FromEmail
+	if fromEmail == "" {
+		fromEmail = defaultFromEmail
+	}
+	fromName := cfg.FromName
+	if fromName == "" {
+		fromName = defaultFromName
+	}
+	return fmt.Sprintf("%s <%s>", fromName, fromEmail)
+}
+
+// Sender 返回最近一次发件人检查的结果，尚未检查时只包含 From 信息
+func Sender() SenderStatus {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	if senderStatus != nil {
+		return *senderStatus
+	}
+	st := newSenderStatus()
+	st.Detail = "not checked yet"
+	return st
+}
+
+// newSenderStatus 按当前配置构造未检查的发件人状态
+func newSenderStatus() SenderStatus {
+	fromEmail := config.Get().Notification.FromEmail
+	st := SenderStatus{From: EffectiveFrom(), Fallback: fromEmail == ""}
+	if st.Fallback {
+		fromEmail = defaultFromEmail
+	}
+	st.Domain = senderDomain(fromEmail)
+	return st
+}
+
+// CheckSender 检查发件域名是否已验证：优先查询 Resend 的域名列表，
+// API Key 没有读取域名的权限时改为检查 Resend 要求的 DKIM/SPF DNS 记录。结果会被缓存供 Sender 返回
+func CheckSender(ctx context.Context) SenderStatus {
+	st := newSenderStatus()
+	st.CheckedAt = time.Now()
+
+	switch {
+	case st.Fallback:
+		st.Detail = "from_email is not set, using the Resend test sender"
+	case st.Domain == "":
+		st.Detail = "from_email is not a valid address"
+	default:
+		var apiErr error
+		if key := resendAPIKey(); key != "" && key != "YOUR_RESEND_API_KEY" {
+			apiErr = checkResendDomain(ctx, resend.NewClient(key), &st)
+		}
+		if st.Method == "" {
+			checkSenderDNS(ctx, &st)
+			if apiErr != nil {
+				st.Detail += fmt.Sprintf(" (Resend API unavailable: %v)", apiErr)
+			}
+		}
+	}
+
+	senderMu.Lock()
+	senderStatus = &st
+	senderMu.Unlock()
+	return st
+}
+
+// checkResendDomain 在 Resend 的域名列表中查找发件域名（或其上级域名）
+func checkResendDomain(ctx context.Context, client *resend.Client, st *SenderStatus) error {
+	resp, err := client.Domains.ListWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	st.Method = "resend_api"
+	for _, d := range resp.Data {
+		name := strings.ToLower(d.Name)
+		if st.Domain == name || strings.HasSuffix(st.Domain, "."+name) {
+			st.Verified = d.Status == "verified"
+			st.Detail = "Resend domain " + d.Name + " is " + d.Status
+			return nil
+		}
+	}
+	st.Detail = "domain is not added to the Resend account"
+	return nil
+}
+
+// checkSenderDNS 检查 Resend 使用的 DKIM（resend._domainkey）和 SPF（send 子域名）记录是否存在
+func checkSenderDNS(ctx context.Context, st *SenderStatus) {
+	st.Method = "dns"
+	var missing []string
+	if !hasTXT(ctx, "resend._domainkey."+st.Domain, "p=") {
+		missing = append(missing, "DKIM (resend._domainkey)")
+	}
+	if !hasTXT(ctx, "send."+st.Domain, "v=spf1") {
+		missing = append(missing, "SPF (send."+st.Domain+")")
+	}
+	st.Verified = len(missing) == 0
+	if st.Verified {
+		st.Detail = "DKIM and SPF records found"
+	} else {
+		st.Detail = "missing " + strings.Join(missing, ", ")
+	}
+}
+
+func hasTXT(ctx context.Context, name, contains string) bool {
+	records, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil {
+		return false
+	}
+	for _, r := range records {
+		if strings.Contains(r, contains) {
+			return true
+		}
+	}
+	return false
+}
+
+// senderDomain 返回邮箱地址的域名部分（小写），无效地址返回空字符串
+func senderDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+const (
+	// sparklineBars 迷你图最多显示的柱数（最近 24 小时，每小时一柱）
+	sparklineBars = 24
+	// ReportSparklineBudget 一封日报中所有迷你图的总字节数上限，超过后其余行不再显示迷你图，
+	// 避免监控项较多时邮件超过 MaxEmailBytes
+	ReportSparklineBudget = 32 * 1024
+
+	sparklineBarWidth = 3
+	sparklineGap      = 1
+	sparklineHeight   = 20
+	sparklineMinBar   = 2 // 可用率很低时仍保留的最小柱高，和没有数据区分开
+)
+
+// UptimeSparkline 把每小时的可用率（0-100，按时间升序，负数表示该小时没有数据）渲染为内联 SVG 柱状图，
+// 返回 data URI，可直接用作 <img src>。相同颜色的柱合并为一个 path，24 柱的结果不超过 1KB
+func UptimeSparkline(hourly []float64) template.URL {
+	if len(hourly) > sparklineBars {
+		hourly = hourly[len(hourly)-sparklineBars:]
+	}
+	if len(hourly) == 0 {
+		return ""
+	}
+
+	paths := make(map[string]*strings.Builder)
+	var order []string
+	for i, v := range hourly {
+		color, h := "#cbd5e1", sparklineMinBar
+		if v >= 0 {
+			v = min(v, 100)
+			h = sparklineMinBar + int(v/100*float64(sparklineHeight-sparklineMinBar)+0.5)
+			switch {
+			case v >= 99:
+				color = "#2ecc71"
+			case v >= 90:
+				color = "#e67e22"
+			default:
+				color = "#e74c3c"
+			}
+		}
+		b, ok := paths[color]
+		if !ok {
+			b = &strings.Builder{}
+			paths[color] = b
+			order = append(order, color)
+		}
+		fmt.Fprintf(b, "M%d %dh%dv%dh-%dz", i*(sparklineBarWidth+sparklineGap), sparklineHeight-h, sparklineBarWidth, h, sparklineBarWidth)
+	}
+
+	width := len(hourly)*(sparklineBarWidth+sparklineGap) - sparklineGap
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, sparklineHeight, width, sparklineHeight)
+	for _, color := range order {
+		fmt.Fprintf(&svg, `<path fill="%s" d="%s"/>`, color, paths[color].String())
+	}
+	svg.WriteString("</svg>")
+	return template.URL("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg.String())))
+}
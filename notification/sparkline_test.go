@@ -0,0 +1,127 @@
+package notification
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	stdhtml "html"
+	"io"
+	"math"
+	"ping-go/pkg/i18n"
+	"strings"
+	"testing"
+)
+
+// decodeSparkline 解码 data URI 并解析 SVG，返回根元素的属性和 path 数
+func decodeSparkline(t *testing.T, uri string) (attrs map[string]string, paths int, raw string) {
+	t.Helper()
+	const prefix = "data:image/svg+xml;base64,"
+	if !strings.HasPrefix(uri, prefix) {
+		t.Fatalf("not an SVG data URI: %.40q", uri)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, prefix))
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	attrs = make(map[string]string)
+	dec := xml.NewDecoder(strings.NewReader(string(data)))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatalf("invalid SVG %s: %v", data, err)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			switch el.Name.Local {
+			case "svg":
+				for _, a := range el.Attr {
+					attrs[a.Name.Local] = a.Value
+				}
+			case "path":
+				paths++
+			}
+		}
+	}
+	return attrs, paths, string(data)
+}
+
+func TestUptimeSparkline(t *testing.T) {
+	tests := []struct {
+		name      string
+		hourly    []float64
+		wantWidth string
+		wantPaths int
+	}{
+		{"single hour", []float64{100}, "3", 1},
+		{"no data hours use their own color", []float64{-1, 100}, "7", 2},
+		{"three colors", []float64{100, 95, 10}, "11", 3},
+		{"full day", make([]float64, 24), "95", 1},
+		{"longer series keeps the last 24 hours", make([]float64, 48), "95", 1},
+		{"values above 100 are clamped", []float64{250, 100}, "7", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs, paths, raw := decodeSparkline(t, string(UptimeSparkline(tt.hourly)))
+			if attrs["width"] != tt.wantWidth || attrs["height"] != "20" {
+				t.Errorf("size = %sx%s, want %sx20", attrs["width"], attrs["height"], tt.wantWidth)
+			}
+			if attrs["viewBox"] != "0 0 "+tt.wantWidth+" 20" {
+				t.Errorf("viewBox = %q", attrs["viewBox"])
+			}
+			if paths != tt.wantPaths {
+				t.Errorf("%d paths, want %d: %s", paths, tt.wantPaths, raw)
+			}
+		})
+	}
+}
+
+func TestUptimeSparklineEmpty(t *testing.T) {
+	if got := UptimeSparkline(nil); got != "" {
+		t.Fatalf("nil series = %q, want empty", got)
+	}
+	if got := UptimeSparkline([]float64{}); got != "" {
+		t.Fatalf("empty series = %q, want empty", got)
+	}
+}
+
+func TestUptimeSparklineBoundedSize(t *testing.T) {
+	// 颜色交替时 path 最长
+	hourly := make([]float64, 24)
+	for i := range hourly {
+		hourly[i] = []float64{-1, 100, 95, 10}[i%4]
+	}
+	uri := UptimeSparkline(hourly)
+	if len(uri) > 1024 {
+		t.Fatalf("24-bar sparkline is %d bytes, want at most 1KB", len(uri))
+	}
+	// 预算内可以容纳的迷你图数量足够覆盖常见的监控项数
+	if n := ReportSparklineBudget / len(uri); n < 30 {
+		t.Fatalf("only %d worst-case sparklines fit in the report budget", n)
+	}
+	// NaN 按没有数据处理，+Inf 按 100 处理
+	if _, _, raw := decodeSparkline(t, string(UptimeSparkline([]float64{math.NaN()}))); strings.Contains(raw, "NaN") {
+		t.Fatalf("NaN in SVG: %s", raw)
+	}
+	if _, _, raw := decodeSparkline(t, string(UptimeSparkline([]float64{math.Inf(1)}))); strings.Contains(raw, "Inf") {
+		t.Fatalf("Inf in SVG: %s", raw)
+	}
+}
+
+// 迷你图是 template.URL，嵌入邮件时不能被 html/template 过滤为 #ZgotmplZ
+func TestUptimeSparklineInEmail(t *testing.T) {
+	data := DailyReportFixture(i18n.DefaultLanguage)
+	spark := UptimeSparkline([]float64{100, 40})
+	data.Monitors[0].Sparkline = spark
+	html, err := RenderDailyReportEmail(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html, "ZgotmplZ") {
+		t.Fatal("sparkline URI was rejected by the HTML escaper")
+	}
+	// 属性值中的 + 会被转义为 &#43;，浏览器解码后与原 URI 相同
+	if !strings.Contains(stdhtml.UnescapeString(html), `src="`+string(spark)+`"`) {
+		t.Fatal("sparkline data URI not embedded intact")
+	}
+}
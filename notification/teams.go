@@ -0,0 +1,231 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Teams 消息格式
+const (
+	TeamsFormatAdaptive    = "adaptive"    // Adaptive Card，Workflows（Power Automate）创建的 webhook 使用
+	TeamsFormatMessageCard = "messagecard" // 旧版 Office 365 Connector 的 MessageCard
+)
+
+// teamsMaxText 正文中检查消息的长度上限，Teams 单条消息约 28KB，超过时拒收
+const teamsMaxText = 4000
+
+// TeamsConfig Microsoft Teams 渠道配置，字段与触发规则配置中的 JSON key 对应
+type TeamsConfig struct {
+	Webhook string `json:"teams_webhook"` // Incoming Webhook 或 Workflows 的 HTTP POST 地址
+	Format  string `json:"teams_format"`  // "adaptive"（默认）或 "messagecard"
+}
+
+// TeamsFact 卡片中的一行键值
+type TeamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// TeamsMessage 一条 Teams 卡片消息
+type TeamsMessage struct {
+	Title     string
+	Text      string // 检查消息，可能为空
+	Facts     []TeamsFact
+	Down      bool   // 决定卡片的强调色：DOWN 为 attention（红），恢复为 good（绿）
+	Color     string // MessageCard 的 themeColor（十六进制，不含 #）
+	LinkURL   string // 按钮链接，为空时不显示按钮
+	LinkTitle string
+}
+
+// Validate 校验配置
+func (c TeamsConfig) Validate() error {
+	if strings.TrimSpace(c.Webhook) == "" {
+		return errors.New("teams_webhook is required")
+	}
+	u, err := url.Parse(strings.TrimSpace(c.Webhook))
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("invalid teams_webhook (use the https URL of an Incoming Webhook or a Workflows HTTP trigger)")
+	}
+	switch c.format() {
+	case TeamsFormatAdaptive, TeamsFormatMessageCard:
+	default:
+		return fmt.Errorf("invalid teams_format %q (use adaptive or messagecard)", c.Format)
+	}
+	return nil
+}
+
+func (c TeamsConfig) format() string {
+	if c.Format == "" {
+		return TeamsFormatAdaptive
+	}
+	return strings.ToLower(c.Format)
+}
+
+// teamsStatusEmoji 标题前的状态图标
+func teamsStatusEmoji(status string) string {
+	switch status {
+	case "UP":
+		return "✅"
+	case "DOWN":
+		return "🔴"
+	default:
+		return "⚠️"
+	}
+}
+
+// TeamsStatusMessage 用与邮件相同的状态变化数据构造卡片：标题带状态图标，事实列表包含名称、类型、前后状态和时间，
+// 按钮指向控制台（dashboard 为空时不显示）
+func TeamsStatusMessage(d StatusChangeData, dashboard string) TeamsMessage {
+	msg := TeamsMessage{
+		Title:     fmt.Sprintf("%s %s is %s", teamsStatusEmoji(d.NewStatus), d.Name, d.NewStatus),
+		Down:      d.NewStatus != "UP",
+		Color:     strings.TrimPrefix(d.Color, "#"),
+		LinkURL:   dashboard,
+		LinkTitle: "Open PingGo dashboard",
+	}
+	msg.Facts = append(msg.Facts, TeamsFact{"Monitor", d.Name})
+	if d.MonitorType != "" {
+		msg.Facts = append(msg.Facts, TeamsFact{"Type", d.MonitorType})
+	}
+	msg.Facts = append(msg.Facts, TeamsFact{"Previous status", d.OldStatus}, TeamsFact{"Current status", d.NewStatus})
+	if d.URL != "" {
+		msg.Facts = append(msg.Facts, TeamsFact{"URL", d.URL})
+	}
+	if d.Condition != "" {
+		msg.Facts = append(msg.Facts, TeamsFact{"Condition", d.Condition})
+	}
+	if o := d.OutageSummary(); o != "" {
+		msg.Facts = append(msg.Facts, TeamsFact{"Outage", o})
+	}
+	msg.Facts = append(msg.Facts, TeamsFact{"Time", d.DateTime})
+	switch {
+	case d.Message != "":
+		msg.Text = truncateRunes(d.Message, teamsMaxText)
+	case d.Redacted:
+		msg.Text = "Details available in dashboard"
+	}
+	return msg
+}
+
+// teamsPayload 按格式生成请求体
+func teamsPayload(format string, msg TeamsMessage) map[string]any {
+	if format == TeamsFormatMessageCard {
+		card := map[string]any{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    msg.Title,
+			"themeColor": msg.Color,
+			"title":      msg.Title,
+			"sections":   []map[string]any{{"facts": messageCardFacts(msg.Facts), "text": msg.Text}},
+		}
+		if msg.LinkURL != "" {
+			card["potentialAction"] = []map[string]any{{
+				"@type": "OpenUri", "name": msg.LinkTitle,
+				"targets": []map[string]string{{"os": "default", "uri": msg.LinkURL}},
+			}}
+		}
+		return card
+	}
+
+	style, color := "good", "Good"
+	if msg.Down {
+		style, color = "attention", "Attention"
+	}
+	body := []map[string]any{
+		{
+			"type": "Container", "style": style, "bleed": true,
+			"items": []map[string]any{{"type": "TextBlock", "text": msg.Title, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true}},
+		},
+		{"type": "FactSet", "facts": msg.Facts},
+	}
+	if msg.Text != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": msg.Text, "wrap": true, "fontType": "Monospace", "isSubtle": true})
+	}
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+		"msteams": map[string]any{"width": "Full"},
+	}
+	if msg.LinkURL != "" {
+		card["actions"] = []map[string]any{{"type": "Action.OpenUrl", "title": msg.LinkTitle, "url": msg.LinkURL}}
+	}
+	return map[string]any{
+		"type":        "message",
+		"attachments": []map[string]any{{"contentType": "application/vnd.microsoft.card.adaptive", "content": card}},
+	}
+}
+
+// messageCardFacts MessageCard 的事实列表使用 name/value
+func messageCardFacts(facts []TeamsFact) []map[string]string {
+	out := make([]map[string]string, len(facts))
+	for i, f := range facts {
+		out[i] = map[string]string{"name": f.Title, "value": f.Value}
+	}
+	return out
+}
+
+// teamsClient 发送请求使用的客户端
+var teamsClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendTeams 发送一条卡片消息。网络错误、5xx 和 429 限流重试，429 按 Retry-After 等待；
+// 旧版 Connector 限流时返回 HTTP 200 并在正文中说明 429，同样按限流处理
+func SendTeams(ctx context.Context, cfg TeamsConfig, msg TeamsMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "Teams message", func() (bool, error) {
+		return postTeams(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send Teams message: %w", err)
+	}
+	return nil
+}
+
+// postTeams 发送一次请求，返回失败是否值得重试
+func postTeams(ctx context.Context, cfg TeamsConfig, msg TeamsMessage) (bool, error) {
+	body, err := json.Marshal(teamsPayload(cfg.format(), msg))
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSpace(cfg.Webhook), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := teamsClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	detail := strings.TrimSpace(string(raw))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		err = fmt.Errorf("teams returned HTTP 429 (throttled): %s", detail)
+		return true, &retryAfterError{err: err, after: retryAfter(resp, 5*time.Second)}
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if strings.Contains(detail, "HTTP error 429") {
+			err = fmt.Errorf("teams throttled the message: %s", detail)
+			return true, &retryAfterError{err: err, after: retryAfter(resp, 5*time.Second)}
+		}
+		return false, nil
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("teams returned HTTP %d: %s", resp.StatusCode, detail)
+	case resp.StatusCode == http.StatusBadRequest:
+		return false, fmt.Errorf("teams rejected the card (HTTP 400): %s (check teams_format matches the webhook type)", detail)
+	}
+	return false, fmt.Errorf("teams returned HTTP %d: %s", resp.StatusCode, detail)
+}
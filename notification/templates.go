@@ -2,19 +2,56 @@ package notification
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"html/template"
+	"ping-go/pkg/i18n"
+	"regexp"
+	"strings"
 )
 
 // StatusChangeData holds data for the status change email template
 type StatusChangeData struct {
-	Name       string
-	URL        string
-	OldStatus  string
-	NewStatus  string
-	Message    string
-	Color      string
-	StatusText string
-	DateTime   string
+	Name        string
+	URL         string
+	OldStatus   string
+	NewStatus   string
+	Message     string
+	Color       string
+	StatusText  string
+	DateTime    string
+	Duration    string // 宕机通知为本轮失败已持续的时间，恢复通知为宕机总时长，持续宕机提醒为已宕机时长
+	DownSince   string // 本次宕机开始的时间（第一次失败的检查，显示时区），只有恢复、持续宕机提醒和升级通知有
+	Redacted    bool   // 消息和地址已被移除，邮件中提示到控制台查看详情
+	Condition   string // 满足的触发条件，如 "down for 3m12s, threshold 2m"，有被抑制的通知时附带其数量；恢复和提醒通知通常为空
+	Suppressed  int    // 上一条通知发出后因限流或抖动被抑制的通知数
+	MonitorID   uint   // 监控项 ID，PagerDuty 和 Opsgenie 用来关联宕机和恢复
+	MonitorType string // 监控类型（http、tcp 等）
+	IncidentURL string // 控制台事件视图的地址，未配置 server.external_url 时为空
+	Lang        string // 邮件语言（zh / en），选择内置模板和 OutageSummary 的文案，为空时使用默认语言
+	SiteName    string // 站点名称（设置项 siteName），显示在邮件页脚
+}
+
+// OutageSummary 宕机时长说明：恢复通知为 "DOWN for 1h23m (since 2024-05-01 03:12)"，
+// 持续宕机时为 "DOWN since 2024-05-01 03:12"，没有 DownSince 时为空
+func (d StatusChangeData) OutageSummary() string {
+	if d.DownSince == "" {
+		return ""
+	}
+	lang := EmailLanguage(d.Lang)
+	if d.NewStatus == "UP" && d.Duration != "" {
+		return fmt.Sprintf(i18n.T(lang, "notify.outage"), d.Duration, d.DownSince)
+	}
+	return fmt.Sprintf(i18n.T(lang, "notify.down_since"), d.DownSince)
+}
+
+// Redact 返回只保留监控名称、状态和时间的副本，用于不可信的通知渠道：
+// 检查消息可能包含响应体等内部信息，URL 可能暴露内网地址
+func (d StatusChangeData) Redact() StatusChangeData {
+	d.URL = ""
+	d.Message = ""
+	d.Redacted = true
+	return d
 }
 
 // DailyReportData holds data for the daily report email template
@@ -25,6 +62,8 @@ type DailyReportData struct {
 	DownCount     int
 	DownColor     string
 	Monitors      []MonitorInfo
+	Lang          string // 邮件语言，选择内置模板
+	SiteName      string // 站点名称（设置项 siteName），显示在标题和页脚
 }
 
 // MonitorInfo holds individual monitor stats for the report
@@ -33,12 +72,17 @@ type MonitorInfo struct {
 	Type           string
 	Uptime24h      float64
 	AvgResponse24h int64
-	Status         string
-	Color          string
+	Status         string // 本地化的状态名
+	StatusKey      string // 状态的规范 key（up/down/...）
+	Color          string // 状态标签颜色：监控项设置了颜色时为该颜色，否则为状态颜色
 	UptimeColor    string
 	RowBg          string
+	DetailURL      string       // 控制台中该监控项的详情页，未配置 server.external_url 时为空
+	Sparkline      template.URL // 最近 24 小时每小时可用率的迷你图（data URI），超出 ReportSparklineBudget 时为空
+	LongestOutage  string       // 最近 24 小时最长的一次宕机，如 "1h23m (since 03:12)"，没有宕机时为空
 }
 
+// 内置模板的源码：[[key]] 为随邮件语言替换的文案（pkg/i18n 中的 email.key），见 builtinTemplates
 const statusChangeTemplate = `
 <!DOCTYPE html>
 <html>
@@ -58,34 +102,49 @@ const statusChangeTemplate = `
 		<div style="padding: 30px 40px; background-color: #ffffff;">
 			<div style="text-align: center; margin-bottom: 30px; padding-bottom: 30px; border-bottom: 1px solid #f1f5f9;">
 				<div style="font-size: 20px; font-weight: 700; color: #1e293b; margin-bottom: 5px;">{{.Name}}</div>
-				<a href="{{.URL}}" style="font-size: 14px; color: #64748b; text-decoration: none; word-break: break-all;">{{.URL}}</a>
+				{{if .URL}}<a href="{{.URL}}" style="font-size: 14px; color: #64748b; text-decoration: none; word-break: break-all;">{{.URL}}</a>{{end}}
 			</div>
 
 			<div style="display: flex; justify-content: space-between; align-items: center; margin-bottom: 30px; background-color: #f8fafc; padding: 20px; border-radius: 8px;">
 				<div style="text-align: center; flex: 1;">
-					<div style="font-size: 12px; color: #94a3b8; text-transform: uppercase; font-weight: 600; margin-bottom: 5px;">Previous Status</div>
+					<div style="font-size: 12px; color: #94a3b8; text-transform: uppercase; font-weight: 600; margin-bottom: 5px;">[[previous_status]]</div>
 					<div style="font-size: 16px; font-weight: 700; color: #64748b;">{{.OldStatus}}</div>
 				</div>
 				<div style="color: #cbd5e1; font-size: 20px;">&rarr;</div>
 				<div style="text-align: center; flex: 1;">
-					<div style="font-size: 12px; color: #94a3b8; text-transform: uppercase; font-weight: 600; margin-bottom: 5px;">Current Status</div>
+					<div style="font-size: 12px; color: #94a3b8; text-transform: uppercase; font-weight: 600; margin-bottom: 5px;">[[current_status]]</div>
 					<div style="font-size: 16px; font-weight: 700; color: {{.Color}};">{{.NewStatus}}</div>
 				</div>
 			</div>
+			{{if .Condition}}
+			<div style="margin: -15px 0 30px; text-align: center; color: #64748b; font-size: 13px;">[[triggered]]{{.Condition}}</div>
+			{{end}}
+			{{with .OutageSummary}}
+			<div style="margin: -15px 0 30px; text-align: center; color: #64748b; font-size: 13px;">{{.}}</div>
+			{{end}}
+			{{if .IncidentURL}}
+			<div style="margin: -10px 0 30px; text-align: center;">
+				<a href="{{.IncidentURL}}" style="display: inline-block; padding: 10px 24px; background-color: #1e293b; color: #ffffff; font-size: 14px; font-weight: 600; text-decoration: none; border-radius: 6px;">[[view_incident]]</a>
+			</div>
+			{{end}}
 
 			<!-- Details -->
 			<div style="background-color: #fff; border: 1px solid #e2e8f0; border-radius: 8px; overflow: hidden;">
 				<div style="padding: 12px 20px; background-color: #f8fafc; border-bottom: 1px solid #e2e8f0; font-size: 13px; font-weight: 600; color: #475569; text-transform: uppercase;">
-					Message Detail
+					[[message_detail]]
 				</div>
+				{{if .Message}}
 				<div style="padding: 20px; color: #334155; font-size: 14px; line-height: 1.6; font-family: monospace; white-space: pre-wrap;">{{.Message}}</div>
+				{{else}}
+				<div style="padding: 20px; color: #94a3b8; font-size: 14px; line-height: 1.6; font-style: italic;">{{if .Redacted}}[[details_in_dashboard]]{{else}}[[no_message]]{{end}}</div>
+				{{end}}
 			</div>
 		</div>
 
 		<!-- Footer -->
 		<div style="padding: 20px 40px; background-color: #f1f5f9; text-align: center; border-bottom-left-radius: 12px; border-bottom-right-radius: 12px;">
 			<p style="margin: 0; color: #94a3b8; font-size: 12px;">
-				PingGo Monitor System
+				{{.SiteName}}
 			</p>
 		</div>
 	</div>
@@ -104,7 +163,7 @@ const dailyReportTemplate = `
 	<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff; border-radius: 12px; overflow: hidden; box-shadow: 0 4px 6px rgba(0,0,0,0.05); margin-top: 20px; margin-bottom: 20px;">
 		<!-- Header -->
 		<div style="background-color: #2ecc71; padding: 30px 40px; text-align: center;">
-			<h1 style="margin: 0; color: #ffffff; font-size: 24px; font-weight: 700; letter-spacing: 0.5px;">PingGo 每日速报</h1>
+			<h1 style="margin: 0; color: #ffffff; font-size: 24px; font-weight: 700; letter-spacing: 0.5px;">{{.SiteName}} [[report_title]]</h1>
 			<p style="margin: 10px 0 0; color: rgba(255,255,255,0.9); font-size: 14px;">{{.Date}}</p>
 		</div>
 
@@ -112,15 +171,15 @@ const dailyReportTemplate = `
 		<div style="padding: 30px 40px; background-color: #f8f9fa; border-bottom: 1px solid #edf2f7;">
 			<div style="display: grid; grid-template-columns: repeat(3, 1fr); gap: 15px; text-align: center;">
 				<div style="background: white; padding: 15px; border-radius: 8px; border: 1px solid #e2e8f0;">
-					<div style="font-size: 12px; color: #64748b; text-transform: uppercase; font-weight: 600;">监控总数</div>
+					<div style="font-size: 12px; color: #64748b; text-transform: uppercase; font-weight: 600;">[[total]]</div>
 					<div style="font-size: 24px; font-weight: 800; color: #1e293b; margin-top: 5px;">{{.TotalCount}}</div>
 				</div>
 				<div style="background: white; padding: 15px; border-radius: 8px; border: 1px solid #e2e8f0;">
-					<div style="font-size: 12px; color: #64748b; text-transform: uppercase; font-weight: 600;">系统在线率</div>
+					<div style="font-size: 12px; color: #64748b; text-transform: uppercase; font-weight: 600;">[[uptime]]</div>
 					<div style="font-size: 24px; font-weight: 800; color: #2ecc71; margin-top: 5px;">{{printf "%.1f" .UptimePercent}}%</div>
 				</div>
 				<div style="background: white; padding: 15px; border-radius: 8px; border: 1px solid #e2e8f0;">
-					<div style="font-size: 12px; color: #64748b; text-transform: uppercase; font-weight: 600;">异常服务</div>
+					<div style="font-size: 12px; color: #64748b; text-transform: uppercase; font-weight: 600;">[[down_services]]</div>
 					<div style="font-size: 24px; font-weight: 800; color: {{.DownColor}}; margin-top: 5px;">{{.DownCount}}</div>
 				</div>
 			</div>
@@ -128,25 +187,27 @@ const dailyReportTemplate = `
 
 		<!-- Detail List -->
 		<div style="padding: 30px 40px;">
-			<h3 style="margin: 0 0 20px; color: #334155; font-size: 16px; font-weight: 700;">监控详情</h3>
+			<h3 style="margin: 0 0 20px; color: #334155; font-size: 16px; font-weight: 700;">[[details]]</h3>
 			<table style="width: 100%; border-collapse: collapse;">
 				<thead style="background-color: #f8f9fa; color: #64748b; font-size: 12px; text-transform: uppercase; text-align: left;">
 					<tr>
-						<th style="padding: 12px 15px; border-radius: 6px 0 0 6px;">服务名称</th>
-						<th style="padding: 12px 15px; text-align: center;">24h 在线率</th>
-						<th style="padding: 12px 15px; text-align: center;">平均延迟</th>
-						<th style="padding: 12px 15px; text-align: right; border-radius: 0 6px 6px 0;">状态</th>
+						<th style="padding: 12px 15px; border-radius: 6px 0 0 6px;">[[col_name]]</th>
+						<th style="padding: 12px 15px; text-align: center;">[[col_uptime]]</th>
+						<th style="padding: 12px 15px; text-align: center;">[[col_latency]]</th>
+						<th style="padding: 12px 15px; text-align: right; border-radius: 0 6px 6px 0;">[[col_status]]</th>
 					</tr>
 				</thead>
 				<tbody style="font-size: 14px; color: #334155;">
 					{{range .Monitors}}
 					<tr style="background-color: {{.RowBg}};">
 						<td style="padding: 12px 15px; border-bottom: 1px solid #f1f5f9;">
-							<div style="font-weight: 600;">{{.Name}}</div>
+							<div style="font-weight: 600;">{{if .DetailURL}}<a href="{{.DetailURL}}" style="color: #334155; text-decoration: none;">{{.Name}}</a>{{else}}{{.Name}}{{end}}</div>
 							<div style="font-size: 11px; color: #94a3b8; margin-top: 2px;">{{.Type}}</div>
+							{{if .LongestOutage}}<div style="font-size: 11px; color: #e74c3c; margin-top: 2px;">[[longest_outage]] {{.LongestOutage}}</div>{{end}}
 						</td>
 						<td style="padding: 12px 15px; border-bottom: 1px solid #f1f5f9; text-align: center; font-family: monospace; font-weight: 600; color: {{.UptimeColor}};">
 							{{printf "%.1f" .Uptime24h}}%
+							{{if .Sparkline}}<img src="{{.Sparkline}}" width="95" height="20" alt="" style="display: block; margin: 4px auto 0;">{{end}}
 						</td>
 						<td style="padding: 12px 15px; border-bottom: 1px solid #f1f5f9; text-align: center; font-family: monospace;">
 							{{.AvgResponse24h}} ms
@@ -165,7 +226,7 @@ const dailyReportTemplate = `
 		<!-- Footer -->
 		<div style="padding: 20px 40px; background-color: #f1f5f9; text-align: center; border-bottom-left-radius: 12px; border-bottom-right-radius: 12px;">
 			<p style="margin: 0; color: #94a3b8; font-size: 12px;">
-				PingGo Monitor System &bull; <a href="#" style="color: #94a3b8; text-decoration: none;">Manage Notifications</a>
+				{{.SiteName}} &bull; <a href="#" style="color: #94a3b8; text-decoration: none;">[[manage]]</a>
 			</p>
 		</div>
 	</div>
@@ -173,22 +234,77 @@ const dailyReportTemplate = `
 </html>
 `
 
-// RenderStatusChangeEmail renders the status change HTML email
-func RenderStatusChangeEmail(data StatusChangeData) (string, error) {
-	tmpl, err := template.New("status_change").Parse(statusChangeTemplate)
-	if err != nil {
-		return "", err
+// MaxEmailBytes 渲染后邮件 HTML 的上限：Gmail 会截断超过约 102KB 的邮件正文，其他客户端也有类似限制
+const MaxEmailBytes = 100 * 1024
+
+// 模板类型，用于校验自定义模板
+const (
+	TemplateStatusChange = "status_change"
+	TemplateDailyReport  = "daily_report"
+)
+
+// ErrMissingField 渲染所需的字段为空
+var ErrMissingField = errors.New("required template field is empty")
+
+// templateLabelRe 内置模板中的文案占位符 [[key]]
+var templateLabelRe = regexp.MustCompile(`\[\[(\w+)\]\]`)
+
+// builtinTemplates 每种语言的内置模板：模板类型 → 语言 → 源码
+var builtinTemplates = func() map[string]map[string]string {
+	sources := map[string]string{TemplateStatusChange: statusChangeTemplate, TemplateDailyReport: dailyReportTemplate}
+	templates := make(map[string]map[string]string, len(sources))
+	for kind, src := range sources {
+		templates[kind] = make(map[string]string)
+		for _, lang := range i18n.Languages() {
+			templates[kind][lang] = templateLabelRe.ReplaceAllStringFunc(src, func(m string) string {
+				return i18n.T(lang, "email."+m[2:len(m)-2])
+			})
+		}
 	}
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", err
+	return templates
+}()
+
+// EmailLanguage 返回邮件使用的语言：lang 不受支持（包括为空）时使用默认语言
+func EmailLanguage(lang string) string {
+	if i18n.Supported(lang) {
+		return lang
 	}
-	return buf.String(), nil
+	return i18n.DefaultLanguage
 }
 
-// RenderDailyReportEmail renders the daily report HTML email
+// RenderStatusChangeEmail renders the status change HTML email，按 data.Lang 选择内置模板
+func RenderStatusChangeEmail(data StatusChangeData) (string, error) {
+	return renderStatusChange(builtinTemplates[TemplateStatusChange][EmailLanguage(data.Lang)], data)
+}
+
+// RenderDailyReportEmail renders the daily report HTML email，按 data.Lang 选择内置模板
 func RenderDailyReportEmail(data DailyReportData) (string, error) {
-	tmpl, err := template.New("daily_report").Parse(dailyReportTemplate)
+	return renderDailyReport(builtinTemplates[TemplateDailyReport][EmailLanguage(data.Lang)], data)
+}
+
+func renderStatusChange(src string, data StatusChangeData) (string, error) {
+	switch {
+	case data.Name == "":
+		return "", fmt.Errorf("%w: Name", ErrMissingField)
+	case data.DateTime == "":
+		return "", fmt.Errorf("%w: DateTime", ErrMissingField)
+	}
+	return renderTemplate(TemplateStatusChange, src, data)
+}
+
+func renderDailyReport(src string, data DailyReportData) (string, error) {
+	if data.Date == "" {
+		return "", fmt.Errorf("%w: Date", ErrMissingField)
+	}
+	return renderTemplate(TemplateDailyReport, src, data)
+}
+
+func parseTemplate(name, src string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(src)
+}
+
+func renderTemplate(name, src string, data any) (string, error) {
+	tmpl, err := parseTemplate(name, src)
 	if err != nil {
 		return "", err
 	}
@@ -198,3 +314,53 @@ func RenderDailyReportEmail(data DailyReportData) (string, error) {
 	}
 	return buf.String(), nil
 }
+
+// ValidateRenderedEmail 检查模板的渲染结果：不能残留未解析的模板片段，不能超过 MaxEmailBytes，
+// 并且必须包含 required 中的每一项（如监控名称和时间），避免字段改名后静默渲染为空。
+// 只用于示例数据：真实的检查消息可能本身就包含 {{ }}，发送时不做此检查
+func ValidateRenderedEmail(html string, required ...string) error {
+	if strings.Contains(html, "{{") || strings.Contains(html, "}}") {
+		return errors.New("rendered email contains unresolved template placeholders")
+	}
+	if len(html) > MaxEmailBytes {
+		return fmt.Errorf("rendered email is %d bytes, exceeds the %d byte limit", len(html), MaxEmailBytes)
+	}
+	for _, r := range required {
+		if !strings.Contains(html, r) {
+			return fmt.Errorf("rendered email is missing required content %q", r)
+		}
+	}
+	return nil
+}
+
+// ValidateTemplate 用固定的示例数据（默认语言）渲染模板源码并校验结果，供保存自定义模板时调用，
+// 在保存时而不是发送时发现错误。示例数据不含特殊字符，渲染结果中应原样出现
+func ValidateTemplate(kind, src string) error {
+	switch kind {
+	case TemplateStatusChange:
+		fixture := StatusChangeFixture(i18n.DefaultLanguage)
+		for _, data := range []StatusChangeData{fixture, fixture.Redact()} {
+			html, err := renderStatusChange(src, data)
+			if err != nil {
+				return err
+			}
+			if err := ValidateRenderedEmail(html, data.Name, data.NewStatus, data.DateTime); err != nil {
+				return err
+			}
+		}
+		return nil
+	case TemplateDailyReport:
+		data := DailyReportFixture(i18n.DefaultLanguage)
+		html, err := renderDailyReport(src, data)
+		if err != nil {
+			return err
+		}
+		required := []string{data.Date}
+		for _, m := range data.Monitors {
+			required = append(required, m.Name)
+		}
+		return ValidateRenderedEmail(html, required...)
+	default:
+		return fmt.Errorf("unknown template %q", kind)
+	}
+}
@@ -0,0 +1,165 @@
+package notification
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"ping-go/pkg/i18n"
+	"strings"
+	"testing"
+)
+
+// go test ./notification -run Golden -update 重新生成 testdata 中的期望输出
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden 比较 got 与 testdata/name，-update 时改为写入
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file; run go test ./notification -run Golden -update and review the diff", name)
+	}
+}
+
+func TestStatusChangeEmailGolden(t *testing.T) {
+	for _, lang := range i18n.Languages() {
+		t.Run(lang, func(t *testing.T) {
+			data := StatusChangeFixture(lang)
+			html, err := RenderStatusChangeEmail(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateRenderedEmail(html, data.Name, data.NewStatus, data.DateTime, data.URL, data.Message); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "status_change_"+lang+".golden", html)
+
+			redacted, err := RenderStatusChangeEmail(data.Redact())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(redacted, data.URL) || strings.Contains(redacted, data.Message) {
+				t.Fatal("redacted email still contains the URL or check message")
+			}
+			checkGolden(t, "status_change_redacted_"+lang+".golden", redacted)
+		})
+	}
+}
+
+func TestDailyReportEmailGolden(t *testing.T) {
+	for _, lang := range i18n.Languages() {
+		t.Run(lang, func(t *testing.T) {
+			data := DailyReportFixture(lang)
+			html, err := RenderDailyReportEmail(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateRenderedEmail(html, data.Date, data.Monitors[0].Name, data.Monitors[1].Name, data.Monitors[0].LongestOutage); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "daily_report_"+lang+".golden", html)
+		})
+	}
+}
+
+// 内置模板的每种语言都必须有完整的文案（缺失时残留 [[key]]），并通过保存自定义模板时的同一校验
+func TestBuiltinTemplatesValid(t *testing.T) {
+	for _, kind := range []string{TemplateStatusChange, TemplateDailyReport} {
+		for lang, src := range builtinTemplates[kind] {
+			if m := templateLabelRe.FindString(src); m != "" {
+				t.Errorf("%s (%s): missing translation for %s", kind, lang, m)
+			}
+			if err := ValidateTemplate(kind, src); err != nil {
+				t.Errorf("%s (%s): %v", kind, lang, err)
+			}
+		}
+	}
+}
+
+func TestRenderRequiresFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func() error
+	}{
+		{"status change without Name", func() error {
+			data := StatusChangeFixture(i18n.DefaultLanguage)
+			data.Name = ""
+			_, err := RenderStatusChangeEmail(data)
+			return err
+		}},
+		{"status change without DateTime", func() error {
+			data := StatusChangeFixture(i18n.DefaultLanguage)
+			data.DateTime = ""
+			_, err := RenderStatusChangeEmail(data)
+			return err
+		}},
+		{"daily report without Date", func() error {
+			data := DailyReportFixture(i18n.DefaultLanguage)
+			data.Date = ""
+			_, err := RenderDailyReportEmail(data)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.render(); !errors.Is(err, ErrMissingField) {
+				t.Fatalf("err = %v, want ErrMissingField", err)
+			}
+		})
+	}
+}
+
+func TestValidateRenderedEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		required []string
+		wantErr  bool
+	}{
+		{"ok", "<p>Payment API</p>", []string{"Payment API"}, false},
+		{"unresolved placeholder", "<p>{{.Name}}</p>", nil, true},
+		{"stray closing braces", "<p>x}}</p>", nil, true},
+		{"missing required content", "<p></p>", []string{"Payment API"}, true},
+		{"too large", strings.Repeat("a", MaxEmailBytes+1), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRenderedEmail(tt.html, tt.required...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{"unknown field", "<p>{{.Name}} {{.NoSuchField}}</p>", false},
+		{"missing monitor name", "<p>{{.NewStatus}} at {{.DateTime}}</p>", false},
+		{"minimal", "<p>{{.Name}} {{.NewStatus}} {{.DateTime}}</p>", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckTemplate(TemplateStatusChange, tt.src); (err == nil) != tt.ok {
+				t.Errorf("CheckTemplate = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
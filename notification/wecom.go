@@ -0,0 +1,301 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// weComAPI 企业微信群机器人发送接口
+var weComAPI = "https://qyapi.weixin.qq.com/cgi-bin/webhook/send"
+
+const (
+	// WeComRateLimit 企业微信每个机器人每分钟最多发送的消息数
+	WeComRateLimit = 20
+	// weComQueueSize 每个机器人排队等待发送的消息上限，超过时丢弃新消息
+	weComQueueSize = 50
+	// weComMaxBytes markdown 内容的长度上限（UTF-8 字节）
+	weComMaxBytes = 4096
+	// weComErrRateLimited 企业微信返回的发送频率超限错误码
+	weComErrRateLimited = 45009
+)
+
+// ErrWeComRateLimited 机器人的发送队列已满（或测试时本分钟额度已用完），消息被丢弃
+var ErrWeComRateLimited = errors.New("wecom robot rate limit reached (20 messages per minute)")
+
+// WeComConfig 企业微信群机器人渠道配置，字段与通知配置中的 JSON key 对应
+type WeComConfig struct {
+	Key string `json:"wecom_key"` // 机器人 Webhook 地址中的 key，也可以直接填写完整 Webhook 地址
+}
+
+// WeComMessage 一条企业微信 markdown 消息
+type WeComMessage struct {
+	Content string
+}
+
+// key 返回机器人 key：填写完整 Webhook 地址时取其中的 key 参数
+func (c WeComConfig) key() string {
+	k := strings.TrimSpace(c.Key)
+	if strings.HasPrefix(k, "http://") || strings.HasPrefix(k, "https://") {
+		if u, err := url.Parse(k); err == nil {
+			return u.Query().Get("key")
+		}
+		return ""
+	}
+	return k
+}
+
+// Validate 校验配置
+func (c WeComConfig) Validate() error {
+	if strings.TrimSpace(c.Key) == "" {
+		return errors.New("wecom_key is required")
+	}
+	k := c.key()
+	if k == "" || strings.ContainsAny(k, "/?#&= ") {
+		return errors.New("invalid wecom_key (use the key parameter of the robot's webhook URL)")
+	}
+	return nil
+}
+
+// WeComStatusMessage 用与邮件相同的状态变化数据构造 markdown 消息：DOWN 使用 warning（橙红）颜色，恢复使用 info（绿色）；
+// 时间按 at 所在时区显示（通常为设置项 timezone）
+func WeComStatusMessage(d StatusChangeData, at time.Time) WeComMessage {
+	color := "warning"
+	if d.NewStatus == "UP" {
+		color = "info"
+	}
+	lines := []string{
+		fmt.Sprintf(`## <font color="%s">%s</font>`, color, d.StatusText),
+		fmt.Sprintf("**%s**", d.Name),
+		fmt.Sprintf(`> 状态：%s → <font color="%s">%s</font>`, d.OldStatus, color, d.NewStatus),
+	}
+	if d.Condition != "" {
+		lines = append(lines, "> 触发条件："+d.Condition)
+	}
+	if o := d.OutageSummary(); o != "" {
+		lines = append(lines, "> 宕机时长："+o)
+	}
+	if d.URL != "" {
+		lines = append(lines, "> 地址："+d.URL)
+	}
+	switch {
+	case d.Message != "":
+		lines = append(lines, `> 消息：<font color="comment">`+d.Message+"</font>")
+	case d.Redacted:
+		lines = append(lines, "> 详情请在控制台查看")
+	}
+	lines = append(lines, "> 时间："+at.Format("2006-01-02 15:04:05 MST"))
+	return WeComMessage{Content: truncateUTF8(strings.Join(lines, "\n"), weComMaxBytes)}
+}
+
+// WeComReportMessage 定时报告的 markdown 版本：总览加上当前不是 UP 或 24 小时可用率低于 99% 的监控项
+func WeComReportMessage(d DailyReportData) WeComMessage {
+	downColor := "info"
+	if d.DownCount > 0 {
+		downColor = "warning"
+	}
+	lines := []string{
+		fmt.Sprintf("## PingGo 日报 - %s", d.Date),
+		fmt.Sprintf(`> 监控项：**%d**　可用率：**%.1f%%**　异常：<font color="%s">%d</font>`, d.TotalCount, d.UptimePercent, downColor, d.DownCount),
+	}
+	var attention []string
+	for _, m := range d.Monitors {
+		if m.StatusKey == "up" && m.Uptime24h >= 99 {
+			continue
+		}
+		color := "comment"
+		if m.StatusKey != "up" {
+			color = "warning"
+		}
+		name := m.Name
+		if m.DetailURL != "" {
+			name = fmt.Sprintf("[%s](%s)", m.Name, m.DetailURL)
+		}
+		line := fmt.Sprintf(`- %s（%s）<font color="%s">%s</font>，24h 可用率 %.1f%%，平均响应 %d ms`,
+			name, m.Type, color, m.Status, m.Uptime24h, m.AvgResponse24h)
+		if m.LongestOutage != "" {
+			line += "，最长宕机 " + m.LongestOutage
+		}
+		attention = append(attention, line)
+	}
+	if len(attention) == 0 {
+		lines = append(lines, `<font color="info">所有监控项运行正常</font>`)
+	} else {
+		lines = append(lines, "", "**需要关注**")
+		lines = append(lines, attention...)
+	}
+	return WeComMessage{Content: truncateUTF8(strings.Join(lines, "\n"), weComMaxBytes)}
+}
+
+// truncateUTF8 把字符串截断到 max 字节以内，不截断多字节字符
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	const ellipsis = "\n…"
+	cut := max - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
+
+// weComClient 发送请求使用的客户端
+var weComClient = &http.Client{Timeout: 10 * time.Second}
+
+// SendWeCom 立即发送一条消息，网络错误、5xx 和频率超限（45009）重试；不经过发送队列，
+// 状态通知和定时报告应使用 EnqueueWeCom
+func SendWeCom(ctx context.Context, cfg WeComConfig, msg WeComMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	err := sendWithRetry(ctx, "WeCom message", func() (bool, error) {
+		return postWeCom(ctx, cfg, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send WeCom message: %w", err)
+	}
+	return nil
+}
+
+// postWeCom 发送一次请求，返回失败是否值得重试
+func postWeCom(ctx context.Context, cfg WeComConfig, msg WeComMessage) (bool, error) {
+	body, err := json.Marshal(map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": msg.Content},
+	})
+	if err != nil {
+		return false, err
+	}
+	endpoint := weComAPI + "?key=" + url.QueryEscape(cfg.key())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := weComClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("wecom returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return false, fmt.Errorf("wecom returned an unexpected response: %s", strings.TrimSpace(string(raw)))
+	}
+	switch result.ErrCode {
+	case 0:
+		return false, nil
+	case weComErrRateLimited:
+		// 同一机器人还被其他程序使用时仍可能超限，等到下一分钟再试
+		return true, &retryAfterError{err: fmt.Errorf("wecom errcode %d: %s", result.ErrCode, result.ErrMsg), after: time.Minute}
+	default:
+		return false, fmt.Errorf("wecom errcode %d: %s", result.ErrCode, result.ErrMsg)
+	}
+}
+
+// weComQueue 单个机器人的发送队列：按滑动窗口限制每分钟的发送数，超出时排队，队列满时丢弃
+type weComQueue struct {
+	mu   sync.Mutex
+	sent []time.Time // 最近一分钟内的发送时间
+	jobs chan weComJob
+}
+
+type weComJob struct {
+	cfg  WeComConfig
+	msg  WeComMessage
+	done func(error)
+}
+
+var (
+	weComQueuesMu sync.Mutex
+	weComQueues   = make(map[string]*weComQueue)
+)
+
+// weComQueueFor 返回机器人的发送队列，第一次使用时启动发送协程
+func weComQueueFor(key string) *weComQueue {
+	weComQueuesMu.Lock()
+	defer weComQueuesMu.Unlock()
+	q, ok := weComQueues[key]
+	if !ok {
+		q = &weComQueue{jobs: make(chan weComJob, weComQueueSize)}
+		weComQueues[key] = q
+		go q.run()
+	}
+	return q
+}
+
+// reserve 在本分钟额度内时占用一个名额并返回 0，否则返回需要等待的时间
+func (q *weComQueue) reserve(now time.Time) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := 0
+	for i < len(q.sent) && now.Sub(q.sent[i]) >= time.Minute {
+		i++
+	}
+	q.sent = q.sent[i:]
+	if len(q.sent) < WeComRateLimit {
+		q.sent = append(q.sent, now)
+		return 0
+	}
+	return q.sent[0].Add(time.Minute).Sub(now)
+}
+
+func (q *weComQueue) run() {
+	for job := range q.jobs {
+		for {
+			wait := q.reserve(time.Now())
+			if wait == 0 {
+				break
+			}
+			time.Sleep(wait)
+		}
+		err := SendWeCom(context.Background(), job.cfg, job.msg)
+		if job.done != nil {
+			job.done(err)
+		}
+	}
+}
+
+// EnqueueWeCom 把消息放入机器人的发送队列后立即返回，不阻塞调用方；超过每分钟 20 条时排队等待，
+// 队列已满时丢弃并返回 ErrWeComRateLimited。发送完成后调用 done，成功时参数为 nil
+func EnqueueWeCom(cfg WeComConfig, msg WeComMessage, done func(error)) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	select {
+	case weComQueueFor(cfg.key()).jobs <- weComJob{cfg: cfg, msg: msg, done: done}:
+		return nil
+	default:
+		log.Printf("WARN: WeCom queue is full (%d pending), dropping message", weComQueueSize)
+		return ErrWeComRateLimited
+	}
+}
+
+// SendWeComNow 立即发送（用于测试消息），与队列共用每分钟的额度；本分钟额度已用完时不发送
+func SendWeComNow(ctx context.Context, cfg WeComConfig, msg WeComMessage) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if wait := weComQueueFor(cfg.key()).reserve(time.Now()); wait > 0 {
+		return fmt.Errorf("%w, try again in %ds", ErrWeComRateLimited, int(wait.Seconds())+1)
+	}
+	return SendWeCom(ctx, cfg, msg)
+}
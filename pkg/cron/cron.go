@@ -0,0 +1,127 @@
+// Package cron 解析标准 5 段 cron 表达式（分 时 日 月 周），用于周期性维护窗口。
+// 支持 *、列表（1,15）、范围（1-5）和步长（*/10、8-18/2）；星期 0 和 7 都表示周日。
+// 日和星期同时受限时按 cron 的惯例满足其一即可
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field 单个字段的取值范围
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Schedule 解析后的 cron 表达式，每个字段用位图表示允许的取值
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // 字段为 * 时不参与日/星期的“满足其一”判断
+}
+
+// Parse 解析 5 段 cron 表达式
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday), got %d", len(parts))
+	}
+	var bits [5]uint64
+	for i, p := range parts {
+		b, err := parseField(p, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	// 7 与 0 同为周日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseField 解析逗号分隔的单个字段
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		lo, hi, step := f.min, f.max, 1
+		rng := item
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", f.name, item)
+			}
+			step, rng = n, item[:i]
+		}
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(a)
+			hi, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field: %q", f.name, item)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field: %q", f.name, item)
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		if lo < f.min || hi > f.max {
+			return 0, fmt.Errorf("%s field out of range %d-%d: %q", f.name, f.min, f.max, item)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Match t 所在的分钟是否满足表达式（按 t 自身的时区）
+func (s *Schedule) Match(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 || s.hour&(1<<uint(t.Hour())) == 0 || s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Prev 返回 (t-within, t] 内最近一次触发的时间（精确到分钟），没有时返回 false
+func (s *Schedule) Prev(t time.Time, within time.Duration) (time.Time, bool) {
+	from := t.Add(-within)
+	for at := t.Truncate(time.Minute); at.After(from); at = at.Add(-time.Minute) {
+		if s.Match(at) {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,140 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// bufferSize 等待写出的事件队列长度，队列满时直接丢弃新事件
+	bufferSize = 4096
+	// dialTimeout TCP 目标的连接超时
+	dialTimeout = 3 * time.Second
+	// redialInterval TCP 写入失败后再次尝试连接前的最短间隔
+	redialInterval = 5 * time.Second
+)
+
+// Sink 将事件以 NDJSON（每行一个 JSON 对象）写入文件或 TCP 端点。
+// Write 永远不会阻塞调用方：事件进入缓冲队列，由后台 goroutine 写出，队列满时丢弃并计数。
+type Sink struct {
+	target  string
+	ch      chan []byte
+	done    chan struct{}
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Open 创建一个事件输出。target 为 "tcp://host:port" 时写入 TCP（如 syslog 收集端），
+// 否则视为文件路径，使用 lumberjack 进行滚动。
+func Open(target string) *Sink {
+	s := &Sink{
+		target: target,
+		ch:     make(chan []byte, bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	var w writer
+	if addr, ok := strings.CutPrefix(target, "tcp://"); ok {
+		w = &tcpWriter{addr: addr}
+	} else {
+		w = &lumberjack.Logger{
+			Filename:   target,
+			MaxSize:    100, // megabytes
+			MaxBackups: 5,
+			MaxAge:     28, // days
+			Compress:   true,
+		}
+	}
+	go s.run(w)
+	return s
+}
+
+type writer interface {
+	io.Writer
+	io.Closer
+}
+
+// Target 返回输出目标
+func (s *Sink) Target() string {
+	return s.target
+}
+
+// Write 序列化事件并放入写出队列
+func (s *Sink) Write(event any) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		s.dropped.Add(1)
+		return
+	}
+	line = append(line, '\n')
+	select {
+	case s.ch <- line:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped 返回因队列满或写出失败而丢弃的事件数
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close 停止后台写出并关闭底层输出，队列中剩余的事件会先被写出
+func (s *Sink) Close() {
+	s.once.Do(func() {
+		close(s.ch)
+		<-s.done
+	})
+}
+
+func (s *Sink) run(w writer) {
+	defer close(s.done)
+	defer w.Close()
+	for line := range s.ch {
+		if _, err := w.Write(line); err != nil {
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// tcpWriter 按需建立 TCP 连接，写入失败时断开，稍后自动重连
+type tcpWriter struct {
+	addr     string
+	conn     net.Conn
+	lastDial time.Time
+}
+
+func (t *tcpWriter) Write(p []byte) (int, error) {
+	if t.conn == nil {
+		if time.Since(t.lastDial) < redialInterval {
+			return 0, net.ErrClosed
+		}
+		t.lastDial = time.Now()
+		conn, err := net.DialTimeout("tcp", t.addr, dialTimeout)
+		if err != nil {
+			return 0, err
+		}
+		t.conn = conn
+	}
+	t.conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	n, err := t.conn.Write(p)
+	if err != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+	return n, err
+}
+
+func (t *tcpWriter) Close() error {
+	if t.conn != nil {
+		return t.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,845 @@
+// Package expr 实现一个小型、无副作用的断言表达式语言，用于监控项的自定义检查。
+//
+// 支持的语法：
+//   - 字面量：数字、"字符串" 或 '字符串'、true、false、nil
+//   - 变量与成员访问：body.data.lag、headers["x-queue-depth"]、body.items[0]；访问不存在的键返回 nil
+//   - 运算符：! - * / % + - < <= > >= == != && || 以及 cond ? a : b
+//   - 字符串运算：contains、startsWith、endsWith、matches（正则），如 text contains "ok"
+//   - 函数：len(x)、num(x)、str(x)、lower(x)、upper(x)、pass(msg)、fail(msg)
+//
+// 表达式结果必须是布尔值。fail(msg) 返回 false 并记录失败消息，pass(msg) 返回 true 并记录成功消息，
+// 例如：status == 200 && (body.lag < 5 || fail("lag " + body.lag))
+//
+// 表达式不能执行任何 I/O，也没有循环；编译时限制长度和节点数，运行时限制求值步数并检查 context 超时。
+package expr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxLength 表达式源码的最大长度
+	MaxLength = 2048
+	// maxNodes 语法树的最大节点数
+	maxNodes = 512
+	// maxSteps 单次求值的最大步数
+	maxSteps = 10000
+	// maxStringLen 求值过程中产生的字符串最大长度
+	maxStringLen = 64 * 1024
+)
+
+var errStepLimit = errors.New("evaluation step limit exceeded")
+
+// Program 编译后的表达式，可并发执行
+type Program struct {
+	source string
+	root   node
+	used   map[string]bool
+}
+
+// Result 表达式执行结果
+type Result struct {
+	Pass    bool
+	Message string // pass()/fail() 记录的消息，可能为空
+}
+
+// Source 返回表达式源码
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Uses 表达式是否引用了变量 name
+func (p *Program) Uses(name string) bool {
+	return p.used[name]
+}
+
+// Compile 编译表达式。vars 为允许引用的变量名，引用其他变量视为编译错误
+func Compile(source string, vars []string) (*Program, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, errors.New("empty expression")
+	}
+	if len(source) > MaxLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", MaxLength)
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		allowed[v] = true
+	}
+	p := &parser{tokens: tokens, vars: allowed, used: make(map[string]bool)}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos+1)
+	}
+	if p.nodes > maxNodes {
+		return nil, fmt.Errorf("expression is too complex (more than %d nodes)", maxNodes)
+	}
+	return &Program{source: source, root: root, used: p.used}, nil
+}
+
+// Run 在给定变量上执行表达式。结果不是布尔值、类型不匹配、超出步数或 ctx 超时都返回错误
+func (p *Program) Run(ctx context.Context, env map[string]any) (Result, error) {
+	st := &state{ctx: ctx, env: env}
+	v, err := st.eval(p.root)
+	if err != nil {
+		return Result{}, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return Result{}, fmt.Errorf("expression must return a boolean, got %s", typeName(v))
+	}
+	res := Result{Pass: b}
+	if b {
+		res.Message = st.passMsg
+	} else {
+		res.Message = st.failMsg
+	}
+	return res, nil
+}
+
+// ---- 词法分析 ----
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9':
+			start := i
+			if n := len(tokens); n > 0 && tokens[n-1].kind == tokOp && tokens[n-1].text == "." {
+				// 点路径中的数组下标（如 items.0.id）只取整数部分
+				for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+					i++
+				}
+			} else {
+				for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.' || src[i] == '_' ||
+					src[i] == 'e' || src[i] == 'E' || ((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+					i++
+				}
+			}
+			n, err := strconv.ParseFloat(strings.ReplaceAll(src[start:i], "_", ""), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", src[start:i], start+1)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(src) {
+				ch := src[i]
+				if ch == c {
+					closed = true
+					i++
+					break
+				}
+				if ch == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(ch)
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: start})
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 'a' && src[i] <= 'z' || src[i] >= 'A' && src[i] <= 'Z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			op := ""
+			for _, two := range twoCharOps {
+				if strings.HasPrefix(src[i:], two) {
+					op = two
+					break
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("+-*/%<>!()[].,?:", rune(c)) {
+					return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+				}
+				op = string(c)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// ---- 语法分析 ----
+
+type node any
+
+type (
+	literalNode struct{ value any }
+	varNode     struct{ name string }
+	memberNode  struct {
+		target node
+		key    node
+	}
+	unaryNode struct {
+		op      string
+		operand node
+	}
+	binaryNode struct {
+		op          string
+		left, right node
+		re          *regexp.Regexp // matches 右侧为字面量时预编译
+	}
+	condNode struct{ cond, then, els node }
+	callNode struct {
+		name string
+		args []node
+	}
+)
+
+// functions 内置函数及其参数个数
+var functions = map[string]int{
+	"len": 1, "num": 1, "str": 1, "lower": 1, "upper": 1, "pass": 1, "fail": 1,
+}
+
+// wordOps 以单词形式书写的二元运算符
+var wordOps = map[string]bool{"contains": true, "startsWith": true, "endsWith": true, "matches": true}
+
+type parser struct {
+	tokens []token
+	pos    int
+	nodes  int
+	vars   map[string]bool
+	used   map[string]bool // 引用过的变量
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.isOp(text) {
+		t := p.peek()
+		return fmt.Errorf("expected '%s' but found %s at position %d", text, t, t.pos+1)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) add(n node) node {
+	p.nodes++
+	return n
+}
+
+func (p *parser) parseExpr() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return cond, nil
+	}
+	p.next()
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return p.add(&condNode{cond: cond, then: then, els: els}), nil
+}
+
+// precedence 二元运算符优先级，数值越大结合越紧
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"contains", "startsWith", "endsWith", "matches"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) binaryOp(level int) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && !(t.kind == tokIdent && wordOps[t.text]) {
+		return "", false
+	}
+	for _, op := range precedence[level] {
+		if t.text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.binaryOp(level)
+		if !ok {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		bn := &binaryNode{op: op, left: left, right: right}
+		if op == "matches" {
+			if lit, ok := right.(*literalNode); ok {
+				s, ok := lit.value.(string)
+				if !ok {
+					return nil, errors.New("matches requires a string pattern")
+				}
+				if bn.re, err = regexp.Compile(s); err != nil {
+					return nil, fmt.Errorf("invalid regex %q: %v", s, err)
+				}
+			}
+		}
+		left = p.add(bn)
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") || p.isOp("-") {
+		op := p.next().text
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return p.add(&unaryNode{op: op, operand: operand}), nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOp("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokIdent && t.kind != tokNumber {
+				return nil, fmt.Errorf("expected field name after '.' at position %d", t.pos+1)
+			}
+			n = p.add(&memberNode{target: n, key: &literalNode{value: t.text}})
+		case p.isOp("["):
+			p.next()
+			key, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = p.add(&memberNode{target: n, key: key})
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return p.add(&literalNode{value: t.num}), nil
+	case tokString:
+		return p.add(&literalNode{value: t.text}), nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return p.add(&literalNode{value: true}), nil
+		case "false":
+			return p.add(&literalNode{value: false}), nil
+		case "nil", "null":
+			return p.add(&literalNode{value: nil}), nil
+		}
+		if p.isOp("(") {
+			return p.parseCall(t)
+		}
+		if !p.vars[t.text] {
+			return nil, fmt.Errorf("unknown variable %q at position %d", t.text, t.pos+1)
+		}
+		p.used[t.text] = true
+		return p.add(&varNode{name: t.text}), nil
+	case tokOp:
+		if t.text == "(" {
+			n, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos+1)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	argc, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos+1)
+	}
+	p.next() // (
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next() // )
+	if len(args) != argc {
+		return nil, fmt.Errorf("%s() takes %d argument(s), got %d", name.text, argc, len(args))
+	}
+	return p.add(&callNode{name: name.text, args: args}), nil
+}
+
+// ---- 求值 ----
+
+type state struct {
+	ctx     context.Context
+	env     map[string]any
+	steps   int
+	passMsg string
+	failMsg string
+}
+
+func (st *state) eval(n node) (any, error) {
+	st.steps++
+	if st.steps > maxSteps {
+		return nil, errStepLimit
+	}
+	if st.steps%64 == 0 {
+		if err := st.ctx.Err(); err != nil {
+			return nil, errors.New("evaluation timed out")
+		}
+	}
+
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+	case *varNode:
+		return normalize(st.env[n.name]), nil
+	case *memberNode:
+		target, err := st.eval(n.target)
+		if err != nil {
+			return nil, err
+		}
+		key, err := st.eval(n.key)
+		if err != nil {
+			return nil, err
+		}
+		return member(target, key)
+	case *unaryNode:
+		v, err := st.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "!" {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("operator ! requires a boolean, got %s", typeName(v))
+			}
+			return !b, nil
+		}
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - requires a number, got %s", typeName(v))
+		}
+		return -f, nil
+	case *condNode:
+		c, err := st.evalBool(n.cond, "?:")
+		if err != nil {
+			return nil, err
+		}
+		if c {
+			return st.eval(n.then)
+		}
+		return st.eval(n.els)
+	case *binaryNode:
+		return st.evalBinary(n)
+	case *callNode:
+		return st.evalCall(n)
+	}
+	return nil, fmt.Errorf("unknown node %T", n)
+}
+
+func (st *state) evalBool(n node, op string) (bool, error) {
+	v, err := st.eval(n)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("operator %s requires a boolean, got %s", op, typeName(v))
+	}
+	return b, nil
+}
+
+func (st *state) evalBinary(n *binaryNode) (any, error) {
+	// 逻辑运算短路求值
+	if n.op == "&&" || n.op == "||" {
+		l, err := st.evalBool(n.left, n.op)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		return st.evalBool(n.right, n.op)
+	}
+
+	l, err := st.eval(n.left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := st.eval(n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, l, r)
+	case "contains", "startsWith", "endsWith", "matches":
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return nil, fmt.Errorf("operator %s requires strings, got %s and %s", n.op, typeName(l), typeName(r))
+		}
+		switch n.op {
+		case "contains":
+			return strings.Contains(ls, rs), nil
+		case "startsWith":
+			return strings.HasPrefix(ls, rs), nil
+		case "endsWith":
+			return strings.HasSuffix(ls, rs), nil
+		}
+		re := n.re
+		if re == nil {
+			if re, err = regexp.Compile(rs); err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %v", rs, err)
+			}
+		}
+		return re.MatchString(ls), nil
+	case "+":
+		// 任一侧是字符串时按字符串拼接，方便组织 fail() 的消息
+		if ls, ok := l.(string); ok {
+			return limitString(ls + toString(r))
+		}
+		if rs, ok := r.(string); ok {
+			return limitString(toString(l) + rs)
+		}
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s requires numbers, got %s and %s", n.op, typeName(l), typeName(r))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if int64(rf) == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return float64(int64(lf) % int64(rf)), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func (st *state) evalCall(n *callNode) (any, error) {
+	v, err := st.eval(n.args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch n.name {
+	case "len":
+		switch x := v.(type) {
+		case string:
+			return float64(len(x)), nil
+		case []any:
+			return float64(len(x)), nil
+		case map[string]any:
+			return float64(len(x)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("len() does not accept %s", typeName(v))
+	case "num":
+		switch x := v.(type) {
+		case float64:
+			return x, nil
+		case bool:
+			if x {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+			if err != nil {
+				return nil, fmt.Errorf("num(): %q is not a number", x)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("num() does not accept %s", typeName(v))
+	case "str":
+		return limitString(toString(v))
+	case "lower", "upper":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() requires a string, got %s", n.name, typeName(v))
+		}
+		if n.name == "lower" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	case "pass":
+		st.passMsg = toString(v)
+		return true, nil
+	case "fail":
+		st.failMsg = toString(v)
+		return false, nil
+	}
+	return nil, fmt.Errorf("unknown function %s", n.name)
+}
+
+// normalize 将常见的 Go 数值类型统一为 float64，与 JSON 解码结果保持一致
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case map[string]string:
+		m := make(map[string]any, len(x))
+		for k, s := range x {
+			m[k] = s
+		}
+		return m
+	}
+	return v
+}
+
+func member(target, key any) (any, error) {
+	switch t := target.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("object key must be a string, got %s", typeName(key))
+		}
+		return normalize(t[k]), nil
+	case []any:
+		f, ok := key.(float64)
+		if !ok {
+			// 点路径中的数字下标（如 items.0）以字符串形式出现
+			s, isStr := key.(string)
+			n, err := strconv.Atoi(s)
+			if !isStr || err != nil {
+				return nil, fmt.Errorf("array index must be a number, got %s", typeName(key))
+			}
+			f = float64(n)
+		}
+		i := int(f)
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+		return normalize(t[i]), nil
+	}
+	return nil, fmt.Errorf("cannot access a field of %s", typeName(target))
+}
+
+// equal 标量按值比较，对象和数组之间不相等
+func equal(l, r any) bool {
+	switch l.(type) {
+	case nil:
+		return r == nil
+	case float64, string, bool:
+		return l == r
+	}
+	return false
+}
+
+func compare(op string, l, r any) (bool, error) {
+	var c int
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %s with %s (use num() to convert strings)", typeName(l), typeName(r))
+		}
+		switch {
+		case lv < rv:
+			c = -1
+		case lv > rv:
+			c = 1
+		}
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %s with %s (use num() to convert strings)", typeName(l), typeName(r))
+		}
+		c = strings.Compare(lv, rv)
+	default:
+		return false, fmt.Errorf("cannot compare %s with %s", typeName(l), typeName(r))
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func toString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func limitString(s string) (any, error) {
+	if len(s) > maxStringLen {
+		return nil, errors.New("string result is too long")
+	}
+	return s, nil
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,235 @@
+package i18n
+
+import "sort"
+
+// DefaultLanguage 未配置或配置了不支持的语言时使用的语言
+const DefaultLanguage = "zh"
+
+// catalog 文案目录：语言 → key → 文案
+var catalog = map[string]map[string]string{
+	"zh": {
+		"status.up":          "正常",
+		"status.down":        "异常",
+		"status.pending":     "检测中",
+		"status.maintenance": "维护中",
+		"status.no_data":     "无数据",
+		"status.unknown":     "未知",
+		"embed.days_ago":     "%d 天前",
+		"embed.today":        "今天",
+
+		// 公开状态页的总体状态
+		"statuspage.operational":    "所有服务运行正常",
+		"statuspage.partial_outage": "部分服务异常",
+		"statuspage.major_outage":   "全部服务异常",
+		"statuspage.degraded":       "部分服务状态不稳定",
+		"statuspage.maintenance":    "部分服务维护中",
+		"statuspage.no_monitors":    "暂无监控项",
+		"statuspage.updated":        "更新于 %s",
+		"statuspage.incidents":      "当前事件",
+		"statuspage.past_incidents": "最近 %d 天已解决的事件",
+		"statuspage.duration":       "持续 %s",
+		"statuspage.affected":       "影响：%s",
+
+		// 事件公告的严重程度和处理进度
+		"incident.severity.info":     "公告",
+		"incident.severity.minor":    "轻微",
+		"incident.severity.major":    "严重",
+		"incident.severity.critical": "紧急",
+		"incident.investigating":     "调查中",
+		"incident.identified":        "已定位",
+		"incident.monitoring":        "观察中",
+		"incident.resolved":          "已解决",
+
+		// RSS/Atom 订阅（/feed.xml、/status/<slug>/feed.xml）
+		"feed.title":             "状态变化与事件",
+		"feed.status_change":     "%s 状态变为 %s",
+		"feed.incident_resolved": "已解决：%s",
+
+		// 状态页邮件订阅
+		"subscription.form_label":     "订阅邮件通知",
+		"subscription.form_submit":    "订阅",
+		"subscription.confirm_title":  "确认订阅 %s",
+		"subscription.confirm_body":   "请点击下方按钮确认订阅，确认后 %s 的事件公告和状态变化会发送到这个邮箱。如果不是你本人的操作，忽略这封邮件即可。",
+		"subscription.confirm_action": "确认订阅",
+		"subscription.view_page":      "查看状态页",
+		"subscription.footer":         "你收到这封邮件是因为订阅了 %s 的状态更新。",
+		"subscription.unsubscribe":    "退订",
+		"subscription.check_inbox":    "确认邮件已发送，请查收邮箱并点击其中的链接完成订阅。",
+		"subscription.confirmed":      "订阅已确认，%s 的事件公告和状态变化会发送到你的邮箱。",
+		"subscription.unsubscribed":   "已退订，不会再收到 %s 的邮件。",
+		"subscription.invalid_link":   "链接无效或订阅已取消。",
+		"subscription.invalid_email":  "请输入有效的邮箱地址。",
+		"subscription.rate_limited":   "请求过于频繁，请稍后再试。",
+		"subscription.unavailable":    "暂时无法订阅，请稍后再试。",
+		"subscription.back":           "返回状态页",
+
+		// 邮件模板中的文案（内置模板中的 [[key]] 对应 email.key）
+		"email.previous_status":      "之前状态",
+		"email.current_status":       "当前状态",
+		"email.triggered":            "触发条件：",
+		"email.view_incident":        "查看事件",
+		"email.message_detail":       "检查消息",
+		"email.no_message":           "无消息",
+		"email.details_in_dashboard": "详情请在控制台查看",
+		"email.report_title":         "每日速报",
+		"email.total":                "监控总数",
+		"email.uptime":               "系统在线率",
+		"email.down_services":        "异常服务",
+		"email.details":              "监控详情",
+		"email.col_name":             "服务名称",
+		"email.col_uptime":           "24h 在线率",
+		"email.col_latency":          "平均延迟",
+		"email.col_status":           "状态",
+		"email.longest_outage":       "最长宕机",
+		"email.manage":               "管理通知",
+
+		// 通知标题和主题，%s 等占位符按 fmt.Sprintf 填充；主题的第一个 %s 为站点名称
+		"notify.status.up":           "已恢复",
+		"notify.status.down":         "宕机",
+		"notify.status.pending":      "检测中",
+		"notify.status.maintenance":  "维护中",
+		"notify.subject.status":      "%s 通知：%s %s",
+		"notify.subject.reminder":    "%s 通知：%s 已持续宕机 %s",
+		"notify.subject.escalation":  "%s 通知：%s 已持续宕机 %s（升级）",
+		"notify.subject.flapping":    "%s 通知：%s 状态频繁变化",
+		"notify.subject.flap_stable": "%s 通知：%s %s（已稳定）",
+		"notify.subject.digest":      "%s 通知：静默时段内被抑制的 %d 条通知",
+		"notify.subject.report":      "%s 日报 - %s",
+		"notify.title.down":          "服务宕机通知",
+		"notify.title.up":            "服务恢复通知",
+		"notify.title.reminder":      "服务持续宕机提醒（已持续 %s）",
+		"notify.title.escalation":    "服务宕机升级通知（已持续 %s）",
+		"notify.title.flapping":      "服务状态频繁变化，稳定前不再逐条通知",
+		"notify.title.flap_stable":   "服务状态已稳定",
+		"notify.title.digest":        "静默时段通知汇总",
+		"notify.title.test":          "（测试）",
+		"notify.digest.name":         "静默时段内的 %d 条通知",
+		"notify.digest.more":         "… 另有 %d 条",
+		"notify.outage":              "宕机 %s（%s 起）",
+		"notify.down_since":          "%s 起宕机",
+		"notify.longest_outage":      "%s（%s 起）",
+	},
+	"en": {
+		"status.up":          "Up",
+		"status.down":        "Down",
+		"status.pending":     "Pending",
+		"status.maintenance": "Maintenance",
+		"status.no_data":     "No data",
+		"status.unknown":     "Unknown",
+		"embed.days_ago":     "%d days ago",
+		"embed.today":        "Today",
+
+		"statuspage.operational":    "All systems operational",
+		"statuspage.partial_outage": "Partial outage",
+		"statuspage.major_outage":   "Major outage",
+		"statuspage.degraded":       "Degraded performance",
+		"statuspage.maintenance":    "Under maintenance",
+		"statuspage.no_monitors":    "No monitors",
+		"statuspage.updated":        "Updated %s",
+		"statuspage.incidents":      "Active incidents",
+		"statuspage.past_incidents": "Resolved in the last %d days",
+		"statuspage.duration":       "Lasted %s",
+		"statuspage.affected":       "Affects: %s",
+
+		"incident.severity.info":     "Notice",
+		"incident.severity.minor":    "Minor",
+		"incident.severity.major":    "Major",
+		"incident.severity.critical": "Critical",
+		"incident.investigating":     "Investigating",
+		"incident.identified":        "Identified",
+		"incident.monitoring":        "Monitoring",
+		"incident.resolved":          "Resolved",
+
+		"feed.title":             "Status changes and incidents",
+		"feed.status_change":     "%s is %s",
+		"feed.incident_resolved": "Resolved: %s",
+
+		"subscription.form_label":     "Get email updates",
+		"subscription.form_submit":    "Subscribe",
+		"subscription.confirm_title":  "Confirm your subscription to %s",
+		"subscription.confirm_body":   "Click the button below to confirm. Incidents and status changes on %s will then be sent to this address. If you did not request this, you can ignore this email.",
+		"subscription.confirm_action": "Confirm subscription",
+		"subscription.view_page":      "View status page",
+		"subscription.footer":         "You are receiving this email because you subscribed to updates from %s.",
+		"subscription.unsubscribe":    "Unsubscribe",
+		"subscription.check_inbox":    "A confirmation email is on its way. Click the link inside to complete your subscription.",
+		"subscription.confirmed":      "Subscription confirmed. Incidents and status changes on %s will be sent to your inbox.",
+		"subscription.unsubscribed":   "You have been unsubscribed and will no longer receive emails from %s.",
+		"subscription.invalid_link":   "This link is invalid or the subscription has been removed.",
+		"subscription.invalid_email":  "Please enter a valid email address.",
+		"subscription.rate_limited":   "Too many requests, please try again later.",
+		"subscription.unavailable":    "Subscriptions are temporarily unavailable, please try again later.",
+		"subscription.back":           "Back to status page",
+
+		"email.previous_status":      "Previous Status",
+		"email.current_status":       "Current Status",
+		"email.triggered":            "Triggered: ",
+		"email.view_incident":        "View incident",
+		"email.message_detail":       "Message Detail",
+		"email.no_message":           "No message",
+		"email.details_in_dashboard": "Details available in dashboard",
+		"email.report_title":         "Daily Report",
+		"email.total":                "Monitors",
+		"email.uptime":               "Uptime",
+		"email.down_services":        "Down",
+		"email.details":              "Monitor Details",
+		"email.col_name":             "Monitor",
+		"email.col_uptime":           "24h Uptime",
+		"email.col_latency":          "Avg Latency",
+		"email.col_status":           "Status",
+		"email.longest_outage":       "Longest outage",
+		"email.manage":               "Manage Notifications",
+
+		"notify.status.up":           "UP",
+		"notify.status.down":         "DOWN",
+		"notify.status.pending":      "PENDING",
+		"notify.status.maintenance":  "MAINTENANCE",
+		"notify.subject.status":      "%s Notification: %s is %s",
+		"notify.subject.reminder":    "%s Notification: %s is still DOWN for %s",
+		"notify.subject.escalation":  "%s Notification: %s is still DOWN for %s (escalated)",
+		"notify.subject.flapping":    "%s Notification: %s is flapping",
+		"notify.subject.flap_stable": "%s Notification: %s is %s (no longer flapping)",
+		"notify.subject.digest":      "%s Notification: %d notifications suppressed during quiet hours",
+		"notify.subject.report":      "%s Daily Report - %s",
+		"notify.title.down":          "Service Down",
+		"notify.title.up":            "Service Recovered",
+		"notify.title.reminder":      "Service still down (%s)",
+		"notify.title.escalation":    "Escalation: service down for %s",
+		"notify.title.flapping":      "Service is flapping; individual notifications are paused until it stabilizes",
+		"notify.title.flap_stable":   "Service status has stabilized",
+		"notify.title.digest":        "Quiet hours digest",
+		"notify.title.test":          " (test)",
+		"notify.digest.name":         "%d notifications during quiet hours",
+		"notify.digest.more":         "… and %d more",
+		"notify.outage":              "DOWN for %s (since %s)",
+		"notify.down_since":          "DOWN since %s",
+		"notify.longest_outage":      "%s (since %s)",
+	},
+}
+
+// Languages 返回支持的语言，按字母排序
+func Languages() []string {
+	langs := make([]string, 0, len(catalog))
+	for lang := range catalog {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Supported 是否支持该语言
+func Supported(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// T 返回 key 在指定语言下的文案，缺失时依次回退到默认语言和 key 本身
+func T(lang, key string) string {
+	if s, ok := catalog[lang][key]; ok {
+		return s
+	}
+	if s, ok := catalog[DefaultLanguage][key]; ok {
+		return s
+	}
+	return key
+}
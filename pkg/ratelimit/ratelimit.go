@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket 单个 key 的令牌桶状态
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 按 key 区分的令牌桶限流器，可用于公开 HTTP 路由（按 IP）和心跳上报接口（按 token）
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64 // 每秒补充的令牌数
+	burst   float64 // 桶容量
+	buckets map[string]*bucket
+}
+
+// New 创建限流器：每 every 补充一个令牌，最多累积 burst 个
+func New(every time.Duration, burst int) *Limiter {
+	if every <= 0 {
+		every = time.Second
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l := &Limiter{
+		rate:    1 / every.Seconds(),
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+	go l.cleanup()
+	return l
+}
+
+// Allow 消耗一个令牌。被限流时返回 false 以及下一个令牌可用前需要等待的时间
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Reset 清除指定 key 的状态（例如监控项被删除或 token 被重置时）
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buckets, key)
+}
+
+// cleanup 定期清理已经回满的桶，避免大量一次性 key 占用内存
+func (l *Limiter) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.mu.Lock()
+		now := time.Now()
+		for key, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Counter 按分钟分桶统计最近一小时内的事件次数（例如被限流的上报次数）
+type Counter struct {
+	mu     sync.Mutex
+	counts map[string]*[60]minuteCount
+}
+
+type minuteCount struct {
+	minute int64
+	count  int
+}
+
+// NewCounter 创建一个滑动一小时窗口的计数器
+func NewCounter() *Counter {
+	return &Counter{counts: make(map[string]*[60]minuteCount)}
+}
+
+// Inc 为指定 key 记录一次事件
+func (c *Counter) Inc(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	minute := time.Now().Unix() / 60
+	slots, ok := c.counts[key]
+	if !ok {
+		slots = &[60]minuteCount{}
+		c.counts[key] = slots
+	}
+	slot := &slots[minute%60]
+	if slot.minute != minute {
+		slot.minute = minute
+		slot.count = 0
+	}
+	slot.count++
+}
+
+// LastHour 返回指定 key 最近一小时内的事件次数
+func (c *Counter) LastHour(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slots, ok := c.counts[key]
+	if !ok {
+		return 0
+	}
+	minute := time.Now().Unix() / 60
+	total := 0
+	for _, slot := range slots {
+		if minute-slot.minute < 60 {
+			total += slot.count
+		}
+	}
+	if total == 0 {
+		delete(c.counts, key)
+	}
+	return total
+}
+
+// Reset 清除指定 key 的计数
+func (c *Counter) Reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.counts, key)
+}
@@ -0,0 +1,117 @@
+// Package secretbox 使用 AES-256-GCM 加密保存在数据库中的敏感字段（如 TOTP 密钥），数据库文件泄露时无法直接读出。
+// 加密密钥取自环境变量 PINGGO_SECRET_KEY（任意字符串，取其 SHA-256）；未设置时使用工作目录下的 pinggo.key，
+// 文件不存在时自动生成（权限 0600）。多实例模式下各实例必须使用相同的密钥
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeyFile 未设置 PINGGO_SECRET_KEY 时使用的密钥文件
+	KeyFile = "pinggo.key"
+	// prefix 密文前缀，便于以后更换算法
+	prefix = "v1:"
+)
+
+var (
+	keyOnce sync.Once
+	aead    cipher.AEAD
+	keyErr  error
+)
+
+// load 读取或生成密钥，只执行一次
+func load() (cipher.AEAD, error) {
+	keyOnce.Do(func() {
+		var key [32]byte
+		if v := os.Getenv("PINGGO_SECRET_KEY"); v != "" {
+			key = sha256.Sum256([]byte(v))
+		} else {
+			key, keyErr = keyFromFile(KeyFile)
+			if keyErr != nil {
+				return
+			}
+		}
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			keyErr = err
+			return
+		}
+		aead, keyErr = cipher.NewGCM(block)
+	})
+	return aead, keyErr
+}
+
+// keyFromFile 读取十六进制编码的密钥文件，不存在时生成
+func keyFromFile(path string) ([32]byte, error) {
+	var key [32]byte
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := rand.Read(key[:]); err != nil {
+			return key, err
+		}
+		// O_EXCL 避免并发启动的进程互相覆盖
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			return keyFromFile(path)
+		}
+		if err != nil {
+			return key, fmt.Errorf("create %s: %w", path, err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(hex.EncodeToString(key[:]) + "\n"); err != nil {
+			return key, fmt.Errorf("write %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return key, fmt.Errorf("read %s: %w", path, err)
+	}
+	decoded, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(decoded) != len(key) {
+		return key, fmt.Errorf("%s must contain a 64-character hex key", path)
+	}
+	copy(key[:], decoded)
+	return key, nil
+}
+
+// Seal 加密 plain，返回可保存在文本字段中的密文
+func Seal(plain string) (string, error) {
+	a, err := load()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, a.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := a.Seal(nonce, nonce, []byte(plain), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 返回的密文。密钥与加密时不同时返回错误
+func Open(sealed string) (string, error) {
+	a, err := load()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, prefix))
+	if !strings.HasPrefix(sealed, prefix) || err != nil || len(data) < a.NonceSize() {
+		return "", errors.New("invalid ciphertext")
+	}
+	plain, err := a.Open(nil, data[:a.NonceSize()], data[a.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt, the secret key has changed")
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,531 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"ping-go/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// backupSchemaVersion 备份文档的格式版本，格式不兼容时递增；恢复时拒绝更高版本的备份
+	backupSchemaVersion = 1
+	// maxRestoreBytes 恢复时读取的备份大小上限（解压后）
+	maxRestoreBytes = 512 << 20
+
+	auditActionBackupCreate  = "backup.create"
+	auditActionBackupRestore = "backup.restore"
+)
+
+// backupDocument 完整备份：监控项（含分组名称，可附带心跳）、分组、通知规则、自定义模板、设置、维护窗口和账号。
+// 记录保留原 ID，替换模式恢复时沿用，状态页、徽章等按 ID 引用的地址恢复后仍然有效
+type backupDocument struct {
+	SchemaVersion      int                       `json:"schema_version"`
+	CreatedAt          time.Time                 `json:"created_at"`
+	IncludesSecrets    bool                      `json:"includes_secrets"`
+	IncludesHistory    bool                      `json:"includes_history"`
+	Monitors           []backupMonitor           `json:"monitors"`
+	Groups             []model.MonitorGroup      `json:"groups"`
+	Notifications      []model.Notification      `json:"notifications"`
+	Templates          []db.CustomTemplate       `json:"templates"`
+	Settings           map[string]string         `json:"settings"`
+	MaintenanceWindows []model.MaintenanceWindow `json:"maintenance_windows"`
+	Users              []backupUser              `json:"users"`
+}
+
+// backupMonitor 备份中的监控项，include_history 时附带原始心跳和小时、日聚合
+type backupMonitor struct {
+	importedMonitor
+	Hourly []model.HeartbeatHourly `json:"hourly,omitempty"`
+	Daily  []model.HeartbeatDaily  `json:"daily,omitempty"`
+}
+
+// backupUser 备份中的账号，密码哈希只在 include_secrets 时导出
+type backupUser struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"`
+	TagScope string `json:"tag_scope"`
+}
+
+// restoreCount 恢复结果中一类数据的数量
+type restoreCount struct {
+	Restored int `json:"restored"`
+	Skipped  int `json:"skipped"`
+}
+
+// restoreReport 恢复结果
+type restoreReport struct {
+	Mode               string       `json:"mode"`
+	Monitors           restoreCount `json:"monitors"`
+	SkippedMonitors    []string     `json:"skipped_monitors"`
+	Groups             restoreCount `json:"groups"`
+	Notifications      restoreCount `json:"notifications"`
+	Templates          restoreCount `json:"templates"`
+	Settings           restoreCount `json:"settings"`
+	MaintenanceWindows restoreCount `json:"maintenance_windows"`
+	Users              restoreCount `json:"users"`
+	Heartbeats         int          `json:"heartbeats"`
+	Aggregates         int          `json:"aggregates"`
+}
+
+// buildBackup 生成完整备份。不含密钥时监控项按 exportMonitorConfig 的规则去掉密钥，
+// 通知规则去掉渠道令牌（同通知列表），账号不含密码哈希
+func buildBackup(includeSecrets, includeHistory bool) (*backupDocument, error) {
+	doc := &backupDocument{
+		SchemaVersion:   backupSchemaVersion,
+		CreatedAt:       time.Now(),
+		IncludesSecrets: includeSecrets,
+		IncludesHistory: includeHistory,
+		Settings:        make(map[string]string),
+	}
+
+	monitors, err := exportMonitorConfig(includeSecrets, includeHistory)
+	if err != nil {
+		return nil, err
+	}
+	doc.Monitors = make([]backupMonitor, len(monitors))
+	for i, m := range monitors {
+		doc.Monitors[i].importedMonitor = m
+		if includeHistory {
+			db.DB.Where("monitor_id = ?", m.ID).Order("hour").Find(&doc.Monitors[i].Hourly)
+			db.DB.Where("monitor_id = ?", m.ID).Order("date").Find(&doc.Monitors[i].Daily)
+		}
+	}
+
+	if doc.Groups, err = db.MonitorGroups(); err != nil {
+		return nil, err
+	}
+	if err := db.DB.Order("id").Find(&doc.Notifications).Error; err != nil {
+		return nil, err
+	}
+	if !includeSecrets {
+		doc.Notifications = redactNotifications(doc.Notifications)
+	}
+	if doc.Templates, err = db.CustomTemplates(); err != nil {
+		return nil, err
+	}
+
+	var settings []model.Setting
+	if err := db.DB.Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	for _, st := range settings {
+		// 模板单独导出，恢复时需要按新的规则 ID 重写 key
+		if !db.IsTemplateSettingKey(st.Key) {
+			doc.Settings[st.Key] = st.Value
+		}
+	}
+
+	if err := db.DB.Order("id").Find(&doc.MaintenanceWindows).Error; err != nil {
+		return nil, err
+	}
+
+	var users []model.User
+	if err := db.DB.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		bu := backupUser{Username: u.Username, Role: u.Role, TagScope: u.TagScope}
+		if includeSecrets {
+			bu.Password = u.Password
+		}
+		doc.Users = append(doc.Users, bu)
+	}
+	return doc, nil
+}
+
+// readBackup 读取备份文档，自动识别 gzip 压缩，并校验格式版本
+func readBackup(r io.Reader) (*backupDocument, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+	var doc backupDocument
+	if err := json.NewDecoder(io.LimitReader(src, maxRestoreBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid backup JSON: %w", err)
+	}
+	if doc.SchemaVersion < 1 || doc.SchemaVersion > backupSchemaVersion {
+		return nil, fmt.Errorf("unsupported backup schema_version %d (supported: 1-%d)", doc.SchemaVersion, backupSchemaVersion)
+	}
+	return &doc, nil
+}
+
+// restoreBackup 在一个事务中恢复备份，任何一步失败都整体回滚。
+// replace 模式先清空监控项（含心跳和聚合）、分组、通知规则、设置和维护窗口，再按备份中的 ID 写入；
+// merge 模式保留现有数据，只添加名称（设置为 key）不存在的项，新建的记录使用新 ID。
+// 两种模式都只添加不存在且带密码哈希的账号，不会修改或删除已有账号。
+// 受影响的监控项在恢复前停止调度，恢复后按新的配置错开启动；恢复失败时重新启动原来的监控项
+func (s *Server) restoreBackup(doc *backupDocument, replace bool) (*restoreReport, error) {
+	report := &restoreReport{Mode: "merge", SkippedMonitors: []string{}}
+	var stopped []model.Monitor
+	if replace {
+		report.Mode = "replace"
+		if err := db.DB.Find(&stopped).Error; err != nil {
+			return nil, err
+		}
+		for _, m := range stopped {
+			s.monitorService.StopMonitor(m.ID)
+		}
+	}
+	var oldRules []uint
+	db.DB.Model(&model.Notification{}).Pluck("id", &oldRules)
+
+	var started []*model.Monitor
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := clearForRestore(tx); err != nil {
+				return err
+			}
+		}
+		var err error
+		if started, err = restoreContent(tx, doc, replace, report); err != nil {
+			return err
+		}
+		if replace {
+			// 替换模式按备份中的 ID 写入
+			return db.ResetIDSequences(tx, &model.MonitorGroup{}, &model.Monitor{}, &model.Notification{}, &model.MaintenanceWindow{})
+		}
+		return nil
+	})
+	if err != nil {
+		// 恢复原来的调度
+		for i := range stopped {
+			if stopped[i].Active == 1 {
+				s.monitorService.StartMonitor(&stopped[i])
+			}
+		}
+		return nil, err
+	}
+
+	for _, id := range oldRules {
+		s.monitorService.ResetNotificationState(id)
+	}
+	s.monitorService.StartMonitorsStaggered(started, importStaggerWindow)
+	db.InvalidateStatusSettings()
+	clearEmbedCache()
+	clearStatusPageCache()
+	s.socketServer.To("public").Emit("updateMonitorList")
+	s.broadcastMonitorList()
+	s.broadcastNotificationList()
+	logger.Info("Backup restored", zap.String("mode", report.Mode), zap.Int("monitors", report.Monitors.Restored),
+		zap.Int("notifications", report.Notifications.Restored), zap.Int("heartbeats", report.Heartbeats))
+	return report, nil
+}
+
+// clearForRestore 替换模式下清空将被备份覆盖的数据，账号、API 密钥、探针、状态页和事件公告保留
+func clearForRestore(tx *gorm.DB) error {
+	all := tx.Session(&gorm.Session{AllowGlobalUpdate: true})
+	for _, table := range []any{
+		&model.Monitor{}, &model.Heartbeat{}, &model.HeartbeatHourly{}, &model.HeartbeatDaily{}, &model.HeartbeatAgentHourly{},
+		&model.StatusEvent{}, &model.MonitorGroup{}, &model.Notification{}, &model.NotificationState{},
+		&model.Setting{}, &model.MaintenanceWindow{},
+	} {
+		if err := all.Delete(table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreContent 写入备份内容，返回需要启动的监控项
+func restoreContent(tx *gorm.DB, doc *backupDocument, replace bool, report *restoreReport) ([]*model.Monitor, error) {
+	for _, g := range doc.Groups {
+		name := strings.TrimSpace(g.Name)
+		if name == "" {
+			report.Groups.Skipped++
+			continue
+		}
+		var count int64
+		tx.Model(&model.MonitorGroup{}).Where("name = ?", name).Count(&count)
+		if count > 0 {
+			report.Groups.Skipped++
+			continue
+		}
+		group := model.MonitorGroup{Name: name, Weight: g.Weight}
+		if replace {
+			group.ID = g.ID
+		}
+		if err := tx.Create(&group).Error; err != nil {
+			return nil, fmt.Errorf("group %s: %w", name, err)
+		}
+		report.Groups.Restored++
+	}
+
+	// 备份中的监控项 ID -> 恢复后的 ID（merge 模式下同名的已有监控项也计入，用于恢复维护窗口）
+	monitorIDs := make(map[uint]uint, len(doc.Monitors))
+	var started []*model.Monitor
+	var history importHistory
+	for _, in := range doc.Monitors {
+		m := in.config()
+		var existing model.Monitor
+		tx.Select("id").Where("name = ?", m.Name).Limit(1).Find(&existing)
+		if m.Name == "" || existing.ID != 0 {
+			if existing.ID != 0 {
+				monitorIDs[m.ID] = existing.ID
+			}
+			report.Monitors.Skipped++
+			report.SkippedMonitors = append(report.SkippedMonitors, m.Name)
+			continue
+		}
+		restored, errMsg := prepareImportedMonitor(tx, m)
+		if errMsg != "" {
+			report.Monitors.Skipped++
+			report.SkippedMonitors = append(report.SkippedMonitors, m.Name)
+			continue
+		}
+		if replace {
+			restored.ID = m.ID
+		}
+		if group := strings.TrimSpace(in.Group); group != "" {
+			groupID, err := db.EnsureMonitorGroup(tx, group)
+			if err != nil {
+				return nil, fmt.Errorf("%s: group: %w", m.Name, err)
+			}
+			restored.GroupID = groupID
+		}
+		if err := createMonitor(tx, &restored); err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Name, err)
+		}
+		// Active 的默认值为 1，暂停的监控项需要单独写入
+		if m.Active != 1 {
+			if err := tx.Model(&restored).Update("active", m.Active).Error; err != nil {
+				return nil, fmt.Errorf("%s: %w", m.Name, err)
+			}
+			restored.Active = m.Active
+		}
+		monitorIDs[m.ID] = restored.ID
+		report.Monitors.Restored++
+		if restored.Active == 1 {
+			started = append(started, &restored)
+		}
+
+		if len(in.Heartbeats) > 0 {
+			h, err := importHeartbeats(tx, &restored, in.Heartbeats)
+			if err != nil {
+				return nil, fmt.Errorf("%s: heartbeats: %w", m.Name, err)
+			}
+			history.add(h)
+		}
+		n, err := restoreAggregates(tx, restored.ID, in.Hourly, in.Daily)
+		if err != nil {
+			return nil, fmt.Errorf("%s: aggregates: %w", m.Name, err)
+		}
+		report.Aggregates += n
+	}
+	report.Heartbeats = history.count
+	if replace {
+		// 状态页中不在备份里的监控项
+		if err := tx.Where("monitor_id NOT IN (SELECT id FROM monitors)").Delete(&model.StatusPageMonitor{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 备份中的规则 ID -> 恢复后的 ID，用于恢复规则模板
+	ruleIDs := make(map[uint]uint, len(doc.Notifications))
+	for _, n := range doc.Notifications {
+		var count int64
+		tx.Model(&model.Notification{}).Where("name = ?", n.Name).Count(&count)
+		if n.Name == "" || count > 0 {
+			report.Notifications.Skipped++
+			continue
+		}
+		rule := model.Notification{Name: n.Name, Type: n.Type, Config: n.Config, Active: n.Active, Version: 1}
+		if replace {
+			rule.ID = n.ID
+		}
+		if err := tx.Create(&rule).Error; err != nil {
+			return nil, fmt.Errorf("notification %s: %w", n.Name, err)
+		}
+		// Active 的默认值为 true，停用的规则需要单独写入
+		if !n.Active {
+			tx.Model(&rule).Update("active", false)
+		}
+		ruleIDs[n.ID] = rule.ID
+		report.Notifications.Restored++
+	}
+
+	for _, t := range doc.Templates {
+		ruleID := uint(0)
+		if t.RuleID != 0 {
+			var ok bool
+			if ruleID, ok = ruleIDs[t.RuleID]; !ok {
+				report.Templates.Skipped++
+				continue
+			}
+		}
+		if created, err := createSettingIfMissing(tx, db.TemplateSettingKey(t.Kind, ruleID), t.Source, "template"); err != nil {
+			return nil, err
+		} else if !created {
+			report.Templates.Skipped++
+			continue
+		}
+		report.Templates.Restored++
+	}
+
+	for key, value := range doc.Settings {
+		if db.IsTemplateSettingKey(key) {
+			report.Settings.Skipped++
+			continue
+		}
+		if created, err := createSettingIfMissing(tx, key, value, ""); err != nil {
+			return nil, err
+		} else if !created {
+			report.Settings.Skipped++
+			continue
+		}
+		report.Settings.Restored++
+	}
+
+	for _, w := range doc.MaintenanceWindows {
+		if w.MonitorID != 0 {
+			id, ok := monitorIDs[w.MonitorID]
+			if !ok {
+				report.MaintenanceWindows.Skipped++
+				continue
+			}
+			w.MonitorID = id
+		}
+		var count int64
+		tx.Model(&model.MaintenanceWindow{}).
+			Where("monitor_id = ? AND tag = ? AND starts_at = ? AND ends_at = ? AND cron = ?", w.MonitorID, w.Tag, w.StartsAt, w.EndsAt, w.Cron).
+			Count(&count)
+		if count > 0 {
+			report.MaintenanceWindows.Skipped++
+			continue
+		}
+		if !replace {
+			w.ID = 0
+		}
+		if err := tx.Create(&w).Error; err != nil {
+			return nil, fmt.Errorf("maintenance window %d: %w", w.ID, err)
+		}
+		report.MaintenanceWindows.Restored++
+	}
+
+	for _, u := range doc.Users {
+		var count int64
+		tx.Unscoped().Model(&model.User{}).Where("username = ?", u.Username).Count(&count)
+		if u.Username == "" || u.Password == "" || count > 0 {
+			report.Users.Skipped++
+			continue
+		}
+		// 没有角色的备份（添加角色之前导出的）按 admin 恢复
+		role := model.RoleViewer
+		if u.Role != model.RoleViewer {
+			role = model.RoleAdmin
+		}
+		if err := tx.Create(&model.User{Username: u.Username, Password: u.Password, Role: role, TagScope: u.TagScope}).Error; err != nil {
+			return nil, fmt.Errorf("user %s: %w", u.Username, err)
+		}
+		report.Users.Restored++
+	}
+	return started, nil
+}
+
+// restoreAggregates 写入监控项的小时和日聚合，返回写入的条数
+func restoreAggregates(tx *gorm.DB, monitorID uint, hourly []model.HeartbeatHourly, daily []model.HeartbeatDaily) (int, error) {
+	for i := range hourly {
+		hourly[i].ID, hourly[i].MonitorID = 0, monitorID
+	}
+	for i := range daily {
+		daily[i].ID, daily[i].MonitorID = 0, monitorID
+	}
+	if len(hourly) > 0 {
+		if err := tx.CreateInBatches(hourly, 500).Error; err != nil {
+			return 0, err
+		}
+	}
+	if len(daily) > 0 {
+		if err := tx.CreateInBatches(daily, 500).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(hourly) + len(daily), nil
+}
+
+// createSettingIfMissing key 不存在时写入设置项，返回是否写入
+func createSettingIfMissing(tx *gorm.DB, key, value, kind string) (bool, error) {
+	var count int64
+	if err := tx.Model(&model.Setting{}).Where("key = ?", key).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := tx.Create(&model.Setting{Key: key, Value: value, Type: kind}).Error; err != nil {
+		return false, fmt.Errorf("setting %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// backupAPI 处理 GET /api/v1/backup?include_secrets=true&include_history=true&gzip=true：下载完整备份
+func (s *Server) backupAPI(c *gin.Context) {
+	includeSecrets, _ := strconv.ParseBool(c.Query("include_secrets"))
+	includeHistory, _ := strconv.ParseBool(c.Query("include_history"))
+	compress, _ := strconv.ParseBool(c.Query("gzip"))
+	doc, err := buildBackup(includeSecrets, includeHistory)
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to create backup"))
+		return
+	}
+	filename := "pinggo-backup-" + doc.CreatedAt.Format("20060102-150405") + ".json"
+	db.RecordAudit(apiActor(c), auditActionBackupCreate, "server",
+		fmt.Sprintf("%s secrets=%t history=%t", filename, includeSecrets, includeHistory))
+
+	c.Header("Cache-Control", "no-store")
+	if !compress {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.JSON(http.StatusOK, doc)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".gz"))
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "application/gzip")
+	gz := gzip.NewWriter(c.Writer)
+	if err := json.NewEncoder(gz).Encode(doc); err != nil {
+		logger.Warn("Failed to write backup", zap.Error(err))
+	}
+	gz.Close()
+}
+
+// restoreAPI 处理 POST /api/v1/restore?mode=merge|replace：请求体为备份文档（JSON 或 gzip），mode 默认为 merge
+func (s *Server) restoreAPI(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "merge")
+	if mode != "merge" && mode != "replace" {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "mode must be merge or replace", http.StatusBadRequest, nil))
+		return
+	}
+	doc, err := readBackup(http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreBytes))
+	if err != nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, err.Error(), http.StatusBadRequest, err))
+		return
+	}
+	report, err := s.restoreBackup(doc, mode == "replace")
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Restore failed, nothing was changed: "+err.Error()))
+		return
+	}
+	db.RecordAudit(apiActor(c), auditActionBackupRestore, "server",
+		fmt.Sprintf("mode=%s monitors=%d notifications=%d", mode, report.Monitors.Restored, report.Notifications.Restored))
+	c.JSON(http.StatusOK, report)
+}
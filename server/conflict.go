@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// conflictReply 构造乐观锁冲突（409）回执：current 为当前保存的内容（含当前 version），前端可据此重新加载并合并后再保存。
+// changed 为读取之后被他人修改的字段，为 nil 时（无法判断）不返回 changed_fields
+func conflictReply(current map[string]any, changed []string) map[string]any {
+	reply := map[string]any{
+		"ok":       false,
+		"code":     409,
+		"conflict": true,
+		"msg":      "已被其他人修改，请重新加载后合并再保存",
+		"version":  current["version"],
+		"current":  current,
+	}
+	if changed != nil {
+		reply["changed_fields"] = changed
+	}
+	return reply
+}
+
+// versionRequiredReply 编辑请求没有回传读取到的版本号
+func versionRequiredReply() map[string]any {
+	return map[string]any{"ok": false, "code": 428, "msg": "缺少 version，请重新加载后再保存"}
+}
+
+// changedSince 返回读取之后被他人修改的字段：submitted 中的 _original 为客户端读取时的内容（getMonitor / 通知详情），
+// 只比较本次提交的字段，按 JSON 语义比较（数字统一为 float64），跳过 id/version 以及读取时或当前不存在的字段。
+// 没有回传 _original 时返回 nil：本次提交与当前值的差异既包含自己的修改也包含他人的修改，无法区分
+func changedSince(current, submitted map[string]any) []string {
+	original, ok := submitted["_original"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	original, now := normalizeJSON(original), normalizeJSON(current)
+	fields := []string{}
+	for k := range submitted {
+		if k == "id" || k == "version" || k == "_original" {
+			continue
+		}
+		before, ok1 := original[k]
+		after, ok2 := now[k]
+		if ok1 && ok2 && !reflect.DeepEqual(before, after) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// normalizeJSON 经过一次 JSON 编解码，使 Go 值与客户端提交的值可以直接比较
+func normalizeJSON(v map[string]any) map[string]any {
+	normalized := make(map[string]any)
+	if b, err := json.Marshal(v); err == nil {
+		json.Unmarshal(b, &normalized)
+	}
+	return normalized
+}
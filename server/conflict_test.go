@@ -0,0 +1,45 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+// changedSince 只报告读取之后被他人修改的字段，不包括本次提交自己的修改
+func TestChangedSince(t *testing.T) {
+	current := map[string]any{"id": uint(1), "version": 3, "name": "api-renamed", "interval": 30, "url": "https://a", "status": 1}
+	original := map[string]any{"id": 1.0, "version": 2.0, "name": "api", "interval": 30.0, "url": "https://a", "status": 0.0}
+
+	for _, tt := range []struct {
+		name      string
+		submitted map[string]any
+		want      []string
+	}{
+		{
+			name:      "no original",
+			submitted: map[string]any{"id": 1.0, "version": 2.0, "name": "api", "interval": 60.0},
+			want:      nil,
+		},
+		{
+			name:      "own edits are not conflicts",
+			submitted: map[string]any{"id": 1.0, "version": 2.0, "name": "api", "interval": 60.0, "url": "https://b", "_original": original},
+			want:      []string{"name"},
+		},
+		{
+			name:      "fields not submitted are ignored",
+			submitted: map[string]any{"id": 1.0, "version": 2.0, "interval": 60.0, "_original": original},
+			want:      []string{},
+		},
+		{
+			name:      "unknown fields are ignored",
+			submitted: map[string]any{"id": 1.0, "version": 2.0, "name": "x", "extra": true, "_original": map[string]any{"extra": false}},
+			want:      []string{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedSince(current, tt.submitted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedSince = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"ping-go/config"
+	apperrors "ping-go/pkg/errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+)
+
+// demoToken 演示模式下登录返回的固定会话 token，不写入数据库
+const demoToken = "demo"
+
+// demoReadEvents 演示模式下仍可调用的需要登录的事件，其余一律返回演示模式回执
+var demoReadEvents = map[string]bool{
+	"getMonitor":                true,
+	"getFailureHeatmap":         true,
+	"getIncidentContext":        true,
+	"getMonitorGroups":          true,
+	"getIncidents":              true,
+	"getMonitorRegions":         true,
+	"getHeartbeatList":          true,
+	"getHeartbeatListWithRange": true,
+	"getMonitorStats":           true,
+	"getChartData":              true,
+}
+
+// demoDetailFields 演示模式下 getMonitor 返回的字段，请求头、请求体、凭据、hook、token 等配置一律不返回
+var demoDetailFields = []string{
+	"id", "version", "name", "url", "type", "tags", "interval", "active", "status", "status_key",
+	"msg", "last_check", "recentResults", "method", "timeout", "expected_status", "public", "badge",
+	"drift_avg_ms", "drift_max_ms", "failure_heatmap", "group_id", "group",
+}
+
+func demoActive() bool {
+	return config.Get().DemoActive()
+}
+
+// demoReply 演示模式拒绝修改操作的回执
+func demoReply() map[string]any {
+	return map[string]any{"ok": false, "code": 403, "demo": true, "msg": "演示模式下不能修改，所有管理功能均为只读"}
+}
+
+// replyDemoMode 回复演示模式回执：有回调时通过 ack 返回，否则发送 notification 事件
+func replyDemoMode(client *socket.Socket, args []any) {
+	for _, arg := range args {
+		if ack, ok := arg.(func([]any, error)); ok {
+			ack([]any{demoReply()}, nil)
+			return
+		}
+	}
+	client.Emit("notification", map[string]any{"message": demoReply()["msg"], "type": "warning"})
+}
+
+// authenticateDemoSocket 将连接标记为演示访客：不关联账号，加入 demo 房间接收脱敏的监控列表
+func authenticateDemoSocket(client *socket.Socket) {
+	socketAuth.Store(client.Id(), map[string]any{
+		"authenticated": true,
+		"userID":        uint(0),
+		"token":         demoToken,
+		"demo":          true,
+	})
+	client.Join("demo")
+}
+
+// expireDemoAuth 演示模式到期后注销演示访客，避免其继续以无账号身份调用管理事件
+func expireDemoAuth(client *socket.Socket) {
+	if demoActive() {
+		return
+	}
+	if val, ok := socketAuth.Load(client.Id()); ok {
+		if data, ok := val.(map[string]any); ok && data["demo"] == true {
+			socketAuth.Delete(client.Id())
+			client.Leave("demo")
+		}
+	}
+}
+
+// maskURL 只保留 scheme 和主机，隐藏用户信息、路径和查询参数；没有 scheme 的地址（ping/tcp/dns）截去路径部分
+func maskURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		masked := u.Scheme + "://" + u.Host
+		if strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+			masked += "/***"
+		}
+		return masked
+	}
+	if i := strings.IndexAny(raw, "/?#"); i >= 0 {
+		return raw[:i] + "/***"
+	}
+	return raw
+}
+
+// demoMonitorDetail 过滤监控详情，只保留 demoDetailFields 并脱敏 URL
+func demoMonitorDetail(data map[string]any) map[string]any {
+	out := make(map[string]any, len(demoDetailFields))
+	for _, k := range demoDetailFields {
+		if v, ok := data[k]; ok {
+			out[k] = v
+		}
+	}
+	if u, ok := out["url"].(string); ok {
+		out["url"] = maskURL(u)
+	}
+	return out
+}
+
+// errDemoReadOnly 演示模式下修改数据的 REST 请求返回的错误
+var errDemoReadOnly = apperrors.New(http.StatusForbidden, "Demo mode: the API is read-only", http.StatusForbidden, nil)
+
+// rejectInDemo 演示模式下拒绝修改数据的 REST 接口
+func rejectInDemo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if demoActive() {
+			writeAPIError(c, errDemoReadOnly)
+			return
+		}
+		c.Next()
+	}
+}
+
+// demoMonitorList 复制管理员监控列表并脱敏 URL
+func demoMonitorList(adminData map[uint]map[string]any) map[uint]map[string]any {
+	out := make(map[uint]map[string]any, len(adminData))
+	for id, data := range adminData {
+		d := make(map[string]any, len(data))
+		for k, v := range data {
+			d[k] = v
+		}
+		if u, ok := d["url"].(string); ok {
+			d["url"] = maskURL(u)
+		}
+		out[id] = d
+	}
+	return out
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/logger"
+	"ping-go/pkg/secretbox"
+	"ping-go/pkg/totp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zishang520/socket.io/socket"
+	"go.uber.org/zap"
+)
+
+const (
+	// totpIssuer 验证器应用中显示的服务名称
+	totpIssuer = "PingGo"
+	// recoveryCodeCount 启用两步验证时生成的恢复码数量，每个只能使用一次
+	recoveryCodeCount = 10
+	// loginChallengeTTL 密码验证通过后输入两步验证码的时限
+	loginChallengeTTL = 5 * time.Minute
+	// maxLoginCodeAttempts 每次密码登录后最多尝试的验证码次数，超过后需要重新输入密码
+	maxLoginCodeAttempts = 5
+
+	auditActionUser2FAEnable   = "user.2fa_enable"
+	auditActionUser2FADisable  = "user.2fa_disable"
+	auditActionUser2FARecovery = "user.2fa_recovery"
+)
+
+// pendingLogin 密码已验证、等待两步验证码的登录
+type pendingLogin struct {
+	userID   uint
+	expires  time.Time
+	attempts atomic.Int32
+}
+
+// pendingLogins 等待两步验证码的登录，key: socketID，连接断开时删除
+var pendingLogins = sync.Map{}
+
+// generateRecoveryCodes 生成恢复码，返回明文（只显示一次）和保存到数据库的摘要
+func generateRecoveryCodes() ([]string, string) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		rand.Read(b)
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashAPIKey(code)
+	}
+	return codes, strings.Join(hashes, ",")
+}
+
+// normalizeRecoveryCode 去掉恢复码中的空白和连字符并转为小写
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+// verifySecondFactor 校验两步验证码或恢复码，返回使用的方式（totp 或 recovery）。
+// 通过的时间步和使用过的恢复码用条件更新记录，并发提交同一个验证码时只有一个请求成功
+func verifySecondFactor(user *model.User, code string) (string, bool) {
+	if user.TOTPSecret == "" {
+		return "", false
+	}
+	secret, err := secretbox.Open(user.TOTPSecret)
+	if err != nil {
+		logger.Error("Failed to decrypt 2FA secret", zap.Uint("user_id", user.ID), zap.Error(err))
+		return "", false
+	}
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		res := db.DB.Model(&model.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).Update("totp_last_step", step)
+		return "totp", res.Error == nil && res.RowsAffected == 1
+	}
+
+	hashes := strings.Split(user.RecoveryCodes, ",")
+	i := slices.Index(hashes, hashAPIKey(normalizeRecoveryCode(code)))
+	if user.RecoveryCodes == "" || i < 0 {
+		return "", false
+	}
+	remaining := strings.Join(slices.Delete(slices.Clone(hashes), i, i+1), ",")
+	res := db.DB.Model(&model.User{}).Where("id = ? AND recovery_codes = ?", user.ID, user.RecoveryCodes).Update("recovery_codes", remaining)
+	return "recovery", res.Error == nil && res.RowsAffected == 1
+}
+
+// recoveryCodesLeft 未使用的恢复码数量
+func recoveryCodesLeft(user *model.User) int {
+	if user.RecoveryCodes == "" {
+		return 0
+	}
+	return strings.Count(user.RecoveryCodes, ",") + 1
+}
+
+// setupTwoFactorHandlers 设置两步验证（TOTP）相关的 Socket.IO 事件处理器。
+// 除 login2FA 外这些事件不在 scopedEvents 中，只有不限范围的管理员可以为自己的账号开启或关闭
+func (s *Server) setupTwoFactorHandlers(client *socket.Socket) {
+	reply := func(ack func([]any, error), ok bool, msg string) {
+		ack([]any{map[string]any{"ok": ok, "msg": msg}}, nil)
+	}
+	currentUser := func() (*model.User, bool) {
+		var user model.User
+		db.DB.Where("id = ?", socketUserID(client)).Limit(1).Find(&user)
+		return &user, user.ID != 0
+	}
+	codeArg := func(args []any) string {
+		if len(args) > 0 {
+			if data, ok := args[0].(map[string]any); ok {
+				return safeMapGetString(data, "code")
+			}
+		}
+		return ""
+	}
+
+	// Handle "get2FAStatus"
+	requireAuth(client, "get2FAStatus", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		user, ok := currentUser()
+		if !ok {
+			reply(ack, false, "User not found")
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "enabled": user.TOTPEnabled, "recovery_codes_left": recoveryCodesLeft(user)}}, nil)
+	})
+
+	// Handle "setup2FA"
+	// 生成新的密钥和 otpauth:// 地址（前端据此显示二维码），调用 confirm2FA 提交正确的验证码后才启用
+	requireAuth(client, "setup2FA", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		user, ok := currentUser()
+		if !ok {
+			reply(ack, false, "User not found")
+			return
+		}
+		if user.TOTPEnabled {
+			reply(ack, false, "Two-factor authentication is already enabled")
+			return
+		}
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			reply(ack, false, "Failed to generate secret")
+			return
+		}
+		sealed, err := secretbox.Seal(secret)
+		if err != nil {
+			logger.Error("Failed to encrypt 2FA secret", zap.Error(err))
+			reply(ack, false, "Failed to encrypt secret: "+err.Error())
+			return
+		}
+		if err := db.DB.Model(user).Updates(map[string]any{"totp_secret": sealed, "totp_last_step": 0}).Error; err != nil {
+			reply(ack, false, "Failed to save secret")
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "secret": secret, "uri": totp.URI(totpIssuer, user.Username, secret)}}, nil)
+	})
+
+	// Handle "confirm2FA" - args: ({code})
+	// 验证码正确时启用两步验证，返回只显示一次的恢复码
+	requireAuth(client, "confirm2FA", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		user, ok := currentUser()
+		if !ok {
+			reply(ack, false, "User not found")
+			return
+		}
+		if user.TOTPEnabled {
+			reply(ack, false, "Two-factor authentication is already enabled")
+			return
+		}
+		if user.TOTPSecret == "" {
+			reply(ack, false, "Call setup2FA first")
+			return
+		}
+		secret, err := secretbox.Open(user.TOTPSecret)
+		if err != nil {
+			reply(ack, false, "Failed to decrypt secret, call setup2FA again")
+			return
+		}
+		step, valid := totp.Validate(secret, codeArg(args), time.Now())
+		if !valid {
+			reply(ack, false, "Invalid code")
+			return
+		}
+		codes, hashes := generateRecoveryCodes()
+		err = db.DB.Model(user).Updates(map[string]any{"totp_enabled": true, "totp_last_step": step, "recovery_codes": hashes}).Error
+		if err != nil {
+			reply(ack, false, "Failed to enable two-factor authentication")
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionUser2FAEnable, userAuditTarget(user.ID), "enabled")
+		ack([]any{map[string]any{"ok": true, "msg": "Two-factor authentication enabled", "recovery_codes": codes}}, nil)
+	})
+
+	// Handle "disable2FA" - args: ({code})
+	// 需要当前的验证码或一个恢复码
+	requireAuth(client, "disable2FA", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		user, ok := currentUser()
+		if !ok {
+			reply(ack, false, "User not found")
+			return
+		}
+		if !user.TOTPEnabled {
+			reply(ack, false, "Two-factor authentication is not enabled")
+			return
+		}
+		method, valid := verifySecondFactor(user, codeArg(args))
+		if !valid {
+			reply(ack, false, "Invalid code")
+			return
+		}
+		err := db.DB.Model(user).Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0, "recovery_codes": ""}).Error
+		if err != nil {
+			reply(ack, false, "Failed to disable two-factor authentication")
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionUser2FADisable, userAuditTarget(user.ID), "disabled with "+method+" code")
+		reply(ack, true, "Two-factor authentication disabled")
+	})
+
+	// Handle "login2FA" - args: ({code})
+	// login 返回 tokenRequired 后提交验证码或恢复码，通过后创建会话
+	client.On("login2FA", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		val, ok := pendingLogins.Load(client.Id())
+		pending, _ := val.(*pendingLogin)
+		if !ok || time.Now().After(pending.expires) {
+			pendingLogins.Delete(client.Id())
+			reply(ack, false, "Login expired, please sign in again")
+			return
+		}
+		if pending.attempts.Add(1) > maxLoginCodeAttempts {
+			pendingLogins.Delete(client.Id())
+			reply(ack, false, "Too many attempts, please sign in again")
+			return
+		}
+		var user model.User
+		db.DB.Where("id = ?", pending.userID).Limit(1).Find(&user)
+		method, valid := verifySecondFactor(&user, codeArg(args))
+		if user.ID == 0 || !valid {
+			reply(ack, false, "Invalid code")
+			return
+		}
+		pendingLogins.Delete(client.Id())
+		if method == "recovery" {
+			db.RecordAudit("user:"+user.Username, auditActionUser2FARecovery, userAuditTarget(user.ID),
+				fmt.Sprintf("signed in with a recovery code, %d left", recoveryCodesLeft(&user)-1))
+		}
+		token, err := createLoginSession(client, user.ID)
+		if err != nil {
+			reply(ack, false, "Failed to create session")
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "token": token}}, nil)
+	})
+}
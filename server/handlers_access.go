@@ -0,0 +1,282 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zishang520/socket.io/socket"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix API 密钥前缀，用于和会话 token 区分
+const apiKeyPrefix = "pgk_"
+
+const (
+	auditActionAPIKeyCreate = "api_key.create"
+	auditActionAPIKeyRevoke = "api_key.revoke"
+	auditActionAPIKeyDelete = "api_key.delete"
+)
+
+// apiKeyExpiry 解析创建密钥时的过期时间：expires_at（RFC3339 或 2006-01-02）或 expires_in_days，都不填表示永不过期
+func apiKeyExpiry(data map[string]any, now time.Time) (*time.Time, string) {
+	raw := strings.TrimSpace(safeMapGetString(data, "expires_at"))
+	days, hasDays := safeMapGetFloat64(data, "expires_in_days")
+	var t time.Time
+	switch {
+	case raw != "" && hasDays && days > 0:
+		return nil, "Specify either expires_at or expires_in_days, not both"
+	case raw != "":
+		var err error
+		if t, err = time.Parse(time.RFC3339, raw); err != nil {
+			if t, err = time.ParseInLocation("2006-01-02", raw, db.Location()); err != nil {
+				return nil, "expires_at must be an RFC3339 timestamp or a date (YYYY-MM-DD)"
+			}
+		}
+	case hasDays && days > 0:
+		t = now.Add(time.Duration(days * float64(24*time.Hour)))
+	default:
+		return nil, ""
+	}
+	if !t.After(now) {
+		return nil, "Expiry must be in the future"
+	}
+	return &t, ""
+}
+
+// hashAPIKey 计算 API 密钥的 SHA-256 摘要，数据库中只保存摘要
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// setupAccessHandlers 设置 API 密钥、只读账号管理和审计日志相关的 Socket.IO 事件处理器。
+// 这些事件不在 scopedEvents 中，只有不限范围的管理员可以调用
+func (s *Server) setupAccessHandlers(client *socket.Socket) {
+	// Handle "getApiKeys"
+	requireAuth(client, "getApiKeys", func(args ...any) {
+		var keys []model.APIKey
+		if err := db.DB.Order("id").Find(&keys).Error; err != nil {
+			client.Emit("error", map[string]any{"msg": "Failed to fetch API keys"})
+			return
+		}
+		client.Emit("apiKeyList", keys)
+	})
+
+	// Handle "createApiKey" - args: {name, scope?, tag_scope, expires_at?, expires_in_days?}
+	// scope 为 read / write / admin，默认 admin。明文密钥只在创建时返回一次
+	requireAuth(client, "createApiKey", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		name := strings.TrimSpace(safeMapGetString(data, "name"))
+		if name == "" {
+			ack([]any{map[string]any{"ok": false, "msg": "Name is required"}}, nil)
+			return
+		}
+		scope := strings.ToLower(strings.TrimSpace(safeMapGetString(data, "scope")))
+		if scope == "" {
+			scope = model.APIKeyScopeAdmin
+		}
+		if !slices.Contains(model.APIKeyScopes, scope) {
+			ack([]any{map[string]any{"ok": false, "msg": "Scope must be one of read, write, admin"}}, nil)
+			return
+		}
+		expiresAt, errMsg := apiKeyExpiry(data, time.Now())
+		if errMsg != "" {
+			ack([]any{map[string]any{"ok": false, "msg": errMsg}}, nil)
+			return
+		}
+
+		plain := apiKeyPrefix + generateToken()
+		key := model.APIKey{
+			Name:      name,
+			KeyHash:   hashAPIKey(plain),
+			Prefix:    plain[:len(apiKeyPrefix)+6],
+			Scope:     scope,
+			TagScope:  model.NormalizeTags(safeMapGetString(data, "tag_scope")),
+			ExpiresAt: expiresAt,
+		}
+		if err := db.DB.Create(&key).Error; err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "Failed to create API key: " + err.Error()}}, nil)
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionAPIKeyCreate, apiKeyAuditTarget(key.ID), key.Name+" scope="+key.Scope)
+		ack([]any{map[string]any{"ok": true, "msg": "API key created", "id": key.ID, "key": plain}}, nil)
+	})
+
+	// Handle "revokeApiKey" - args: id
+	// 吊销后密钥立即失效（REST 请求返回 401），记录保留在列表中
+	requireAuth(client, "revokeApiKey", func(args ...any) {
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+		ok, msg := true, "API key revoked"
+		res := db.DB.Model(&model.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+		if res.Error != nil {
+			ok, msg = false, res.Error.Error()
+		} else if res.RowsAffected == 0 {
+			ok, msg = false, "API key not found or already revoked"
+		} else {
+			db.RecordAudit(socketActor(client), auditActionAPIKeyRevoke, apiKeyAuditTarget(id), "revoked")
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": ok, "msg": msg}}, nil)
+		}
+	})
+
+	// Handle "deleteApiKey" - args: id
+	requireAuth(client, "deleteApiKey", func(args ...any) {
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+		ok, msg := true, "API key deleted"
+		if err := db.DB.Delete(&model.APIKey{}, id).Error; err != nil {
+			ok, msg = false, err.Error()
+		} else {
+			db.RecordAudit(socketActor(client), auditActionAPIKeyDelete, apiKeyAuditTarget(id), "deleted")
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": ok, "msg": msg}}, nil)
+		}
+	})
+
+	// Handle "getUsers"
+	requireAuth(client, "getUsers", func(args ...any) {
+		var users []model.User
+		if err := db.DB.Order("id").Find(&users).Error; err != nil {
+			client.Emit("error", map[string]any{"msg": "Failed to fetch users"})
+			return
+		}
+		client.Emit("userList", users)
+	})
+
+	// Handle "createViewer" - args: {username, password, tag_scope}
+	// 只读账号必须限定标签范围，不限范围的账号等同于管理员
+	requireAuth(client, "createViewer", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		username := strings.TrimSpace(safeMapGetString(data, "username"))
+		password := safeMapGetString(data, "password")
+		tagScope := model.NormalizeTags(safeMapGetString(data, "tag_scope"))
+		if username == "" || password == "" {
+			ack([]any{map[string]any{"ok": false, "msg": "Username and password are required"}}, nil)
+			return
+		}
+		if tagScope == "" {
+			ack([]any{map[string]any{"ok": false, "msg": "Tag scope is required"}}, nil)
+			return
+		}
+
+		var count int64
+		db.DB.Model(&model.User{}).Where("username = ?", username).Count(&count)
+		if count > 0 {
+			ack([]any{map[string]any{"ok": false, "msg": "Username already exists"}}, nil)
+			return
+		}
+		hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "Failed to hash password"}}, nil)
+			return
+		}
+		user := model.User{Username: username, Password: string(hashedPwd), TagScope: tagScope}
+		if err := db.DB.Create(&user).Error; err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "Failed to create user: " + err.Error()}}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "msg": "Viewer created", "id": user.ID}}, nil)
+	})
+
+	// Handle "createUser" - args: {username, password, role, tag_scope?}
+	// role 为 admin 或 viewer；viewer 只能查看，修改操作返回 403
+	requireAuth(client, "createUser", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		user, errMsg := createUser(data)
+		if errMsg != "" {
+			ack([]any{map[string]any{"ok": false, "msg": errMsg}}, nil)
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionUserCreate, userAuditTarget(user.ID), user.Username+" role="+user.Role)
+		ack([]any{map[string]any{"ok": true, "msg": "User created", "id": user.ID}}, nil)
+	})
+
+	// Handle "deleteUser" - args: id
+	// 不能删除自己和最后一个不限范围的管理员；同时清除其会话并断开已登录的连接
+	requireAuth(client, "deleteUser", func(args ...any) {
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+		ok, msg := true, "User deleted"
+		var user model.User
+		if err := db.DB.First(&user, id).Error; err != nil {
+			ok, msg = false, "User not found"
+		} else if user.ID == socketUserID(client) {
+			ok, msg = false, "You cannot delete your own account"
+		} else if isLastAdmin(&user) {
+			ok, msg = false, "Cannot delete the last admin account"
+		} else if err := db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Delete(&user).Error; err != nil {
+				return err
+			}
+			return tx.Where("user_id = ?", user.ID).Delete(&model.Session{}).Error
+		}); err != nil {
+			ok, msg = false, err.Error()
+		} else {
+			s.logoutOtherSockets(user.ID, "")
+			db.RecordAudit(socketActor(client), auditActionUserDelete, userAuditTarget(user.ID), user.Username)
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": ok, "msg": msg}}, nil)
+		}
+	})
+
+	// Handle "getAuditLog" - args: limit（可选，默认 100，最多 1000）
+	requireAuth(client, "getAuditLog", func(args ...any) {
+		limit := 100
+		if v, err := getArgAsUint(args, 0); err == nil && v > 0 {
+			limit = min(int(v), 1000)
+		}
+		logs, err := db.RecentAuditLogs(limit)
+		if err != nil {
+			client.Emit("error", map[string]any{"msg": "Failed to fetch audit log"})
+			return
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": true, "logs": logs}}, nil)
+			return
+		}
+		client.Emit("auditLog", logs)
+	})
+}
+
+// apiKeyAuditTarget 审计日志中 API 密钥的目标标识
+func apiKeyAuditTarget(id uint) string {
+	return fmt.Sprintf("api_key:%d", id)
+}
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// minPasswordLength 新密码的最小长度（字符数）
+	minPasswordLength = 8
+	// maxUsernameLength 用户名的最大长度（字符数）
+	maxUsernameLength = 64
+
+	auditActionUserCreate   = "user.create"
+	auditActionUserDelete   = "user.delete"
+	auditActionUserPassword = "user.password"
+	auditActionUserRename   = "user.rename"
+)
+
+// accountPasswordRequest changePassword 和 POST /api/v1/account/password 的参数
+type accountPasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// accountUsernameRequest changeUsername 和 POST /api/v1/account/username 的参数
+type accountUsernameRequest struct {
+	Username string `json:"username"`
+}
+
+// accountPasswordChanged 修改密码的结果，token 为替换所有旧会话的新会话
+type accountPasswordChanged struct {
+	Token string `json:"token"`
+}
+
+// accountUsernameChanged 修改用户名的结果
+type accountUsernameChanged struct {
+	Username string `json:"username"`
+}
+
+// changePassword 校验当前密码后保存新密码，删除该账号的全部会话并创建一个新会话，返回新会话的 token。
+// 该账号的其他 Socket 连接随即被登出，当前连接（keep）由调用方用新 token 重新认证
+func (s *Server) changePassword(userID uint, req accountPasswordRequest, keep socket.SocketId, actor string) (string, *apperrors.AppError) {
+	bad := func(msg string) *apperrors.AppError {
+		return apperrors.New(http.StatusBadRequest, msg, http.StatusBadRequest, nil)
+	}
+	var user model.User
+	if err := db.DB.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return "", apperrors.Wrap(err, "Failed to load user")
+	}
+	if user.ID == 0 {
+		return "", apperrors.ErrUnauthorized
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)) != nil {
+		return "", apperrors.New(http.StatusForbidden, "Current password is incorrect", http.StatusForbidden, nil)
+	}
+	if utf8.RuneCountInString(req.NewPassword) < minPasswordLength {
+		return "", bad(fmt.Sprintf("New password must be at least %d characters", minPasswordLength))
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", bad("New password must be at most 72 bytes")
+	}
+	if err != nil {
+		return "", apperrors.Wrap(err, "Failed to hash password")
+	}
+
+	token := generateToken()
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", string(hashed)).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.Session{Token: token, UserID: user.ID, Role: user.Role, ExpiresAt: newSessionExpiry()}).Error
+	})
+	if err != nil {
+		return "", apperrors.Wrap(err, "Failed to change password")
+	}
+	s.logoutOtherSockets(user.ID, keep)
+	db.RecordAudit(actor, auditActionUserPassword, userAuditTarget(user.ID), "password changed, other sessions signed out")
+	return token, nil
+}
+
+// changeUsername 修改账号的用户名，用户名不能与其他账号（包括已删除的账号）重复
+func changeUsername(userID uint, req accountUsernameRequest, actor string) (string, *apperrors.AppError) {
+	bad := func(msg string) *apperrors.AppError {
+		return apperrors.New(http.StatusBadRequest, msg, http.StatusBadRequest, nil)
+	}
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		return "", bad("Username is required")
+	}
+	if utf8.RuneCountInString(username) > maxUsernameLength {
+		return "", bad(fmt.Sprintf("Username must be at most %d characters", maxUsernameLength))
+	}
+	var user model.User
+	if err := db.DB.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return "", apperrors.Wrap(err, "Failed to load user")
+	}
+	if user.ID == 0 {
+		return "", apperrors.ErrUnauthorized
+	}
+	if user.Username == username {
+		return username, nil
+	}
+	// 唯一索引包括软删除的账号
+	var count int64
+	db.DB.Unscoped().Model(&model.User{}).Where("username = ? AND id <> ?", username, user.ID).Count(&count)
+	if count > 0 {
+		return "", apperrors.New(http.StatusConflict, "Username already exists", http.StatusConflict, nil)
+	}
+	previous := user.Username
+	if err := db.DB.Model(&user).Update("username", username).Error; err != nil {
+		return "", apperrors.Wrap(err, "Failed to change username")
+	}
+	db.RecordAudit(actor, auditActionUserRename, userAuditTarget(user.ID), previous+" -> "+username)
+	return username, nil
+}
+
+// createUser 校验参数并创建账号（createUser 事件），失败时返回错误信息
+func createUser(data map[string]any) (*model.User, string) {
+	username := strings.TrimSpace(safeMapGetString(data, "username"))
+	password := safeMapGetString(data, "password")
+	role := strings.TrimSpace(safeMapGetString(data, "role"))
+	if username == "" || password == "" {
+		return nil, "Username and password are required"
+	}
+	if utf8.RuneCountInString(username) > maxUsernameLength {
+		return nil, fmt.Sprintf("Username must be at most %d characters", maxUsernameLength)
+	}
+	if role != model.RoleAdmin && role != model.RoleViewer {
+		return nil, "Role must be admin or viewer"
+	}
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return nil, fmt.Sprintf("Password must be at least %d characters", minPasswordLength)
+	}
+	var count int64
+	db.DB.Unscoped().Model(&model.User{}).Where("username = ?", username).Count(&count)
+	if count > 0 {
+		return nil, "Username already exists"
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return nil, "Password must be at most 72 bytes"
+	}
+	if err != nil {
+		return nil, "Failed to hash password"
+	}
+	user := model.User{
+		Username: username,
+		Password: string(hashed),
+		Role:     role,
+		TagScope: model.NormalizeTags(safeMapGetString(data, "tag_scope")),
+	}
+	if err := db.DB.Create(&user).Error; err != nil {
+		return nil, "Failed to create user: " + err.Error()
+	}
+	return &user, ""
+}
+
+// isLastAdmin 账号是否为唯一一个不限范围的 admin，删除后将没有人能管理实例
+func isLastAdmin(user *model.User) bool {
+	if user.IsViewer() || user.TagScope != "" {
+		return false
+	}
+	var others int64
+	db.DB.Model(&model.User{}).
+		Where("id <> ? AND (role IS NULL OR role <> ?) AND (tag_scope IS NULL OR tag_scope = '')", user.ID, model.RoleViewer).
+		Count(&others)
+	return others == 0
+}
+
+// logoutOtherSockets 登出账号除 keep 以外的所有 Socket 连接（修改密码后旧会话失效）
+func (s *Server) logoutOtherSockets(userID uint, keep socket.SocketId) {
+	s.socketServer.Sockets().Sockets().Range(func(id socket.SocketId, client *socket.Socket) bool {
+		if id == keep || socketUserID(client) != userID {
+			return true
+		}
+		forgetScopedSocket(client)
+		socketAuth.Delete(id)
+		client.Leave("admin")
+		client.Emit("error", map[string]any{"code": 401, "msg": "Unauthorized"})
+		return true
+	})
+}
+
+// socketToken 返回连接登录使用的会话 token，未登录时为空
+func socketToken(client *socket.Socket) string {
+	val, ok := socketAuth.Load(client.Id())
+	if !ok {
+		return ""
+	}
+	data, _ := val.(map[string]any)
+	token, _ := data["token"].(string)
+	return token
+}
+
+// socketUserID 返回连接登录的账号 ID，未登录时为 0
+func socketUserID(client *socket.Socket) uint {
+	val, ok := socketAuth.Load(client.Id())
+	if !ok {
+		return 0
+	}
+	data, _ := val.(map[string]any)
+	id, _ := data["userID"].(uint)
+	return id
+}
+
+// userAuditTarget 审计日志中账号的目标标识
+func userAuditTarget(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+// setupAccountHandlers 设置修改当前账号用户名和密码的 Socket.IO 事件处理器。
+// 这些事件不在 scopedEvents 中，只有不限范围的管理员可以调用
+func (s *Server) setupAccountHandlers(client *socket.Socket) {
+	// Handle "changePassword" - args: ({currentPassword, newPassword})
+	// 成功后该账号的其他会话全部失效，当前连接改用返回的新 token
+	requireAuth(client, "changePassword", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		userID := socketUserID(client)
+		req := accountPasswordRequest{
+			CurrentPassword: safeMapGetString(data, "currentPassword"),
+			NewPassword:     safeMapGetString(data, "newPassword"),
+		}
+		token, appErr := s.changePassword(userID, req, client.Id(), socketActor(client))
+		if appErr != nil {
+			ack([]any{map[string]any{"ok": false, "code": appErr.Code, "msg": appErr.Message}}, nil)
+			return
+		}
+		authenticateSocket(client, userID, token)
+		ack([]any{map[string]any{"ok": true, "msg": "Password changed", "token": token}}, nil)
+	})
+
+	// Handle "changeUsername" - args: ({username})
+	requireAuth(client, "changeUsername", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		username, appErr := changeUsername(socketUserID(client), accountUsernameRequest{Username: safeMapGetString(data, "username")}, socketActor(client))
+		if appErr != nil {
+			ack([]any{map[string]any{"ok": false, "code": appErr.Code, "msg": appErr.Message}}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "msg": "Username changed", "username": username}}, nil)
+	})
+}
+
+// apiUserID 返回 REST 请求的会话所属账号 ID，API 密钥认证时为 0
+func apiUserID(c *gin.Context) uint {
+	id, _ := c.Get("userID")
+	userID, _ := id.(uint)
+	return userID
+}
+
+// changePasswordAPI 处理 POST /api/v1/account/password：与 changePassword 相同，只能使用会话 token 调用，
+// 返回替换所有旧会话的新 token
+func (s *Server) changePasswordAPI(c *gin.Context) {
+	var req accountPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Invalid JSON body", http.StatusBadRequest, err))
+		return
+	}
+	token, appErr := s.changePassword(apiUserID(c), req, "", apiActor(c))
+	if appErr != nil {
+		writeAPIError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, accountPasswordChanged{Token: token})
+}
+
+// changeUsernameAPI 处理 POST /api/v1/account/username：与 changeUsername 相同，只能使用会话 token 调用
+func (s *Server) changeUsernameAPI(c *gin.Context) {
+	var req accountUsernameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Invalid JSON body", http.StatusBadRequest, err))
+		return
+	}
+	username, appErr := changeUsername(apiUserID(c), req, apiActor(c))
+	if appErr != nil {
+		writeAPIError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, accountUsernameChanged{Username: username})
+}
@@ -0,0 +1,515 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"ping-go/pkg/logger"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// agentTokenPrefix 探针令牌前缀，与 API 密钥（pgk_）和会话 token 区分
+const agentTokenPrefix = "pga_"
+
+const (
+	auditActionAgentCreate = "agent.create"
+	auditActionAgentUpdate = "agent.update"
+	auditActionAgentDelete = "agent.delete"
+	auditActionAgentAssign = "agent.assign"
+
+	// maxAgentResults 单次上报的结果数量上限
+	maxAgentResults = 1000
+	// agentResultMaxAge 上报结果的时间最早可以是多久之前（探针与服务器断开后补报）
+	agentResultMaxAge = 24 * time.Hour
+	// maxAgentMessageRunes 上报结果消息的最大长度，超出部分截断
+	maxAgentMessageRunes = 1000
+	// maxAgentBodyBytes 上报请求体上限，足够容纳 maxAgentResults 条带最长消息的结果
+	maxAgentBodyBytes = 8 << 20
+	// agentReportInterval、agentReportBurst 每个探针的上报限流：探针每 10 秒上报一次，
+	// 恢复连接后连续补报多批缓存的结果，被限流的批次留在探针缓存中下次重试
+	agentReportInterval = time.Second
+	agentReportBurst    = 10
+)
+
+// agentView 探针列表中的一项
+type agentView struct {
+	model.Agent
+	Online     bool   `json:"online"`
+	MonitorIDs []uint `json:"monitor_ids"`
+}
+
+// agentMonitorsResponse GET /api/agent/monitors 的响应：探针信息、建议的拉取间隔和分配给它的已启用监控项（含检查所需的凭据）
+type agentMonitorsResponse struct {
+	Agent        model.Agent     `json:"agent"`
+	PollInterval int             `json:"poll_interval"` // 秒
+	Monitors     []model.Monitor `json:"monitors"`
+}
+
+// agentResult 探针上报的一次检查结果
+type agentResult struct {
+	MonitorID uint      `json:"monitor_id"`
+	Status    int       `json:"status"`
+	Message   string    `json:"msg"`
+	Duration  int       `json:"duration"`
+	Time      time.Time `json:"time"`
+}
+
+// agentReport POST /api/agent/heartbeats 的请求体
+type agentReport struct {
+	Results []agentResult `json:"results"`
+}
+
+// agentReportResult 上报结果：写入的数量和被拒绝（未分配给该探针、状态无效或时间超出范围）的数量
+type agentReportResult struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+// regionStatus 监控项在一个来源的状态和统计，AgentID 为 0 表示中心服务器
+type regionStatus struct {
+	AgentID        uint       `json:"agent_id"`
+	Name           string     `json:"name"`
+	Region         string     `json:"region"`
+	Online         bool       `json:"online"`
+	Status         int        `json:"status"`
+	StatusKey      string     `json:"status_key"`
+	Message        string     `json:"msg"`
+	LastCheck      *time.Time `json:"last_check"`
+	Uptime24h      float64    `json:"uptime_24h"`
+	Uptime7d       float64    `json:"uptime_7d"`
+	AvgResponse24h float64    `json:"avg_response_24h"`
+}
+
+// regionSummary 所有来源合并后的统计
+type regionSummary struct {
+	Uptime24h      float64 `json:"uptime_24h"`
+	Uptime7d       float64 `json:"uptime_7d"`
+	AvgResponse24h float64 `json:"avg_response_24h"`
+}
+
+// monitorRegions 监控项的分地区状态：中心服务器和分配了该监控项的探针，以及合并的统计
+type monitorRegions struct {
+	MonitorID uint           `json:"monitor_id"`
+	Regions   []regionStatus `json:"regions"`
+	Combined  regionSummary  `json:"combined"`
+}
+
+// agentViews 返回探针列表及各自分配的监控项
+func agentViews() ([]agentView, error) {
+	var agents []model.Agent
+	if err := db.DB.Order("id").Find(&agents).Error; err != nil {
+		return nil, err
+	}
+	var links []model.AgentMonitor
+	if err := db.DB.Order("monitor_id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	assigned := make(map[uint][]uint)
+	for _, l := range links {
+		assigned[l.AgentID] = append(assigned[l.AgentID], l.MonitorID)
+	}
+	now := time.Now()
+	views := make([]agentView, len(agents))
+	for i, a := range agents {
+		views[i] = agentView{Agent: a, Online: a.Online(now), MonitorIDs: assigned[a.ID]}
+		if views[i].MonitorIDs == nil {
+			views[i].MonitorIDs = []uint{}
+		}
+	}
+	return views, nil
+}
+
+// regionsForMonitor 汇总监控项在中心服务器和各探针的最近状态，以及 24 小时 / 7 天的分地区和合并统计
+func regionsForMonitor(m *model.Monitor) monitorRegions {
+	stats24h := db.GetRegionStats(m.ID, 24*time.Hour)
+	stats7d := db.GetRegionStats(m.ID, 7*24*time.Hour)
+	region := func(id uint) regionStatus {
+		return regionStatus{
+			AgentID:        id,
+			Uptime24h:      uptimeOrDefault(stats24h, id),
+			Uptime7d:       uptimeOrDefault(stats7d, id),
+			AvgResponse24h: stats24h[id].AvgResponse,
+		}
+	}
+
+	central := region(0)
+	central.Name, central.Online = "central", true
+	central.Status, central.Message = m.Status, m.Message
+	if !m.LastCheck.IsZero() {
+		lastCheck := m.LastCheck
+		central.LastCheck = &lastCheck
+	}
+	central.StatusKey = model.StatusKey(central.Status)
+	out := monitorRegions{MonitorID: m.ID, Regions: []regionStatus{central}}
+
+	var links []model.AgentMonitor
+	db.DB.Where("monitor_id = ?", m.ID).Order("agent_id").Find(&links)
+	if len(links) > 0 {
+		ids := make([]uint, len(links))
+		for i, l := range links {
+			ids[i] = l.AgentID
+		}
+		var agents []model.Agent
+		db.DB.Where("id IN ?", ids).Find(&agents)
+		byID := make(map[uint]model.Agent, len(agents))
+		for _, a := range agents {
+			byID[a.ID] = a
+		}
+		now := time.Now()
+		for _, l := range links {
+			a, ok := byID[l.AgentID]
+			if !ok {
+				continue
+			}
+			r := region(a.ID)
+			r.Name, r.Region, r.Online = a.Name, a.Region, a.Online(now)
+			r.Status, r.Message, r.LastCheck = l.Status, l.Message, l.LastCheck
+			r.StatusKey = model.StatusKey(r.Status)
+			out.Regions = append(out.Regions, r)
+		}
+	}
+
+	combined24h := db.GetCombinedStats(m.ID, 24*time.Hour)
+	out.Combined = regionSummary{
+		Uptime24h:      combined24h.Uptime,
+		Uptime7d:       db.GetCombinedStats(m.ID, 7*24*time.Hour).Uptime,
+		AvgResponse24h: combined24h.AvgResponse,
+	}
+	return out
+}
+
+// uptimeOrDefault 返回来源的可用率，没有数据时为 100（与 GetUptimeStats 相同）
+func uptimeOrDefault(stats map[uint]db.RegionStats, id uint) float64 {
+	if s, ok := stats[id]; ok {
+		return s.Uptime
+	}
+	return 100
+}
+
+// setupAgentHandlers 设置远程探针管理相关的 Socket.IO 事件处理器。
+// 除 getMonitorRegions 外这些事件不在 scopedEvents 中，只有不限范围的管理员可以调用
+func (s *Server) setupAgentHandlers(client *socket.Socket) {
+	// Handle "getAgents"
+	requireAuth(client, "getAgents", func(args ...any) {
+		views, err := agentViews()
+		if err != nil {
+			client.Emit("error", map[string]any{"msg": "Failed to fetch agents"})
+			return
+		}
+		client.Emit("agentList", views)
+	})
+
+	// Handle "addAgent" - args: ({name, region})
+	// 明文令牌只在创建时返回一次，用于启动 pinggo --agent --token=...
+	requireAuth(client, "addAgent", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		name := strings.TrimSpace(safeMapGetString(data, "name"))
+		if name == "" {
+			ack([]any{map[string]any{"ok": false, "msg": "Name is required"}}, nil)
+			return
+		}
+		plain := agentTokenPrefix + generateToken()
+		agent := model.Agent{
+			Name:        name,
+			Region:      strings.TrimSpace(safeMapGetString(data, "region")),
+			TokenHash:   hashAPIKey(plain),
+			TokenPrefix: plain[:len(agentTokenPrefix)+6],
+		}
+		if err := db.DB.Create(&agent).Error; err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "Failed to add agent: " + err.Error()}}, nil)
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionAgentCreate, agentAuditTarget(agent.ID), agent.Name+" ("+agent.Region+")")
+		ack([]any{map[string]any{"ok": true, "msg": "Agent added", "id": agent.ID, "token": plain}}, nil)
+	})
+
+	// Handle "editAgent" - args: ({id, name, region})
+	requireAuth(client, "editAgent", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		id, _ := safeMapGetFloat64(data, "id")
+		name := strings.TrimSpace(safeMapGetString(data, "name"))
+		if name == "" {
+			ack([]any{map[string]any{"ok": false, "msg": "Name is required"}}, nil)
+			return
+		}
+		region := strings.TrimSpace(safeMapGetString(data, "region"))
+		res := db.DB.Model(&model.Agent{}).Where("id = ?", uint(id)).Updates(map[string]any{"name": name, "region": region})
+		if res.Error != nil {
+			ack([]any{map[string]any{"ok": false, "msg": res.Error.Error()}}, nil)
+			return
+		}
+		if res.RowsAffected == 0 {
+			ack([]any{map[string]any{"ok": false, "code": 404, "msg": "Agent not found"}}, nil)
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionAgentUpdate, agentAuditTarget(uint(id)), name+" ("+region+")")
+		ack([]any{map[string]any{"ok": true, "msg": "Agent updated"}}, nil)
+	})
+
+	// Handle "deleteAgent" - args: id
+	// 删除探针和它的分配，已上报的心跳保留在合并统计中，令牌立即失效
+	requireAuth(client, "deleteAgent", func(args ...any) {
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+		ok, msg := true, "Agent deleted"
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("agent_id = ?", id).Delete(&model.AgentMonitor{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&model.Agent{}, id).Error
+		})
+		if err != nil {
+			ok, msg = false, err.Error()
+		} else {
+			db.RecordAudit(socketActor(client), auditActionAgentDelete, agentAuditTarget(id), "deleted")
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": ok, "msg": msg}}, nil)
+		}
+	})
+
+	// Handle "assignAgentMonitors" - args: ({agent_id, monitor_ids})
+	// 用 monitor_ids 替换探针的监控项，push 监控项由上报驱动，不能分配给探针
+	requireAuth(client, "assignAgentMonitors", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		agentID, _ := safeMapGetFloat64(data, "agent_id")
+		var agent model.Agent
+		db.DB.Where("id = ?", uint(agentID)).Limit(1).Find(&agent)
+		if agent.ID == 0 {
+			ack([]any{map[string]any{"ok": false, "code": 404, "msg": "Agent not found"}}, nil)
+			return
+		}
+		var ids []uint
+		if list, ok := data["monitor_ids"].([]any); ok {
+			for _, v := range list {
+				if f, ok := v.(float64); ok && f > 0 {
+					ids = append(ids, uint(f))
+				}
+			}
+		}
+		var monitorIDs []uint
+		if len(ids) > 0 {
+			db.DB.Model(&model.Monitor{}).Where("id IN ? AND type <> ?", ids, model.MonitorTypePush).Order("id").Pluck("id", &monitorIDs)
+		}
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			// 保留仍然分配的监控项的最近状态
+			q := tx.Where("agent_id = ?", agent.ID)
+			if len(monitorIDs) > 0 {
+				q = q.Where("monitor_id NOT IN ?", monitorIDs)
+			}
+			if err := q.Delete(&model.AgentMonitor{}).Error; err != nil {
+				return err
+			}
+			for _, id := range monitorIDs {
+				link := model.AgentMonitor{AgentID: agent.ID, MonitorID: id, Status: model.StatusPending}
+				if err := tx.Where(model.AgentMonitor{AgentID: agent.ID, MonitorID: id}).FirstOrCreate(&link).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "Failed to assign monitors: " + err.Error()}}, nil)
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionAgentAssign, agentAuditTarget(agent.ID), fmt.Sprintf("monitors=%v", monitorIDs))
+		ack([]any{map[string]any{"ok": true, "msg": "Monitors assigned", "assigned": len(monitorIDs),
+			"skipped": len(ids) - len(monitorIDs)}}, nil)
+	})
+
+	// Handle "getMonitorRegions" - args: (monitorID)
+	// 监控项在中心服务器和各探针的最近状态，以及分地区和合并的可用率
+	requireAuth(client, "getMonitorRegions", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "monitorID is required"}}, nil)
+			return
+		}
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
+		var m model.Monitor
+		if err := db.DB.Where("id = ?", id).Limit(1).Find(&m).Error; err != nil || m.ID == 0 {
+			ack([]any{map[string]any{"ok": false, "code": 404, "msg": "Monitor not found"}}, nil)
+			return
+		}
+		regions := regionsForMonitor(&m)
+		ack([]any{map[string]any{"ok": true, "monitor_id": regions.MonitorID, "regions": regions.Regions, "combined": regions.Combined}}, nil)
+	})
+}
+
+// agentAuditTarget 审计日志中探针的目标标识
+func agentAuditTarget(id uint) string {
+	return fmt.Sprintf("agent:%d", id)
+}
+
+// requireAgentAuth 探针接口认证中间件：通过 "Authorization: Bearer pga_..." 传递创建探针时返回的令牌。
+// 每次认证成功时更新最近请求时间，并清除离线标记
+func requireAgentAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		var agent model.Agent
+		if strings.HasPrefix(token, agentTokenPrefix) {
+			db.DB.Where("token_hash = ?", hashAPIKey(token)).Limit(1).Find(&agent)
+		}
+		if agent.ID == 0 {
+			writeAPIError(c, apperrors.ErrUnauthorized)
+			return
+		}
+		now := time.Now()
+		if agent.Offline {
+			logger.Info("Probe agent is reporting again", zap.String("agent", agent.Name))
+		}
+		db.DB.Model(&agent).Updates(map[string]any{"last_seen": now, "offline": false})
+		c.Set("agent", &agent)
+		c.Next()
+	}
+}
+
+// agentMonitorsAPI 处理 GET /api/agent/monitors：返回分配给探针的已启用监控项。
+// 探针需要凭据才能执行检查，因此包含 Basic Auth 密码等字段；webhook 和 push 配置由中心服务器负责，不返回
+func (s *Server) agentMonitorsAPI(c *gin.Context) {
+	agent := c.MustGet("agent").(*model.Agent)
+	monitors, err := db.AgentMonitors(agent.ID)
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to fetch monitors"))
+		return
+	}
+	for i := range monitors {
+		monitors[i].WebhookURL, monitors[i].WebhookSecret = "", ""
+		monitors[i].PushToken, monitors[i].PushSecret = "", ""
+	}
+	c.JSON(http.StatusOK, agentMonitorsResponse{
+		Agent:        *agent,
+		PollInterval: int(model.AgentPollInterval / time.Second),
+		Monitors:     monitors,
+	})
+}
+
+// agentHeartbeatsAPI 处理 POST /api/agent/heartbeats：写入探针上报的检查结果（心跳带 agent_id），
+// 并更新该探针对每个监控项的最近状态。只接受分配给该探针的监控项，时间必须在最近 24 小时内；
+// 补报了已聚合的小时时排队重建这些监控项的聚合。探针的结果不改变监控项的状态，也不触发通知。
+// 每个探针按 agentReportInterval 限流，请求体不超过 maxAgentBodyBytes
+func (s *Server) agentHeartbeatsAPI(c *gin.Context) {
+	agent := c.MustGet("agent").(*model.Agent)
+	if ok, wait := s.agentLimiter.Allow(strconv.FormatUint(uint64(agent.ID), 10)); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeAPIError(c, apperrors.New(http.StatusTooManyRequests, "Too many requests", http.StatusTooManyRequests, nil))
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAgentBodyBytes)
+	var report agentReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeAPIError(c, apperrors.New(http.StatusRequestEntityTooLarge, "Request body too large", http.StatusRequestEntityTooLarge, err))
+			return
+		}
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Invalid JSON body", http.StatusBadRequest, err))
+		return
+	}
+	if len(report.Results) > maxAgentResults {
+		writeAPIError(c, apperrors.New(http.StatusRequestEntityTooLarge, "Too many results in one request", http.StatusRequestEntityTooLarge, nil))
+		return
+	}
+
+	var assignedIDs []uint
+	db.DB.Model(&model.AgentMonitor{}).Where("agent_id = ?", agent.ID).Pluck("monitor_id", &assignedIDs)
+	now := time.Now()
+	var result agentReportResult
+	heartbeats := make([]model.Heartbeat, 0, len(report.Results))
+	latest := make(map[uint]agentResult)
+	var earliest time.Time
+	for _, r := range report.Results {
+		if !slices.Contains(assignedIDs, r.MonitorID) || !slices.Contains(model.StatusCodes, r.Status) ||
+			r.Time.IsZero() || r.Time.After(now.Add(time.Minute)) || r.Time.Before(now.Add(-agentResultMaxAge)) {
+			result.Rejected++
+			continue
+		}
+		if utf8.RuneCountInString(r.Message) > maxAgentMessageRunes {
+			r.Message = string([]rune(r.Message)[:maxAgentMessageRunes])
+		}
+		if r.Time.After(now) {
+			r.Time = now
+		}
+		heartbeats = append(heartbeats, model.Heartbeat{
+			MonitorID: r.MonitorID, Status: r.Status, Message: r.Message, Time: r.Time,
+			Duration: max(r.Duration, 0), RepresentedCount: 1, AgentID: agent.ID,
+		})
+		if prev, ok := latest[r.MonitorID]; !ok || r.Time.After(prev.Time) {
+			latest[r.MonitorID] = r
+		}
+		if earliest.IsZero() || r.Time.Before(earliest) {
+			earliest = r.Time
+		}
+	}
+	if len(heartbeats) > 0 {
+		if err := db.DB.CreateInBatches(heartbeats, 500).Error; err != nil {
+			writeAPIError(c, apperrors.Wrap(err, "Failed to save heartbeats"))
+			return
+		}
+	}
+	result.Accepted = len(heartbeats)
+
+	monitorIDs := make([]uint, 0, len(latest))
+	for id, r := range latest {
+		monitorIDs = append(monitorIDs, id)
+		db.DB.Model(&model.AgentMonitor{}).
+			Where("agent_id = ? AND monitor_id = ? AND (last_check IS NULL OR last_check < ?)", agent.ID, id, r.Time).
+			Updates(map[string]any{"status": r.Status, "message": r.Message, "last_check": r.Time})
+	}
+	// 当前小时之前的结果错过了定时聚合，重建对应时间段；多个探针同时补报时合并为一次重建
+	if !earliest.IsZero() && earliest.Before(now.Truncate(time.Hour)) {
+		db.QueueAggregation(monitorIDs, earliest)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// monitorRegionsAPI 处理 GET /api/v1/monitors/:id/regions：与 getMonitorRegions 相同
+func (s *Server) monitorRegionsAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, regionsForMonitor(m))
+}
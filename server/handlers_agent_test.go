@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createTestAgent 创建探针并分配监控项，返回探针和令牌
+func createTestAgent(t *testing.T, monitorIDs ...uint) (model.Agent, string) {
+	t.Helper()
+	token := agentTokenPrefix + randomHex(t, 16)
+	agent := model.Agent{Name: "probe", Region: "eu", TokenHash: hashAPIKey(token), TokenPrefix: token[:len(agentTokenPrefix)+6]}
+	if err := db.DB.Create(&agent).Error; err != nil {
+		t.Fatalf("create agent: %v", err)
+	}
+	for _, id := range monitorIDs {
+		if err := db.DB.Create(&model.AgentMonitor{AgentID: agent.ID, MonitorID: id, Status: model.StatusPending}).Error; err != nil {
+			t.Fatalf("assign monitor: %v", err)
+		}
+	}
+	return agent, token
+}
+
+// agentResults 生成 n 条上报结果的 JSON 请求体
+func agentResults(monitorID uint, status, n int, at time.Time) string {
+	results := make([]string, n)
+	for i := range results {
+		results[i] = fmt.Sprintf(`{"monitor_id":%d,"status":%d,"msg":"from probe","time":%q,"duration":500}`,
+			monitorID, status, at.Add(-time.Duration(i)*time.Second).Format(time.RFC3339Nano))
+	}
+	return `{"results":[` + strings.Join(results, ",") + `]}`
+}
+
+func TestAgentHeartbeatsLimits(t *testing.T) {
+	_, ts := newTestServer(t)
+	m := createTestMonitor(t, model.Monitor{Name: "api", PublicVisible: true})
+	_, token := createTestAgent(t, m.ID)
+
+	t.Run("oversized body", func(t *testing.T) {
+		body := `{"results":[],"padding":"` + strings.Repeat("x", maxAgentBodyBytes) + `"}`
+		status, resp := doAPI(t, ts, http.MethodPost, "/api/agent/heartbeats", token, body)
+		assertAPIError(t, status, resp, http.StatusRequestEntityTooLarge, "too large")
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		var limited *http.Response
+		for i := 0; i < agentReportBurst+2 && limited == nil; i++ {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/agent/heartbeats",
+				strings.NewReader(agentResults(m.ID, model.StatusUp, 1, time.Now())))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusTooManyRequests {
+				limited = resp
+			}
+		}
+		if limited == nil {
+			t.Fatalf("no 429 after %d reports", agentReportBurst+2)
+		}
+		if limited.Header.Get("Retry-After") == "" {
+			t.Error("429 without Retry-After")
+		}
+	})
+}
+
+// 探针的结果只进入分地区统计：中心服务器的可用率、响应时间、心跳列表和小时聚合不包含探针上报的心跳，
+// 合并的统计同时包含两者
+func TestAgentHeartbeatsExcludedFromCentralStats(t *testing.T) {
+	_, ts := newTestServer(t)
+	m := createTestMonitor(t, model.Monitor{Name: "api", PublicVisible: true})
+	agent, token := createTestAgent(t, m.ID)
+	admin := createTestAPIKey(t, "admin", "")
+
+	// 中心服务器 3 次 UP（12ms），探针 3 次 DOWN
+	for i := 0; i < 3; i++ {
+		hb := model.Heartbeat{MonitorID: m.ID, Status: model.StatusUp, Time: time.Now().Add(-time.Duration(i+1) * time.Minute), Duration: 12, RepresentedCount: 1}
+		if err := db.DB.Create(&hb).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, body := doAPI(t, ts, http.MethodPost, "/api/agent/heartbeats", token, agentResults(m.ID, model.StatusDown, 3, time.Now()))
+	if status != http.StatusOK {
+		t.Fatalf("report: %d %s", status, body)
+	}
+
+	if got := db.GetUptimeStats(m.ID, 24*time.Hour); got != 100 {
+		t.Errorf("central uptime = %v, want 100", got)
+	}
+	if got := db.GetUptimeStatsBatch([]uint{m.ID}, 24*time.Hour)[m.ID]; got != 100 {
+		t.Errorf("central batch uptime = %v, want 100", got)
+	}
+	if got := db.GetAvgResponseTime(m.ID, 24*time.Hour); got != 12 {
+		t.Errorf("central avg response = %v, want 12", got)
+	}
+
+	status, body = doAPI(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d/heartbeats?hours=24", m.ID), admin, "")
+	if status != http.StatusOK {
+		t.Fatalf("heartbeats: %d %s", status, body)
+	}
+	var page struct {
+		Heartbeats []struct {
+			Status  int  `json:"status"`
+			AgentID uint `json:"agent_id"`
+		} `json:"heartbeats"`
+	}
+	decodeJSON(t, body, &page)
+	if len(page.Heartbeats) != 3 {
+		t.Errorf("heartbeat list has %d rows, want 3", len(page.Heartbeats))
+	}
+	for _, h := range page.Heartbeats {
+		if h.AgentID != 0 || h.Status != model.StatusUp {
+			t.Errorf("agent heartbeat in list: %+v", h)
+		}
+	}
+
+	status, body = doAPI(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d/regions", m.ID), admin, "")
+	if status != http.StatusOK {
+		t.Fatalf("regions: %d %s", status, body)
+	}
+	var regions monitorRegions
+	decodeJSON(t, body, &regions)
+	uptimes := make(map[uint]float64)
+	for _, r := range regions.Regions {
+		uptimes[r.AgentID] = r.Uptime24h
+	}
+	if uptimes[0] != 100 || uptimes[agent.ID] != 0 {
+		t.Errorf("region uptimes = %v, want central 100 and agent 0", uptimes)
+	}
+	if regions.Combined.Uptime24h != 50 {
+		t.Errorf("combined uptime = %v, want 50", regions.Combined.Uptime24h)
+	}
+}
+
+// 补报已聚合小时的结果时在后台重建聚合：探针的心跳只写入探针小时聚合
+func TestAgentBackfillAggregation(t *testing.T) {
+	_, ts := newTestServer(t)
+	m := createTestMonitor(t, model.Monitor{Name: "api", PublicVisible: true})
+	_, token := createTestAgent(t, m.ID)
+
+	at := time.Now().Truncate(time.Hour).Add(-2*time.Hour + 30*time.Minute)
+	central := model.Heartbeat{MonitorID: m.ID, Status: model.StatusUp, Time: at, Duration: 12, RepresentedCount: 1}
+	if err := db.DB.Create(&central).Error; err != nil {
+		t.Fatal(err)
+	}
+	status, body := doAPI(t, ts, http.MethodPost, "/api/agent/heartbeats", token, agentResults(m.ID, model.StatusDown, 2, at))
+	if status != http.StatusOK {
+		t.Fatalf("report: %d %s", status, body)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var agentHourly []model.HeartbeatAgentHourly
+	for len(agentHourly) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		db.DB.Where("monitor_id = ?", m.ID).Find(&agentHourly)
+	}
+	if len(agentHourly) != 1 || agentHourly[0].DownCount != 2 || agentHourly[0].TotalCount != 2 {
+		t.Fatalf("agent hourly = %+v, want one bucket with 2 DOWN", agentHourly)
+	}
+	var hourly []model.HeartbeatHourly
+	db.DB.Where("monitor_id = ?", m.ID).Find(&hourly)
+	if len(hourly) != 1 || hourly[0].UpCount != 1 || hourly[0].TotalCount != 1 {
+		t.Errorf("hourly = %+v, want one bucket with only the central UP", hourly)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"ping-go/db"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// serverAlertListLimit 通知中心一次返回的最大告警数
+const serverAlertListLimit = 200
+
+// setupServerAlertHandlers 设置系统告警（通知中心）相关的 Socket.IO 事件处理器
+func (s *Server) setupServerAlertHandlers(client *socket.Socket) {
+	// Handle "getServerAlerts"，可选参数 {include_acknowledged: bool}
+	requireAuth(client, "getServerAlerts", func(args ...any) {
+		includeAcknowledged := false
+		if len(args) > 0 {
+			if opts, ok := args[0].(map[string]any); ok {
+				includeAcknowledged, _ = opts["include_acknowledged"].(bool)
+			}
+		}
+		alerts, err := db.GetServerAlerts(includeAcknowledged, serverAlertListLimit)
+		if err != nil {
+			client.Emit("error", map[string]any{"msg": "Failed to fetch server alerts"})
+			return
+		}
+		client.Emit("serverAlertList", alerts)
+	})
+
+	// Handle "acknowledgeServerAlert"
+	requireAuth(client, "acknowledgeServerAlert", func(args ...any) {
+		if len(args) < 1 {
+			return
+		}
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+
+		ok, msg := true, "Alert acknowledged"
+		if err := db.AcknowledgeAlert(id); err != nil {
+			ok, msg = false, err.Error()
+		}
+		for _, arg := range args {
+			if ack, isAck := arg.(func([]any, error)); isAck {
+				ack([]any{map[string]any{"ok": ok, "msg": msg}}, nil)
+				break
+			}
+		}
+	})
+}
@@ -0,0 +1,570 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPIHeartbeatHours 心跳查询的最大时间范围（90 天）
+const maxAPIHeartbeatHours = 2160
+
+// exportParams 导出和备份接口的查询参数
+var exportParams = []apiParam{
+	{Name: "include_secrets", Type: "boolean", Description: "Include passwords, tokens and other secrets"},
+	{Name: "include_history", Type: "boolean", Description: "Include heartbeat history"},
+}
+
+// API 文档中的请求体和响应体，监控项和通知规则详情中的附加字段（如 recentResults）未列出
+type (
+	apiActiveRequest struct {
+		Active bool `json:"active"`
+	}
+	apiMonitorList struct {
+		Monitors []model.Monitor `json:"monitors"`
+	}
+	apiNotificationList struct {
+		Notifications []map[string]any `json:"notifications"`
+	}
+	apiDeleted struct {
+		ID      uint `json:"id"`
+		Deleted bool `json:"deleted"`
+	}
+	apiHeartbeatPage struct {
+		MonitorID  uint             `json:"monitor_id"`
+		Hours      int              `json:"hours"`
+		DataType   string           `json:"data_type"`
+		Heartbeats []map[string]any `json:"heartbeats"`
+		Total      int64            `json:"total"`
+		HasMore    bool             `json:"has_more"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	apiHeartbeatsCleared struct {
+		MonitorID uint `json:"monitor_id"`
+		Cleared   bool `json:"cleared"`
+	}
+	apiChart struct {
+		MonitorID uint                `json:"monitor_id"`
+		View      string              `json:"view"`
+		Data      []db.ChartDataPoint `json:"data"`
+	}
+)
+
+// registerAPIv1Routes 注册 REST API v1，覆盖 Socket API 的监控项、心跳、通知规则、设置和导入导出，以及完整备份和恢复，
+// 供 Terraform、脚本等无法使用 Socket.IO 的客户端调用。
+// 通过 "Authorization: Bearer <API 密钥>" 认证，请求体与对应 Socket 事件的参数相同，失败时返回 AppError 格式的 {code, message}。
+// 密钥限定了标签范围时只能访问范围内的监控项，不能访问通知规则、设置、导入导出和备份
+func (s *Server) registerAPIv1Routes() {
+	v1 := s.apiRoutes().Group("/api/v1", requireAPIAuth())
+
+	v1.GET("/monitors", apiDoc{Summary: "List monitors", Tag: "monitors", Response: apiMonitorList{}}, s.listMonitorsAPI)
+	v1.POST("/monitors", apiDoc{Summary: "Create a monitor", Tag: "monitors", Body: model.Monitor{}, Response: model.Monitor{}, Status: http.StatusCreated},
+		rejectInDemo(), s.createMonitorAPI)
+	v1.GET("/monitors/export", apiDoc{Summary: "Export monitors", Tag: "monitors", Query: exportParams, Response: monitorExport{}},
+		requireFullAPIAccess(), s.exportMonitorsAPI)
+	v1.POST("/monitors/import", apiDoc{Summary: "Import monitors", Tag: "monitors", Body: monitorExport{}, Response: importJob{}},
+		rejectInDemo(), requireFullAPIAccess(), s.importMonitorsAPI)
+	v1.POST("/monitors/bulk", apiDoc{Summary: "Apply an action to several monitors", Tag: "monitors", Body: bulkRequest{}, Response: bulkReport{}},
+		rejectInDemo(), s.bulkMonitorsAPI)
+	v1.GET("/monitors/:id", apiDoc{Summary: "Get a monitor", Tag: "monitors", Response: model.Monitor{}}, s.getMonitorAPI)
+	v1.PUT("/monitors/:id", apiDoc{Summary: "Replace a monitor (version required)", Tag: "monitors", Body: model.Monitor{}, Response: model.Monitor{}},
+		rejectInDemo(), s.updateMonitorAPI)
+	v1.DELETE("/monitors/:id", apiDoc{Summary: "Delete a monitor", Tag: "monitors", Response: apiDeleted{}}, rejectInDemo(), s.deleteMonitorAPI)
+	v1.PATCH("/monitors/:id/active", apiDoc{Summary: "Pause or resume a monitor", Tag: "monitors", Body: apiActiveRequest{}, Response: model.Monitor{}},
+		rejectInDemo(), s.setMonitorActiveAPI)
+	v1.GET("/monitors/:id/heartbeats", apiDoc{Summary: "List heartbeats", Tag: "heartbeats", Response: apiHeartbeatPage{}, Query: []apiParam{
+		{Name: "hours", Type: "integer", Description: "Time range, 1-" + strconv.Itoa(maxAPIHeartbeatHours) + " (default 24)"},
+		{Name: "limit", Type: "integer", Description: "Page size, 1-" + strconv.Itoa(maxHeartbeatPageSize)},
+		{Name: "offset", Type: "integer"},
+		{Name: "cursor", Type: "string", Description: "next_cursor of the previous page"},
+		{Name: "status", Type: "string", Description: "Status code or key (up, down, ...)"},
+	}}, s.monitorHeartbeatsAPI)
+	v1.DELETE("/monitors/:id/heartbeats", apiDoc{Summary: "Clear heartbeats", Tag: "heartbeats", Response: apiHeartbeatsCleared{}},
+		rejectInDemo(), s.clearHeartbeatsAPI)
+	v1.GET("/monitors/:id/stats", apiDoc{Summary: "Uptime and response time statistics", Tag: "heartbeats", Response: map[string]any{}}, s.monitorStatsAPI)
+	v1.GET("/monitors/:id/regions", apiDoc{Summary: "Status and uptime per region (central server and agents)", Tag: "heartbeats", Response: monitorRegions{}},
+		s.monitorRegionsAPI)
+	v1.GET("/monitors/:id/chart", apiDoc{Summary: "Chart data", Tag: "heartbeats", Response: apiChart{},
+		Query: []apiParam{{Name: "view", Type: "string", Description: "24h (default) or 7d"}}}, s.monitorChartAPI)
+
+	notifications := v1.Group("/notifications", requireFullAPIAccess())
+	notifications.GET("", apiDoc{Summary: "List notification rules", Tag: "notifications", Response: apiNotificationList{}}, s.listNotificationsAPI)
+	notifications.POST("", apiDoc{Summary: "Create a notification rule", Tag: "notifications", Body: map[string]any{}, Response: map[string]any{}, Status: http.StatusCreated},
+		rejectInDemo(), s.createNotificationAPI)
+	notifications.GET("/:id", apiDoc{Summary: "Get a notification rule", Tag: "notifications", Response: map[string]any{}}, s.getNotificationAPI)
+	notifications.PUT("/:id", apiDoc{Summary: "Replace a notification rule (version required)", Tag: "notifications", Body: map[string]any{}, Response: map[string]any{}},
+		rejectInDemo(), s.updateNotificationAPI)
+	notifications.DELETE("/:id", apiDoc{Summary: "Delete a notification rule", Tag: "notifications", Response: apiDeleted{}}, rejectInDemo(), s.deleteNotificationAPI)
+	notifications.PATCH("/:id/active", apiDoc{Summary: "Enable or disable a notification rule", Tag: "notifications", Body: apiActiveRequest{}, Response: map[string]any{}},
+		rejectInDemo(), s.setNotificationActiveAPI)
+
+	v1.GET("/settings", apiDoc{Summary: "Get settings", Tag: "settings", Response: map[string]any{}}, requireFullAPIAccess(), s.getSettingsAPI)
+	v1.PATCH("/settings", apiDoc{Summary: "Update settings", Tag: "settings", Body: map[string]any{}, Response: map[string]any{}},
+		rejectInDemo(), requireFullAPIAccess(), s.updateSettingsAPI)
+
+	v1.GET("/backup", apiDoc{Summary: "Download a full backup", Tag: "backup", Response: backupDocument{},
+		Query: slices.Concat(exportParams, []apiParam{{Name: "gzip", Type: "boolean", Description: "Compress with gzip"}})}, requireFullAPIAccess(), s.backupAPI)
+	v1.POST("/restore", apiDoc{Summary: "Restore a backup", Tag: "backup", Body: backupDocument{}, Response: restoreReport{},
+		Query: []apiParam{{Name: "mode", Type: "string", Description: "merge (default) or replace"}}}, rejectInDemo(), requireFullAPIAccess(), s.restoreAPI)
+
+	// 修改当前账号，只能使用会话 token（API 密钥不属于任何账号）
+	v1.POST("/account/password", apiDoc{Summary: "Change the password of the current account", Tag: "account", Body: accountPasswordRequest{}, Response: accountPasswordChanged{}},
+		rejectInDemo(), requireAdminSession(), s.changePasswordAPI)
+	v1.POST("/account/username", apiDoc{Summary: "Change the username of the current account", Tag: "account", Body: accountUsernameRequest{}, Response: accountUsernameChanged{}},
+		rejectInDemo(), requireAdminSession(), s.changeUsernameAPI)
+}
+
+// writeAPIError 以 AppError 的格式输出错误并中止请求
+func writeAPIError(c *gin.Context, err *apperrors.AppError) {
+	c.AbortWithStatusJSON(err.StatusCode, err)
+}
+
+// writeReplyError 把 Socket 处理函数的失败回复（ok: false）转换为 AppError 格式的响应：
+// 状态码取回复中的 code（默认 400），出错的字段（field）和版本冲突的详情（current、changed_fields、versions）原样附带
+func writeReplyError(c *gin.Context, reply map[string]any) {
+	code, ok := reply["code"].(int)
+	if !ok {
+		code = http.StatusBadRequest
+	}
+	msg, _ := reply["msg"].(string)
+	body := gin.H{"code": code, "message": msg}
+	for _, k := range []string{"field", "current", "changed_fields", "versions"} {
+		if v, ok := reply[k]; ok && v != "" {
+			body[k] = v
+		}
+	}
+	c.AbortWithStatusJSON(code, body)
+}
+
+// requireFullAPIAccess 只允许不限标签范围的 admin 账号或 admin 权限且不限标签范围的密钥访问（通知规则、设置、导入导出、备份）
+func requireFullAPIAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiScope(c) != nil {
+			writeAPIError(c, apperrors.ErrForbidden)
+			return
+		}
+		if key, ok := c.Get("apiKey"); ok && !key.(*model.APIKey).Allows(model.APIKeyScopeAdmin) {
+			writeAPIError(c, errAPIKeyNotAdmin)
+			return
+		}
+		if c.GetString("role") == model.RoleViewer {
+			writeAPIError(c, apperrors.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// fullAPIAccess 请求是否满足 requireFullAPIAccess 的条件，用于在普通接口中决定是否返回仅限管理员的字段
+func fullAPIAccess(c *gin.Context) bool {
+	if apiScope(c) != nil || c.GetString("role") == model.RoleViewer {
+		return false
+	}
+	key, ok := c.Get("apiKey")
+	return !ok || key.(*model.APIKey).Allows(model.APIKeyScopeAdmin)
+}
+
+// 密钥或账号权限不足时返回的错误
+var (
+	errAPIKeyReadOnly = apperrors.New(http.StatusForbidden, "API key is read-only", http.StatusForbidden, nil)
+	errViewerReadOnly = apperrors.New(http.StatusForbidden, "Viewer accounts are read-only", http.StatusForbidden, nil)
+	errAPIKeyNotAdmin = apperrors.New(http.StatusForbidden, "API key requires the admin scope", http.StatusForbidden, nil)
+)
+
+// bindAPIBody 读取 JSON 对象请求体，无效时输出 400
+func bindAPIBody(c *gin.Context) (map[string]any, bool) {
+	var data map[string]any
+	if err := c.ShouldBindJSON(&data); err != nil || data == nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Invalid JSON body", http.StatusBadRequest, err))
+		return nil, false
+	}
+	return data, true
+}
+
+// bindActive 读取 PATCH .../active 的请求体 {"active": true}，也接受 1/0
+func bindActive(c *gin.Context) (bool, bool) {
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return false, false
+	}
+	switch v := data["active"].(type) {
+	case bool:
+		return v, true
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, true
+		}
+	}
+	writeAPIError(c, apperrors.New(http.StatusBadRequest, "active must be true or false", http.StatusBadRequest, nil))
+	return false, false
+}
+
+// apiMonitor 解析路径中的监控项 ID 并检查标签范围，不存在或超出范围时直接输出 404/403
+func apiMonitor(c *gin.Context) (*model.Monitor, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	var m model.Monitor
+	if err == nil {
+		err = db.DB.Where("id = ?", id).Limit(1).Find(&m).Error
+	}
+	if err != nil || m.ID == 0 {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Monitor not found", http.StatusNotFound, err))
+		return nil, false
+	}
+	if !m.InScope(apiScope(c)) {
+		writeAPIError(c, apperrors.ErrForbidden)
+		return nil, false
+	}
+	return &m, true
+}
+
+// writeMonitorSaveAPI 输出 addMonitor/editMonitor 的结果：成功时返回保存后的监控详情
+func (s *Server) writeMonitorSaveAPI(c *gin.Context, reply map[string]any, okStatus int) {
+	if ok, _ := reply["ok"].(bool); !ok {
+		writeReplyError(c, reply)
+		return
+	}
+	var m model.Monitor
+	id, _ := reply["monitorID"].(uint)
+	if err := db.DB.First(&m, id).Error; err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to load monitor"))
+		return
+	}
+	body := s.monitorDetail(&m, fullAPIAccess(c))
+	if note, _ := reply["note"].(string); note != "" {
+		body["note"] = note
+	}
+	c.JSON(okStatus, body)
+}
+
+// listMonitorsAPI 处理 GET /api/v1/monitors：范围内的全部监控项详情
+func (s *Server) listMonitorsAPI(c *gin.Context) {
+	var monitors []model.Monitor
+	if err := db.DB.Order("id").Find(&monitors).Error; err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to fetch monitors"))
+		return
+	}
+	scope, full := apiScope(c), fullAPIAccess(c)
+	list := make([]map[string]any, 0, len(monitors))
+	for i := range monitors {
+		if monitors[i].InScope(scope) {
+			list = append(list, s.monitorDetail(&monitors[i], full))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"monitors": list})
+}
+
+// getMonitorAPI 处理 GET /api/v1/monitors/:id：与 getMonitor 相同的详情，修改时需回传其中的 version
+func (s *Server) getMonitorAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, s.monitorDetail(m, fullAPIAccess(c)))
+}
+
+// createMonitorAPI 处理 POST /api/v1/monitors：创建监控项并开始检查
+func (s *Server) createMonitorAPI(c *gin.Context) {
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return
+	}
+	s.writeMonitorSaveAPI(c, s.addMonitor(data, apiScope(c)), http.StatusCreated)
+}
+
+// updateMonitorAPI 处理 PUT /api/v1/monitors/:id：整体替换监控项配置。
+// 请求体必须包含读取时的 version，已被他人修改时返回 409
+func (s *Server) updateMonitorAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return
+	}
+	data["id"] = float64(m.ID)
+	s.writeMonitorSaveAPI(c, s.editMonitor(data, apiScope(c)), http.StatusOK)
+}
+
+// deleteMonitorAPI 处理 DELETE /api/v1/monitors/:id：停止检查并删除监控项及其历史数据
+func (s *Server) deleteMonitorAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	if err := s.removeMonitor(m.ID); err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to delete monitor: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, apiDeleted{ID: m.ID, Deleted: true})
+}
+
+// setMonitorActiveAPI 处理 PATCH /api/v1/monitors/:id/active：{"active": false} 暂停，{"active": true} 恢复
+func (s *Server) setMonitorActiveAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	active, ok := bindActive(c)
+	if !ok {
+		return
+	}
+	value := 0
+	if active {
+		value = 1
+	}
+	if err := s.setMonitorActive(m, value); err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, s.monitorDetail(m, fullAPIAccess(c)))
+}
+
+// monitorHeartbeatsAPI 处理 GET /api/v1/monitors/:id/heartbeats?hours=24：
+// 与 getHeartbeatListWithRange 相同，24 小时内为原始心跳，7 天内为小时聚合，更长为日聚合。
+// 可选 limit（1-1000）、offset 或 cursor（上一页的 next_cursor）分页，status 按状态码或 key 过滤
+func (s *Server) monitorHeartbeatsAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > maxAPIHeartbeatHours {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "hours must be between 1 and "+strconv.Itoa(maxAPIHeartbeatHours), http.StatusBadRequest, err))
+		return
+	}
+	limit, offset := 0, 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeAPIError(c, apperrors.New(http.StatusBadRequest, errLimitRange.Error(), http.StatusBadRequest, err))
+			return
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			writeAPIError(c, apperrors.New(http.StatusBadRequest, "offset must be a number", http.StatusBadRequest, err))
+			return
+		}
+	}
+	q, err := heartbeatQuery(limit, offset, c.Query("cursor"), c.Query("status"))
+	if err != nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, err.Error(), http.StatusBadRequest, err))
+		return
+	}
+	page, err := db.GetHeartbeatsPage(m.ID, hours, q)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, err.Error(), http.StatusBadRequest, err))
+		return
+	} else if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, apiHeartbeatPage{
+		MonitorID:  m.ID,
+		Hours:      hours,
+		DataType:   page.DataType,
+		Heartbeats: page.Data,
+		Total:      page.Total,
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// clearHeartbeatsAPI 处理 DELETE /api/v1/monitors/:id/heartbeats：与 clearEvents 相同，清除原始心跳和聚合数据
+func (s *Server) clearHeartbeatsAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	if err := db.PurgeMonitorHeartbeats(m.ID); err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to clear events: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, apiHeartbeatsCleared{MonitorID: m.ID, Cleared: true})
+}
+
+// monitorStatsAPI 处理 GET /api/v1/monitors/:id/stats：与 getMonitorStats 相同的可用率和平均响应时间
+func (s *Server) monitorStatsAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	stats := s.getMonitorStats(m.ID)
+	stats["monitor_id"] = m.ID
+	c.JSON(http.StatusOK, stats)
+}
+
+// monitorChartAPI 处理 GET /api/v1/monitors/:id/chart?view=24h：与 getChartData 相同，view 为 24h（默认）或 7d
+func (s *Server) monitorChartAPI(c *gin.Context) {
+	m, ok := apiMonitor(c)
+	if !ok {
+		return
+	}
+	view := c.DefaultQuery("view", "24h")
+	if view != "24h" && view != "7d" {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "view must be 24h or 7d", http.StatusBadRequest, nil))
+		return
+	}
+	c.JSON(http.StatusOK, apiChart{MonitorID: m.ID, View: view, Data: db.GetChartData(m.ID, view)})
+}
+
+// exportMonitorsAPI 处理 GET /api/v1/monitors/export?include_secrets=true&include_history=true：
+// 与 exportMonitorConfig 相同（监控项、通知规则和对应关系），结果可直接用于导入
+func (s *Server) exportMonitorsAPI(c *gin.Context) {
+	includeSecrets, _ := strconv.ParseBool(c.Query("include_secrets"))
+	includeHistory, _ := strconv.ParseBool(c.Query("include_history"))
+	exported, err := exportMonitorFile(includeSecrets, includeHistory)
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to fetch monitors"))
+		return
+	}
+	c.JSON(http.StatusOK, exported)
+}
+
+// importMonitorsAPI 处理 POST /api/v1/monitors/import：请求体为导出的对象，或只包含监控项的数组。
+// 与 importMonitorConfig 不同，导入完成后才返回结果（监控项和通知规则各自导入、跳过和失败的数量及明细）
+func (s *Server) importMonitorsAPI(c *gin.Context) {
+	data, err := c.GetRawData()
+	var in monitorExport
+	if err == nil {
+		in, err = decodeMonitorImport(data)
+	}
+	if err != nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Invalid JSON format", http.StatusBadRequest, err))
+		return
+	}
+	if len(in.Monitors) == 0 && len(in.Notifications) == 0 {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "No monitors to import", http.StatusBadRequest, nil))
+		return
+	}
+	job := newImportJob(in)
+	s.runImportJob(nil, job, in)
+	c.JSON(http.StatusOK, job)
+}
+
+// apiNotification 解析路径中的通知规则 ID，不存在时直接输出 404
+func apiNotification(c *gin.Context) (*model.Notification, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	var n model.Notification
+	if err == nil {
+		err = db.DB.Where("id = ?", id).Limit(1).Find(&n).Error
+	}
+	if err != nil || n.ID == 0 {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Notification not found", http.StatusNotFound, err))
+		return nil, false
+	}
+	return &n, true
+}
+
+// writeNotificationSaveAPI 输出 addNotificationRule/editNotificationRule 的结果：成功时返回保存后的通知规则
+func writeNotificationSaveAPI(c *gin.Context, id uint, reply map[string]any, okStatus int) {
+	if ok, _ := reply["ok"].(bool); !ok {
+		writeReplyError(c, reply)
+		return
+	}
+	var n model.Notification
+	if err := db.DB.First(&n, id).Error; err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to load notification"))
+		return
+	}
+	body := notificationDetail(&n)
+	body["sender"] = reply["sender"]
+	c.JSON(okStatus, body)
+}
+
+// listNotificationsAPI 处理 GET /api/v1/notifications：全部通知规则，配置字段展开到顶层，令牌和密码只返回 <key>_set
+func (s *Server) listNotificationsAPI(c *gin.Context) {
+	var notifications []model.Notification
+	if err := db.DB.Order("id").Find(&notifications).Error; err != nil {
+		writeAPIError(c, apperrors.Wrap(err, "Failed to fetch notifications"))
+		return
+	}
+	list := make([]map[string]any, len(notifications))
+	for i := range notifications {
+		list[i] = notificationDetail(&notifications[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": list})
+}
+
+// getNotificationAPI 处理 GET /api/v1/notifications/:id
+func (s *Server) getNotificationAPI(c *gin.Context) {
+	n, ok := apiNotification(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, notificationDetail(n))
+}
+
+// createNotificationAPI 处理 POST /api/v1/notifications：请求体与 addNotification 相同
+func (s *Server) createNotificationAPI(c *gin.Context) {
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return
+	}
+	reply := s.addNotificationRule(data)
+	id, _ := reply["id"].(uint)
+	writeNotificationSaveAPI(c, id, reply, http.StatusCreated)
+}
+
+// updateNotificationAPI 处理 PUT /api/v1/notifications/:id：请求体与 editNotification 相同，必须包含读取时的 version
+func (s *Server) updateNotificationAPI(c *gin.Context) {
+	n, ok := apiNotification(c)
+	if !ok {
+		return
+	}
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return
+	}
+	data["id"] = float64(n.ID)
+	writeNotificationSaveAPI(c, n.ID, s.editNotificationRule(data), http.StatusOK)
+}
+
+// deleteNotificationAPI 处理 DELETE /api/v1/notifications/:id：删除通知规则及其自定义模板
+func (s *Server) deleteNotificationAPI(c *gin.Context) {
+	n, ok := apiNotification(c)
+	if !ok {
+		return
+	}
+	s.deleteNotificationRule(n.ID)
+	c.JSON(http.StatusOK, apiDeleted{ID: n.ID, Deleted: true})
+}
+
+// setNotificationActiveAPI 处理 PATCH /api/v1/notifications/:id/active：{"active": true|false}
+func (s *Server) setNotificationActiveAPI(c *gin.Context) {
+	n, ok := apiNotification(c)
+	if !ok {
+		return
+	}
+	active, ok := bindActive(c)
+	if !ok {
+		return
+	}
+	s.setNotificationActive(n, active)
+	c.JSON(http.StatusOK, notificationDetail(n))
+}
+
+// getSettingsAPI 处理 GET /api/v1/settings：与 getSettings 相同，settingVersions 为各设置项的版本号
+func (s *Server) getSettingsAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, settingsSnapshot())
+}
+
+// updateSettingsAPI 处理 PATCH /api/v1/settings：只修改请求体中的设置项，格式与 setSettings 相同
+// （_versions 回传 settingVersions 中对应的版本，新增的 key 为 0），成功时返回全部设置
+func (s *Server) updateSettingsAPI(c *gin.Context) {
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return
+	}
+	if reply := saveSettings(data); reply["ok"] != true {
+		writeReplyError(c, reply)
+		return
+	}
+	c.JSON(http.StatusOK, settingsSnapshot())
+}
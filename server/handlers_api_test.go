@@ -0,0 +1,298 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"ping-go/db"
+	"ping-go/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// assertAPIError 失败响应必须是 AppError：{"code": <HTTP 状态码>, "message": ...}
+func assertAPIError(t *testing.T, code int, body []byte, want int, wantMessage string) map[string]any {
+	t.Helper()
+	if code != want {
+		t.Fatalf("got %d %s, want %d", code, body, want)
+	}
+	var e map[string]any
+	decodeJSON(t, body, &e)
+	msg, _ := e["message"].(string)
+	if e["code"] != float64(want) || msg == "" || !strings.Contains(msg, wantMessage) {
+		t.Fatalf("error body = %s, want an AppError with code %d and message %q", body, want, wantMessage)
+	}
+	return e
+}
+
+// apiJSON 发送请求并要求返回 want，解析响应体
+func apiJSON(t *testing.T, ts *httptest.Server, method, path, key, body string, want int) map[string]any {
+	t.Helper()
+	code, data := doAPI(t, ts, method, path, key, body)
+	if code != want {
+		t.Fatalf("%s %s = %d %s, want %d", method, path, code, data, want)
+	}
+	var out map[string]any
+	decodeJSON(t, data, &out)
+	return out
+}
+
+func TestMonitorActiveAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+	key := createTestAPIKey(t, model.APIKeyScopeAdmin, "")
+	m := createTestMonitor(t, model.Monitor{Name: "api"})
+	path := fmt.Sprintf("/api/v1/monitors/%d/active", m.ID)
+
+	for _, tt := range []struct {
+		body string
+		want float64
+	}{
+		{`{"active":false}`, 0},
+		{`{"active":true}`, 1},
+		{`{"active":0}`, 0},
+	} {
+		out := apiJSON(t, ts, http.MethodPatch, path, key, tt.body, http.StatusOK)
+		if out["active"] != tt.want || out["id"] != float64(m.ID) {
+			t.Fatalf("%s: monitor = %v, want active %v", tt.body, out, tt.want)
+		}
+		var stored model.Monitor
+		db.DB.First(&stored, m.ID)
+		if float64(stored.Active) != tt.want {
+			t.Fatalf("%s: stored active = %d", tt.body, stored.Active)
+		}
+	}
+
+	code, body := doAPI(t, ts, http.MethodPatch, path, key, `{"active":"yes"}`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "active must be true or false")
+	code, body = doAPI(t, ts, http.MethodPatch, path, key, `{"active":`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "")
+	code, body = doAPI(t, ts, http.MethodPatch, "/api/v1/monitors/9999/active", key, `{"active":true}`)
+	assertAPIError(t, code, body, http.StatusNotFound, "Monitor not found")
+}
+
+func TestMonitorHeartbeatsAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+	key := createTestAPIKey(t, model.APIKeyScopeAdmin, "")
+	m := createTestMonitor(t, model.Monitor{Name: "api"})
+	createTestHeartbeats(t, m.ID, 5)
+	base := fmt.Sprintf("/api/v1/monitors/%d", m.ID)
+
+	out := apiJSON(t, ts, http.MethodGet, base+"/heartbeats?hours=1", key, "", http.StatusOK)
+	if rows, _ := out["heartbeats"].([]any); len(rows) != 5 || out["total"] != float64(5) || out["hours"] != float64(1) || out["monitor_id"] != float64(m.ID) {
+		t.Fatalf("heartbeats = %v, want 5 rows", out)
+	}
+
+	// 按 cursor 翻页，两页合起来是全部心跳
+	out = apiJSON(t, ts, http.MethodGet, base+"/heartbeats?hours=1&limit=3", key, "", http.StatusOK)
+	cursor, _ := out["next_cursor"].(string)
+	if rows, _ := out["heartbeats"].([]any); len(rows) != 3 || out["has_more"] != true || cursor == "" {
+		t.Fatalf("first page = %v", out)
+	}
+	out = apiJSON(t, ts, http.MethodGet, base+"/heartbeats?hours=1&limit=3&cursor="+cursor, key, "", http.StatusOK)
+	if rows, _ := out["heartbeats"].([]any); len(rows) != 2 || out["has_more"] != false {
+		t.Fatalf("second page = %v", out)
+	}
+
+	for _, q := range []string{"hours=0", "hours=99999", "hours=abc", "limit=0", "cursor=bogus"} {
+		code, body := doAPI(t, ts, http.MethodGet, base+"/heartbeats?"+q, key, "")
+		assertAPIError(t, code, body, http.StatusBadRequest, "")
+	}
+
+	out = apiJSON(t, ts, http.MethodGet, base+"/stats", key, "", http.StatusOK)
+	if out["monitor_id"] != float64(m.ID) {
+		t.Fatalf("stats = %v", out)
+	}
+	for _, view := range []string{"", "?view=24h", "?view=7d"} {
+		out = apiJSON(t, ts, http.MethodGet, base+"/chart"+view, key, "", http.StatusOK)
+		if out["monitor_id"] != float64(m.ID) || out["view"] == "" {
+			t.Fatalf("chart%s = %v", view, out)
+		}
+	}
+	code, body := doAPI(t, ts, http.MethodGet, base+"/chart?view=1y", key, "")
+	assertAPIError(t, code, body, http.StatusBadRequest, "view must be 24h or 7d")
+
+	out = apiJSON(t, ts, http.MethodDelete, base+"/heartbeats", key, "", http.StatusOK)
+	if out["cleared"] != true || out["monitor_id"] != float64(m.ID) {
+		t.Fatalf("clear = %v", out)
+	}
+	if n := countHeartbeats(t, m.ID); n != 0 {
+		t.Fatalf("%d heartbeats left after DELETE", n)
+	}
+	code, body = doAPI(t, ts, http.MethodDelete, "/api/v1/monitors/9999/heartbeats", key, "")
+	assertAPIError(t, code, body, http.StatusNotFound, "Monitor not found")
+}
+
+// 导出的文件可以原样导入：删除监控项后导入会重新创建
+func TestImportExportAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+	key := createTestAPIKey(t, model.APIKeyScopeAdmin, "")
+	m := createTestMonitor(t, model.Monitor{Name: "api", URL: "https://api.example.com"})
+
+	code, exported := doAPI(t, ts, http.MethodGet, "/api/v1/monitors/export?include_secrets=true", key, "")
+	if code != http.StatusOK || !strings.Contains(string(exported), `"api"`) {
+		t.Fatalf("export = %d %s", code, exported)
+	}
+	apiJSON(t, ts, http.MethodDelete, fmt.Sprintf("/api/v1/monitors/%d", m.ID), key, "", http.StatusOK)
+
+	job := apiJSON(t, ts, http.MethodPost, "/api/v1/monitors/import", key, string(exported), http.StatusOK)
+	if job["imported"] != float64(1) || job["failed"] != float64(0) || job["done"] != true {
+		t.Fatalf("import = %v", job)
+	}
+	var imported model.Monitor
+	if err := db.DB.Where("name = ?", "api").First(&imported).Error; err != nil || imported.URL != m.URL {
+		t.Fatalf("imported monitor = %+v, %v", imported, err)
+	}
+	// 再次导入时同名监控项被跳过
+	job = apiJSON(t, ts, http.MethodPost, "/api/v1/monitors/import", key, string(exported), http.StatusOK)
+	if job["imported"] != float64(0) || job["skipped"] != float64(1) {
+		t.Fatalf("second import = %v", job)
+	}
+
+	code, body := doAPI(t, ts, http.MethodPost, "/api/v1/monitors/import", key, `{"monitors":`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "Invalid JSON format")
+	code, body = doAPI(t, ts, http.MethodPost, "/api/v1/monitors/import", key, `[]`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "No monitors to import")
+}
+
+func TestNotificationsAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+	key := createTestAPIKey(t, model.APIKeyScopeAdmin, "")
+
+	created := apiJSON(t, ts, http.MethodPost, "/api/v1/notifications", key,
+		`{"name":"ops","type":"trigger","channel":"ntfy","ntfy_topic":"alerts","ntfy_token":"tk_secret"}`, http.StatusCreated)
+	id, _ := created["id"].(float64)
+	if id == 0 || created["name"] != "ops" || created["version"] == nil {
+		t.Fatalf("created = %v", created)
+	}
+	path := fmt.Sprintf("/api/v1/notifications/%d", int(id))
+
+	// 令牌只返回 ntfy_token_set
+	for _, p := range []string{path, "/api/v1/notifications"} {
+		code, body := doAPI(t, ts, http.MethodGet, p, key, "")
+		if code != http.StatusOK || strings.Contains(string(body), "tk_secret") || !strings.Contains(string(body), `"ntfy_token_set":true`) {
+			t.Fatalf("GET %s = %d %s, want the token redacted", p, code, body)
+		}
+	}
+
+	code, body := doAPI(t, ts, http.MethodPut, path, key, `{"name":"ops-2","type":"trigger","channel":"ntfy","ntfy_topic":"alerts"}`)
+	assertAPIError(t, code, body, http.StatusPreconditionRequired, "")
+	updated := apiJSON(t, ts, http.MethodPut, path, key,
+		fmt.Sprintf(`{"name":"ops-2","type":"trigger","channel":"ntfy","ntfy_topic":"alerts","version":%v}`, created["version"]), http.StatusOK)
+	if updated["name"] != "ops-2" || updated["version"] == created["version"] {
+		t.Fatalf("updated = %v", updated)
+	}
+	// 用旧版本保存：409，返回当前内容
+	code, body = doAPI(t, ts, http.MethodPut, path, key,
+		fmt.Sprintf(`{"name":"ops-3","type":"trigger","channel":"ntfy","ntfy_topic":"alerts","version":%v}`, created["version"]))
+	if e := assertAPIError(t, code, body, http.StatusConflict, ""); e["current"] == nil || e["changed_fields"] != nil {
+		t.Fatalf("conflict body = %s, want current without changed_fields", body)
+	}
+	// 回传读取时的内容：只报告他人改过的 name，不报告本次自己改的 ntfy_topic
+	code, body = doAPI(t, ts, http.MethodPut, path, key,
+		fmt.Sprintf(`{"name":"ops-3","type":"trigger","channel":"ntfy","ntfy_topic":"other","version":%v,`+
+			`"_original":{"name":"ops","type":"trigger","channel":"ntfy","ntfy_topic":"alerts"}}`, created["version"]))
+	if e := assertAPIError(t, code, body, http.StatusConflict, ""); fmt.Sprint(e["changed_fields"]) != "[name]" {
+		t.Fatalf("conflict body = %s, want changed_fields [name]", body)
+	}
+
+	out := apiJSON(t, ts, http.MethodPatch, path+"/active", key, `{"active":false}`, http.StatusOK)
+	if out["active"] != false {
+		t.Fatalf("after disabling = %v", out)
+	}
+	code, body = doAPI(t, ts, http.MethodPatch, path+"/active", key, `{"active":"no"}`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "active must be true or false")
+
+	out = apiJSON(t, ts, http.MethodDelete, path, key, "", http.StatusOK)
+	if out["deleted"] != true {
+		t.Fatalf("delete = %v", out)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		code, body = doAPI(t, ts, method, path, key, "")
+		assertAPIError(t, code, body, http.StatusNotFound, "")
+	}
+}
+
+func TestSettingsAPI(t *testing.T) {
+	_, ts := newTestServer(t)
+	key := createTestAPIKey(t, model.APIKeyScopeAdmin, "")
+
+	settings := apiJSON(t, ts, http.MethodGet, "/api/v1/settings", key, "", http.StatusOK)
+	versions, _ := settings["settingVersions"].(map[string]any)
+	if versions == nil || settings[db.SiteNameSettingKey] == nil {
+		t.Fatalf("settings = %v", settings)
+	}
+	base, _ := versions[db.SiteNameSettingKey].(float64)
+
+	patch := fmt.Sprintf(`{"siteName":"Acme Status","_versions":{"siteName":%v}}`, base)
+	settings = apiJSON(t, ts, http.MethodPatch, "/api/v1/settings", key, patch, http.StatusOK)
+	versions, _ = settings["settingVersions"].(map[string]any)
+	if settings[db.SiteNameSettingKey] != "Acme Status" || versions[db.SiteNameSettingKey] == base {
+		t.Fatalf("after PATCH = %v", settings)
+	}
+
+	// 再用旧版本保存：409，返回当前值和版本，设置不变
+	code, body := doAPI(t, ts, http.MethodPatch, "/api/v1/settings", key, strings.Replace(patch, "Acme Status", "Other", 1))
+	if e := assertAPIError(t, code, body, http.StatusConflict, ""); e["versions"] == nil || e["current"] == nil {
+		t.Fatalf("conflict body = %s, want current and versions", body)
+	}
+	if v := db.SiteName(); v != "Acme Status" {
+		t.Fatalf("siteName = %q after conflict", v)
+	}
+
+	code, body = doAPI(t, ts, http.MethodPatch, "/api/v1/settings", key, `{"emailTemplate.down":"x"}`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "setNotificationTemplate")
+	code, body = doAPI(t, ts, http.MethodPatch, "/api/v1/settings", key, `[1]`)
+	assertAPIError(t, code, body, http.StatusBadRequest, "")
+}
+
+// 诊断输出可能包含内网地址，只返回给不限范围的管理员（admin 密钥、admin 会话或 admin Socket 连接）
+func TestMonitorDiagnosticsAdminOnly(t *testing.T) {
+	_, ts := newTestServer(t)
+	const trace = "traceroute to 10.20.30.40"
+	m := createTestMonitor(t, model.Monitor{Name: "db", Type: "ping", URL: "10.20.30.40", RunDiagnostics: true})
+	db.DB.Model(&model.Monitor{}).Where("id = ?", m.ID).Updates(map[string]any{"diagnostics": trace, "diagnostics_at": time.Now()})
+	path := fmt.Sprintf("/api/v1/monitors/%d", m.ID)
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"read key", createTestAPIKey(t, model.APIKeyScopeRead, ""), false},
+		{"write key", createTestAPIKey(t, model.APIKeyScopeWrite, ""), false},
+		{"scoped admin key", createTestAPIKey(t, model.APIKeyScopeAdmin, "prod"), false},
+		{"admin key", createTestAPIKey(t, model.APIKeyScopeAdmin, ""), true},
+		{"viewer session", createTestSession(t, "viewer", model.RoleViewer), false},
+		{"admin session", createTestSession(t, "admin", model.RoleAdmin), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, p := range []string{path, "/api/v1/monitors"} {
+				code, body := doAPI(t, ts, http.MethodGet, p, tt.token, "")
+				if code == http.StatusForbidden && !tt.want {
+					continue
+				}
+				if code != http.StatusOK || strings.Contains(string(body), trace) != tt.want {
+					t.Errorf("GET %s = %d %s, want diagnostics %v", p, code, body, tt.want)
+				}
+			}
+		})
+	}
+
+	for _, role := range []string{model.RoleViewer, model.RoleAdmin} {
+		t.Run(role+" socket", func(t *testing.T) {
+			client := dialSocket(t, ts)
+			if reply, err := client.EmitWithAck("auth", map[string]any{"token": createTestSession(t, role+"-socket", role)}); err != nil || reply[0].(map[string]any)["ok"] != true {
+				t.Fatalf("auth = %v, %v", reply, err)
+			}
+			client.Emit("getMonitor", m.ID)
+			ev, err := client.Next("monitor", 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := role == model.RoleAdmin; strings.Contains(ev.Raw, trace) != want {
+				t.Errorf("getMonitor as %s = %s, want diagnostics %v", role, ev.Raw, want)
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@ package server
 
 import (
 	"fmt"
+	"net/http"
 	"ping-go/db"
 	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
 	"ping-go/pkg/logger"
+	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/zishang520/socket.io/socket"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -22,13 +26,17 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 		if len(args) > 0 {
 			ack := args[0].(func([]any, error))
 			ack([]any{map[string]any{
-				"needSetup": count == 0,
+				"needSetup": count == 0 && !demoActive(),
 			}}, nil)
 		}
 	})
 
 	// Handle "setup"
 	client.On("setup", func(args ...any) {
+		if demoActive() {
+			replyDemoMode(client, args)
+			return
+		}
 		if len(args) < 1 {
 			logger.Warn("setup: missing arguments", zap.String("client", string(client.Id())))
 			return
@@ -105,6 +113,14 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 
 	// Handle "login"
 	client.On("login", func(args ...any) {
+		// 演示模式不校验账号密码，直接返回只读的演示会话，避免公开演示站点被暴力破解
+		if demoActive() {
+			authenticateDemoSocket(client)
+			if ack := getCallback(args); ack != nil {
+				ack([]any{map[string]any{"ok": true, "token": demoToken, "demo": true}}, nil)
+			}
+			return
+		}
 		if len(args) < 1 {
 			fmt.Printf("login: missing arguments from %s\n", client.Id())
 			return
@@ -127,26 +143,21 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 		if err == nil {
 			// Compare password
 			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err == nil {
-				// Create persistent session
-				token := generateToken()
-				sess := model.Session{
-					Token:     token,
-					UserID:    user.ID,
-					ExpiresAt: time.Now().Add(24 * time.Hour), // 24h expiration
+				// 开启了两步验证时先不创建会话，等待 login2FA 提交验证码
+				if user.TOTPEnabled {
+					pendingLogins.Store(client.Id(), &pendingLogin{userID: user.ID, expires: time.Now().Add(loginChallengeTTL)})
+					if ack := getCallback(args); ack != nil {
+						ack([]any{map[string]any{"ok": false, "tokenRequired": true, "msg": "Two-factor code required"}}, nil)
+					}
+					return
 				}
-				if err := db.DB.Create(&sess).Error; err != nil {
+
+				token, err := createLoginSession(client, user.ID)
+				if err != nil {
 					client.Emit("error", map[string]any{"msg": "Failed to create session"})
 					return
 				}
 
-				// Mark as authenticated in socket data
-				socketAuth.Store(client.Id(), map[string]any{
-					"authenticated": true,
-					"userID":        user.ID,
-					"token":         token,
-				})
-				client.Join("admin")
-
 				if len(args) > 1 {
 					ack := args[1].(func([]any, error))
 					ack([]any{map[string]any{
@@ -170,6 +181,13 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 
 	// Handle "auth" for token-based session recovery
 	client.On("auth", func(args ...any) {
+		if demoActive() {
+			authenticateDemoSocket(client)
+			if ack := getCallback(args); ack != nil {
+				ack([]any{map[string]any{"ok": true, "demo": true}}, nil)
+			}
+			return
+		}
 		if len(args) < 1 {
 			return
 		}
@@ -182,17 +200,8 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 			return
 		}
 
-		var sess model.Session
-		err := db.DB.First(&sess, "token = ?", token).Error
-		exists := err == nil
-
-		if exists && time.Now().Before(sess.ExpiresAt) {
-			socketAuth.Store(client.Id(), map[string]any{
-				"authenticated": true,
-				"userID":        sess.UserID,
-				"token":         token,
-			})
-			client.Join("admin")
+		if sess, ok := validSession(token); ok {
+			authenticateSocket(client, sess.UserID, token)
 			if len(args) > 1 {
 				ack := args[1].(func([]any, error))
 				ack([]any{map[string]any{
@@ -220,7 +229,9 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 				}
 			}
 		}
+		forgetScopedSocket(client)
 		socketAuth.Delete(client.Id())
+		client.Leave("demo")
 		if len(args) > 0 {
 			ack := args[0].(func([]any, error))
 			ack([]any{map[string]any{
@@ -230,9 +241,59 @@ func (s *Server) setupAuthHandlers(client *socket.Socket) {
 	})
 }
 
-// requireAuth 创建一个需要认证的事件处理器包装器
+// createLoginSession 创建登录会话并将连接标记为已登录，返回会话 token
+func createLoginSession(client *socket.Socket, userID uint) (string, error) {
+	var user model.User
+	db.DB.Select("id", "role").First(&user, userID)
+	token := generateToken()
+	sess := model.Session{
+		Token:     token,
+		UserID:    userID,
+		Role:      user.Role,
+		ExpiresAt: newSessionExpiry(),
+	}
+	if err := db.DB.Create(&sess).Error; err != nil {
+		return "", err
+	}
+	authenticateSocket(client, userID, token)
+	return token, nil
+}
+
+// requireAuth 创建一个需要认证的事件处理器包装器。viewer 角色只能调用 viewerEvents 中的只读事件
 func requireAuth(client *socket.Socket, eventName string, handler func(args ...any)) {
+	role := model.RoleAdmin
+	if viewerEvents[eventName] {
+		role = model.RoleViewer
+	}
+	requireRole(client, role, eventName, handler)
+}
+
+// requireRole 与 requireAuth 相同，并要求账号的角色满足 role：role 为 admin 时 viewer 账号调用返回 403
+func requireRole(client *socket.Socket, role string, eventName string, handler func(args ...any)) {
+	// allowed 检查限定标签范围的账号和 viewer 角色能否调用该事件
+	allowed := func(args []any) bool {
+		if socketScope(client) != nil && !scopedEvents[eventName] {
+			replyForbidden(client, args)
+			return false
+		}
+		if role == model.RoleAdmin && socketRole(client) == model.RoleViewer {
+			replyForbidden(client, args)
+			return false
+		}
+		return true
+	}
 	client.On(eventName, func(args ...any) {
+		// 演示模式：不论是否登录，只读事件以外一律拒绝；只读事件允许匿名调用
+		expireDemoAuth(client)
+		if demoActive() {
+			if !demoReadEvents[eventName] {
+				replyDemoMode(client, args)
+				return
+			}
+			handler(args...)
+			return
+		}
+
 		var authed bool
 		if val, ok := socketAuth.Load(client.Id()); ok {
 			if data, ok := val.(map[string]any); ok {
@@ -247,18 +308,12 @@ func requireAuth(client *socket.Socket, eventName string, handler func(args ...a
 			if len(args) > 0 {
 				if data, ok := args[0].(map[string]any); ok {
 					if token, ok := data["token"].(string); ok {
-						var sess model.Session
-						if err := db.DB.First(&sess, "token = ?", token).Error; err == nil {
-							if time.Now().Before(sess.ExpiresAt) {
-								socketAuth.Store(client.Id(), map[string]any{
-									"authenticated": true,
-									"userID":        sess.UserID,
-									"token":         token,
-								})
-								client.Join("admin")
+						if sess, ok := validSession(token); ok {
+							authenticateSocket(client, sess.UserID, token)
+							if allowed(args) {
 								handler(args...)
-								return
 							}
+							return
 						}
 					}
 				}
@@ -270,6 +325,71 @@ func requireAuth(client *socket.Socket, eventName string, handler func(args ...a
 			})
 			return
 		}
-		handler(args...)
+
+		// 滑动过期：已登录的连接每次调用事件都会延长会话有效期（写数据库有节流）
+		touchSession(socketToken(client))
+
+		// 限定标签范围的账号只能调用监控项相关的事件，viewer 角色只能调用只读事件
+		if allowed(args) {
+			handler(args...)
+		}
 	})
 }
+
+// requireAPIAuth REST API 认证中间件
+// 通过 "Authorization: Bearer <token>" 请求头传递登录时返回的会话 token 或 pgk_ 开头的 API 密钥；
+// 账号或密钥限定了标签范围时，范围保存在 "tagScope" 中，由各接口据此过滤。认证失败时返回 AppError 格式的 401。
+// read 权限的密钥和 viewer 角色的会话只能发送 GET / HEAD 请求，其他请求返回 403；admin 权限由 requireFullAPIAccess 检查
+func requireAPIAuth() gin.HandlerFunc {
+	unauthorized := func(c *gin.Context) { writeAPIError(c, apperrors.ErrUnauthorized) }
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if token == "" {
+			unauthorized(c)
+			return
+		}
+
+		if strings.HasPrefix(token, apiKeyPrefix) {
+			var key model.APIKey
+			now := time.Now()
+			if err := db.DB.Where("key_hash = ?", hashAPIKey(token)).Limit(1).Find(&key).Error; err != nil || key.ID == 0 || !key.Usable(now) {
+				unauthorized(c)
+				return
+			}
+			if !key.Allows(model.APIKeyScopeWrite) && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				writeAPIError(c, errAPIKeyReadOnly)
+				return
+			}
+			db.DB.Model(&key).Update("last_used_at", now)
+			c.Set("apiKeyID", key.ID)
+			c.Set("apiKey", &key)
+			c.Set("tagScope", model.SplitTags(key.TagScope))
+			c.Next()
+			return
+		}
+
+		sess, ok := validSession(token)
+		if !ok {
+			unauthorized(c)
+			return
+		}
+		// viewer 角色的会话只能读取
+		if sess.Role == model.RoleViewer && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			writeAPIError(c, errViewerReadOnly)
+			return
+		}
+		var user model.User
+		db.DB.Select("id", "tag_scope").First(&user, sess.UserID)
+		c.Set("userID", sess.UserID)
+		c.Set("role", sess.Role)
+		c.Set("tagScope", model.SplitTags(user.TagScope))
+		c.Next()
+	}
+}
+
+// apiScope 返回 REST 请求的标签范围，nil 表示不限制
+func apiScope(c *gin.Context) []string {
+	scope, _ := c.Get("tagScope")
+	tags, _ := scope.([]string)
+	return tags
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// badgeCacheTTL 徽章数据的缓存时间，也是 Cache-Control 的 max-age
+const badgeCacheTTL = 30 * time.Second
+
+// maxBadgeLabelRunes label 查询参数的长度限制
+const maxBadgeLabelRunes = 64
+
+// badgeColors shields.io 风格的命名颜色，color 查询参数也可以是 3 或 6 位十六进制
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"gray":        "#555",
+	"lightgrey":   "#9f9f9f",
+	"lightgray":   "#9f9f9f",
+}
+
+var badgeHexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// badgeDurations uptime 徽章支持的统计时长
+var badgeDurations = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// badgeStatus 状态徽章的文字和颜色
+var badgeStatus = map[int][2]string{
+	model.StatusUp:          {"up", "brightgreen"},
+	model.StatusDown:        {"down", "red"},
+	model.StatusPending:     {"pending", "yellow"},
+	model.StatusMaintenance: {"maintenance", "blue"},
+}
+
+// badgeMonitor 徽章使用的监控数据快照
+type badgeMonitor struct {
+	Name    string
+	Status  int
+	Active  int
+	Uptime  map[string]float64 // 按 badgeDurations 的 key 懒加载
+	expires time.Time
+}
+
+var (
+	badgeMu    sync.Mutex
+	badgeCache = make(map[uint]*badgeMonitor)
+)
+
+// loadBadgeMonitor 返回启用了徽章的监控项，缓存 badgeCacheTTL；监控项不存在、未启用徽章或对未登录客户端隐藏时返回 false
+func loadBadgeMonitor(monitorID uint) (*badgeMonitor, bool) {
+	if !publicVisible(monitorID) {
+		return nil, false
+	}
+	now := time.Now()
+	badgeMu.Lock()
+	defer badgeMu.Unlock()
+	if b, ok := badgeCache[monitorID]; ok && now.Before(b.expires) {
+		return b, true
+	}
+
+	var m model.Monitor
+	err := db.DB.Select("id", "name", "status", "active", "badge").Where("id = ?", monitorID).Limit(1).Find(&m).Error
+	if err != nil || m.ID == 0 || !m.Badge {
+		return nil, false
+	}
+	for k, v := range badgeCache {
+		if now.After(v.expires) {
+			delete(badgeCache, k)
+		}
+	}
+	b := &badgeMonitor{Name: m.Name, Status: m.Status, Active: m.Active, Uptime: map[string]float64{}, expires: now.Add(badgeCacheTTL)}
+	badgeCache[monitorID] = b
+	return b, true
+}
+
+// badgeUptime 返回缓存的可用率，未缓存时通过 GetUptimeStats 计算
+func badgeUptime(monitorID uint, b *badgeMonitor, key string) float64 {
+	badgeMu.Lock()
+	v, ok := b.Uptime[key]
+	badgeMu.Unlock()
+	if ok {
+		return v
+	}
+	v = db.GetUptimeStats(monitorID, badgeDurations[key])
+	badgeMu.Lock()
+	b.Uptime[key] = v
+	badgeMu.Unlock()
+	return v
+}
+
+// uptimeBadgeColor 可用率对应的颜色
+func uptimeBadgeColor(uptime float64) string {
+	switch {
+	case uptime >= 99.9:
+		return "brightgreen"
+	case uptime >= 99:
+		return "green"
+	case uptime >= 95:
+		return "yellow"
+	case uptime >= 90:
+		return "orange"
+	default:
+		return "red"
+	}
+}
+
+// formatBadgeUptime 可用率保留最多两位小数，去掉末尾的 0（如 100%、99.5%）
+func formatBadgeUptime(uptime float64) string {
+	s := strconv.FormatFloat(uptime, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + "%"
+}
+
+// badgeColor 解析 color 查询参数，无效时使用默认颜色
+func badgeColor(param, fallback string) string {
+	if c, ok := badgeColors[strings.ToLower(param)]; ok {
+		return c
+	}
+	if badgeHexColor.MatchString(param) {
+		return "#" + strings.TrimPrefix(param, "#")
+	}
+	return badgeColors[fallback]
+}
+
+// badgeLabel 返回 label 查询参数，未设置时使用默认标签
+func badgeLabel(c *gin.Context, fallback string) string {
+	label, ok := c.GetQuery("label")
+	if !ok {
+		return fallback
+	}
+	if utf8.RuneCountInString(label) > maxBadgeLabelRunes {
+		label = string([]rune(label)[:maxBadgeLabelRunes])
+	}
+	return label
+}
+
+// badgeTextWidth 估算 11px Verdana 下文字的宽度，用于计算徽章两段的宽度
+func badgeTextWidth(s string) int {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case r > 0x2E80:
+			width += 11
+		case strings.ContainsRune("fijlrt.,:;!|'()[] ", r):
+			width += 4
+		case strings.ContainsRune("mwMW%@", r):
+			width += 10
+		case r >= 'A' && r <= 'Z':
+			width += 7.5
+		default:
+			width += 6.5
+		}
+	}
+	return int(width + 0.5)
+}
+
+// renderBadge 输出 shields.io flat 风格的 SVG 徽章；label 为空时只有右半部分
+func renderBadge(label, value, color string) string {
+	lw := 0
+	if label != "" {
+		lw = badgeTextWidth(label) + 10
+	}
+	vw := badgeTextWidth(value) + 10
+	total := lw + vw
+	title := value
+	if label != "" {
+		title = label + ": " + value
+	}
+	label, value, title = html.EscapeString(label), html.EscapeString(value), html.EscapeString(title)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s">`, total, title)
+	fmt.Fprintf(&b, `<title>%s</title>`, title)
+	b.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, total)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		lw, lw, vw, color, total)
+	b.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	if label != "" {
+		fmt.Fprintf(&b, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, lw/2, label, lw/2, label)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`, lw+vw/2, value, lw+vw/2, value)
+	b.WriteString(`</g></svg>`)
+	return b.String()
+}
+
+// writeBadge 输出 SVG 徽章。徽章总是返回 200，避免嵌入 README 的图片因监控项删除或关闭徽章而显示为损坏
+func writeBadge(c *gin.Context, label, value, color string) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(badgeCacheTTL.Seconds())))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderBadge(label, value, color)))
+}
+
+// badgeTarget 解析路径中的监控项 ID 并加载数据；不存在或未启用徽章时直接输出 "not found" 徽章
+func badgeTarget(c *gin.Context, label string) (uint, *badgeMonitor, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err == nil {
+		if b, ok := loadBadgeMonitor(uint(id)); ok {
+			return uint(id), b, true
+		}
+	}
+	writeBadge(c, badgeLabel(c, label), "not found", badgeColors["lightgrey"])
+	return 0, nil, false
+}
+
+// statusBadge 处理 GET /badge/:id/status.svg：当前状态，暂停的监控项为 paused
+// 查询参数：label=左侧文字（默认为监控项名称，为空时不显示），color=右侧颜色（命名颜色或十六进制）
+func (s *Server) statusBadge(c *gin.Context) {
+	_, b, ok := badgeTarget(c, "status")
+	if !ok {
+		return
+	}
+	value, color := "unknown", "lightgrey"
+	if b.Active == 0 {
+		value = "paused"
+	} else if st, ok := badgeStatus[b.Status]; ok {
+		value, color = st[0], st[1]
+	}
+	writeBadge(c, badgeLabel(c, b.Name), value, badgeColor(c.Query("color"), color))
+}
+
+// uptimeBadge 处理 GET /badge/:id/uptime/:duration.svg：最近 24h、7d 或 30d 的可用率
+// 查询参数同 statusBadge，label 默认为 "uptime 24h" 等
+func (s *Server) uptimeBadge(c *gin.Context) {
+	key := strings.TrimSuffix(c.Param("duration"), ".svg")
+	if _, ok := badgeDurations[key]; !ok {
+		writeBadge(c, badgeLabel(c, "uptime"), "invalid duration", badgeColors["lightgrey"])
+		return
+	}
+	id, b, ok := badgeTarget(c, "uptime "+key)
+	if !ok {
+		return
+	}
+	uptime := badgeUptime(id, b, key)
+	writeBadge(c, badgeLabel(c, "uptime "+key), formatBadgeUptime(uptime), badgeColor(c.Query("color"), uptimeBadgeColor(uptime)))
+}
@@ -0,0 +1,326 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"ping-go/pkg/logger"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 批量操作
+const (
+	bulkPause              = "pause"
+	bulkResume             = "resume"
+	bulkDelete             = "delete"
+	bulkSetInterval        = "set_interval"
+	bulkAssignTag          = "assign_tag"
+	bulkAssignNotification = "assign_notification"
+
+	// maxBulkMonitors 单次批量操作的监控项数量上限
+	maxBulkMonitors = 1000
+
+	auditActionMonitorBulk = "monitor.bulk"
+)
+
+// bulkRequest 批量操作的参数：interval 用于 set_interval，tag 用于 assign_tag，notification_id 用于 assign_notification
+type bulkRequest struct {
+	Action         string `json:"action"`
+	MonitorIDs     []uint `json:"monitor_ids"`
+	Interval       int    `json:"interval,omitempty"`
+	Tag            string `json:"tag,omitempty"`
+	NotificationID uint   `json:"notification_id,omitempty"`
+}
+
+// bulkResult 单个监控项的处理结果
+type bulkResult struct {
+	ID  uint   `json:"id"`
+	OK  bool   `json:"ok"`
+	Msg string `json:"msg,omitempty"`
+}
+
+// bulkReport 批量操作的结果，results 与请求中的 monitor_ids 顺序一致
+type bulkReport struct {
+	Action    string       `json:"action"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []bulkResult `json:"results"`
+}
+
+// bulkRequestFromMap 解析 Socket 事件的参数
+func bulkRequestFromMap(data map[string]any) bulkRequest {
+	req := bulkRequest{Action: safeMapGetString(data, "action"), Tag: safeMapGetString(data, "tag")}
+	if list, ok := data["monitor_ids"].([]any); ok {
+		for _, v := range list {
+			if f, ok := v.(float64); ok && f > 0 {
+				req.MonitorIDs = append(req.MonitorIDs, uint(f))
+			}
+		}
+	}
+	if v, ok := safeMapGetFloat64(data, "interval"); ok {
+		req.Interval = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "notification_id"); ok && v > 0 {
+		req.NotificationID = uint(v)
+	}
+	return req
+}
+
+// validate 检查请求整体是否有效；单个监控项的问题（不存在、超出范围）在结果中逐个报告
+func (req *bulkRequest) validate(scope []string) *apperrors.AppError {
+	bad := func(msg string) *apperrors.AppError {
+		return apperrors.New(http.StatusBadRequest, msg, http.StatusBadRequest, nil)
+	}
+	if len(req.MonitorIDs) == 0 {
+		return bad("monitor_ids is required")
+	}
+	if len(req.MonitorIDs) > maxBulkMonitors {
+		return bad(fmt.Sprintf("At most %d monitors per request", maxBulkMonitors))
+	}
+	switch req.Action {
+	case bulkPause, bulkResume, bulkDelete:
+	case bulkSetInterval:
+		if req.Interval < 20 {
+			return bad("interval must be at least 20 seconds")
+		}
+	case bulkAssignTag:
+		req.Tag = model.NormalizeTags(req.Tag)
+		if req.Tag == "" || strings.Contains(req.Tag, ",") {
+			return bad("tag must be a single tag")
+		}
+	case bulkAssignNotification:
+		// 通知规则只有不限范围的账号可以管理
+		if scope != nil {
+			return apperrors.ErrForbidden
+		}
+		if req.NotificationID == 0 {
+			return bad("notification_id is required")
+		}
+	default:
+		return bad("action must be one of pause, resume, delete, set_interval, assign_tag, assign_notification")
+	}
+	return nil
+}
+
+// bulkAction 在一个事务中对多个监控项执行同一操作。每个监控项在各自的保存点中处理，
+// 不存在、超出范围或写入失败的监控项回滚到保存点并在结果中报告，不影响其他监控项。
+// 事务提交后再停止、启动调度：暂停和删除的监控项停止，恢复和修改了间隔的监控项错开重新启动；
+// 删除的监控项提交后分批清理心跳和聚合数据（同 deleteMonitor）。
+// assign_notification 为每个监控项复制一条只针对该监控项的触发规则（名称为 "<规则名> (<监控项名>)"），
+// 原规则已覆盖该监控项（monitor_name 为 * 或同名）或副本已存在时视为成功
+func (s *Server) bulkAction(req bulkRequest, scope []string, actor string) (*bulkReport, *apperrors.AppError) {
+	if err := req.validate(scope); err != nil {
+		return nil, err
+	}
+
+	var rule model.Notification
+	var ruleConfig map[string]any
+	if req.Action == bulkAssignNotification {
+		db.DB.Where("id = ?", req.NotificationID).Limit(1).Find(&rule)
+		if rule.ID == 0 {
+			return nil, apperrors.New(http.StatusNotFound, "Notification not found", http.StatusNotFound, nil)
+		}
+		if rule.Type != "trigger" || json.Unmarshal([]byte(rule.Config), &ruleConfig) != nil {
+			return nil, apperrors.New(http.StatusBadRequest, "Only trigger rules can be assigned to monitors", http.StatusBadRequest, nil)
+		}
+	}
+
+	// 删除前先停止调度，避免删除过程中仍有新的检查结果写入
+	if req.Action == bulkDelete {
+		for _, id := range req.MonitorIDs {
+			s.monitorService.StopMonitor(id)
+		}
+	}
+
+	report := &bulkReport{Action: req.Action, Results: make([]bulkResult, len(req.MonitorIDs))}
+	applied := make([]*model.Monitor, len(req.MonitorIDs))
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.MonitorIDs {
+			report.Results[i].ID = id
+			var m model.Monitor
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Where("id = ?", id).Limit(1).Find(&m).Error; err != nil {
+					return err
+				}
+				if m.ID == 0 {
+					return errBulkNotFound
+				}
+				if !m.InScope(scope) {
+					return errBulkForbidden
+				}
+				return applyBulkAction(tx, &m, req, &rule, ruleConfig)
+			})
+			if err != nil {
+				report.Results[i].Msg = err.Error()
+				continue
+			}
+			report.Results[i].OK = true
+			applied[i] = &m
+		}
+		return nil
+	})
+	if err != nil {
+		// 提交失败时所有修改都已回滚，恢复删除前停止的调度
+		if req.Action == bulkDelete {
+			s.restartActive(req.MonitorIDs)
+		}
+		return nil, apperrors.Wrap(err, "Bulk action failed, nothing was changed: "+err.Error())
+	}
+
+	var restart []*model.Monitor
+	var notDeleted []uint
+	for i, m := range applied {
+		if m == nil {
+			report.Failed++
+			notDeleted = append(notDeleted, req.MonitorIDs[i])
+			continue
+		}
+		report.Succeeded++
+		switch req.Action {
+		case bulkPause:
+			s.monitorService.StopMonitor(m.ID)
+			s.monitorService.ResetNotificationStateByMonitor(m.ID)
+		case bulkResume:
+			restart = append(restart, m)
+			s.monitorService.ResetNotificationStateByMonitor(m.ID)
+		case bulkSetInterval:
+			if m.Active == 1 {
+				s.monitorService.StopMonitor(m.ID)
+				restart = append(restart, m)
+			}
+		case bulkDelete:
+			s.monitorService.ResetNotificationStateByMonitor(m.ID)
+			if err := db.PurgeMonitorHeartbeats(m.ID); err != nil {
+				// 监控项已删除，剩余的心跳由 PurgeOrphanedHeartbeats 定期清理
+				logger.Warn("Failed to purge heartbeats of deleted monitor", zap.Uint("monitor_id", m.ID), zap.Error(err))
+			}
+		}
+	}
+	if req.Action == bulkDelete {
+		s.restartActive(notDeleted)
+		clearStatusPageCache()
+	}
+	if len(restart) > 0 {
+		s.monitorService.StartMonitorsStaggered(restart, importStaggerWindow)
+	}
+	if req.Action == bulkAssignNotification && report.Succeeded > 0 {
+		s.broadcastNotificationList()
+	}
+	s.broadcastMonitorList()
+
+	detail := fmt.Sprintf("%s monitors=%v succeeded=%d failed=%d", req.Action, req.MonitorIDs, report.Succeeded, report.Failed)
+	switch req.Action {
+	case bulkSetInterval:
+		detail += fmt.Sprintf(" interval=%d", req.Interval)
+	case bulkAssignTag:
+		detail += " tag=" + req.Tag
+	case bulkAssignNotification:
+		detail += fmt.Sprintf(" notification=%d", req.NotificationID)
+	}
+	db.RecordAudit(actor, auditActionMonitorBulk, "monitors", detail)
+	return report, nil
+}
+
+var (
+	errBulkNotFound  = errors.New("Monitor not found")
+	errBulkForbidden = errors.New("Forbidden")
+)
+
+// applyBulkAction 在保存点中修改单个监控项，m 会更新为修改后的值。
+// 修改配置的操作同时递增版本号，打开着旧版本编辑表单的用户保存时会得到冲突提示
+func applyBulkAction(tx *gorm.DB, m *model.Monitor, req bulkRequest, rule *model.Notification, ruleConfig map[string]any) error {
+	bump := gorm.Expr("version + 1")
+	switch req.Action {
+	case bulkPause, bulkResume:
+		active := 0
+		if req.Action == bulkResume {
+			active = 1
+		}
+		if m.Active == active {
+			return nil
+		}
+		if err := tx.Model(m).Updates(map[string]any{"active": active, "version": bump}).Error; err != nil {
+			return err
+		}
+		m.Active = active
+	case bulkDelete:
+		return db.DeleteMonitorRecords(tx, m.ID)
+	case bulkSetInterval:
+		if err := tx.Model(m).Updates(map[string]any{"interval": req.Interval, "version": bump}).Error; err != nil {
+			return err
+		}
+		m.Interval = req.Interval
+	case bulkAssignTag:
+		tags := model.NormalizeTags(m.Tags + "," + req.Tag)
+		if tags == m.Tags {
+			return nil
+		}
+		if err := tx.Model(m).Updates(map[string]any{"tags": tags, "version": bump}).Error; err != nil {
+			return err
+		}
+		m.Tags = tags
+	case bulkAssignNotification:
+		_, err := assignTriggerRule(tx, rule, ruleConfig, m.Name)
+		return err
+	}
+	return nil
+}
+
+// restartActive 重新启动启用中的监控项（批量删除失败时恢复调度）
+func (s *Server) restartActive(ids []uint) {
+	if len(ids) == 0 {
+		return
+	}
+	var monitors []model.Monitor
+	db.DB.Where("id IN ? AND active = ?", ids, 1).Find(&monitors)
+	for i := range monitors {
+		s.monitorService.StartMonitor(&monitors[i])
+	}
+}
+
+// setupBulkHandlers 设置批量操作的处理器
+func (s *Server) setupBulkHandlers(client *socket.Socket) {
+	// Handle "bulkAction" - args: ({action, monitor_ids, interval, tag, notification_id})
+	// 限定范围的账号只能操作范围内的监控项，不能使用 assign_notification
+	requireAuth(client, "bulkAction", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		report, appErr := s.bulkAction(bulkRequestFromMap(data), socketScope(client), socketActor(client))
+		if appErr != nil {
+			ack([]any{map[string]any{"ok": false, "code": appErr.Code, "msg": appErr.Message}}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "action": report.Action, "succeeded": report.Succeeded,
+			"failed": report.Failed, "results": report.Results}}, nil)
+	})
+}
+
+// bulkMonitorsAPI 处理 POST /api/v1/monitors/bulk：与 bulkAction 相同，返回每个监控项的结果
+func (s *Server) bulkMonitorsAPI(c *gin.Context) {
+	data, ok := bindAPIBody(c)
+	if !ok {
+		return
+	}
+	report, appErr := s.bulkAction(bulkRequestFromMap(data), apiScope(c), apiActor(c))
+	if appErr != nil {
+		writeAPIError(c, appErr)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
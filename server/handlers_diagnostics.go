@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+)
+
+const (
+	// auditActionDebugDump 下载诊断信息和访问 pprof 的审计动作
+	auditActionDebugDump = "debug.dump"
+	// maxHeapProfileBytes 诊断信息中堆内存分配采样的最大长度
+	maxHeapProfileBytes = 64 << 10
+	// maxGoroutineDumpBytes 诊断信息中 goroutine 栈的最大长度
+	maxGoroutineDumpBytes = 8 << 20
+)
+
+// setupDiagnosticsHandlers 设置诊断信息相关的 Socket.IO 事件处理器
+func (s *Server) setupDiagnosticsHandlers(client *socket.Socket) {
+	// Handle "getDiagnosticsDump"
+	// 仅管理员（限定标签范围的账号和演示模式由 requireAuth 拒绝），返回文本格式的诊断信息和建议的文件名
+	requireAuth(client, "getDiagnosticsDump", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		filename, content := s.buildDiagnosticsDump()
+		db.RecordAudit(socketActor(client), auditActionDebugDump, "server", "socket: "+filename)
+		ack([]any{map[string]any{"ok": true, "filename": filename, "content": content}}, nil)
+	})
+}
+
+// requireAdminSession 只允许不限定标签范围的 admin 账号以会话 token 访问，API 密钥一律拒绝。
+// 需要放在 requireAPIAuth 之后
+func requireAdminSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			writeAPIError(c, apperrors.New(http.StatusForbidden, "API keys cannot access this endpoint, use a session token", http.StatusForbidden, nil))
+			return
+		}
+		if apiScope(c) != nil || c.GetString("role") == model.RoleViewer {
+			writeAPIError(c, apperrors.ErrForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireDebugFlag 配置项未开启时返回 404，配置热加载后立即生效
+func requireDebugFlag(enabled func(cfg *config.Config) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled(config.Get()) {
+			writeAPIError(c, apperrors.New(http.StatusNotFound, "Not Found", http.StatusNotFound, nil))
+			return
+		}
+		c.Next()
+	}
+}
+
+// registerDebugRoutes 注册 GET /api/debug/dump（server.debug_dump）和 /debug/pprof/（server.debug_profiling），
+// 两者默认关闭，开启后也只允许管理员会话访问，每次访问记录审计日志
+func (s *Server) registerDebugRoutes() {
+	s.apiRoutes().GET("/api/debug/dump", apiDoc{Summary: "Download a diagnostics dump (admin session only)", Tag: "debug", Produces: "text/plain"},
+		requireDebugFlag(func(cfg *config.Config) bool { return cfg.Server.DebugDump }),
+		rejectInDemo(), requireAPIAuth(), requireAdminSession(), s.debugDumpAPI)
+
+	profiling := s.router.Group("/debug/pprof",
+		requireDebugFlag(func(cfg *config.Config) bool { return cfg.Server.DebugProfiling }),
+		rejectInDemo(), requireAPIAuth(), requireAdminSession(), auditPprof())
+	profiling.GET("/", gin.WrapF(pprof.Index))
+	profiling.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	profiling.GET("/profile", gin.WrapF(pprof.Profile))
+	profiling.GET("/symbol", gin.WrapF(pprof.Symbol))
+	profiling.POST("/symbol", gin.WrapF(pprof.Symbol))
+	profiling.GET("/trace", gin.WrapF(pprof.Trace))
+	profiling.GET("/:name", func(c *gin.Context) {
+		pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// auditPprof 记录每次 pprof 访问
+func auditPprof() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db.RecordAudit(apiActor(c), auditActionDebugDump, "server", "pprof: "+c.Request.URL.RequestURI())
+		c.Next()
+	}
+}
+
+// debugDumpAPI 处理 GET /api/debug/dump：以附件形式下载诊断信息
+func (s *Server) debugDumpAPI(c *gin.Context) {
+	filename, content := s.buildDiagnosticsDump()
+	db.RecordAudit(apiActor(c), auditActionDebugDump, "server", "api: "+filename)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(content))
+}
+
+// buildDiagnosticsDump 生成诊断信息：调度 goroutine 数与应运行数、心跳缓冲区深度、数据库连接池、
+// 内存统计、堆内存分配采样和全部 goroutine 栈。返回建议的文件名和内容
+func (s *Server) buildDiagnosticsDump() (string, string) {
+	now := time.Now()
+	var b bytes.Buffer
+	section := func(title string) { fmt.Fprintf(&b, "\n== %s ==\n", title) }
+
+	fmt.Fprintf(&b, "PingGo diagnostics dump\n")
+	fmt.Fprintf(&b, "generated:   %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&b, "go:          %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "cpus:        %d (GOMAXPROCS %d)\n", runtime.NumCPU(), runtime.GOMAXPROCS(0))
+	fmt.Fprintf(&b, "role:        %s\n", s.monitorService.Role())
+
+	section("Scheduler")
+	running, expected := s.monitorService.SchedulerStats()
+	fmt.Fprintf(&b, "monitor goroutines running:  %d\n", running)
+	fmt.Fprintf(&b, "monitor goroutines expected: %d\n", expected)
+	if running != expected {
+		fmt.Fprintf(&b, "WARNING: %d monitor goroutines running but %d active monitors are scheduled\n", running, expected)
+	}
+	fmt.Fprintf(&b, "total goroutines:            %d\n", runtime.NumGoroutine())
+
+	section("Heartbeat buffer")
+	depth, capacity := db.HeartbeatBufferDepth()
+	fmt.Fprintf(&b, "depth: %d / %d\n", depth, capacity)
+
+	section("SQL connection pool")
+	if sqlDB, err := db.DB.DB(); err != nil {
+		fmt.Fprintf(&b, "unavailable: %v\n", err)
+	} else {
+		st := sqlDB.Stats()
+		fmt.Fprintf(&b, "open connections:     %d (max %d)\n", st.OpenConnections, st.MaxOpenConnections)
+		fmt.Fprintf(&b, "in use / idle:        %d / %d\n", st.InUse, st.Idle)
+		fmt.Fprintf(&b, "wait count:           %d\n", st.WaitCount)
+		fmt.Fprintf(&b, "wait duration:        %s\n", st.WaitDuration)
+		fmt.Fprintf(&b, "max idle closed:      %d\n", st.MaxIdleClosed)
+		fmt.Fprintf(&b, "max idle time closed: %d\n", st.MaxIdleTimeClosed)
+		fmt.Fprintf(&b, "max lifetime closed:  %d\n", st.MaxLifetimeClosed)
+	}
+
+	section("Memory")
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	fmt.Fprintf(&b, "heap alloc:    %s\n", formatBytes(ms.HeapAlloc))
+	fmt.Fprintf(&b, "heap in use:   %s\n", formatBytes(ms.HeapInuse))
+	fmt.Fprintf(&b, "heap idle:     %s (released %s)\n", formatBytes(ms.HeapIdle), formatBytes(ms.HeapReleased))
+	fmt.Fprintf(&b, "heap objects:  %d\n", ms.HeapObjects)
+	fmt.Fprintf(&b, "stack in use:  %s\n", formatBytes(ms.StackInuse))
+	fmt.Fprintf(&b, "sys:           %s\n", formatBytes(ms.Sys))
+	fmt.Fprintf(&b, "total alloc:   %s\n", formatBytes(ms.TotalAlloc))
+	fmt.Fprintf(&b, "gc cycles:     %d (pause total %s)\n", ms.NumGC, time.Duration(ms.PauseTotalNs))
+	if ms.LastGC > 0 {
+		fmt.Fprintf(&b, "last gc:       %s\n", time.Unix(0, int64(ms.LastGC)).Format(time.RFC3339))
+	}
+
+	section("Heap profile")
+	writeProfile(&b, "heap", 1, maxHeapProfileBytes)
+
+	section("Goroutines")
+	writeProfile(&b, "goroutine", 2, maxGoroutineDumpBytes)
+
+	return "pinggo-diagnostics-" + now.Format("20060102-150405") + ".txt", b.String()
+}
+
+// writeProfile 以文本格式写入运行时 profile，超过 limit 时截断
+func writeProfile(b *bytes.Buffer, name string, debug, limit int) {
+	var p bytes.Buffer
+	if err := rpprof.Lookup(name).WriteTo(&p, debug); err != nil {
+		fmt.Fprintf(b, "failed to write %s profile: %v\n", name, err)
+		return
+	}
+	if p.Len() > limit {
+		b.Write(p.Bytes()[:limit])
+		fmt.Fprintf(b, "\n... truncated (%d of %d bytes)\n", limit, p.Len())
+		return
+	}
+	b.Write(p.Bytes())
+}
+
+// formatBytes 以 KiB/MiB 等单位显示字节数
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
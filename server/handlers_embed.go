@@ -0,0 +1,204 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/i18n"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// embedCacheTTL 嵌入页数据的缓存时间，也是页面自动刷新的间隔
+const embedCacheTTL = 60 * time.Second
+
+// 嵌入页显示的天数（每天一根柱子）
+const (
+	defaultEmbedBars = 90
+	maxEmbedBars     = 90
+)
+
+// embedData 嵌入页使用的监控数据快照
+type embedData struct {
+	Name    string
+	Status  model.StatusMeta
+	Uptime  float64
+	Bars    []embedBar
+	expires time.Time
+}
+
+type embedBar struct {
+	Color string
+	Title string
+}
+
+var (
+	embedMu    sync.Mutex
+	embedCache = make(map[string]*embedData)
+)
+
+// loadEmbedData 返回公开监控项的嵌入数据，缓存 embedCacheTTL；监控项不存在、未公开或对未登录客户端隐藏时返回 false。
+// 隐藏在读取缓存之前检查，改为隐藏后立即生效
+func loadEmbedData(monitorID uint, bars int) (*embedData, bool) {
+	if !publicVisible(monitorID) {
+		return nil, false
+	}
+	key := fmt.Sprintf("%d:%d", monitorID, bars)
+	now := time.Now()
+
+	embedMu.Lock()
+	if d, ok := embedCache[key]; ok && now.Before(d.expires) {
+		embedMu.Unlock()
+		return d, true
+	}
+	embedMu.Unlock()
+
+	var m model.Monitor
+	if err := db.DB.Select("id", "name", "status", "active", "public").First(&m, monitorID).Error; err != nil || !m.Public {
+		return nil, false
+	}
+
+	status := m.Status
+	if m.Active == 0 {
+		status = model.StatusMaintenance
+	}
+	d := &embedData{Name: m.Name, Status: db.StatusMeta(status), expires: now.Add(embedCacheTTL)}
+	var up, total int64
+	for _, b := range db.GetDailyUptimeBars(m.ID, bars) {
+		up, total = up+b.Total-b.Down, total+b.Total
+		d.Bars = append(d.Bars, embedBar{Color: embedBarColor(b), Title: embedBarTitle(b)})
+	}
+	d.Uptime = 100
+	if total > 0 {
+		d.Uptime = float64(up) / float64(total) * 100.0
+	}
+
+	embedMu.Lock()
+	for k, v := range embedCache {
+		if now.After(v.expires) {
+			delete(embedCache, k)
+		}
+	}
+	embedCache[key] = d
+	embedMu.Unlock()
+	return d, true
+}
+
+// clearEmbedCache 清空嵌入页缓存，导入历史心跳后调用，避免继续展示导入前的空数据
+func clearEmbedCache() {
+	embedMu.Lock()
+	clear(embedCache)
+	embedMu.Unlock()
+}
+
+// embedBarColor 没有数据为灰色，全部正常为 UP 颜色，可用率不低于 95% 为 PENDING 颜色，否则为 DOWN 颜色
+func embedBarColor(b db.UptimeBar) string {
+	switch {
+	case b.Total == 0:
+		return db.StatusMeta(model.StatusNoData).Color
+	case b.Down == 0:
+		return db.StatusMeta(model.StatusUp).Color
+	case b.Uptime >= 95:
+		return db.StatusMeta(model.StatusPending).Color
+	default:
+		return db.StatusMeta(model.StatusDown).Color
+	}
+}
+
+func embedBarTitle(b db.UptimeBar) string {
+	date := b.Date.Format("2006-01-02")
+	if b.Total == 0 {
+		return date + ": " + db.StatusMeta(model.StatusNoData).Label
+	}
+	return fmt.Sprintf("%s: %.2f%%", date, b.Uptime)
+}
+
+// setEmbedFrameHeaders 只允许同源和 embed.allowed_origins 中的来源通过 iframe 嵌入
+// X-Frame-Options 无法表达多个来源，配置了来源时只依赖 CSP frame-ancestors
+func setEmbedFrameHeaders(c *gin.Context) {
+	ancestors := append([]string{"'self'"}, config.Get().Embed.AllowedOrigins...)
+	c.Header("Content-Security-Policy",
+		"default-src 'none'; style-src 'unsafe-inline'; frame-ancestors "+strings.Join(ancestors, " "))
+	if len(ancestors) == 1 {
+		c.Header("X-Frame-Options", "SAMEORIGIN")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+}
+
+// embedMonitor 处理 GET /embed/monitor/:id：输出公开监控项的当前状态和每日可用率柱状图
+// 页面为自包含 HTML（内联 CSS，无外部资源），通过 meta refresh 定时刷新
+// 查询参数：theme=light|dark，bars=显示天数（1-90，默认 90）
+func (s *Server) embedMonitor(c *gin.Context) {
+	setEmbedFrameHeaders(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusNotFound, "monitor not found")
+		return
+	}
+	bars := defaultEmbedBars
+	if v, err := strconv.Atoi(c.Query("bars")); err == nil && v > 0 {
+		bars = min(v, maxEmbedBars)
+	}
+	theme := c.Query("theme")
+	if theme != "dark" {
+		theme = "light"
+	}
+
+	data, ok := loadEmbedData(uint(id), bars)
+	if !ok {
+		c.String(http.StatusNotFound, "monitor not found")
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedCacheTTL.Seconds())))
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	lang := db.Language()
+	if err := embedTemplate.Execute(c.Writer, map[string]any{
+		"Data":    data,
+		"Dark":    theme == "dark",
+		"Lang":    lang,
+		"Since":   fmt.Sprintf(i18n.T(lang, "embed.days_ago"), len(data.Bars)),
+		"Today":   i18n.T(lang, "embed.today"),
+		"Refresh": int(embedCacheTTL.Seconds()),
+	}); err != nil {
+		c.Error(err)
+	}
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Data.Name}}</title>
+<style>
+body{margin:0;padding:12px;font:14px -apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif;{{if .Dark}}background:#1e1e1e;color:#e6e6e6{{else}}background:#fff;color:#2d3436{{end}}}
+.head{display:flex;justify-content:space-between;align-items:center;margin-bottom:8px}
+.name{font-weight:600;overflow:hidden;text-overflow:ellipsis;white-space:nowrap}
+.status{font-weight:600;white-space:nowrap}
+.dot{display:inline-block;width:9px;height:9px;border-radius:50%;margin-right:6px}
+.bars{display:flex;gap:2px;height:32px}
+.bars span{flex:1;border-radius:2px}
+.foot{display:flex;justify-content:space-between;margin-top:6px;font-size:12px;opacity:.7}
+</style>
+</head>
+<body>
+<div class="head">
+<span class="name">{{.Data.Name}}</span>
+<span class="status"><span class="dot" style="background:{{.Data.Status.Color}}"></span>{{.Data.Status.Label}}</span>
+</div>
+<div class="bars">{{range .Data.Bars}}<span style="background:{{.Color}}" title="{{.Title}}"></span>{{end}}</div>
+<div class="foot"><span>{{.Since}}</span><span>{{printf "%.2f" .Data.Uptime}}%</span><span>{{.Today}}</span></div>
+</body>
+</html>
+`))
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/monitor"
+	"ping-go/pkg/i18n"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFeedEntries 订阅中最多包含的条目数（状态变化和事件合计）
+const maxFeedEntries = 50
+
+// atomFeed Atom 1.0 订阅
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// atomEntry 订阅条目。ID 按事件固定（urn:pinggo:status-event:<id>、urn:pinggo:incident:<id>[:resolved]），
+// 不随访问地址变化，转发到聊天工具时不会重复推送
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Link      atomLink   `xml:"link"`
+	Category  atomTerm   `xml:"category"`
+	Summary   string     `xml:"summary,omitempty"`
+	Author    atomAuthor `xml:"author"`
+	at        time.Time
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// feedBaseURL 订阅中链接使用的地址：优先使用 server.external_url，否则按请求推断
+func feedBaseURL(c *gin.Context) string {
+	if u := config.Get().Server.ExternalURL; u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// buildFeed 生成监控项的状态变化和相关事件公告的订阅，最新的在前，最多 maxFeedEntries 条。
+// 订阅不需要登录，状态变化的摘要只包含检查消息的公开版本（PublicMessage）。
+// 事件只包含全局公告和关联了 monitors 中监控项的事件，关联的监控项也只显示 monitors 中的
+func buildFeed(title, link, self string, monitors []model.Monitor) atomFeed {
+	lang := db.Language()
+	loc := db.Location()
+	names := make(map[uint]string, len(monitors))
+	ids := make([]uint, 0, len(monitors))
+	for _, m := range monitors {
+		names[m.ID] = m.Name
+		ids = append(ids, m.ID)
+	}
+	stamp := func(t time.Time) string { return t.In(loc).Format(time.RFC3339) }
+
+	var entries []atomEntry
+	events, _ := db.RecentStatusEvents(ids, maxFeedEntries)
+	for _, e := range events {
+		key := model.StatusKey(e.Status)
+		entries = append(entries, atomEntry{
+			Title:    fmt.Sprintf(i18n.T(lang, "feed.status_change"), names[e.MonitorID], strings.ToUpper(key)),
+			ID:       fmt.Sprintf("urn:pinggo:status-event:%d", e.ID),
+			Link:     atomLink{Href: link},
+			Category: atomTerm{Term: key},
+			Summary:  monitor.PublicMessage(e.Status, e.Message),
+			at:       e.Time,
+		})
+	}
+
+	incidents, _ := db.Incidents(maxFeedEntries)
+	for _, inc := range incidents {
+		var affected []string
+		for _, id := range inc.MonitorIDs {
+			if name, ok := names[id]; ok {
+				affected = append(affected, name)
+			}
+		}
+		if !inc.Global() && len(affected) == 0 {
+			continue
+		}
+		summary := inc.Body
+		if len(affected) > 0 {
+			summary = strings.TrimSpace(fmt.Sprintf(i18n.T(lang, "statuspage.affected"), strings.Join(affected, ", ")) + "\n\n" + summary)
+		}
+		severity := i18n.T(lang, "incident.severity."+inc.Severity)
+		entries = append(entries, atomEntry{
+			Title:    fmt.Sprintf("[%s] %s", severity, inc.Title),
+			ID:       fmt.Sprintf("urn:pinggo:incident:%d", inc.ID),
+			Link:     atomLink{Href: link},
+			Category: atomTerm{Term: "incident"},
+			Summary:  summary,
+			at:       inc.CreatedAt,
+		})
+		if inc.ResolvedAt != nil {
+			entries = append(entries, atomEntry{
+				Title:    fmt.Sprintf(i18n.T(lang, "feed.incident_resolved"), inc.Title),
+				ID:       fmt.Sprintf("urn:pinggo:incident:%d:resolved", inc.ID),
+				Link:     atomLink{Href: link},
+				Category: atomTerm{Term: "incident_resolved"},
+				Summary:  summary,
+				at:       *inc.ResolvedAt,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].at.After(entries[j].at) })
+	if len(entries) > maxFeedEntries {
+		entries = entries[:maxFeedEntries]
+	}
+	updated := time.Now()
+	if len(entries) > 0 {
+		updated = entries[0].at
+	}
+	for i := range entries {
+		entries[i].Updated = stamp(entries[i].at)
+		entries[i].Published = entries[i].Updated
+		entries[i].Author = atomAuthor{Name: db.SiteName()}
+	}
+	return atomFeed{
+		Title:   title,
+		ID:      self,
+		Updated: stamp(updated),
+		Links:   []atomLink{{Href: link}, {Href: self, Rel: "self", Type: "application/atom+xml"}},
+		Entries: entries,
+	}
+}
+
+// writeFeed 输出 Atom XML
+func writeFeed(c *gin.Context, feed atomFeed) {
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedCacheTTL.Seconds())))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
+
+// feed 处理 GET /feed.xml：公开监控项（public: true）的状态变化和相关事件公告，不需要登录；
+// 对未登录客户端隐藏的监控项（public_visible: false）不包含在内
+func (s *Server) feed(c *gin.Context) {
+	var monitors []model.Monitor
+	if err := db.DB.Select("id", "name").Where("public = ? AND public_visible = ?", true, true).Order("id").Find(&monitors).Error; err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	base := feedBaseURL(c)
+	writeFeed(c, buildFeed(db.SiteName()+" - "+i18n.T(db.Language(), "feed.title"), base+"/", base+"/feed.xml", monitors))
+}
+
+// statusPageFeed 处理 GET /status/:slug/feed.xml：已发布状态页中监控项的状态变化和相关事件公告
+func (s *Server) statusPageFeed(c *gin.Context) {
+	page, monitors, err := db.PublishedStatusPage(c.Param("slug"))
+	if err != nil {
+		c.String(http.StatusNotFound, "status page not found")
+		return
+	}
+	link := feedBaseURL(c) + "/status/" + page.Slug
+	writeFeed(c, buildFeed(page.Title, link, link+"/feed.xml", monitors))
+}
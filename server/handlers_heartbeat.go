@@ -1,17 +1,93 @@
 package server
 
 import (
+	"errors"
 	"ping-go/db"
 	"ping-go/model"
-	"time"
+	"strconv"
 
 	"github.com/zishang520/socket.io/socket"
 )
 
+// defaultHeartbeatListSize getHeartbeatList 未指定 limit 时返回的条数
+const defaultHeartbeatListSize = 30
+
+// maxHeartbeatPageSize 心跳分页查询每页的最大条数
+const maxHeartbeatPageSize = 1000
+
+var errLimitRange = errors.New("limit must be between 1 and " + strconv.Itoa(maxHeartbeatPageSize))
+
+// heartbeatQuery 校验心跳分页和过滤参数：limit 为 0 到 maxHeartbeatPageSize（0 表示不限制），
+// status 为状态码或状态 key（up/down/...），为空表示不过滤
+func heartbeatQuery(limit, offset int, cursor, status string) (db.HeartbeatQuery, error) {
+	if limit < 0 || limit > maxHeartbeatPageSize {
+		return db.HeartbeatQuery{}, errLimitRange
+	}
+	if offset < 0 {
+		return db.HeartbeatQuery{}, errors.New("offset must not be negative")
+	}
+	q := db.HeartbeatQuery{Limit: limit, Offset: offset, Cursor: cursor}
+	if status != "" {
+		code, ok := model.StatusCode(status)
+		if n, err := strconv.Atoi(status); err == nil {
+			code, ok = n, model.StatusKey(n) != "unknown"
+		}
+		if !ok {
+			return db.HeartbeatQuery{}, errors.New("unknown status " + strconv.Quote(status))
+		}
+		q.Status = &code
+	}
+	return q, nil
+}
+
+// heartbeatQueryFromOptions 从 socket 事件的 options 对象读取 heartbeatQuery 的参数，未指定 limit 时使用 defaultLimit
+func heartbeatQueryFromOptions(opts map[string]any, defaultLimit int) (db.HeartbeatQuery, error) {
+	limit := defaultLimit
+	if v, ok := safeMapGetFloat64(opts, "limit"); ok {
+		if limit = int(v); limit < 1 {
+			return db.HeartbeatQuery{}, errLimitRange
+		}
+	}
+	offset, _ := safeMapGetFloat64(opts, "offset")
+	cursor := safeMapGetString(opts, "cursor")
+	status := safeMapGetString(opts, "status")
+	if v, ok := safeMapGetFloat64(opts, "status"); ok {
+		status = strconv.Itoa(int(v))
+	}
+	return heartbeatQuery(limit, int(offset), cursor, status)
+}
+
+// heartbeatPageMeta 分页结果中除数据外的字段
+func heartbeatPageMeta(page db.HeartbeatPage) map[string]any {
+	return map[string]any{
+		"total":      page.Total,
+		"hasMore":    page.HasMore,
+		"nextCursor": page.NextCursor,
+	}
+}
+
+// queryHeartbeatPage 按 options 分页查询心跳；参数无效时通过 ack（如有）返回错误并返回 false
+func queryHeartbeatPage(args []any, monitorID uint, hours int, opts map[string]any, defaultLimit int) (db.HeartbeatPage, bool) {
+	q, err := heartbeatQueryFromOptions(opts, defaultLimit)
+	var page db.HeartbeatPage
+	if err == nil {
+		page, err = db.GetHeartbeatsPage(monitorID, hours, q)
+	}
+	if err != nil {
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+		}
+		return page, false
+	}
+	return page, true
+}
+
 // setupHeartbeatHandlers 设置心跳数据相关的 Socket.IO 事件处理器
 func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 	// Handle "getHeartbeatList"
-	client.On("getHeartbeatList", func(args ...any) {
+	// 参数：monitorID, [options], [ack]。options 为 {limit, offset, cursor, status}，limit 默认 30；
+	// 有 ack 时通过 ack 返回 {ok, data, dataType, total, hasMore, nextCursor}（用于加载更多），否则发送 heartbeatList
+	requireAuth(client, "getHeartbeatList", func(args ...any) {
 		if len(args) < 1 {
 			return
 		}
@@ -19,28 +95,44 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
+		if !checkMonitorScope(client, monitorID, args) {
+			return
+		}
+		opts, _ := getArgAsMap(args, 1)
+		page, ok := queryHeartbeatPage(args, monitorID, 0, opts, defaultHeartbeatListSize)
+		if !ok {
+			return
+		}
+		meta := heartbeatPageMeta(page)
+		if ack := getCallback(args); ack != nil {
+			meta["ok"] = true
+			meta["data"] = page.Data
+			meta["dataType"] = page.DataType
+			ack([]any{meta}, nil)
+			return
+		}
+		client.Emit("heartbeatList", monitorID, page.Data, meta)
+	})
 
-		var heartbeats []model.Heartbeat
-		db.DB.Where("monitor_id = ?", monitorID).Order("time desc").Limit(30).Find(&heartbeats)
-
-		// Format for frontend
-		results := make([]map[string]any, 0)
-		for _, h := range heartbeats {
-			results = append(results, map[string]any{
-				"id":        h.ID,
-				"monitorID": h.MonitorID,
-				"status":    h.Status,
-				"msg":       h.Message,
-				"time":      h.Time.Format(time.RFC3339),
-				"duration":  h.Duration,
-			})
+	// Handle "getPublicHeartbeatList" - 状态页使用，不需要登录
+	// 参数：monitorID。发送 heartbeatList，只包含最近 30 条心跳的时间、状态和响应时间，不包含检查消息
+	client.On("getPublicHeartbeatList", func(args ...any) {
+		monitorID, err := getArgAsUint(args, 0)
+		if err != nil || !publicVisible(monitorID) {
+			return
+		}
+		page, err := db.GetHeartbeatsPage(monitorID, 0, db.HeartbeatQuery{Limit: defaultHeartbeatListSize})
+		if err != nil {
+			return
 		}
-		client.Emit("heartbeatList", monitorID, results)
+		client.Emit("heartbeatList", monitorID, publicHeartbeatRows(page.Data))
 	})
 
 	// Handle "getHeartbeatListWithRange" - 支持时间范围智能查询
 	// 根据时间范围自动选择数据源：24h内用原始数据，7天内用小时聚合，更长用日聚合
-	client.On("getHeartbeatListWithRange", func(args ...any) {
+	// 参数：monitorID, hours, [options], [ack]。options 同 getHeartbeatList，未指定 limit 时返回范围内的全部数据；
+	// 有 ack 时通过 ack 返回，否则发送 heartbeatListWithRange，结果中都包含 total、hasMore 和 nextCursor
+	requireAuth(client, "getHeartbeatListWithRange", func(args ...any) {
 		if len(args) < 2 {
 			return
 		}
@@ -48,25 +140,39 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
+		if !checkMonitorScope(client, monitorID, args) {
+			return
+		}
 		hoursFloat, err := getArgAsFloat64(args, 1)
 		if err != nil {
 			return
 		}
 		hours := int(hoursFloat)
+		if hours < 1 {
+			hours = 1
+		}
 
-		// 使用智能查询层
-		results, dataType := db.GetHeartbeatsWithTimeRange(monitorID, hours)
+		opts, _ := getArgAsMap(args, 2)
+		page, ok := queryHeartbeatPage(args, monitorID, hours, opts, 0)
+		if !ok {
+			return
+		}
 
 		// 返回结果和数据类型（让前端知道是原始/小时/日数据）
-		client.Emit("heartbeatListWithRange", monitorID, map[string]any{
-			"data":     results,
-			"dataType": dataType,
-			"hours":    hours,
-		})
+		reply := heartbeatPageMeta(page)
+		reply["data"] = page.Data
+		reply["dataType"] = page.DataType
+		reply["hours"] = hours
+		if ack := getCallback(args); ack != nil {
+			reply["ok"] = true
+			ack([]any{reply}, nil)
+			return
+		}
+		client.Emit("heartbeatListWithRange", monitorID, reply)
 	})
 
 	// Handle "getMonitorStats"
-	client.On("getMonitorStats", func(args ...any) {
+	requireAuth(client, "getMonitorStats", func(args ...any) {
 		if len(args) < 1 {
 			return
 		}
@@ -74,6 +180,9 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
+		if !checkMonitorScope(client, monitorID, args) {
+			return
+		}
 		stats := s.getMonitorStats(monitorID)
 		client.Emit("monitorStats", monitorID, stats)
 	})
@@ -81,7 +190,7 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 	// Handle "getChartData" - 获取图表数据
 	// 支持 "24h"（24个点）和 "7d"（28个点）两种视图
 	// 使用降采样的小时聚合数据，最近一个点从原始数据获取
-	client.On("getChartData", func(args ...any) {
+	requireAuth(client, "getChartData", func(args ...any) {
 		if len(args) < 2 {
 			return
 		}
@@ -89,6 +198,9 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
+		if !checkMonitorScope(client, monitorID, args) {
+			return
+		}
 		view, _ := args[1].(string) // "24h" 或 "7d"
 
 		// 获取图表数据
@@ -102,7 +214,7 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 	})
 
 	// Handle "clearEvents" - 清理所有心跳数据（包括聚合数据）
-	client.On("clearEvents", func(args ...any) {
+	requireAuth(client, "clearEvents", func(args ...any) {
 		if len(args) < 1 {
 			return
 		}
@@ -110,13 +222,21 @@ func (s *Server) setupHeartbeatHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
+		if !checkMonitorScope(client, monitorID, args) {
+			return
+		}
 
-		// 清理原始数据
-		db.DB.Where("monitor_id = ?", monitorID).Delete(&model.Heartbeat{})
-		// 清理小时聚合数据
-		db.DB.Where("monitor_id = ?", monitorID).Delete(&model.HeartbeatHourly{})
-		// 清理日聚合数据
-		db.DB.Where("monitor_id = ?", monitorID).Delete(&model.HeartbeatDaily{})
+		// 清理原始数据、小时聚合数据和日聚合数据
+		if err := db.PurgeMonitorHeartbeats(monitorID); err != nil {
+			if len(args) > 1 {
+				ack := args[1].(func([]any, error))
+				ack([]any{map[string]any{
+					"ok":  false,
+					"msg": "Failed to clear events: " + err.Error(),
+				}}, nil)
+			}
+			return
+		}
 
 		if len(args) > 1 {
 			ack := args[1].(func([]any, error))
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"ping-go/db"
+	"ping-go/model"
+	"slices"
+	"testing"
+	"time"
+)
+
+// createTestHeartbeats 为监控项写入 n 条带内部消息的心跳
+func createTestHeartbeats(t *testing.T, monitorID uint, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		hb := model.Heartbeat{MonitorID: monitorID, Status: model.StatusDown, Message: internalMessage, Time: time.Now().Add(-time.Duration(i) * time.Minute), Duration: 12}
+		if err := db.DB.Create(&hb).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func countHeartbeats(t *testing.T, monitorID uint) int64 {
+	t.Helper()
+	var n int64
+	db.DB.Model(&model.Heartbeat{}).Where("monitor_id = ?", monitorID).Count(&n)
+	return n
+}
+
+func TestHeartbeatEventsRequireLogin(t *testing.T) {
+	_, ts := newTestServer(t)
+	m := createTestMonitor(t, model.Monitor{Name: "api", PublicVisible: true})
+	createTestHeartbeats(t, m.ID, 3)
+
+	events := []struct {
+		name  string
+		args  []any
+		reply string // 已登录时回复的事件
+	}{
+		{"getHeartbeatList", []any{m.ID}, "heartbeatList"},
+		{"getHeartbeatListWithRange", []any{m.ID, 24}, "heartbeatListWithRange"},
+		{"getMonitorStats", []any{m.ID}, "monitorStats"},
+		{"getChartData", []any{m.ID, "24h"}, "chartData"},
+		{"clearEvents", []any{m.ID}, ""},
+	}
+	for _, ev := range events {
+		t.Run(ev.name, func(t *testing.T) {
+			client := dialSocket(t, ts)
+			if err := client.Emit(ev.name, ev.args...); err != nil {
+				t.Fatal(err)
+			}
+			e, err := client.Next("error", 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reply, _ := e.Args[0].(map[string]any); reply["code"] != float64(401) {
+				t.Fatalf("error = %s, want code 401", e.Raw)
+			}
+			if ev.reply != "" {
+				if e, err := client.Next(ev.reply, 200*time.Millisecond); err == nil {
+					t.Fatalf("unauthenticated %s answered with %s", ev.name, e.Raw)
+				}
+			}
+		})
+	}
+	if n := countHeartbeats(t, m.ID); n != 3 {
+		t.Fatalf("%d heartbeats left after unauthenticated clearEvents, want 3", n)
+	}
+}
+
+// clearEvents 需要管理员：viewer 返回 403，管理员可以清除
+func TestClearEventsRoles(t *testing.T) {
+	_, ts := newTestServer(t)
+	m := createTestMonitor(t, model.Monitor{Name: "api"})
+	createTestHeartbeats(t, m.ID, 3)
+
+	viewer := dialSocket(t, ts)
+	if reply, err := viewer.EmitWithAck("auth", map[string]any{"token": createTestSession(t, "viewer", model.RoleViewer)}); err != nil || reply[0].(map[string]any)["ok"] != true {
+		t.Fatalf("viewer auth = %v, %v", reply, err)
+	}
+	reply, err := viewer.EmitWithAck("getHeartbeatList", m.ID, map[string]any{})
+	if err != nil || reply[0].(map[string]any)["ok"] != true {
+		t.Fatalf("viewer getHeartbeatList = %v, %v", reply, err)
+	}
+	reply, err = viewer.EmitWithAck("clearEvents", m.ID)
+	if err != nil || reply[0].(map[string]any)["code"] != float64(403) {
+		t.Fatalf("viewer clearEvents = %v, %v, want 403", reply, err)
+	}
+	if n := countHeartbeats(t, m.ID); n != 3 {
+		t.Fatalf("viewer cleared heartbeats: %d left", n)
+	}
+
+	admin := dialSocket(t, ts)
+	admin.EmitWithAck("auth", map[string]any{"token": createTestSession(t, "admin", model.RoleAdmin)})
+	reply, err = admin.EmitWithAck("clearEvents", m.ID)
+	if err != nil || reply[0].(map[string]any)["ok"] != true {
+		t.Fatalf("admin clearEvents = %v, %v", reply, err)
+	}
+	if n := countHeartbeats(t, m.ID); n != 0 {
+		t.Fatalf("%d heartbeats left after clearEvents", n)
+	}
+}
+
+// getPublicHeartbeatList 不需要登录，只返回时间、状态和响应时间
+func TestPublicHeartbeatListOmitsMessage(t *testing.T) {
+	_, ts := newTestServer(t)
+	visible := createTestMonitor(t, model.Monitor{Name: "api", PublicVisible: true})
+	hidden := createTestMonitor(t, model.Monitor{Name: "db", PublicVisible: false})
+	createTestHeartbeats(t, visible.ID, 3)
+	createTestHeartbeats(t, hidden.ID, 3)
+	client := dialSocket(t, ts)
+
+	client.Emit("getPublicHeartbeatList", visible.ID)
+	ev, err := client.Next("heartbeatList", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoInternalDetails(t, "getPublicHeartbeatList", ev.Raw)
+	rows, _ := ev.Args[1].([]any)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %s", len(rows), ev.Raw)
+	}
+	for _, r := range rows {
+		row := r.(map[string]any)
+		for k := range row {
+			if !slices.Contains(publicHeartbeatFields, k) {
+				t.Errorf("public heartbeat has field %q", k)
+			}
+		}
+		if _, ok := row["msg"]; ok {
+			t.Errorf("public heartbeat has msg: %v", row)
+		}
+	}
+
+	client.Emit("getPublicHeartbeatList", hidden.ID)
+	if ev, err := client.Next("heartbeatList", 200*time.Millisecond); err == nil {
+		t.Fatalf("hidden monitor returned %s", ev.Raw)
+	}
+}
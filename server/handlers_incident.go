@@ -0,0 +1,188 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"strings"
+	"time"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+const (
+	// incidentWindow 事件视图显示的心跳范围：触发时间前后各 30 分钟
+	incidentWindow = 30 * time.Minute
+	// incidentNoteLimit 事件视图返回的最近备注数
+	incidentNoteLimit = 20
+	// maxIncidentNoteLength 确认备注的最大长度
+	maxIncidentNoteLength = 500
+)
+
+// incidentRuleFields 事件视图返回的触发规则字段，渠道密钥和收件人不返回
+var incidentRuleFields = []string{"monitor_name", "on_status", "channel", "max_retries", "max_retries_recovery",
+	"down_for_seconds", "resend_interval", "resend_unit", "quiet_hours",
+	"rate_limit_per_hour", "flap_transitions", "flap_window_minutes", "flap_stable_minutes",
+	"escalation_after_minutes", "escalation_channel", "language"}
+
+// setupIncidentHandlers 设置事件视图相关的 Socket.IO 事件处理器（通知中的 #/incident/<id>/<timestamp> 链接）
+func (s *Server) setupIncidentHandlers(client *socket.Socket) {
+	// Handle "getIncidentContext"
+	// 参数为 (monitorID, aroundTimestamp, ruleID)，aroundTimestamp 为 Unix 秒，0 表示当前时间；ruleID 可选，为触发通知的规则
+	requireAuth(client, "getIncidentContext", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			ack([]any{map[string]any{"ok": false, "msg": "monitorID is required"}}, nil)
+			return
+		}
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
+		var m model.Monitor
+		if err := db.DB.First(&m, id).Error; err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "Monitor not found"}}, nil)
+			return
+		}
+
+		at := time.Now()
+		if ts, _ := getArgAsFloat64(args, 1); ts > 0 {
+			at = time.Unix(int64(ts), 0)
+		}
+		ruleID, _ := getArgAsUint(args, 2)
+
+		heartbeats, tier := db.GetHeartbeatsAround(id, at, incidentWindow)
+		start := db.IncidentStart(id, at)
+		resp := map[string]any{
+			"ok": true,
+			"monitor": map[string]any{
+				"id": m.ID, "name": m.Name, "type": m.Type, "active": m.Active,
+				"status": m.Status, "status_key": model.StatusKey(m.Status), "msg": m.Message, "last_check": m.LastCheck,
+			},
+			"at":             at,
+			"window_minutes": int(incidentWindow.Minutes()),
+			"tier":           tier,
+			"heartbeats":     heartbeats,
+			"incident_start": start,
+			"ack":            incidentAckView(db.GetIncidentAck(id, start)),
+			"rules":          []map[string]any{},
+			"notes":          []map[string]any{},
+		}
+		// 演示模式不返回规则和备注（包含操作者和内部说明）
+		if !demoActive() {
+			resp["rules"] = incidentRules(m.Name, ruleID)
+			resp["notes"] = incidentNotes(id, at)
+		}
+		ack([]any{resp}, nil)
+	})
+
+	// Handle "acknowledgeIncident"
+	// 参数为 (monitorID, aroundTimestamp, note)：确认 aroundTimestamp 所在的宕机，记录到审计日志，备注出现在事件视图中
+	requireAuth(client, "acknowledgeIncident", func(args ...any) {
+		ack := getCallback(args)
+		reply := func(resp map[string]any) {
+			if ack != nil {
+				ack([]any{resp}, nil)
+			}
+		}
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			reply(map[string]any{"ok": false, "msg": "monitorID is required"})
+			return
+		}
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
+		var m model.Monitor
+		if err := db.DB.Select("id").First(&m, id).Error; err != nil {
+			reply(map[string]any{"ok": false, "msg": "Monitor not found"})
+			return
+		}
+		at := time.Now()
+		if ts, _ := getArgAsFloat64(args, 1); ts > 0 {
+			at = time.Unix(int64(ts), 0)
+		}
+		note := ""
+		if len(args) > 2 {
+			note, _ = args[2].(string)
+		}
+		note = strings.TrimSpace(note)
+		if len([]rune(note)) > maxIncidentNoteLength {
+			reply(map[string]any{"ok": false, "msg": fmt.Sprintf("Note must be at most %d characters", maxIncidentNoteLength)})
+			return
+		}
+
+		start := db.IncidentStart(id, at)
+		if existing := db.GetIncidentAck(id, start); existing != nil {
+			reply(map[string]any{"ok": true, "msg": "Incident already acknowledged", "ack": incidentAckView(existing)})
+			return
+		}
+		db.RecordAudit(socketActor(client), db.AuditActionIncidentAck, db.MonitorAuditTarget(id), note)
+		reply(map[string]any{"ok": true, "msg": "Incident acknowledged", "ack": incidentAckView(db.GetIncidentAck(id, start))})
+	})
+}
+
+// socketActor 返回审计日志中连接的操作者：user:<用户名>
+func socketActor(client *socket.Socket) string {
+	val, ok := socketAuth.Load(client.Id())
+	if !ok {
+		return "unknown"
+	}
+	data, _ := val.(map[string]any)
+	var user model.User
+	if db.DB.Select("id", "username").First(&user, data["userID"]).Error == nil {
+		return "user:" + user.Username
+	}
+	return fmt.Sprintf("user:%v", data["userID"])
+}
+
+// incidentAckView 事件确认状态，未确认时为 nil
+func incidentAckView(entry *model.AuditLog) map[string]any {
+	if entry == nil {
+		return nil
+	}
+	return map[string]any{"by": entry.Actor, "at": entry.CreatedAt, "note": entry.Detail}
+}
+
+// incidentRules 返回适用于监控项的触发规则：ruleID 指定的规则（即使已停用）排在最前，其后为其他启用的规则
+func incidentRules(monitorName string, ruleID uint) []map[string]any {
+	var rules []model.Notification
+	db.DB.Where("type = ? AND (active = ? OR id = ?)", "trigger", true, ruleID).Order("id").Find(&rules)
+
+	result := []map[string]any{}
+	for _, rule := range rules {
+		var cfg map[string]any
+		if json.Unmarshal([]byte(rule.Config), &cfg) != nil {
+			continue
+		}
+		if rule.ID != ruleID && cfg["monitor_name"] != "*" && cfg["monitor_name"] != monitorName {
+			continue
+		}
+		view := map[string]any{"id": rule.ID, "name": rule.Name, "active": rule.Active, "fired": rule.ID == ruleID}
+		for _, key := range incidentRuleFields {
+			if v, ok := cfg[key]; ok {
+				view[key] = v
+			}
+		}
+		if view["fired"] == true {
+			result = append([]map[string]any{view}, result...)
+		} else {
+			result = append(result, view)
+		}
+	}
+	return result
+}
+
+// incidentNotes 返回监控项在事件窗口结束前的最近审计记录（维护窗口原因、事件确认备注等）
+func incidentNotes(monitorID uint, at time.Time) []map[string]any {
+	logs, _ := db.MonitorAuditLogs(monitorID, at.Add(incidentWindow), incidentNoteLimit)
+	notes := make([]map[string]any, 0, len(logs))
+	for _, l := range logs {
+		notes = append(notes, map[string]any{"action": l.Action, "by": l.Actor, "at": l.CreatedAt, "detail": l.Detail})
+	}
+	return notes
+}
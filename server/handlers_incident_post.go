@@ -0,0 +1,265 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
+)
+
+// 事件公告的长度限制
+const (
+	maxIncidentTitleRunes = 200
+	maxIncidentBodyRunes  = 10000
+)
+
+// maxIncidentList getIncidents 最多返回的事件数
+const maxIncidentList = 200
+
+// 事件公告审计动作；确认宕机事件的 incident.ack 见 db.AuditActionIncidentAck
+const (
+	auditActionIncidentCreate  = "incident.create"
+	auditActionIncidentUpdate  = "incident.update"
+	auditActionIncidentResolve = "incident.resolve"
+	auditActionIncidentDelete  = "incident.delete"
+)
+
+// publicIncident 公开监控列表中的事件，不包含发布者
+type publicIncident struct {
+	ID         uint       `json:"id"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Severity   string     `json:"severity"`
+	Status     string     `json:"status"`
+	MonitorIDs []uint     `json:"monitor_ids"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Duration   int64      `json:"duration_seconds"` // 进行中的事件为到现在为止的时长
+}
+
+func newPublicIncident(i model.Incident, now time.Time) publicIncident {
+	return publicIncident{
+		ID: i.ID, Title: i.Title, Body: i.Body, Severity: i.Severity, Status: i.Status,
+		MonitorIDs: i.MonitorIDs, CreatedAt: i.CreatedAt, UpdatedAt: i.UpdatedAt, ResolvedAt: i.ResolvedAt,
+		Duration: int64(i.Duration(now).Seconds()),
+	}
+}
+
+// publicIncidents 转换为公开的事件列表；scope 不为空时只保留全局公告和关联了范围内监控项的事件，
+// 关联的监控项也只保留范围内的
+func publicIncidents(incidents []model.Incident, monitors []model.Monitor, scope []string) []publicIncident {
+	now := time.Now()
+	out := make([]publicIncident, 0, len(incidents))
+	for _, inc := range incidents {
+		if len(scope) > 0 && !inc.Global() {
+			var visible []uint
+			for _, m := range monitors {
+				if inc.Affects(m.ID) && m.InScope(scope) {
+					visible = append(visible, m.ID)
+				}
+			}
+			if len(visible) == 0 {
+				continue
+			}
+			inc.MonitorIDs = visible
+		}
+		out = append(out, newPublicIncident(inc, now))
+	}
+	return out
+}
+
+// monitorIncidentRefs 监控列表中每个监控项附带的进行中事件（不含全局公告）
+func monitorIncidentRefs(incidents []model.Incident, monitorID uint) []map[string]any {
+	refs := []map[string]any{}
+	for _, inc := range incidents {
+		if inc.Affects(monitorID) {
+			refs = append(refs, map[string]any{"id": inc.ID, "title": inc.Title, "severity": inc.Severity, "status": inc.Status})
+		}
+	}
+	return refs
+}
+
+// parseIncidentArgs 读取并校验事件表单，status 省略时为 investigating，severity 省略时为 minor
+func parseIncidentArgs(data map[string]any) (model.Incident, string) {
+	inc := model.Incident{
+		Title:    strings.TrimSpace(safeMapGetString(data, "title")),
+		Body:     strings.TrimSpace(safeMapGetString(data, "body")),
+		Severity: strings.TrimSpace(safeMapGetString(data, "severity")),
+		Status:   strings.TrimSpace(safeMapGetString(data, "status")),
+	}
+	if v, ok := safeMapGetFloat64(data, "id"); ok && v > 0 {
+		inc.ID = uint(v)
+	}
+	if inc.Severity == "" {
+		inc.Severity = model.IncidentSeverityMinor
+	}
+	if inc.Status == "" {
+		inc.Status = model.IncidentInvestigating
+	}
+	if list, ok := data["monitor_ids"].([]any); ok {
+		for _, v := range list {
+			if f, ok := v.(float64); ok && f > 0 {
+				inc.MonitorIDs = append(inc.MonitorIDs, uint(f))
+			}
+		}
+	}
+	switch {
+	case inc.Title == "":
+		return inc, "标题不能为空"
+	case utf8.RuneCountInString(inc.Title) > maxIncidentTitleRunes:
+		return inc, fmt.Sprintf("标题不能超过 %d 个字符", maxIncidentTitleRunes)
+	case utf8.RuneCountInString(inc.Body) > maxIncidentBodyRunes:
+		return inc, fmt.Sprintf("内容不能超过 %d 个字符", maxIncidentBodyRunes)
+	case !slices.Contains(model.IncidentSeverities, inc.Severity):
+		return inc, fmt.Sprintf("严重程度必须是 %s 之一", strings.Join(model.IncidentSeverities, "、"))
+	case !slices.Contains(model.IncidentStatuses, inc.Status):
+		return inc, fmt.Sprintf("状态必须是 %s 之一", strings.Join(model.IncidentStatuses, "、"))
+	}
+	return inc, ""
+}
+
+// incidentChanged 事件修改后刷新公开监控列表和状态页
+func (s *Server) incidentChanged() {
+	clearStatusPageCache()
+	s.broadcastMonitorList()
+}
+
+// incidentActive 事件是否存在且仍在进行中，用于判断这次操作是否解决了事件
+func incidentActive(id uint) bool {
+	var inc model.Incident
+	db.DB.Select("id", "status").Where("id = ?", id).Limit(1).Find(&inc)
+	return inc.ID != 0 && inc.Active()
+}
+
+// setupIncidentPostHandlers 设置事件公告相关的 Socket.IO 事件处理器
+func (s *Server) setupIncidentPostHandlers(client *socket.Socket) {
+	// Handle "getIncidents"
+	// 返回最近的事件（包括已解决的），最新的在前
+	requireAuth(client, "getIncidents", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		incidents, err := db.Incidents(maxIncidentList)
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		ack([]any{map[string]any{
+			"ok": true, "incidents": incidents,
+			"severities": model.IncidentSeverities, "statuses": model.IncidentStatuses,
+		}}, nil)
+	})
+
+	// Handle "addIncident" / "editIncident" - args: ({id, title, body, severity, status, monitor_ids})
+	// addIncident 忽略 id；monitor_ids 为空表示全局公告；status 改为 resolved 时记录解决时间
+	for _, event := range []string{"addIncident", "editIncident"} {
+		requireAuth(client, event, func(args ...any) {
+			ack := getCallback(args)
+			if len(args) < 1 || ack == nil {
+				return
+			}
+			data, ok := args[0].(map[string]any)
+			if !ok {
+				ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+				return
+			}
+			inc, errMsg := parseIncidentArgs(data)
+			action := auditActionIncidentUpdate
+			if event == "addIncident" {
+				inc.ID, inc.CreatedBy, action = 0, socketActor(client), auditActionIncidentCreate
+			} else if inc.ID == 0 && errMsg == "" {
+				errMsg = "id is required"
+			}
+			if errMsg != "" {
+				ack([]any{map[string]any{"ok": false, "msg": errMsg}}, nil)
+				return
+			}
+			wasActive := inc.ID != 0 && incidentActive(inc.ID)
+			if err := db.SaveIncident(&inc); err != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("事件 %d 不存在", inc.ID)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+				return
+			}
+			db.RecordAudit(socketActor(client), action, fmt.Sprintf("incident:%d", inc.ID),
+				fmt.Sprintf("status=%s severity=%s title=%s", inc.Status, inc.Severity, inc.Title))
+			ack([]any{map[string]any{"ok": true, "msg": "Incident saved", "incident": inc}}, nil)
+			s.incidentChanged()
+			// 新发布的事件和这次编辑中解决的事件通知状态页订阅者
+			if event == "addIncident" || (wasActive && !inc.Active()) {
+				s.notifyIncidentSubscribers(&inc)
+			}
+		})
+	}
+
+	// Handle "resolveIncident" - args: (id)
+	requireAuth(client, "resolveIncident", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "id is required"}}, nil)
+			}
+			return
+		}
+		wasActive := incidentActive(id)
+		inc, err := db.ResolveIncident(id)
+		if err != nil {
+			if ack != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("事件 %d 不存在", id)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionIncidentResolve, fmt.Sprintf("incident:%d", id),
+			"duration="+inc.Duration(time.Now()).Round(time.Second).String())
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "msg": "Incident resolved", "incident": inc}}, nil)
+		}
+		s.incidentChanged()
+		if wasActive {
+			s.notifyIncidentSubscribers(inc)
+		}
+	})
+
+	// Handle "deleteIncident" - args: (id)
+	requireAuth(client, "deleteIncident", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "id is required"}}, nil)
+			}
+			return
+		}
+		if err := db.DeleteIncident(id); err != nil {
+			if ack != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("事件 %d 不存在", id)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionIncidentDelete, fmt.Sprintf("incident:%d", id), "deleted")
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "msg": "Incident deleted"}}, nil)
+		}
+		s.incidentChanged()
+	})
+}
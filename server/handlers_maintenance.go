@@ -0,0 +1,468 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/pkg/cron"
+	apperrors "ping-go/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
+)
+
+// maxMaintenanceDuration 单次通过 API 创建的维护窗口最长时间，避免流水线异常退出后长期静默告警
+const maxMaintenanceDuration = 24 * time.Hour
+
+// maxMaintenanceReasonRunes 维护原因的长度限制
+const maxMaintenanceReasonRunes = 200
+
+// maintenanceHistory getMaintenanceWindows 同时返回最近多久内结束的窗口
+const maintenanceHistory = 7 * 24 * time.Hour
+
+// 维护窗口审计动作，maintenance.create 和 maintenance.end 也用于 REST API
+const (
+	auditActionMaintenanceCreate = "maintenance.create"
+	auditActionMaintenanceUpdate = "maintenance.update"
+	auditActionMaintenanceEnd    = "maintenance.end"
+	auditActionMaintenanceDelete = "maintenance.delete"
+)
+
+// maintenanceRequest POST /api/maintenance 的请求体
+// monitor_ids 与 tag 二选一；duration_minutes 与 ends_at（RFC3339）二选一
+type maintenanceRequest struct {
+	MonitorIDs      []uint `json:"monitor_ids"`
+	Tag             string `json:"tag"`
+	DurationMinutes int    `json:"duration_minutes"`
+	EndsAt          string `json:"ends_at"`
+	Reason          string `json:"reason"`
+}
+
+// maintenanceCreated POST /api/maintenance 的响应
+type maintenanceCreated struct {
+	Windows []model.MaintenanceWindow `json:"windows"`
+}
+
+// maintenanceEnded DELETE /api/maintenance/:id 的响应
+type maintenanceEnded struct {
+	Window *model.MaintenanceWindow `json:"window"`
+}
+
+// apiActor 返回审计日志中的操作者：API Key 为 api_key:<名称>，会话为 user:<用户名>
+func apiActor(c *gin.Context) string {
+	if v, ok := c.Get("apiKeyID"); ok {
+		var key model.APIKey
+		if db.DB.Select("id", "name").First(&key, v).Error == nil {
+			return "api_key:" + key.Name
+		}
+		return fmt.Sprintf("api_key:%v", v)
+	}
+	if v, ok := c.Get("userID"); ok {
+		var user model.User
+		if db.DB.Select("id", "username").First(&user, v).Error == nil {
+			return "user:" + user.Username
+		}
+		return fmt.Sprintf("user:%v", v)
+	}
+	return "unknown"
+}
+
+// createMaintenanceAPI 处理 POST /api/maintenance：为指定监控项或带有某标签的全部监控项开启维护窗口，立即生效
+// 同一监控项已有重叠的窗口时合并为一个，返回每个监控项合并后的窗口
+func (s *Server) createMaintenanceAPI(c *gin.Context) {
+	var req maintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Invalid JSON body", http.StatusBadRequest, nil))
+		return
+	}
+
+	now := time.Now()
+	var end time.Time
+	switch {
+	case req.DurationMinutes > 0 && req.EndsAt != "":
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Specify either duration_minutes or ends_at, not both", http.StatusBadRequest, nil))
+		return
+	case req.DurationMinutes > 0:
+		end = now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+	case req.EndsAt != "":
+		t, err := time.Parse(time.RFC3339, req.EndsAt)
+		if err != nil {
+			writeAPIError(c, apperrors.New(http.StatusBadRequest, "ends_at must be an RFC3339 timestamp", http.StatusBadRequest, nil))
+			return
+		}
+		end = t
+	default:
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "duration_minutes or ends_at is required", http.StatusBadRequest, nil))
+		return
+	}
+	if !end.After(now) {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "ends_at must be in the future", http.StatusBadRequest, nil))
+		return
+	}
+	if end.Sub(now) > maxMaintenanceDuration {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, fmt.Sprintf("Maintenance window cannot exceed %s", maxMaintenanceDuration), http.StatusBadRequest, nil))
+		return
+	}
+
+	monitors, status, err := s.maintenanceTargets(c, req)
+	if err != nil {
+		writeAPIError(c, apperrors.New(status, err.Error(), status, err))
+		return
+	}
+	ids := make([]uint, len(monitors))
+	for i, m := range monitors {
+		ids[i] = m.ID
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	actor := apiActor(c)
+	windows, err := db.CreateMaintenance(ids, now, end, reason, actor)
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	for _, w := range windows {
+		db.RecordAudit(actor, auditActionMaintenanceCreate, maintenanceAuditTarget(w),
+			fmt.Sprintf("window %d until %s: %s", w.ID, w.EndsAt.Format(time.RFC3339), w.Reason))
+	}
+
+	s.monitorService.ApplyMaintenance(windows)
+	s.broadcastMonitorList()
+	c.JSON(http.StatusCreated, maintenanceCreated{Windows: windows})
+}
+
+// maintenanceTargets 解析请求中的目标监控项并检查标签范围
+// 按 ID 指定时任一监控项不在范围内即拒绝；按标签指定时只取范围内的监控项
+func (s *Server) maintenanceTargets(c *gin.Context, req maintenanceRequest) ([]model.Monitor, int, error) {
+	scope := apiScope(c)
+	tag := strings.ToLower(strings.TrimSpace(req.Tag))
+	var monitors []model.Monitor
+
+	switch {
+	case len(req.MonitorIDs) > 0 && tag != "":
+		return nil, http.StatusBadRequest, errors.New("Specify either monitor_ids or tag, not both")
+	case len(req.MonitorIDs) > 0:
+		if err := db.DB.Select("id", "name", "tags").Where("id IN ?", req.MonitorIDs).Find(&monitors).Error; err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		found := make(map[uint]bool, len(monitors))
+		for _, m := range monitors {
+			found[m.ID] = true
+		}
+		for _, id := range req.MonitorIDs {
+			if !found[id] {
+				return nil, http.StatusNotFound, fmt.Errorf("Monitor %d not found", id)
+			}
+		}
+		for _, m := range monitors {
+			if !m.InScope(scope) {
+				return nil, http.StatusForbidden, errors.New("Forbidden")
+			}
+		}
+	case tag != "":
+		var all []model.Monitor
+		if err := db.DB.Select("id", "name", "tags").Order("id").Find(&all).Error; err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		for _, m := range all {
+			if m.InScope([]string{tag}) && m.InScope(scope) {
+				monitors = append(monitors, m)
+			}
+		}
+		if len(monitors) == 0 {
+			return nil, http.StatusNotFound, fmt.Errorf("No monitors with tag %q", tag)
+		}
+	default:
+		return nil, http.StatusBadRequest, errors.New("monitor_ids or tag is required")
+	}
+	return monitors, 0, nil
+}
+
+// endMaintenanceAPI 处理 DELETE /api/maintenance/:id：提前关闭维护窗口，监控项立即恢复检查
+func (s *Server) endMaintenanceAPI(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Maintenance window not found", http.StatusNotFound, nil))
+		return
+	}
+	var w model.MaintenanceWindow
+	if err := db.DB.First(&w, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeAPIError(c, apperrors.New(http.StatusNotFound, "Maintenance window not found", http.StatusNotFound, nil))
+		} else {
+			writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		}
+		return
+	}
+	if !maintenanceInScope(w, apiScope(c)) {
+		writeAPIError(c, apperrors.ErrForbidden)
+		return
+	}
+
+	ended, err := db.EndMaintenance(w.ID)
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	db.RecordAudit(apiActor(c), auditActionMaintenanceEnd, maintenanceAuditTarget(*ended),
+		fmt.Sprintf("window %d", ended.ID))
+
+	s.monitorService.ApplyMaintenance([]model.MaintenanceWindow{*ended})
+	s.broadcastMonitorList()
+	c.JSON(http.StatusOK, maintenanceEnded{Window: ended})
+}
+
+// maintenanceInScope 标签范围内的调用方是否可以管理该窗口：按标签的窗口要求标签在范围内
+func maintenanceInScope(w model.MaintenanceWindow, scope []string) bool {
+	if len(scope) == 0 {
+		return true
+	}
+	if w.MonitorID == 0 {
+		return model.Monitor{Tags: w.Tag}.InScope(scope)
+	}
+	var m model.Monitor
+	if err := db.DB.Select("id", "tags").First(&m, w.MonitorID).Error; err != nil {
+		return true
+	}
+	return m.InScope(scope)
+}
+
+// maintenanceAuditTarget 维护窗口在审计日志中的 target：监控项或 tag:<标签>
+func maintenanceAuditTarget(w model.MaintenanceWindow) string {
+	if w.MonitorID == 0 {
+		return "tag:" + w.Tag
+	}
+	return db.MonitorAuditTarget(w.MonitorID)
+}
+
+// maintenanceWindowView getMaintenanceWindows 返回的窗口，附带当前是否生效及本次维护的结束时间
+type maintenanceWindowView struct {
+	model.MaintenanceWindow
+	Active      bool       `json:"active"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+}
+
+// parseMaintenanceTime 解析 RFC3339 时间，空字符串返回零值
+func parseMaintenanceTime(data map[string]any, key string) (time.Time, error) {
+	v := strings.TrimSpace(safeMapGetString(data, key))
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s 必须是 RFC3339 时间", key)
+	}
+	return t, nil
+}
+
+// parseMaintenanceArgs 读取并校验维护窗口表单，返回窗口模板和目标监控项 ID（按标签时为空）。
+// 一次性窗口 starts_at 省略时为现在、ends_at 必填；周期性窗口需要 cron 和 duration_minutes，
+// starts_at/ends_at 为有效期，ends_at 省略表示长期有效
+func parseMaintenanceArgs(data map[string]any) (model.MaintenanceWindow, []uint, string) {
+	w := model.MaintenanceWindow{
+		Tag:      strings.ToLower(strings.TrimSpace(safeMapGetString(data, "tag"))),
+		Cron:     strings.Join(strings.Fields(safeMapGetString(data, "cron")), " "),
+		Timezone: strings.TrimSpace(safeMapGetString(data, "timezone")),
+		Reason:   strings.TrimSpace(safeMapGetString(data, "reason")),
+	}
+	if v, ok := safeMapGetFloat64(data, "id"); ok && v > 0 {
+		w.ID = uint(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "duration_minutes"); ok && v > 0 {
+		w.DurationMinutes = int(v)
+	}
+	var ids []uint
+	if list, ok := data["monitor_ids"].([]any); ok {
+		for _, v := range list {
+			if f, ok := v.(float64); ok && f > 0 {
+				ids = append(ids, uint(f))
+			}
+		}
+	}
+
+	var err error
+	if w.StartsAt, err = parseMaintenanceTime(data, "starts_at"); err != nil {
+		return w, nil, err.Error()
+	}
+	if w.EndsAt, err = parseMaintenanceTime(data, "ends_at"); err != nil {
+		return w, nil, err.Error()
+	}
+	if w.StartsAt.IsZero() {
+		w.StartsAt = time.Now()
+	}
+
+	switch {
+	case len(ids) > 0 && w.Tag != "":
+		return w, nil, "监控项和标签只能指定其一"
+	case len(ids) == 0 && w.Tag == "":
+		return w, nil, "请选择监控项或标签"
+	case utf8.RuneCountInString(w.Reason) > maxMaintenanceReasonRunes:
+		return w, nil, fmt.Sprintf("原因不能超过 %d 个字符", maxMaintenanceReasonRunes)
+	}
+	if w.Timezone != "" {
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return w, nil, fmt.Sprintf("未知的时区 %q", w.Timezone)
+		}
+	}
+	if w.Cron == "" {
+		w.DurationMinutes, w.Timezone = 0, ""
+		if w.EndsAt.IsZero() {
+			return w, nil, "请设置结束时间"
+		}
+	} else {
+		if _, err := cron.Parse(w.Cron); err != nil {
+			return w, nil, "cron 表达式无效: " + err.Error()
+		}
+		if w.DurationMinutes <= 0 || w.DurationMinutes > int(model.MaxMaintenanceOccurrence/time.Minute) {
+			return w, nil, fmt.Sprintf("每次持续时间必须在 1 到 %d 分钟之间", int(model.MaxMaintenanceOccurrence/time.Minute))
+		}
+		if w.EndsAt.IsZero() {
+			w.EndsAt = model.MaintenanceForever
+		}
+	}
+	if !w.EndsAt.After(w.StartsAt) {
+		return w, nil, "结束时间必须晚于开始时间"
+	}
+	return w, ids, ""
+}
+
+// maintenanceChanged 维护窗口修改后立即应用到受影响的监控项并刷新监控列表和状态页
+func (s *Server) maintenanceChanged(windows []model.MaintenanceWindow) {
+	s.monitorService.ApplyMaintenance(windows)
+	clearStatusPageCache()
+	s.broadcastMonitorList()
+}
+
+// setupMaintenanceHandlers 设置维护窗口相关的 Socket.IO 事件处理器
+func (s *Server) setupMaintenanceHandlers(client *socket.Socket) {
+	// Handle "getMaintenanceWindows"
+	// 返回未结束的窗口和最近 7 天内结束的窗口，最新创建的在前
+	requireAuth(client, "getMaintenanceWindows", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		now := time.Now()
+		windows, err := db.MaintenanceWindows(now.Add(-maintenanceHistory))
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		views := make([]maintenanceWindowView, len(windows))
+		for i, w := range windows {
+			views[i].MaintenanceWindow = w
+			if until, ok := w.ActiveUntil(now); ok {
+				views[i].Active, views[i].ActiveUntil = true, &until
+			}
+		}
+		ack([]any{map[string]any{"ok": true, "windows": views}}, nil)
+	})
+
+	// Handle "saveMaintenanceWindow" - args: ({id, monitor_ids, tag, starts_at, ends_at, cron, duration_minutes, timezone, reason})
+	// 新建时每个监控项各创建一个窗口，按标签时创建一个作用于该标签全部监控项的窗口；修改时只能指定一个监控项或标签
+	requireAuth(client, "saveMaintenanceWindow", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		tmpl, ids, errMsg := parseMaintenanceArgs(data)
+		if errMsg == "" && tmpl.ID != 0 && len(ids) > 1 {
+			errMsg = "修改维护窗口时只能指定一个监控项"
+		}
+		if errMsg == "" && len(ids) > 0 {
+			var count int64
+			db.DB.Model(&model.Monitor{}).Where("id IN ?", ids).Count(&count)
+			if int(count) != len(ids) {
+				errMsg = "部分监控项不存在"
+			}
+		}
+		if errMsg != "" {
+			ack([]any{map[string]any{"ok": false, "msg": errMsg}}, nil)
+			return
+		}
+
+		var targets []model.MaintenanceWindow
+		if len(ids) == 0 {
+			targets = append(targets, tmpl)
+		}
+		for _, id := range ids {
+			w := tmpl
+			w.MonitorID = id
+			targets = append(targets, w)
+		}
+
+		actor := socketActor(client)
+		affected := []model.MaintenanceWindow{}
+		action := auditActionMaintenanceCreate
+		if tmpl.ID != 0 {
+			action = auditActionMaintenanceUpdate
+			var old model.MaintenanceWindow
+			if err := db.DB.First(&old, tmpl.ID).Error; err == nil {
+				affected = append(affected, old)
+			}
+		}
+		saved := make([]model.MaintenanceWindow, 0, len(targets))
+		for _, w := range targets {
+			if w.ID == 0 {
+				w.CreatedBy = actor
+			}
+			if err := db.SaveMaintenanceWindow(&w); err != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("维护窗口 %d 不存在", w.ID)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+				return
+			}
+			detail := fmt.Sprintf("window %d until %s: %s", w.ID, w.EndsAt.Format(time.RFC3339), w.Reason)
+			if w.Recurring() {
+				detail = fmt.Sprintf("window %d cron %q for %dm: %s", w.ID, w.Cron, w.DurationMinutes, w.Reason)
+			}
+			db.RecordAudit(actor, action, maintenanceAuditTarget(w), detail)
+			saved = append(saved, w)
+		}
+		ack([]any{map[string]any{"ok": true, "msg": "Maintenance window saved", "windows": saved}}, nil)
+		s.maintenanceChanged(append(affected, saved...))
+	})
+
+	// Handle "deleteMaintenanceWindow" - args: (id)
+	// 删除后受影响的监控项立即恢复检查
+	requireAuth(client, "deleteMaintenanceWindow", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "id is required"}}, nil)
+			}
+			return
+		}
+		w, err := db.DeleteMaintenanceWindow(id)
+		if err != nil {
+			if ack != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("维护窗口 %d 不存在", id)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionMaintenanceDelete, maintenanceAuditTarget(*w), fmt.Sprintf("window %d", w.ID))
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "msg": "Maintenance window deleted"}}, nil)
+		}
+		s.maintenanceChanged([]model.MaintenanceWindow{*w})
+	})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/monitor"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsCacheTTL /metrics 输出的缓存时间，抓取间隔短于该值时返回缓存的内容
+const metricsCacheTTL = 10 * time.Second
+
+var (
+	metricsMu      sync.Mutex
+	metricsBody    []byte
+	metricsExpires time.Time
+)
+
+// promLabelEscaper 按 Prometheus 文本格式转义标签值中的反斜杠、双引号和换行
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricsAuthorized 校验 metrics 配置的 Basic 认证或 Bearer token，都未配置时不需要认证
+func metricsAuthorized(c *gin.Context, cfg config.MetricsConfig) bool {
+	if cfg.Username == "" && cfg.Token == "" {
+		return true
+	}
+	if cfg.Token != "" {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) == 1 {
+			return true
+		}
+	}
+	if cfg.Username != "" {
+		if user, pass, ok := c.Request.BasicAuth(); ok &&
+			subtle.ConstantTimeCompare([]byte(user), []byte(cfg.Username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.Password)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// metrics 处理 GET /metrics：Prometheus 文本格式的监控项状态、响应时间、24 小时可用率和进程计数器
+func (s *Server) metrics(c *gin.Context) {
+	cfg := config.Get().Metrics
+	if !metricsAuthorized(c, cfg) {
+		if cfg.Username != "" {
+			c.Header("WWW-Authenticate", `Basic realm="PingGo metrics"`)
+		}
+		c.String(http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	metricsMu.Lock()
+	if time.Now().After(metricsExpires) {
+		metricsBody = s.renderMetrics()
+		metricsExpires = time.Now().Add(metricsCacheTTL)
+	}
+	body := metricsBody
+	metricsMu.Unlock()
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", body)
+}
+
+// renderMetrics 生成指标。监控项列表和状态来自数据库（所有实例一致），响应时间来自本实例最近一次检查，
+// 只有执行检查的实例（多实例模式下的领导者）有该指标
+func (s *Server) renderMetrics() []byte {
+	var b bytes.Buffer
+	header := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	var monitors []model.Monitor
+	db.DB.Select("id", "name", "type", "status").Where("active = ?", 1).Order("id").Find(&monitors)
+	ids := make([]uint, len(monitors))
+	labels := make([]string, len(monitors))
+	for i, m := range monitors {
+		ids[i] = m.ID
+		labels[i] = fmt.Sprintf(`{id="%d",name="%s",type="%s"}`, m.ID, promLabelEscaper.Replace(m.Name), promLabelEscaper.Replace(string(m.Type)))
+	}
+
+	header(promStatusMetric, "gauge", "Current status of active monitors (1 = UP, 0 = DOWN, 2 = PENDING, 3 = MAINTENANCE).")
+	for i, m := range monitors {
+		status := m.Status
+		if last, ok := s.monitorService.LastCheckOf(m.ID); ok {
+			status = last.Status
+		}
+		fmt.Fprintf(&b, "%s%s %d\n", promStatusMetric, labels[i], status)
+	}
+
+	header("pinggo_monitor_response_ms", "gauge", "Response time of the last check in milliseconds.")
+	for i, m := range monitors {
+		if last, ok := s.monitorService.LastCheckOf(m.ID); ok {
+			fmt.Fprintf(&b, "pinggo_monitor_response_ms%s %d\n", labels[i], last.Duration)
+		}
+	}
+
+	header("pinggo_monitor_uptime_24h", "gauge", "Uptime ratio over the last 24 hours (0-1).")
+	uptime := db.GetUptimeStatsBatch(ids, 24*time.Hour)
+	for i, m := range monitors {
+		fmt.Fprintf(&b, "pinggo_monitor_uptime_24h%s %g\n", labels[i], uptime[m.ID]/100)
+	}
+
+	counters := monitor.ReadCounters()
+	header("pinggo_checks_total", "counter", "Checks executed by this process.")
+	fmt.Fprintf(&b, "pinggo_checks_total %d\n", counters.Checks)
+	header("pinggo_checks_failed_total", "counter", "Checks by this process that resulted in DOWN.")
+	fmt.Fprintf(&b, "pinggo_checks_failed_total %d\n", counters.ChecksFailed)
+	header("pinggo_notifications_sent_total", "counter", "Notifications delivered successfully by this process.")
+	fmt.Fprintf(&b, "pinggo_notifications_sent_total %d\n", counters.NotificationsSent)
+	header("pinggo_notifications_failed_total", "counter", "Notification deliveries that failed in this process.")
+	fmt.Fprintf(&b, "pinggo_notifications_failed_total %d\n", counters.NotificationsFailed)
+
+	depth, capacity := db.HeartbeatBufferDepth()
+	header("pinggo_heartbeat_buffer_depth", "gauge", "Heartbeats waiting in the write buffer.")
+	fmt.Fprintf(&b, "pinggo_heartbeat_buffer_depth %d\n", depth)
+	header("pinggo_heartbeat_buffer_capacity", "gauge", "Capacity of the heartbeat write buffer.")
+	fmt.Fprintf(&b, "pinggo_heartbeat_buffer_capacity %d\n", capacity)
+	header("pinggo_heartbeats_dropped_total", "counter", "Heartbeats dropped because the write buffer was full.")
+	fmt.Fprintf(&b, "pinggo_heartbeats_dropped_total %d\n", db.HeartbeatsDropped())
+
+	return b.Bytes()
+}
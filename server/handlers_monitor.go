@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"ping-go/db"
@@ -8,6 +9,7 @@ import (
 	"ping-go/monitor"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/zishang520/socket.io/socket"
 )
@@ -28,46 +30,58 @@ func (s *Server) setupMonitorHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
 		var m model.Monitor
 		if err := db.DB.First(&m, id).Error; err == nil {
-			data := make(map[string]any)
-			data["id"] = m.ID
-			data["name"] = m.Name
-			data["url"] = m.URL
-			data["type"] = m.Type
-			data["interval"] = m.Interval
-			data["active"] = m.Active
-			data["status"] = m.Status
-			data["msg"] = m.Message
-			data["last_check"] = m.LastCheck
-			data["recentResults"] = s.getRecentResults(m.ID)
-			data["method"] = m.Method
-			data["body"] = m.Body
-			data["headers"] = m.Headers
-			data["timeout"] = m.Timeout
-			data["expected_status"] = m.ExpectedStatus
-
-			data["response_regex"] = m.ResponseRegex
-			data["form_data"] = m.FormData
-			data["follow_redirects"] = m.FollowRedirects
+			data := s.monitorDetail(&m, fullAdminSocket(client))
+			// 编辑器需要回显签名密钥，只返回给不限范围的管理员
+			if m.Type == model.MonitorTypePush && fullAdminSocket(client) {
+				data["push_secret"] = m.PushSecret
+			}
+			// 按需附带失败热力图：getMonitor(id, {heatmap: true, heatmap_days: 90})
+			if len(args) > 1 {
+				if opts, ok := args[1].(map[string]any); ok {
+					if include, _ := opts["heatmap"].(bool); include {
+						days, _ := safeMapGetFloat64(opts, "heatmap_days")
+						data["failure_heatmap"] = db.GetFailureHeatmap(m.ID, heatmapDays(days), db.Location())
+					}
+				}
+			}
+			if demoActive() {
+				data = demoMonitorDetail(data)
+			}
 			client.Emit("monitor", data)
 		}
 	})
 
 	// Handle "exportMonitorConfig"
 	requireAuth(client, "exportMonitorConfig", func(args ...any) {
-		var monitors []model.Monitor
-		if err := db.DB.Find(&monitors).Error; err != nil {
+		includeSecrets, includeHistory := false, false
+		if len(args) > 0 {
+			if opts, ok := args[0].(map[string]any); ok {
+				includeSecrets, _ = opts["include_secrets"].(bool)
+				includeHistory, _ = opts["include_history"].(bool)
+			}
+		}
+		exported, err := exportMonitorFile(includeSecrets, includeHistory)
+		if err != nil {
 			client.Emit("error", map[string]any{"msg": "Failed to fetch monitors"})
 			return
 		}
-		client.Emit("monitorConfigExport", monitors)
+		client.Emit("monitorConfigExport", exported)
 	})
 
 	// Handle "importMonitorConfig"
 	s.setupImportMonitorHandler(client)
 	// Handle "testMonitor"
 	s.setupTestMonitorHandler(client)
+	s.setupTestAssertionsHandler(client)
+	// Handle "validateMonitor"
+	s.setupValidateMonitorHandler(client)
+	// Handle "importCurl"
+	s.setupImportCurlHandler(client)
 	// Handle "add"
 	s.setupAddMonitorHandler(client)
 	// Handle "edit"
@@ -76,6 +90,213 @@ func (s *Server) setupMonitorHandlers(client *socket.Socket) {
 	s.setupToggleActiveHandler(client)
 	// Handle "deleteMonitor"
 	s.setupDeleteMonitorHandler(client)
+	// Handle "setTagColor"
+	s.setupSetTagColorHandler(client)
+	// Handle "startMonitorDebug" / "stopMonitorDebug"
+	s.setupMonitorDebugHandlers(client)
+	// Handle "getFailureHeatmap"
+	s.setupFailureHeatmapHandler(client)
+}
+
+// monitorDetail 构造 getMonitor 返回的监控详情；密钥类字段只返回是否已设置。
+// 诊断输出（traceroute 等，可能包含内网地址）只在 full 为 true（不限范围的管理员）时返回。
+// 编辑冲突时也用它返回当前保存的配置
+func (s *Server) monitorDetail(m *model.Monitor, full bool) map[string]any {
+	data := make(map[string]any)
+	data["id"] = m.ID
+	data["version"] = m.Version
+	data["updated_at"] = m.UpdatedAt
+	data["name"] = m.Name
+	data["url"] = m.URL
+	data["type"] = m.Type
+	data["tags"] = m.Tags
+	data["color"] = m.Color
+	data["icon"] = m.Icon
+	data["group_id"] = m.GroupID
+	if m.GroupID != 0 {
+		var group model.MonitorGroup
+		if db.DB.Select("name").First(&group, m.GroupID).Error == nil {
+			data["group"] = group.Name
+		}
+	}
+	data["interval"] = m.Interval
+	data["sample_every"] = m.SampleEvery
+	data["active"] = m.Active
+	data["status"] = m.Status
+	data["status_key"] = model.StatusKey(m.Status)
+	data["msg"] = m.Message
+	data["last_check"] = m.LastCheck
+	data["recentResults"] = s.getRecentResults(m.ID)
+	data["method"] = m.Method
+	data["body"] = m.Body
+	data["headers"] = m.Headers
+	data["timeout"] = m.Timeout
+	data["expected_status"] = m.ExpectedStatus
+
+	data["response_regex"] = m.ResponseRegex
+	data["form_data"] = m.FormData
+	data["follow_redirects"] = m.FollowRedirects
+	data["expected_redirect_status"] = m.ExpectedRedirectStatus
+	data["expected_final_url"] = m.ExpectedFinalURL
+	data["pre_hook"] = m.PreHook
+	data["post_hook"] = m.PostHook
+	data["expression"] = m.Expression
+	data["steps"] = m.Steps
+	data["proxy_url"] = monitor.MaskProxyURL(m.ProxyURL)
+	data["ip_version"] = m.IPVersion
+	data["basic_auth_user"] = m.BasicAuthUser
+	data["basic_auth_pass_set"] = m.BasicAuthPass != ""
+	data["token_url"] = m.OAuthTokenURL
+	data["client_id"] = m.OAuthClientID
+	data["scopes"] = m.OAuthScopes
+	data["client_secret_set"] = m.OAuthClientSecret != ""
+	data["client_cert"] = m.ClientCert
+	data["client_key_set"] = m.ClientKey != ""
+	data["max_offset_ms"] = m.MaxOffsetMs
+	data["ssh_host_key"] = m.SSHHostKey
+	data["ping_count"] = m.PingCount
+	data["regex_scan_bytes"] = m.RegexScanBytes
+	data["assert_on_empty_body"] = m.AssertOnEmptyBody
+	data["ping_size"] = m.PingSize
+	data["max_packet_loss"] = m.MaxPacketLoss
+	data["udp_payload"] = m.UDPPayload
+	data["udp_payload_format"] = m.UDPPayloadFormat
+	data["run_diagnostics"] = m.RunDiagnostics
+	data["public"] = m.Public
+	data["badge"] = m.Badge
+	data["public_visible"] = m.PublicVisible
+	data["webhook_url"] = m.WebhookURL
+	data["webhook_filter"] = m.WebhookFilter
+	data["webhook_enabled"] = m.WebhookEnabled
+	data["webhook_secret_set"] = m.WebhookSecret != ""
+	if full && m.Diagnostics != "" {
+		data["diagnostics"] = m.Diagnostics
+		data["diagnostics_at"] = m.DiagnosticsAt
+	}
+	drift := s.monitorService.DriftStats(m.ID)
+	data["drift_avg_ms"] = drift.AvgMs
+	data["drift_max_ms"] = drift.MaxMs
+	if m.Type == model.MonitorTypePush {
+		data["push_token"] = m.PushToken
+		data["push_secret_set"] = m.PushSecret != ""
+		data["last_push"] = m.LastPush
+		// 最近一小时被限流丢弃的上报次数，帮助用户发现上报频率配置错误的客户端
+		data["throttled_1h"] = s.pushThrottled.LastHour(m.PushToken)
+	}
+	return data
+}
+
+// setupFailureHeatmapHandler 设置 getFailureHeatmap 事件处理 - args: (monitorID, days)
+// 返回最近 days 天（默认 90）按 星期×小时 统计的 DOWN 次数，用于选择维护窗口；
+// monitorID 为 0 时合并统计全部监控项，只有不限范围的管理员可以使用
+func (s *Server) setupFailureHeatmapHandler(client *socket.Socket) {
+	requireAuth(client, "getFailureHeatmap", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": "monitorID is required"}}, nil)
+			return
+		}
+		if id == 0 && socketScope(client) != nil {
+			replyForbidden(client, args)
+			return
+		}
+		if id != 0 && !checkMonitorScope(client, id, args) {
+			return
+		}
+		days, _ := getArgAsFloat64(args, 1)
+		ack([]any{map[string]any{"ok": true, "heatmap": db.GetFailureHeatmap(id, heatmapDays(days), db.Location())}}, nil)
+	})
+}
+
+// heatmapDays 规范化热力图天数：未提供时默认 90 天，最多 365 天
+func heatmapDays(days float64) int {
+	if days <= 0 {
+		return db.DefaultHeatmapDays
+	}
+	return min(int(days), db.MaxHeatmapDays)
+}
+
+// exportMonitorConfig 导出全部监控项配置，分组以名称导出，导入到其他实例时按名称对应。
+// 默认不导出 Basic Auth 密码、OAuth client secret、客户端证书私钥、代理密码、webhook 密钥和 push 签名密钥，需显式指定 includeSecrets；
+// includeHistory 附带原始心跳（仅保留期内的），导入后会重建聚合
+func exportMonitorConfig(includeSecrets, includeHistory bool) ([]importedMonitor, error) {
+	var monitors []model.Monitor
+	if err := db.DB.Find(&monitors).Error; err != nil {
+		return nil, err
+	}
+	if !includeSecrets {
+		for i := range monitors {
+			monitors[i].BasicAuthPass = ""
+			monitors[i].OAuthClientSecret = ""
+			monitors[i].ClientKey = ""
+			monitors[i].ProxyURL = monitor.StripProxyPassword(monitors[i].ProxyURL)
+			monitors[i].WebhookSecret = ""
+			monitors[i].PushSecret = ""
+		}
+	}
+	groups, _ := db.MonitorGroups()
+	groupNames := monitorGroupNames(groups)
+	exported := make([]importedMonitor, len(monitors))
+	for i, m := range monitors {
+		exported[i].Monitor = m
+		exported[i].PublicVisible = &m.PublicVisible
+		exported[i].Group = groupNames[m.GroupID]
+		if includeHistory {
+			db.DB.Where("monitor_id = ? AND agent_id = 0", m.ID).Order("time").Find(&exported[i].Heartbeats)
+		}
+	}
+	return exported, nil
+}
+
+// exportMonitorFile 导出监控项（同 exportMonitorConfig）、通知规则及其自定义模板，以及触发规则与监控项的对应关系。
+// 不含密钥时通知规则去掉渠道令牌和密码（同通知列表）
+func exportMonitorFile(includeSecrets, includeHistory bool) (*monitorExport, error) {
+	monitors, err := exportMonitorConfig(includeSecrets, includeHistory)
+	if err != nil {
+		return nil, err
+	}
+	var rules []model.Notification
+	if err := db.DB.Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if !includeSecrets {
+		rules = redactNotifications(rules)
+	}
+	templates, err := db.CustomTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &monitorExport{
+		Monitors:      monitors,
+		Notifications: make([]exportedNotification, len(rules)),
+		Assignments:   []notificationAssignment{},
+	}
+	for i, n := range rules {
+		out.Notifications[i] = exportedNotification{Name: n.Name, Type: n.Type, Config: n.Config, Active: n.Active}
+		for _, t := range templates {
+			if t.RuleID == n.ID {
+				if out.Notifications[i].Templates == nil {
+					out.Notifications[i].Templates = make(map[string]string)
+				}
+				out.Notifications[i].Templates[t.Kind] = t.Source
+			}
+		}
+		if n.Type != "trigger" {
+			continue
+		}
+		var cfg map[string]any
+		if json.Unmarshal([]byte(n.Config), &cfg) == nil {
+			if target, _ := cfg["monitor_name"].(string); target != "" {
+				out.Assignments = append(out.Assignments, notificationAssignment{Notification: n.Name, Monitor: target})
+			}
+		}
+	}
+	return out, nil
 }
 
 func (s *Server) setupImportMonitorHandler(client *socket.Socket) {
@@ -83,75 +304,35 @@ func (s *Server) setupImportMonitorHandler(client *socket.Socket) {
 		if len(args) < 1 {
 			return
 		}
-		var monitorsInput []model.Monitor
 		jsonData, err := json.Marshal(args[0])
 		if err != nil {
 			client.Emit("error", map[string]any{"msg": "Invalid data format"})
 			return
 		}
-		if err := json.Unmarshal(jsonData, &monitorsInput); err != nil {
+		in, err := decodeMonitorImport(jsonData)
+		if err != nil {
 			client.Emit("error", map[string]any{"msg": "Invalid JSON format"})
 			return
 		}
 
-		importedCount := 0
-		skippedCount := 0
-		var skippedNames []string
-
-		for _, m := range monitorsInput {
-			if m.Name == "" || m.URL == "" {
-				continue
-			}
-			var count int64
-			db.DB.Model(&model.Monitor{}).Where("name = ?", m.Name).Count(&count)
-			if count > 0 {
-				skippedCount++
-				skippedNames = append(skippedNames, m.Name)
-				continue
-			}
-
-			newMonitor := model.Monitor{
-				Name: m.Name, URL: m.URL,
-				Type: func() model.MonitorType {
-					switch m.Type {
-					case model.MonitorTypeHTTP, model.MonitorTypePing, model.MonitorTypeTCP, model.MonitorTypeDNS:
-						return m.Type
-					default:
-						return model.MonitorTypeHTTP
-					}
-				}(),
-				Method: m.Method, Body: m.Body, Headers: m.Headers,
-				FormData: sanitizeFormData(m.FormData), Timeout: m.Timeout,
-				ExpectedStatus: m.ExpectedStatus, ResponseRegex: m.ResponseRegex,
-				FollowRedirects: m.FollowRedirects, Interval: m.Interval,
-				Active: m.Active, Weight: m.Weight,
-			}
-			if newMonitor.Interval < 10 {
-				newMonitor.Interval = 60
-			}
-			if newMonitor.Timeout < 1 {
-				newMonitor.Timeout = 10
-			}
-			if newMonitor.Method == "" {
-				newMonitor.Method = "GET"
-			}
-
-			if err := db.DB.Create(&newMonitor).Error; err == nil {
-				importedCount++
-				if newMonitor.Active == 1 {
-					s.monitorService.StartMonitor(&newMonitor)
-				}
+		if len(in.Monitors) == 0 && len(in.Notifications) == 0 {
+			if ack := getCallback(args); ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "No monitors to import"}}, nil)
 			}
+			return
 		}
 
-		if len(args) > 1 {
-			ack := args[1].(func([]any, error))
-			ack([]any{map[string]any{
-				"ok": true, "imported": importedCount,
-				"skipped": skippedCount, "skippedNames": skippedNames,
-			}}, nil)
+		// 导入在后台执行，立即返回任务 ID，进度通过 importProgress 事件推送给发起导入的连接
+		job := newImportJob(in)
+		heartbeats := 0
+		for _, m := range in.Monitors {
+			heartbeats += len(m.Heartbeats)
 		}
-		s.socketServer.To("public").Emit("updateMonitorList")
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": true, "job_id": job.ID, "total": job.Total, "heartbeats": heartbeats,
+				"notifications": len(in.Notifications)}}, nil)
+		}
+		go s.runImportJob(func(progress any) { client.Emit("importProgress", progress) }, job, in)
 	})
 }
 
@@ -164,59 +345,121 @@ func (s *Server) setupTestMonitorHandler(client *socket.Socket) {
 		if !ok {
 			return
 		}
-
-		method, _ := data["method"].(string)
-		if method == "" {
-			method = "GET"
-		}
-		body, _ := data["body"].(string)
-		headers, _ := data["headers"].(string)
-		timeout := 10
-		if t, ok := data["timeout"].(float64); ok {
-			timeout = int(t)
-		}
-		expectedStatus := 0
-		if st, ok := data["expected_status"].(float64); ok {
-			expectedStatus = int(st)
-		}
-		responseRegex, _ := data["response_regex"].(string)
-		responseRegex = convertJSONToRegex(responseRegex)
-		formData, _ := data["form_data"].(string)
-		followRedirects := true
-		if fr, ok := data["follow_redirects"].(bool); ok {
-			followRedirects = fr
+		// 已保存的凭据按 id 读取，限定范围的账号只能测试自己范围内的监控项
+		if id, ok := safeMapGetFloat64(data, "id"); ok && !checkMonitorScope(client, uint(id), args) {
+			return
 		}
 
-		m := model.Monitor{
-			URL: safeMapGetString(data, "url"), Type: model.MonitorType(safeMapGetString(data, "type")),
-			Method: method, Body: body, Headers: headers, Timeout: timeout,
-			ExpectedStatus: expectedStatus, ResponseRegex: responseRegex,
-			FormData: formData, FollowRedirects: followRedirects,
+		m := testMonitorFromData(data)
+
+		errMsg := validateSteps(&m)
+		var note string
+		if errMsg == "" {
+			note, errMsg = normalizeMonitorURL(&m)
+		}
+		if errMsg != "" {
+			if len(args) > 1 {
+				if ack, ok := args[1].(func([]any, error)); ok {
+					ack([]any{map[string]any{"ok": false, "msg": errMsg}}, nil)
+				}
+			}
+			return
 		}
 
 		var status int
 		var msg string
+		extra := make(map[string]any)
+		if note != "" {
+			extra["note"] = note
+			extra["normalized_url"] = m.URL
+		}
 		switch m.Type {
 		case model.MonitorTypeHTTP:
+			if errMsg := validateRegexScanBytes(m.RegexScanBytes); errMsg != "" {
+				msg = errMsg
+				break
+			}
 			status, msg = monitor.TestHTTP(m)
+			// 缓存响应，之后修改断言时用 testMonitorAssertions 重新计算，不必再次请求目标
+			id, _ := safeMapGetFloat64(data, "id")
+			storeTestResponse(testCacheKey(client, uint(id)), &testResponse{
+				Status: status, Body: msg, URL: m.URL, Method: m.Method, FetchedAt: time.Now(),
+			})
 		case model.MonitorTypePing:
-			st, m2, _ := monitor.CheckPing(m.URL, m.Timeout)
+			if errMsg := validatePingOptions(m.PingCount, m.PingSize, m.MaxPacketLoss); errMsg != "" {
+				msg = errMsg
+				break
+			}
+			st, m2, _ := monitor.CheckPing(m.URL, m.Timeout, monitor.PingOptions{
+				Count: m.PingCount, Size: m.PingSize, MaxLossPercent: m.MaxPacketLoss, IPVersion: m.IPVersion,
+			})
 			msg = m2
 			if st == model.StatusUp {
 				status = 200
 			}
 		case model.MonitorTypeTCP:
-			st, m2, _ := monitor.CheckTCP(m.URL, m.Timeout)
+			st, m2, _ := monitor.CheckTCP(m.URL, m.Timeout, m.IPVersion, m.ProxyURL)
 			msg = m2
 			if st == model.StatusUp {
 				status = 200
 			}
 		case model.MonitorTypeDNS:
-			st, m2 := monitor.CheckDNS(m.URL, m.Timeout)
+			st, m2 := monitor.CheckDNS(m.URL, m.Timeout, m.IPVersion)
 			msg = m2
 			if st == model.StatusUp {
 				status = 200
 			}
+		case model.MonitorTypeNTP:
+			// 返回实测偏移量，便于保存前验证阈值
+			result, err := monitor.QueryNTP(m.URL, m.Timeout)
+			if err == nil {
+				extra["offset_ms"] = result.OffsetMs()
+				extra["stratum"] = result.Stratum
+				extra["rtt_ms"] = result.RTT.Milliseconds()
+			}
+			st, m2 := monitor.EvaluateNTP(result, err, m.MaxOffsetMs)
+			msg = m2
+			if st == model.StatusUp {
+				status = 200
+			}
+		case model.MonitorTypeSSH:
+			// 始终获取主机公钥指纹，方便用户复制到预期指纹字段
+			result, err := monitor.QuerySSH(m.URL, m.Timeout, true)
+			if err == nil {
+				extra["banner"] = result.Banner
+				extra["host_key"] = result.Fingerprint
+				extra["host_key_type"] = result.HostKeyType
+			}
+			st, m2 := monitor.EvaluateSSH(result, err, m.ResponseRegex, m.SSHHostKey)
+			msg = m2
+			if st == model.StatusUp {
+				status = 200
+			}
+		case model.MonitorTypeUDP:
+			payload, err := monitor.ParseUDPPayload(m.UDPPayload, m.UDPPayloadFormat)
+			if err != nil {
+				msg = err.Error()
+				break
+			}
+			result, err := monitor.QueryUDP(m.URL, m.Timeout, payload)
+			if err == nil {
+				extra["reply_hex"] = hex.EncodeToString(result.Reply)
+				extra["rtt_ms"] = float64(result.RTT.Microseconds()) / 1000.0
+			}
+			st, m2 := monitor.EvaluateUDP(result, err, m.UDPPayloadFormat, m.ResponseRegex)
+			msg = m2
+			if st == model.StatusUp {
+				status = 200
+			}
+		case model.MonitorTypeMultiStep:
+			st, m2, _ := monitor.CheckSteps(m)
+			msg = m2
+			if st == model.StatusUp {
+				status = 200
+			}
+		case model.MonitorTypePush:
+			// push 监控是被动接收上报，无法主动测试
+			msg = "Push monitors receive reports via /api/push/<token>, nothing to test"
 		default:
 			msg = "Unknown monitor type"
 		}
@@ -226,11 +469,95 @@ func (s *Server) setupTestMonitorHandler(client *socket.Socket) {
 		}
 		if len(args) > 1 {
 			ack := args[1].(func([]any, error))
-			ack([]any{map[string]any{"ok": true, "status": status, "msg": msg}}, nil)
+			resp := map[string]any{"ok": true, "status": status, "msg": msg}
+			for k, v := range extra {
+				resp[k] = v
+			}
+			ack([]any{resp}, nil)
 		}
 	})
 }
 
+// testMonitorFromData 按 testMonitor 的表单数据构造待测试的监控项，编辑时未提交的凭据从已保存的监控项读取
+func testMonitorFromData(data map[string]any) model.Monitor {
+	method, _ := data["method"].(string)
+	if method == "" {
+		method = "GET"
+	}
+	body, _ := data["body"].(string)
+	headers, _ := data["headers"].(string)
+	timeout := 10
+	if t, ok := data["timeout"].(float64); ok {
+		timeout = int(t)
+	}
+	expectedStatus := 0
+	if st, ok := data["expected_status"].(float64); ok {
+		expectedStatus = int(st)
+	}
+	responseRegex, _ := data["response_regex"].(string)
+	responseRegex = convertJSONToRegex(responseRegex)
+	formData, _ := data["form_data"].(string)
+	followRedirects := true
+	if fr, ok := data["follow_redirects"].(bool); ok {
+		followRedirects = fr
+	}
+	maxOffsetMs, _ := safeMapGetFloat64(data, "max_offset_ms")
+	redirectStatus, _ := safeMapGetFloat64(data, "expected_redirect_status")
+
+	m := model.Monitor{
+		URL: safeMapGetString(data, "url"), Type: model.MonitorType(safeMapGetString(data, "type")),
+		Method: method, Body: body, Headers: headers, Timeout: timeout,
+		ExpectedStatus: expectedStatus, ResponseRegex: responseRegex,
+		FormData: formData, FollowRedirects: followRedirects,
+		ExpectedRedirectStatus: int(redirectStatus), ExpectedFinalURL: strings.TrimSpace(safeMapGetString(data, "expected_final_url")),
+		MaxOffsetMs: int(maxOffsetMs), SSHHostKey: safeMapGetString(data, "ssh_host_key"),
+		UDPPayload: safeMapGetString(data, "udp_payload"), UDPPayloadFormat: safeMapGetString(data, "udp_payload_format"),
+		BasicAuthUser: safeMapGetString(data, "basic_auth_user"), BasicAuthPass: safeMapGetString(data, "basic_auth_pass"),
+		OAuthTokenURL: strings.TrimSpace(safeMapGetString(data, "token_url")), OAuthClientID: safeMapGetString(data, "client_id"),
+		OAuthClientSecret: safeMapGetString(data, "client_secret"), OAuthScopes: safeMapGetString(data, "scopes"),
+		ClientCert: strings.TrimSpace(safeMapGetString(data, "client_cert")), ClientKey: strings.TrimSpace(safeMapGetString(data, "client_key")),
+		PreHook: strings.TrimSpace(safeMapGetString(data, "pre_hook")), PostHook: strings.TrimSpace(safeMapGetString(data, "post_hook")),
+		ProxyURL: strings.TrimSpace(safeMapGetString(data, "proxy_url")), Steps: stepsArg(data),
+	}
+	// 编辑已有监控项时前端拿不到密码、client secret、私钥和代理密码，未填写则沿用已保存的值；
+	// 新建前测试时证书和私钥可直接随请求提交
+	_, passSent := data["basic_auth_pass"]
+	_, secretSent := data["client_secret"]
+	_, keySent := data["client_key"]
+	if (!passSent && m.BasicAuthUser != "") || (!secretSent && m.OAuthTokenURL != "") || (!keySent && m.ClientCert != "") || m.ProxyURL != "" {
+		if id, ok := safeMapGetFloat64(data, "id"); ok {
+			var saved model.Monitor
+			if db.DB.Select("basic_auth_pass", "oauth_client_secret", "client_key", "proxy_url").First(&saved, uint(id)).Error == nil {
+				m.ProxyURL = monitor.RestoreProxyPassword(m.ProxyURL, saved.ProxyURL)
+				if !passSent {
+					m.BasicAuthPass = saved.BasicAuthPass
+				}
+				if !secretSent {
+					m.OAuthClientSecret = saved.OAuthClientSecret
+				}
+				if !keySent {
+					m.ClientKey = saved.ClientKey
+				}
+			}
+		}
+	}
+	m.IPVersion, _ = normalizeIPVersion(safeMapGetString(data, "ip_version"))
+	if v, ok := safeMapGetFloat64(data, "ping_count"); ok {
+		m.PingCount = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "regex_scan_bytes"); ok {
+		m.RegexScanBytes = int64(v)
+	}
+	m.AssertOnEmptyBody, _ = data["assert_on_empty_body"].(bool)
+	if v, ok := safeMapGetFloat64(data, "ping_size"); ok {
+		m.PingSize = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "max_packet_loss"); ok {
+		m.MaxPacketLoss = int(v)
+	}
+	return m
+}
+
 // convertJSONToRegex 将 JSON 格式输入转换为正则表达式
 func convertJSONToRegex(responseRegex string) string {
 	if responseRegex != "" && json.Valid([]byte(responseRegex)) {
@@ -1,10 +1,19 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
 	"ping-go/db"
 	"ping-go/model"
+	"ping-go/monitor"
+	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
 )
 
 // setupAddMonitorHandler 设置添加监控项的处理器
@@ -17,78 +26,175 @@ func (s *Server) setupAddMonitorHandler(client *socket.Socket) {
 		if !ok {
 			return
 		}
+		replyMonitorSave(client, args, s.addMonitor(data, socketScope(client)))
+	})
+}
 
-		method, _ := data["method"].(string)
-		if method == "" {
-			method = "GET"
-		}
-		body, _ := data["body"].(string)
-		headers, _ := data["headers"].(string)
-		timeout := 10
-		if t, ok := data["timeout"].(float64); ok {
-			timeout = int(t)
-		}
-		expectedStatus := 0
-		if st, ok := data["expected_status"].(float64); ok {
-			expectedStatus = int(st)
-		}
-		responseRegex, _ := data["response_regex"].(string)
-		responseRegex = convertJSONToRegex(responseRegex)
-		formData, _ := data["form_data"].(string)
-		followRedirects := true
-		if fr, ok := data["follow_redirects"].(bool); ok {
-			followRedirects = fr
-		}
+// replyMonitorSave 回复添加或编辑监控项的结果；没有回调时失败原因以通知的形式发送
+func replyMonitorSave(client *socket.Socket, args []any, reply map[string]any) {
+	if ack := getCallback(args); ack != nil {
+		ack([]any{reply}, nil)
+		return
+	}
+	if ok, _ := reply["ok"].(bool); !ok {
+		client.Emit("notification", map[string]any{"message": reply["msg"], "type": "error"})
+	}
+}
 
-		name := safeMapGetString(data, "name")
-		if name == "" {
-			client.Emit("notification", map[string]any{"message": "Name is required", "type": "error"})
-			return
-		}
-		url := safeMapGetString(data, "url")
-		mType := safeMapGetString(data, "type")
-		intervalFloat, _ := safeMapGetFloat64(data, "interval")
-		interval := int(intervalFloat)
+// addMonitor 按表单数据创建监控项并开始调度，返回回复内容（ok、msg，成功时包含 monitorID 等）。
+// scope 为操作者的标签范围，nil 表示不限制；Socket 事件 "add" 和 REST 接口共用
+func (s *Server) addMonitor(data map[string]any, scope []string) map[string]any {
+	method, _ := data["method"].(string)
+	if method == "" {
+		method = "GET"
+	}
+	body, _ := data["body"].(string)
+	headers, _ := data["headers"].(string)
+	timeout := 10
+	if t, ok := data["timeout"].(float64); ok {
+		timeout = int(t)
+	}
+	expectedStatus := 0
+	if st, ok := data["expected_status"].(float64); ok {
+		expectedStatus = int(st)
+	}
+	responseRegex, _ := data["response_regex"].(string)
+	responseRegex = convertJSONToRegex(responseRegex)
+	formData, _ := data["form_data"].(string)
+	followRedirects := true
+	if fr, ok := data["follow_redirects"].(bool); ok {
+		followRedirects = fr
+	}
 
-		m := model.Monitor{
-			Name: name, URL: url, Type: model.MonitorType(mType), Interval: interval,
-			Method: method, Body: body, Headers: headers, Timeout: timeout,
-			ExpectedStatus: expectedStatus, ResponseRegex: responseRegex,
-			FormData: formData, FollowRedirects: followRedirects,
-			Status: model.StatusPending, Active: 1,
-		}
+	name := safeMapGetString(data, "name")
+	if name == "" {
+		return map[string]any{"ok": false, "msg": "Name is required"}
+	}
+	url := safeMapGetString(data, "url")
+	mType := safeMapGetString(data, "type")
+	intervalFloat, _ := safeMapGetFloat64(data, "interval")
+	interval := int(intervalFloat)
+	maxOffsetMs, _ := safeMapGetFloat64(data, "max_offset_ms")
+	sampleEvery, _ := safeMapGetFloat64(data, "sample_every")
+	redirectStatus, _ := safeMapGetFloat64(data, "expected_redirect_status")
 
-		if m.Interval < 20 {
-			m.Interval = 20
-		}
+	m := model.Monitor{
+		Name: name, URL: url, Type: model.MonitorType(mType), Interval: interval,
+		Method: method, Body: body, Headers: headers, Timeout: timeout,
+		ExpectedStatus: expectedStatus, ResponseRegex: responseRegex,
+		FormData: formData, FollowRedirects: followRedirects,
+		ExpectedRedirectStatus: int(redirectStatus), ExpectedFinalURL: strings.TrimSpace(safeMapGetString(data, "expected_final_url")),
+		MaxOffsetMs: int(maxOffsetMs), SSHHostKey: safeMapGetString(data, "ssh_host_key"),
+		UDPPayload: safeMapGetString(data, "udp_payload"), UDPPayloadFormat: safeMapGetString(data, "udp_payload_format"),
+		BasicAuthUser: safeMapGetString(data, "basic_auth_user"), BasicAuthPass: safeMapGetString(data, "basic_auth_pass"),
+		OAuthTokenURL: strings.TrimSpace(safeMapGetString(data, "token_url")), OAuthClientID: safeMapGetString(data, "client_id"),
+		OAuthClientSecret: safeMapGetString(data, "client_secret"), OAuthScopes: safeMapGetString(data, "scopes"),
+		ClientCert: strings.TrimSpace(safeMapGetString(data, "client_cert")), ClientKey: strings.TrimSpace(safeMapGetString(data, "client_key")),
+		PreHook: strings.TrimSpace(safeMapGetString(data, "pre_hook")), PostHook: strings.TrimSpace(safeMapGetString(data, "post_hook")),
+		ProxyURL: strings.TrimSpace(safeMapGetString(data, "proxy_url")), Expression: strings.TrimSpace(safeMapGetString(data, "expression")),
+		Steps: stepsArg(data), SampleEvery: max(int(sampleEvery), 0), Status: model.StatusPending, Active: 1,
+		Tags: model.NormalizeTags(safeMapGetString(data, "tags")),
+	}
+	if rd, ok := data["run_diagnostics"].(bool); ok {
+		m.RunDiagnostics = rd
+	}
+	if pub, ok := data["public"].(bool); ok {
+		m.Public = pub
+	}
+	if badge, ok := data["badge"].(bool); ok {
+		m.Badge = badge
+	}
+	m.PublicVisible = true
+	if visible, ok := data["public_visible"].(bool); ok {
+		m.PublicVisible = visible
+	}
 
-		var count int64
-		db.DB.Model(&model.Monitor{}).Where("name = ?", name).Count(&count)
-		if count > 0 {
-			for _, arg := range args {
-				if ack, ok := arg.(func([]any, error)); ok {
-					ack([]any{map[string]any{"ok": false, "msg": "监控项名称已存在，请使用唯一名称"}}, nil)
-					return
-				}
-			}
-			return
+	if m.Interval < 20 {
+		m.Interval = 20
+	}
+	if m.Type == model.MonitorTypePush {
+		m.PushToken = generateToken()
+		m.PushSecret = strings.TrimSpace(safeMapGetString(data, "push_secret"))
+		if errMsg := validatePushSecret(m.PushSecret); errMsg != "" {
+			return map[string]any{"ok": false, "msg": errMsg}
 		}
-
-		if err := db.DB.Create(&m).Error; err != nil {
-			client.Emit("notification", map[string]any{"message": "Failed to add monitor: " + err.Error(), "type": "error"})
-			return
+	}
+	if v, ok := safeMapGetFloat64(data, "ping_count"); ok {
+		m.PingCount = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "regex_scan_bytes"); ok {
+		m.RegexScanBytes = int64(v)
+	}
+	m.AssertOnEmptyBody, _ = data["assert_on_empty_body"].(bool)
+	if v, ok := safeMapGetFloat64(data, "ping_size"); ok {
+		m.PingSize = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "max_packet_loss"); ok {
+		m.MaxPacketLoss = int(v)
+	}
+	if field, errMsg := applyAppearanceArgs(&m, data); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg, "field": field}
+	}
+	if errMsg := validateClientCert(m.ClientCert, m.ClientKey); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateRedirectStatus(m.ExpectedRedirectStatus); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateHooks(m.PreHook, m.PostHook); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	applyWebhookArgs(&m, data)
+	if errMsg := validateWebhook(m); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateProxyURL(m.Type, m.ProxyURL); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateExpression(m.Type, m.Expression); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateTagScope(scope, m.Tags); errMsg != "" {
+		return map[string]any{"ok": false, "code": 403, "msg": errMsg}
+	}
+	if errMsg := validatePingOptions(m.PingCount, m.PingSize, m.MaxPacketLoss); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateRegexScanBytes(m.RegexScanBytes); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	ipVersion, ok := normalizeIPVersion(safeMapGetString(data, "ip_version"))
+	if !ok {
+		return map[string]any{"ok": false, "msg": "IP 版本必须是 auto、ipv4 或 ipv6"}
+	}
+	m.IPVersion = ipVersion
+	if m.Type == model.MonitorTypeUDP {
+		if _, err := monitor.ParseUDPPayload(m.UDPPayload, m.UDPPayloadFormat); err != nil {
+			return map[string]any{"ok": false, "msg": err.Error()}
 		}
+	}
+	if errMsg := validateSteps(&m); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	note, errMsg := normalizeMonitorURL(&m)
+	if errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
 
-		s.monitorService.StartMonitor(&m)
+	var count int64
+	db.DB.Model(&model.Monitor{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return map[string]any{"ok": false, "msg": "监控项名称已存在，请使用唯一名称"}
+	}
 
-		for _, arg := range args {
-			if ack, ok := arg.(func([]any, error)); ok {
-				ack([]any{map[string]any{"ok": true, "msg": "Added successfully", "monitorID": m.ID}}, nil)
-				break
-			}
-		}
-		s.broadcastMonitorList()
-	})
+	if err := createMonitor(db.DB, &m); err != nil {
+		return map[string]any{"ok": false, "msg": "Failed to add monitor: " + err.Error()}
+	}
+
+	s.monitorService.StartMonitor(&m)
+
+	s.broadcastMonitorList()
+	return map[string]any{"ok": true, "msg": "Added successfully", "note": note, "monitorID": m.ID, "pushToken": m.PushToken}
 }
 
 // setupEditMonitorHandler 设置编辑监控项的处理器
@@ -101,107 +207,253 @@ func (s *Server) setupEditMonitorHandler(client *socket.Socket) {
 		if !ok {
 			return
 		}
+		replyMonitorSave(client, args, s.editMonitor(data, socketScope(client)))
+	})
+}
 
-		idFloat, ok := safeMapGetFloat64(data, "id")
-		if !ok {
-			return
-		}
-		id := uint(idFloat)
-		var m model.Monitor
-		if err := db.DB.First(&m, id).Error; err != nil {
-			return
-		}
+// editMonitor 按表单数据（必须包含 id 和读取到的 version）修改监控项并重新调度，返回回复内容；
+// 失败时 code 为 403（超出范围）、404、409（版本冲突）或 428（缺少 version）
+func (s *Server) editMonitor(data map[string]any, scope []string) map[string]any {
+	idFloat, ok := safeMapGetFloat64(data, "id")
+	if !ok {
+		return map[string]any{"ok": false, "msg": "id is required"}
+	}
+	id := uint(idFloat)
+	var m model.Monitor
+	if err := db.DB.First(&m, id).Error; err != nil {
+		return map[string]any{"ok": false, "code": 404, "msg": "Monitor not found"}
+	}
+	if !m.InScope(scope) {
+		return map[string]any{"ok": false, "code": 403, "msg": "Forbidden"}
+	}
 
-		oldActive := m.Active
-		newName := safeMapGetString(data, "name")
-		if newName == "" {
-			client.Emit("notification", map[string]any{"message": "Name is required", "type": "error"})
-			return
-		}
+	// 乐观锁：必须回传 getMonitor 返回的 version，不一致说明已被他人修改
+	baseVersion, ok := safeMapGetFloat64(data, "version")
+	if !ok {
+		return versionRequiredReply()
+	}
+	base := int(baseVersion)
+	if base != m.Version {
+		current := s.monitorDetail(&m, false)
+		return conflictReply(current, changedSince(current, data))
+	}
 
-		if m.Name != newName {
-			var count int64
-			db.DB.Model(&model.Monitor{}).Where("name = ? AND id != ?", newName, id).Count(&count)
-			if count > 0 {
-				for _, arg := range args {
-					if ack, ok := arg.(func([]any, error)); ok {
-						ack([]any{map[string]any{"ok": false, "msg": "监控项名称已存在，请使用唯一名称"}}, nil)
-						return
-					}
-				}
-				return
-			}
+	oldActive := m.Active
+	newName := safeMapGetString(data, "name")
+	if newName == "" {
+		return map[string]any{"ok": false, "msg": "Name is required"}
+	}
+
+	if m.Name != newName {
+		var count int64
+		db.DB.Model(&model.Monitor{}).Where("name = ? AND id != ?", newName, id).Count(&count)
+		if count > 0 {
+			return map[string]any{"ok": false, "msg": "监控项名称已存在，请使用唯一名称"}
 		}
+	}
 
-		m.Name = newName
-		m.URL = safeMapGetString(data, "url")
-		m.Type = model.MonitorType(safeMapGetString(data, "type"))
+	m.Name = newName
+	m.URL = safeMapGetString(data, "url")
+	m.Type = model.MonitorType(safeMapGetString(data, "type"))
 
-		if intervalFloat, ok := safeMapGetFloat64(data, "interval"); ok {
-			m.Interval = int(intervalFloat)
-		} else {
-			m.Interval = 60
-		}
-		if active, ok := safeMapGetFloat64(data, "active"); ok {
-			m.Active = int(active)
-		}
+	if intervalFloat, ok := safeMapGetFloat64(data, "interval"); ok {
+		m.Interval = int(intervalFloat)
+	} else {
+		m.Interval = 60
+	}
+	if active, ok := safeMapGetFloat64(data, "active"); ok {
+		m.Active = int(active)
+	}
+	if sampleEvery, ok := safeMapGetFloat64(data, "sample_every"); ok {
+		m.SampleEvery = max(int(sampleEvery), 0)
+	}
 
-		if method := safeMapGetString(data, "method"); method != "" {
-			m.Method = method
-		} else {
-			m.Method = "GET"
-		}
-		m.Body = safeMapGetString(data, "body")
-		m.Headers = safeMapGetString(data, "headers")
-		if t, ok := safeMapGetFloat64(data, "timeout"); ok {
-			m.Timeout = int(t)
-		} else {
-			m.Timeout = 10
+	if method := safeMapGetString(data, "method"); method != "" {
+		m.Method = method
+	} else {
+		m.Method = "GET"
+	}
+	m.Body = safeMapGetString(data, "body")
+	m.Headers = safeMapGetString(data, "headers")
+	// 凭据只在请求中携带对应字段时修改，传空字符串即清除
+	if v, ok := data["basic_auth_user"].(string); ok {
+		m.BasicAuthUser = v
+	}
+	if v, ok := data["basic_auth_pass"].(string); ok {
+		m.BasicAuthPass = v
+	}
+	m.OAuthTokenURL = strings.TrimSpace(safeMapGetString(data, "token_url"))
+	m.OAuthClientID = safeMapGetString(data, "client_id")
+	m.OAuthScopes = safeMapGetString(data, "scopes")
+	if v, ok := data["client_secret"].(string); ok {
+		m.OAuthClientSecret = v
+	}
+	m.ClientCert = strings.TrimSpace(safeMapGetString(data, "client_cert"))
+	if v, ok := data["client_key"].(string); ok {
+		m.ClientKey = strings.TrimSpace(v)
+	}
+	if m.ClientCert == "" {
+		m.ClientKey = ""
+	}
+	if t, ok := safeMapGetFloat64(data, "timeout"); ok {
+		m.Timeout = int(t)
+	} else {
+		m.Timeout = 10
+	}
+	if st, ok := safeMapGetFloat64(data, "expected_status"); ok {
+		m.ExpectedStatus = int(st)
+	} else {
+		m.ExpectedStatus = 0
+	}
+	m.ResponseRegex = convertJSONToRegex(safeMapGetString(data, "response_regex"))
+	m.FormData = safeMapGetString(data, "form_data")
+	if fr, ok := data["follow_redirects"].(bool); ok {
+		m.FollowRedirects = fr
+	} else {
+		m.FollowRedirects = true
+	}
+	if rd, ok := data["run_diagnostics"].(bool); ok {
+		m.RunDiagnostics = rd
+	}
+	if pub, ok := data["public"].(bool); ok {
+		m.Public = pub
+	}
+	if badge, ok := data["badge"].(bool); ok {
+		m.Badge = badge
+	}
+	if visible, ok := data["public_visible"].(bool); ok {
+		m.PublicVisible = visible
+	}
+	m.ExpectedRedirectStatus = 0
+	if v, ok := safeMapGetFloat64(data, "expected_redirect_status"); ok {
+		m.ExpectedRedirectStatus = int(v)
+	}
+	m.ExpectedFinalURL = strings.TrimSpace(safeMapGetString(data, "expected_final_url"))
+	m.PreHook = strings.TrimSpace(safeMapGetString(data, "pre_hook"))
+	m.PostHook = strings.TrimSpace(safeMapGetString(data, "post_hook"))
+	// getMonitor 返回的代理地址密码已脱敏，未修改时沿用已保存的密码
+	m.ProxyURL = monitor.RestoreProxyPassword(strings.TrimSpace(safeMapGetString(data, "proxy_url")), m.ProxyURL)
+	m.Expression = strings.TrimSpace(safeMapGetString(data, "expression"))
+	m.Steps = stepsArg(data)
+	if tags, ok := data["tags"].(string); ok {
+		m.Tags = model.NormalizeTags(tags)
+	}
+	if maxOffsetMs, ok := safeMapGetFloat64(data, "max_offset_ms"); ok {
+		m.MaxOffsetMs = int(maxOffsetMs)
+	} else {
+		m.MaxOffsetMs = 0
+	}
+	m.SSHHostKey = safeMapGetString(data, "ssh_host_key")
+	m.PingCount, m.PingSize, m.MaxPacketLoss, m.RegexScanBytes = 0, 0, 0, 0
+	if v, ok := safeMapGetFloat64(data, "ping_count"); ok {
+		m.PingCount = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "regex_scan_bytes"); ok {
+		m.RegexScanBytes = int64(v)
+	}
+	m.AssertOnEmptyBody, _ = data["assert_on_empty_body"].(bool)
+	if v, ok := safeMapGetFloat64(data, "ping_size"); ok {
+		m.PingSize = int(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "max_packet_loss"); ok {
+		m.MaxPacketLoss = int(v)
+	}
+	if field, errMsg := applyAppearanceArgs(&m, data); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg, "field": field}
+	}
+	if errMsg := validateClientCert(m.ClientCert, m.ClientKey); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateRedirectStatus(m.ExpectedRedirectStatus); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateHooks(m.PreHook, m.PostHook); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	applyWebhookArgs(&m, data)
+	if errMsg := validateWebhook(m); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateProxyURL(m.Type, m.ProxyURL); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateExpression(m.Type, m.Expression); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateTagScope(scope, m.Tags); errMsg != "" {
+		return map[string]any{"ok": false, "code": 403, "msg": errMsg}
+	}
+	if errMsg := validatePingOptions(m.PingCount, m.PingSize, m.MaxPacketLoss); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if errMsg := validateRegexScanBytes(m.RegexScanBytes); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	ipVersion, ok := normalizeIPVersion(safeMapGetString(data, "ip_version"))
+	if !ok {
+		return map[string]any{"ok": false, "msg": "IP 版本必须是 auto、ipv4 或 ipv6"}
+	}
+	m.IPVersion = ipVersion
+	m.UDPPayload = safeMapGetString(data, "udp_payload")
+	m.UDPPayloadFormat = safeMapGetString(data, "udp_payload_format")
+	if m.Type == model.MonitorTypeUDP {
+		if _, err := monitor.ParseUDPPayload(m.UDPPayload, m.UDPPayloadFormat); err != nil {
+			return map[string]any{"ok": false, "msg": err.Error()}
 		}
-		if st, ok := safeMapGetFloat64(data, "expected_status"); ok {
-			m.ExpectedStatus = int(st)
-		} else {
-			m.ExpectedStatus = 0
+	}
+	if errMsg := validateSteps(&m); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	note, errMsg := normalizeMonitorURL(&m)
+	if errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+	if m.Interval < 20 {
+		m.Interval = 20
+	}
+	if m.Type == model.MonitorTypePush {
+		// 重置 token 后旧的上报地址立即失效
+		if reset, _ := data["reset_push_token"].(bool); reset || m.PushToken == "" {
+			m.PushToken = generateToken()
 		}
-		m.ResponseRegex = convertJSONToRegex(safeMapGetString(data, "response_regex"))
-		m.FormData = safeMapGetString(data, "form_data")
-		if fr, ok := data["follow_redirects"].(bool); ok {
-			m.FollowRedirects = fr
-		} else {
-			m.FollowRedirects = true
-		}
-		if m.Interval < 20 {
-			m.Interval = 20
+		// 未提交 push_secret 时保留原密钥；提交空值表示不再校验签名，已有的普通 token 保持可用
+		if v, ok := data["push_secret"].(string); ok {
+			m.PushSecret = strings.TrimSpace(v)
+			if errMsg := validatePushSecret(m.PushSecret); errMsg != "" {
+				return map[string]any{"ok": false, "msg": errMsg}
+			}
 		}
+	}
 
-		if err := db.DB.Save(&m).Error; err != nil {
-			client.Emit("notification", map[string]any{"message": "Failed to edit monitor: " + err.Error(), "type": "error"})
-			return
+	m.Version = base + 1
+	if err := db.SaveVersioned(&m, base); err != nil {
+		if errors.Is(err, db.ErrVersionConflict) {
+			var current model.Monitor
+			if db.DB.First(&current, id).Error == nil {
+				detail := s.monitorDetail(&current, false)
+				return conflictReply(detail, changedSince(detail, data))
+			}
 		}
+		return map[string]any{"ok": false, "msg": "Failed to edit monitor: " + err.Error()}
+	}
 
-		if oldActive != m.Active {
-			if m.Active == 0 {
-				s.monitorService.StopMonitor(m.ID)
-			} else {
-				s.monitorService.StartMonitor(&m)
-			}
-			// Reset notification states so rules re-arm from the fresh start
-			s.monitorService.ResetNotificationStateByMonitor(m.ID)
-		} else if m.Active == 1 {
+	if oldActive != m.Active {
+		if m.Active == 0 {
 			s.monitorService.StopMonitor(m.ID)
+		} else {
 			s.monitorService.StartMonitor(&m)
-			// Also reset if it's currently running and modified
-			s.monitorService.ResetNotificationStateByMonitor(m.ID)
 		}
+		// Reset notification states so rules re-arm from the fresh start
+		s.monitorService.ResetNotificationStateByMonitor(m.ID)
+	} else if m.Active == 1 {
+		s.monitorService.StopMonitor(m.ID)
+		s.monitorService.StartMonitor(&m)
+		// Also reset if it's currently running and modified
+		s.monitorService.ResetNotificationStateByMonitor(m.ID)
+	}
 
-		for _, arg := range args {
-			if ack, ok := arg.(func([]any, error)); ok {
-				ack([]any{map[string]any{"ok": true, "msg": "Saved successfully", "monitorID": m.ID}}, nil)
-				break
-			}
-		}
-		s.broadcastMonitorList()
-	})
+	s.broadcastMonitorList()
+	return map[string]any{"ok": true, "msg": "Saved successfully", "note": note, "monitorID": m.ID, "pushToken": m.PushToken}
 }
 
 // setupToggleActiveHandler 设置切换监控项启用状态的处理器
@@ -220,15 +472,16 @@ func (s *Server) setupToggleActiveHandler(client *socket.Socket) {
 		}
 		id := uint(idFloat)
 		newActive := int(activeFloat)
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
 
 		var m model.Monitor
 		if err := db.DB.First(&m, id).Error; err != nil {
 			return
 		}
 
-		oldActive := m.Active
-		m.Active = newActive
-		if err := db.DB.Save(&m).Error; err != nil {
+		if err := s.setMonitorActive(&m, newActive); err != nil {
 			for _, arg := range args {
 				if ack, ok := arg.(func([]any, error)); ok {
 					ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
@@ -238,42 +491,62 @@ func (s *Server) setupToggleActiveHandler(client *socket.Socket) {
 			return
 		}
 
-		if oldActive != newActive {
-			if newActive == 0 {
-				s.monitorService.StopMonitor(m.ID)
-			} else {
-				s.monitorService.StartMonitor(&m)
-			}
-			// Reset notification memory states for this monitor across all rules
-			s.monitorService.ResetNotificationStateByMonitor(m.ID)
-		}
-
 		for _, arg := range args {
 			if ack, ok := arg.(func([]any, error)); ok {
 				ack([]any{map[string]any{"ok": true}}, nil)
 				break
 			}
 		}
-		s.broadcastMonitorList()
 	})
 }
 
+// setMonitorActive 启用（1）或暂停（0）监控项并相应地开始或停止调度，然后广播新的监控列表
+func (s *Server) setMonitorActive(m *model.Monitor, active int) error {
+	oldActive := m.Active
+	// 只更新启用状态并递增版本号，避免覆盖并发的编辑，同时让打开着旧版本的编辑表单保存时得到冲突提示
+	err := db.DB.Model(m).Updates(map[string]any{"active": active, "version": gorm.Expr("version + 1")}).Error
+	if err != nil {
+		return err
+	}
+	m.Active = active
+	m.Version++
+
+	if oldActive != active {
+		if active == 0 {
+			s.monitorService.StopMonitor(m.ID)
+		} else {
+			s.monitorService.StartMonitor(m)
+		}
+		// Reset notification memory states for this monitor across all rules
+		s.monitorService.ResetNotificationStateByMonitor(m.ID)
+	}
+	s.broadcastMonitorList()
+	return nil
+}
+
 // setupDeleteMonitorHandler 设置删除监控项的处理器
 func (s *Server) setupDeleteMonitorHandler(client *socket.Socket) {
 	requireAuth(client, "deleteMonitor", func(args ...any) {
 		if len(args) < 1 {
 			return
 		}
-		id := uint(args[0].(float64))
-
-		if err := db.DB.Delete(&model.Monitor{}, id).Error; err != nil {
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+		if !checkMonitorScope(client, id, args) {
 			return
 		}
 
-		s.monitorService.StopMonitor(id)
-		db.DB.Where("monitor_id = ?", id).Delete(&model.Heartbeat{})
-		db.DB.Where("monitor_id = ?", id).Delete(&model.HeartbeatHourly{})
-		db.DB.Where("monitor_id = ?", id).Delete(&model.HeartbeatDaily{})
+		if err := s.removeMonitor(id); err != nil {
+			for _, arg := range args {
+				if ack, ok := arg.(func([]any, error)); ok {
+					ack([]any{map[string]any{"ok": false, "msg": "Failed to delete monitor: " + err.Error()}}, nil)
+					return
+				}
+			}
+			return
+		}
 
 		for _, arg := range args {
 			if ack, ok := arg.(func([]any, error)); ok {
@@ -281,6 +554,483 @@ func (s *Server) setupDeleteMonitorHandler(client *socket.Socket) {
 				break
 			}
 		}
-		s.broadcastMonitorList()
 	})
 }
+
+// removeMonitor 停止调度并删除监控项及其数据，然后广播新的监控列表
+func (s *Server) removeMonitor(id uint) error {
+	// 先停止调度，避免删除过程中仍有新的检查结果写入
+	s.monitorService.StopMonitor(id)
+	if err := db.DeleteMonitor(id); err != nil {
+		return err
+	}
+	s.monitorService.ResetNotificationStateByMonitor(id)
+	s.broadcastMonitorList()
+	return nil
+}
+
+// setupSetTagColorHandler 设置 setTagColor 事件处理 - args: ({tag, color})
+// 把带有该标签的所有监控项设为同一颜色，color 为空字符串时清除；限定范围的账号只能设置自己范围内的标签
+func (s *Server) setupSetTagColorHandler(client *socket.Socket) {
+	requireAuth(client, "setTagColor", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		tag := model.NormalizeTags(safeMapGetString(data, "tag"))
+		if tag == "" || strings.Contains(tag, ",") {
+			ack([]any{map[string]any{"ok": false, "msg": "需要指定一个标签", "field": "tag"}}, nil)
+			return
+		}
+		color, ok := normalizeMonitorColor(safeMapGetString(data, "color"))
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "颜色必须是 #rgb 或 #rrggbb 格式的十六进制颜色", "field": "color"}}, nil)
+			return
+		}
+		if errMsg := validateTagScope(socketScope(client), tag); errMsg != "" {
+			ack([]any{map[string]any{"ok": false, "code": 403, "msg": errMsg}}, nil)
+			return
+		}
+
+		var monitors []model.Monitor
+		if err := db.DB.Select("id", "tags").Find(&monitors).Error; err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		var ids []uint
+		for _, m := range monitors {
+			if m.InScope([]string{tag}) {
+				ids = append(ids, m.ID)
+			}
+		}
+		if len(ids) > 0 {
+			// 与 toggleActive 一样递增版本号，打开着旧版本的编辑表单保存时会得到冲突提示
+			err := db.DB.Model(&model.Monitor{}).Where("id IN ?", ids).
+				Updates(map[string]any{"color": color, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+				return
+			}
+			s.monitorService.UpdateColor(ids, color)
+			db.RecordAudit(socketActor(client), "monitor.color", "tag:"+tag,
+				fmt.Sprintf("color %q on %d monitors", color, len(ids)))
+		}
+
+		ack([]any{map[string]any{"ok": true, "updated": len(ids)}}, nil)
+		if len(ids) > 0 {
+			s.broadcastMonitorList()
+		}
+	})
+}
+
+// setupValidateMonitorHandler 设置监控项预检的处理器：按保存时的规则校验表单但不写入数据库，
+// 同时返回断言表达式可用的变量和函数，供前端展示
+func (s *Server) setupValidateMonitorHandler(client *socket.Socket) {
+	requireAuth(client, "validateMonitor", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+
+		redirectStatus, _ := safeMapGetFloat64(data, "expected_redirect_status")
+		m := model.Monitor{
+			URL: safeMapGetString(data, "url"), Type: model.MonitorType(safeMapGetString(data, "type")),
+			ExpectedRedirectStatus: int(redirectStatus), Tags: model.NormalizeTags(safeMapGetString(data, "tags")),
+			PreHook: strings.TrimSpace(safeMapGetString(data, "pre_hook")), PostHook: strings.TrimSpace(safeMapGetString(data, "post_hook")),
+			ProxyURL: strings.TrimSpace(safeMapGetString(data, "proxy_url")), Expression: strings.TrimSpace(safeMapGetString(data, "expression")),
+			Steps: stepsArg(data),
+		}
+		if v, ok := safeMapGetFloat64(data, "ping_count"); ok {
+			m.PingCount = int(v)
+		}
+		if v, ok := safeMapGetFloat64(data, "regex_scan_bytes"); ok {
+			m.RegexScanBytes = int64(v)
+		}
+		m.AssertOnEmptyBody, _ = data["assert_on_empty_body"].(bool)
+		if v, ok := safeMapGetFloat64(data, "ping_size"); ok {
+			m.PingSize = int(v)
+		}
+		if v, ok := safeMapGetFloat64(data, "max_packet_loss"); ok {
+			m.MaxPacketLoss = int(v)
+		}
+		applyWebhookArgs(&m, data)
+
+		field, errMsg := applyAppearanceArgs(&m, data)
+		if errMsg == "" {
+			errMsg = validateRedirectStatus(m.ExpectedRedirectStatus)
+		}
+		for _, check := range []func() string{
+			func() string { return validateHooks(m.PreHook, m.PostHook) },
+			func() string { return validateProxyURL(m.Type, m.ProxyURL) },
+			func() string { return validateExpression(m.Type, m.Expression) },
+			func() string { return validateSteps(&m) },
+			func() string { return validateTagScope(socketScope(client), m.Tags) },
+			func() string { return validatePingOptions(m.PingCount, m.PingSize, m.MaxPacketLoss) },
+			func() string { return validateRegexScanBytes(m.RegexScanBytes) },
+			func() string { return validateWebhook(m) },
+		} {
+			if errMsg != "" {
+				break
+			}
+			errMsg = check()
+		}
+		var note string
+		if errMsg == "" {
+			note, errMsg = normalizeMonitorURL(&m)
+		}
+
+		result := map[string]any{
+			"ok":                   errMsg == "",
+			"msg":                  errMsg,
+			"note":                 note,
+			"field":                field,
+			"expression_variables": monitor.ExpressionVariables,
+			"expression_functions": monitor.ExpressionFunctions,
+		}
+		ack([]any{result}, nil)
+	})
+}
+
+// setupImportCurlHandler 设置 importCurl 事件处理：把粘贴的 curl 命令解析为预填的 HTTP 监控项，不保存
+// 参数为命令字符串或 {command}，不支持的参数会被忽略并在 warnings 中返回
+func (s *Server) setupImportCurlHandler(client *socket.Socket) {
+	requireAuth(client, "importCurl", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		command, _ := args[0].(string)
+		if data, ok := args[0].(map[string]any); ok {
+			command = safeMapGetString(data, "command")
+		}
+
+		req, warnings, err := monitor.ParseCurl(command)
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error(), "warnings": warnings}}, nil)
+			return
+		}
+		if warnings == nil {
+			warnings = []string{}
+		}
+
+		headers := ""
+		if len(req.Headers) > 0 {
+			b, _ := json.Marshal(req.Headers)
+			headers = string(b)
+		}
+		name := req.URL
+		if u, err := url.Parse(req.URL); err == nil && u.Host != "" {
+			name = u.Hostname()
+		}
+		timeout := req.Timeout
+		if timeout <= 0 {
+			timeout = 10
+		}
+
+		ack([]any{map[string]any{
+			"ok": true,
+			"monitor": map[string]any{
+				"name":             name,
+				"type":             model.MonitorTypeHTTP,
+				"url":              req.URL,
+				"method":           req.Method,
+				"headers":          headers,
+				"body":             req.Body,
+				"basic_auth_user":  req.BasicAuthUser,
+				"basic_auth_pass":  req.BasicAuthPass,
+				"follow_redirects": req.FollowRedirects,
+				"timeout":          timeout,
+			},
+			"warnings": warnings,
+		}}, nil)
+	})
+}
+
+// validateClientCert 校验 mTLS 客户端证书与私钥，都为空表示不使用客户端证书
+func validateClientCert(certPEM, keyPEM string) string {
+	if certPEM == "" && keyPEM == "" {
+		return ""
+	}
+	if certPEM == "" || keyPEM == "" {
+		return "客户端证书和私钥需要同时填写"
+	}
+	if _, err := monitor.ParseClientCertificate(certPEM, keyPEM); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// validateRedirectStatus 校验期望的重定向状态码，0 表示不校验
+func validateRedirectStatus(status int) string {
+	if status != 0 && (status < 300 || status > 399) {
+		return "期望的重定向状态码必须在 300-399 之间"
+	}
+	return ""
+}
+
+// validateTagScope 限定范围的账号添加或编辑的监控项必须至少带有一个范围内的标签，否则保存后自己也无法访问
+func validateTagScope(scope []string, tags string) string {
+	if scope == nil {
+		return ""
+	}
+	if !(model.Monitor{Tags: tags}).InScope(scope) {
+		return "监控项标签必须包含以下之一: " + strings.Join(scope, ", ")
+	}
+	return ""
+}
+
+// validateExpression 编译断言表达式，空值表示不使用；只有 HTTP 监控支持
+func validateExpression(mType model.MonitorType, source string) string {
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+	if mType != model.MonitorTypeHTTP {
+		return "只有 HTTP 监控支持断言表达式"
+	}
+	if _, err := monitor.CompileExpression(source); err != nil {
+		return "表达式无效: " + err.Error()
+	}
+	return ""
+}
+
+// stepsArg 读取多步骤配置，前端可以提交 JSON 字符串或步骤数组
+func stepsArg(data map[string]any) string {
+	switch v := data["steps"].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		if len(v) == 0 {
+			return ""
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	return ""
+}
+
+// validateSteps 校验多步骤监控的步骤配置；未填写 URL 时使用第一步的地址作为展示地址。
+// 其他类型不使用步骤，提交的值直接清空
+func validateSteps(m *model.Monitor) string {
+	if m.Type != model.MonitorTypeMultiStep {
+		m.Steps = ""
+		return ""
+	}
+	steps, err := monitor.ParseSteps(m.Steps)
+	if err != nil {
+		return "步骤配置无效: " + err.Error()
+	}
+	if strings.TrimSpace(m.URL) == "" {
+		m.URL = steps[0].URL
+	}
+	return ""
+}
+
+// validateProxyURL 校验代理地址，空值表示直连；TCP 监控只支持 socks5 代理
+func validateProxyURL(mType model.MonitorType, raw string) string {
+	if raw == "" {
+		return ""
+	}
+	if mType != model.MonitorTypeHTTP && mType != model.MonitorTypeMultiStep && mType != model.MonitorTypeTCP {
+		return "只有 HTTP、多步骤和 TCP 监控支持代理"
+	}
+	u, err := monitor.ParseProxyURL(raw)
+	if err != nil {
+		return "代理地址无效: " + err.Error()
+	}
+	if mType == model.MonitorTypeTCP && u.Scheme != "socks5" {
+		return "TCP 监控只支持 socks5:// 代理"
+	}
+	return ""
+}
+
+// validateHooks 校验前置/后置钩子配置，空值表示不使用钩子
+func validateHooks(pre, post string) string {
+	if _, err := monitor.ParseHook(pre); err != nil {
+		return "前置钩子配置错误: " + err.Error()
+	}
+	if _, err := monitor.ParseHook(post); err != nil {
+		return "后置钩子配置错误: " + err.Error()
+	}
+	return ""
+}
+
+// validatePingOptions 校验 ping 发包数量、包大小与丢包阈值，0 表示使用默认值；返回空字符串表示校验通过
+func validatePingOptions(count, size, maxLoss int) string {
+	if count != 0 && (count < 1 || count > monitor.MaxPingCount) {
+		return fmt.Sprintf("发包数量必须在 1-%d 之间", monitor.MaxPingCount)
+	}
+	if size < 0 || size > monitor.MaxPingSize {
+		return fmt.Sprintf("包大小必须在 0-%d 字节之间", monitor.MaxPingSize)
+	}
+	if maxLoss < 0 || maxLoss > 100 {
+		return "丢包率阈值必须在 0-100 之间"
+	}
+	return ""
+}
+
+// validateRegexScanBytes 校验响应正则的扫描上限，0 表示使用默认值；上限不能超过 monitor.max_body_bytes
+func validateRegexScanBytes(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	if max := monitor.MaxBodyBytes(); n < monitor.MinRegexScanBytes || n > max {
+		return fmt.Sprintf("正则扫描上限必须在 %d-%d 字节之间", monitor.MinRegexScanBytes, max)
+	}
+	return ""
+}
+
+// normalizeIPVersion 规范化 IP 版本设置，空值视为 auto；第二个返回值表示输入是否合法
+func normalizeIPVersion(v string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", model.IPVersionAuto:
+		return model.IPVersionAuto, true
+	case model.IPVersion4:
+		return model.IPVersion4, true
+	case model.IPVersion6:
+		return model.IPVersion6, true
+	default:
+		return model.IPVersionAuto, false
+	}
+}
+
+// normalizeMonitorURL 规范化监控地址，URL 中的 user:pass 移到 Basic Auth 字段。
+// 返回对地址所做修改的提示，以及错误信息（为空表示成功）
+func normalizeMonitorURL(m *model.Monitor) (string, string) {
+	n, err := monitor.NormalizeURL(m.Type, m.URL)
+	if err != nil {
+		return "", err.Error()
+	}
+	if n.HasUserinfo {
+		if (m.BasicAuthUser != "" || m.BasicAuthPass != "") && (m.BasicAuthUser != n.User || m.BasicAuthPass != n.Password) {
+			return "", "URL 中的凭据与 Basic Auth 设置不一致，请删除 URL 中的 user:pass@ 部分，只在 Basic Auth 中填写"
+		}
+		m.BasicAuthUser, m.BasicAuthPass = n.User, n.Password
+		n.Notes = append(n.Notes, "URL 中的凭据已移到 Basic Auth 设置")
+	}
+	m.URL = n.URL
+	return strings.Join(n.Notes, "；"), ""
+}
+
+// applyWebhookArgs 从表单读取监控项 webhook 配置。密钥只在请求中携带该字段时修改，传空字符串即清除
+func applyWebhookArgs(m *model.Monitor, data map[string]any) {
+	m.WebhookURL = strings.TrimSpace(safeMapGetString(data, "webhook_url"))
+	m.WebhookFilter = strings.TrimSpace(safeMapGetString(data, "webhook_filter"))
+	if m.WebhookFilter == "" {
+		m.WebhookFilter = model.WebhookFilterAll
+	}
+	if v, ok := data["webhook_secret"].(string); ok {
+		m.WebhookSecret = strings.TrimSpace(v)
+	}
+	if v, ok := data["webhook_enabled"].(bool); ok {
+		m.WebhookEnabled = v
+	}
+}
+
+// validateWebhook 校验监控项 webhook 配置：地址必须是 http(s) URL，启用时不能为空
+func validateWebhook(m model.Monitor) string {
+	switch m.WebhookFilter {
+	case "", model.WebhookFilterAll, model.WebhookFilterFailures, model.WebhookFilterTransitions:
+	default:
+		return "webhook_filter 只能是 all、failures 或 transitions"
+	}
+	if m.WebhookURL == "" {
+		if m.WebhookEnabled {
+			return "启用 webhook 需要填写 webhook_url"
+		}
+		return ""
+	}
+	u, err := url.Parse(m.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "webhook_url 必须是 http:// 或 https:// 开头的完整地址"
+	}
+	if m.WebhookSecret != "" && len(m.WebhookSecret) < minSecretLength {
+		return fmt.Sprintf("webhook 签名密钥长度至少为 %d 个字符", minSecretLength)
+	}
+	return ""
+}
+
+// validatePushSecret 校验 push 签名密钥，空值表示不启用签名
+func validatePushSecret(secret string) string {
+	if secret != "" && len(secret) < minSecretLength {
+		return fmt.Sprintf("签名密钥长度至少为 %d 个字符", minSecretLength)
+	}
+	return ""
+}
+
+// maxIconRunes 单个 emoji 图标允许的最大字符数（含肤色修饰、变体选择符和 ZWJ 组合）
+const maxIconRunes = 8
+
+// applyAppearanceArgs 从表单读取监控项颜色、图标和分组，只在请求中携带对应字段时修改，传空字符串（分组传 0）即清除。
+// 校验失败时返回出错的字段名和错误消息
+func applyAppearanceArgs(m *model.Monitor, data map[string]any) (string, string) {
+	if v, ok := data["color"].(string); ok {
+		color, ok := normalizeMonitorColor(v)
+		if !ok {
+			return "color", "颜色必须是 #rgb 或 #rrggbb 格式的十六进制颜色"
+		}
+		m.Color = color
+	}
+	if v, ok := data["icon"].(string); ok {
+		icon := strings.TrimSpace(v)
+		if !validMonitorIcon(icon) {
+			return "icon", "图标必须是 " + strings.Join(model.MonitorIcons, "、") + " 之一或单个 emoji"
+		}
+		m.Icon = icon
+	}
+	if v, ok := safeMapGetFloat64(data, "group_id"); ok {
+		groupID := uint(max(v, 0))
+		if !db.MonitorGroupExists(groupID) {
+			return "group_id", fmt.Sprintf("分组 %d 不存在", groupID)
+		}
+		m.GroupID = groupID
+	}
+	return "", ""
+}
+
+// normalizeMonitorColor 把 #rgb / #rrggbb 规范化为小写的 #rrggbb，空字符串表示不设置颜色
+func normalizeMonitorColor(v string) (string, bool) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return "", true
+	}
+	if !strings.HasPrefix(v, "#") || (len(v) != 4 && len(v) != 7) {
+		return "", false
+	}
+	for _, c := range v[1:] {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", false
+		}
+	}
+	if len(v) == 4 {
+		v = string([]byte{'#', v[1], v[1], v[2], v[2], v[3], v[3]})
+	}
+	return v, true
+}
+
+// validMonitorIcon 图标为空、MonitorIcons 中的名称或单个 emoji（以符号开头，只包含符号和组合用的修饰字符）
+func validMonitorIcon(icon string) bool {
+	if icon == "" || slices.Contains(model.MonitorIcons, icon) {
+		return true
+	}
+	runes := []rune(icon)
+	if len(runes) > maxIconRunes || !unicode.Is(unicode.So, runes[0]) {
+		return false
+	}
+	for _, r := range runes {
+		if !unicode.In(r, unicode.So, unicode.Sk, unicode.Mn, unicode.Me) && r != '\u200d' {
+			return false
+		}
+	}
+	return true
+}
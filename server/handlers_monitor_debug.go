@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/monitor"
+	"time"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// setupMonitorDebugHandlers 设置监控调试模式（实时查看单个监控项的检查细节）的处理器
+func (s *Server) setupMonitorDebugHandlers(client *socket.Socket) {
+	// Handle "startMonitorDebug" - args: id, durationSeconds
+	requireAuth(client, "startMonitorDebug", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "Invalid monitor id"}}, nil)
+			}
+			return
+		}
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
+
+		var m model.Monitor
+		if err := db.DB.First(&m, id).Error; err != nil {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "Monitor not found"}}, nil)
+			}
+			return
+		}
+
+		duration := monitor.MaxDebugDuration
+		if seconds, err := getArgAsFloat64(args, 1); err == nil && seconds > 0 {
+			duration = time.Duration(seconds) * time.Second
+		}
+		if duration > monitor.MaxDebugDuration {
+			duration = monitor.MaxDebugDuration
+		}
+
+		err = s.monitorService.StartDebug(id, string(client.Id()), duration,
+			func(info *monitor.DebugInfo) {
+				client.Emit("monitorDebug", info)
+			},
+			func() {
+				client.Emit("monitorDebugStopped", id, "expired")
+			},
+		)
+		if err != nil {
+			msg := err.Error()
+			if errors.Is(err, monitor.ErrDebugSessionActive) {
+				msg = "该监控项已有正在进行的调试会话"
+			}
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+
+		if ack != nil {
+			ack([]any{map[string]any{
+				"ok":        true,
+				"monitorID": id,
+				"expiresAt": time.Now().Add(duration).Format(time.RFC3339),
+			}}, nil)
+		}
+	})
+
+	// Handle "stopMonitorDebug" - args: id
+	requireAuth(client, "stopMonitorDebug", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil {
+			return
+		}
+		if !checkMonitorScope(client, id, args) {
+			return
+		}
+		stopped := s.monitorService.StopDebug(id, string(client.Id()))
+		if stopped {
+			client.Emit("monitorDebugStopped", id, "stopped")
+		}
+		if ack != nil {
+			ack([]any{map[string]any{"ok": stopped}}, nil)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
+)
+
+// maxGroupNameRunes 分组名称的最大字符数
+const maxGroupNameRunes = 64
+
+// 监控分组审计动作
+const (
+	auditActionGroupSave   = "monitor_group.save"
+	auditActionGroupDelete = "monitor_group.delete"
+	auditActionGroupAssign = "monitor_group.assign"
+)
+
+// monitorGroupNames 分组 ID 到名称的映射，用于在监控列表中附带分组名称
+func monitorGroupNames(groups []model.MonitorGroup) map[uint]string {
+	names := make(map[uint]string, len(groups))
+	for _, g := range groups {
+		names[g.ID] = g.Name
+	}
+	return names
+}
+
+// monitorGroupSummaries 计算各分组的汇总状态（成员中最严重的状态，暂停的监控项不参与），按分组顺序返回。
+// scope 不为空时只统计范围内的监控项，并且只返回包含这些监控项的分组
+func monitorGroupSummaries(groups []model.MonitorGroup, monitors []model.Monitor, scope []string) []map[string]any {
+	statuses := make(map[uint][]int)
+	members := make(map[uint]int)
+	for _, m := range monitors {
+		if m.GroupID == 0 || !m.InScope(scope) {
+			continue
+		}
+		members[m.GroupID]++
+		if m.Active != 0 {
+			statuses[m.GroupID] = append(statuses[m.GroupID], m.Status)
+		}
+	}
+	out := make([]map[string]any, 0, len(groups))
+	for _, g := range groups {
+		if len(scope) > 0 && members[g.ID] == 0 {
+			continue
+		}
+		status := model.WorstStatus(statuses[g.ID])
+		out = append(out, map[string]any{
+			"id":         g.ID,
+			"name":       g.Name,
+			"weight":     g.Weight,
+			"monitors":   members[g.ID],
+			"status":     status,
+			"status_key": model.StatusKey(status),
+		})
+	}
+	return out
+}
+
+// parseMonitorGroupArgs 读取并校验分组名称和权重，weight 省略时为默认值 2000
+func parseMonitorGroupArgs(data map[string]any) (model.MonitorGroup, string) {
+	group := model.MonitorGroup{Name: strings.TrimSpace(safeMapGetString(data, "name")), Weight: 2000}
+	if v, ok := safeMapGetFloat64(data, "id"); ok && v > 0 {
+		group.ID = uint(v)
+	}
+	if v, ok := safeMapGetFloat64(data, "weight"); ok {
+		group.Weight = int(v)
+	}
+	if group.Name == "" {
+		return group, "分组名称不能为空"
+	}
+	if utf8.RuneCountInString(group.Name) > maxGroupNameRunes {
+		return group, fmt.Sprintf("分组名称不能超过 %d 个字符", maxGroupNameRunes)
+	}
+	return group, ""
+}
+
+// setupMonitorGroupHandlers 设置监控分组相关的 Socket.IO 事件处理器
+func (s *Server) setupMonitorGroupHandlers(client *socket.Socket) {
+	// Handle "getMonitorGroups"
+	// 返回分组及其汇总状态；限定范围的账号只能看到包含其范围内监控项的分组
+	requireAuth(client, "getMonitorGroups", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		groups, err := db.MonitorGroups()
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		var monitors []model.Monitor
+		db.DB.Select("id", "group_id", "tags", "active", "status").Find(&monitors)
+		ack([]any{map[string]any{"ok": true, "groups": monitorGroupSummaries(groups, monitors, socketScope(client))}}, nil)
+	})
+
+	// Handle "addMonitorGroup" / "editMonitorGroup" - args: ({id, name, weight})
+	// addMonitorGroup 忽略 id；editMonitorGroup 用于重命名和调整排序
+	for _, event := range []string{"addMonitorGroup", "editMonitorGroup"} {
+		requireAuth(client, event, func(args ...any) {
+			ack := getCallback(args)
+			if len(args) < 1 || ack == nil {
+				return
+			}
+			data, ok := args[0].(map[string]any)
+			if !ok {
+				ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+				return
+			}
+			group, errMsg := parseMonitorGroupArgs(data)
+			if event == "addMonitorGroup" {
+				group.ID = 0
+			} else if group.ID == 0 && errMsg == "" {
+				errMsg = "id is required"
+			}
+			if errMsg != "" {
+				ack([]any{map[string]any{"ok": false, "msg": errMsg}}, nil)
+				return
+			}
+			if err := db.SaveMonitorGroup(&group); err != nil {
+				msg := err.Error()
+				switch {
+				case errors.Is(err, db.ErrGroupNameTaken):
+					msg = fmt.Sprintf("分组名称 %q 已存在", group.Name)
+				case errors.Is(err, gorm.ErrRecordNotFound):
+					msg = fmt.Sprintf("分组 %d 不存在", group.ID)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+				return
+			}
+			clearStatusPageCache()
+			db.RecordAudit(socketActor(client), auditActionGroupSave, fmt.Sprintf("monitor_group:%d", group.ID),
+				fmt.Sprintf("name=%s weight=%d", group.Name, group.Weight))
+			ack([]any{map[string]any{"ok": true, "msg": "Group saved", "group": group}}, nil)
+			s.broadcastMonitorList()
+		})
+	}
+
+	// Handle "deleteMonitorGroup" - args: (id)
+	// 只删除分组，其中的监控项变为未分组
+	requireAuth(client, "deleteMonitorGroup", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "id is required"}}, nil)
+			}
+			return
+		}
+		moved, err := db.DeleteMonitorGroup(id)
+		if err != nil {
+			if ack != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("分组 %d 不存在", id)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+		clearStatusPageCache()
+		db.RecordAudit(socketActor(client), auditActionGroupDelete, fmt.Sprintf("monitor_group:%d", id),
+			fmt.Sprintf("unassigned %d monitors", moved))
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "msg": "Group deleted", "unassigned": moved}}, nil)
+		}
+		s.broadcastMonitorList()
+	})
+
+	// Handle "assignMonitorGroup" - args: ({group_id, monitor_ids})
+	// group_id 为 0 时把监控项移出分组
+	requireAuth(client, "assignMonitorGroup", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		var groupID uint
+		if v, ok := safeMapGetFloat64(data, "group_id"); ok && v > 0 {
+			groupID = uint(v)
+		}
+		var monitorIDs []uint
+		if list, ok := data["monitor_ids"].([]any); ok {
+			for _, v := range list {
+				if f, ok := v.(float64); ok && f > 0 {
+					monitorIDs = append(monitorIDs, uint(f))
+				}
+			}
+		}
+		if len(monitorIDs) == 0 {
+			ack([]any{map[string]any{"ok": false, "msg": "monitor_ids is required"}}, nil)
+			return
+		}
+		updated, err := db.AssignMonitorGroup(groupID, monitorIDs)
+		if err != nil {
+			msg := err.Error()
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				msg = fmt.Sprintf("分组 %d 不存在", groupID)
+			}
+			ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			return
+		}
+		clearStatusPageCache()
+		db.RecordAudit(socketActor(client), auditActionGroupAssign, fmt.Sprintf("monitor_group:%d", groupID),
+			fmt.Sprintf("monitors=%v updated=%d", monitorIDs, updated))
+		ack([]any{map[string]any{"ok": true, "msg": "Monitors assigned", "updated": updated}}, nil)
+		s.broadcastMonitorList()
+	})
+}
@@ -1,13 +1,22 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"ping-go/config"
 	"ping-go/db"
 	"ping-go/model"
+	"ping-go/monitor"
 	"ping-go/notification"
+	"ping-go/pkg/i18n"
+	"strings"
+	"time"
 
 	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
 )
 
 // setupNotificationHandlers 设置通知相关的 Socket.IO 事件处理器
@@ -16,7 +25,7 @@ func (s *Server) setupNotificationHandlers(client *socket.Socket) {
 	requireAuth(client, "getNotificationList", func(args ...any) {
 		var notifications []model.Notification
 		db.DB.Find(&notifications)
-		client.Emit("notificationList", notifications)
+		client.Emit("notificationList", redactNotifications(notifications))
 	})
 
 	// Handle "addNotification"
@@ -30,33 +39,10 @@ func (s *Server) setupNotificationHandlers(client *socket.Socket) {
 			fmt.Printf("addNotification: invalid data format from %s\n", client.Id())
 			return
 		}
-
-		name, _ := data["name"].(string)
-		ntype, _ := data["type"].(string)
-
-		configBytes, _ := json.Marshal(data)
-
-		n := model.Notification{
-			Name:   name,
-			Type:   ntype,
-			Config: string(configBytes),
-			Active: true,
-		}
-		db.DB.Create(&n)
-
-		if len(args) > 1 {
-			ack := args[1].(func([]any, error))
-			ack([]any{map[string]any{
-				"ok":  true,
-				"msg": "Notification added",
-				"id":  n.ID,
-			}}, nil)
+		reply := s.addNotificationRule(data)
+		if ack := getCallback(args); ack != nil {
+			ack([]any{reply}, nil)
 		}
-
-		// Broadcast updated list
-		var notifications []model.Notification
-		db.DB.Find(&notifications)
-		s.socketServer.To("public").Emit("notificationList", notifications)
 	})
 
 	// Handle "editNotification"
@@ -68,56 +54,10 @@ func (s *Server) setupNotificationHandlers(client *socket.Socket) {
 		if !ok {
 			return
 		}
-
-		idVal, ok := data["id"]
-		if !ok {
-			return
-		}
-		idFloat, err := getFloat64(idVal)
-		if err != nil {
-			return
-		}
-		id := uint(idFloat)
-
-		var n model.Notification
-		if err := db.DB.First(&n, id).Error; err != nil {
-			if len(args) > 1 {
-				ack := args[1].(func([]any, error))
-				ack([]any{map[string]any{
-					"ok":  false,
-					"msg": "Notification not found",
-				}}, nil)
-			}
-			return
+		reply := s.editNotificationRule(data)
+		if ack := getCallback(args); ack != nil {
+			ack([]any{reply}, nil)
 		}
-
-		name, _ := data["name"].(string)
-		ntype, _ := data["type"].(string)
-
-		// Remove the id from data to avoid it being stored in config if desired,
-		// or just marshal the whole thing as config.
-		configBytes, _ := json.Marshal(data)
-
-		n.Name = name
-		n.Type = ntype
-		n.Config = string(configBytes)
-		db.DB.Save(&n)
-
-		// Reset memory state so it re-arms immediately
-		s.monitorService.ResetNotificationState(n.ID)
-
-		if len(args) > 1 {
-			ack := args[1].(func([]any, error))
-			ack([]any{map[string]any{
-				"ok":  true,
-				"msg": "Notification updated",
-			}}, nil)
-		}
-
-		// Broadcast updated list
-		var notifications []model.Notification
-		db.DB.Find(&notifications)
-		s.socketServer.To("public").Emit("notificationList", notifications)
 	})
 
 	// Handle "deleteNotification"
@@ -129,20 +69,14 @@ func (s *Server) setupNotificationHandlers(client *socket.Socket) {
 		if err != nil {
 			return
 		}
-		db.DB.Delete(&model.Notification{}, id)
+		s.deleteNotificationRule(id)
 
-		if len(args) > 1 {
-			ack := args[1].(func([]any, error))
+		if ack := getCallback(args); ack != nil {
 			ack([]any{map[string]any{
 				"ok":  true,
 				"msg": "Deleted successfully",
 			}}, nil)
 		}
-
-		// Broadcast updated list
-		var notifications []model.Notification
-		db.DB.Find(&notifications)
-		s.socketServer.To("public").Emit("notificationList", notifications)
 	})
 
 	// Handle "toggleNotification"
@@ -159,19 +93,7 @@ func (s *Server) setupNotificationHandlers(client *socket.Socket) {
 		if err := db.DB.First(&n, id).Error; err != nil {
 			return
 		}
-
-		n.Active = !n.Active
-		db.DB.Save(&n)
-
-		// If turning ON, reset memory state so it re-arms immediately
-		if n.Active {
-			s.monitorService.ResetNotificationState(n.ID)
-		}
-
-		// Broadcast updated list
-		var notifications []model.Notification
-		db.DB.Find(&notifications)
-		s.socketServer.To("public").Emit("notificationList", notifications)
+		s.setNotificationActive(&n, !n.Active)
 	})
 
 	// Handle "testNotification"
@@ -186,40 +108,481 @@ func (s *Server) setupNotificationHandlers(client *socket.Socket) {
 			return
 		}
 
-		// For now, only handle email via Resend
-		if t, ok := data["type"].(string); ok && t == "email" {
-			// Try to get recipient from data
-			recipient, _ := data["resendRecipientEmail"].(string)
-			if recipient == "" {
-				recipient, _ = data["recipientEmail"].(string) // fallback
+		// 旧版表单的邮件测试使用 resendRecipientEmail / recipientEmail 字段
+		if t, _ := data["type"].(string); t == "email" {
+			if _, ok := data["email"]; !ok {
+				recipient, _ := data["resendRecipientEmail"].(string)
+				if recipient == "" {
+					recipient, _ = data["recipientEmail"].(string)
+				}
+				data["email"] = recipient
 			}
+		}
 
-			if recipient != "" {
-				err := notification.SendEmail([]string{recipient}, "Test Notification", "This is a test notification from ping-go.")
-				if len(args) > 1 {
-					ack := args[1].(func([]any, error))
-					if err == nil {
-						ack([]any{map[string]any{
-							"ok":  true,
-							"msg": "Test email sent",
-						}}, nil)
-					} else {
-						ack([]any{map[string]any{
-							"ok":  false,
-							"msg": err.Error(),
-						}}, nil)
+		// 用表单中的配置发送一条测试消息（编辑时未修改的令牌和密码从已保存的规则读取）
+		channel, _ := data["channel"].(string)
+		var ruleID uint
+		if id, ok := safeMapGetFloat64(data, "id"); ok {
+			var n model.Notification
+			if db.DB.First(&n, uint(id)).Error == nil {
+				ruleID = n.ID
+				var saved map[string]any
+				json.Unmarshal([]byte(n.Config), &saved)
+				for _, key := range notificationSecretKeys {
+					if _, sent := data[key]; !sent && saved[key] != nil {
+						data[key] = saved[key]
 					}
 				}
-				return
 			}
 		}
 
-		if len(args) > 1 {
-			ack := args[1].(func([]any, error))
-			ack([]any{map[string]any{
-				"ok":  false,
-				"msg": "Unsupported notification type or missing recipient",
-			}}, nil)
+		msg, err := sendTestNotification(channel, ruleID, data)
+		if ack := getCallback(args); ack != nil {
+			if err != nil {
+				ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			} else {
+				ack([]any{map[string]any{"ok": true, "msg": msg}}, nil)
+			}
 		}
 	})
 }
+
+// testNotificationTimeout 发送测试消息的超时时间（PagerDuty、Opsgenie 需要发送两次请求）
+const testNotificationTimeout = 60 * time.Second
+
+// testMonitorName 测试状态通知中虚拟监控项的名称
+const testMonitorName = "PingGo Test Monitor"
+
+// sendTestNotification 校验配置后通过渠道发送测试消息，结果写入发送记录（类型为 test），返回成功时的提示；ruleID 为编辑中的规则，新规则为 0。
+// 触发规则模拟一次 DOWN→UP（两条状态通知，使用规则的模板和 redact_details），定时报告发送一份示例日报，渠道返回的错误原样返回
+func sendTestNotification(channel string, ruleID uint, data map[string]any) (string, error) {
+	p, err := notification.Lookup(channel)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	if err := p.ValidateConfig(raw); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), testNotificationTimeout)
+	defer cancel()
+	now := time.Now().In(db.Location())
+	lang := safeMapGetString(data, "language")
+	if !i18n.Supported(lang) {
+		lang = db.EmailLanguage()
+	}
+	base := notification.Event{
+		Link:   config.Get().DashboardURL(),
+		Time:   now,
+		Config: raw,
+	}
+
+	switch ntype, _ := data["type"].(string); ntype {
+	case "trigger":
+		redact, _ := data["redact_details"].(bool)
+		for _, ev := range testStatusEvents(base, ruleID, lang, redact) {
+			if _, err := monitor.SendRecorded(ctx, p.Name(), ruleID, ev, 0); err != nil {
+				return "", fmt.Errorf("%s: %w", ev.Subject, err)
+			}
+		}
+		return fmt.Sprintf("Test DOWN and UP notifications for %q sent", testMonitorName), nil
+	case "schedule":
+		if !notification.SupportsReports(p.Name()) {
+			return "", fmt.Errorf("定时报告不支持通知渠道 %q", p.Name())
+		}
+		ev := base
+		ev.Kind, ev.Test = notification.EventDailyReport, true
+		ev.Report = notification.DailyReportFixture(lang)
+		ev.Report.Date, ev.Report.SiteName = now.Format("2006-01-02"), db.SiteName()
+		ev.Subject = "[Test] " + monitor.NotifySubject(lang, "notify.subject.report", ev.Report.Date)
+		ev.Template = db.NotificationTemplate(notification.TemplateDailyReport, ruleID)
+		if _, err := monitor.SendRecorded(ctx, p.Name(), ruleID, ev, 0); err != nil {
+			return "", err
+		}
+		return "Sample daily report sent", nil
+	}
+
+	// 未指定规则类型（旧版邮件测试）：只发送一条简单的测试消息
+	ev := base
+	ev.Kind = notification.EventTest
+	ev.Subject = "Test Notification"
+	ev.Status = notification.StatusChangeData{Color: db.StatusMeta(model.StatusUp).Color, DateTime: now.Format("2006-01-02 15:04:05"), SiteName: db.SiteName()}
+	if _, err := monitor.SendRecorded(ctx, p.Name(), ruleID, ev, 0); err != nil {
+		return "", err
+	}
+	return "Test notification sent", nil
+}
+
+// testStatusEvents 测试用的一次 DOWN→UP：虚拟监控项 3 分钟前宕机、现在恢复，经过与真实通知相同的模板和渠道格式化，文案使用 lang 语言
+func testStatusEvents(base notification.Event, ruleID uint, lang string, redact bool) []notification.Event {
+	downAt := base.Time.Add(-3 * time.Minute)
+	down := notification.StatusChangeData{
+		Name:       testMonitorName,
+		URL:        "https://example.com/health",
+		OldStatus:  "UP",
+		NewStatus:  "DOWN",
+		Message:    "Test notification: simulated failure",
+		Color:      db.StatusMeta(model.StatusDown).Color,
+		StatusText: i18n.T(lang, "notify.title.down") + i18n.T(lang, "notify.title.test"),
+		DateTime:   downAt.Format("2006-01-02 15:04:05"),
+		Condition:  "test notification",
+		Lang:       lang,
+		SiteName:   db.SiteName(),
+	}
+	up := down
+	up.OldStatus, up.NewStatus = "DOWN", "UP"
+	up.Message = "Test notification: simulated recovery"
+	up.Color = db.StatusMeta(model.StatusUp).Color
+	up.StatusText = i18n.T(lang, "notify.title.up") + i18n.T(lang, "notify.title.test")
+	up.DateTime = base.Time.Format("2006-01-02 15:04:05")
+	up.Duration = "3m"
+	up.DownSince = downAt.Format("2006-01-02 15:04")
+	up.Condition = ""
+
+	tmpl := db.NotificationTemplate(notification.TemplateStatusChange, ruleID)
+	events := make([]notification.Event, 0, 2)
+	for _, d := range []notification.StatusChangeData{down, up} {
+		if redact {
+			d = d.Redact()
+		}
+		ev := base
+		ev.Kind, ev.Test = notification.EventStatusChange, true
+		ev.Subject = "[Test] " + monitor.NotifySubject(lang, "notify.subject.status", d.Name, i18n.T(lang, "notify.status."+strings.ToLower(d.NewStatus)))
+		ev.Status = d
+		ev.Template = tmpl
+		events = append(events, ev)
+	}
+	return events
+}
+
+// senderCheckTimeout 检查发件域名（Resend API 或 DNS）的超时时间
+const senderCheckTimeout = 10 * time.Second
+
+// refreshEmailSender 重新检查发件人配置；使用默认发件人或域名未验证时记录系统告警，并向管理员推送 systemWarning
+func (s *Server) refreshEmailSender() notification.SenderStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), senderCheckTimeout)
+	defer cancel()
+	st := notification.CheckSender(ctx)
+	if warning := st.Warning(); warning != "" {
+		log.Printf("Warning: %s", warning)
+		db.RaiseAlert(model.AlertSeverityWarning, "Email sender not verified", warning)
+		s.socketServer.To("admin").Emit("systemWarning", map[string]any{
+			"code": "email_sender", "msg": warning, "sender": st,
+		})
+	}
+	return st
+}
+
+// broadcastNotificationList 广播通知规则列表（令牌和密码已移除）
+func (s *Server) broadcastNotificationList() {
+	var notifications []model.Notification
+	db.DB.Find(&notifications)
+	s.socketServer.To("public").Emit("notificationList", redactNotifications(notifications))
+}
+
+// addNotificationRule 校验并保存新的通知规则，返回回复内容（成功时包含 id 和发件人检查结果）。
+// Socket 事件 addNotification 和 REST 接口共用
+func (s *Server) addNotificationRule(data map[string]any) map[string]any {
+	name, _ := data["name"].(string)
+	ntype, _ := data["type"].(string)
+	if errMsg := validateNotificationRule(data); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+
+	configBytes, _ := json.Marshal(data)
+
+	n := model.Notification{
+		Name:   name,
+		Type:   ntype,
+		Config: string(configBytes),
+		Active: true,
+	}
+	if err := db.DB.Create(&n).Error; err != nil {
+		return map[string]any{"ok": false, "msg": "Failed to add notification: " + err.Error()}
+	}
+	reply := map[string]any{
+		"ok":     true,
+		"msg":    "Notification added",
+		"id":     n.ID,
+		"sender": s.refreshEmailSender(),
+	}
+	s.broadcastNotificationList()
+	return reply
+}
+
+// editNotificationRule 按提交的内容（必须包含 id 和列表中的 version）修改通知规则，返回回复内容；
+// 失败时 code 为 404、409（版本冲突）或 428（缺少 version）
+func (s *Server) editNotificationRule(data map[string]any) map[string]any {
+	idFloat, ok := safeMapGetFloat64(data, "id")
+	if !ok {
+		return map[string]any{"ok": false, "msg": "id is required"}
+	}
+	id := uint(idFloat)
+
+	var n model.Notification
+	if err := db.DB.First(&n, id).Error; err != nil {
+		return map[string]any{"ok": false, "code": 404, "msg": "Notification not found"}
+	}
+
+	// 乐观锁：必须回传通知列表中的 version，不一致说明已被他人修改
+	baseVersion, ok := safeMapGetFloat64(data, "version")
+	if !ok {
+		return versionRequiredReply()
+	}
+	base := int(baseVersion)
+	if base != n.Version {
+		current := notificationDetail(&n)
+		return conflictReply(current, changedSince(current, data))
+	}
+
+	name, _ := data["name"].(string)
+	ntype, _ := data["type"].(string)
+
+	// 推送渠道的令牌和密码在列表中不返回，未携带时沿用已保存的值
+	var saved map[string]any
+	json.Unmarshal([]byte(n.Config), &saved)
+	for _, key := range notificationSecretKeys {
+		if _, sent := data[key]; !sent && saved[key] != nil {
+			data[key] = saved[key]
+		}
+	}
+	if errMsg := validateNotificationRule(data); errMsg != "" {
+		return map[string]any{"ok": false, "msg": errMsg}
+	}
+
+	// Remove the id from data to avoid it being stored in config if desired,
+	// or just marshal the whole thing as config.
+	delete(data, "version")
+	delete(data, "_original")
+	configBytes, _ := json.Marshal(data)
+
+	n.Name = name
+	n.Type = ntype
+	n.Config = string(configBytes)
+	n.Version = base + 1
+	if err := db.SaveVersioned(&n, base); err != nil {
+		var current model.Notification
+		if errors.Is(err, db.ErrVersionConflict) && db.DB.First(&current, id).Error == nil {
+			detail := notificationDetail(&current)
+			return conflictReply(detail, changedSince(detail, data))
+		}
+		return map[string]any{"ok": false, "msg": err.Error()}
+	}
+
+	// Reset memory state so it re-arms immediately
+	s.monitorService.ResetNotificationState(n.ID)
+
+	reply := map[string]any{
+		"ok":     true,
+		"msg":    "Notification updated",
+		"sender": s.refreshEmailSender(),
+	}
+	s.broadcastNotificationList()
+	return reply
+}
+
+// deleteNotificationRule 删除通知规则及其自定义模板
+func (s *Server) deleteNotificationRule(id uint) {
+	db.DB.Delete(&model.Notification{}, id)
+	db.DeleteRuleTemplates(id)
+	s.monitorService.ResetNotificationState(id)
+	s.broadcastNotificationList()
+}
+
+// setNotificationActive 启用或停用通知规则，只更新启用状态并递增版本号
+func (s *Server) setNotificationActive(n *model.Notification, active bool) {
+	n.Active = active
+	db.DB.Model(n).Updates(map[string]any{"active": n.Active, "version": gorm.Expr("version + 1")})
+	n.Version++
+
+	// If turning ON, reset memory state so it re-arms immediately
+	if n.Active {
+		s.monitorService.ResetNotificationState(n.ID)
+	}
+	s.broadcastNotificationList()
+}
+
+// notificationDetail 通知规则的当前内容：配置字段展开到顶层，与 editNotification 的提交格式一致
+func notificationDetail(n *model.Notification) map[string]any {
+	data := make(map[string]any)
+	json.Unmarshal([]byte(n.Config), &data)
+	data["id"] = n.ID
+	data["name"] = n.Name
+	data["type"] = n.Type
+	data["active"] = n.Active
+	data["version"] = n.Version
+	redactNotificationSecrets(data)
+	return data
+}
+
+// notificationSecretKeys 不在通知列表中返回的配置字段
+var notificationSecretKeys = []string{"ntfy_token", "ntfy_password", "gotify_token", "pushover_user", "pushover_token", "bark_device_key",
+	"dingtalk_webhook", "dingtalk_secret", "wecom_key", "pagerduty_routing_key", "opsgenie_api_key",
+	"teams_webhook"}
+
+// redactNotificationSecrets 移除配置中的密钥，改为返回 <key>_set 标记
+func redactNotificationSecrets(cfg map[string]any) {
+	for _, key := range notificationSecretKeys {
+		if v, ok := cfg[key]; ok {
+			delete(cfg, key)
+			s, _ := v.(string)
+			cfg[key+"_set"] = s != ""
+		}
+	}
+}
+
+// assignTriggerRule 让触发规则覆盖名为 monitorName 的监控项。规则的 monitor_name 为 * 或已是该监控项时不做修改；
+// 否则复制一条只针对该监控项的规则，名称为 "<规则名> (<监控项名>)"，同名副本已存在时同样视为已覆盖。
+// 返回是否新建了规则
+func assignTriggerRule(tx *gorm.DB, rule *model.Notification, cfg map[string]any, monitorName string) (bool, error) {
+	if target, _ := cfg["monitor_name"].(string); target == "*" || target == monitorName {
+		return false, nil
+	}
+	name := fmt.Sprintf("%s (%s)", rule.Name, monitorName)
+	var count int64
+	tx.Model(&model.Notification{}).Where("name = ? AND type = ?", name, rule.Type).Count(&count)
+	if count > 0 {
+		return false, nil
+	}
+	config := make(map[string]any, len(cfg))
+	for k, v := range cfg {
+		config[k] = v
+	}
+	config["name"] = name
+	config["monitor_name"] = monitorName
+	configBytes, _ := json.Marshal(config)
+	copied := model.Notification{Name: name, Type: rule.Type, Config: string(configBytes), Active: true}
+	if err := tx.Create(&copied).Error; err != nil {
+		return false, err
+	}
+	// Active 的默认值为 true，停用的规则需要单独写入
+	if !rule.Active {
+		if err := tx.Model(&copied).Update("active", false).Error; err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// redactNotifications 返回移除了密钥的通知列表副本，用于推送给前端
+func redactNotifications(list []model.Notification) []model.Notification {
+	out := make([]model.Notification, len(list))
+	for i, n := range list {
+		var cfg map[string]any
+		if json.Unmarshal([]byte(n.Config), &cfg) == nil {
+			redactNotificationSecrets(cfg)
+			if b, err := json.Marshal(cfg); err == nil {
+				n.Config = string(b)
+			}
+		}
+		out[i] = n
+	}
+	return out
+}
+
+// maxDownForSeconds 按持续时间触发时允许的最大阈值（1 天）
+const maxDownForSeconds = 86400
+
+// validateNotificationRule 校验触发条件和发送渠道；定时报告只能发送到支持日报的渠道（邮件、企业微信）
+func validateNotificationRule(data map[string]any) string {
+	if ntype, _ := data["type"].(string); ntype == "schedule" {
+		if channel, _ := data["channel"].(string); !notification.SupportsReports(channel) {
+			return fmt.Sprintf("定时报告不支持通知渠道 %q", channel)
+		}
+	}
+	if v, ok := data["down_for_seconds"]; ok && v != nil {
+		secs, ok := v.(float64)
+		if !ok || secs != float64(int(secs)) || secs < 0 || secs > maxDownForSeconds {
+			return fmt.Sprintf("down_for_seconds 必须是 0 到 %d 之间的整数", maxDownForSeconds)
+		}
+	}
+	for _, f := range []struct {
+		key      string
+		min, max int
+	}{
+		{"rate_limit_per_hour", 0, 3600},
+		{"flap_transitions", 0, 100},
+		{"flap_window_minutes", 0, 1440},
+		{"flap_stable_minutes", 0, 1440},
+		{"escalation_after_minutes", 0, 10080},
+	} {
+		if v, ok := data[f.key]; ok && v != nil {
+			n, ok := v.(float64)
+			if !ok || n != float64(int(n)) || n < float64(f.min) || n > float64(f.max) {
+				return fmt.Sprintf("%s 必须是 %d 到 %d 之间的整数", f.key, f.min, f.max)
+			}
+		}
+	}
+	if n, _ := data["flap_transitions"].(float64); n == 1 {
+		return "flap_transitions 至少为 2（0 表示关闭抖动检测）"
+	}
+	if n, _ := data["escalation_after_minutes"].(float64); n > 0 {
+		if msg := validateEscalation(data); msg != "" {
+			return msg
+		}
+	}
+	if lang, _ := data["language"].(string); lang != "" && !i18n.Supported(lang) {
+		return fmt.Sprintf("不支持的通知语言 %q（可选 %s）", lang, strings.Join(i18n.Languages(), "、"))
+	}
+	if v, ok := data["quiet_hours"]; ok && v != nil {
+		var q monitor.QuietHours
+		raw, _ := json.Marshal(v)
+		if err := json.Unmarshal(raw, &q); err != nil {
+			return "quiet_hours 格式无效"
+		}
+		if err := q.Validate(); err != nil {
+			return "quiet_hours: " + err.Error()
+		}
+	}
+	return validateNotificationChannel(data)
+}
+
+// validateEscalation 校验升级渠道：必须与发送渠道不同（邮件可以改用 escalation_email 发给其他收件人），
+// 渠道字段同样从规则配置中读取，由升级渠道的 ValidateConfig 检查
+func validateEscalation(data map[string]any) string {
+	channel, _ := data["escalation_channel"].(string)
+	if channel == "" {
+		return "开启升级时必须选择 escalation_channel"
+	}
+	primary, _ := data["channel"].(string)
+	if primary == "" {
+		primary = notification.DefaultProvider
+	}
+	email, _ := data["escalation_email"].(string)
+	if channel == primary && (channel != notification.DefaultProvider || email == "") {
+		return "升级渠道不能与发送渠道相同"
+	}
+	escalated := make(map[string]any, len(data))
+	for k, v := range data {
+		escalated[k] = v
+	}
+	escalated["channel"] = channel
+	if channel == notification.DefaultProvider && email != "" {
+		escalated["email"] = email
+	}
+	if msg := validateNotificationChannel(escalated); msg != "" {
+		return "escalation_channel: " + msg
+	}
+	return ""
+}
+
+// validateNotificationChannel 校验规则的发送渠道及其配置：渠道必须已注册，配置由渠道的 ValidateConfig 检查
+// （如 email 需要收件邮箱，ntfy 需要有效的主题和服务器地址，teams 需要 https 的 webhook 地址）
+func validateNotificationChannel(data map[string]any) string {
+	channel, _ := data["channel"].(string)
+	p, err := notification.Lookup(channel)
+	if err != nil {
+		return err.Error()
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err.Error()
+	}
+	if err := p.ValidateConfig(raw); err != nil {
+		return err.Error()
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"ping-go/db"
+	"ping-go/monitor"
+	"time"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// auditActionNotificationResend 重新发送通知的审计动作
+const auditActionNotificationResend = "notification.resend"
+
+// setupNotificationLogHandlers 设置通知发送记录相关的 Socket.IO 事件处理器
+func (s *Server) setupNotificationLogHandlers(client *socket.Socket) {
+	// Handle "getNotificationHistory"
+	// 参数 {page, page_size, rule_id, monitor_id, channel, kind, success, since, until}，均可省略；
+	// page 从 1 开始，page_size 默认 50、最多 200；success 为 true/false 时只返回成功/失败的记录；since/until 为 Unix 秒
+	requireAuth(client, "getNotificationHistory", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		var f db.NotificationLogFilter
+		if len(args) > 1 {
+			if data, ok := args[0].(map[string]any); ok {
+				if v, ok := safeMapGetFloat64(data, "page"); ok {
+					f.Page = int(v)
+				}
+				if v, ok := safeMapGetFloat64(data, "page_size"); ok {
+					f.PageSize = int(v)
+				}
+				if v, ok := safeMapGetFloat64(data, "rule_id"); ok && v > 0 {
+					f.RuleID = uint(v)
+				}
+				if v, ok := safeMapGetFloat64(data, "monitor_id"); ok && v > 0 {
+					f.MonitorID = uint(v)
+				}
+				f.Channel = safeMapGetString(data, "channel")
+				f.Kind = safeMapGetString(data, "kind")
+				if v, ok := data["success"].(bool); ok {
+					f.Success = &v
+				}
+				if v, ok := safeMapGetFloat64(data, "since"); ok && v > 0 {
+					f.Since = time.Unix(int64(v), 0)
+				}
+				if v, ok := safeMapGetFloat64(data, "until"); ok && v > 0 {
+					f.Until = time.Unix(int64(v), 0)
+				}
+			}
+		}
+		logs, total, err := db.NotificationLogs(f)
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "logs": logs, "total": total}}, nil)
+	})
+
+	// Handle "resendNotification" - args: (logID)
+	// 通过原渠道重新发送一条失败的记录，等待发送完成后返回新记录
+	requireAuth(client, "resendNotification", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "logID is required"}}, nil)
+			}
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), testNotificationTimeout)
+		defer cancel()
+		entry, err := monitor.ResendNotification(ctx, id)
+		result := "sent"
+		if err != nil {
+			result = "failed: " + err.Error()
+		}
+		if entry != nil {
+			db.RecordAudit(socketActor(client), auditActionNotificationResend, fmt.Sprintf("notification_log:%d", id), result)
+		}
+		if ack == nil {
+			return
+		}
+		reply := map[string]any{"ok": err == nil, "msg": "Notification resent"}
+		if err != nil {
+			reply["msg"] = err.Error()
+		}
+		if entry != nil {
+			reply["log"] = entry
+		}
+		ack([]any{reply}, nil)
+	})
+}
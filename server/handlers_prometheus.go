@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// promStatusMetric 告警规则引用的监控状态指标（由 /metrics 导出），标签为 id/name/type，取值 1 表示 UP、0 表示 DOWN
+const promStatusMetric = "pinggo_monitor_status"
+
+// promRuleFile Prometheus 规则文件（groups: 格式）
+type promRuleFile struct {
+	Groups []promRuleGroup `yaml:"groups"`
+}
+
+type promRuleGroup struct {
+	Name  string          `yaml:"name"`
+	Rules []promAlertRule `yaml:"rules"`
+}
+
+type promAlertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// prometheusRulesAPI 根据当前启用的触发规则生成 Prometheus 告警规则，每次请求实时生成
+func (s *Server) prometheusRulesAPI(c *gin.Context) {
+	var rules []model.Notification
+	if err := db.DB.Where("type = ? AND active = ?", "trigger", true).Order("id").Find(&rules).Error; err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	var monitors []model.Monitor
+	if err := db.DB.Order("id").Find(&monitors).Error; err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	// 限定范围的凭据只生成范围内监控项的规则
+	scope := apiScope(c)
+	if scope != nil {
+		inScope := monitors[:0]
+		for _, m := range monitors {
+			if m.InScope(scope) {
+				inScope = append(inScope, m)
+			}
+		}
+		monitors = inScope
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated by PingGo at %s from %d active trigger rule(s)\n", time.Now().Format(time.RFC3339), len(rules))
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(buildPrometheusRules(rules, monitors, scope != nil)); err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	enc.Close()
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", buf.Bytes())
+}
+
+// buildPrometheusRules 将触发规则转换为告警规则
+// 匹配 "*" 的规则按监控间隔分组，用 name=~ 标签选择器表达，而不是为每个监控项生成一条规则。
+// scoped 为 true 时 monitors 只是部分监控项，始终写出 name 选择器，避免规则匹配到范围外的监控项
+func buildPrometheusRules(rules []model.Notification, monitors []model.Monitor, scoped bool) promRuleFile {
+	group := promRuleGroup{Name: "pinggo", Rules: []promAlertRule{}}
+
+	for _, rule := range rules {
+		var cfg struct {
+			MonitorName string `json:"monitor_name"`
+			OnStatus    string `json:"on_status"`
+			MaxRetries  int    `json:"max_retries"`
+			RunbookURL  string `json:"runbook_url"`
+		}
+		if err := json.Unmarshal([]byte(rule.Config), &cfg); err != nil || cfg.MonitorName == "" {
+			continue
+		}
+		retries := cfg.MaxRetries
+		if retries <= 0 {
+			retries = 1
+		}
+
+		labels := map[string]string{"severity": "critical", "pinggo_rule": rule.Name}
+		if cfg.OnStatus != "" {
+			labels["on_status"] = cfg.OnStatus
+		}
+		annotations := map[string]string{
+			"summary":     "PingGo monitor {{ $labels.name }} is down",
+			"description": fmt.Sprintf("Trigger rule %q: monitor {{ $labels.name }} ({{ $labels.type }}) has been DOWN for %d consecutive check(s).", rule.Name, retries),
+		}
+		if cfg.RunbookURL != "" {
+			annotations["runbook_url"] = cfg.RunbookURL
+		}
+
+		if cfg.MonitorName != "*" {
+			for _, m := range monitors {
+				if m.Name != cfg.MonitorName {
+					continue
+				}
+				ruleLabels := copyLabels(labels)
+				ruleLabels["name"] = m.Name
+				group.Rules = append(group.Rules, promAlertRule{
+					Alert:       "PingGoMonitorDown",
+					Expr:        fmt.Sprintf("%s{name=%s} == 0", promStatusMetric, strconv.Quote(m.Name)),
+					For:         promDuration(retries * m.Interval),
+					Labels:      ruleLabels,
+					Annotations: annotations,
+				})
+				break
+			}
+			continue
+		}
+
+		// "*"：按间隔分组，间隔相同的监控项共用一条规则
+		byInterval := make(map[int][]string)
+		for _, m := range monitors {
+			if m.Active == 1 {
+				byInterval[m.Interval] = append(byInterval[m.Interval], m.Name)
+			}
+		}
+		intervals := make([]int, 0, len(byInterval))
+		for interval := range byInterval {
+			intervals = append(intervals, interval)
+		}
+		sort.Ints(intervals)
+
+		for _, interval := range intervals {
+			expr := promStatusMetric + " == 0"
+			if len(intervals) > 1 || scoped {
+				names := byInterval[interval]
+				for i, name := range names {
+					names[i] = regexp.QuoteMeta(name)
+				}
+				expr = fmt.Sprintf("%s{name=~%s} == 0", promStatusMetric, strconv.Quote(strings.Join(names, "|")))
+			}
+			group.Rules = append(group.Rules, promAlertRule{
+				Alert:       "PingGoMonitorDown",
+				Expr:        expr,
+				For:         promDuration(retries * interval),
+				Labels:      copyLabels(labels),
+				Annotations: annotations,
+			})
+		}
+	}
+
+	return promRuleFile{Groups: []promRuleGroup{group}}
+}
+
+// promDuration 将秒数格式化为 Prometheus 持续时间（如 90s、5m、1h）
+func promDuration(seconds int) string {
+	switch {
+	case seconds <= 0:
+		return ""
+	case seconds%3600 == 0:
+		return fmt.Sprintf("%dh", seconds/3600)
+	case seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
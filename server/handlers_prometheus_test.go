@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"ping-go/model"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// promExpr 生成的表达式只有三种形式：不带选择器、name= 精确匹配和 name=~ 正则匹配
+var promExpr = regexp.MustCompile(`^` + promStatusMetric + `(?:\{name(=~|=)("(?:[^"\\]|\\.)*")\})? == 0$`)
+
+// promFor Prometheus 持续时间的格式（这里只会生成秒、分、小时）
+var promFor = regexp.MustCompile(`^[1-9][0-9]*[smh]$`)
+
+// exprMatches 按 PromQL 的语义判断表达式是否选中 name 标签为 name 的序列：
+// 字符串字面量按 Go 的转义规则解析，=~ 是锚定到整个标签值的 RE2 正则
+func exprMatches(t *testing.T, expr, name string) bool {
+	t.Helper()
+	m := promExpr.FindStringSubmatch(expr)
+	if m == nil {
+		t.Fatalf("unexpected expression %q", expr)
+	}
+	if m[1] == "" {
+		return true
+	}
+	value, err := strconv.Unquote(m[2])
+	if err != nil {
+		t.Fatalf("expression %q: bad string literal: %v", expr, err)
+	}
+	if m[1] == "=" {
+		return value == name
+	}
+	re, err := regexp.Compile("^(?:" + value + ")$")
+	if err != nil {
+		t.Fatalf("expression %q: bad regexp: %v", expr, err)
+	}
+	return re.MatchString(name)
+}
+
+// 生成的规则文件必须是 Prometheus 能加载的结构（YAML 字段、持续时间、注解模板），
+// 名称选择器必须恰好匹配对应的监控项，包括名称中含引号、反斜杠和正则元字符的情况
+func TestBuildPrometheusRules(t *testing.T) {
+	mixed := []model.Monitor{
+		{Name: "api", Interval: 60, Active: 1},
+		{Name: `db "primary"`, Interval: 90, Active: 1},
+		{Name: `C:\share`, Interval: 3600, Active: 1},
+		{Name: "a.b|c (staging)", Interval: 90, Active: 1},
+		{Name: "状态页", Interval: 60, Active: 1},
+		{Name: "paused", Interval: 300, Active: 0},
+	}
+	sameInterval := []model.Monitor{
+		{Name: "api", Interval: 60, Active: 1},
+		{Name: "a.b|c", Interval: 60, Active: 1},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		config   string
+		monitors []model.Monitor
+		scoped   bool
+		outside  []model.Monitor   // 不在范围内、不能被规则匹配的监控项
+		want     map[string]string // 规则应匹配的监控项 → for
+	}{
+		{
+			name:     "wildcard grouped by interval",
+			config:   `{"monitor_name":"*","max_retries":2}`,
+			monitors: mixed,
+			want:     map[string]string{"api": "2m", `db "primary"`: "3m", `C:\share`: "2h", "a.b|c (staging)": "3m", "状态页": "2m"},
+		},
+		{
+			name:     "wildcard single interval",
+			config:   `{"monitor_name":"*"}`,
+			monitors: sameInterval,
+			want:     map[string]string{"api": "1m", "a.b|c": "1m"},
+		},
+		{
+			name:     "scoped single interval",
+			config:   `{"monitor_name":"*"}`,
+			monitors: sameInterval,
+			scoped:   true,
+			outside:  []model.Monitor{{Name: "internal", Interval: 60, Active: 1}, {Name: "a.b-c", Interval: 60, Active: 1}},
+			want:     map[string]string{"api": "1m", "a.b|c": "1m"},
+		},
+		{
+			name:     "quoted name",
+			config:   `{"monitor_name":"db \"primary\"","max_retries":3,"on_status":"down","runbook_url":"https://runbooks.example.com/db?x=1&y=2"}`,
+			monitors: mixed,
+			want:     map[string]string{`db "primary"`: "270s"},
+		},
+		{
+			name:     "backslash in name",
+			config:   `{"monitor_name":"C:\\share"}`,
+			monitors: mixed,
+			want:     map[string]string{`C:\share`: "1h"},
+		},
+		{
+			name:     "unknown monitor",
+			config:   `{"monitor_name":"missing"}`,
+			monitors: mixed,
+			want:     map[string]string{},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []model.Notification{{Name: tt.name, Type: "trigger", Active: true, Config: tt.config}}
+			built := buildPrometheusRules(rules, tt.monitors, tt.scoped)
+
+			var buf bytes.Buffer
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if err := enc.Encode(built); err != nil {
+				t.Fatal(err)
+			}
+			var parsed promRuleFile
+			dec := yaml.NewDecoder(&buf)
+			dec.KnownFields(true)
+			if err := dec.Decode(&parsed); err != nil {
+				t.Fatalf("rules do not decode: %v", err)
+			}
+			if !reflect.DeepEqual(parsed, built) {
+				t.Fatalf("YAML round trip changed the rules:\n%+v\n%+v", parsed, built)
+			}
+
+			got := make(map[string]string)
+			for _, rule := range parsed.Groups[0].Rules {
+				if rule.Alert == "" || !promFor.MatchString(rule.For) {
+					t.Errorf("rule %+v: missing alert name or bad for", rule)
+				}
+				for key, text := range rule.Annotations {
+					// Prometheus 渲染注解前会定义 $labels 和 $value
+					if _, err := template.New(key).Parse("{{$labels := .Labels}}{{$value := .Value}}" + text); err != nil {
+						t.Errorf("annotation %s: %v", key, err)
+					}
+				}
+				for _, m := range tt.outside {
+					if exprMatches(t, rule.Expr, m.Name) {
+						t.Errorf("rule %q matches %q outside the scope", rule.Expr, m.Name)
+					}
+				}
+				for _, m := range tt.monitors {
+					if m.Active == 1 && exprMatches(t, rule.Expr, m.Name) {
+						if _, dup := got[m.Name]; dup {
+							t.Errorf("monitor %q matched by more than one rule", m.Name)
+						}
+						got[m.Name] = rule.For
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched monitors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
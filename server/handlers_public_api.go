@@ -0,0 +1,223 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPublicHeartbeatHours、maxPublicHeartbeatHours 公开心跳历史的默认和最大查询小时数
+	defaultPublicHeartbeatHours = 24
+	maxPublicHeartbeatHours     = 90 * 24
+)
+
+// publicUptimeDurations 公开接口返回的可用率统计时长
+var publicUptimeDurations = []struct {
+	Key      string
+	Duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// publicHeartbeatFields 公开心跳历史中保留的字段；检查消息可能包含内部地址或错误详情，不返回
+var publicHeartbeatFields = []string{"time", "status", "status_key", "duration", "uptime", "type"}
+
+// publicMonitor GET /api/status 中的监控项，只包含公开展示需要的字段，不包含地址和检查消息
+type publicMonitor struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Type        model.MonitorType  `json:"type"`
+	Active      bool               `json:"active"`
+	Status      int                `json:"status"`
+	StatusKey   string             `json:"status_key"`
+	Uptime      map[string]float64 `json:"uptime"`          // 24h、7d、30d 可用率（百分比）
+	AvgResponse float64            `json:"avg_response_ms"` // 最近 24 小时成功检查的平均响应时间
+}
+
+// publicStatusResponse GET /api/status 的响应
+type publicStatusResponse struct {
+	Monitors []publicMonitor `json:"monitors"`
+}
+
+// publicAPIEntry 缓存的响应体，ETag 为响应体的哈希
+type publicAPIEntry struct {
+	body     []byte
+	etag     string
+	modified time.Time
+	expires  time.Time
+}
+
+var (
+	publicAPIMu    sync.Mutex
+	publicAPICache = make(map[string]*publicAPIEntry)
+)
+
+// requirePublicAPI server.public_api_disabled 开启时公开 JSON 接口返回 404
+func requirePublicAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if config.Get().Server.PublicAPIDisabled {
+			writeAPIError(c, apperrors.New(http.StatusNotFound, "Public API is disabled", http.StatusNotFound, nil))
+			return
+		}
+		c.Next()
+	}
+}
+
+// cachedPublicAPI 返回 key 对应的缓存响应，过期时调用 build 重新生成并缓存 embedCacheTTL。
+// build 返回响应数据和数据的最后修改时间（最新一条心跳的时间）
+func cachedPublicAPI(key string, build func() (any, time.Time, error)) (*publicAPIEntry, error) {
+	now := time.Now()
+	publicAPIMu.Lock()
+	if e, ok := publicAPICache[key]; ok && now.Before(e.expires) {
+		publicAPIMu.Unlock()
+		return e, nil
+	}
+	publicAPIMu.Unlock()
+
+	data, modified, err := build()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	if modified.IsZero() {
+		modified = now
+	}
+	e := &publicAPIEntry{body: body, etag: `"` + hex.EncodeToString(sum[:16]) + `"`, modified: modified.UTC().Truncate(time.Second), expires: now.Add(embedCacheTTL)}
+
+	publicAPIMu.Lock()
+	for k, v := range publicAPICache {
+		if now.After(v.expires) {
+			delete(publicAPICache, k)
+		}
+	}
+	publicAPICache[key] = e
+	publicAPIMu.Unlock()
+	return e, nil
+}
+
+// writePublicAPI 输出带 ETag 和 Last-Modified 的 JSON；If-None-Match 或 If-Modified-Since 命中时返回 304
+func writePublicAPI(c *gin.Context, e *publicAPIEntry) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedCacheTTL.Seconds())))
+	c.Header("ETag", e.etag)
+	c.Header("Last-Modified", e.modified.Format(http.TimeFormat))
+	c.Header("Access-Control-Allow-Origin", "*")
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			if tag = strings.TrimSpace(tag); tag == e.etag || tag == "W/"+e.etag || tag == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !e.modified.After(since) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", e.body)
+}
+
+// latestHeartbeat 返回监控项最新一条心跳的时间，没有心跳时为零值
+func latestHeartbeat(monitorIDs []uint) time.Time {
+	var latest model.Heartbeat
+	if len(monitorIDs) > 0 {
+		db.DB.Select("time").Where("monitor_id IN ? AND agent_id = 0", monitorIDs).Order("time DESC").Limit(1).Find(&latest)
+	}
+	return latest.Time
+}
+
+// publicStatusAPI 处理 GET /api/status：公开监控项（public: true）的名称、类型、状态、可用率和平均响应时间，不需要登录。
+// 对未登录客户端隐藏的监控项（public_visible: false）不包含在内。
+// 供 Grafana JSON 数据源、大屏等无法使用 Socket.IO 的外部看板使用
+func (s *Server) publicStatusAPI(c *gin.Context) {
+	e, err := cachedPublicAPI("status", func() (any, time.Time, error) {
+		var monitors []model.Monitor
+		if err := db.DB.Select("id", "name", "type", "status", "active").Where("public = ? AND public_visible = ?", true, true).Order("id").Find(&monitors).Error; err != nil {
+			return nil, time.Time{}, err
+		}
+		list := make([]publicMonitor, len(monitors))
+		ids := make([]uint, len(monitors))
+		for i, m := range monitors {
+			ids[i] = m.ID
+			list[i] = publicMonitor{
+				ID: m.ID, Name: m.Name, Type: m.Type, Active: m.Active != 0,
+				Status: m.Status, StatusKey: model.StatusKey(m.Status),
+				Uptime:      make(map[string]float64, len(publicUptimeDurations)),
+				AvgResponse: db.GetAvgResponseTime(m.ID, 24*time.Hour),
+			}
+			for _, d := range publicUptimeDurations {
+				list[i].Uptime[d.Key] = db.GetUptimeStats(m.ID, d.Duration)
+			}
+		}
+		return publicStatusResponse{Monitors: list}, latestHeartbeat(ids), nil
+	})
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	writePublicAPI(c, e)
+}
+
+// publicHeartbeatRows 只保留心跳中 publicHeartbeatFields 的字段
+func publicHeartbeatRows(rows []map[string]any) []map[string]any {
+	list := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		list[i] = make(map[string]any, len(publicHeartbeatFields))
+		for _, k := range publicHeartbeatFields {
+			if v, ok := row[k]; ok {
+				list[i][k] = v
+			}
+		}
+	}
+	return list
+}
+
+// publicHeartbeatsAPI 处理 GET /api/status/:slug/heartbeats?hours=24：公开监控项的心跳历史，不需要登录，隐藏的监控项返回 404。
+// 路径参数为监控项 ID（与状态页接口 /api/status/:slug 共用同一位置的参数名）；
+// 按查询时长自动使用原始、小时或日聚合数据（同 getHeartbeatListWithRange），不返回检查消息
+func (s *Server) publicHeartbeatsAPI(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("slug"), 10, 64)
+	if err != nil || id == 0 {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Monitor not found", http.StatusNotFound, nil))
+		return
+	}
+	hours := defaultPublicHeartbeatHours
+	if v := c.Query("hours"); v != "" {
+		if hours, err = strconv.Atoi(v); err != nil || hours < 1 || hours > maxPublicHeartbeatHours {
+			writeAPIError(c, apperrors.New(http.StatusBadRequest, fmt.Sprintf("hours must be between 1 and %d", maxPublicHeartbeatHours), http.StatusBadRequest, nil))
+			return
+		}
+	}
+	var m model.Monitor
+	if err := db.DB.Select("id", "public").Where("id = ?", id).Limit(1).Find(&m).Error; err != nil || m.ID == 0 || !m.Public || !publicVisible(m.ID) {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Monitor not found", http.StatusNotFound, nil))
+		return
+	}
+
+	e, err := cachedPublicAPI(fmt.Sprintf("heartbeats:%d:%d", m.ID, hours), func() (any, time.Time, error) {
+		rows, source := db.GetHeartbeatsWithTimeRange(m.ID, hours)
+		return gin.H{"monitor_id": m.ID, "hours": hours, "source": source, "heartbeats": publicHeartbeatRows(rows)}, latestHeartbeat([]uint{m.ID}), nil
+	})
+	if err != nil {
+		writeAPIError(c, apperrors.Wrap(err, err.Error()))
+		return
+	}
+	writePublicAPI(c, e)
+}
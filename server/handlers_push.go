@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	apperrors "ping-go/pkg/errors"
+	"ping-go/pkg/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPushIntervalSeconds = 10
+	defaultPushBurst           = 1
+	defaultMaxPayloadBytes     = 4096
+	// maxPushMessageLength 上报消息写入心跳前的最大字符数
+	maxPushMessageLength = 255
+)
+
+// newPushLimiter 根据配置创建 push 上报限流器
+func newPushLimiter() *ratelimit.Limiter {
+	cfg := config.Get().Ingest
+	interval := cfg.PushIntervalSeconds
+	if interval <= 0 {
+		interval = defaultPushIntervalSeconds
+	}
+	burst := cfg.PushBurst
+	if burst <= 0 {
+		burst = defaultPushBurst
+	}
+	return ratelimit.New(time.Duration(interval)*time.Second, burst)
+}
+
+// maxPayloadBytes 返回单次上报允许的请求体大小
+func maxPayloadBytes() int64 {
+	if n := config.Get().Ingest.MaxPayloadBytes; n > 0 {
+		return n
+	}
+	return defaultMaxPayloadBytes
+}
+
+// pushRequest push 上报参数，可通过查询参数或 JSON 请求体提交
+type pushRequest struct {
+	Status string  `json:"status" form:"status"` // "up"（默认）或 "down"
+	Msg    string  `json:"msg" form:"msg"`
+	Ping   float64 `json:"ping" form:"ping"` // 响应时间（毫秒）
+}
+
+// pushResponse push 上报的响应，与 Uptime Kuma 兼容；限流时附带 retry_after（秒）
+type pushResponse struct {
+	OK         bool   `json:"ok"`
+	Msg        string `json:"msg,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// handlePush 处理 /api/push/:token 上报
+// 限流在查询数据库之前进行，失控的客户端不会给 SQLite 带来额外压力
+func (s *Server) handlePush(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "msg": "Monitor not found"})
+		return
+	}
+
+	if allowed, wait := s.pushLimiter.Allow(token); !allowed {
+		s.pushThrottled.Inc(token)
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"ok": false, "msg": "Too many requests", "retry_after": retryAfter})
+		return
+	}
+
+	limit := maxPayloadBytes()
+	if int64(len(c.Request.URL.RawQuery)) > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "msg": "Payload too large"})
+		return
+	}
+
+	// POST 带请求体时使用 JSON 请求体，否则使用查询参数
+	hasBody := c.Request.Method == http.MethodPost && c.Request.ContentLength != 0
+	var raw []byte
+	if hasBody {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		b, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"ok": false, "msg": "Payload too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "msg": "Failed to read body"})
+			return
+		}
+		raw = b
+	}
+
+	var m model.Monitor
+	if err := db.DB.Where("push_token = ? AND type = ?", token, model.MonitorTypePush).First(&m).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "msg": "Monitor not found"})
+		return
+	}
+
+	// 配置了签名密钥的 token 必须携带有效签名；GET 请求对原始查询字符串签名
+	if m.PushSecret != "" {
+		signed := raw
+		if !hasBody {
+			signed = []byte(c.Request.URL.RawQuery)
+		}
+		if err := s.verifySignature(m.PushSecret, c.GetHeader(timestampHeader), c.GetHeader(signatureHeader), signed); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "msg": err.Error()})
+			return
+		}
+	}
+
+	if m.Active != 1 {
+		c.JSON(http.StatusOK, gin.H{"ok": false, "msg": "Monitor is paused"})
+		return
+	}
+
+	var req pushRequest
+	if hasBody {
+		if err := json.Unmarshal(raw, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "msg": "Invalid JSON body"})
+			return
+		}
+	} else if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "msg": "Invalid parameters"})
+		return
+	}
+
+	status := model.StatusUp
+	if strings.EqualFold(req.Status, "down") {
+		status = model.StatusDown
+	}
+	msg := strings.TrimSpace(req.Msg)
+	if msg == "" {
+		msg = "OK"
+		if status == model.StatusDown {
+			msg = "Reported down"
+		}
+	}
+	if runes := []rune(msg); len(runes) > maxPushMessageLength {
+		msg = string(runes[:maxPushMessageLength-3]) + "..."
+	}
+	duration := 0
+	if req.Ping > 0 {
+		duration = int(req.Ping)
+	}
+
+	s.monitorService.RecordPush(m, status, msg, duration)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// signExampleAPI 返回当前签名密钥下的完整签名示例，方便接入方核对实现。
+// 由 requireFullAPIAccess 限定为不限范围的 admin 凭据：read 权限或限定标签范围的密钥不能获取签名
+func (s *Server) signExampleAPI(c *gin.Context) {
+	var m model.Monitor
+	if err := db.DB.Where("push_token = ? AND type = ?", c.Param("token"), model.MonitorTypePush).First(&m).Error; err != nil {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Monitor not found", http.StatusNotFound, nil))
+		return
+	}
+	if m.PushSecret == "" {
+		writeAPIError(c, apperrors.New(http.StatusBadRequest, "Signature verification is not enabled for this token", http.StatusBadRequest, nil))
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s/api/push/%s", scheme, c.Request.Host, m.PushToken)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := `{"status":"up","msg":"OK","ping":12}`
+	signature := computeSignature(m.PushSecret, timestamp, []byte(body))
+
+	c.JSON(http.StatusOK, gin.H{
+		"algorithm":      "HMAC-SHA256, hex encoded",
+		"string_to_sign": timestamp + body,
+		"timestamp":      timestamp,
+		"body":           body,
+		"signature":      signature,
+		"headers": gin.H{
+			timestampHeader: timestamp,
+			signatureHeader: signature,
+		},
+		"curl": fmt.Sprintf("curl -X POST '%s' -H 'Content-Type: application/json' -H '%s: %s' -H '%s: %s' -d '%s'",
+			url, timestampHeader, timestamp, signatureHeader, signature, body),
+		"notes": "Sign timestamp + raw request body (GET requests sign timestamp + raw query string). " +
+			"Timestamps must be within 5 minutes of server time and each signature is accepted only once.",
+	})
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"ping-go/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignExample(t *testing.T) {
+	_, ts := newTestServer(t)
+	signed := createTestMonitor(t, model.Monitor{Name: "job", Type: model.MonitorTypePush, PushToken: "tok123", PushSecret: "0123456789abcdef", Tags: "ops"})
+	createTestMonitor(t, model.Monitor{Name: "plain", Type: model.MonitorTypePush, PushToken: "plain123"})
+	session := createTestSession(t, "admin", model.RoleAdmin)
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  int
+	}{
+		{"no credentials", "", "tok123", http.StatusUnauthorized},
+		{"unknown session", "not-a-session", "tok123", http.StatusUnauthorized},
+		{"unknown push token", session, "missing", http.StatusNotFound},
+		{"token without secret", session, "plain123", http.StatusBadRequest},
+		{"read key", createTestAPIKey(t, model.APIKeyScopeRead, ""), "tok123", http.StatusForbidden},
+		{"write key", createTestAPIKey(t, model.APIKeyScopeWrite, ""), "tok123", http.StatusForbidden},
+		{"tag-scoped admin key", createTestAPIKey(t, model.APIKeyScopeAdmin, "ops"), "tok123", http.StatusForbidden},
+		{"viewer session", createTestSession(t, "viewer", model.RoleViewer), "tok123", http.StatusForbidden},
+		{"admin key", createTestAPIKey(t, model.APIKeyScopeAdmin, ""), "tok123", http.StatusOK},
+		{"admin session", session, "tok123", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := doAPI(t, ts, http.MethodGet, "/api/inbound/"+tt.path+"/sign-example", tt.token, "")
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", code, tt.want, body)
+			}
+			if code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Timestamp string `json:"timestamp"`
+				Body      string `json:"body"`
+				Signature string `json:"signature"`
+			}
+			decodeJSON(t, body, &resp)
+			if want := computeSignature(signed.PushSecret, resp.Timestamp, []byte(resp.Body)); resp.Signature != want {
+				t.Fatalf("signature = %q, want %q", resp.Signature, want)
+			}
+		})
+	}
+}
+
+// 签名密钥只通过 getMonitor 返回给不限范围的管理员，其他读取途径只有 push_secret_set
+func TestPushSecretRedacted(t *testing.T) {
+	_, ts := newTestServer(t)
+	const secret = "0123456789abcdef-secret"
+	m := createTestMonitor(t, model.Monitor{Name: "job", Type: model.MonitorTypePush, PushToken: "tok123", PushSecret: secret})
+	path := fmt.Sprintf("/api/v1/monitors/%d", m.ID)
+
+	for _, scope := range []string{model.APIKeyScopeRead, model.APIKeyScopeWrite, model.APIKeyScopeAdmin} {
+		key := createTestAPIKey(t, scope, "")
+		for _, p := range []string{path, "/api/v1/monitors"} {
+			code, body := doAPI(t, ts, http.MethodGet, p, key, "")
+			if code != http.StatusOK || strings.Contains(string(body), secret) || !strings.Contains(string(body), `"push_secret_set":true`) {
+				t.Fatalf("%s key GET %s = %d %s, want the secret redacted", scope, p, code, body)
+			}
+		}
+	}
+
+	key := createTestAPIKey(t, model.APIKeyScopeAdmin, "")
+	if code, body := doAPI(t, ts, http.MethodGet, "/api/v1/monitors/export", key, ""); code != http.StatusOK || strings.Contains(string(body), secret) {
+		t.Fatalf("export without secrets = %d %s", code, body)
+	}
+	if code, body := doAPI(t, ts, http.MethodGet, "/api/v1/monitors/export?include_secrets=true", key, ""); code != http.StatusOK || !strings.Contains(string(body), secret) {
+		t.Fatalf("export with secrets = %d %s", code, body)
+	}
+
+	for _, tt := range []struct {
+		role string
+		want bool
+	}{
+		{model.RoleViewer, false},
+		{model.RoleAdmin, true},
+	} {
+		t.Run(tt.role, func(t *testing.T) {
+			client := dialSocket(t, ts)
+			if reply, err := client.EmitWithAck("auth", map[string]any{"token": createTestSession(t, tt.role, tt.role)}); err != nil || reply[0].(map[string]any)["ok"] != true {
+				t.Fatalf("auth = %v, %v", reply, err)
+			}
+			client.Emit("getMonitor", m.ID)
+			ev, err := client.Next("monitor", 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(ev.Raw, secret); got != tt.want {
+				t.Fatalf("getMonitor as %s = %s, want secret %v", tt.role, ev.Raw, tt.want)
+			}
+		})
+	}
+}
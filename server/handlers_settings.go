@@ -1,49 +1,305 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"net/url"
+	"ping-go/config"
 	"ping-go/db"
 	"ping-go/model"
+	"ping-go/notification"
+	"ping-go/pkg/i18n"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
 )
 
+// 站点品牌设置的长度限制
+const (
+	maxSiteNameRunes   = 64
+	maxSiteFooterRunes = 500
+	maxSiteLogoURLLen  = 2048
+)
+
+// siteColorRe 站点主色只接受 #rgb 或 #rrggbb，会被注入到页面的 Tailwind 配置中
+var siteColorRe = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validateSiteSettings 校验 setSettings 中的站点品牌设置，返回错误信息；未包含这些设置或全部有效时返回空字符串。
+// 值为空表示恢复默认
+func validateSiteSettings(settingsMap map[string]any) string {
+	value := func(key string) (string, bool) {
+		v, ok := settingsMap[key]
+		if !ok {
+			return "", false
+		}
+		return strings.TrimSpace(fmt.Sprintf("%v", v)), true
+	}
+	if name, ok := value(db.SiteNameSettingKey); ok && utf8.RuneCountInString(name) > maxSiteNameRunes {
+		return fmt.Sprintf("站点名称不能超过 %d 个字符", maxSiteNameRunes)
+	}
+	if footer, ok := value(db.SiteFooterTextSettingKey); ok && utf8.RuneCountInString(footer) > maxSiteFooterRunes {
+		return fmt.Sprintf("页脚文字不能超过 %d 个字符", maxSiteFooterRunes)
+	}
+	if color, ok := value(db.SitePrimaryColorSettingKey); ok && color != "" && !siteColorRe.MatchString(color) {
+		return fmt.Sprintf("主色 %q 无效，应为 #rgb 或 #rrggbb 格式", color)
+	}
+	if logo, ok := value(db.SiteLogoURLSettingKey); ok && logo != "" {
+		// 只接受 http(s) 绝对地址或以 / 开头的站内路径，不接受 javascript:、data: 等
+		u, err := url.Parse(logo)
+		valid := err == nil && len(logo) <= maxSiteLogoURLLen &&
+			((u.Scheme == "http" || u.Scheme == "https") && u.Host != "" ||
+				u.Scheme == "" && u.Host == "" && strings.HasPrefix(logo, "/") && !strings.HasPrefix(logo, "//"))
+		if !valid {
+			return fmt.Sprintf("Logo 地址 %q 无效，应为 http(s) 地址或以 / 开头的路径", logo)
+		}
+	}
+	return ""
+}
+
+// settingsSnapshot 返回全部设置项，settingVersions 为各设置项的版本号（setSettings 时需要在 _versions 中回传），
+// emailSender 为只读的发件人检查结果
+func settingsSnapshot() map[string]any {
+	var settings []model.Setting
+	db.DB.Find(&settings)
+	settingsMap := make(map[string]any)
+	versions := make(map[string]int)
+	for _, setting := range settings {
+		settingsMap[setting.Key] = setting.Value
+		versions[setting.Key] = setting.Version
+	}
+	settingsMap["settingVersions"] = versions
+	// Add some default settings if missing
+	if _, ok := settingsMap[db.SiteNameSettingKey]; !ok {
+		settingsMap[db.SiteNameSettingKey] = db.DefaultSiteName
+	}
+	// 发件人检查结果（只读），使用默认发件人或域名未验证时邮件可能进入垃圾箱
+	settingsMap["emailSender"] = notification.Sender()
+	return settingsMap
+}
+
+// saveSettings 校验并在一个事务中保存设置项，返回回复内容；settingsMap 中的 _versions 为各 key 读取时的版本。
+// 任一 key 已被他人修改时整体不保存，回复 409 和这些 key 的当前值（current）与版本（versions）。
+// Socket 事件 setSettings 和 REST 接口共用
+func saveSettings(settingsMap map[string]any) map[string]any {
+	versions, _ := settingsMap["_versions"].(map[string]any)
+	delete(settingsMap, "_versions")
+	// 自定义模板需要校验，只能通过 setNotificationTemplate 修改
+	for k := range settingsMap {
+		if db.IsTemplateSettingKey(k) {
+			return map[string]any{"ok": false, "msg": fmt.Sprintf("%s 只能通过 setNotificationTemplate 修改", k)}
+		}
+	}
+	if msg := validateSiteSettings(settingsMap); msg != "" {
+		return map[string]any{"ok": false, "msg": msg}
+	}
+	if msg := validateSessionSettings(settingsMap); msg != "" {
+		return map[string]any{"ok": false, "msg": msg}
+	}
+	// 邮件语言为空时恢复默认（配置 notification.language 或界面语言）
+	if v, ok := settingsMap["emailLanguage"]; ok {
+		if lang := fmt.Sprintf("%v", v); lang != "" && !i18n.Supported(lang) {
+			return map[string]any{"ok": false, "msg": fmt.Sprintf("不支持的邮件语言 %q", lang)}
+		}
+	}
+
+	current := make(map[string]any)
+	currentVersions := make(map[string]int)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for k, v := range settingsMap {
+			base := 0
+			if f, ok := versions[k].(float64); ok {
+				base = int(f)
+			}
+			var setting model.Setting
+			if err := tx.Where("key = ?", k).Limit(1).Find(&setting).Error; err != nil {
+				return err
+			}
+			if setting.Version != base {
+				current[k] = setting.Value
+				currentVersions[k] = setting.Version
+				continue
+			}
+			value := fmt.Sprintf("%v", v)
+			if setting.ID == 0 {
+				if err := tx.Create(&model.Setting{Key: k, Value: value}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			result := tx.Model(&setting).Where("version = ?", base).
+				Updates(map[string]any{"value": value, "version": base + 1})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				tx.Where("key = ?", k).Limit(1).Find(&setting)
+				current[k], currentVersions[k] = setting.Value, setting.Version
+			}
+		}
+		if len(current) > 0 {
+			return db.ErrVersionConflict
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrVersionConflict) {
+			// current 只包含版本已变化的 key，都是读取之后被他人修改的
+			changed := slices.Sorted(maps.Keys(current))
+			reply := conflictReply(current, changed)
+			delete(reply, "version")
+			reply["versions"] = currentVersions
+			return reply
+		}
+		return map[string]any{"ok": false, "msg": err.Error()}
+	}
+	db.InvalidateStatusSettings()
+	return map[string]any{"ok": true, "msg": "Settings saved"}
+}
+
 // setupSettingsHandlers 设置系统设置相关的 Socket.IO 事件处理器
 func (s *Server) setupSettingsHandlers(client *socket.Socket) {
 	// Handle "getSettings"
 	requireAuth(client, "getSettings", func(args ...any) {
-		var settings []model.Setting
-		db.DB.Find(&settings)
-		settingsMap := make(map[string]any)
-		for _, setting := range settings {
-			settingsMap[setting.Key] = setting.Value
-		}
-		// Add some default settings if missing
-		if _, ok := settingsMap["siteName"]; !ok {
-			settingsMap["siteName"] = "ping-go"
-		}
-		client.Emit("settings", settingsMap)
+		client.Emit("settings", settingsSnapshot())
 	})
 
-	// Handle "setSettings"
+	// Handle "setSettings" - args: {key: value, ..., _versions: {key: version}}
+	// 乐观锁：_versions 回传 getSettings 的 settingVersions 中对应 key 的版本（新增的 key 为 0），
+	// 任一 key 已被他人修改时整体不保存，返回 409 冲突
 	requireAuth(client, "setSettings", func(args ...any) {
 		if len(args) < 1 {
 			return
 		}
-		settingsMap := args[0].(map[string]any)
-		for k, v := range settingsMap {
-			var setting model.Setting
-			db.DB.Where("key = ?", k).First(&setting)
-			setting.Key = k
-			setting.Value = fmt.Sprintf("%v", v)
-			db.DB.Save(&setting)
+		settingsMap, ok := args[0].(map[string]any)
+		if !ok {
+			return
+		}
+		reply := saveSettings(settingsMap)
+		if ack := getCallback(args); ack != nil {
+			ack([]any{reply}, nil)
 		}
+	})
+
+	// Handle "getPublicSettings"
+	// 站点名称、Logo、主色和页脚文字，公开仪表盘和登录页也需要，因此不要求登录
+	client.On("getPublicSettings", func(args ...any) {
+		site := db.Site()
+		if ack := getCallback(args); ack != nil {
+			ack([]any{site}, nil)
+			return
+		}
+		client.Emit("publicSettings", site)
+	})
+
+	// Handle "getStatusMeta"
+	// 状态码 → key → 名称 → 颜色 的完整映射，未登录的状态页也需要，因此不要求登录
+	client.On("getStatusMeta", func(args ...any) {
+		meta := db.StatusMetaList()
+		if ack := getCallback(args); ack != nil {
+			ack([]any{meta}, nil)
+			return
+		}
+		client.Emit("statusMeta", meta)
+	})
+
+	// Handle "previewRetentionChange" - args: {raw_hours, hourly_days, daily_days, notification_log_days}
+	// 只统计按新配置清理时会删除的数据，不修改配置也不删除任何数据；未提供的字段沿用当前配置
+	requireAuth(client, "previewRetentionChange", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		retention := config.Get().Retention
 		if len(args) > 1 {
-			ack := args[1].(func([]any, error))
-			ack([]any{map[string]any{
-				"ok":  true,
-				"msg": "Settings saved",
-			}}, nil)
+			if data, ok := args[0].(map[string]any); ok {
+				for key, field := range map[string]*int{
+					"raw_hours":   &retention.RawHours,
+					"hourly_days": &retention.HourlyDays,
+					"daily_days":  &retention.DailyDays,
+
+					"notification_log_days": &retention.NotificationLogDays,
+				} {
+					v, ok := safeMapGetFloat64(data, key)
+					if !ok {
+						continue
+					}
+					if v < 0 {
+						ack([]any{map[string]any{"ok": false, "msg": key + " must not be negative"}}, nil)
+						return
+					}
+					*field = int(v)
+				}
+			}
+		}
+		ack([]any{map[string]any{"ok": true, "tiers": db.PreviewRetention(retention)}}, nil)
+	})
+
+	// Handle "getStorageBreakdown"
+	// 按监控项统计原始/小时/日心跳的行数和估算占用，结果缓存几分钟；只读，不删除任何数据
+	requireAuth(client, "getStorageBreakdown", func(args ...any) {
+		breakdown, err := db.GetStorageBreakdown()
+		ack := getCallback(args)
+		if err != nil {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			}
+			return
+		}
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "storage": breakdown}}, nil)
+			return
+		}
+		client.Emit("storageBreakdown", breakdown)
+	})
+
+	// Handle "getSystemInfo"
+	// 版本、运行时长和数据库占用；存储统计失败时只省略 storage 字段
+	requireAuth(client, "getSystemInfo", func(args ...any) {
+		info := map[string]any{
+			"version":        serverVersion,
+			"go_version":     runtime.Version(),
+			"started_at":     startedAt,
+			"uptime_seconds": int64(time.Since(startedAt).Seconds()),
+		}
+		retention := config.Get().Retention
+		info["retention"] = map[string]int{
+			"raw_hours":   retention.RawHours,
+			"hourly_days": retention.HourlyDays,
+			"daily_days":  retention.DailyDays,
+
+			"notification_log_days": retention.NotificationLogDays,
+		}
+		if breakdown, err := db.GetStorageBreakdown(); err == nil {
+			info["storage"] = breakdown
+			info["database_bytes"] = breakdown.DatabaseBytes
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": true, "info": info}}, nil)
+			return
+		}
+		client.Emit("systemInfo", info)
+	})
+
+	// Handle "applyRetentionNow"
+	// 按当前配置立即执行清理，返回各级删除的行数
+	requireAuth(client, "applyRetentionNow", func(args ...any) {
+		reports := db.ApplyRetentionNow()
+		ok, msg := true, "Retention cleanup finished"
+		for _, r := range reports {
+			if r.Error != "" {
+				ok, msg = false, "Retention cleanup failed for "+r.Tier+" data: "+r.Error
+				break
+			}
+		}
+		if ack := getCallback(args); ack != nil {
+			ack([]any{map[string]any{"ok": ok, "msg": msg, "tiers": reports}}, nil)
 		}
 	})
 }
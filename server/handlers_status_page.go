@@ -0,0 +1,486 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/monitor"
+	apperrors "ping-go/pkg/errors"
+	"ping-go/pkg/i18n"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+	"gorm.io/gorm"
+)
+
+// statusPageDays 状态页每个监控项显示的天数（每天一根柱子，来自日聚合数据）
+const statusPageDays = 90
+
+// statusPageIncidentDays 状态页中显示最近多少天内已解决的事件
+const statusPageIncidentDays = 7
+
+// maxStatusPageMonitors 单个状态页最多包含的监控项数
+const maxStatusPageMonitors = 200
+
+// 状态页审计动作
+const (
+	auditActionStatusPageSave   = "status_page.save"
+	auditActionStatusPageDelete = "status_page.delete"
+)
+
+// statusPageData 公开状态页的数据，同时用于 JSON 接口和 HTML 页面。
+// 只包含名称、状态和可用率，不包含监控地址等配置
+type statusPageData struct {
+	Slug        string               `json:"slug"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Overall     statusPageOverall    `json:"overall"`
+	Monitors    []statusPageMonitor  `json:"monitors"`
+	Groups      []statusPageGroup    `json:"groups"`
+	Incidents   []statusPageIncident `json:"incidents"`      // 进行中的事件，最新的在前
+	Resolved    []statusPageIncident `json:"past_incidents"` // 最近 statusPageIncidentDays 天内解决的事件
+	UpdatedAt   time.Time            `json:"updated_at"`
+	expires     time.Time
+}
+
+// statusPageGroup 按监控分组划分的区块，按分组在页面中首次出现的顺序排列；
+// 未分组的监控项归入 Name 为空的区块，不显示标题
+type statusPageGroup struct {
+	Name     string              `json:"name"`
+	Status   model.StatusMeta    `json:"status"` // 区块内最严重的状态
+	Monitors []statusPageMonitor `json:"monitors"`
+}
+
+// statusPageOverall 页面顶部的总体状态
+type statusPageOverall struct {
+	Key   string `json:"key"` // operational / partial_outage / major_outage / degraded / maintenance / no_monitors
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+type statusPageMonitor struct {
+	Name   string           `json:"name"`
+	Type   string           `json:"type"`
+	Group  string           `json:"group,omitempty"`
+	Color  string           `json:"color,omitempty"`
+	Icon   string           `json:"icon,omitempty"`
+	Status model.StatusMeta `json:"status"`
+	Uptime float64          `json:"uptime"` // 最近 statusPageDays 天的可用率
+	Bars   []statusPageBar  `json:"bars"`
+}
+
+// statusPageIncident 状态页中的事件：只包含全局公告和关联了页面中监控项的事件，关联的监控项以名称表示
+type statusPageIncident struct {
+	Title         string     `json:"title"`
+	Body          string     `json:"body"`
+	Severity      string     `json:"severity"`
+	SeverityLabel string     `json:"severity_label"`
+	Status        string     `json:"status"`
+	StatusLabel   string     `json:"status_label"`
+	Monitors      []string   `json:"monitors"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	Duration      int64      `json:"duration_seconds"`
+	Color         string     `json:"color"` // 按严重程度
+	DurationLabel string     `json:"-"`
+	AffectedLabel string     `json:"-"`
+}
+
+// incidentSeverityColors 状态页中事件的颜色
+var incidentSeverityColors = map[string]string{
+	model.IncidentSeverityInfo:     "#3498db",
+	model.IncidentSeverityMinor:    "#f1c40f",
+	model.IncidentSeverityMajor:    "#e67e22",
+	model.IncidentSeverityCritical: "#e74c3c",
+}
+
+// statusPageIncidents 筛选与状态页相关的事件，names 为页面中监控项 ID 到名称的映射
+func statusPageIncidents(incidents []model.Incident, names map[uint]string, lang string, now time.Time) []statusPageIncident {
+	out := []statusPageIncident{}
+	for _, inc := range incidents {
+		affected := []string{}
+		for _, id := range inc.MonitorIDs {
+			if name, ok := names[id]; ok {
+				affected = append(affected, name)
+			}
+		}
+		if !inc.Global() && len(affected) == 0 {
+			continue
+		}
+		d := inc.Duration(now)
+		si := statusPageIncident{
+			Title: inc.Title, Body: inc.Body,
+			Severity: inc.Severity, SeverityLabel: i18n.T(lang, "incident.severity."+inc.Severity),
+			Status: inc.Status, StatusLabel: i18n.T(lang, "incident."+inc.Status),
+			Monitors: affected, CreatedAt: inc.CreatedAt, ResolvedAt: inc.ResolvedAt,
+			Duration: int64(d.Seconds()), Color: incidentSeverityColors[inc.Severity],
+			DurationLabel: fmt.Sprintf(i18n.T(lang, "statuspage.duration"), monitor.FormatDowntime(d)),
+		}
+		if len(affected) > 0 {
+			si.AffectedLabel = fmt.Sprintf(i18n.T(lang, "statuspage.affected"), strings.Join(affected, ", "))
+		}
+		out = append(out, si)
+	}
+	return out
+}
+
+type statusPageBar struct {
+	Date   string  `json:"date"`
+	Uptime float64 `json:"uptime"` // 没有数据时为 -1
+	Color  string  `json:"color"`
+	Title  string  `json:"-"`
+}
+
+var (
+	statusPageMu    sync.Mutex
+	statusPageCache = make(map[string]*statusPageData)
+)
+
+// loadStatusPageData 返回已发布状态页的数据，缓存 embedCacheTTL；状态页不存在或未发布时返回 false
+func loadStatusPageData(slug string) (*statusPageData, bool) {
+	now := time.Now()
+	statusPageMu.Lock()
+	if d, ok := statusPageCache[slug]; ok && now.Before(d.expires) {
+		statusPageMu.Unlock()
+		return d, true
+	}
+	statusPageMu.Unlock()
+
+	page, monitors, err := db.PublishedStatusPage(slug)
+	if err != nil {
+		return nil, false
+	}
+	d := &statusPageData{
+		Slug:        page.Slug,
+		Title:       page.Title,
+		Description: page.Description,
+		Monitors:    make([]statusPageMonitor, 0, len(monitors)),
+		Groups:      []statusPageGroup{},
+		UpdatedAt:   now,
+		expires:     now.Add(embedCacheTTL),
+	}
+	groups, _ := db.MonitorGroups()
+	groupNames := monitorGroupNames(groups)
+	statuses := make([]int, 0, len(monitors))
+	sections := make(map[string]int)
+	var sectionStatuses [][]int
+	for _, m := range monitors {
+		status := m.Status
+		if m.Active == 0 {
+			status = model.StatusMaintenance
+		}
+		statuses = append(statuses, status)
+		sm := statusPageMonitor{Name: m.Name, Type: string(m.Type), Group: groupNames[m.GroupID], Color: m.Color, Icon: m.Icon, Status: db.StatusMeta(status), Uptime: 100}
+		var up, total int64
+		for _, b := range db.GetDailyUptimeBars(m.ID, statusPageDays) {
+			up, total = up+b.Total-b.Down, total+b.Total
+			bar := statusPageBar{Date: b.Date.Format("2006-01-02"), Uptime: b.Uptime, Color: embedBarColor(b), Title: embedBarTitle(b)}
+			if b.Total == 0 {
+				bar.Uptime = -1
+			}
+			sm.Bars = append(sm.Bars, bar)
+		}
+		if total > 0 {
+			sm.Uptime = float64(up) / float64(total) * 100.0
+		}
+		d.Monitors = append(d.Monitors, sm)
+
+		i, ok := sections[sm.Group]
+		if !ok {
+			i = len(d.Groups)
+			sections[sm.Group] = i
+			d.Groups = append(d.Groups, statusPageGroup{Name: sm.Group})
+			sectionStatuses = append(sectionStatuses, nil)
+		}
+		d.Groups[i].Monitors = append(d.Groups[i].Monitors, sm)
+		sectionStatuses[i] = append(sectionStatuses[i], status)
+	}
+	for i := range d.Groups {
+		d.Groups[i].Status = db.StatusMeta(model.WorstStatus(sectionStatuses[i]))
+	}
+	d.Overall = overallStatus(statuses)
+
+	lang := db.Language()
+	names := make(map[uint]string, len(monitors))
+	for _, m := range monitors {
+		names[m.ID] = m.Name
+	}
+	active, _ := db.ActiveIncidents()
+	d.Incidents = statusPageIncidents(active, names, lang, now)
+	resolved, _ := db.ResolvedIncidentsSince(now.AddDate(0, 0, -statusPageIncidentDays))
+	d.Resolved = statusPageIncidents(resolved, names, lang, now)
+
+	statusPageMu.Lock()
+	for k, v := range statusPageCache {
+		if now.After(v.expires) {
+			delete(statusPageCache, k)
+		}
+	}
+	statusPageCache[slug] = d
+	statusPageMu.Unlock()
+	return d, true
+}
+
+// clearStatusPageCache 状态页被修改或导入历史心跳后清空缓存
+func clearStatusPageCache() {
+	statusPageMu.Lock()
+	clear(statusPageCache)
+	statusPageMu.Unlock()
+}
+
+// overallStatus 根据各监控项的状态计算总体状态：全部 DOWN 为 major_outage，部分 DOWN 为 partial_outage，
+// 其次是 PENDING（degraded）和维护中，其余为 operational
+func overallStatus(statuses []int) statusPageOverall {
+	lang := db.Language()
+	if len(statuses) == 0 {
+		return statusPageOverall{Key: "no_monitors", Label: i18n.T(lang, "statuspage.no_monitors"), Color: db.StatusMeta(model.StatusNoData).Color}
+	}
+	count := make(map[int]int)
+	for _, s := range statuses {
+		count[s]++
+	}
+	key, color := "operational", model.StatusUp
+	switch {
+	case count[model.StatusDown] == len(statuses):
+		key, color = "major_outage", model.StatusDown
+	case count[model.StatusDown] > 0:
+		key, color = "partial_outage", model.StatusDown
+	case count[model.StatusPending] > 0:
+		key, color = "degraded", model.StatusPending
+	case count[model.StatusMaintenance] > 0:
+		key, color = "maintenance", model.StatusMaintenance
+	}
+	return statusPageOverall{Key: key, Label: i18n.T(lang, "statuspage."+key), Color: db.StatusMeta(color).Color}
+}
+
+// statusPageAPI 处理 GET /api/status/:slug：返回已发布状态页的数据（JSON），不需要登录
+func (s *Server) statusPageAPI(c *gin.Context) {
+	data, ok := loadStatusPageData(c.Param("slug"))
+	if !ok {
+		writeAPIError(c, apperrors.New(http.StatusNotFound, "Status page not found", http.StatusNotFound, nil))
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedCacheTTL.Seconds())))
+	c.JSON(http.StatusOK, data)
+}
+
+// statusPage 处理 GET /status/:slug：输出已发布的状态页，不需要登录
+// 页面为自包含 HTML（内联 CSS，无外部资源），通过 meta refresh 定时刷新；同样的数据可以从 /api/status/:slug 获取
+func (s *Server) statusPage(c *gin.Context) {
+	setEmbedFrameHeaders(c)
+	data, ok := loadStatusPageData(c.Param("slug"))
+	if !ok {
+		c.String(http.StatusNotFound, "status page not found")
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedCacheTTL.Seconds())))
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	lang := db.Language()
+	if err := statusPageTemplate.Execute(c.Writer, map[string]any{
+		"Data":     data,
+		"Lang":     lang,
+		"Since":    fmt.Sprintf(i18n.T(lang, "embed.days_ago"), statusPageDays),
+		"Today":    i18n.T(lang, "embed.today"),
+		"Updated":  fmt.Sprintf(i18n.T(lang, "statuspage.updated"), data.UpdatedAt.In(db.Location()).Format("2006-01-02 15:04")),
+		"Refresh":  int(embedCacheTTL.Seconds()),
+		"Active":   i18n.T(lang, "statuspage.incidents"),
+		"Past":     fmt.Sprintf(i18n.T(lang, "statuspage.past_incidents"), statusPageIncidentDays),
+		"Location": db.Location(),
+		// 邮件中的确认和退订链接需要 server.external_url，未配置时不显示订阅表单
+		"Subscribe":       config.Get().Server.ExternalURL != "" && !demoActive(),
+		"SubscribeLabel":  i18n.T(lang, "subscription.form_label"),
+		"SubscribeSubmit": i18n.T(lang, "subscription.form_submit"),
+	}); err != nil {
+		c.Error(err)
+	}
+}
+
+// setupStatusPageHandlers 设置状态页管理相关的 Socket.IO 事件处理器
+func (s *Server) setupStatusPageHandlers(client *socket.Socket) {
+	// Handle "getStatusPages"
+	// 返回全部状态页（包括未发布的）及其监控项 ID
+	requireAuth(client, "getStatusPages", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		pages, err := db.StatusPages()
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "pages": pages}}, nil)
+	})
+
+	// Handle "saveStatusPage" - args: ({id, slug, title, description, published, monitor_ids})
+	// id 为 0 或省略时创建；monitor_ids 的顺序即展示顺序
+	requireAuth(client, "saveStatusPage", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		page := model.StatusPage{
+			Slug:        strings.ToLower(strings.TrimSpace(safeMapGetString(data, "slug"))),
+			Title:       strings.TrimSpace(safeMapGetString(data, "title")),
+			Description: strings.TrimSpace(safeMapGetString(data, "description")),
+		}
+		if v, ok := safeMapGetFloat64(data, "id"); ok && v > 0 {
+			page.ID = uint(v)
+		}
+		page.Published, _ = data["published"].(bool)
+		if !model.ValidStatusPageSlug(page.Slug) {
+			ack([]any{map[string]any{"ok": false, "msg": "slug 只能包含小写字母、数字和连字符（1-64 个字符），且不能以连字符开头或结尾"}}, nil)
+			return
+		}
+		if page.Title == "" {
+			ack([]any{map[string]any{"ok": false, "msg": "标题不能为空"}}, nil)
+			return
+		}
+		var monitorIDs []uint
+		if list, ok := data["monitor_ids"].([]any); ok {
+			for _, v := range list {
+				if f, ok := v.(float64); ok && f > 0 {
+					monitorIDs = append(monitorIDs, uint(f))
+				}
+			}
+		}
+		if len(monitorIDs) > maxStatusPageMonitors {
+			ack([]any{map[string]any{"ok": false, "msg": fmt.Sprintf("每个状态页最多 %d 个监控项", maxStatusPageMonitors)}}, nil)
+			return
+		}
+
+		if err := db.SaveStatusPage(&page, monitorIDs); err != nil {
+			msg := err.Error()
+			switch {
+			case errors.Is(err, db.ErrSlugTaken):
+				msg = fmt.Sprintf("slug %q 已被其他状态页使用", page.Slug)
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				msg = fmt.Sprintf("状态页 %d 不存在", page.ID)
+			}
+			ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			return
+		}
+		clearStatusPageCache()
+		db.RecordAudit(socketActor(client), auditActionStatusPageSave, fmt.Sprintf("status_page:%d", page.ID),
+			fmt.Sprintf("slug=%s published=%t monitors=%d", page.Slug, page.Published, len(monitorIDs)))
+		ack([]any{map[string]any{"ok": true, "msg": "Status page saved", "page": page}}, nil)
+	})
+
+	// Handle "deleteStatusPage" - args: (id)
+	requireAuth(client, "deleteStatusPage", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "id is required"}}, nil)
+			}
+			return
+		}
+		if err := db.DeleteStatusPage(id); err != nil {
+			if ack != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("状态页 %d 不存在", id)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+		clearStatusPageCache()
+		db.RecordAudit(socketActor(client), auditActionStatusPageDelete, fmt.Sprintf("status_page:%d", id), "deleted")
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "msg": "Status page deleted"}}, nil)
+		}
+	})
+}
+
+var statusPageTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Data.Title}}</title>
+<link rel="alternate" type="application/atom+xml" title="{{.Data.Title}}" href="/status/{{.Data.Slug}}/feed.xml">
+<style>
+body{margin:0;padding:32px 16px;font:14px -apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif;background:#f6f9fc;color:#2d3436}
+main{max-width:760px;margin:0 auto}
+h1{margin:0 0 8px;font-size:26px}
+.desc{margin:0 0 20px;color:#636e72;white-space:pre-line}
+.banner{padding:16px 20px;border-radius:10px;color:#fff;font-size:16px;font-weight:600;margin-bottom:24px}
+.card{background:#fff;border-radius:10px;padding:16px 20px;margin-bottom:12px;box-shadow:0 1px 3px rgba(0,0,0,.06)}
+.head{display:flex;justify-content:space-between;align-items:center;margin-bottom:10px;gap:12px}
+.name{font-weight:600;overflow:hidden;text-overflow:ellipsis;white-space:nowrap}
+.mark{display:inline-block;width:4px;height:14px;border-radius:2px;margin-right:8px;vertical-align:-2px}
+.status{font-weight:600;white-space:nowrap}
+.dot{display:inline-block;width:9px;height:9px;border-radius:50%;margin-right:6px}
+.bars{display:flex;gap:2px;height:32px}
+.bars span{flex:1;border-radius:2px}
+.foot{display:flex;justify-content:space-between;margin-top:6px;font-size:12px;color:#636e72}
+.updated{text-align:center;font-size:12px;color:#95a5a6;margin-top:24px}
+details{margin-bottom:16px}
+summary{display:flex;justify-content:space-between;align-items:center;cursor:pointer;padding:8px 4px;font-size:16px;font-weight:600;list-style:none}
+summary::-webkit-details-marker{display:none}
+summary::before{content:"\25be";margin-right:8px;color:#95a5a6}
+details:not([open]) summary::before{content:"\25b8"}
+summary .title{flex:1}
+h2{font-size:16px;margin:24px 0 12px}
+.incident{border-left:4px solid}
+.incident .head{margin-bottom:6px}
+.incident .body{margin:0 0 6px;white-space:pre-line}
+.incident .meta{font-size:12px;color:#636e72}
+.subscribe{display:flex;gap:8px;align-items:center;flex-wrap:wrap;margin-top:24px}
+.subscribe label{font-weight:600;margin-right:4px}
+.subscribe input{flex:1;min-width:180px;padding:8px 10px;border:1px solid #dfe4ea;border-radius:6px;font:inherit}
+.subscribe button{padding:8px 16px;border:0;border-radius:6px;background:#0984e3;color:#fff;font:inherit;cursor:pointer}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Data.Title}}</h1>
+{{with .Data.Description}}<p class="desc">{{.}}</p>{{end}}
+<div class="banner" style="background:{{.Data.Overall.Color}}">{{.Data.Overall.Label}}</div>
+{{with .Data.Incidents}}<h2>{{$.Active}}</h2>
+{{range .}}<div class="card incident" style="border-color:{{.Color}}">
+<div class="head"><span class="name">{{.Title}}</span><span class="status">{{.SeverityLabel}} · {{.StatusLabel}}</span></div>
+{{with .Body}}<p class="body">{{.}}</p>{{end}}
+<div class="meta">{{(.CreatedAt.In $.Location).Format "2006-01-02 15:04"}} · {{.DurationLabel}}{{with .AffectedLabel}} · {{.}}{{end}}</div>
+</div>
+{{end}}{{end}}{{range .Data.Groups}}{{if .Name}}<details open>
+<summary><span class="title">{{.Name}}</span><span class="status"><span class="dot" style="background:{{.Status.Color}}"></span>{{.Status.Label}}</span></summary>
+{{end}}{{range .Monitors}}<div class="card">
+<div class="head">
+<span class="name">{{with .Color}}<span class="mark" style="background:{{.}}"></span>{{end}}{{.Name}}</span>
+<span class="status"><span class="dot" style="background:{{.Status.Color}}"></span>{{.Status.Label}}</span>
+</div>
+<div class="bars">{{range .Bars}}<span style="background:{{.Color}}" title="{{.Title}}"></span>{{end}}</div>
+<div class="foot"><span>{{$.Since}}</span><span>{{printf "%.2f" .Uptime}}%</span><span>{{$.Today}}</span></div>
+</div>
+{{end}}{{if .Name}}</details>
+{{end}}{{end}}{{with .Data.Resolved}}<h2>{{$.Past}}</h2>
+{{range .}}<div class="card incident" style="border-color:#dfe4ea">
+<div class="head"><span class="name">{{.Title}}</span><span class="status">{{.StatusLabel}}</span></div>
+<div class="meta">{{(.CreatedAt.In $.Location).Format "2006-01-02 15:04"}} · {{.DurationLabel}}{{with .AffectedLabel}} · {{.}}{{end}}</div>
+</div>
+{{end}}{{end}}{{if .Subscribe}}<form class="card subscribe" method="post" action="/status/{{.Data.Slug}}/subscribe">
+<label for="email">{{.SubscribeLabel}}</label><input id="email" name="email" type="email" required maxlength="254" placeholder="you@example.com"><button type="submit">{{.SubscribeSubmit}}</button>
+</form>
+{{end}}<p class="updated">{{.Updated}}</p>
+</main>
+</body>
+</html>
+`))
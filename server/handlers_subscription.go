@@ -0,0 +1,251 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/mail"
+	"ping-go/config"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/monitor"
+	"ping-go/notification"
+	"ping-go/pkg/i18n"
+	"ping-go/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zishang520/socket.io/socket"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 订阅接口按 IP 限流：每 subscribeInterval 补充一次，最多连续 subscribeBurst 次
+	subscribeInterval = time.Minute
+	subscribeBurst    = 5
+	// subscriptionConfirmCooldown 同一邮箱重复提交时，再次发送确认邮件的最短间隔
+	subscriptionConfirmCooldown = 10 * time.Minute
+	// maxEmailLength 邮箱地址的最大长度（RFC 5321）
+	maxEmailLength = 254
+)
+
+// auditActionSubscriptionDelete 管理员删除订阅者的审计动作
+const auditActionSubscriptionDelete = "subscription.delete"
+
+// subscriptionEmail 校验并规范化访客提交的邮箱地址：只接受不带显示名的纯地址，统一转为小写
+func subscriptionEmail(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > maxEmailLength {
+		return "", false
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || !strings.Contains(s[strings.LastIndexByte(s, '@')+1:], ".") {
+		return "", false
+	}
+	return strings.ToLower(s), true
+}
+
+// subscriptionResult 输出订阅接口的结果：JSON 请求返回 {ok, msg}，表单提交和邮件中的链接返回简单的 HTML 页面
+func subscriptionResult(c *gin.Context, status int, page *model.StatusPage, msg string) {
+	if c.ContentType() == "application/json" {
+		c.JSON(status, gin.H{"ok": status < http.StatusBadRequest, "msg": msg})
+		return
+	}
+	setEmbedFrameHeaders(c)
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	lang := db.Language()
+	data := map[string]any{"Lang": lang, "Title": db.SiteName(), "Message": msg}
+	if page != nil {
+		data["Title"], data["Slug"], data["Back"] = page.Title, page.Slug, i18n.T(lang, "subscription.back")
+	}
+	if err := subscriptionTemplate.Execute(c.Writer, data); err != nil {
+		c.Error(err)
+	}
+}
+
+// sendSubscriptionConfirm 发送带签名确认链接的确认邮件
+func sendSubscriptionConfirm(page model.StatusPage, sub model.Subscription) {
+	cfg := config.Get()
+	lang := db.Language()
+	link := cfg.SubscriptionURL(model.SubscriptionConfirm, sub.ID, sub.Token(model.SubscriptionConfirm))
+	title := fmt.Sprintf(i18n.T(lang, "subscription.confirm_title"), page.Title)
+	body := monitor.SubscriptionEmailHTML(title, fmt.Sprintf(i18n.T(lang, "subscription.confirm_body"), page.Title),
+		i18n.T(lang, "subscription.confirm_action"), link, fmt.Sprintf(i18n.T(lang, "subscription.footer"), page.Title), "", "")
+	if err := notification.SendEmail([]string{sub.Email}, title, body); err != nil {
+		logger.Error("Failed to send subscription confirmation", zap.Uint("subscriptionID", sub.ID), zap.Error(err))
+	}
+}
+
+// subscribe 处理 POST /status/:slug/subscribe：为已发布的状态页登记邮件订阅并发送确认邮件，不需要登录。
+// 参数 email 可以是表单字段或 JSON；按 IP 限流。为避免泄露邮箱是否已订阅，已订阅和重复提交返回同样的结果
+func (s *Server) subscribe(c *gin.Context) {
+	lang := db.Language()
+	if ok, wait := s.subscribeLimiter.Allow(c.ClientIP()); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		subscriptionResult(c, http.StatusTooManyRequests, nil, i18n.T(lang, "subscription.rate_limited"))
+		return
+	}
+	page, _, err := db.PublishedStatusPage(c.Param("slug"))
+	if err != nil {
+		c.String(http.StatusNotFound, "status page not found")
+		return
+	}
+	// 邮件中的确认和退订链接需要外部访问地址
+	if config.Get().Server.ExternalURL == "" {
+		subscriptionResult(c, http.StatusServiceUnavailable, page, i18n.T(lang, "subscription.unavailable"))
+		return
+	}
+	var req struct {
+		Email string `form:"email" json:"email"`
+	}
+	c.ShouldBind(&req)
+	email, ok := subscriptionEmail(req.Email)
+	if !ok {
+		subscriptionResult(c, http.StatusBadRequest, page, i18n.T(lang, "subscription.invalid_email"))
+		return
+	}
+
+	sub, send, err := db.RequestSubscription(page.ID, email, subscriptionConfirmCooldown)
+	if err != nil {
+		logger.Error("Failed to save subscription", zap.String("slug", page.Slug), zap.Error(err))
+		subscriptionResult(c, http.StatusInternalServerError, page, i18n.T(lang, "subscription.unavailable"))
+		return
+	}
+	if send {
+		go sendSubscriptionConfirm(*page, *sub)
+	}
+	subscriptionResult(c, http.StatusAccepted, page, i18n.T(lang, "subscription.check_inbox"))
+}
+
+// subscriptionFromLink 按链接中的 id 和 token 查找订阅并校验签名，同时返回订阅的状态页
+func subscriptionFromLink(c *gin.Context, action string) (*model.Subscription, *model.StatusPage, bool) {
+	id, _ := strconv.ParseUint(c.Query("id"), 10, 64)
+	sub, err := db.GetSubscription(uint(id))
+	if err != nil || !sub.VerifyToken(action, c.Query("token")) {
+		subscriptionResult(c, http.StatusNotFound, nil, i18n.T(db.Language(), "subscription.invalid_link"))
+		return nil, nil, false
+	}
+	var page model.StatusPage
+	db.DB.Where("id = ?", sub.StatusPageID).Limit(1).Find(&page)
+	return sub, &page, true
+}
+
+// confirmSubscription 处理 GET /subscriptions/confirm：确认邮件中的链接
+func (s *Server) confirmSubscription(c *gin.Context) {
+	sub, page, ok := subscriptionFromLink(c, model.SubscriptionConfirm)
+	if !ok {
+		return
+	}
+	if err := db.ConfirmSubscription(sub); err != nil {
+		subscriptionResult(c, http.StatusInternalServerError, page, i18n.T(db.Language(), "subscription.unavailable"))
+		return
+	}
+	subscriptionResult(c, http.StatusOK, page, fmt.Sprintf(i18n.T(db.Language(), "subscription.confirmed"), page.Title))
+}
+
+// unsubscribe 处理 GET/POST /subscriptions/unsubscribe：邮件中的退订链接；POST 用于邮件客户端的一键退订（RFC 8058）
+func (s *Server) unsubscribe(c *gin.Context) {
+	sub, page, ok := subscriptionFromLink(c, model.SubscriptionUnsubscribe)
+	if !ok {
+		return
+	}
+	if err := db.DeleteSubscription(sub.ID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		subscriptionResult(c, http.StatusInternalServerError, page, i18n.T(db.Language(), "subscription.unavailable"))
+		return
+	}
+	subscriptionResult(c, http.StatusOK, page, fmt.Sprintf(i18n.T(db.Language(), "subscription.unsubscribed"), page.Title))
+}
+
+// notifyIncidentSubscribers 事件公告发布或解决时通知相关状态页的订阅者，全局公告发给所有已发布状态页的订阅者
+func (s *Server) notifyIncidentSubscribers(inc *model.Incident) {
+	lang := db.Language()
+	subject := fmt.Sprintf("[%s] %s", i18n.T(lang, "incident.severity."+inc.Severity), inc.Title)
+	if !inc.Active() {
+		subject = fmt.Sprintf(i18n.T(lang, "feed.incident_resolved"), inc.Title)
+	}
+	s.monitorService.NotifySubscribers(monitor.SubscriberUpdate{
+		MonitorIDs: inc.MonitorIDs,
+		Subject:    subject,
+		Body:       strings.TrimSpace(i18n.T(lang, "incident."+inc.Status) + "\n\n" + inc.Body),
+	})
+}
+
+// setupSubscriptionHandlers 设置状态页订阅者管理相关的 Socket.IO 事件处理器
+func (s *Server) setupSubscriptionHandlers(client *socket.Socket) {
+	// Handle "getSubscriptions"
+	// 返回全部订阅者（包括未确认的），最新的在前
+	requireAuth(client, "getSubscriptions", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		subs, err := db.Subscriptions()
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "subscriptions": subs}}, nil)
+	})
+
+	// Handle "deleteSubscription" - args: (id)
+	requireAuth(client, "deleteSubscription", func(args ...any) {
+		ack := getCallback(args)
+		id, err := getArgAsUint(args, 0)
+		if err != nil || id == 0 {
+			if ack != nil {
+				ack([]any{map[string]any{"ok": false, "msg": "id is required"}}, nil)
+			}
+			return
+		}
+		sub, err := db.GetSubscription(id)
+		if err == nil {
+			err = db.DeleteSubscription(id)
+		}
+		if err != nil {
+			if ack != nil {
+				msg := err.Error()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					msg = fmt.Sprintf("订阅 %d 不存在", id)
+				}
+				ack([]any{map[string]any{"ok": false, "msg": msg}}, nil)
+			}
+			return
+		}
+		db.RecordAudit(socketActor(client), auditActionSubscriptionDelete, fmt.Sprintf("subscription:%d", id),
+			fmt.Sprintf("email=%s status_page=%d", sub.Email, sub.StatusPageID))
+		if ack != nil {
+			ack([]any{map[string]any{"ok": true, "msg": "Subscription deleted"}}, nil)
+		}
+	})
+}
+
+var subscriptionTemplate = template.Must(template.New("subscription").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body{margin:0;padding:64px 16px;font:14px -apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif;background:#f6f9fc;color:#2d3436}
+main{max-width:480px;margin:0 auto;background:#fff;border-radius:10px;padding:24px;box-shadow:0 1px 3px rgba(0,0,0,.06)}
+h1{margin:0 0 12px;font-size:20px}
+p{margin:0 0 16px;line-height:1.6}
+a{color:#0984e3}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{with .Slug}}<a href="/status/{{.}}">{{$.Back}}</a>{{end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+	"ping-go/pkg/i18n"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// templateKinds 可以自定义的邮件模板
+var templateKinds = []string{notification.TemplateStatusChange, notification.TemplateDailyReport}
+
+// setupTemplateHandlers 设置自定义邮件模板相关的 Socket.IO 事件处理器
+func (s *Server) setupTemplateHandlers(client *socket.Socket) {
+	// Handle "getNotificationTemplates" - args: ({language}) 可省略
+	// 返回已保存的自定义模板、内置模板源码和每种模板可用的变量；内置模板为 language 的版本，默认为邮件语言
+	requireAuth(client, "getNotificationTemplates", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		lang := db.EmailLanguage()
+		if len(args) > 1 {
+			if data, ok := args[0].(map[string]any); ok {
+				if l := safeMapGetString(data, "language"); i18n.Supported(l) {
+					lang = l
+				}
+			}
+		}
+		templates, err := db.CustomTemplates()
+		if err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		builtin := make(map[string]string, len(templateKinds))
+		for _, kind := range templateKinds {
+			builtin[kind], _ = notification.BuiltinTemplate(kind, lang)
+		}
+		ack([]any{map[string]any{
+			"ok":        true,
+			"templates": templates,
+			"builtin":   builtin,
+			"language":  lang,
+			"languages": i18n.Languages(),
+			"variables": notification.TemplateVariables,
+		}}, nil)
+	})
+
+	// Handle "setNotificationTemplate" - args: ({kind, rule_id, source, version})
+	// rule_id 为 0 或省略时为全局模板；source 为空时删除，恢复使用全局或内置模板。
+	// 模板错误在保存时返回，带有出错的行号和列号（line / column）
+	requireAuth(client, "setNotificationTemplate", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		kind := safeMapGetString(data, "kind")
+		if _, err := notification.BuiltinTemplate(kind, ""); err != nil {
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+		var ruleID uint
+		if v, ok := safeMapGetFloat64(data, "rule_id"); ok && v > 0 {
+			ruleID = uint(v)
+			if err := db.DB.First(&model.Notification{}, ruleID).Error; err != nil {
+				ack([]any{map[string]any{"ok": false, "msg": fmt.Sprintf("通知规则 %d 不存在", ruleID)}}, nil)
+				return
+			}
+		}
+		source := safeMapGetString(data, "source")
+		if source != "" {
+			if err := notification.CheckTemplate(kind, source); err != nil {
+				ack([]any{templateErrorReply(err)}, nil)
+				return
+			}
+		}
+
+		base, _ := safeMapGetFloat64(data, "version")
+		if err := db.SetNotificationTemplate(kind, ruleID, source, int(base)); err != nil {
+			if errors.Is(err, db.ErrVersionConflict) {
+				ack([]any{map[string]any{"ok": false, "code": 409, "conflict": true, "msg": "模板已被他人修改，请重新加载后再保存"}}, nil)
+				return
+			}
+			ack([]any{map[string]any{"ok": false, "msg": err.Error()}}, nil)
+			return
+		}
+
+		detail := "updated"
+		if source == "" {
+			detail = "reset to default"
+		}
+		db.RecordAudit(socketActor(client), db.AuditActionTemplateUpdate, db.TemplateSettingKey(kind, ruleID), detail)
+		ack([]any{map[string]any{"ok": true, "msg": "Template saved"}}, nil)
+	})
+
+	// Handle "previewTemplate" - args: ({kind, source, language})
+	// 用示例数据渲染模板并返回 HTML，不保存；source 为空时渲染内置模板，language 省略时使用邮件语言
+	requireAuth(client, "previewTemplate", func(args ...any) {
+		ack := getCallback(args)
+		if len(args) < 1 || ack == nil {
+			return
+		}
+		data, ok := args[0].(map[string]any)
+		if !ok {
+			ack([]any{map[string]any{"ok": false, "msg": "Invalid arguments"}}, nil)
+			return
+		}
+		source := safeMapGetString(data, "source")
+		if len(source) > notification.MaxTemplateBytes {
+			ack([]any{map[string]any{"ok": false, "msg": fmt.Sprintf("模板不能超过 %d 字节", notification.MaxTemplateBytes)}}, nil)
+			return
+		}
+		lang := safeMapGetString(data, "language")
+		if !i18n.Supported(lang) {
+			lang = db.EmailLanguage()
+		}
+		html, err := notification.PreviewTemplate(safeMapGetString(data, "kind"), source, lang)
+		if err != nil {
+			ack([]any{templateErrorReply(err)}, nil)
+			return
+		}
+		ack([]any{map[string]any{"ok": true, "html": html}}, nil)
+	})
+}
+
+// templateErrorReply 模板错误的回执，能定位时附带 line / column
+func templateErrorReply(err error) map[string]any {
+	reply := map[string]any{"ok": false, "msg": err.Error()}
+	var te *notification.TemplateError
+	if errors.As(err, &te) {
+		reply["line"], reply["column"] = te.Line, te.Column
+	}
+	return reply
+}
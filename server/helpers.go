@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"ping-go/db"
 	"ping-go/model"
+	"ping-go/monitor"
 	"strings"
 	"time"
 
@@ -50,92 +51,75 @@ func (s *Server) serveStaticFileHelper(c *gin.Context, filename string) {
 	}
 }
 
-// getMonitorsAPI REST API 处理器
-func (s *Server) getMonitorsAPI(c *gin.Context) {
-	var monitors []model.Monitor
-	if err := db.DB.Find(&monitors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	c.JSON(http.StatusOK, monitors)
-}
-
-// createMonitorAPI REST API 处理器
-func (s *Server) createMonitorAPI(c *gin.Context) {
-	var m model.Monitor
-	if err := c.ShouldBindJSON(&m); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	if m.Interval < 20 {
-		m.Interval = 20
-	}
-
-	if err := db.DB.Create(&m).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	s.monitorService.StartMonitor(&m)
-	c.JSON(http.StatusCreated, m)
-}
-
-// deleteMonitorAPI REST API 处理器
-func (s *Server) deleteMonitorAPI(c *gin.Context) {
-	idStr := c.Param("id")
-	var id uint64
-	fmt.Sscanf(idStr, "%d", &id)
-
-	if err := db.DB.Delete(&model.Monitor{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	s.monitorService.StopMonitor(uint(id))
-	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
-}
-
 // broadcastMonitorList 广播监控列表给所有客户端
 func (s *Server) broadcastMonitorList() {
 	var monitors []model.Monitor
 	db.DB.Find(&monitors)
+	groups, _ := db.MonitorGroups()
+	groupNames := monitorGroupNames(groups)
+	incidents, _ := db.ActiveIncidents()
 
 	publicData := make(map[uint]map[string]any)
 	adminData := make(map[uint]map[string]any)
+	var publicMonitors []model.Monitor
 
 	for _, m := range monitors {
 		data := make(map[string]any)
 		data["id"] = m.ID
 		data["name"] = m.Name
 		data["type"] = m.Type
+		data["color"] = m.Color
+		data["icon"] = m.Icon
+		data["group_id"] = m.GroupID
+		data["group"] = groupNames[m.GroupID]
 		data["interval"] = m.Interval
 		data["active"] = m.Active
 		data["status"] = m.Status
+		data["status_key"] = model.StatusKey(m.Status)
 		data["msg"] = m.Message
 		data["last_check"] = m.LastCheck
 		data["recentResults"] = s.getRecentResults(m.ID)
+		data["incidents"] = monitorIncidentRefs(incidents, m.ID)
 
-		pData := make(map[string]any)
-		for k, v := range data {
-			pData[k] = v
+		// 未登录的客户端只能看到 PublicVisible 的监控项，检查消息只保留类别
+		if m.PublicVisible {
+			pData := make(map[string]any)
+			for k, v := range data {
+				pData[k] = v
+			}
+			pData["msg"] = monitor.PublicMessage(m.Status, m.Message)
+			publicData[m.ID] = pData
+			publicMonitors = append(publicMonitors, m)
 		}
-		publicData[m.ID] = pData
 
 		aData := make(map[string]any)
 		for k, v := range data {
 			aData[k] = v
 		}
 		aData["url"] = m.URL
+		aData["tags"] = m.Tags
 		adminData[m.ID] = aData
 	}
-
-	s.socketServer.To("public").Emit("monitorList", publicData)
+	cacheMonitorTags(monitors)
+	cachePublicVisibility(monitors)
+
+	// 管理员同时在 public 房间中，公开的列表不发给管理员
+	public := s.socketServer.To("public").Except("admin")
+	public.Emit("monitorList", publicData)
+	public.Emit("monitorGroupList", monitorGroupSummaries(groups, publicMonitors, nil))
+	public.Emit("incidentList", publicIncidents(incidents, publicMonitors, nil))
 	s.socketServer.To("admin").Emit("adminMonitorList", adminData)
+	s.socketServer.To("admin").Emit("monitorGroupList", monitorGroupSummaries(groups, monitors, nil))
+	s.socketServer.To("admin").Emit("incidentList", publicIncidents(incidents, monitors, nil))
+	broadcastScopedMonitorLists(monitors, groups, incidents, adminData)
+	if demoActive() {
+		s.socketServer.To("demo").Emit("adminMonitorList", demoMonitorList(adminData))
+	}
 }
 
 // sendMonitorList 发送监控列表给单个客户端
 func (s *Server) sendMonitorList(client *socket.Socket) {
+	expireDemoAuth(client)
 	var monitors []model.Monitor
 	db.DB.Find(&monitors)
 	monitorData := make(map[uint]map[string]any)
@@ -148,42 +132,90 @@ func (s *Server) sendMonitorList(client *socket.Socket) {
 			}
 		}
 	}
+	scope := socketScope(client)
+	cacheMonitorTags(monitors)
+	cachePublicVisibility(monitors)
+	// 未登录的客户端只能看到 PublicVisible 的监控项
+	if !isAuth {
+		visible := monitors[:0]
+		for _, m := range monitors {
+			if m.PublicVisible {
+				visible = append(visible, m)
+			}
+		}
+		monitors = visible
+	}
+	groups, _ := db.MonitorGroups()
+	groupNames := monitorGroupNames(groups)
+	incidents, _ := db.ActiveIncidents()
 
 	for _, m := range monitors {
+		if !m.InScope(scope) {
+			continue
+		}
 		data := make(map[string]any)
 		data["id"] = m.ID
 		data["name"] = m.Name
 		if isAuth {
 			data["url"] = m.URL
+			data["tags"] = m.Tags
+			if demoActive() {
+				data["url"] = maskURL(m.URL)
+			}
 		}
 		data["type"] = m.Type
+		data["color"] = m.Color
+		data["icon"] = m.Icon
+		data["group_id"] = m.GroupID
+		data["group"] = groupNames[m.GroupID]
 		data["interval"] = m.Interval
 		data["active"] = m.Active
 		data["status"] = m.Status
+		data["status_key"] = model.StatusKey(m.Status)
 		data["msg"] = m.Message
+		if !isAuth {
+			data["msg"] = monitor.PublicMessage(m.Status, m.Message)
+		}
 		data["last_check"] = m.LastCheck
 		data["recentResults"] = s.getRecentResults(m.ID)
+		data["incidents"] = monitorIncidentRefs(incidents, m.ID)
 		monitorData[m.ID] = data
 	}
+	client.Emit("monitorGroupList", monitorGroupSummaries(groups, monitors, scope))
+	client.Emit("incidentList", publicIncidents(incidents, monitors, scope))
 
 	if isAuth {
 		client.Emit("adminMonitorList", monitorData)
+		// 启动调度尚未完成时，让刚登录的管理员也能看到进度
+		if p := s.monitorService.GetStartupProgress(); p.Starting && scope == nil && !demoActive() {
+			client.Emit("startupProgress", startupProgressView(p))
+		}
 	} else {
 		client.Emit("monitorList", monitorData)
 	}
 }
 
+// startupProgressView 启动进度推送内容，msg 如 "250/1000 scheduled"
+func startupProgressView(p monitor.StartupProgress) map[string]any {
+	return map[string]any{
+		"starting":  p.Starting,
+		"scheduled": p.Scheduled,
+		"total":     p.Total,
+		"msg":       fmt.Sprintf("%d/%d scheduled", p.Scheduled, p.Total),
+	}
+}
+
 // getRecentResults 获取最近的30条监控结果
 func (s *Server) getRecentResults(monitorID uint) []int {
 	var statuses []int
 	db.DB.Model(&model.Heartbeat{}).
-		Where("monitor_id = ?", monitorID).
+		Where("monitor_id = ? AND agent_id = 0", monitorID).
 		Order("time desc").
 		Limit(30).
 		Pluck("status", &statuses)
 
 	for len(statuses) < 30 {
-		statuses = append(statuses, -1)
+		statuses = append(statuses, model.StatusNoData)
 	}
 
 	for i, j := 0, len(statuses)-1; i < j; i, j = i+1, j-1 {
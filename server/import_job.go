@@ -0,0 +1,463 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/notification"
+	"ping-go/pkg/logger"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// importBatchSize 每个事务写入的监控项数量，失败时只回滚当前批次
+	importBatchSize = 50
+	// importStaggerWindow 导入完成后启动监控的错开时间窗口
+	importStaggerWindow = 30 * time.Second
+)
+
+// importJob 后台导入任务的进度与结果，通过 importProgress 事件推送
+type importJob struct {
+	ID           string          `json:"job_id"`
+	Total        int             `json:"total"`
+	Processed    int             `json:"processed"`
+	Imported     int             `json:"imported"`
+	Skipped      int             `json:"skipped"`
+	SkippedNames []string        `json:"skippedNames"`
+	Failed       int             `json:"failed"`
+	Failures     []importFailure `json:"failures"`
+	// 随监控项一起导入的历史心跳，以及导入后重建的聚合
+	Heartbeats        int                   `json:"heartbeats"`
+	HeartbeatsSkipped int                   `json:"heartbeats_skipped"`
+	Rebuilt           *db.AggregationReport `json:"rebuilt,omitempty"`
+	// 随监控项一起导入的通知规则：新建、同名已存在（跳过）和写入失败的数量，以及为对应关系复制的规则数
+	NotificationsImported int      `json:"notifications_imported"`
+	NotificationsSkipped  int      `json:"notifications_skipped"`
+	SkippedNotifications  []string `json:"skipped_notifications"`
+	NotificationsFailed   int      `json:"notifications_failed"`
+	AssignmentsCreated    int      `json:"assignments_created"`
+	Done                  bool     `json:"done"`
+}
+
+// monitorExport 导出文件：监控项、通知规则，以及触发规则与监控项的对应关系（按名称，导入到其他实例时按名称对应）。
+// 导入时也接受只包含监控项的数组（旧版本导出的文件）
+type monitorExport struct {
+	Monitors      []importedMonitor        `json:"monitors"`
+	Notifications []exportedNotification   `json:"notifications"`
+	Assignments   []notificationAssignment `json:"assignments"`
+}
+
+// exportedNotification 导出的通知规则，不导出密钥时渠道令牌和密码替换为 <key>_set（同通知列表）。
+// Templates 为规则自己的自定义模板（kind -> 源码）
+type exportedNotification struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Config    string            `json:"config"`
+	Active    bool              `json:"active"`
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// notificationAssignment 触发规则覆盖的监控项，Monitor 为 "*" 表示全部监控项
+type notificationAssignment struct {
+	Notification string `json:"notification"`
+	Monitor      string `json:"monitor"`
+}
+
+// decodeMonitorImport 解析导入数据：导出的对象，或只包含监控项的数组
+func decodeMonitorImport(data []byte) (monitorExport, error) {
+	var in monitorExport
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &in.Monitors)
+		return in, err
+	}
+	err := json.Unmarshal(data, &in)
+	return in, err
+}
+
+// importedMonitor 导入数据中的一项：监控项配置，可附带历史心跳（备份或从 Uptime Kuma 迁移的数据）。
+// 分组按名称 Group 导入，不存在时自动创建；数据中的 group_id 属于导出的实例，导入时忽略
+type importedMonitor struct {
+	model.Monitor
+	// PublicVisible 覆盖 Monitor 中的同名字段，添加该字段之前导出的数据中没有，缺省时视为显示
+	PublicVisible *bool             `json:"public_visible,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Heartbeats    []model.Heartbeat `json:"heartbeats,omitempty"`
+}
+
+// config 返回导入的监控项配置，PublicVisible 缺省时为 true
+func (in importedMonitor) config() model.Monitor {
+	m := in.Monitor
+	m.PublicVisible = in.PublicVisible == nil || *in.PublicVisible
+	return m
+}
+
+// createMonitor 创建监控项。PublicVisible 的默认值为 true，创建时 false 会被忽略（并被回填为 true），需要单独写入
+func createMonitor(tx *gorm.DB, m *model.Monitor) error {
+	visible := m.PublicVisible
+	if err := tx.Create(m).Error; err != nil {
+		return err
+	}
+	if visible {
+		return nil
+	}
+	m.PublicVisible = false
+	return tx.Model(m).Update("public_visible", false).Error
+}
+
+// importHistory 一批导入写入的历史心跳统计
+type importHistory struct {
+	count, skipped int
+	from, to       time.Time
+	monitorIDs     []uint
+}
+
+func (h *importHistory) add(o importHistory) {
+	h.count += o.count
+	h.skipped += o.skipped
+	h.monitorIDs = append(h.monitorIDs, o.monitorIDs...)
+	if !o.from.IsZero() && (h.from.IsZero() || o.from.Before(h.from)) {
+		h.from = o.from
+	}
+	if o.to.After(h.to) {
+		h.to = o.to
+	}
+}
+
+// importFailure 写入失败（所在批次已回滚）的监控项
+type importFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// newImportJob 创建导入任务
+func newImportJob(in monitorExport) *importJob {
+	return &importJob{ID: generateToken()[:16], Total: len(in.Monitors), Failures: []importFailure{},
+		SkippedNames: []string{}, SkippedNotifications: []string{}}
+}
+
+// runImportJob 分批校验并写入监控项，每批一个事务；全部写入后再错开启动监控。
+// 带历史心跳时，导入结束后立即重建这些监控项在历史时间范围内的聚合，列表中的最近结果和可用率无需等待下一次定时聚合。
+// 监控项写入后在一个事务中导入通知规则并恢复对应关系（见 importNotifications）。
+// 每批写入后和结束时通过 progress 报告进度，为 nil 时不报告
+func (s *Server) runImportJob(progress func(any), job *importJob, in monitorExport) {
+	monitorsInput := in.Monitors
+	if progress == nil {
+		progress = func(any) {}
+	}
+	var started []*model.Monitor
+	var history importHistory
+
+	for start := 0; start < len(monitorsInput); start += importBatchSize {
+		batch := monitorsInput[start:min(start+importBatchSize, len(monitorsInput))]
+
+		var created []*model.Monitor
+		var skippedNames []string
+		var batchHistory importHistory
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			for _, in := range batch {
+				m := in.config()
+				if m.Name == "" || (m.URL == "" && m.Type != model.MonitorTypePush && m.Type != model.MonitorTypeMultiStep) {
+					continue
+				}
+				var count int64
+				tx.Model(&model.Monitor{}).Where("name = ?", m.Name).Count(&count)
+				if count > 0 {
+					skippedNames = append(skippedNames, m.Name)
+					continue
+				}
+				newMonitor, errMsg := prepareImportedMonitor(tx, m)
+				if errMsg != "" {
+					skippedNames = append(skippedNames, m.Name)
+					continue
+				}
+				if group := strings.TrimSpace(in.Group); group != "" && utf8.RuneCountInString(group) <= maxGroupNameRunes {
+					groupID, err := db.EnsureMonitorGroup(tx, group)
+					if err != nil {
+						return fmt.Errorf("%s: group: %w", m.Name, err)
+					}
+					newMonitor.GroupID = groupID
+				}
+				if err := createMonitor(tx, &newMonitor); err != nil {
+					return fmt.Errorf("%s: %w", m.Name, err)
+				}
+				if len(in.Heartbeats) > 0 {
+					h, err := importHeartbeats(tx, &newMonitor, in.Heartbeats)
+					if err != nil {
+						return fmt.Errorf("%s: heartbeats: %w", m.Name, err)
+					}
+					batchHistory.add(h)
+				}
+				created = append(created, &newMonitor)
+			}
+			return nil
+		})
+
+		job.Processed += len(batch)
+		if err != nil {
+			// 整批回滚，批次内的每一项都计为失败
+			logger.Warn("Import batch failed", zap.String("job", job.ID), zap.Int("offset", start), zap.Error(err))
+			for _, in := range batch {
+				job.Failures = append(job.Failures, importFailure{Name: in.Name, Error: err.Error()})
+			}
+			job.Failed += len(batch)
+		} else {
+			job.Imported += len(created)
+			job.Skipped += len(skippedNames)
+			job.SkippedNames = append(job.SkippedNames, skippedNames...)
+			history.add(batchHistory)
+			job.Heartbeats, job.HeartbeatsSkipped = history.count, history.skipped
+			for _, m := range created {
+				if m.Active == 1 {
+					started = append(started, m)
+				}
+			}
+		}
+		// 中间进度只推送计数，跳过和失败明细在最终结果中一次返回
+		progress(map[string]any{
+			"job_id": job.ID, "total": job.Total, "processed": job.Processed,
+			"imported": job.Imported, "skipped": job.Skipped, "failed": job.Failed, "done": false,
+		})
+	}
+
+	if history.count > 0 {
+		// 最后一个小时也要聚合，窗口结束于最新心跳所在小时之后
+		report := db.ForceAggregationFor(history.monitorIDs, history.from, history.to.Add(time.Hour))
+		job.Rebuilt = &report
+		clearEmbedCache()
+		clearStatusPageCache()
+	}
+
+	if len(in.Notifications) > 0 || len(in.Assignments) > 0 {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			return importNotifications(tx, job, in)
+		})
+		if err != nil {
+			logger.Warn("Import notifications failed", zap.String("job", job.ID), zap.Error(err))
+			job.NotificationsImported, job.AssignmentsCreated = 0, 0
+			job.NotificationsSkipped, job.SkippedNotifications = 0, []string{}
+			job.NotificationsFailed = len(in.Notifications)
+		}
+		if job.NotificationsImported+job.AssignmentsCreated > 0 {
+			s.broadcastNotificationList()
+		}
+	}
+
+	s.monitorService.StartMonitorsStaggered(started, importStaggerWindow)
+	job.Done = true
+	progress(job)
+	logger.Info("Import finished", zap.String("job", job.ID), zap.Int("imported", job.Imported),
+		zap.Int("skipped", job.Skipped), zap.Int("failed", job.Failed), zap.Int("heartbeats", job.Heartbeats),
+		zap.Int("notifications", job.NotificationsImported))
+	s.socketServer.To("public").Emit("updateMonitorList")
+	s.broadcastMonitorList()
+}
+
+// importNotifications 导入通知规则：同名规则已存在时跳过并沿用已有规则，否则新建（包括规则自己的模板）。
+// 之后按对应关系让规则覆盖导入文件中的监控项：导出的规则本身已指向该监控项时不需要修改，
+// 同名的已有规则指向其他监控项时复制一条针对该监控项的规则（同 bulkAction 的 assign_notification），不改动已有规则
+func importNotifications(tx *gorm.DB, job *importJob, in monitorExport) error {
+	for _, n := range in.Notifications {
+		name := strings.TrimSpace(n.Name)
+		if name == "" {
+			continue
+		}
+		var count int64
+		tx.Model(&model.Notification{}).Where("name = ?", name).Count(&count)
+		if count > 0 {
+			job.NotificationsSkipped++
+			job.SkippedNotifications = append(job.SkippedNotifications, name)
+			continue
+		}
+		var cfg map[string]any
+		if json.Unmarshal([]byte(n.Config), &cfg) != nil || cfg == nil {
+			job.NotificationsSkipped++
+			job.SkippedNotifications = append(job.SkippedNotifications, name)
+			continue
+		}
+		// 未导出密钥时的 <key>_set 标记只用于显示，导入后需要重新填写令牌和密码
+		for _, key := range notificationSecretKeys {
+			delete(cfg, key+"_set")
+		}
+		cfg["name"] = name
+		configBytes, _ := json.Marshal(cfg)
+		rule := model.Notification{Name: name, Type: n.Type, Config: string(configBytes), Active: true}
+		if err := tx.Create(&rule).Error; err != nil {
+			return fmt.Errorf("notification %s: %w", name, err)
+		}
+		// Active 的默认值为 true，停用的规则需要单独写入
+		if !n.Active {
+			if err := tx.Model(&rule).Update("active", false).Error; err != nil {
+				return fmt.Errorf("notification %s: %w", name, err)
+			}
+		}
+		for kind, src := range n.Templates {
+			if !slices.Contains(templateKinds, kind) || src == "" || notification.CheckTemplate(kind, src) != nil {
+				continue
+			}
+			if _, err := createSettingIfMissing(tx, db.TemplateSettingKey(kind, rule.ID), src, "template"); err != nil {
+				return err
+			}
+		}
+		job.NotificationsImported++
+	}
+
+	for _, a := range in.Assignments {
+		var rule model.Notification
+		tx.Where("name = ? AND type = ?", a.Notification, "trigger").Limit(1).Find(&rule)
+		if rule.ID == 0 || a.Monitor == "" {
+			continue
+		}
+		var cfg map[string]any
+		if json.Unmarshal([]byte(rule.Config), &cfg) != nil {
+			continue
+		}
+		created, err := assignTriggerRule(tx, &rule, cfg, a.Monitor)
+		if err != nil {
+			return fmt.Errorf("notification %s: %w", a.Notification, err)
+		}
+		if created {
+			job.AssignmentsCreated++
+		}
+	}
+	return nil
+}
+
+// importHeartbeats 写入监控项的历史心跳：丢弃时间为空或在未来、状态无效以及探针上报的记录，
+// 并用最新一条心跳更新监控项的状态、最后检查时间和消息
+func importHeartbeats(tx *gorm.DB, m *model.Monitor, heartbeats []model.Heartbeat) (importHistory, error) {
+	h := importHistory{monitorIDs: []uint{m.ID}}
+	now := time.Now()
+	valid := make([]model.Heartbeat, 0, len(heartbeats))
+	var latest *model.Heartbeat
+	for _, hb := range heartbeats {
+		if hb.AgentID != 0 || hb.Time.IsZero() || hb.Time.After(now) || !slices.Contains(model.StatusCodes, hb.Status) {
+			h.skipped++
+			continue
+		}
+		hb.ID = 0
+		hb.MonitorID = m.ID
+		hb.RepresentedCount = max(hb.RepresentedCount, 1)
+		valid = append(valid, hb)
+		if latest == nil || hb.Time.After(latest.Time) {
+			latest = &valid[len(valid)-1]
+		}
+		if h.from.IsZero() || hb.Time.Before(h.from) {
+			h.from = hb.Time
+		}
+		if hb.Time.After(h.to) {
+			h.to = hb.Time
+		}
+	}
+	if len(valid) == 0 {
+		h.monitorIDs = nil
+		return h, nil
+	}
+	// latest 指向 valid 中的元素，CreateInBatches 之前先复制出来
+	last := *latest
+	if err := tx.CreateInBatches(valid, 500).Error; err != nil {
+		return h, err
+	}
+	h.count = len(valid)
+	if err := tx.Model(m).Updates(map[string]any{
+		"status": last.Status, "last_check": last.Time, "message": last.Message,
+	}).Error; err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+// prepareImportedMonitor 按保存时的规则构造导入的监控项，无效的可选配置直接丢弃；
+// URL 无法规范化时返回错误消息，该项计为跳过
+func prepareImportedMonitor(tx *gorm.DB, m model.Monitor) (model.Monitor, string) {
+	newMonitor := model.Monitor{
+		Name: m.Name, URL: m.URL,
+		Type: func() model.MonitorType {
+			switch m.Type {
+			case model.MonitorTypeHTTP, model.MonitorTypePing, model.MonitorTypeTCP, model.MonitorTypeDNS, model.MonitorTypeNTP, model.MonitorTypeSSH, model.MonitorTypePush, model.MonitorTypeUDP, model.MonitorTypeMultiStep:
+				return m.Type
+			default:
+				return model.MonitorTypeHTTP
+			}
+		}(),
+		Method: m.Method, Body: m.Body, Headers: m.Headers,
+		FormData: sanitizeFormData(m.FormData), Timeout: m.Timeout,
+		ExpectedStatus: m.ExpectedStatus, ResponseRegex: m.ResponseRegex,
+		FollowRedirects: m.FollowRedirects, Interval: m.Interval, SampleEvery: max(m.SampleEvery, 0),
+		Active: m.Active, Weight: m.Weight, MaxOffsetMs: m.MaxOffsetMs,
+		SSHHostKey: m.SSHHostKey, PushToken: m.PushToken, PushSecret: m.PushSecret,
+		UDPPayload: m.UDPPayload, UDPPayloadFormat: m.UDPPayloadFormat, RunDiagnostics: m.RunDiagnostics,
+		BasicAuthUser: m.BasicAuthUser, BasicAuthPass: m.BasicAuthPass, ExpectedFinalURL: m.ExpectedFinalURL,
+		OAuthTokenURL: m.OAuthTokenURL, OAuthClientID: m.OAuthClientID,
+		OAuthClientSecret: m.OAuthClientSecret, OAuthScopes: m.OAuthScopes,
+		Tags: model.NormalizeTags(m.Tags), Public: m.Public, Badge: m.Badge, PublicVisible: m.PublicVisible,
+	}
+	if ipVersion, ok := normalizeIPVersion(m.IPVersion); ok {
+		newMonitor.IPVersion = ipVersion
+	}
+	newMonitor.Steps = m.Steps
+	if errMsg := validateSteps(&newMonitor); errMsg != "" {
+		return newMonitor, errMsg
+	}
+	if _, errMsg := normalizeMonitorURL(&newMonitor); errMsg != "" {
+		return newMonitor, errMsg
+	}
+	if validateClientCert(m.ClientCert, m.ClientKey) == "" {
+		newMonitor.ClientCert, newMonitor.ClientKey = m.ClientCert, m.ClientKey
+	}
+	if validateRedirectStatus(m.ExpectedRedirectStatus) == "" {
+		newMonitor.ExpectedRedirectStatus = m.ExpectedRedirectStatus
+	}
+	if validateHooks(m.PreHook, m.PostHook) == "" {
+		newMonitor.PreHook, newMonitor.PostHook = m.PreHook, m.PostHook
+	}
+	if validateProxyURL(newMonitor.Type, m.ProxyURL) == "" {
+		newMonitor.ProxyURL = m.ProxyURL
+	}
+	if validateExpression(newMonitor.Type, m.Expression) == "" {
+		newMonitor.Expression = strings.TrimSpace(m.Expression)
+	}
+	if validatePingOptions(m.PingCount, m.PingSize, m.MaxPacketLoss) == "" {
+		newMonitor.PingCount, newMonitor.PingSize, newMonitor.MaxPacketLoss = m.PingCount, m.PingSize, m.MaxPacketLoss
+	}
+	if validateRegexScanBytes(m.RegexScanBytes) == "" {
+		newMonitor.RegexScanBytes = m.RegexScanBytes
+	}
+	newMonitor.AssertOnEmptyBody = m.AssertOnEmptyBody
+	if color, ok := normalizeMonitorColor(m.Color); ok {
+		newMonitor.Color = color
+	}
+	if icon := strings.TrimSpace(m.Icon); validMonitorIcon(icon) {
+		newMonitor.Icon = icon
+	}
+	if validateWebhook(m) == "" {
+		newMonitor.WebhookURL, newMonitor.WebhookSecret = m.WebhookURL, m.WebhookSecret
+		newMonitor.WebhookFilter, newMonitor.WebhookEnabled = m.WebhookFilter, m.WebhookEnabled
+	}
+	if newMonitor.Type == model.MonitorTypePush {
+		// 导入的 token 已被占用时重新生成，避免两个监控项共用同一个上报地址
+		var used int64
+		tx.Model(&model.Monitor{}).Where("push_token = ?", newMonitor.PushToken).Count(&used)
+		if newMonitor.PushToken == "" || used > 0 {
+			newMonitor.PushToken = generateToken()
+		}
+	}
+	if newMonitor.Interval < 10 {
+		newMonitor.Interval = 60
+	}
+	if newMonitor.Timeout < 1 {
+		newMonitor.Timeout = 10
+	}
+	if newMonitor.Method == "" {
+		newMonitor.Method = "GET"
+	}
+
+	return newMonitor, ""
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	apperrors "ping-go/pkg/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiDoc 接口文档。请求体和响应体按 Body、Response 的 Go 类型（json 标签）生成 schema，
+// 与处理函数实际编码的结构体一致；map 类型表示字段不固定的对象
+type apiDoc struct {
+	Summary  string
+	Tag      string
+	Public   bool       // 不需要认证
+	Query    []apiParam // 查询参数，路径参数按路由自动生成
+	Body     any        // 请求体，nil 表示没有
+	Response any        // 成功时的 JSON 响应体，nil 表示没有固定结构
+	Status   int        // 成功时的状态码，默认 200
+	Produces string     // 非 JSON 响应的 Content-Type，如 application/yaml
+}
+
+// apiParam 查询参数，Type 为 OpenAPI 的基本类型（string、integer、boolean）
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+type apiOperation struct {
+	method string
+	path   string // gin 路由格式，如 /api/v1/monitors/:id
+	doc    apiDoc
+}
+
+// apiRoutes 注册路由的同时记录接口文档，/api/openapi.json 由记录生成，文档不会与实际路由脱节。
+// /api 下的路由都应通过它注册，遗漏的路由会使 TestOpenAPICoversAPIRoutes 失败
+type apiRoutes struct {
+	group *gin.RouterGroup
+	ops   *[]apiOperation
+}
+
+// apiRoutes 返回根路由上的 apiRoutes
+func (s *Server) apiRoutes() apiRoutes {
+	return apiRoutes{group: &s.router.RouterGroup, ops: &s.apiOps}
+}
+
+// Group 同 gin.RouterGroup.Group
+func (r apiRoutes) Group(relativePath string, handlers ...gin.HandlerFunc) apiRoutes {
+	return apiRoutes{group: r.group.Group(relativePath, handlers...), ops: r.ops}
+}
+
+// Handle 注册路由并记录文档
+func (r apiRoutes) Handle(method, relativePath string, doc apiDoc, handlers ...gin.HandlerFunc) {
+	r.group.Handle(method, relativePath, handlers...)
+	full := path.Join(r.group.BasePath(), relativePath)
+	*r.ops = append(*r.ops, apiOperation{method: method, path: full, doc: doc})
+}
+
+func (r apiRoutes) GET(relativePath string, doc apiDoc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodGet, relativePath, doc, handlers...)
+}
+
+func (r apiRoutes) POST(relativePath string, doc apiDoc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPost, relativePath, doc, handlers...)
+}
+
+func (r apiRoutes) PUT(relativePath string, doc apiDoc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPut, relativePath, doc, handlers...)
+}
+
+func (r apiRoutes) PATCH(relativePath string, doc apiDoc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPatch, relativePath, doc, handlers...)
+}
+
+func (r apiRoutes) DELETE(relativePath string, doc apiDoc, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, relativePath, doc, handlers...)
+}
+
+// openAPI 处理 GET /api/openapi.json：OpenAPI 3 文档，首次请求时生成
+func (s *Server) openAPI(c *gin.Context) {
+	s.openAPIOnce.Do(func() {
+		s.openAPISpec, s.openAPIErr = json.Marshal(buildOpenAPI(s.apiOps))
+	})
+	if s.openAPIErr != nil {
+		writeAPIError(c, apperrors.Wrap(s.openAPIErr, s.openAPIErr.Error()))
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.openAPISpec)
+}
+
+// buildOpenAPI 生成 OpenAPI 3.0 文档
+func buildOpenAPI(ops []apiOperation) map[string]any {
+	b := &schemaBuilder{components: make(map[string]any), types: make(map[string]reflect.Type)}
+	errorSchema := b.schema(reflect.TypeOf(apperrors.AppError{}))
+	errorResponse := func(desc string) map[string]any {
+		return map[string]any{
+			"description": desc,
+			"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+		}
+	}
+
+	paths := make(map[string]map[string]any)
+	for _, op := range ops {
+		doc := op.doc
+		specPath, params := openAPIPath(op.path)
+		for _, q := range doc.Query {
+			params = append(params, map[string]any{
+				"name": q.Name, "in": "query", "description": q.Description,
+				"schema": map[string]any{"type": q.Type},
+			})
+		}
+
+		status := doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		switch {
+		case doc.Produces != "":
+			success["content"] = map[string]any{doc.Produces: map[string]any{"schema": map[string]any{"type": "string"}}}
+		case doc.Response != nil:
+			success["content"] = map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(doc.Response))}}
+		}
+		responses := map[string]any{
+			strconv.Itoa(status): success,
+			"default":            errorResponse("Error"),
+		}
+
+		operation := map[string]any{
+			"summary":     doc.Summary,
+			"operationId": operationID(op.method, op.path),
+			"responses":   responses,
+		}
+		if doc.Tag != "" {
+			operation["tags"] = []string{doc.Tag}
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if doc.Body != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(doc.Body))}},
+			}
+		}
+		if doc.Public {
+			operation["security"] = []any{}
+		} else {
+			responses["401"] = errorResponse("Unauthorized")
+		}
+
+		if paths[specPath] == nil {
+			paths[specPath] = make(map[string]any)
+		}
+		paths[specPath][strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "PingGo API",
+			"version":     serverVersion,
+			"description": "Errors are returned as {code, message}. Authenticate with \"Authorization: Bearer <API key or session token>\".",
+		},
+		"paths":    paths,
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":   "http",
+					"scheme": "bearer",
+					"description": "API key (pgk_...) created in the dashboard, or a session token returned by login. " +
+						"Read keys may only send GET requests; notification, settings, import/export and backup endpoints require an admin key",
+				},
+			},
+		},
+	}
+}
+
+// openAPIPath 把 gin 路由（/monitors/:id）转换为 OpenAPI 路径（/monitors/{id}），并生成路径参数
+func openAPIPath(route string) (string, []map[string]any) {
+	segments := strings.Split(route, "/")
+	var params []map[string]any
+	for i, seg := range segments {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			name := seg[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID 由方法和路径生成唯一的 operationId，如 get_api_v1_monitors_id
+func operationID(method, route string) string {
+	id := strings.ToLower(method)
+	for _, seg := range strings.Split(route, "/") {
+		seg = strings.TrimLeft(seg, ":*")
+		seg = strings.NewReplacer(".", "_", "-", "_").Replace(seg)
+		if seg != "" {
+			id += "_" + seg
+		}
+	}
+	return id
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaBuilder 按 Go 类型生成 JSON Schema，具名结构体放入 components/schemas 并以 $ref 引用
+type schemaBuilder struct {
+	components map[string]any
+	types      map[string]reflect.Type // 组件名 -> 类型，不同包的同名类型加包名区分
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(marshalerType) {
+		// 自定义编码（如 json.RawMessage）无法推断结构
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := schemaName(t)
+		if other, ok := b.types[name]; ok && other != t {
+			name = schemaName(t, path.Base(t.PkgPath()))
+		}
+		if _, ok := b.components[name]; !ok {
+			b.types[name] = t
+			b.components[name] = map[string]any{} // 先占位，结构体引用自身时不会无限递归
+			b.components[name] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object 生成结构体的 schema，规则与 encoding/json 一致：跳过未导出和 json:"-" 的字段，展开匿名嵌入的结构体
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	b.addFields(t, props)
+	return map[string]any{"type": "object", "properties": props}
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, props map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, props)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, exists := props[name]; exists {
+			continue // 外层字段优先于嵌入结构体的同名字段
+		}
+		props[name] = b.schema(ft)
+	}
+}
+
+// schemaName 组件名：类型名首字母大写（importedMonitor -> ImportedMonitor），指定包名时加在前面（ModelStatusPageMonitor）
+func schemaName(t reflect.Type, pkg ...string) string {
+	var name string
+	for _, part := range append(pkg, t.Name()) {
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		name += string(r)
+	}
+	return name
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// undocumentedRoutes 返回 /api 下已注册到 gin、但不在 /api/openapi.json 中的路由
+func undocumentedRoutes(t *testing.T, s *Server, ts *httptest.Server) []string {
+	t.Helper()
+	code, body := doAPI(t, ts, http.MethodGet, "/api/openapi.json", "", "")
+	if code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json = %d", code)
+	}
+	var spec struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	decodeJSON(t, body, &spec)
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q, want 3.x", spec.OpenAPI)
+	}
+
+	var missing []string
+	for _, r := range s.Router().Routes() {
+		if !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+		specPath, _ := openAPIPath(r.Path)
+		if _, ok := spec.Paths[specPath][strings.ToLower(r.Method)]; !ok {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// /api 下的每个路由都必须通过 apiRoutes 注册，从而出现在 OpenAPI 文档中
+func TestOpenAPICoversAPIRoutes(t *testing.T) {
+	s, ts := newTestServer(t)
+	if missing := undocumentedRoutes(t, s, ts); len(missing) > 0 {
+		t.Fatalf("routes missing from the OpenAPI spec (register them through apiRoutes):\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+// 绕过 apiRoutes 直接注册到 gin 的路由会被发现
+func TestOpenAPICoverageDetectsRawRoute(t *testing.T) {
+	s, ts := newTestServer(t)
+	s.Router().GET("/api/v1/undocumented/:id", func(c *gin.Context) {})
+	if missing := undocumentedRoutes(t, s, ts); !slices.Equal(missing, []string{"GET /api/v1/undocumented/:id"}) {
+		t.Fatalf("undocumented routes = %v", missing)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"ping-go/db"
+	"ping-go/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// internalMessage 含内部地址的检查消息，不能出现在任何未登录可见的输出中
+const internalMessage = "Timeout: dial tcp 10.20.30.40:5432 (db.internal.corp): i/o timeout"
+
+var internalDetails = []string{"10.20.30.40", "db.internal.corp", "i/o timeout"}
+
+func assertNoInternalDetails(t *testing.T, what, out string) {
+	t.Helper()
+	for _, s := range internalDetails {
+		if strings.Contains(out, s) {
+			t.Errorf("%s leaks %q: %s", what, s, out)
+		}
+	}
+}
+
+// publicOutputFixture 一个公开显示的监控项和一个对未登录客户端隐藏的监控项，都处于 DOWN 并带有内部消息
+func publicOutputFixture(t *testing.T) (visible, hidden model.Monitor) {
+	t.Helper()
+	visible = createTestMonitor(t, model.Monitor{Name: "public-api", Public: true, PublicVisible: true, Status: model.StatusDown, Message: internalMessage})
+	hidden = createTestMonitor(t, model.Monitor{Name: "hidden-db", Public: true, PublicVisible: false, Status: model.StatusDown, Message: internalMessage})
+	for _, m := range []model.Monitor{visible, hidden} {
+		ev := model.StatusEvent{MonitorID: m.ID, Status: model.StatusDown, PreviousStatus: model.StatusUp, Message: internalMessage, Time: time.Now()}
+		if err := db.DB.Create(&ev).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return visible, hidden
+}
+
+func TestFeedsUsePublicMessage(t *testing.T) {
+	_, ts := newTestServer(t)
+	visible, hidden := publicOutputFixture(t)
+	page := model.StatusPage{Slug: "main", Title: "Main", Published: true}
+	db.DB.Create(&page)
+	db.DB.Create(&model.StatusPageMonitor{StatusPageID: page.ID, MonitorID: visible.ID})
+
+	for _, path := range []string{"/feed.xml", "/status/main/feed.xml"} {
+		code, body := doAPI(t, ts, http.MethodGet, path, "", "")
+		if code != http.StatusOK {
+			t.Fatalf("GET %s = %d", path, code)
+		}
+		out := string(body)
+		assertNoInternalDetails(t, path, out)
+		if !strings.Contains(out, visible.Name) || !strings.Contains(out, "<summary>Timeout</summary>") {
+			t.Errorf("%s does not contain the public status change: %s", path, out)
+		}
+		if strings.Contains(out, hidden.Name) {
+			t.Errorf("%s contains the hidden monitor", path)
+		}
+	}
+}
+
+// 公开 JSON 接口、嵌入页和状态徽章不能返回对未登录客户端隐藏的监控项
+func TestPublicEndpointsSkipHiddenMonitors(t *testing.T) {
+	_, ts := newTestServer(t)
+	visible, hidden := publicOutputFixture(t)
+	db.DB.Model(&model.Monitor{}).Where("id IN ?", []uint{visible.ID, hidden.ID}).Update("badge", true)
+
+	t.Run("status list", func(t *testing.T) {
+		code, body := doAPI(t, ts, http.MethodGet, "/api/status", "", "")
+		if code != http.StatusOK {
+			t.Fatalf("GET /api/status = %d %s", code, body)
+		}
+		var resp publicStatusResponse
+		decodeJSON(t, body, &resp)
+		if len(resp.Monitors) != 1 || resp.Monitors[0].ID != visible.ID {
+			t.Errorf("monitors = %+v, want only %q", resp.Monitors, visible.Name)
+		}
+	})
+
+	for _, tt := range []struct {
+		name, path string
+		hidden     func(code int, body string) bool // 隐藏的监控项返回的是否为“不存在”
+	}{
+		{"heartbeats", "/api/status/%d/heartbeats", func(code int, _ string) bool { return code == http.StatusNotFound }},
+		{"embed", "/embed/monitor/%d", func(code int, _ string) bool { return code == http.StatusNotFound }},
+		{"status badge", "/badge/%d/status.svg", func(_ int, body string) bool { return strings.Contains(body, "not found") }},
+		{"uptime badge", "/badge/%d/uptime/24h.svg", func(_ int, body string) bool { return strings.Contains(body, "not found") }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := doAPI(t, ts, http.MethodGet, fmt.Sprintf(tt.path, visible.ID), "", "")
+			if code != http.StatusOK || tt.hidden(code, string(body)) {
+				t.Errorf("visible monitor: %d %s", code, body)
+			}
+			code, body = doAPI(t, ts, http.MethodGet, fmt.Sprintf(tt.path, hidden.ID), "", "")
+			if !tt.hidden(code, string(body)) || strings.Contains(string(body), hidden.Name) {
+				t.Errorf("hidden monitor: %d %s", code, body)
+			}
+		})
+	}
+}
+
+func TestPublicSocketOutput(t *testing.T) {
+	s, ts := newTestServer(t)
+	visible, hidden := publicOutputFixture(t)
+	client := dialSocket(t, ts)
+
+	if err := client.Emit("getMonitorList"); err != nil {
+		t.Fatal(err)
+	}
+	ev, err := client.Next("monitorList", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoInternalDetails(t, "monitorList", ev.Raw)
+	if !strings.Contains(ev.Raw, visible.Name) || strings.Contains(ev.Raw, hidden.Name) {
+		t.Errorf("monitorList = %s, want only the visible monitor", ev.Raw)
+	}
+
+	for _, m := range []model.Monitor{hidden, visible} {
+		s.monitorService.OnHeartbeat(&model.Heartbeat{ID: m.ID, MonitorID: m.ID, Status: model.StatusDown, Message: internalMessage, Time: time.Now()})
+	}
+	ev, err = client.Next("heartbeat", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoInternalDetails(t, "heartbeat", ev.Raw)
+	hb, _ := ev.Args[0].(map[string]any)
+	if hb["monitorID"] != float64(visible.ID) || hb["msg"] != "Timeout" {
+		t.Errorf("heartbeat = %s, want the visible monitor with msg Timeout", ev.Raw)
+	}
+	if ev, err := client.Next("heartbeat", 200*time.Millisecond); err == nil {
+		t.Errorf("unexpected second heartbeat %s", ev.Raw)
+	}
+}
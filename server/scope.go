@@ -0,0 +1,251 @@
+package server
+
+import (
+	"ping-go/db"
+	"ping-go/model"
+	"sync"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// scopedEvents 限定标签范围的账号可以调用的事件；其余需要完整权限的事件（设置、通知、导入导出、账号管理等）一律拒绝
+var scopedEvents = map[string]bool{
+	"getMonitor":                true,
+	"add":                       true,
+	"edit":                      true,
+	"toggleActive":              true,
+	"deleteMonitor":             true,
+	"bulkAction":                true,
+	"setTagColor":               true,
+	"testMonitor":               true,
+	"testMonitorAssertions":     true,
+	"validateMonitor":           true,
+	"startMonitorDebug":         true,
+	"stopMonitorDebug":          true,
+	"getFailureHeatmap":         true,
+	"getIncidentContext":        true,
+	"acknowledgeIncident":       true,
+	"getMonitorGroups":          true,
+	"getMonitorRegions":         true,
+	"getHeartbeatList":          true,
+	"getHeartbeatListWithRange": true,
+	"getMonitorStats":           true,
+	"getChartData":              true,
+	"clearEvents":               true,
+}
+
+// viewerEvents viewer 角色可以调用的事件：只读的查询，以及修改自己的密码和两步验证；其余事件返回 403
+var viewerEvents = map[string]bool{
+	"getMonitor":                true,
+	"getFailureHeatmap":         true,
+	"getIncidentContext":        true,
+	"getIncidents":              true,
+	"getMonitorGroups":          true,
+	"getMonitorRegions":         true,
+	"getHeartbeatList":          true,
+	"getHeartbeatListWithRange": true,
+	"getMonitorStats":           true,
+	"getChartData":              true,
+	"getMaintenanceWindows":     true,
+	"getStatusPages":            true,
+	"getServerAlerts":           true,
+	"getSystemInfo":             true,
+	"getStorageBreakdown":       true,
+	"changePassword":            true,
+	"get2FAStatus":              true,
+	"setup2FA":                  true,
+	"confirm2FA":                true,
+	"disable2FA":                true,
+}
+
+var (
+	// scopedSockets 已登录的限定范围连接，key: socketID，value: *socket.Socket。
+	// 这些连接不加入 public/admin 房间，监控列表按各自范围单独发送
+	scopedSockets = sync.Map{}
+	// monitorTags 监控项标签缓存（监控列表广播时刷新），用于把心跳只推送给有权查看的限定范围连接
+	monitorTags = sync.Map{}
+	// publicVisibility 监控项是否向未登录的客户端显示的缓存（监控列表广播时刷新），key: monitorID，value: bool
+	publicVisibility = sync.Map{}
+)
+
+// authenticateSocket 将连接标记为已登录，并记录账号的角色。账号限定了标签范围时，连接加入各标签房间而不是 admin/public
+func authenticateSocket(client *socket.Socket, userID uint, token string) {
+	var user model.User
+	db.DB.Select("id", "tag_scope", "role").First(&user, userID)
+	scope := model.SplitTags(user.TagScope)
+
+	role := model.RoleAdmin
+	if user.IsViewer() {
+		role = model.RoleViewer
+	}
+	auth := map[string]any{
+		"authenticated": true,
+		"userID":        userID,
+		"token":         token,
+		"role":          role,
+	}
+	if len(scope) == 0 {
+		socketAuth.Store(client.Id(), auth)
+		client.Join("admin")
+		return
+	}
+
+	auth["scope"] = scope
+	socketAuth.Store(client.Id(), auth)
+	client.Leave("public")
+	for _, tag := range scope {
+		client.Join(tagRoom(tag))
+	}
+	scopedSockets.Store(client.Id(), client)
+}
+
+// forgetScopedSocket 连接登出或断开时移出限定范围连接列表
+func forgetScopedSocket(client *socket.Socket) {
+	if _, ok := scopedSockets.LoadAndDelete(client.Id()); ok {
+		for _, tag := range socketScope(client) {
+			client.Leave(tagRoom(tag))
+		}
+		client.Join("public")
+	}
+}
+
+func tagRoom(tag string) socket.Room {
+	return socket.Room("tag:" + tag)
+}
+
+// socketScope 返回连接的标签范围，nil 表示不限制
+func socketScope(client *socket.Socket) []string {
+	if val, ok := socketAuth.Load(client.Id()); ok {
+		if data, ok := val.(map[string]any); ok {
+			if scope, ok := data["scope"].([]string); ok {
+				return scope
+			}
+		}
+	}
+	return nil
+}
+
+// socketRole 返回连接登录账号的角色，未登录时为空
+func socketRole(client *socket.Socket) string {
+	if val, ok := socketAuth.Load(client.Id()); ok {
+		if data, ok := val.(map[string]any); ok {
+			role, _ := data["role"].(string)
+			return role
+		}
+	}
+	return ""
+}
+
+// fullAdminSocket 连接是否为不限标签范围的管理员账号
+func fullAdminSocket(client *socket.Socket) bool {
+	return socketRole(client) == model.RoleAdmin && socketScope(client) == nil
+}
+
+// replyForbidden 回复 403：有回调时通过 ack 返回，否则发送 error 事件
+func replyForbidden(client *socket.Socket, args []any) {
+	for _, arg := range args {
+		if ack, ok := arg.(func([]any, error)); ok {
+			ack([]any{map[string]any{"ok": false, "code": 403, "msg": "Forbidden"}}, nil)
+			return
+		}
+	}
+	client.Emit("error", map[string]any{
+		"code": 403,
+		"msg":  "Forbidden",
+	})
+}
+
+// checkMonitorScope 检查连接是否有权访问监控项，无权访问时回复 403 并返回 false
+func checkMonitorScope(client *socket.Socket, id uint, args []any) bool {
+	scope := socketScope(client)
+	if scope == nil {
+		return true
+	}
+	var m model.Monitor
+	if err := db.DB.Select("id", "tags").First(&m, id).Error; err != nil || !m.InScope(scope) {
+		replyForbidden(client, args)
+		return false
+	}
+	return true
+}
+
+// hasScopedSockets 是否存在已登录的限定范围连接
+func hasScopedSockets() bool {
+	found := false
+	scopedSockets.Range(func(_, _ any) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// cacheMonitorTags 刷新监控项标签缓存
+func cacheMonitorTags(monitors []model.Monitor) {
+	for _, m := range monitors {
+		monitorTags.Store(m.ID, model.SplitTags(m.Tags))
+	}
+}
+
+// cachePublicVisibility 刷新监控项公开显示缓存
+func cachePublicVisibility(monitors []model.Monitor) {
+	for _, m := range monitors {
+		publicVisibility.Store(m.ID, m.PublicVisible)
+	}
+}
+
+// publicVisible 监控项是否向未登录的客户端显示，不存在的监控项不显示
+func publicVisible(monitorID uint) bool {
+	if val, ok := publicVisibility.Load(monitorID); ok {
+		return val.(bool)
+	}
+	var m model.Monitor
+	if err := db.DB.Select("id", "public_visible").Where("id = ?", monitorID).Limit(1).Find(&m).Error; err != nil || m.ID == 0 {
+		return false
+	}
+	publicVisibility.Store(monitorID, m.PublicVisible)
+	return m.PublicVisible
+}
+
+// emitToScoped 将监控项相关的事件推送给有权查看该监控项的限定范围连接
+func (s *Server) emitToScoped(monitorID uint, event string, data any) {
+	if !hasScopedSockets() {
+		return
+	}
+	var tags []string
+	if val, ok := monitorTags.Load(monitorID); ok {
+		tags = val.([]string)
+	} else {
+		var m model.Monitor
+		if err := db.DB.Select("id", "tags").First(&m, monitorID).Error; err != nil {
+			return
+		}
+		tags = model.SplitTags(m.Tags)
+		monitorTags.Store(monitorID, tags)
+	}
+	if len(tags) == 0 {
+		return
+	}
+	rooms := make([]socket.Room, len(tags))
+	for i, tag := range tags {
+		rooms[i] = tagRoom(tag)
+	}
+	s.socketServer.To(rooms...).Emit(event, data)
+}
+
+// broadcastScopedMonitorLists 按各限定范围连接的标签分别发送监控列表
+func broadcastScopedMonitorLists(monitors []model.Monitor, groups []model.MonitorGroup, incidents []model.Incident, adminData map[uint]map[string]any) {
+	scopedSockets.Range(func(_, val any) bool {
+		client := val.(*socket.Socket)
+		scope := socketScope(client)
+		data := make(map[uint]map[string]any)
+		for _, m := range monitors {
+			if m.InScope(scope) {
+				data[m.ID] = adminData[m.ID]
+			}
+		}
+		client.Emit("adminMonitorList", data)
+		client.Emit("monitorGroupList", monitorGroupSummaries(groups, monitors, scope))
+		client.Emit("incidentList", publicIncidents(incidents, monitors, scope))
+		return true
+	})
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"ping-go/model"
+	"slices"
+	"testing"
+)
+
+// API 密钥的权限（read、write、admin）和标签范围对每组 REST 接口的效果：允许的返回 2xx，其余返回 403
+func TestAPIKeyScopes(t *testing.T) {
+	_, ts := newTestServer(t)
+	in := createTestMonitor(t, model.Monitor{Name: "api", Tags: "ops"})
+	out := createTestMonitor(t, model.Monitor{Name: "db", Tags: "storage"})
+	push := createTestMonitor(t, model.Monitor{Name: "job", Type: model.MonitorTypePush, PushToken: "scope-tok", PushSecret: "s3cret", Tags: "ops"})
+
+	keys := map[string]string{
+		"read":   createTestAPIKey(t, model.APIKeyScopeRead, ""),
+		"write":  createTestAPIKey(t, model.APIKeyScopeWrite, ""),
+		"admin":  createTestAPIKey(t, model.APIKeyScopeAdmin, ""),
+		"tagged": createTestAPIKey(t, model.APIKeyScopeAdmin, "ops"), // 限定 ops 标签的 admin 密钥
+	}
+	var (
+		all       = []string{"read", "write", "admin", "tagged"}
+		writers   = []string{"write", "admin", "tagged"}
+		unscoped  = []string{"read", "write", "admin"}
+		fullAdmin = []string{"admin"}
+	)
+
+	tests := []struct {
+		group   string
+		method  string
+		path    string
+		body    string
+		allowed []string
+	}{
+		// 监控项：读取对所有密钥开放，修改需要 write；限定范围的密钥只能访问范围内的监控项
+		{"monitors", http.MethodGet, "/api/v1/monitors", "", all},
+		{"monitors", http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d", in.ID), "", all},
+		{"monitors", http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d", out.ID), "", unscoped},
+		{"monitors", http.MethodPatch, fmt.Sprintf("/api/v1/monitors/%d/active", in.ID), `{"active":false}`, writers},
+		{"monitors", http.MethodPatch, fmt.Sprintf("/api/v1/monitors/%d/active", out.ID), `{"active":false}`, []string{"write", "admin"}},
+		{"heartbeats", http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d/heartbeats?hours=1", in.ID), "", all},
+		{"heartbeats", http.MethodGet, fmt.Sprintf("/api/v1/monitors/%d/stats", out.ID), "", unscoped},
+		{"heartbeats", http.MethodDelete, fmt.Sprintf("/api/v1/monitors/%d/heartbeats", in.ID), "", writers},
+		{"maintenance", http.MethodPost, "/api/maintenance", fmt.Sprintf(`{"monitor_ids":[%d],"duration_minutes":5}`, in.ID), writers},
+		{"maintenance", http.MethodPost, "/api/maintenance", fmt.Sprintf(`{"monitor_ids":[%d],"duration_minutes":5}`, out.ID), []string{"write", "admin"}},
+		{"prometheus", http.MethodGet, "/api/prometheus/rules", "", all},
+		// 通知规则、设置、导入导出、备份和签名示例需要不限范围的 admin 密钥
+		{"notifications", http.MethodGet, "/api/v1/notifications", "", fullAdmin},
+		{"notifications", http.MethodPost, "/api/v1/notifications", `{"name":"ops","type":"trigger","channel":"ntfy","ntfy_topic":"alerts"}`, fullAdmin},
+		{"settings", http.MethodGet, "/api/v1/settings", "", fullAdmin},
+		{"export", http.MethodGet, "/api/v1/monitors/export", "", fullAdmin},
+		{"backup", http.MethodGet, "/api/v1/backup", "", fullAdmin},
+		{"push", http.MethodGet, "/api/inbound/" + push.PushToken + "/sign-example", "", fullAdmin},
+		// 账号接口只接受会话 token
+		{"account", http.MethodPost, "/api/v1/account/username", `{"username":"x"}`, nil},
+	}
+	for _, tt := range tests {
+		for _, name := range all {
+			t.Run(fmt.Sprintf("%s/%s %s/%s", tt.group, tt.method, tt.path, name), func(t *testing.T) {
+				code, body := doAPI(t, ts, tt.method, tt.path, keys[name], tt.body)
+				switch {
+				case slices.Contains(tt.allowed, name):
+					if code != http.StatusOK && code != http.StatusCreated {
+						t.Fatalf("%s key: got %d %s, want 200/201", name, code, body)
+					}
+				default:
+					if code != http.StatusForbidden {
+						t.Fatalf("%s key: got %d %s, want 403", name, code, body)
+					}
+					var e struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					}
+					decodeJSON(t, body, &e)
+					if e.Code != http.StatusForbidden || e.Message == "" {
+						t.Fatalf("403 body = %s, want an AppError", body)
+					}
+				}
+			})
+		}
+	}
+}
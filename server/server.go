@@ -1,12 +1,19 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
+	"ping-go/config"
 	"ping-go/db"
 	"ping-go/model"
 	"ping-go/monitor"
+	apperrors "ping-go/pkg/errors"
+	"ping-go/pkg/ratelimit"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,24 +21,45 @@ import (
 	"github.com/zishang520/socket.io/socket"
 )
 
+// serverVersion 通过 info 和 getSystemInfo 返回的版本号
+const serverVersion = "1.0.0-go"
+
+// startedAt 进程启动时间，用于计算运行时长
+var startedAt = time.Now()
+
 // Server 是应用程序的核心服务器结构体
 type Server struct {
-	router         *gin.Engine
-	socketServer   *socket.Server
-	monitorService *monitor.Service
-	staticFS       http.FileSystem
+	router           *gin.Engine
+	socketServer     *socket.Server
+	monitorService   *monitor.Service
+	staticFS         http.FileSystem
+	pushLimiter      *ratelimit.Limiter
+	pushThrottled    *ratelimit.Counter
+	replayCache      *replayCache
+	subscribeLimiter *ratelimit.Limiter // 状态页邮件订阅接口按 IP 限流
+	agentLimiter     *ratelimit.Limiter // 远程探针上报接口按探针限流
+
+	apiOps      []apiOperation // 通过 apiRoutes 注册的接口，用于生成 OpenAPI 文档
+	openAPIOnce sync.Once
+	openAPISpec []byte
+	openAPIErr  error
 }
 
 // NewServer 创建并初始化一个新的服务器实例
 func NewServer(monitorService *monitor.Service, staticFS http.FileSystem) *Server {
 	s := &Server{
-		router:         gin.Default(),
-		socketServer:   socket.NewServer(nil, nil),
-		monitorService: monitorService,
-		staticFS:       staticFS,
+		router:           gin.Default(),
+		socketServer:     socket.NewServer(nil, nil),
+		monitorService:   monitorService,
+		staticFS:         staticFS,
+		pushLimiter:      newPushLimiter(),
+		subscribeLimiter: ratelimit.New(subscribeInterval, subscribeBurst),
+		agentLimiter:     ratelimit.New(agentReportInterval, agentReportBurst),
+		pushThrottled:    ratelimit.NewCounter(),
+		replayCache:      newReplayCache(replayCacheSize),
 	}
 
-	// 健康检查端点
+	// 健康检查端点。?mode=readiness 时启动调度尚未完成返回 503，供负载均衡和编排系统判断是否就绪
 	s.router.GET("/health", func(c *gin.Context) {
 		health := s.monitorService.HealthCheck()
 		sqlDB, err := db.DB.DB()
@@ -42,13 +70,15 @@ func NewServer(monitorService *monitor.Service, staticFS http.FileSystem) *Serve
 		} else {
 			health["database"] = "up"
 		}
+		if c.Query("mode") == "readiness" && health["status"] == "starting" {
+			c.JSON(http.StatusServiceUnavailable, health)
+			return
+		}
 		c.JSON(http.StatusOK, health)
 	})
 
-	// 指标端点
-	s.router.GET("/metrics", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "enabled"})
-	})
+	// Prometheus 指标端点，可通过配置 metrics 开启认证
+	s.router.GET("/metrics", s.metrics)
 
 	// 启动会话清理任务
 	go startSessionCleanup()
@@ -59,14 +89,48 @@ func NewServer(monitorService *monitor.Service, staticFS http.FileSystem) *Serve
 	// 绑定监控心跳回调
 	s.monitorService.OnHeartbeat = func(h *model.Heartbeat) {
 		heartbeat := map[string]any{
-			"id":        h.ID,
-			"monitorID": h.MonitorID,
-			"status":    h.Status,
-			"msg":       h.Message,
-			"time":      h.Time.Format(time.RFC3339),
-			"duration":  h.Duration,
-		}
-		s.socketServer.To("public").Emit("heartbeat", heartbeat)
+			"id":         h.ID,
+			"monitorID":  h.MonitorID,
+			"status":     h.Status,
+			"status_key": model.StatusKey(h.Status),
+			"msg":        h.Message,
+			"time":       h.Time.Format(time.RFC3339),
+			"duration":   h.Duration,
+		}
+		s.socketServer.To("admin").Emit("heartbeat", heartbeat)
+		s.emitToScoped(h.MonitorID, "heartbeat", heartbeat)
+		// 未登录的客户端只收到公开显示的监控项的心跳，检查消息只保留类别
+		if publicVisible(h.MonitorID) {
+			public := maps.Clone(heartbeat)
+			public["msg"] = monitor.PublicMessage(h.Status, h.Message)
+			s.socketServer.To("public").Except("admin").Emit("heartbeat", public)
+		}
+	}
+
+	// 系统告警实时推送给已登录的管理员
+	db.OnServerAlert = func(a *model.ServerAlert) {
+		s.socketServer.To("admin").Emit("serverAlert", a)
+	}
+
+	// 自定义 DNS 不可用时回退到系统解析器，进入和退出回退都通知管理员
+	monitor.OnResolverChange = func(h monitor.ResolverHealth) {
+		msg := fmt.Sprintf("Custom DNS %s is reachable again, using it for checks", h.Server)
+		if h.Fallback {
+			msg = fmt.Sprintf("Custom DNS %s is not responding (%s), checks are using the system resolver", h.Server, h.LastError)
+		}
+		s.socketServer.To("admin").Emit("systemWarning", map[string]any{
+			"code": "dns_resolver", "msg": msg, "resolver": h,
+		})
+	}
+
+	// 启动时分批调度监控项的进度推送给管理员
+	monitor.OnStartupProgress = func(p monitor.StartupProgress) {
+		s.socketServer.To("admin").Emit("startupProgress", startupProgressView(p))
+	}
+
+	// 配置了 Resend 时在后台检查发件域名，避免通知静默进入垃圾箱
+	if config.Get().Notification.ResendAPIKey != "" {
+		go s.refreshEmailSender()
 	}
 
 	// CORS 配置
@@ -91,22 +155,48 @@ func (s *Server) setupSocketHandlers() {
 		client := clients[0].(*socket.Socket)
 		client.Join("public")
 
-		// 断开连接时清理认证状态
+		// 断开连接时清理认证状态和调试会话
 		client.On("disconnect", func(reason ...any) {
+			scopedSockets.Delete(client.Id())
 			socketAuth.Delete(client.Id())
+			pendingLogins.Delete(client.Id())
+			s.monitorService.StopDebugByOwner(string(client.Id()))
 		})
 
 		// 发送服务器信息
-		client.Emit("info", map[string]any{
-			"version": "1.0.0-go",
-		})
+		// 演示模式下附带 demo 标记，前端据此显示演示横幅
+		info := map[string]any{
+			"version": serverVersion,
+		}
+		if cfg := config.Get(); cfg.DemoActive() {
+			info["demo"] = true
+			if !cfg.Demo.Until.IsZero() {
+				info["demo_until"] = cfg.Demo.Until
+			}
+		}
+		client.Emit("info", info)
 
 		// 设置各功能模块的事件处理器
 		s.setupAuthHandlers(client)
 		s.setupNotificationHandlers(client)
+		s.setupTemplateHandlers(client)
+		s.setupNotificationLogHandlers(client)
 		s.setupSettingsHandlers(client)
 		s.setupMonitorHandlers(client)
+		s.setupBulkHandlers(client)
+		s.setupAgentHandlers(client)
+		s.setupAccountHandlers(client)
+		s.setupTwoFactorHandlers(client)
 		s.setupHeartbeatHandlers(client)
+		s.setupServerAlertHandlers(client)
+		s.setupIncidentHandlers(client)
+		s.setupAccessHandlers(client)
+		s.setupDiagnosticsHandlers(client)
+		s.setupStatusPageHandlers(client)
+		s.setupMonitorGroupHandlers(client)
+		s.setupIncidentPostHandlers(client)
+		s.setupMaintenanceHandlers(client)
+		s.setupSubscriptionHandlers(client)
 
 		// 断开连接日志
 		client.On("disconnect", func(reason ...any) {
@@ -138,6 +228,73 @@ func (s *Server) registerRoutes() {
 		s.serveStaticFileGin(c, "assets/favicon.avif")
 	})
 
+	// 公开监控项的可嵌入小部件
+	s.router.GET("/embed/monitor/:id", s.embedMonitor)
+
+	// 启用了徽章的监控项的 SVG 状态和可用率徽章
+	s.router.GET("/badge/:id/status.svg", s.statusBadge)
+	s.router.GET("/badge/:id/uptime/:duration", s.uptimeBadge)
+
+	// 公开状态页（只展示已发布状态页中选择的监控项）
+	s.router.GET("/status/:slug", s.statusPage)
+	api := s.apiRoutes()
+	api.GET("/api/status/:slug", apiDoc{Summary: "Published status page", Tag: "public", Public: true, Response: statusPageData{}}, s.statusPageAPI)
+
+	// 公开监控项的只读 JSON 接口，供外部看板使用（server.public_api_disabled 可关闭）
+	api.GET("/api/status", apiDoc{Summary: "Public monitors", Tag: "public", Public: true, Response: publicStatusResponse{}},
+		requirePublicAPI(), s.publicStatusAPI)
+	api.GET("/api/status/:slug/heartbeats", apiDoc{Summary: "Heartbeats of a public monitor (slug is the monitor ID)", Tag: "public", Public: true,
+		Response: map[string]any{}, Query: []apiParam{{Name: "hours", Type: "integer", Description: "Time range in hours"}}},
+		requirePublicAPI(), s.publicHeartbeatsAPI)
+
+	// 状态变化和事件公告的 Atom 订阅（公开监控项 / 状态页中的监控项）
+	s.router.GET("/feed.xml", s.feed)
+	s.router.GET("/status/:slug/feed.xml", s.statusPageFeed)
+
+	// 状态页邮件订阅（按 IP 限流），以及确认邮件和通知邮件中的确认、退订链接
+	s.router.POST("/status/:slug/subscribe", rejectInDemo(), s.subscribe)
+	s.router.GET("/subscriptions/confirm", s.confirmSubscription)
+	s.router.GET("/subscriptions/unsubscribe", s.unsubscribe)
+	s.router.POST("/subscriptions/unsubscribe", s.unsubscribe)
+
+	// Push 监控上报接口
+	// Push 接口沿用 Uptime Kuma 兼容的 {ok, msg} 响应格式
+	pushQuery := []apiParam{
+		{Name: "status", Type: "string", Description: "up (default) or down"},
+		{Name: "msg", Type: "string"},
+		{Name: "ping", Type: "number", Description: "Response time in milliseconds"},
+	}
+	api.GET("/api/push/:token", apiDoc{Summary: "Report a push heartbeat", Tag: "push", Public: true, Query: pushQuery, Response: pushResponse{}}, s.handlePush)
+	api.POST("/api/push/:token", apiDoc{Summary: "Report a push heartbeat", Tag: "push", Public: true, Body: pushRequest{}, Response: pushResponse{}}, s.handlePush)
+	// 签名示例包含用签名密钥计算出的签名，只允许不限范围的管理员凭据访问
+	api.GET("/api/inbound/:token/sign-example", apiDoc{Summary: "Signed push request example", Tag: "push", Response: map[string]any{}},
+		requireAPIAuth(), requireFullAPIAccess(), s.signExampleAPI)
+
+	// Prometheus 告警规则导出（需要 API 认证）
+	api.GET("/api/prometheus/rules", apiDoc{Summary: "Prometheus alerting rules", Tag: "prometheus", Produces: "application/yaml"},
+		requireAPIAuth(), s.prometheusRulesAPI)
+
+	// REST API v1（API 密钥认证）
+	s.registerAPIv1Routes()
+
+	// 远程探针拉取分配的监控项、上报检查结果（探针令牌认证）
+	agent := api.Group("/api/agent", requireAgentAuth())
+	agent.GET("/monitors", apiDoc{Summary: "Monitors assigned to the calling agent", Tag: "agents", Response: agentMonitorsResponse{}}, s.agentMonitorsAPI)
+	agent.POST("/heartbeats", apiDoc{Summary: "Report check results from an agent", Tag: "agents", Body: agentReport{}, Response: agentReportResult{}},
+		s.agentHeartbeatsAPI)
+
+	// 维护窗口（供 CI/CD 流水线在部署前后调用，需要 API 认证）
+	api.POST("/api/maintenance", apiDoc{Summary: "Start a maintenance window", Tag: "maintenance", Body: maintenanceRequest{},
+		Response: maintenanceCreated{}, Status: http.StatusCreated}, rejectInDemo(), requireAPIAuth(), s.createMaintenanceAPI)
+	api.DELETE("/api/maintenance/:id", apiDoc{Summary: "End a maintenance window", Tag: "maintenance", Response: maintenanceEnded{}},
+		rejectInDemo(), requireAPIAuth(), s.endMaintenanceAPI)
+
+	// 诊断信息和 pprof（默认关闭，仅管理员会话）
+	s.registerDebugRoutes()
+
+	// OpenAPI 文档，由上面通过 apiRoutes 注册的接口生成
+	api.GET("/api/openapi.json", apiDoc{Summary: "OpenAPI document", Tag: "meta", Public: true, Response: map[string]any{}}, s.openAPI)
+
 	// Socket.IO 端点
 	handler := s.socketServer.ServeHandler(nil)
 	s.router.GET("/socket.io/*any", gin.WrapH(handler))
@@ -160,18 +317,43 @@ func (s *Server) serveStaticFileGin(c *gin.Context, filename string) {
 		}
 
 		contentType := getContentType(filename)
+		if strings.HasSuffix(filename, ".html") {
+			content = injectSiteConfig(content)
+		}
 		c.Data(http.StatusOK, contentType, content)
 	} else {
 		c.String(http.StatusOK, "Frontend not loaded")
 	}
 }
 
+// injectSiteConfig 在 HTML 的 <head> 后插入 window.__CONFIG__（站点名称、Logo、主题色和页脚），
+// 页面首次渲染时即可使用，不必等待 getPublicSettings。json.Marshal 会转义 <、>，不会提前结束 script 标签
+func injectSiteConfig(content []byte) []byte {
+	i := bytes.Index(content, []byte("<head>"))
+	if i < 0 {
+		return content
+	}
+	cfg, err := json.Marshal(db.Site())
+	if err != nil {
+		return content
+	}
+	i += len("<head>")
+	var b bytes.Buffer
+	b.Grow(len(content) + len(cfg) + 64)
+	b.Write(content[:i])
+	b.WriteString("\n    <script>window.__CONFIG__ = ")
+	b.Write(cfg)
+	b.WriteString(";</script>")
+	b.Write(content[i:])
+	return b.Bytes()
+}
+
 // SetStatic 设置静态文件处理
 func (s *Server) SetStatic(fs http.FileSystem) {
 	s.router.NoRoute(func(c *gin.Context) {
 		path := c.Request.URL.Path
 		if len(path) >= 4 && path[:4] == "/api" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+			writeAPIError(c, apperrors.New(http.StatusNotFound, "Not Found", http.StatusNotFound, nil))
 			return
 		}
 
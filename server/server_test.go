@@ -0,0 +1,148 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"ping-go/db"
+	"ping-go/internal/testutil"
+	"ping-go/model"
+	"ping-go/monitor"
+	"ping-go/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// newTestServer 在 t.TempDir() 中的临时数据库上启动完整的服务器（HTTP 路由和 Socket.IO），
+// 日志丢弃，测试结束时关闭服务器、停止监控服务并关闭数据库
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	logger.Logger = zap.NewNop()
+	if err := db.Init(filepath.Join(t.TempDir(), "pinggo.db")); err != nil {
+		t.Fatalf("db.Init: %v", err)
+	}
+	t.Cleanup(db.Close)
+	// 公开显示和公开接口的缓存是全局的，每个测试的数据库都从 ID 1 开始
+	publicVisibility.Clear()
+	publicAPIMu.Lock()
+	clear(publicAPICache)
+	publicAPIMu.Unlock()
+	clearEmbedCache()
+	badgeMu.Lock()
+	clear(badgeCache)
+	badgeMu.Unlock()
+	svc := monitor.NewService()
+	t.Cleanup(svc.StopAll)
+	s := NewServer(svc, nil)
+	ts := httptest.NewServer(s.Router())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// createTestAPIKey 创建指定权限和标签范围的 API 密钥，返回明文
+func createTestAPIKey(t *testing.T, scope, tagScope string) string {
+	t.Helper()
+	key := apiKeyPrefix + randomHex(t, 16)
+	rec := model.APIKey{Name: scope + " key", KeyHash: hashAPIKey(key), Prefix: key[:8], Scope: scope, TagScope: tagScope}
+	if err := db.DB.Create(&rec).Error; err != nil {
+		t.Fatalf("create API key: %v", err)
+	}
+	return key
+}
+
+// createTestSession 创建指定角色的账号和会话，返回会话 token
+func createTestSession(t *testing.T, username, role string) string {
+	t.Helper()
+	user := model.User{Username: username, Password: "x", Role: role}
+	if err := db.DB.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	token := generateToken()
+	sess := model.Session{Token: token, UserID: user.ID, Role: role, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.DB.Create(&sess).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return token
+}
+
+// createTestMonitor 创建监控项并补全测试中不关心的必填字段
+func createTestMonitor(t *testing.T, m model.Monitor) model.Monitor {
+	t.Helper()
+	if m.Type == "" {
+		m.Type = model.MonitorTypeHTTP
+	}
+	if m.URL == "" && m.Type == model.MonitorTypeHTTP {
+		m.URL = "http://example.invalid"
+	}
+	if m.Interval == 0 {
+		m.Interval = 60
+	}
+	if err := createMonitor(db.DB, &m); err != nil {
+		t.Fatalf("create monitor: %v", err)
+	}
+	return m
+}
+
+// doAPI 以 Bearer token 发送请求，返回状态码和响应体
+func doAPI(t *testing.T, ts *httptest.Server, method, path, token, body string) (int, []byte) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, data
+}
+
+// decodeJSON 解析 JSON 响应，失败时终止测试
+func decodeJSON(t *testing.T, data []byte, v any) {
+	t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", data, err)
+	}
+}
+
+// dialSocket 建立一个未登录的 Socket.IO 连接，测试结束时（在关闭服务器之前）断开
+func dialSocket(t *testing.T, ts *httptest.Server) *testutil.Client {
+	t.Helper()
+	c, err := testutil.Dial(ts.URL)
+	if err != nil {
+		t.Fatalf("socket connect: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
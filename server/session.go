@@ -3,19 +3,138 @@ package server
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"ping-go/config"
 	"ping-go/db"
 	"ping-go/model"
 	"ping-go/pkg/logger"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// 会话有效期设置项（整数），覆盖配置 session.ttl_hours / session.max_days，为空时使用配置
+const (
+	sessionTTLSettingKey    = "sessionTTLHours"
+	sessionMaxAgeSettingKey = "sessionMaxDays"
+)
+
+const (
+	// defaultSessionTTL 最后一次使用后会话保持有效的默认时长
+	defaultSessionTTL = 24 * time.Hour
+	// defaultSessionMaxAge 登录后会话的默认最长期限
+	defaultSessionMaxAge = 30 * 24 * time.Hour
+	// sessionTouchInterval 同一会话延长有效期时写数据库的最小间隔，避免每个事件都写一次
+	sessionTouchInterval = 10 * time.Minute
+
+	// 设置项的上限
+	maxSessionTTLHours = 24 * 365
+	maxSessionMaxDays  = 365
+)
+
 // socketAuth 存储 socket 连接的认证状态
 // key: socketID (string), value: map[string]any
 var socketAuth = sync.Map{}
 
+// sessionTouched 各会话最近一次尝试延长有效期的时间，key: token
+var sessionTouched = sync.Map{}
+
+// sessionLifetime 返回会话的滑动有效期和最长期限：设置项优先，其次为配置，都未设置时为 24 小时和 30 天。
+// 滑动有效期不超过最长期限
+func sessionLifetime() (ttl, maxAge time.Duration) {
+	cfg := config.Get().Session
+	ttl, maxAge = defaultSessionTTL, defaultSessionMaxAge
+	if cfg.TTLHours > 0 {
+		ttl = time.Duration(cfg.TTLHours) * time.Hour
+	}
+	if cfg.MaxDays > 0 {
+		maxAge = time.Duration(cfg.MaxDays) * 24 * time.Hour
+	}
+
+	var settings []model.Setting
+	db.DB.Where("key IN ?", []string{sessionTTLSettingKey, sessionMaxAgeSettingKey}).Find(&settings)
+	for _, setting := range settings {
+		n, err := strconv.Atoi(strings.TrimSpace(setting.Value))
+		if err != nil || n <= 0 {
+			continue
+		}
+		switch setting.Key {
+		case sessionTTLSettingKey:
+			ttl = time.Duration(min(n, maxSessionTTLHours)) * time.Hour
+		case sessionMaxAgeSettingKey:
+			maxAge = time.Duration(min(n, maxSessionMaxDays)) * 24 * time.Hour
+		}
+	}
+	return min(ttl, maxAge), maxAge
+}
+
+// validateSessionSettings 校验 setSettings 中的会话有效期设置，返回错误信息；值为空表示恢复配置中的值
+func validateSessionSettings(settingsMap map[string]any) string {
+	limits := map[string]int{sessionTTLSettingKey: maxSessionTTLHours, sessionMaxAgeSettingKey: maxSessionMaxDays}
+	for key, limit := range limits {
+		v, ok := settingsMap[key]
+		if !ok {
+			continue
+		}
+		raw := strings.TrimSpace(fmt.Sprintf("%v", v))
+		if raw == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(raw); err != nil || n < 1 || n > limit {
+			return fmt.Sprintf("%s 应为 1 到 %d 之间的整数", key, limit)
+		}
+	}
+	return ""
+}
+
+// newSessionExpiry 新会话的过期时间
+func newSessionExpiry() time.Time {
+	ttl, _ := sessionLifetime()
+	return time.Now().Add(ttl)
+}
+
+// validSession 读取 token 对应的未过期会话，并按滑动过期延长其有效期
+func validSession(token string) (*model.Session, bool) {
+	if token == "" {
+		return nil, false
+	}
+	var sess model.Session
+	if err := db.DB.Where("token = ?", token).Limit(1).Find(&sess).Error; err != nil || sess.Token == "" || !time.Now().Before(sess.ExpiresAt) {
+		return nil, false
+	}
+	touchSession(token)
+	return &sess, true
+}
+
+// touchSession 滑动过期：把会话的过期时间延长到现在起 ttl 之后，但不超过登录时间加最长期限。
+// 同一会话每 sessionTouchInterval 最多写一次数据库；已过期的会话不再延长，由 startSessionCleanup 删除
+func touchSession(token string) {
+	now := time.Now()
+	if last, ok := sessionTouched.Load(token); ok && now.Sub(last.(time.Time)) < sessionTouchInterval {
+		return
+	}
+	sessionTouched.Store(token, now)
+
+	var sess model.Session
+	if err := db.DB.Where("token = ?", token).Limit(1).Find(&sess).Error; err != nil || sess.Token == "" || !now.Before(sess.ExpiresAt) {
+		return
+	}
+	ttl, maxAge := sessionLifetime()
+	expires := now.Add(ttl)
+	if limit := sess.CreatedAt.Add(maxAge); expires.After(limit) {
+		expires = limit
+	}
+	if !expires.After(sess.ExpiresAt) {
+		return
+	}
+	if err := db.DB.Model(&model.Session{}).Where("token = ? AND expires_at < ?", token, expires).Update("expires_at", expires).Error; err != nil {
+		logger.Error("Failed to extend session", zap.Error(err))
+	}
+}
+
 // startSessionCleanup 启动会话清理任务，每小时清理过期的会话
 func startSessionCleanup() {
 	ticker := time.NewTicker(1 * time.Hour)
@@ -24,6 +143,13 @@ func startSessionCleanup() {
 		if err := db.DB.Where("expires_at < ?", time.Now()).Delete(&model.Session{}).Error; err != nil {
 			logger.Error("Failed to clean up sessions", zap.Error(err))
 		}
+		// 节流记录过了间隔就不再需要，下次使用时重新记录
+		sessionTouched.Range(func(key, val any) bool {
+			if time.Since(val.(time.Time)) >= sessionTouchInterval {
+				sessionTouched.Delete(key)
+			}
+			return true
+		})
 	}
 }
 
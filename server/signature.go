@@ -0,0 +1,105 @@
+package server
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	signatureHeader = "X-PingGo-Signature"
+	timestampHeader = "X-PingGo-Timestamp"
+	// signatureWindow 时间戳允许与服务器时间相差的范围
+	signatureWindow = 5 * time.Minute
+	// replayCacheSize 防重放缓存最多记录的签名数量
+	replayCacheSize = 10000
+	// minSecretLength 签名密钥的最小长度
+	minSecretLength = 16
+)
+
+var (
+	errSignatureMissing  = errors.New("missing " + timestampHeader + " or " + signatureHeader + " header")
+	errSignatureExpired  = errors.New("timestamp outside the allowed window")
+	errSignatureInvalid  = errors.New("invalid signature")
+	errSignatureReplayed = errors.New("signature already used")
+)
+
+// computeSignature 计算 HMAC-SHA256(secret, timestamp + body) 的十六进制签名
+func computeSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature 校验请求签名：时间戳需在 ±5 分钟内，签名正确且未被使用过
+func (s *Server) verifySignature(secret, timestamp, signature string, body []byte) error {
+	if timestamp == "" || signature == "" {
+		return errSignatureMissing
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errSignatureExpired
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > signatureWindow || d < -signatureWindow {
+		return errSignatureExpired
+	}
+
+	signature = strings.TrimPrefix(strings.ToLower(signature), "sha256=")
+	expected := computeSignature(secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errSignatureInvalid
+	}
+
+	// 签名在时间窗口内有效，缓存两倍窗口时长即可覆盖所有可能的重放
+	if s.replayCache.seen(expected, 2*signatureWindow) {
+		return errSignatureReplayed
+	}
+	return nil
+}
+
+// replayCache 记录已使用过的签名，按 LRU 淘汰，过期的条目视为未出现
+type replayCache struct {
+	mu    sync.Mutex
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type replayEntry struct {
+	key     string
+	expires time.Time
+}
+
+func newReplayCache(max int) *replayCache {
+	return &replayCache{max: max, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// seen 返回 key 是否已在有效期内出现过；未出现时记录下来
+func (c *replayCache) seen(key string, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if el, ok := c.items[key]; ok {
+		if now.Before(el.Value.(*replayEntry).expires) {
+			return true
+		}
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+
+	c.items[key] = c.ll.PushFront(&replayEntry{key: key, expires: now.Add(ttl)})
+	for c.ll.Len() > c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*replayEntry).key)
+	}
+	return false
+}
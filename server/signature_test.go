@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "0123456789abcdef"
+	body := []byte(`{"status":"up"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	at := func(d time.Duration) string { return strconv.FormatInt(time.Now().Add(d).Unix(), 10) }
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{"valid", now, computeSignature(secret, now, body), body, nil},
+		{"sha256= prefix, upper case", now, "sha256=" + strings.ToUpper(computeSignature(secret, now, []byte("x"))), []byte("x"), nil},
+		{"missing timestamp", "", computeSignature(secret, now, body), body, errSignatureMissing},
+		{"missing signature", now, "", body, errSignatureMissing},
+		{"timestamp not a number", "yesterday", computeSignature(secret, "yesterday", body), body, errSignatureExpired},
+		{"too old", at(-6 * time.Minute), computeSignature(secret, at(-6*time.Minute), body), body, errSignatureExpired},
+		{"too far ahead", at(6 * time.Minute), computeSignature(secret, at(6*time.Minute), body), body, errSignatureExpired},
+		{"within window", at(-4 * time.Minute), computeSignature(secret, at(-4*time.Minute), body), body, nil},
+		{"wrong secret", now, computeSignature("another-secret-value", now, body), body, errSignatureInvalid},
+		{"tampered body", now, computeSignature(secret, now, body), []byte(`{"status":"down"}`), errSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{replayCache: newReplayCache(replayCacheSize)}
+			if err := s.verifySignature(secret, tt.timestamp, tt.signature, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("verifySignature = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureRejectsReplay(t *testing.T) {
+	const secret = "0123456789abcdef"
+	s := &Server{replayCache: newReplayCache(2)}
+	sign := func(body string) (string, string) {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		return ts, computeSignature(secret, ts, []byte(body))
+	}
+
+	ts, sig := sign("a")
+	if err := s.verifySignature(secret, ts, sig, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.verifySignature(secret, ts, sig, []byte("a")); !errors.Is(err, errSignatureReplayed) {
+		t.Fatalf("replayed signature: %v, want %v", err, errSignatureReplayed)
+	}
+	// 缓存满后淘汰最久未使用的签名
+	for _, body := range []string{"b", "c"} {
+		ts, sig := sign(body)
+		if err := s.verifySignature(secret, ts, sig, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.replayCache.ll.Len(); n != 2 {
+		t.Fatalf("replay cache holds %d signatures, want at most 2", n)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"ping-go/db"
+	"ping-go/model"
+	"ping-go/monitor"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// 编辑器测试响应缓存：只在内存中保存，按连接和监控项区分，超过数量时淘汰最早的
+const (
+	testCacheTTL        = 5 * time.Minute
+	testCacheMaxEntries = 64
+	// maxAssertionMatches 正则断言最多返回的匹配位置数
+	maxAssertionMatches = 50
+)
+
+// testResponse 一次 HTTP 测试的响应（TestHTTP 返回的状态码和响应体预览）
+type testResponse struct {
+	Status    int
+	Body      string
+	URL       string
+	Method    string
+	FetchedAt time.Time
+}
+
+var (
+	testCacheMu sync.Mutex
+	testCache   = make(map[string]*testResponse)
+)
+
+// testCacheKey 缓存 key：连接 ID + 监控项 ID（新建时为 0）
+func testCacheKey(client *socket.Socket, monitorID uint) string {
+	return fmt.Sprintf("%s:%d", client.Id(), monitorID)
+}
+
+// storeTestResponse 保存测试响应，同时清理过期项；仍超过上限时淘汰最早获取的
+func storeTestResponse(key string, r *testResponse) {
+	testCacheMu.Lock()
+	defer testCacheMu.Unlock()
+	now := time.Now()
+	for k, v := range testCache {
+		if now.Sub(v.FetchedAt) >= testCacheTTL {
+			delete(testCache, k)
+		}
+	}
+	if _, ok := testCache[key]; !ok && len(testCache) >= testCacheMaxEntries {
+		oldest := ""
+		for k, v := range testCache {
+			if oldest == "" || v.FetchedAt.Before(testCache[oldest].FetchedAt) {
+				oldest = k
+			}
+		}
+		delete(testCache, oldest)
+	}
+	testCache[key] = r
+}
+
+// cachedTestResponse 返回未过期的测试响应
+func cachedTestResponse(key string) (*testResponse, bool) {
+	testCacheMu.Lock()
+	defer testCacheMu.Unlock()
+	r, ok := testCache[key]
+	if !ok || time.Since(r.FetchedAt) >= testCacheTTL {
+		delete(testCache, key)
+		return nil, false
+	}
+	return r, true
+}
+
+// setupTestAssertionsHandler 注册 testMonitorAssertions：用缓存的测试响应重新计算断言，不再请求目标地址
+func (s *Server) setupTestAssertionsHandler(client *socket.Socket) {
+	// Handle "testMonitorAssertions"
+	// 参数为 (monitorID, {expected_status, response_regex, json_path, json_value, refresh, monitor})，
+	// monitorID 为 0 表示尚未保存的监控项；refresh 为 true 或没有缓存时重新请求，monitor 为编辑器当前的表单
+	requireAuth(client, "testMonitorAssertions", func(args ...any) {
+		ack := getCallback(args)
+		if ack == nil {
+			return
+		}
+		reply := func(resp map[string]any) { ack([]any{resp}, nil) }
+
+		monitorID, _ := getArgAsUint(args, 0)
+		var opts map[string]any
+		if len(args) > 1 {
+			opts, _ = args[1].(map[string]any)
+		}
+		if opts == nil {
+			reply(map[string]any{"ok": false, "msg": "Missing assertions"})
+			return
+		}
+		if monitorID != 0 && !checkMonitorScope(client, monitorID, args) {
+			return
+		}
+
+		key := testCacheKey(client, monitorID)
+		refresh, _ := opts["refresh"].(bool)
+		cached, ok := cachedTestResponse(key)
+		if refresh || !ok {
+			m, errMsg := assertionTestMonitor(monitorID, opts)
+			if errMsg != "" {
+				reply(map[string]any{"ok": false, "msg": errMsg})
+				return
+			}
+			status, body := monitor.TestHTTP(m)
+			cached = &testResponse{Status: status, Body: body, URL: m.URL, Method: m.Method, FetchedAt: time.Now()}
+			storeTestResponse(key, cached)
+		}
+
+		results, passed := evaluateTestAssertions(cached, opts)
+		resp := map[string]any{
+			"ok":          true,
+			"passed":      passed,
+			"cached":      !refresh && ok,
+			"fetched_at":  cached.FetchedAt,
+			"age_seconds": int(time.Since(cached.FetchedAt).Seconds()),
+			"url":         cached.URL,
+			"status":      cached.Status,
+			"body":        cached.Body,
+			"results":     results,
+		}
+		// 已保存的监控项附带最近一次正式检查的结果，便于和测试响应对比
+		if monitorID != 0 {
+			var last model.Heartbeat
+			if err := db.DB.Where("monitor_id = ? AND agent_id = 0", monitorID).Order("time desc").First(&last).Error; err == nil {
+				resp["last_check"] = map[string]any{"status": last.Status, "msg": last.Message, "time": last.Time, "duration": last.Duration}
+			}
+		}
+		reply(resp)
+	})
+}
+
+// assertionTestMonitor 需要重新请求时的监控项：优先使用编辑器提交的表单，否则读取已保存的监控项
+func assertionTestMonitor(monitorID uint, opts map[string]any) (model.Monitor, string) {
+	var m model.Monitor
+	if form, ok := opts["monitor"].(map[string]any); ok {
+		if monitorID != 0 {
+			form["id"] = float64(monitorID)
+		}
+		m = testMonitorFromData(form)
+	} else if monitorID != 0 {
+		if err := db.DB.First(&m, monitorID).Error; err != nil {
+			return m, "Monitor not found"
+		}
+	} else {
+		return m, "No cached response, submit the monitor form to fetch one"
+	}
+	if m.Type != model.MonitorTypeHTTP {
+		return m, "Assertions can only be tested on HTTP monitors"
+	}
+	if errMsg := validateRegexScanBytes(m.RegexScanBytes); errMsg != "" {
+		return m, errMsg
+	}
+	if _, errMsg := normalizeMonitorURL(&m); errMsg != "" {
+		return m, errMsg
+	}
+	return m, ""
+}
+
+// assertionMatch 正则匹配的位置，start/end 为 JavaScript 字符串下标（UTF-16），便于前端直接高亮
+type assertionMatch struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// evaluateTestAssertions 在缓存的响应上计算状态码、响应正则和 JSON 路径断言，返回每项结果以及是否全部通过
+func evaluateTestAssertions(r *testResponse, opts map[string]any) (map[string]any, bool) {
+	results := make(map[string]any)
+	passed := true
+
+	if v, ok := safeMapGetFloat64(opts, "expected_status"); ok && v > 0 {
+		ok := r.Status == int(v)
+		passed = passed && ok
+		results["status"] = map[string]any{"ok": ok, "expected": int(v), "actual": r.Status}
+	}
+
+	if pattern := convertJSONToRegex(safeMapGetString(opts, "response_regex")); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			passed = false
+			results["regex"] = map[string]any{"ok": false, "error": err.Error()}
+		} else {
+			var matches []assertionMatch
+			total := 0
+			for _, loc := range re.FindAllStringIndex(r.Body, -1) {
+				total++
+				if len(matches) < maxAssertionMatches {
+					matches = append(matches, assertionMatch{
+						Start: utf16Offset(r.Body, loc[0]), End: utf16Offset(r.Body, loc[1]),
+						Text: r.Body[loc[0]:loc[1]],
+					})
+				}
+			}
+			passed = passed && total > 0
+			results["regex"] = map[string]any{"ok": total > 0, "pattern": pattern, "count": total, "matches": matches}
+		}
+	}
+
+	if path := safeMapGetString(opts, "json_path"); path != "" {
+		res := map[string]any{"path": path}
+		var doc any
+		if err := json.Unmarshal([]byte(r.Body), &doc); err != nil {
+			res["ok"], res["error"] = false, "response is not valid JSON (or was truncated to the 50KB preview)"
+		} else if value, found := monitor.LookupJSONPath(doc, path); !found {
+			res["ok"], res["error"] = false, "path not found"
+		} else {
+			res["value"] = value
+			expected, hasExpected := opts["json_value"]
+			res["ok"] = !hasExpected || value == jsonValueString(expected)
+			if hasExpected {
+				res["expected"] = expected
+			}
+		}
+		passed = passed && res["ok"] == true
+		results["json_path"] = res
+	}
+	return results, passed
+}
+
+// jsonValueString 期望值按 LookupJSONPath 的格式转为字符串比较
+func jsonValueString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, _ := json.Marshal(val)
+		return string(b)
+	}
+}
+
+// utf16Offset 把字节下标转换为 UTF-16 下标
+func utf16Offset(s string, byteOffset int) int {
+	n := 0
+	for _, r := range s[:byteOffset] {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}